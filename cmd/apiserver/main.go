@@ -15,6 +15,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -23,10 +24,14 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/nexodus-io/nexodus/internal/api/syncv1"
 	"github.com/nexodus-io/nexodus/internal/database"
+	"github.com/nexodus-io/nexodus/internal/email"
 	"github.com/nexodus-io/nexodus/internal/fflags"
 	"github.com/nexodus-io/nexodus/internal/handlers"
+	"github.com/nexodus-io/nexodus/internal/idp"
 	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/nexodus-io/nexodus/internal/routers"
 	"github.com/open-policy-agent/opa/storage/inmem"
 	"go.opentelemetry.io/otel"
@@ -89,7 +94,7 @@ func main() {
 			&cli.StringFlag{
 				Name:    "listen-grpc",
 				Value:   "0.0.0.0:5080",
-				Usage:   "The address and port to listen for GRPC requests on",
+				Usage:   "The address and port to listen for GRPC requests on, such as the device sync service",
 				EnvVars: []string{"NEXAPI_LISTEN_GRPC"},
 			},
 
@@ -129,6 +134,29 @@ func main() {
 				Usage:   "OIDC client id for cli",
 				EnvVars: []string{"NEXAPI_OIDC_CLIENT_ID_CLI"},
 			},
+			&cli.StringFlag{
+				Name:    "oidc-audience",
+				Value:   "account",
+				Usage:   "Audience of the access tokens of the oidc provider",
+				EnvVars: []string{"NEXAPI_OIDC_AUDIENCE"},
+			},
+			&cli.StringSliceFlag{
+				Name:    "oidc-allowed-email-domains",
+				Usage:   "Only allow the users of the oidc provider with an email of these domains, every user is allowed when not set",
+				EnvVars: []string{"NEXAPI_OIDC_ALLOWED_EMAIL_DOMAINS"},
+			},
+			&cli.StringFlag{
+				Name:    "oidc-email-domain-claim",
+				Value:   "",
+				Usage:   "Only check the email domain of the users whose access token has this claim set to true",
+				EnvVars: []string{"NEXAPI_OIDC_EMAIL_DOMAIN_CLAIM"},
+			},
+			&cli.StringFlag{
+				Name:    "idp-config",
+				Value:   "",
+				Usage:   "Path to a yaml file configuring additional identity providers that devices can log in with",
+				EnvVars: []string{"NEXAPI_IDP_CONFIG"},
+			},
 			&cli.StringFlag{
 				Name:    "db-host",
 				Value:   "apiserver-db",
@@ -168,9 +196,131 @@ func main() {
 			&cli.StringFlag{
 				Name:    "ipam-address",
 				Value:   "ipam:9090",
-				Usage:   "Address of ipam grpc service",
+				Usage:   "Address of ipam grpc service, used by the 'remote' ipam backend",
 				EnvVars: []string{"NEXAPI_IPAM_URL"},
 			},
+			&cli.StringFlag{
+				Name:    "ipam-backend",
+				Value:   ipam.BackendDatabase,
+				Usage:   "IPAM backend, 'database' allocates in the apiserver database, 'remote' uses the ipam grpc service",
+				EnvVars: []string{"NEXAPI_IPAM_BACKEND"},
+			},
+			&cli.DurationFlag{
+				Name:    "ipam-reconcile-interval",
+				Usage:   "How often the IPAM allocations are compared with the devices to find leaked, missing and conflicting allocations, 0 disables it",
+				Value:   10 * time.Minute,
+				EnvVars: []string{"NEXAPI_IPAM_RECONCILE_INTERVAL"},
+			},
+			&cli.BoolFlag{
+				Name:    "ipam-reconcile-repair",
+				Usage:   "Release the leaked IPAM allocations and allocate the missing ones that the reconciliation finds",
+				EnvVars: []string{"NEXAPI_IPAM_RECONCILE_REPAIR"},
+			},
+			&cli.StringSliceFlag{
+				Name:    "admin-users",
+				Usage:   "The ids of the users that administer the apiserver, who can list the IPAM drift of all the organizations",
+				Value:   &cli.StringSlice{},
+				EnvVars: []string{"NEXAPI_ADMIN_USERS"},
+			},
+			&cli.DurationFlag{
+				Name:    "group-sync-interval",
+				Usage:   "How often the organization memberships of the group mappings are resynced with the last seen groups of the users, 0 disables it",
+				Value:   10 * time.Minute,
+				EnvVars: []string{"NEXAPI_GROUP_SYNC_INTERVAL"},
+			},
+			&cli.DurationFlag{
+				Name:    "group-sync-max-age",
+				Usage:   "Remove the users whose groups haven't been seen for this long from the organizations of the group mappings, 0 keeps them",
+				Value:   time.Hour,
+				EnvVars: []string{"NEXAPI_GROUP_SYNC_MAX_AGE"},
+			},
+			&cli.DurationFlag{
+				Name:    "jwks-refresh-interval",
+				Usage:   "How often the key sets of the OIDC identity providers are refreshed, the key sets are also refreshed when a token is signed with an unknown key. 0 disables the periodic refresh",
+				Value:   time.Minute,
+				EnvVars: []string{"NEXAPI_JWKS_REFRESH_INTERVAL"},
+			},
+			&cli.StringFlag{
+				Name:    "policy-bundle",
+				Usage:   "A directory, a bundle file or the https url of a bundle server with an OPA bundle whose deny rules restrict the authorization policy",
+				EnvVars: []string{"NEXAPI_POLICY_BUNDLE"},
+			},
+			&cli.StringFlag{
+				Name:    "policy-bundle-verification-key",
+				Usage:   "The public key, or the HMAC secret, or a file holding it, that verifies the signature of the policy bundle, required for a bundle server",
+				EnvVars: []string{"NEXAPI_POLICY_BUNDLE_VERIFICATION_KEY"},
+			},
+			&cli.StringFlag{
+				Name:    "policy-bundle-verification-key-alg",
+				Usage:   "The signing algorithm of the policy bundle verification key",
+				Value:   "RS256",
+				EnvVars: []string{"NEXAPI_POLICY_BUNDLE_VERIFICATION_KEY_ALG"},
+			},
+			&cli.StringFlag{
+				Name:    "policy-bundle-verification-key-id",
+				Usage:   "The id of the policy bundle verification key in the signatures of the bundle",
+				Value:   "default",
+				EnvVars: []string{"NEXAPI_POLICY_BUNDLE_VERIFICATION_KEY_ID"},
+			},
+			&cli.DurationFlag{
+				Name:    "policy-bundle-poll-interval",
+				Usage:   "How often the policy bundle is reloaded when it changes, 0 only loads it at startup",
+				Value:   time.Minute,
+				EnvVars: []string{"NEXAPI_POLICY_BUNDLE_POLL_INTERVAL"},
+			},
+			&cli.BoolFlag{
+				Name:    "policy-decision-log",
+				Usage:   "Log the decisions of the authorization policy",
+				EnvVars: []string{"NEXAPI_POLICY_DECISION_LOG"},
+			},
+			&cli.StringFlag{
+				Name:    "scim-token",
+				Usage:   "The bearer token of the identity provider for the SCIM provisioning endpoints, they are disabled when it is empty",
+				EnvVars: []string{"NEXAPI_SCIM_TOKEN"},
+			},
+			&cli.StringFlag{
+				Name:    "scim-identity-provider",
+				Usage:   "The identity provider whose users and groups are provisioned with SCIM",
+				Value:   idp.DefaultProviderName,
+				EnvVars: []string{"NEXAPI_SCIM_IDENTITY_PROVIDER"},
+			},
+			&cli.DurationFlag{
+				Name:    "invitation-expiry",
+				Usage:   "How long the invitations are valid when they are created without an expiry",
+				Value:   models.DefaultInvitationExpiry,
+				EnvVars: []string{"NEXAPI_INVITATION_EXPIRY"},
+			},
+			&cli.StringFlag{
+				Name:    "invitation-link-url",
+				Usage:   "The url of the invitation links, the invitations page of the SPA at the redirect url by default",
+				EnvVars: []string{"NEXAPI_INVITATION_LINK_URL"},
+			},
+			&cli.StringFlag{
+				Name:    "smtp-address",
+				Usage:   "The host:port of the SMTP server the invitations are sent through, they are not sent by email when it is empty",
+				EnvVars: []string{"NEXAPI_SMTP_ADDRESS"},
+			},
+			&cli.StringFlag{
+				Name:    "smtp-from",
+				Usage:   "The sender of the emails",
+				Value:   "Nexodus <noreply@example.com>",
+				EnvVars: []string{"NEXAPI_SMTP_FROM"},
+			},
+			&cli.StringFlag{
+				Name:    "smtp-username",
+				Usage:   "The username to authenticate with the SMTP server",
+				EnvVars: []string{"NEXAPI_SMTP_USERNAME"},
+			},
+			&cli.StringFlag{
+				Name:    "smtp-password",
+				Usage:   "The password to authenticate with the SMTP server",
+				EnvVars: []string{"NEXAPI_SMTP_PASSWORD"},
+			},
+			&cli.BoolFlag{
+				Name:    "smtp-insecure-tls",
+				Usage:   "Don't verify the certificate of the SMTP server",
+				EnvVars: []string{"NEXAPI_SMTP_INSECURE_TLS"},
+			},
 			&cli.BoolFlag{
 				Name:    "trace-insecure",
 				Value:   false,
@@ -227,6 +377,57 @@ func main() {
 				Value:   1,
 				EnvVars: []string{"NEXAPI_REDIS_DB"},
 			},
+			&cli.StringFlag{
+				Name:    "signal-bus",
+				Usage:   "How change notifications are shared between apiserver replicas: postgres (LISTEN/NOTIFY) or redis (pub/sub)",
+				Value:   "postgres",
+				EnvVars: []string{"NEXAPI_SIGNAL_BUS"},
+			},
+			&cli.IntFlag{
+				Name:    "rate-limit-user",
+				Usage:   "Maximum api requests per minute of each user, 0 disables the limit",
+				Value:   1200,
+				EnvVars: []string{"NEXAPI_RATE_LIMIT_USER"},
+			},
+			&cli.IntFlag{
+				Name:    "rate-limit-organization",
+				Usage:   "Maximum api requests per minute to the apis of each organization, 0 disables the limit",
+				Value:   6000,
+				EnvVars: []string{"NEXAPI_RATE_LIMIT_ORGANIZATION"},
+			},
+			&cli.IntFlag{
+				Name:    "quota-devices-per-organization",
+				Usage:   "Maximum number of devices in an organization, 0 is unlimited",
+				EnvVars: []string{"NEXAPI_QUOTA_DEVICES_PER_ORGANIZATION"},
+			},
+			&cli.IntFlag{
+				Name:    "quota-organizations-per-user",
+				Usage:   "Maximum number of organizations a user can own, 0 is unlimited",
+				EnvVars: []string{"NEXAPI_QUOTA_ORGANIZATIONS_PER_USER"},
+			},
+			&cli.IntFlag{
+				Name:    "quota-metadata-keys-per-device",
+				Usage:   "Maximum number of metadata keys on a device, 0 is unlimited",
+				EnvVars: []string{"NEXAPI_QUOTA_METADATA_KEYS_PER_DEVICE"},
+			},
+			&cli.DurationFlag{
+				Name:    "tombstone-retention",
+				Usage:   "How long deleted resources are kept so that watches can be resumed from before they were deleted",
+				Value:   24 * time.Hour,
+				EnvVars: []string{"NEXAPI_TOMBSTONE_RETENTION"},
+			},
+			&cli.DurationFlag{
+				Name:    "relay-heartbeat-timeout",
+				Usage:   "How long after its last heartbeat a relay is reported down with the relay.down webhook event, 0 disables it",
+				Value:   2 * time.Minute,
+				EnvVars: []string{"NEXAPI_RELAY_HEARTBEAT_TIMEOUT"},
+			},
+			&cli.DurationFlag{
+				Name:    "ephemeral-device-timeout",
+				Usage:   "How long after its last heartbeat, or its registration, an ephemeral device is deleted, 0 disables it",
+				Value:   10 * time.Minute,
+				EnvVars: []string{"NEXAPI_EPHEMERAL_DEVICE_TIMEOUT"},
+			},
 		},
 
 		Action: func(cCtx *cli.Context) error {
@@ -240,21 +441,39 @@ func main() {
 					log.Fatal(err)
 				}
 
-				signalBus := signalbus.NewPgSignalBus(signalbus.NewSignalBus(), db, dsn, logger.Sugar())
+				redisClient := redis.NewClient(&redis.Options{
+					Addr: cCtx.String("redis-server"),
+					DB:   cCtx.Int("redis-db"),
+				})
+
 				wg := &sync.WaitGroup{}
-				signalBus.Start(ctx, wg)
+				var signalBus signalbus.SignalBus
+				switch cCtx.String("signal-bus") {
+				case "postgres":
+					pgSignalBus := signalbus.NewPgSignalBus(signalbus.NewSignalBus(), db, dsn, logger.Sugar())
+					pgSignalBus.Start(ctx, wg)
+					signalBus = pgSignalBus
+				case "redis":
+					redisSignalBus := signalbus.NewRedisSignalBus(signalbus.NewSignalBus(), redisClient, logger.Sugar())
+					redisSignalBus.Start(ctx, wg)
+					signalBus = redisSignalBus
+				default:
+					log.Fatalf("invalid --signal-bus: %s", cCtx.String("signal-bus"))
+				}
 
-				ipam := ipam.NewIPAM(logger.Sugar(), cCtx.String("ipam-address"))
+				ipam, err := ipam.NewIPAM(logger.Sugar(), cCtx.String("ipam-backend"), db, cCtx.String("ipam-address"))
+				if err != nil {
+					log.Fatal(err)
+				}
+				// the first start with the database backend allocates what the devices already use.
+				if err := cmd.RebuildIfEmpty(ctx, logger, db, ipam); err != nil {
+					log.Fatal(err)
+				}
 
 				fflags := fflags.NewFFlags(logger.Sugar())
 
 				store := inmem.New()
 
-				redisClient := redis.NewClient(&redis.Options{
-					Addr: cCtx.String("redis-server"),
-					DB:   cCtx.Int("redis-db"),
-				})
-
 				sessionStore := redisStore.NewRedisStore(&redisStore.Options{
 					Addr: cCtx.String("redis-server"),
 					DB:   cCtx.Int("redis-db"),
@@ -269,6 +488,25 @@ func main() {
 				if err != nil {
 					log.Fatal(err)
 				}
+				api.SetQuotas(handlers.Quotas{
+					DevicesPerOrganization: cCtx.Int("quota-devices-per-organization"),
+					OrganizationsPerUser:   cCtx.Int("quota-organizations-per-user"),
+					MetadataKeysPerDevice:  cCtx.Int("quota-metadata-keys-per-device"),
+				})
+				api.SetAdmins(cCtx.StringSlice("admin-users"))
+				api.StartWebhookDelivery(ctx, wg)
+				api.StartRelayMonitor(ctx, wg, cCtx.Duration("relay-heartbeat-timeout"))
+				api.StartEphemeralDeviceReaper(ctx, wg, cCtx.Duration("ephemeral-device-timeout"))
+				api.StartTombstoneCompaction(ctx, wg, cCtx.Duration("tombstone-retention"))
+				api.StartOrganizationRenumbering(ctx, wg)
+				api.StartIPAMReconciler(ctx, wg, cCtx.Duration("ipam-reconcile-interval"), cCtx.Bool("ipam-reconcile-repair"))
+				// the groups of the active users are seen at least as often as their cache expires.
+				if maxAge := cCtx.Duration("group-sync-max-age"); maxAge > 0 && maxAge <= handlers.GroupsCacheExp {
+					log.Fatalf("invalid --group-sync-max-age: it must be longer than %s", handlers.GroupsCacheExp)
+				}
+				api.StartGroupSync(ctx, wg, cCtx.Duration("group-sync-interval"), cCtx.Duration("group-sync-max-age"))
+				api.StartInvitationCleanup(ctx, wg)
+
 				scopes := []string{"openid", "profile", "email"}
 				scopes = append(scopes, cCtx.StringSlice("scopes")...)
 
@@ -291,42 +529,112 @@ func main() {
 					log.Fatal(err)
 				}
 
-				cliAuth, err := agent.NewOidcAgent(
-					ctx,
-					logger,
-					cCtx.String("oidc-url"),
-					cCtx.String("oidc-backchannel-url"),
-					cCtx.Bool("insecure-tls"),
-					cCtx.String("oidc-client-id-cli"),
-					"", // clientSecret
-					"", // redirectURL
-					scopes,
-					cCtx.String("domain"),
-					[]string{}, // origins
-					"",         // backend
-					"",         // cookieKey
-				)
+				identityProviders, err := newIdentityProviders(cCtx)
 				if err != nil {
 					log.Fatal(err)
 				}
+				scimProvider := identityProviders.Provider(cCtx.String("scim-identity-provider"))
+				if scimProvider == nil {
+					log.Fatalf("invalid --scim-identity-provider: unknown identity provider %s", cCtx.String("scim-identity-provider"))
+				}
+				api.SetSCIM(handlers.SCIMConfig{
+					Token:        cCtx.String("scim-token"),
+					UserIDPrefix: identityProviders.UserIDPrefix(scimProvider),
+				})
+				invitations := handlers.InvitationConfig{
+					Expiry:  cCtx.Duration("invitation-expiry"),
+					LinkURL: cCtx.String("invitation-link-url"),
+				}
+				if invitations.LinkURL == "" {
+					invitations.LinkURL = strings.TrimSuffix(cCtx.String("redirect-url"), "/") + "/#/invitations/accept"
+				}
+				if address := cCtx.String("smtp-address"); address != "" {
+					invitations.Sender, err = email.NewSMTPSender(email.SMTPOptions{
+						Address:     address,
+						From:        cCtx.String("smtp-from"),
+						Username:    cCtx.String("smtp-username"),
+						Password:    cCtx.String("smtp-password"),
+						InsecureTLS: cCtx.Bool("smtp-insecure-tls"),
+					})
+					if err != nil {
+						log.Fatal(err)
+					}
+				}
+				api.SetInvitations(invitations)
+				deviceFlows := agent.NewDeviceFlows()
+				for _, p := range identityProviders.Providers {
+					if p.Type == idp.TypeOAuth2 {
+						deviceFlows.Add(p.Name, agent.NewOAuth2Agent(
+							logger,
+							p.ClientID,
+							p.DeviceAuthorizationURL,
+							p.TokenURL,
+							p.Scopes,
+							routers.OAuth2TokenPrefix+p.Name+":",
+						))
+						continue
+					}
+					cliAuth, err := agent.NewOidcAgent(
+						ctx,
+						logger,
+						p.IssuerURL,
+						p.BackchannelURL,
+						cCtx.Bool("insecure-tls"),
+						p.ClientID,
+						"", // clientSecret
+						"", // redirectURL
+						p.Scopes,
+						cCtx.String("domain"),
+						[]string{}, // origins
+						"",         // backend
+						"",         // cookieKey
+					)
+					if err != nil {
+						log.Fatal(err)
+					}
+					deviceFlows.Add(p.Name, cliAuth)
+				}
 
-				router, err := routers.NewAPIRouter(ctx, routers.APIRouterOptions{
-					Logger:          logger.Sugar(),
-					Api:             api,
-					ClientIdWeb:     cCtx.String("oidc-client-id-web"),
-					ClientIdCli:     cCtx.String("oidc-client-id-cli"),
-					OidcURL:         cCtx.String("oidc-url"),
-					OidcBackchannel: cCtx.String("oidc-backchannel-url"),
-					InsecureTLS:     cCtx.Bool("insecure-tls"),
-					BrowserFlow:     webAuth,
-					DeviceFlow:      cliAuth,
-					Store:           store,
-					SessionStore:    sessionStore,
+				policy, err := routers.NewPolicy(ctx, logger.Sugar(), store, routers.PolicyOptions{
+					Bundle:                   cCtx.String("policy-bundle"),
+					VerificationKey:          cCtx.String("policy-bundle-verification-key"),
+					VerificationKeyAlgorithm: cCtx.String("policy-bundle-verification-key-alg"),
+					VerificationKeyID:        cCtx.String("policy-bundle-verification-key-id"),
+					PollInterval:             cCtx.Duration("policy-bundle-poll-interval"),
+					DecisionLog:              cCtx.Bool("policy-decision-log"),
 				})
 				if err != nil {
 					log.Fatal(err)
 				}
 
+				routerOptions := routers.APIRouterOptions{
+					Logger:            logger.Sugar(),
+					Api:               api,
+					ClientIdWeb:       cCtx.String("oidc-client-id-web"),
+					ClientIdCli:       cCtx.String("oidc-client-id-cli"),
+					InsecureTLS:       cCtx.Bool("insecure-tls"),
+					BrowserFlow:       webAuth,
+					DeviceFlows:       deviceFlows,
+					IdentityProviders: identityProviders,
+					Store:             store,
+					SessionStore:      sessionStore,
+					RateLimits: handlers.RateLimits{
+						UserRequestsPerMinute:         cCtx.Int("rate-limit-user"),
+						OrganizationRequestsPerMinute: cCtx.Int("rate-limit-organization"),
+					},
+					JWKSRefreshInterval: cCtx.Duration("jwks-refresh-interval"),
+					Policy:              policy,
+				}
+				// the http and the gRPC apis share the authenticator.
+				authenticator, err := routers.NewAuthenticator(ctx, routerOptions)
+				if err != nil {
+					log.Fatal(err)
+				}
+				router, err := routers.NewAPIRouter(ctx, routerOptions, authenticator)
+				if err != nil {
+					log.Fatal(err)
+				}
+
 				httpServer := &http.Server{
 					Addr:              cCtx.String("listen"),
 					Handler:           router,
@@ -352,6 +660,7 @@ func main() {
 				grpcServer := grpc.NewServer()
 				defer grpcServer.Stop()
 				auth.RegisterAuthorizationServer(grpcServer, api)
+				syncv1.RegisterDeviceSyncServer(grpcServer, handlers.NewDeviceSyncServer(api, authenticator.AuthenticateGrpc))
 				util.GoWithWaitGroup(wg, func() {
 					if err = grpcServer.Serve(grpcListener); err != nil {
 						serveErrors <- err
@@ -426,11 +735,14 @@ func main() {
 		Subcommands: []*cli.Command{
 			{
 				Name:  "rebuild",
-				Usage: "Rebuild the IPAM service using the allocated ips and cidrs in nexodus database",
+				Usage: "Rebuild the IPAM backend using the allocated ips and cidrs in nexodus database",
 				Action: func(cCtx *cli.Context) error {
 					ctx := cCtx.Context
 					withLoggerAndDB(ctx, cCtx, func(logger *zap.Logger, db *gorm.DB, dsn string) {
-						ipam := ipam.NewIPAM(logger.Sugar(), cCtx.String("ipam-address"))
+						ipam, err := ipam.NewIPAM(logger.Sugar(), cCtx.String("ipam-backend"), db, cCtx.String("ipam-address"))
+						if err != nil {
+							log.Fatal(err)
+						}
 						if err := cmd.Rebuild(ctx, logger, db, ipam); err != nil {
 							log.Fatal(err)
 						}
@@ -509,6 +821,33 @@ func main() {
 	}
 }
 
+// newIdentityProviders configures the provider of the --oidc-* flags, followed by the ones
+// of the --idp-config file.
+func newIdentityProviders(cCtx *cli.Context) (*idp.Config, error) {
+	config := &idp.Config{}
+	if path := cCtx.String("idp-config"); path != "" {
+		var err error
+		config, err = idp.LoadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	config.Providers = append([]idp.Provider{{
+		Name:                idp.DefaultProviderName,
+		Type:                idp.TypeOIDC,
+		IssuerURL:           cCtx.String("oidc-url"),
+		BackchannelURL:      cCtx.String("oidc-backchannel-url"),
+		Audience:            cCtx.String("oidc-audience"),
+		ClientID:            cCtx.String("oidc-client-id-cli"),
+		AllowedEmailDomains: cCtx.StringSlice("oidc-allowed-email-domains"),
+		EmailDomainClaim:    cCtx.String("oidc-email-domain-claim"),
+	}}, config.Providers...)
+	if err := config.Complete(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
 func getLogger(cCtx *cli.Context) *zap.Logger {
 	var logger *zap.Logger
 	var err error
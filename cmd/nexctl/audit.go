@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/urfave/cli/v2"
+)
+
+var organizationAuditSubcommands []*cli.Command
+
+func init() {
+	auditFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "organization-id",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "action",
+			Usage: "only show events with this action: create, update or delete",
+		},
+		&cli.StringFlag{
+			Name:  "resource-type",
+			Usage: "only show events for this resource type, for example device",
+		},
+		&cli.StringFlag{
+			Name:  "resource-id",
+			Usage: "only show events for this resource",
+		},
+		&cli.StringFlag{
+			Name:  "actor-id",
+			Usage: "only show events made by this user",
+		},
+		&cli.DurationFlag{
+			Name:  "since",
+			Usage: "only show events newer than this duration, for example 24h",
+		},
+	}
+	organizationAuditSubcommands = []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List the audit events of an organization",
+			Flags: auditFlags,
+			Action: func(c *cli.Context) error {
+				events, err := listAuditEvents(c)
+				if err != nil {
+					return err
+				}
+				showOutput(c, auditEventTableFields(), events)
+				return nil
+			},
+		},
+		{
+			Name:  "export",
+			Usage: "Export the audit events of an organization as JSON lines",
+			Flags: auditFlags,
+			Action: func(c *cli.Context) error {
+				events, err := listAuditEvents(c)
+				if err != nil {
+					return err
+				}
+				encoder := json.NewEncoder(os.Stdout)
+				for _, event := range events {
+					if err := encoder.Encode(event); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+func auditEventTableFields() []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "TIME", Field: "CreatedAt"})
+	fields = append(fields, TableField{Header: "ACTOR", Field: "ActorName"})
+	fields = append(fields, TableField{Header: "ACTION", Field: "Action"})
+	fields = append(fields, TableField{Header: "RESOURCE TYPE", Field: "ResourceType"})
+	fields = append(fields, TableField{Header: "RESOURCE ID", Field: "ResourceId"})
+	fields = append(fields, TableField{Header: "SOURCE IP", Field: "SourceIp"})
+	return fields
+}
+
+func listAuditEvents(c *cli.Context) ([]public.ModelsAuditEvent, error) {
+	orgId, err := uuid.Parse(c.String("organization-id"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid organization-id: %w", err)
+	}
+
+	client := mustCreateAPIClient(c)
+	request := client.AuditApi.ListAuditEvents(context.Background(), orgId.String())
+	if action := c.String("action"); action != "" {
+		request = request.Action(action)
+	}
+	if resourceType := c.String("resource-type"); resourceType != "" {
+		request = request.ResourceType(resourceType)
+	}
+	if resourceId := c.String("resource-id"); resourceId != "" {
+		request = request.ResourceId(resourceId)
+	}
+	if actorId := c.String("actor-id"); actorId != "" {
+		request = request.ActorId(actorId)
+	}
+	if since := c.Duration("since"); since > 0 {
+		request = request.Since(time.Now().Add(-since).UTC().Format(time.RFC3339))
+	}
+	events, _, err := request.Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return events, nil
+}
@@ -2,19 +2,43 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/urfave/cli/v2"
 	"log"
+	"sort"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/api/public"
 )
 
-func listOrgDevices(cCtx *cli.Context, c *public.APIClient, organizationID uuid.UUID) error {
-	devices, _, err := c.DevicesApi.ListDevicesInOrganization(context.Background(), organizationID.String()).Execute()
-	if err != nil {
-		log.Fatal(err)
+func listOrgDevices(cCtx *cli.Context, c *public.APIClient, organizationID uuid.UUID, selector string) error {
+	var devices []public.ModelsDevice
+	cursor := ""
+	for {
+		request := c.DevicesApi.ListDevicesInOrganization(context.Background(), organizationID.String())
+		if selector != "" {
+			request = request.Selector(selector)
+		}
+		if query := cCtx.String("query"); query != "" {
+			request = request.Q(query)
+		}
+		if pageSize := cCtx.Int("page-size"); pageSize > 0 {
+			request = request.Limit(int32(pageSize))
+		}
+		if cursor != "" {
+			request = request.Cursor(cursor)
+		}
+		page, resp, err := request.Execute()
+		if err != nil {
+			log.Fatal(err)
+		}
+		devices = append(devices, page...)
+		if cursor = public.NextCursor(resp); cursor == "" {
+			break
+		}
 	}
 	showOutput(cCtx, deviceTableFields(cCtx), devices)
 	return nil
@@ -76,13 +100,42 @@ func deviceTableFields(cCtx *cli.Context) []TableField {
 		fields = append(fields, TableField{Header: "ENDPOINT LOCAL IPv4", Field: "EndpointLocalAddressIp4"})
 		fields = append(fields, TableField{Header: "OS", Field: "Os"})
 		fields = append(fields, TableField{Header: "SECURITY GROUP ID", Field: "SecurityGroupId"})
+		fields = append(fields, TableField{Header: "LABELS",
+			Formatter: func(item interface{}) string {
+				dev := item.(public.ModelsDevice)
+				labels := make([]string, 0, len(dev.Labels))
+				for key, value := range dev.Labels {
+					labels = append(labels, key+"="+value)
+				}
+				sort.Strings(labels)
+				return strings.Join(labels, ",")
+			},
+		})
 	}
 	return fields
 }
 func listAllDevices(cCtx *cli.Context, c *public.APIClient) error {
-	devices, _, err := c.DevicesApi.ListDevices(context.Background()).Execute()
-	if err != nil {
-		log.Fatal(err)
+	var devices []public.ModelsDevice
+	cursor := ""
+	for {
+		request := c.DevicesApi.ListDevices(context.Background())
+		if query := cCtx.String("query"); query != "" {
+			request = request.Q(query)
+		}
+		if pageSize := cCtx.Int("page-size"); pageSize > 0 {
+			request = request.Limit(int32(pageSize))
+		}
+		if cursor != "" {
+			request = request.Cursor(cursor)
+		}
+		page, resp, err := request.Execute()
+		if err != nil {
+			log.Fatal(err)
+		}
+		devices = append(devices, page...)
+		if cursor = public.NextCursor(resp); cursor == "" {
+			break
+		}
 	}
 	showOutput(cCtx, deviceTableFields(cCtx), devices)
 	return nil
@@ -111,3 +164,61 @@ func deleteDevice(c *public.APIClient, encodeOut, devID string) error {
 
 	return nil
 }
+
+// deviceSelectorFromFlags returns the devices selected by the --selector, --hostname, --metadata and
+// --organization-id flags. The --selector is a label selector, the same as in device list.
+func deviceSelectorFromFlags(cCtx *cli.Context) (public.ModelsDeviceSelector, error) {
+	result := public.ModelsDeviceSelector{
+		Labels:   cCtx.String("selector"),
+		Hostname: cCtx.String("hostname"),
+	}
+	if orgID := cCtx.String("organization-id"); orgID != "" {
+		id, err := uuid.Parse(orgID)
+		if err != nil {
+			return result, fmt.Errorf("invalid organization id %q: %w", orgID, err)
+		}
+		result.OrganizationId = id.String()
+	}
+	if metadata := cCtx.String("metadata"); metadata != "" {
+		key, value, hasValue := strings.Cut(metadata, "=")
+		result.MetadataKey = key
+		if hasValue {
+			// the value is compared as json, a value that is not valid json is a string.
+			var v interface{}
+			if err := json.Unmarshal([]byte(value), &v); err != nil {
+				v = value
+			}
+			result.MetadataValue = v
+		}
+	}
+	if result.Labels == "" && result.Hostname == "" && result.OrganizationId == "" && result.MetadataKey == "" {
+		return result, fmt.Errorf("the selector does not select any devices")
+	}
+	return result, nil
+}
+
+func deleteDevicesBySelector(cCtx *cli.Context, c *public.APIClient, deviceSelector public.ModelsDeviceSelector) error {
+	res, _, err := c.DevicesApi.BatchDeleteDevices(context.Background()).Batch(public.ModelsDeviceBatchDelete{
+		Selector: deviceSelector,
+	}).Execute()
+	if err != nil {
+		var apiErr *public.GenericOpenAPIError
+		if errors.As(err, &apiErr) {
+			if batchResult, ok := apiErr.Model().(public.ModelsDeviceBatchResult); ok {
+				showOutput(cCtx, deviceBatchResultTableFields(), batchResult.Results)
+			}
+		}
+		log.Fatalf("device delete failed: %v\n", err)
+	}
+
+	showOutput(cCtx, deviceBatchResultTableFields(), res.Results)
+	return nil
+}
+
+func deviceBatchResultTableFields() []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "DEVICE ID", Field: "DeviceId"})
+	fields = append(fields, TableField{Header: "STATUS", Field: "Status"})
+	fields = append(fields, TableField{Header: "ERROR", Field: "Error"})
+	return fields
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"github.com/nexodus-io/nexodus/internal/api/public"
 	"log"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/client"
@@ -21,6 +24,25 @@ func acceptInvitation(c *client.APIClient, id string) error {
 	return nil
 }
 
+// acceptInvitationLink accepts an invitation with its link, or with the token of its link.
+func acceptInvitationLink(c *client.APIClient, link string) error {
+	token := link
+	// the token can be in the query of the fragment of the link, for the links to the SPA.
+	if i := strings.LastIndex(link, "?"); i >= 0 {
+		query, err := url.ParseQuery(link[i+1:])
+		if err != nil || query.Get("token") == "" {
+			log.Fatalf("failed to parse the token of the invitation link %s", link)
+		}
+		token = query.Get("token")
+	}
+	if _, err := c.InvitationApi.AcceptInvitationLink(context.Background()).AcceptInvitation(public.ModelsAcceptInvitation{
+		Token: token,
+	}).Execute(); err != nil {
+		log.Fatal(err)
+	}
+	return nil
+}
+
 func deleteInvitation(c *client.APIClient, id string) error {
 	invID, err := uuid.Parse(id)
 	if err != nil {
@@ -32,21 +54,29 @@ func deleteInvitation(c *client.APIClient, id string) error {
 	return nil
 }
 
-func createInvitation(c *client.APIClient, encodeOut, userID string, orgID string) error {
+func createInvitation(c *client.APIClient, encodeOut, userID string, email string, orgID string, role string, expiresIn time.Duration) error {
 	orgUUID, err := uuid.Parse(orgID)
 	if err != nil {
 		log.Fatalf("failed to parse a valid UUID from %s %v", orgID, err)
 	}
-	res, _, err := c.InvitationApi.CreateInvitation(context.Background()).Invitation(public.ModelsAddInvitation{
+	request := public.ModelsAddInvitation{
 		UserId:         userID,
+		Email:          email,
 		OrganizationId: orgUUID.String(),
-	}).Execute()
+		Role:           role,
+	}
+	if expiresIn > 0 {
+		request.ExpiresIn = expiresIn.String()
+	}
+	res, _, err := c.InvitationApi.CreateInvitation(context.Background()).Invitation(request).Execute()
 	if err != nil {
 		log.Fatalf("create invitation failed: %v\n", err)
 	}
 
 	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
 		fmt.Printf("successfully created invitation %s\n", res.Id)
+		// the link is only returned when the invitation is created.
+		fmt.Printf("invitation link: %s\n", res.Link)
 		return nil
 	}
 
@@ -66,6 +66,11 @@ func main() {
 				Name:  "password",
 				Usage: "Password",
 			},
+			&cli.StringFlag{
+				Name:    "token",
+				Usage:   "Service account api token, used instead of a username and password",
+				EnvVars: []string{"NEXCTL_TOKEN"},
+			},
 			&cli.StringFlag{
 				Name:     "output",
 				Value:    encodeColumn,
@@ -148,8 +153,54 @@ func main() {
 						Usage:       "Commands relating to device metadata across the organization",
 						Subcommands: organizationMetadataSubcommands,
 					},
+					{
+						Name:        "keys",
+						Usage:       "Commands relating to registration keys for the organization",
+						Subcommands: organizationKeysSubcommands,
+					},
+					{
+						Name:        "members",
+						Usage:       "Commands relating to organization members and their roles",
+						Subcommands: organizationMembersSubcommands,
+					},
+					{
+						Name:        "group-mappings",
+						Usage:       "Commands relating to the identity provider groups whose users are organization members",
+						Subcommands: organizationGroupMappingsSubcommands,
+					},
+					{
+						Name:        "audit",
+						Usage:       "Commands relating to the audit log of the organization",
+						Subcommands: organizationAuditSubcommands,
+					},
+					{
+						Name:        "webhooks",
+						Usage:       "Commands relating to webhooks for organization events",
+						Subcommands: organizationWebhooksSubcommands,
+					},
+					{
+						Name:        "peerings",
+						Usage:       "Commands relating to peerings with other organizations",
+						Subcommands: organizationPeeringsSubcommands,
+					},
+					{
+						Name:        "ipam",
+						Usage:       "Commands relating to the IPAM allocations of an organization",
+						Subcommands: organizationIpamSubcommands,
+					},
+					organizationResizeCommand,
+					{
+						Name:        "renumberings",
+						Usage:       "Commands relating to the renumbering of an organization after a resize",
+						Subcommands: organizationRenumberingsSubcommands,
+					},
 				},
 			},
+			{
+				Name:        "service-account",
+				Usage:       "Commands relating to service accounts",
+				Subcommands: serviceAccountSubcommands,
+			},
 			{
 				Name:  "device",
 				Usage: "Commands relating to devices",
@@ -163,6 +214,18 @@ func main() {
 								Value:    "",
 								Required: false,
 							},
+							&cli.StringFlag{
+								Name:  "selector",
+								Usage: "only list the devices with labels that match the selector, e.g. env=prod,tier!=db (requires --organization-id)",
+							},
+							&cli.StringFlag{
+								Name:  "query",
+								Usage: "only list the devices that match the query, e.g. hostname^=lab-,os in (linux,darwin),created_at>=2023-06-01",
+							},
+							&cli.IntFlag{
+								Name:  "page-size",
+								Usage: "fetch the devices in pages of this size",
+							},
 							&cli.BoolFlag{
 								Name:    "full",
 								Aliases: []string{"f"},
@@ -172,28 +235,58 @@ func main() {
 						},
 						Action: func(cCtx *cli.Context) error {
 							orgID := cCtx.String("organization-id")
+							selector := cCtx.String("selector")
 							if orgID != "" {
 								id, err := uuid.Parse(orgID)
 								if err != nil {
 									log.Fatal(err)
 								}
-								return listOrgDevices(cCtx, mustCreateAPIClient(cCtx), id)
+								return listOrgDevices(cCtx, mustCreateAPIClient(cCtx), id, selector)
+							}
+							if selector != "" {
+								return fmt.Errorf("--selector requires --organization-id")
 							}
 							return listAllDevices(cCtx, mustCreateAPIClient(cCtx))
 						},
 					},
 					{
 						Name:  "delete",
-						Usage: "Delete a device",
+						Usage: "Delete a device, or all the devices matched by a selector",
 						Flags: []cli.Flag{
 							&cli.StringFlag{
-								Name:     "device-id",
-								Required: true,
+								Name: "device-id",
+							},
+							&cli.StringFlag{
+								Name:  "selector",
+								Usage: "delete the devices with labels that match the selector, e.g. env=lab,tier!=db",
+							},
+							&cli.StringFlag{
+								Name:  "hostname",
+								Usage: "delete the devices with a matching hostname, * matches any characters and ? a single one, e.g. lab-*",
+							},
+							&cli.StringFlag{
+								Name:  "metadata",
+								Usage: "delete the devices that have the metadata key, or the key with the value, e.g. lab=teardown",
+							},
+							&cli.StringFlag{
+								Name:  "organization-id",
+								Usage: "delete the devices of the organization",
 							},
 						},
 						Action: func(cCtx *cli.Context) error {
 							encodeOut := cCtx.String("output")
 							devID := cCtx.String("device-id")
+							selecting := cCtx.IsSet("selector") || cCtx.IsSet("hostname") || cCtx.IsSet("metadata") || cCtx.IsSet("organization-id")
+							if (devID == "") == !selecting {
+								return fmt.Errorf("exactly one of --device-id or a --selector, --hostname, --metadata or --organization-id is required")
+							}
+							if selecting {
+								deviceSelector, err := deviceSelectorFromFlags(cCtx)
+								if err != nil {
+									return err
+								}
+								return deleteDevicesBySelector(cCtx, mustCreateAPIClient(cCtx), deviceSelector)
+							}
 							return deleteDevice(mustCreateAPIClient(cCtx), encodeOut, devID)
 						},
 					},
@@ -418,19 +511,36 @@ func main() {
 						Usage: "create an invitation",
 						Flags: []cli.Flag{
 							&cli.StringFlag{
-								Name:     "user-id",
-								Required: true,
+								Name:  "user-id",
+								Usage: "the user to invite",
+							},
+							&cli.StringFlag{
+								Name:  "email",
+								Usage: "the email address to send the invitation to, for users that never logged in",
 							},
 							&cli.StringFlag{
 								Name:     "organization-id",
 								Required: true,
 							},
+							&cli.StringFlag{
+								Name:  "role",
+								Usage: "the role of the user in the organization: admin, member or read-only",
+								Value: "member",
+							},
+							&cli.DurationFlag{
+								Name:  "expires-in",
+								Usage: "how long the invitation is valid, the default of the service when it's not set",
+							},
 						},
 						Action: func(cCtx *cli.Context) error {
 							encodeOut := cCtx.String("output")
 							userID := cCtx.String("user-id")
+							email := cCtx.String("email")
+							if (userID == "") == (email == "") {
+								return fmt.Errorf("exactly one of --user-id or --email is required")
+							}
 							orgID := cCtx.String("organization-id")
-							return createInvitation(mustCreateAPIClient(cCtx), encodeOut, userID, orgID)
+							return createInvitation(mustCreateAPIClient(cCtx), encodeOut, userID, email, orgID, cCtx.String("role"), cCtx.Duration("expires-in"))
 						},
 					},
 					{
@@ -452,13 +562,24 @@ func main() {
 						Usage: "accept an invitation",
 						Flags: []cli.Flag{
 							&cli.StringFlag{
-								Name:     "inv-id",
-								Required: true,
+								Name:  "inv-id",
+								Usage: "the invitation to accept",
+							},
+							&cli.StringFlag{
+								Name:  "link",
+								Usage: "the link of the invitation to accept",
 							},
 						},
 						Action: func(cCtx *cli.Context) error {
-							userID := cCtx.String("inv-id")
-							return acceptInvitation(mustCreateAPIClient(cCtx), userID)
+							invID := cCtx.String("inv-id")
+							link := cCtx.String("link")
+							if (invID == "") == (link == "") {
+								return fmt.Errorf("exactly one of --inv-id or --link is required")
+							}
+							if link != "" {
+								return acceptInvitationLink(mustCreateAPIClient(cCtx), link)
+							}
+							return acceptInvitation(mustCreateAPIClient(cCtx), invID)
 						},
 					},
 				},
@@ -521,6 +642,9 @@ func createClientOptions(cCtx *cli.Context) []client.Option {
 		cCtx.String("username"),
 		cCtx.String("password"),
 	)}
+	if cCtx.IsSet("token") {
+		options = append(options, client.WithBearerToken(cCtx.String("token")))
+	}
 	if cCtx.Bool("insecure-skip-tls-verify") { // #nosec G402
 		options = append(options, client.WithTLSConfig(&tls.Config{
 			InsecureSkipVerify: true,
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/urfave/cli/v2"
+)
+
+var organizationGroupMappingsSubcommands []*cli.Command
+
+func init() {
+	organizationGroupMappingsSubcommands = []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List the identity provider groups whose users are members of an organization",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "organization-id",
+					Required: true,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return listGroupMappings(c, orgId)
+			},
+		},
+		{
+			Name:  "create",
+			Usage: "Make the users of an identity provider group members of an organization",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "organization-id",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "group",
+					Usage:    "the group in the groups claim of the users",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "role",
+					Usage: "the role of the members of the group, one of admin, member or read-only",
+					Value: "member",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return createGroupMapping(c, orgId, public.ModelsAddOrganizationGroupMapping{
+					Group: c.String("group"),
+					Role:  c.String("role"),
+				})
+			},
+		},
+		{
+			Name:  "delete",
+			Usage: "Delete a group mapping, the users that are members only through the group are removed from the organization",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "organization-id",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "mapping-id",
+					Required: true,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				mappingId, err := uuid.Parse(c.String("mapping-id"))
+				if err != nil {
+					return fmt.Errorf("invalid mapping-id: %w", err)
+				}
+				return deleteGroupMapping(c, orgId, mappingId)
+			},
+		},
+	}
+}
+
+func groupMappingTableFields() []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "MAPPING ID", Field: "Id"})
+	fields = append(fields, TableField{Header: "GROUP", Field: "Group"})
+	fields = append(fields, TableField{Header: "ROLE", Field: "Role"})
+	return fields
+}
+
+func listGroupMappings(c *cli.Context, orgId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.ListGroupMappings(context.Background(), orgId.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, groupMappingTableFields(), res)
+	return nil
+}
+
+func createGroupMapping(c *cli.Context, orgId uuid.UUID, request public.ModelsAddOrganizationGroupMapping) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.CreateGroupMapping(context.Background(), orgId.String()).GroupMapping(request).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, groupMappingTableFields(), res)
+	return nil
+}
+
+func deleteGroupMapping(c *cli.Context, orgId, mappingId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.DeleteGroupMapping(context.Background(), orgId.String(), mappingId.String()).Execute()
+	if err != nil {
+		log.Fatalf("Group mapping delete failed: %v\n", err)
+	}
+
+	showOutput(c, groupMappingTableFields(), res)
+	encodeOut := c.String("output")
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Println("\nsuccessfully deleted")
+	}
+	return nil
+}
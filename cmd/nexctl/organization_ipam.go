@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/urfave/cli/v2"
+)
+
+var organizationIpamSubcommands []*cli.Command
+
+func init() {
+	organizationIpamSubcommands = []*cli.Command{
+		{
+			Name:  "drift",
+			Usage: "List the IPAM allocations of an organization that don't match its devices, or of all the organizations for the admins of the apiserver",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "organization-id",
+					Usage: "The organization, all the organizations when it's not set",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.String("organization-id") == "" {
+					return listIpamDrift(c, uuid.Nil)
+				}
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return listIpamDrift(c, orgId)
+			},
+		},
+		{
+			Name:  "reservations",
+			Usage: "Commands relating to the tunnel IPs reserved for devices",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "list",
+					Usage: "List the tunnel IPs reserved in an organization",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "organization-id",
+							Required: true,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						orgId, err := uuid.Parse(c.String("organization-id"))
+						if err != nil {
+							return fmt.Errorf("invalid organization-id: %w", err)
+						}
+						return listIpReservations(c, orgId)
+					},
+				},
+				{
+					Name:  "create",
+					Usage: "Reserve tunnel IPs for the device with a hostname, public key or registration key",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "organization-id",
+							Required: true,
+						},
+						&cli.StringFlag{
+							Name:  "hostname",
+							Usage: "reserve the IPs for the device with the hostname",
+						},
+						&cli.StringFlag{
+							Name:  "public-key",
+							Usage: "reserve the IPs for the device with the public key",
+						},
+						&cli.StringFlag{
+							Name:  "key-id",
+							Usage: "reserve the IPs for the device that registers with the registration key",
+						},
+						&cli.StringFlag{
+							Name:  "ip",
+							Usage: "the IPv4 tunnel IP to reserve",
+						},
+						&cli.StringFlag{
+							Name:  "ip-v6",
+							Usage: "the IPv6 tunnel IP to reserve",
+						},
+						&cli.StringFlag{
+							Name: "description",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						orgId, err := uuid.Parse(c.String("organization-id"))
+						if err != nil {
+							return fmt.Errorf("invalid organization-id: %w", err)
+						}
+						request := public.ModelsAddIpReservation{
+							Hostname:    c.String("hostname"),
+							PublicKey:   c.String("public-key"),
+							TunnelIp:    c.String("ip"),
+							TunnelIpV6:  c.String("ip-v6"),
+							Description: c.String("description"),
+						}
+						if c.String("key-id") != "" {
+							keyId, err := uuid.Parse(c.String("key-id"))
+							if err != nil {
+								return fmt.Errorf("invalid key-id: %w", err)
+							}
+							request.RegistrationKeyId = keyId.String()
+						}
+						return createIpReservation(c, orgId, request)
+					},
+				},
+				{
+					Name:  "delete",
+					Usage: "Delete a reservation, the IPs are released unless a device uses them",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "organization-id",
+							Required: true,
+						},
+						&cli.StringFlag{
+							Name:     "reservation-id",
+							Required: true,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						orgId, err := uuid.Parse(c.String("organization-id"))
+						if err != nil {
+							return fmt.Errorf("invalid organization-id: %w", err)
+						}
+						reservationId, err := uuid.Parse(c.String("reservation-id"))
+						if err != nil {
+							return fmt.Errorf("invalid reservation-id: %w", err)
+						}
+						return deleteIpReservation(c, orgId, reservationId)
+					},
+				},
+			},
+		},
+	}
+}
+
+func ipamDriftTableFields() []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "KIND", Field: "Kind"})
+	fields = append(fields, TableField{Header: "ORGANIZATION ID", Field: "OrganizationId"})
+	fields = append(fields, TableField{Header: "PREFIX", Field: "Prefix"})
+	fields = append(fields, TableField{Header: "ADDRESS", Field: "Address"})
+	fields = append(fields, TableField{Header: "DEVICE IDS", Formatter: func(item interface{}) string {
+		return strings.Join(item.(public.ModelsIpamDrift).DeviceIds, ",")
+	}})
+	fields = append(fields, TableField{Header: "ADDRESSES", Formatter: func(item interface{}) string {
+		drift := item.(public.ModelsIpamDrift)
+		if drift.Kind != "addresses" {
+			return ""
+		}
+		return fmt.Sprintf("%d allocated, %d used", drift.AllocatedAddresses, drift.UsedAddresses)
+	}})
+	fields = append(fields, TableField{Header: "REPAIRED", Field: "Repaired"})
+	return fields
+}
+
+// listIpamDrift lists the ipam drift of the organization, or of all the organizations when orgId is nil.
+func listIpamDrift(c *cli.Context, orgId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	var res *public.ModelsIpamDriftReport
+	var err error
+	if orgId == uuid.Nil {
+		res, _, err = client.OrganizationsApi.ListIpamDrift(context.Background()).Execute()
+	} else {
+		res, _, err = client.OrganizationsApi.GetIpamDrift(context.Background(), orgId.String()).Execute()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	encodeOut := c.String("output")
+	if encodeOut != encodeColumn && encodeOut != encodeNoHeader {
+		showOutput(c, nil, res)
+		return nil
+	}
+	if res.CheckedAt == "" {
+		fmt.Println("the IPAM has not been reconciled yet")
+		return nil
+	}
+	showOutput(c, ipamDriftTableFields(), res.Drift)
+	return nil
+}
+
+func ipReservationTableFields() []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "RESERVATION ID", Field: "Id"})
+	fields = append(fields, TableField{Header: "IDENTITY", Formatter: func(item interface{}) string {
+		var reservation public.ModelsIpReservation
+		switch item := item.(type) {
+		case public.ModelsIpReservation:
+			reservation = item
+		case *public.ModelsIpReservation:
+			reservation = *item
+		}
+		switch {
+		case reservation.PublicKey != "":
+			return "public-key=" + reservation.PublicKey
+		case reservation.Hostname != "":
+			return "hostname=" + reservation.Hostname
+		}
+		return "key-id=" + reservation.RegistrationKeyId
+	}})
+	fields = append(fields, TableField{Header: "TUNNEL IP", Field: "TunnelIp"})
+	fields = append(fields, TableField{Header: "TUNNEL IPV6", Field: "TunnelIpV6"})
+	fields = append(fields, TableField{Header: "DESCRIPTION", Field: "Description"})
+	return fields
+}
+
+func listIpReservations(c *cli.Context, orgId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.ListIpReservations(context.Background(), orgId.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, ipReservationTableFields(), res)
+	return nil
+}
+
+func createIpReservation(c *cli.Context, orgId uuid.UUID, request public.ModelsAddIpReservation) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.CreateIpReservation(context.Background(), orgId.String()).Reservation(request).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, ipReservationTableFields(), res)
+	return nil
+}
+
+func deleteIpReservation(c *cli.Context, orgId, reservationId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.DeleteIpReservation(context.Background(), orgId.String(), reservationId.String()).Execute()
+	if err != nil {
+		log.Fatalf("Reservation delete failed: %v\n", err)
+	}
+
+	showOutput(c, ipReservationTableFields(), res)
+	encodeOut := c.String("output")
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Println("\nsuccessfully deleted")
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/urfave/cli/v2"
+)
+
+var organizationMembersSubcommands []*cli.Command
+
+func init() {
+	organizationMembersSubcommands = []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List the members of an organization and their roles",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "organization-id",
+					Required: true,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return listOrganizationMembers(c, orgId)
+			},
+		},
+		{
+			Name:  "set-role",
+			Usage: "Change the role of an organization member",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "organization-id",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "user-id",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "role",
+					Usage:    "one of admin, member or read-only",
+					Required: true,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return setOrganizationMemberRole(c, orgId, c.String("user-id"), c.String("role"))
+			},
+		},
+	}
+}
+
+func organizationMemberTableFields() []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "USER ID", Field: "UserId"})
+	fields = append(fields, TableField{Header: "USER NAME", Field: "Username"})
+	fields = append(fields, TableField{Header: "ROLE", Field: "Role"})
+	fields = append(fields, TableField{Header: "GROUP MANAGED", Field: "GroupManaged"})
+	return fields
+}
+
+func listOrganizationMembers(c *cli.Context, orgId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	members, _, err := client.OrganizationsApi.ListOrganizationMembers(context.Background(), orgId.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, organizationMemberTableFields(), members)
+	return nil
+}
+
+func setOrganizationMemberRole(c *cli.Context, orgId uuid.UUID, userId, role string) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.UpdateOrganizationMember(context.Background(), orgId.String(), userId).Update(public.ModelsUpdateOrganizationMember{
+		Role: role,
+	}).Execute()
+	if err != nil {
+		log.Fatalf("Organization member update failed: %v\n", err)
+	}
+
+	showOutput(c, organizationMemberTableFields(), res)
+	return nil
+}
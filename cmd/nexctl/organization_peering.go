@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/urfave/cli/v2"
+)
+
+var organizationPeeringsSubcommands []*cli.Command
+
+func init() {
+	orgFlag := &cli.StringFlag{
+		Name:     "organization-id",
+		Required: true,
+	}
+	peeringFlag := &cli.StringFlag{
+		Name:     "peering-id",
+		Required: true,
+	}
+	selectorFlag := &cli.StringFlag{
+		Name:  "selector",
+		Usage: "the devices of the organization that the other organization can reach, e.g. role=build-cache",
+	}
+	prefixFlag := &cli.StringSliceFlag{
+		Name:  "prefix",
+		Usage: "a child prefix of the devices of the organization that the other organization can reach, e.g. 172.16.10.0/24, a --selector or --prefix is required",
+	}
+	organizationPeeringsSubcommands = []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List the peerings of an organization",
+			Flags: []cli.Flag{orgFlag},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return listOrganizationPeerings(c, orgId)
+			},
+		},
+		{
+			Name:  "create",
+			Usage: "Request a peering with another organization",
+			Flags: []cli.Flag{
+				orgFlag,
+				&cli.StringFlag{
+					Name:     "peer-organization-id",
+					Required: true,
+				},
+				selectorFlag,
+				prefixFlag,
+			},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				peerOrgId, err := uuid.Parse(c.String("peer-organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid peer-organization-id: %w", err)
+				}
+				return createOrganizationPeering(c, orgId, public.ModelsAddOrganizationPeering{
+					PeerOrganizationId: peerOrgId.String(),
+					Selector:           c.String("selector"),
+					Prefixes:           c.StringSlice("prefix"),
+				})
+			},
+		},
+		{
+			Name:  "accept",
+			Usage: "Accept a peering that another organization requested",
+			Flags: []cli.Flag{orgFlag, peeringFlag, selectorFlag, prefixFlag},
+			Action: func(c *cli.Context) error {
+				orgId, peeringId, err := parsePeeringFlags(c)
+				if err != nil {
+					return err
+				}
+				return acceptOrganizationPeering(c, orgId, peeringId, public.ModelsAcceptOrganizationPeering{
+					Selector: c.String("selector"),
+					Prefixes: c.StringSlice("prefix"),
+				})
+			},
+		},
+		{
+			Name:  "delete",
+			Usage: "Delete a peering",
+			Flags: []cli.Flag{orgFlag, peeringFlag},
+			Action: func(c *cli.Context) error {
+				orgId, peeringId, err := parsePeeringFlags(c)
+				if err != nil {
+					return err
+				}
+				return deleteOrganizationPeering(c, orgId, peeringId)
+			},
+		},
+	}
+}
+
+func parsePeeringFlags(c *cli.Context) (uuid.UUID, uuid.UUID, error) {
+	orgId, err := uuid.Parse(c.String("organization-id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid organization-id: %w", err)
+	}
+	peeringId, err := uuid.Parse(c.String("peering-id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid peering-id: %w", err)
+	}
+	return orgId, peeringId, nil
+}
+
+func organizationPeeringTableFields() []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "PEERING ID", Field: "Id"})
+	fields = append(fields, TableField{Header: "ORGANIZATION ID", Field: "OrganizationId"})
+	fields = append(fields, TableField{Header: "SELECTOR", Field: "Selector"})
+	fields = append(fields, TableField{Header: "PREFIXES", Field: "Prefixes"})
+	fields = append(fields, TableField{Header: "PEER ORGANIZATION ID", Field: "PeerOrganizationId"})
+	fields = append(fields, TableField{Header: "PEER SELECTOR", Field: "PeerSelector"})
+	fields = append(fields, TableField{Header: "PEER PREFIXES", Field: "PeerPrefixes"})
+	fields = append(fields, TableField{Header: "STATUS", Field: "Status"})
+	return fields
+}
+
+func listOrganizationPeerings(c *cli.Context, orgId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.ListOrganizationPeerings(context.Background(), orgId.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, organizationPeeringTableFields(), res)
+	return nil
+}
+
+func createOrganizationPeering(c *cli.Context, orgId uuid.UUID, request public.ModelsAddOrganizationPeering) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.CreateOrganizationPeering(context.Background(), orgId.String()).Peering(request).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, organizationPeeringTableFields(), res)
+	return nil
+}
+
+func acceptOrganizationPeering(c *cli.Context, orgId, peeringId uuid.UUID, request public.ModelsAcceptOrganizationPeering) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.AcceptOrganizationPeering(context.Background(), orgId.String(), peeringId.String()).Peering(request).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, organizationPeeringTableFields(), res)
+	return nil
+}
+
+func deleteOrganizationPeering(c *cli.Context, orgId, peeringId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.DeleteOrganizationPeering(context.Background(), orgId.String(), peeringId.String()).Execute()
+	if err != nil {
+		log.Fatalf("Peering delete failed: %v\n", err)
+	}
+
+	showOutput(c, organizationPeeringTableFields(), res)
+	encodeOut := c.String("output")
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Println("\nsuccessfully deleted")
+	}
+	return nil
+}
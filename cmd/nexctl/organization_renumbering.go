@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/urfave/cli/v2"
+)
+
+var organizationResizeCommand *cli.Command
+var organizationRenumberingsSubcommands []*cli.Command
+
+func init() {
+	orgFlag := &cli.StringFlag{
+		Name:     "organization-id",
+		Required: true,
+	}
+	organizationResizeCommand = &cli.Command{
+		Name:  "resize",
+		Usage: "Grow the CIDRs of an organization or move it to new ones",
+		Flags: []cli.Flag{
+			orgFlag,
+			&cli.StringFlag{
+				Name:  "cidr",
+				Usage: "the new IPv4 CIDR, a CIDR that contains the current one keeps the addresses of the devices",
+			},
+			&cli.StringFlag{
+				Name:  "cidr-v6",
+				Usage: "the new IPv6 CIDR, a CIDR that contains the current one keeps the addresses of the devices",
+			},
+			&cli.IntFlag{
+				Name:  "batch-size",
+				Usage: "the number of devices that are given new addresses at a time, all of them when 0",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			orgId, err := uuid.Parse(c.String("organization-id"))
+			if err != nil {
+				return fmt.Errorf("invalid organization-id: %w", err)
+			}
+			if c.String("cidr") == "" && c.String("cidr-v6") == "" {
+				return fmt.Errorf("--cidr or --cidr-v6 is required")
+			}
+			return resizeOrganization(c, orgId, public.ModelsResizeOrganization{
+				Cidr:      c.String("cidr"),
+				CidrV6:    c.String("cidr-v6"),
+				BatchSize: int32(c.Int("batch-size")),
+			})
+		},
+	}
+	organizationRenumberingsSubcommands = []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List the resizes of an organization and the progress of their renumbering",
+			Flags: []cli.Flag{orgFlag},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return listOrganizationRenumberings(c, orgId)
+			},
+		},
+	}
+}
+
+func organizationRenumberingTableFields() []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "RENUMBERING ID", Field: "Id"})
+	fields = append(fields, TableField{Header: "PREVIOUS CIDR", Field: "PreviousCidr"})
+	fields = append(fields, TableField{Header: "CIDR", Field: "Cidr"})
+	fields = append(fields, TableField{Header: "PREVIOUS CIDR V6", Field: "PreviousCidrV6"})
+	fields = append(fields, TableField{Header: "CIDR V6", Field: "CidrV6"})
+	fields = append(fields, TableField{Header: "DEVICES REMAINING", Field: "DevicesRemaining"})
+	fields = append(fields, TableField{Header: "STATUS", Field: "Status"})
+	return fields
+}
+
+func resizeOrganization(c *cli.Context, orgId uuid.UUID, request public.ModelsResizeOrganization) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.ResizeOrganization(context.Background(), orgId.String()).Resize(request).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, organizationRenumberingTableFields(), res)
+	return nil
+}
+
+func listOrganizationRenumberings(c *cli.Context, orgId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.OrganizationsApi.ListOrganizationRenumberings(context.Background(), orgId.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, organizationRenumberingTableFields(), res)
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/urfave/cli/v2"
+)
+
+var organizationKeysSubcommands []*cli.Command
+
+func init() {
+	organizationKeysSubcommands = []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List registration keys",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "organization-id",
+					Required: true,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return listRegKeys(c, orgId)
+			},
+		},
+		{
+			Name:  "create",
+			Usage: "Create a registration key",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "organization-id",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "description",
+					Required: false,
+				},
+				&cli.BoolFlag{
+					Name:  "single-use",
+					Usage: "the key can only be used to register one device",
+					Value: false,
+				},
+				&cli.BoolFlag{
+					Name:  "ephemeral",
+					Usage: "devices registered with the key are removed when nexd stops, or stops sending heartbeats",
+					Value: false,
+				},
+				&cli.StringSliceFlag{
+					Name:  "tag",
+					Usage: "tag to apply to devices registered with the key",
+				},
+				&cli.DurationFlag{
+					Name:  "expiration",
+					Usage: "how long the key remains valid, defaults to no expiration",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				request := public.ModelsAddRegKey{
+					Description: c.String("description"),
+					SingleUse:   c.Bool("single-use"),
+					Ephemeral:   c.Bool("ephemeral"),
+					Tags:        c.StringSlice("tag"),
+				}
+				if expiration := c.Duration("expiration"); expiration > 0 {
+					request.ExpiresAt = time.Now().Add(expiration).UTC().Format(time.RFC3339)
+				}
+				return createRegKey(c, orgId, request)
+			},
+		},
+		{
+			Name:  "revoke",
+			Usage: "Revoke a registration key",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "organization-id",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "key-id",
+					Required: true,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				keyId, err := uuid.Parse(c.String("key-id"))
+				if err != nil {
+					return fmt.Errorf("invalid key-id: %w", err)
+				}
+				return revokeRegKey(c, orgId, keyId)
+			},
+		},
+	}
+}
+
+func regKeyTableFields(withToken bool) []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "KEY ID", Field: "Id"})
+	fields = append(fields, TableField{Header: "DESCRIPTION", Field: "Description"})
+	fields = append(fields, TableField{Header: "SINGLE USE", Field: "SingleUse"})
+	fields = append(fields, TableField{Header: "EPHEMERAL", Field: "Ephemeral"})
+	fields = append(fields, TableField{Header: "TAGS", Field: "Tags"})
+	fields = append(fields, TableField{Header: "EXPIRES AT", Field: "ExpiresAt"})
+	if withToken {
+		fields = append(fields, TableField{Header: "TOKEN", Field: "BearerToken"})
+	}
+	return fields
+}
+
+func listRegKeys(c *cli.Context, orgId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	keys, _, err := client.RegKeyApi.ListRegKeys(context.Background(), orgId.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, regKeyTableFields(false), keys)
+	return nil
+}
+
+func createRegKey(c *cli.Context, orgId uuid.UUID, request public.ModelsAddRegKey) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.RegKeyApi.CreateRegKey(context.Background(), orgId.String()).RegKey(request).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, regKeyTableFields(true), res)
+	return nil
+}
+
+func revokeRegKey(c *cli.Context, orgId uuid.UUID, keyId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.RegKeyApi.DeleteRegKey(context.Background(), orgId.String(), keyId.String()).Execute()
+	if err != nil {
+		log.Fatalf("Registration key revoke failed: %v\n", err)
+	}
+
+	showOutput(c, regKeyTableFields(false), res)
+	encodeOut := c.String("output")
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Println("\nsuccessfully revoked")
+	}
+	return nil
+}
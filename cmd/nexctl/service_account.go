@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/urfave/cli/v2"
+)
+
+var serviceAccountSubcommands []*cli.Command
+
+func init() {
+	orgFlag := &cli.StringFlag{
+		Name:     "organization-id",
+		Required: true,
+	}
+	serviceAccountFlag := &cli.StringFlag{
+		Name:     "service-account-id",
+		Required: true,
+	}
+	serviceAccountSubcommands = []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List the service accounts of an organization",
+			Flags: []cli.Flag{orgFlag},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return listServiceAccounts(c, orgId)
+			},
+		},
+		{
+			Name:  "create",
+			Usage: "Create a service account",
+			Flags: []cli.Flag{
+				orgFlag,
+				&cli.StringFlag{
+					Name:     "name",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "description",
+					Required: false,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return createServiceAccount(c, orgId, c.String("name"), c.String("description"))
+			},
+		},
+		{
+			Name:  "delete",
+			Usage: "Delete a service account and revoke its tokens",
+			Flags: []cli.Flag{orgFlag, serviceAccountFlag},
+			Action: func(c *cli.Context) error {
+				orgId, saId, err := parseServiceAccountFlags(c)
+				if err != nil {
+					return err
+				}
+				return deleteServiceAccount(c, orgId, saId)
+			},
+		},
+		{
+			Name:  "token",
+			Usage: "Commands relating to service account api tokens",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "list",
+					Usage: "List the api tokens of a service account",
+					Flags: []cli.Flag{orgFlag, serviceAccountFlag},
+					Action: func(c *cli.Context) error {
+						orgId, saId, err := parseServiceAccountFlags(c)
+						if err != nil {
+							return err
+						}
+						return listApiTokens(c, orgId, saId)
+					},
+				},
+				{
+					Name:  "create",
+					Usage: "Issue an api token for a service account",
+					Flags: []cli.Flag{
+						orgFlag,
+						serviceAccountFlag,
+						&cli.StringFlag{
+							Name:     "description",
+							Required: false,
+						},
+						&cli.StringSliceFlag{
+							Name:  "scope",
+							Usage: "scope granted to the token, defaults to all scopes",
+						},
+						&cli.DurationFlag{
+							Name:  "expiration",
+							Usage: "how long the token remains valid, defaults to no expiration",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						orgId, saId, err := parseServiceAccountFlags(c)
+						if err != nil {
+							return err
+						}
+						request := public.ModelsAddApiToken{
+							Description: c.String("description"),
+							Scopes:      c.StringSlice("scope"),
+						}
+						if expiration := c.Duration("expiration"); expiration > 0 {
+							request.ExpiresAt = time.Now().Add(expiration).UTC().Format(time.RFC3339)
+						}
+						return createApiToken(c, orgId, saId, request)
+					},
+				},
+				{
+					Name:  "revoke",
+					Usage: "Revoke an api token",
+					Flags: []cli.Flag{
+						orgFlag,
+						serviceAccountFlag,
+						&cli.StringFlag{
+							Name:     "token-id",
+							Required: true,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						orgId, saId, err := parseServiceAccountFlags(c)
+						if err != nil {
+							return err
+						}
+						tokenId, err := uuid.Parse(c.String("token-id"))
+						if err != nil {
+							return fmt.Errorf("invalid token-id: %w", err)
+						}
+						return revokeApiToken(c, orgId, saId, tokenId)
+					},
+				},
+			},
+		},
+	}
+}
+
+func parseServiceAccountFlags(c *cli.Context) (uuid.UUID, uuid.UUID, error) {
+	orgId, err := uuid.Parse(c.String("organization-id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid organization-id: %w", err)
+	}
+	saId, err := uuid.Parse(c.String("service-account-id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid service-account-id: %w", err)
+	}
+	return orgId, saId, nil
+}
+
+func serviceAccountTableFields() []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "SERVICE ACCOUNT ID", Field: "Id"})
+	fields = append(fields, TableField{Header: "NAME", Field: "Name"})
+	fields = append(fields, TableField{Header: "DESCRIPTION", Field: "Description"})
+	fields = append(fields, TableField{Header: "ORGANIZATION ID", Field: "OrganizationId"})
+	return fields
+}
+
+func apiTokenTableFields(withToken bool) []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "TOKEN ID", Field: "Id"})
+	fields = append(fields, TableField{Header: "DESCRIPTION", Field: "Description"})
+	fields = append(fields, TableField{Header: "SCOPES", Field: "Scopes"})
+	fields = append(fields, TableField{Header: "EXPIRES AT", Field: "ExpiresAt"})
+	fields = append(fields, TableField{Header: "LAST USED AT", Field: "LastUsedAt"})
+	if withToken {
+		fields = append(fields, TableField{Header: "TOKEN", Field: "BearerToken"})
+	}
+	return fields
+}
+
+func listServiceAccounts(c *cli.Context, orgId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.ServiceAccountApi.ListServiceAccounts(context.Background(), orgId.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, serviceAccountTableFields(), res)
+	return nil
+}
+
+func createServiceAccount(c *cli.Context, orgId uuid.UUID, name, description string) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.ServiceAccountApi.CreateServiceAccount(context.Background(), orgId.String()).ServiceAccount(public.ModelsAddServiceAccount{
+		Name:        name,
+		Description: description,
+	}).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, serviceAccountTableFields(), res)
+	return nil
+}
+
+func deleteServiceAccount(c *cli.Context, orgId, saId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.ServiceAccountApi.DeleteServiceAccount(context.Background(), orgId.String(), saId.String()).Execute()
+	if err != nil {
+		log.Fatalf("Service account delete failed: %v\n", err)
+	}
+
+	showOutput(c, serviceAccountTableFields(), res)
+	encodeOut := c.String("output")
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Println("\nsuccessfully deleted")
+	}
+	return nil
+}
+
+func listApiTokens(c *cli.Context, orgId, saId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.ServiceAccountApi.ListApiTokens(context.Background(), orgId.String(), saId.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, apiTokenTableFields(false), res)
+	return nil
+}
+
+func createApiToken(c *cli.Context, orgId, saId uuid.UUID, request public.ModelsAddApiToken) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.ServiceAccountApi.CreateApiToken(context.Background(), orgId.String(), saId.String()).ApiToken(request).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, apiTokenTableFields(true), res)
+	return nil
+}
+
+func revokeApiToken(c *cli.Context, orgId, saId, tokenId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.ServiceAccountApi.DeleteApiToken(context.Background(), orgId.String(), saId.String(), tokenId.String()).Execute()
+	if err != nil {
+		log.Fatalf("Api token revoke failed: %v\n", err)
+	}
+
+	showOutput(c, apiTokenTableFields(false), res)
+	encodeOut := c.String("output")
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Println("\nsuccessfully revoked")
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/urfave/cli/v2"
+)
+
+var organizationWebhooksSubcommands []*cli.Command
+
+func init() {
+	orgFlag := &cli.StringFlag{
+		Name:     "organization-id",
+		Required: true,
+	}
+	webhookFlag := &cli.StringFlag{
+		Name:     "webhook-id",
+		Required: true,
+	}
+	organizationWebhooksSubcommands = []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "List the webhooks of an organization",
+			Flags: []cli.Flag{orgFlag},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return listWebhooks(c, orgId)
+			},
+		},
+		{
+			Name:  "create",
+			Usage: "Create a webhook",
+			Flags: []cli.Flag{
+				orgFlag,
+				&cli.StringFlag{
+					Name:     "url",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "description",
+					Required: false,
+				},
+				&cli.StringSliceFlag{
+					Name:  "event",
+					Usage: "event to deliver to the webhook, defaults to all events",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				orgId, err := uuid.Parse(c.String("organization-id"))
+				if err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				}
+				return createWebhook(c, orgId, public.ModelsAddWebhook{
+					Url:         c.String("url"),
+					Description: c.String("description"),
+					Events:      c.StringSlice("event"),
+				})
+			},
+		},
+		{
+			Name:  "delete",
+			Usage: "Delete a webhook",
+			Flags: []cli.Flag{orgFlag, webhookFlag},
+			Action: func(c *cli.Context) error {
+				orgId, webhookId, err := parseWebhookFlags(c)
+				if err != nil {
+					return err
+				}
+				return deleteWebhook(c, orgId, webhookId)
+			},
+		},
+		{
+			Name:  "deliveries",
+			Usage: "List the delivery log of a webhook",
+			Flags: []cli.Flag{orgFlag, webhookFlag},
+			Action: func(c *cli.Context) error {
+				orgId, webhookId, err := parseWebhookFlags(c)
+				if err != nil {
+					return err
+				}
+				return listWebhookDeliveries(c, orgId, webhookId)
+			},
+		},
+	}
+}
+
+func parseWebhookFlags(c *cli.Context) (uuid.UUID, uuid.UUID, error) {
+	orgId, err := uuid.Parse(c.String("organization-id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid organization-id: %w", err)
+	}
+	webhookId, err := uuid.Parse(c.String("webhook-id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid webhook-id: %w", err)
+	}
+	return orgId, webhookId, nil
+}
+
+func webhookTableFields(withSecret bool) []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "WEBHOOK ID", Field: "Id"})
+	fields = append(fields, TableField{Header: "URL", Field: "Url"})
+	fields = append(fields, TableField{Header: "DESCRIPTION", Field: "Description"})
+	fields = append(fields, TableField{Header: "EVENTS", Field: "Events"})
+	if withSecret {
+		fields = append(fields, TableField{Header: "SECRET", Field: "Secret"})
+	}
+	return fields
+}
+
+func webhookDeliveryTableFields() []TableField {
+	var fields []TableField
+	fields = append(fields, TableField{Header: "DELIVERY ID", Field: "Id"})
+	fields = append(fields, TableField{Header: "EVENT", Field: "Event"})
+	fields = append(fields, TableField{Header: "STATUS", Field: "Status"})
+	fields = append(fields, TableField{Header: "ATTEMPTS", Field: "Attempts"})
+	fields = append(fields, TableField{Header: "RESPONSE CODE", Field: "ResponseCode"})
+	fields = append(fields, TableField{Header: "NEXT ATTEMPT AT", Field: "NextAttemptAt"})
+	fields = append(fields, TableField{Header: "LAST ERROR", Field: "LastError"})
+	return fields
+}
+
+func listWebhooks(c *cli.Context, orgId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.WebhookApi.ListWebhooks(context.Background(), orgId.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, webhookTableFields(false), res)
+	return nil
+}
+
+func createWebhook(c *cli.Context, orgId uuid.UUID, request public.ModelsAddWebhook) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.WebhookApi.CreateWebhook(context.Background(), orgId.String()).Webhook(request).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, webhookTableFields(true), res)
+	return nil
+}
+
+func deleteWebhook(c *cli.Context, orgId, webhookId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.WebhookApi.DeleteWebhook(context.Background(), orgId.String(), webhookId.String()).Execute()
+	if err != nil {
+		log.Fatalf("Webhook delete failed: %v\n", err)
+	}
+
+	showOutput(c, webhookTableFields(false), res)
+	encodeOut := c.String("output")
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Println("\nsuccessfully deleted")
+	}
+	return nil
+}
+
+func listWebhookDeliveries(c *cli.Context, orgId, webhookId uuid.UUID) error {
+	client := mustCreateAPIClient(c)
+	res, _, err := client.WebhookApi.ListWebhookDeliveries(context.Background(), orgId.String(), webhookId.String()).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	showOutput(c, webhookDeliveryTableFields(), res)
+	return nil
+}
@@ -144,6 +144,8 @@ func nexdRun(cCtx *cli.Context, logger *zap.Logger, logLevel *zap.AtomicLevel, m
 		apiURL,
 		cCtx.String("username"),
 		cCtx.String("password"),
+		cCtx.String("auth-key"),
+		cCtx.String("identity-provider"),
 		cCtx.Int("listen-port"),
 		cCtx.String("request-ip"),
 		cCtx.String("local-endpoint-ip"),
@@ -159,7 +161,8 @@ func nexdRun(cCtx *cli.Context, logger *zap.Logger, logLevel *zap.AtomicLevel, m
 		stateStore,
 		stateDir,
 		ctx,
-		cCtx.String("organization-id"),
+		cCtx.StringSlice("organization-id"),
+		cCtx.String("grpc-sync-url"),
 	)
 	if err != nil {
 		logger.Fatal(err.Error())
@@ -400,6 +403,22 @@ func main() {
 				Required: false,
 				Category: nexServiceOptions,
 			},
+			&cli.StringFlag{
+				Name:     "auth-key",
+				Value:    "",
+				Usage:    "Registration key `string` used to join an organization without an interactive login",
+				EnvVars:  []string{"NEXD_AUTH_KEY"},
+				Required: false,
+				Category: nexServiceOptions,
+			},
+			&cli.StringFlag{
+				Name:     "identity-provider",
+				Value:    "",
+				Usage:    "Identity provider `name` to log in with, the default provider of the nexodus service is used when not set",
+				EnvVars:  []string{"NEXD_IDENTITY_PROVIDER"},
+				Required: false,
+				Category: nexServiceOptions,
+			},
 			&cli.BoolFlag{
 				Name:     "insecure-skip-tls-verify",
 				Value:    false,
@@ -421,13 +440,20 @@ func main() {
 				EnvVars:  []string{"NEXD_STUN_SERVER"},
 				Category: nexServiceOptions,
 			},
-			&cli.StringFlag{
+			&cli.StringSliceFlag{
 				Name:     "organization-id",
-				Usage:    "Organization ID to use when registering with the nexodus service",
+				Usage:    "Organization ID to use when registering with the nexodus service. Repeat it to join several organizations, each with its own tunnel interface",
 				EnvVars:  []string{"NEXD_ORG_ID"},
 				Required: false,
 				Category: nexServiceOptions,
 			},
+			&cli.StringFlag{
+				Name:     "grpc-sync-url",
+				Usage:    "URL to the gRPC device sync service, when set it is used to watch for device and security group changes instead of the http api. A http:// URL uses an unencrypted connection",
+				EnvVars:  []string{"NEXD_GRPC_SYNC_URL"},
+				Required: false,
+				Category: nexServiceOptions,
+			},
 			&cli.StringFlag{
 				Name:     "service-url",
 				Usage:    "URL to the Nexodus service",
@@ -18,7 +18,7 @@ function build_project {
   make load-images
   kind load --name nexodus-dev docker-image quay.io/nexodus/apiserver:latest
   kubectl delete deployment auth -n nexodus
-  kubectl delete -n nexodus deploy/apiserver postgrescluster/database deploy/ipam
+  kubectl delete -n nexodus deploy/apiserver postgrescluster/database
   kubectl delete -n nexodus deploy/frontend
   sleep 10
   kubectl apply -k ./deploy/nexodus/overlays/dev
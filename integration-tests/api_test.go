@@ -277,3 +277,76 @@ func TestDevicesInformer(t *testing.T) {
 	require.Len(devices, 0)
 
 }
+
+func TestSecurityGroupsInformer(t *testing.T) {
+	t.Parallel()
+	helper := NewHelper(t)
+	require := helper.require
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+
+	password := "floofykittens"
+	username, cancel := helper.createNewUser(ctx, password)
+	defer cancel()
+
+	c, err := client.NewAPIClient(ctx, "https://api.try.nexodus.127.0.0.1.nip.io", nil, client.WithPasswordGrant(
+		username,
+		password,
+	))
+	require.NoError(err)
+	orgs, _, err := c.OrganizationsApi.ListOrganizations(ctx).Execute()
+	require.NoError(err)
+
+	informer := c.SecurityGroupApi.ListSecurityGroups(ctx, orgs[0].Id).Informer()
+	isChanged := func() bool {
+		select {
+		case <-informer.Changed():
+			return true
+		default:
+		}
+		return false
+	}
+
+	// the organization starts with its default security group
+	securityGroups, _, err := informer.Execute()
+	require.NoError(err)
+	require.Len(securityGroups, 1)
+	require.True(isChanged())
+
+	securityGroup, _, err := c.SecurityGroupApi.CreateSecurityGroup(ctx, orgs[0].Id).SecurityGroup(public.ModelsAddSecurityGroup{
+		GroupName: "informer",
+		OrgId:     orgs[0].Id,
+		InboundRules: []public.ModelsSecurityRule{
+			{IpProtocol: "tcp", FromPort: 22, ToPort: 22},
+		},
+	}).Execute()
+	require.NoError(err)
+
+	require.Eventually(isChanged, 2*time.Second, time.Millisecond)
+	securityGroups, _, err = informer.Execute()
+	require.NoError(err)
+	require.Len(securityGroups, 2)
+	require.Len(securityGroups[securityGroup.Id].InboundRules, 1)
+
+	_, _, err = c.SecurityGroupApi.UpdateSecurityGroup(ctx, orgs[0].Id, securityGroup.Id).Update(public.ModelsUpdateSecurityGroup{
+		GroupName: "informer",
+		InboundRules: []public.ModelsSecurityRule{
+			{IpProtocol: "tcp", FromPort: 22, ToPort: 22},
+			{IpProtocol: "tcp", FromPort: 443, ToPort: 443},
+		},
+	}).Execute()
+	require.NoError(err)
+
+	require.Eventually(isChanged, 2*time.Second, time.Millisecond)
+	securityGroups, _, err = informer.Execute()
+	require.NoError(err)
+	require.Len(securityGroups[securityGroup.Id].InboundRules, 2)
+
+	_, _, err = c.SecurityGroupApi.DeleteSecurityGroup(ctx, orgs[0].Id, securityGroup.Id).Execute()
+	require.NoError(err)
+
+	require.Eventually(isChanged, 2*time.Second, time.Millisecond)
+	securityGroups, _, err = informer.Execute()
+	require.NoError(err)
+	require.Len(securityGroups, 1)
+}
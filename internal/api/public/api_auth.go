@@ -124,6 +124,13 @@ func (a *AuthApiService) ClaimsExecute(r ApiClaimsRequest) (map[string]interface
 type ApiDeviceStartRequest struct {
 	ctx        context.Context
 	ApiService *AuthApiService
+	provider   *string
+}
+
+// Name of the identity provider, the default provider is used when not set
+func (r ApiDeviceStartRequest) Provider(provider string) ApiDeviceStartRequest {
+	r.provider = &provider
+	return r
 }
 
 func (r ApiDeviceStartRequest) Execute() (*ModelsDeviceStartResponse, *http.Response, error) {
@@ -133,7 +140,7 @@ func (r ApiDeviceStartRequest) Execute() (*ModelsDeviceStartResponse, *http.Resp
 /*
 DeviceStart Start Login
 
-Starts a device login request
+Starts a device login request with an identity provider
 
 	@param ctx context.Context - for authentication, logging, cancellation, deadlines, tracing, etc. Passed from http.Request or context.Background().
 	@return ApiDeviceStartRequest
@@ -167,6 +174,9 @@ func (a *AuthApiService) DeviceStartExecute(r ApiDeviceStartRequest) (*ModelsDev
 	localVarQueryParams := url.Values{}
 	localVarFormParams := url.Values{}
 
+	if r.provider != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "provider", r.provider, "")
+	}
 	// to determine the Content-Type header
 	localVarHTTPContentTypes := []string{}
 
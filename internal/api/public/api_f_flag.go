@@ -134,7 +134,7 @@ func (a *FFlagApiService) GetFeatureFlagExecute(r ApiGetFeatureFlagRequest) (map
 			return localVarReturnValue, localVarHTTPResponse, newErr
 		}
 		if localVarHTTPResponse.StatusCode == 429 {
-			var v ModelsBaseError
+			var v ModelsTooManyRequestsError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
 			if err != nil {
 				newErr.error = err.Error()
@@ -244,7 +244,7 @@ func (a *FFlagApiService) ListFeatureFlagsExecute(r ApiListFeatureFlagsRequest)
 			error: localVarHTTPResponse.Status,
 		}
 		if localVarHTTPResponse.StatusCode == 429 {
-			var v ModelsBaseError
+			var v ModelsTooManyRequestsError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
 			if err != nil {
 				newErr.error = err.Error()
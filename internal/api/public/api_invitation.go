@@ -62,7 +62,7 @@ func (a *InvitationApiService) AcceptInvitationExecute(r ApiAcceptInvitationRequ
 		return nil, &GenericOpenAPIError{error: err.Error()}
 	}
 
-	localVarPath := localBasePath + "/api/invitations/:invitation/accept"
+	localVarPath := localBasePath + "/api/invitations/{invitation}/accept"
 	localVarPath = strings.Replace(localVarPath, "{"+"invitation"+"}", url.PathEscape(parameterValueToString(r.invitation, "invitation")), -1)
 
 	localVarHeaderParams := make(map[string]string)
@@ -131,6 +131,116 @@ func (a *InvitationApiService) AcceptInvitationExecute(r ApiAcceptInvitationRequ
 			return localVarHTTPResponse, newErr
 		}
 		if localVarHTTPResponse.StatusCode == 429 {
+			var v ModelsTooManyRequestsError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+		}
+		return localVarHTTPResponse, newErr
+	}
+
+	return localVarHTTPResponse, nil
+}
+
+type ApiAcceptInvitationLinkRequest struct {
+	ctx              context.Context
+	ApiService       *InvitationApiService
+	acceptInvitation *ModelsAcceptInvitation
+}
+
+// Accept Invitation
+func (r ApiAcceptInvitationLinkRequest) AcceptInvitation(acceptInvitation ModelsAcceptInvitation) ApiAcceptInvitationLinkRequest {
+	r.acceptInvitation = &acceptInvitation
+	return r
+}
+
+func (r ApiAcceptInvitationLinkRequest) Execute() (*http.Response, error) {
+	return r.ApiService.AcceptInvitationLinkExecute(r)
+}
+
+/*
+AcceptInvitationLink Accept an invitation link
+
+Accept an invitation to an organization with the token of its link, the link can only be used once
+
+	@param ctx context.Context - for authentication, logging, cancellation, deadlines, tracing, etc. Passed from http.Request or context.Background().
+	@return ApiAcceptInvitationLinkRequest
+*/
+func (a *InvitationApiService) AcceptInvitationLink(ctx context.Context) ApiAcceptInvitationLinkRequest {
+	return ApiAcceptInvitationLinkRequest{
+		ApiService: a,
+		ctx:        ctx,
+	}
+}
+
+// Execute executes the request
+func (a *InvitationApiService) AcceptInvitationLinkExecute(r ApiAcceptInvitationLinkRequest) (*http.Response, error) {
+	var (
+		localVarHTTPMethod = http.MethodPost
+		localVarPostBody   interface{}
+		formFiles          []formFile
+	)
+
+	localBasePath, err := a.client.cfg.ServerURLWithContext(r.ctx, "InvitationApiService.AcceptInvitationLink")
+	if err != nil {
+		return nil, &GenericOpenAPIError{error: err.Error()}
+	}
+
+	localVarPath := localBasePath + "/api/invitations/accept"
+
+	localVarHeaderParams := make(map[string]string)
+	localVarQueryParams := url.Values{}
+	localVarFormParams := url.Values{}
+	if r.acceptInvitation == nil {
+		return nil, reportError("acceptInvitation is required and must be specified")
+	}
+
+	// to determine the Content-Type header
+	localVarHTTPContentTypes := []string{"application/json"}
+
+	// set Content-Type header
+	localVarHTTPContentType := selectHeaderContentType(localVarHTTPContentTypes)
+	if localVarHTTPContentType != "" {
+		localVarHeaderParams["Content-Type"] = localVarHTTPContentType
+	}
+
+	// to determine the Accept header
+	localVarHTTPHeaderAccepts := []string{"application/json"}
+
+	// set Accept header
+	localVarHTTPHeaderAccept := selectHeaderAccept(localVarHTTPHeaderAccepts)
+	if localVarHTTPHeaderAccept != "" {
+		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
+	}
+	// body params
+	localVarPostBody = r.acceptInvitation
+	req, err := a.client.prepareRequest(r.ctx, localVarPath, localVarHTTPMethod, localVarPostBody, localVarHeaderParams, localVarQueryParams, localVarFormParams, formFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	localVarHTTPResponse, err := a.client.callAPI(req)
+	if err != nil || localVarHTTPResponse == nil {
+		return localVarHTTPResponse, err
+	}
+
+	localVarBody, err := io.ReadAll(localVarHTTPResponse.Body)
+	localVarHTTPResponse.Body.Close()
+	localVarHTTPResponse.Body = io.NopCloser(bytes.NewBuffer(localVarBody))
+	if err != nil {
+		return localVarHTTPResponse, err
+	}
+
+	if localVarHTTPResponse.StatusCode >= 300 {
+		newErr := &GenericOpenAPIError{
+			body:  localVarBody,
+			error: localVarHTTPResponse.Status,
+		}
+		if localVarHTTPResponse.StatusCode == 400 {
 			var v ModelsBaseError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
 			if err != nil {
@@ -139,6 +249,39 @@ func (a *InvitationApiService) AcceptInvitationExecute(r ApiAcceptInvitationRequ
 			}
 			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
 			newErr.model = v
+			return localVarHTTPResponse, newErr
+		}
+		if localVarHTTPResponse.StatusCode == 403 {
+			var v ModelsBaseError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+			return localVarHTTPResponse, newErr
+		}
+		if localVarHTTPResponse.StatusCode == 404 {
+			var v ModelsBaseError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+			return localVarHTTPResponse, newErr
+		}
+		if localVarHTTPResponse.StatusCode == 429 {
+			var v ModelsTooManyRequestsError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
 		}
 		return localVarHTTPResponse, newErr
 	}
@@ -165,7 +308,7 @@ func (r ApiCreateInvitationRequest) Execute() (*ModelsInvitation, *http.Response
 /*
 CreateInvitation Create an invitation
 
-Create an invitation to an organization
+Create an invitation to an organization, for a user or an email address. The link of the invitation is only returned once.
 
 	@param ctx context.Context - for authentication, logging, cancellation, deadlines, tracing, etc. Passed from http.Request or context.Background().
 	@return ApiCreateInvitationRequest
@@ -254,6 +397,17 @@ func (a *InvitationApiService) CreateInvitationExecute(r ApiCreateInvitationRequ
 			newErr.model = v
 			return localVarReturnValue, localVarHTTPResponse, newErr
 		}
+		if localVarHTTPResponse.StatusCode == 403 {
+			var v ModelsBaseError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
 		if localVarHTTPResponse.StatusCode == 404 {
 			var v ModelsBaseError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
@@ -265,8 +419,19 @@ func (a *InvitationApiService) CreateInvitationExecute(r ApiCreateInvitationRequ
 			newErr.model = v
 			return localVarReturnValue, localVarHTTPResponse, newErr
 		}
+		if localVarHTTPResponse.StatusCode == 409 {
+			var v ModelsConflictsError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
 		if localVarHTTPResponse.StatusCode == 429 {
-			var v ModelsBaseError
+			var v ModelsTooManyRequestsError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
 			if err != nil {
 				newErr.error = err.Error()
@@ -402,7 +567,7 @@ func (a *InvitationApiService) DeleteInvitationExecute(r ApiDeleteInvitationRequ
 			return localVarReturnValue, localVarHTTPResponse, newErr
 		}
 		if localVarHTTPResponse.StatusCode == 429 {
-			var v ModelsBaseError
+			var v ModelsTooManyRequestsError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
 			if err != nil {
 				newErr.error = err.Error()
@@ -440,6 +605,34 @@ func (a *InvitationApiService) DeleteInvitationExecute(r ApiDeleteInvitationRequ
 type ApiListInvitationsRequest struct {
 	ctx        context.Context
 	ApiService *InvitationApiService
+	gtRevision *int32
+	q          *string
+	limit      *int32
+	cursor     *string
+}
+
+// greater than revision
+func (r ApiListInvitationsRequest) GtRevision(gtRevision int32) ApiListInvitationsRequest {
+	r.gtRevision = &gtRevision
+	return r
+}
+
+// conditions on the fields, for example role=member,expiry<2023-07-01
+func (r ApiListInvitationsRequest) Q(q string) ApiListInvitationsRequest {
+	r.q = &q
+	return r
+}
+
+// the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page
+func (r ApiListInvitationsRequest) Limit(limit int32) ApiListInvitationsRequest {
+	r.limit = &limit
+	return r
+}
+
+// the cursor of the page to list
+func (r ApiListInvitationsRequest) Cursor(cursor string) ApiListInvitationsRequest {
+	r.cursor = &cursor
+	return r
 }
 
 func (r ApiListInvitationsRequest) Execute() ([]ModelsInvitation, *http.Response, error) {
@@ -483,6 +676,18 @@ func (a *InvitationApiService) ListInvitationsExecute(r ApiListInvitationsReques
 	localVarQueryParams := url.Values{}
 	localVarFormParams := url.Values{}
 
+	if r.gtRevision != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "gt_revision", r.gtRevision, "")
+	}
+	if r.q != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "q", r.q, "")
+	}
+	if r.limit != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "limit", r.limit, "")
+	}
+	if r.cursor != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "cursor", r.cursor, "")
+	}
 	// to determine the Content-Type header
 	localVarHTTPContentTypes := []string{}
 
@@ -522,6 +727,17 @@ func (a *InvitationApiService) ListInvitationsExecute(r ApiListInvitationsReques
 			body:  localVarBody,
 			error: localVarHTTPResponse.Status,
 		}
+		if localVarHTTPResponse.StatusCode == 400 {
+			var v ModelsBaseError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
 		if localVarHTTPResponse.StatusCode == 401 {
 			var v ModelsBaseError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
@@ -533,8 +749,19 @@ func (a *InvitationApiService) ListInvitationsExecute(r ApiListInvitationsReques
 			newErr.model = v
 			return localVarReturnValue, localVarHTTPResponse, newErr
 		}
+		if localVarHTTPResponse.StatusCode == 410 {
+			var v ModelsGoneError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
 		if localVarHTTPResponse.StatusCode == 429 {
-			var v ModelsBaseError
+			var v ModelsTooManyRequestsError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
 			if err != nil {
 				newErr.error = err.Error()
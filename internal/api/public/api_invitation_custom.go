@@ -0,0 +1,35 @@
+package public
+
+import (
+	"net/http"
+)
+
+func (r ApiListInvitationsRequest) Watch() (*WatchStream[ModelsInvitation], *http.Response, error) {
+	return r.ApiService.ListInvitationsWatch(r)
+}
+
+func (a *InvitationApiService) ListInvitationsWatch(r ApiListInvitationsRequest) (*WatchStream[ModelsInvitation], *http.Response, error) {
+	localVarPath := "/api/invitations"
+	return watchList[ModelsInvitation](a.client, r.ctx, "InvitationApiService.ListInvitations", localVarPath, r.gtRevision)
+}
+
+// Informer creates a *ApiListInvitationsInformer which provides a simpler API to list
+// invitations but which is implemented with the Watch api.  The *ApiListInvitationsInformer
+// maintains a local cache of the invitations, keyed by id, which gets updated with the Watch events.
+func (r ApiListInvitationsRequest) Informer() *ApiListInvitationsInformer {
+	return &ApiListInvitationsInformer{
+		WatchInformer: NewWatchInformer(r.ctx, func(gtRevision int32) (WatchEventStream[ModelsInvitation], *http.Response, error) {
+			return r.GtRevision(gtRevision).Watch()
+		}, func(item ModelsInvitation) string {
+			return item.Id
+		}, func(item ModelsInvitation) string {
+			return item.Id
+		}, func(item ModelsInvitation) int32 {
+			return item.Revision
+		}),
+	}
+}
+
+type ApiListInvitationsInformer struct {
+	*WatchInformer[string, ModelsInvitation]
+}
@@ -0,0 +1,35 @@
+package public
+
+import (
+	"net/http"
+)
+
+func (r ApiListOrganizationsRequest) Watch() (*WatchStream[ModelsOrganization], *http.Response, error) {
+	return r.ApiService.ListOrganizationsWatch(r)
+}
+
+func (a *OrganizationsApiService) ListOrganizationsWatch(r ApiListOrganizationsRequest) (*WatchStream[ModelsOrganization], *http.Response, error) {
+	localVarPath := "/api/organizations"
+	return watchList[ModelsOrganization](a.client, r.ctx, "OrganizationsApiService.ListOrganizations", localVarPath, r.gtRevision)
+}
+
+// Informer creates a *ApiListOrganizationsInformer which provides a simpler API to list
+// organizations but which is implemented with the Watch api.  The *ApiListOrganizationsInformer
+// maintains a local cache of the organizations, keyed by id, which gets updated with the Watch events.
+func (r ApiListOrganizationsRequest) Informer() *ApiListOrganizationsInformer {
+	return &ApiListOrganizationsInformer{
+		WatchInformer: NewWatchInformer(r.ctx, func(gtRevision int32) (WatchEventStream[ModelsOrganization], *http.Response, error) {
+			return r.GtRevision(gtRevision).Watch()
+		}, func(item ModelsOrganization) string {
+			return item.Id
+		}, func(item ModelsOrganization) string {
+			return item.Id
+		}, func(item ModelsOrganization) int32 {
+			return item.Revision
+		}),
+	}
+}
+
+type ApiListOrganizationsInformer struct {
+	*WatchInformer[string, ModelsOrganization]
+}
@@ -0,0 +1,38 @@
+package public
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func (r ApiListSecurityGroupsRequest) Watch() (*WatchStream[ModelsSecurityGroup], *http.Response, error) {
+	return r.ApiService.ListSecurityGroupsWatch(r)
+}
+
+func (a *SecurityGroupApiService) ListSecurityGroupsWatch(r ApiListSecurityGroupsRequest) (*WatchStream[ModelsSecurityGroup], *http.Response, error) {
+	localVarPath := "/api/organizations/{organization_id}/security_groups"
+	localVarPath = strings.Replace(localVarPath, "{"+"organization_id"+"}", url.PathEscape(parameterValueToString(r.organizationId, "organizationId")), -1)
+	return watchList[ModelsSecurityGroup](a.client, r.ctx, "SecurityGroupApiService.ListSecurityGroups", localVarPath, r.gtRevision)
+}
+
+// Informer creates a *ApiListSecurityGroupsInformer which provides a simpler API to list
+// security groups but which is implemented with the Watch api.  The *ApiListSecurityGroupsInformer
+// maintains a local cache of the security groups, keyed by id, which gets updated with the Watch events.
+func (r ApiListSecurityGroupsRequest) Informer() *ApiListSecurityGroupsInformer {
+	return &ApiListSecurityGroupsInformer{
+		WatchInformer: NewWatchInformer(r.ctx, func(gtRevision int32) (WatchEventStream[ModelsSecurityGroup], *http.Response, error) {
+			return r.GtRevision(gtRevision).Watch()
+		}, func(item ModelsSecurityGroup) string {
+			return item.Id
+		}, func(item ModelsSecurityGroup) string {
+			return item.Id
+		}, func(item ModelsSecurityGroup) int32 {
+			return item.Revision
+		}),
+	}
+}
+
+type ApiListSecurityGroupsInformer struct {
+	*WatchInformer[string, ModelsSecurityGroup]
+}
@@ -123,7 +123,7 @@ func (a *UsersApiService) DeleteUserExecute(r ApiDeleteUserRequest) (*ModelsUser
 			return localVarReturnValue, localVarHTTPResponse, newErr
 		}
 		if localVarHTTPResponse.StatusCode == 429 {
-			var v ModelsBaseError
+			var v ModelsTooManyRequestsError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
 			if err != nil {
 				newErr.error = err.Error()
@@ -262,6 +262,28 @@ func (a *UsersApiService) DeleteUserFromOrganizationExecute(r ApiDeleteUserFromO
 			newErr.model = v
 			return localVarReturnValue, localVarHTTPResponse, newErr
 		}
+		if localVarHTTPResponse.StatusCode == 403 {
+			var v ModelsBaseError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
+		if localVarHTTPResponse.StatusCode == 404 {
+			var v ModelsBaseError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
 		if localVarHTTPResponse.StatusCode == 500 {
 			var v ModelsBaseError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
@@ -410,7 +432,7 @@ func (a *UsersApiService) GetUserExecute(r ApiGetUserRequest) (*ModelsUser, *htt
 			return localVarReturnValue, localVarHTTPResponse, newErr
 		}
 		if localVarHTTPResponse.StatusCode == 429 {
-			var v ModelsBaseError
+			var v ModelsTooManyRequestsError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
 			if err != nil {
 				newErr.error = err.Error()
@@ -448,6 +470,34 @@ func (a *UsersApiService) GetUserExecute(r ApiGetUserRequest) (*ModelsUser, *htt
 type ApiListUsersRequest struct {
 	ctx        context.Context
 	ApiService *UsersApiService
+	gtRevision *int32
+	q          *string
+	limit      *int32
+	cursor     *string
+}
+
+// greater than revision
+func (r ApiListUsersRequest) GtRevision(gtRevision int32) ApiListUsersRequest {
+	r.gtRevision = &gtRevision
+	return r
+}
+
+// conditions on the fields, for example user_name^=alice
+func (r ApiListUsersRequest) Q(q string) ApiListUsersRequest {
+	r.q = &q
+	return r
+}
+
+// the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page
+func (r ApiListUsersRequest) Limit(limit int32) ApiListUsersRequest {
+	r.limit = &limit
+	return r
+}
+
+// the cursor of the page to list
+func (r ApiListUsersRequest) Cursor(cursor string) ApiListUsersRequest {
+	r.cursor = &cursor
+	return r
 }
 
 func (r ApiListUsersRequest) Execute() ([]ModelsUser, *http.Response, error) {
@@ -491,6 +541,18 @@ func (a *UsersApiService) ListUsersExecute(r ApiListUsersRequest) ([]ModelsUser,
 	localVarQueryParams := url.Values{}
 	localVarFormParams := url.Values{}
 
+	if r.gtRevision != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "gt_revision", r.gtRevision, "")
+	}
+	if r.q != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "q", r.q, "")
+	}
+	if r.limit != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "limit", r.limit, "")
+	}
+	if r.cursor != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "cursor", r.cursor, "")
+	}
 	// to determine the Content-Type header
 	localVarHTTPContentTypes := []string{}
 
@@ -530,6 +592,17 @@ func (a *UsersApiService) ListUsersExecute(r ApiListUsersRequest) ([]ModelsUser,
 			body:  localVarBody,
 			error: localVarHTTPResponse.Status,
 		}
+		if localVarHTTPResponse.StatusCode == 400 {
+			var v ModelsBaseError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
 		if localVarHTTPResponse.StatusCode == 401 {
 			var v ModelsBaseError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
@@ -541,8 +614,19 @@ func (a *UsersApiService) ListUsersExecute(r ApiListUsersRequest) ([]ModelsUser,
 			newErr.model = v
 			return localVarReturnValue, localVarHTTPResponse, newErr
 		}
+		if localVarHTTPResponse.StatusCode == 410 {
+			var v ModelsGoneError
+			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
+			if err != nil {
+				newErr.error = err.Error()
+				return localVarReturnValue, localVarHTTPResponse, newErr
+			}
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+			return localVarReturnValue, localVarHTTPResponse, newErr
+		}
 		if localVarHTTPResponse.StatusCode == 429 {
-			var v ModelsBaseError
+			var v ModelsTooManyRequestsError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
 			if err != nil {
 				newErr.error = err.Error()
@@ -570,6 +654,27 @@ type ApiListUsersInOrganizationRequest struct {
 	ctx        context.Context
 	ApiService *UsersApiService
 	id         string
+	q          *string
+	limit      *int32
+	cursor     *string
+}
+
+// conditions on the fields, for example user_name^=alice,created_at>=2023-06-01
+func (r ApiListUsersInOrganizationRequest) Q(q string) ApiListUsersInOrganizationRequest {
+	r.q = &q
+	return r
+}
+
+// the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page
+func (r ApiListUsersInOrganizationRequest) Limit(limit int32) ApiListUsersInOrganizationRequest {
+	r.limit = &limit
+	return r
+}
+
+// the cursor of the page to list
+func (r ApiListUsersInOrganizationRequest) Cursor(cursor string) ApiListUsersInOrganizationRequest {
+	r.cursor = &cursor
+	return r
 }
 
 func (r ApiListUsersInOrganizationRequest) Execute() ([]ModelsUser, *http.Response, error) {
@@ -616,6 +721,15 @@ func (a *UsersApiService) ListUsersInOrganizationExecute(r ApiListUsersInOrganiz
 	localVarQueryParams := url.Values{}
 	localVarFormParams := url.Values{}
 
+	if r.q != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "q", r.q, "")
+	}
+	if r.limit != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "limit", r.limit, "")
+	}
+	if r.cursor != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "cursor", r.cursor, "")
+	}
 	// to determine the Content-Type header
 	localVarHTTPContentTypes := []string{}
 
@@ -678,7 +792,7 @@ func (a *UsersApiService) ListUsersInOrganizationExecute(r ApiListUsersInOrganiz
 			return localVarReturnValue, localVarHTTPResponse, newErr
 		}
 		if localVarHTTPResponse.StatusCode == 429 {
-			var v ModelsBaseError
+			var v ModelsTooManyRequestsError
 			err = a.client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type"))
 			if err != nil {
 				newErr.error = err.Error()
@@ -0,0 +1,35 @@
+package public
+
+import (
+	"net/http"
+)
+
+func (r ApiListUsersRequest) Watch() (*WatchStream[ModelsUser], *http.Response, error) {
+	return r.ApiService.ListUsersWatch(r)
+}
+
+func (a *UsersApiService) ListUsersWatch(r ApiListUsersRequest) (*WatchStream[ModelsUser], *http.Response, error) {
+	localVarPath := "/api/users"
+	return watchList[ModelsUser](a.client, r.ctx, "UsersApiService.ListUsers", localVarPath, r.gtRevision)
+}
+
+// Informer creates a *ApiListUsersInformer which provides a simpler API to list
+// users but which is implemented with the Watch api.  The *ApiListUsersInformer
+// maintains a local cache of the users, keyed by id, which gets updated with the Watch events.
+func (r ApiListUsersRequest) Informer() *ApiListUsersInformer {
+	return &ApiListUsersInformer{
+		WatchInformer: NewWatchInformer(r.ctx, func(gtRevision int32) (WatchEventStream[ModelsUser], *http.Response, error) {
+			return r.GtRevision(gtRevision).Watch()
+		}, func(item ModelsUser) string {
+			return item.Id
+		}, func(item ModelsUser) string {
+			return item.Id
+		}, func(item ModelsUser) int32 {
+			return item.Revision
+		}),
+	}
+}
+
+type ApiListUsersInformer struct {
+	*WatchInformer[string, ModelsUser]
+}
@@ -50,6 +50,8 @@ type APIClient struct {
 
 	// API Services
 
+	AuditApi *AuditApiService
+
 	AuthApi *AuthApiService
 
 	DevicesApi *DevicesApiService
@@ -60,9 +62,15 @@ type APIClient struct {
 
 	OrganizationsApi *OrganizationsApiService
 
+	RegKeyApi *RegKeyApiService
+
 	SecurityGroupApi *SecurityGroupApiService
 
+	ServiceAccountApi *ServiceAccountApiService
+
 	UsersApi *UsersApiService
+
+	WebhookApi *WebhookApiService
 }
 
 type service struct {
@@ -81,13 +89,17 @@ func NewAPIClient(cfg *Configuration) *APIClient {
 	c.common.client = c
 
 	// API Services
+	c.AuditApi = (*AuditApiService)(&c.common)
 	c.AuthApi = (*AuthApiService)(&c.common)
 	c.DevicesApi = (*DevicesApiService)(&c.common)
 	c.FFlagApi = (*FFlagApiService)(&c.common)
 	c.InvitationApi = (*InvitationApiService)(&c.common)
 	c.OrganizationsApi = (*OrganizationsApiService)(&c.common)
+	c.RegKeyApi = (*RegKeyApiService)(&c.common)
 	c.SecurityGroupApi = (*SecurityGroupApiService)(&c.common)
+	c.ServiceAccountApi = (*ServiceAccountApiService)(&c.common)
 	c.UsersApi = (*UsersApiService)(&c.common)
+	c.WebhookApi = (*WebhookApiService)(&c.common)
 
 	return c
 }
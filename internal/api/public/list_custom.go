@@ -0,0 +1,17 @@
+package public
+
+import (
+	"net/http"
+)
+
+// NextCursorHeader is the header of a list response that holds the cursor of the next page.
+const NextCursorHeader = "X-Next-Cursor"
+
+// NextCursor returns the cursor of the page after the one of a list response requested with a
+// limit, or an empty string when it was the last page.
+func NextCursor(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+	return resp.Header.Get(NextCursorHeader)
+}
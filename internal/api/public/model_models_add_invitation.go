@@ -12,9 +12,16 @@ package public
 
 // ModelsAddInvitation struct for ModelsAddInvitation
 type ModelsAddInvitation struct {
+	// The email address to send the invitation to, the user doesn't need to have logged in before
+	// (one of username, user_id or email is required)
+	Email string `json:"email,omitempty"`
+	// How long the invitation is valid, for example 72h, the default of the api server when empty
+	ExpiresIn      string `json:"expires_in,omitempty"`
 	OrganizationId string `json:"organization_id,omitempty"`
-	// The user id to invite (one of username or user_id is required)
+	// The role of the user in the organization, member by default
+	Role string `json:"role,omitempty"`
+	// The user id to invite (one of username, user_id or email is required)
 	UserId string `json:"user_id,omitempty"`
-	// The username to invite (one of username or user_id is required)
+	// The username to invite (one of username, user_id or email is required)
 	UserName string `json:"user_name,omitempty"`
 }
@@ -17,18 +17,24 @@ type ModelsDevice struct {
 	Discovery               bool             `json:"discovery,omitempty"`
 	EndpointLocalAddressIp4 string           `json:"endpoint_local_address_ip4,omitempty"`
 	Endpoints               []ModelsEndpoint `json:"endpoints,omitempty"`
+	Ephemeral               bool             `json:"ephemeral,omitempty"`
 	Hostname                string           `json:"hostname,omitempty"`
 	Id                      string           `json:"id,omitempty"`
-	OrganizationId          string           `json:"organization_id,omitempty"`
-	OrganizationPrefix      string           `json:"organization_prefix,omitempty"`
-	OrganizationPrefixV6    string           `json:"organization_prefix_v6,omitempty"`
-	Os                      string           `json:"os,omitempty"`
-	PublicKey               string           `json:"public_key,omitempty"`
-	Relay                   bool             `json:"relay,omitempty"`
-	Revision                int32            `json:"revision,omitempty"`
-	SecurityGroupId         string           `json:"security_group_id,omitempty"`
-	SymmetricNat            bool             `json:"symmetric_nat,omitempty"`
-	TunnelIp                string           `json:"tunnel_ip,omitempty"`
-	TunnelIpV6              string           `json:"tunnel_ip_v6,omitempty"`
-	UserId                  string           `json:"user_id,omitempty"`
+	// Labels can be used to select the device with a label selector.
+	Labels               map[string]string `json:"labels,omitempty"`
+	OrganizationId       string            `json:"organization_id,omitempty"`
+	OrganizationPrefix   string            `json:"organization_prefix,omitempty"`
+	OrganizationPrefixV6 string            `json:"organization_prefix_v6,omitempty"`
+	Os                   string            `json:"os,omitempty"`
+	PublicKey            string            `json:"public_key,omitempty"`
+	// RegKeyID is the registration key the device was registered with, registration keys can only
+	// manage the devices they registered.
+	RegKeyId        string `json:"reg_key_id,omitempty"`
+	Relay           bool   `json:"relay,omitempty"`
+	Revision        int32  `json:"revision,omitempty"`
+	SecurityGroupId string `json:"security_group_id,omitempty"`
+	SymmetricNat    bool   `json:"symmetric_nat,omitempty"`
+	TunnelIp        string `json:"tunnel_ip,omitempty"`
+	TunnelIpV6      string `json:"tunnel_ip_v6,omitempty"`
+	UserId          string `json:"user_id,omitempty"`
 }
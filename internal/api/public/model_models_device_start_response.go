@@ -16,4 +16,12 @@ type ModelsDeviceStartResponse struct {
 	// TODO: Remove this once golang/oauth2 supports device flow and when coreos/go-oidc adds device_authorization_endpoint discovery
 	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
 	Issuer                      string `json:"issuer,omitempty"`
+	// Provider is the name of the identity provider, when the device can choose one.
+	Provider string `json:"provider,omitempty"`
+	// Scopes are requested by the device.
+	Scopes []string `json:"scopes,omitempty"`
+	// TokenEndpoint is set when the provider has no issuer to discover it from.
+	TokenEndpoint string `json:"token_endpoint,omitempty"`
+	// TokenPrefix is prepended to the access tokens sent to the api.
+	TokenPrefix string `json:"token_prefix,omitempty"`
 }
@@ -12,8 +12,17 @@ package public
 
 // ModelsInvitation struct for ModelsInvitation
 type ModelsInvitation struct {
-	Expiry         string `json:"expiry,omitempty"`
-	Id             string `json:"id,omitempty"`
+	// Email is the address the invitation was sent to.
+	Email  string `json:"email,omitempty"`
+	Expiry string `json:"expiry,omitempty"`
+	Id     string `json:"id,omitempty"`
+	// Link accepts the invitation, it's the token of the invitation when the api server has no link url.
+	// It's only returned when the invitation is created.
+	Link           string `json:"link,omitempty"`
 	OrganizationId string `json:"organization_id,omitempty"`
-	UserId         string `json:"user_id,omitempty"`
+	Revision       int32  `json:"revision,omitempty"`
+	// Role is the role of the user in the organization once they accept the invitation.
+	Role string `json:"role,omitempty"`
+	// UserID is the invited user, it's empty for the invitations by email of users that never logged in.
+	UserId string `json:"user_id,omitempty"`
 }
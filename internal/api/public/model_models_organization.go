@@ -21,5 +21,6 @@ type ModelsOrganization struct {
 	Name            string             `json:"name,omitempty"`
 	OwnerId         string             `json:"owner_id,omitempty"`
 	PrivateCidr     bool               `json:"private_cidr,omitempty"`
+	Revision        int32              `json:"revision,omitempty"`
 	SecurityGroupId string             `json:"security_group_id,omitempty"`
 }
@@ -16,7 +16,9 @@ type ModelsUpdateDevice struct {
 	EndpointLocalAddressIp4 string           `json:"endpoint_local_address_ip4,omitempty"`
 	Endpoints               []ModelsEndpoint `json:"endpoints,omitempty"`
 	Hostname                string           `json:"hostname,omitempty"`
-	OrganizationId          string           `json:"organization_id,omitempty"`
-	Revision                int32            `json:"revision,omitempty"`
-	SymmetricNat            bool             `json:"symmetric_nat,omitempty"`
+	// Labels replaces all the labels of the device when set.
+	Labels         map[string]string `json:"labels,omitempty"`
+	OrganizationId string            `json:"organization_id,omitempty"`
+	Revision       int32             `json:"revision,omitempty"`
+	SymmetricNat   bool              `json:"symmetric_nat,omitempty"`
 }
@@ -15,6 +15,7 @@ type ModelsUser struct {
 	CreatedAt string `json:"createdAt,omitempty"`
 	// Since the ID comes from the IDP, we have no control over the format...
 	Id              string `json:"id,omitempty"`
+	Revision        int32  `json:"revision,omitempty"`
 	SecurityGroupId string `json:"security_group_id,omitempty"`
 	UpdatedAt       string `json:"updatedAt,omitempty"`
 	UserName        string `json:"userName,omitempty"`
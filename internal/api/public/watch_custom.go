@@ -0,0 +1,285 @@
+package public
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+var ErrContextCanceled = errors.New("context canceled")
+
+// ErrResyncRequired is returned by a WatchEventStream when the watch can't be resumed from the
+// requested revision because it has been compacted.
+var ErrResyncRequired = errors.New("resync required")
+
+// WatchEventStream is a stream of watch events.
+type WatchEventStream[T any] interface {
+	// Receive blocks until the next event is received.
+	Receive() (string, T, error)
+	Close() error
+}
+
+// WatchStream decodes the events sent by a list api that was called with watch=true.
+type WatchStream[T any] struct {
+	decoder *json.Decoder
+	close   func() error
+}
+
+func (ws *WatchStream[T]) Receive() (string, T, error) {
+	event := struct {
+		Type  string `json:"type"`
+		Value T      `json:"value"`
+	}{}
+	err := ws.decoder.Decode(&event)
+	if err != nil {
+		return "", event.Value, err
+	}
+	return event.Type, event.Value, nil
+}
+
+func (ws *WatchStream[T]) Close() error {
+	return ws.close()
+}
+
+// watchList calls the list api at path with watch=true and returns the stream of watch events.
+func watchList[T any](client *APIClient, ctx context.Context, operation string, path string, gtRevision *int32) (*WatchStream[T], *http.Response, error) {
+	localBasePath, err := client.cfg.ServerURLWithContext(ctx, operation)
+	if err != nil {
+		return nil, nil, &GenericOpenAPIError{error: err.Error()}
+	}
+
+	localVarHeaderParams := map[string]string{}
+	localVarQueryParams := url.Values{}
+	if gtRevision != nil {
+		parameterAddToHeaderOrQuery(localVarQueryParams, "gt_revision", gtRevision, "")
+	}
+	localVarQueryParams["watch"] = []string{"true"}
+
+	localVarHTTPHeaderAccept := selectHeaderAccept([]string{"application/json"})
+	if localVarHTTPHeaderAccept != "" {
+		localVarHeaderParams["Accept"] = localVarHTTPHeaderAccept
+	}
+	req, err := client.prepareRequest(ctx, localBasePath+path, http.MethodGet, nil, localVarHeaderParams, localVarQueryParams, url.Values{}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	localVarHTTPResponse, err := client.callAPI(req)
+	if err != nil || localVarHTTPResponse == nil {
+		return nil, localVarHTTPResponse, err
+	}
+
+	if localVarHTTPResponse.StatusCode >= 300 {
+		localVarBody, err := io.ReadAll(localVarHTTPResponse.Body)
+		localVarHTTPResponse.Body.Close()
+		localVarHTTPResponse.Body = io.NopCloser(bytes.NewBuffer(localVarBody))
+		if err != nil {
+			return nil, localVarHTTPResponse, err
+		}
+
+		newErr := &GenericOpenAPIError{
+			body:  localVarBody,
+			error: localVarHTTPResponse.Status,
+		}
+		if localVarHTTPResponse.StatusCode == http.StatusGone {
+			var v ModelsGoneError
+			if err := client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type")); err == nil {
+				newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+				newErr.model = v
+			}
+			return nil, localVarHTTPResponse, newErr
+		}
+		var v ModelsBaseError
+		if err := client.decode(&v, localVarBody, localVarHTTPResponse.Header.Get("Content-Type")); err == nil {
+			newErr.error = formatErrorMessage(localVarHTTPResponse.Status, &v)
+			newErr.model = v
+		}
+		return nil, localVarHTTPResponse, newErr
+	}
+
+	return &WatchStream[T]{
+		close:   localVarHTTPResponse.Body.Close,
+		decoder: json.NewDecoder(localVarHTTPResponse.Body),
+	}, localVarHTTPResponse, nil
+}
+
+// WatchInformer maintains a local cache of the items of a list api. The cache is kept up to date
+// with the events of the Watch api, items are tracked by id and returned keyed by key.
+//
+// When the watch is disconnected it is resumed from the last revision received, and when the
+// server responds that the revision has been compacted the cache is rebuilt from a fresh watch.
+type WatchInformer[K comparable, T any] struct {
+	ctx            context.Context
+	watch          func(gtRevision int32) (WatchEventStream[T], *http.Response, error)
+	id             func(T) string
+	key            func(T) K
+	revision       func(T) int32
+	stream         WatchEventStream[T]
+	inSync         chan struct{}
+	modifiedSignal chan struct{}
+	mu             sync.RWMutex
+	data           map[K]T
+	items          map[string]T
+	response       *http.Response
+	err            error
+	lastRevision   int32
+}
+
+// NewWatchInformer creates a WatchInformer that uses watch to start a stream of events after
+// a revision, items are tracked by id and returned keyed by key.
+func NewWatchInformer[K comparable, T any](ctx context.Context, watch func(gtRevision int32) (WatchEventStream[T], *http.Response, error), id func(T) string, key func(T) K, revision func(T) int32) *WatchInformer[K, T] {
+	return &WatchInformer[K, T]{
+		ctx:            ctx,
+		watch:          watch,
+		id:             id,
+		key:            key,
+		revision:       revision,
+		modifiedSignal: make(chan struct{}, 1),
+	}
+}
+
+// Changed is signaled when the cached items change.
+func (s *WatchInformer[K, T]) Changed() <-chan struct{} {
+	return s.modifiedSignal
+}
+
+// Execute returns the cached items, it (re)starts the watch if it is not running, and waits for
+// the initial list of items to be received.
+func (s *WatchInformer[K, T]) Execute() (map[K]T, *http.Response, error) {
+
+	var err error
+	s.mu.Lock()
+	if s.stream == nil {
+		// after an error we can recover by resuming event's from the last revision.
+		s.stream, s.response, s.err = s.watch(s.lastRevision)
+		if s.err != nil && s.lastRevision != 0 && isResyncRequired(s.response, s.err) {
+			// deletes we have not seen have been compacted, start over with a full list.
+			s.lastRevision = 0
+			s.stream, s.response, s.err = s.watch(s.lastRevision)
+		}
+		err = s.err
+		if s.err != nil {
+			s.stream = nil
+		} else {
+			s.inSync = make(chan struct{})
+			// events resume from the last revision, so keep the items received so far.
+			if s.items == nil || s.lastRevision == 0 {
+				s.items = map[string]T{}
+			}
+			go s.readStream(s.stream, s.inSync, s.items, s.lastRevision)
+		}
+	}
+	inSync := s.inSync
+	s.mu.Unlock()
+
+	// initial api request may have failed...
+	if err != nil {
+		return s.data, s.response, s.err
+	}
+
+	// avoid returning a partial data list by, waiting for the bookmark event
+	// which signals that all known data items have sent.
+	select {
+	case <-s.ctx.Done():
+		return s.data, s.response, ErrContextCanceled
+	case <-inSync:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data, s.response, s.err
+}
+
+func isResyncRequired(response *http.Response, err error) bool {
+	return errors.Is(err, ErrResyncRequired) || response != nil && response.StatusCode == http.StatusGone
+}
+
+func (s *WatchInformer[K, T]) readStream(stream WatchEventStream[T], inSync chan struct{}, items map[string]T, lastRevision int32) {
+	isInSync := false
+
+	defer func() {
+		s.mu.Lock()
+		err := stream.Close()
+		if err != nil {
+			s.err = err
+		}
+		s.stream = nil
+		s.mu.Unlock()
+		if !isInSync {
+			isInSync = true
+			close(inSync)
+		}
+	}()
+
+	for {
+		event, item, err := stream.Receive()
+		if err != nil {
+			s.setResult(nil, lastRevision, err)
+			return
+		}
+		switch event {
+		case "change":
+			lastRevision = s.revision(item)
+			items[s.id(item)] = item
+			if isInSync {
+				s.setResult(s.copyItems(items), lastRevision, nil)
+			}
+		case "delete":
+			lastRevision = s.revision(item)
+			delete(items, s.id(item))
+			if isInSync {
+				s.setResult(s.copyItems(items), lastRevision, nil)
+			}
+		case "bookmark":
+			// the value of a bookmark only holds the revision up to which all the changes have been
+			// sent, resuming from it avoids replaying the changes to items we don't list.
+			if revision := s.revision(item); revision > lastRevision {
+				lastRevision = revision
+			}
+			if !isInSync {
+				isInSync = true
+				s.setResult(s.copyItems(items), lastRevision, nil)
+				close(inSync)
+			} else {
+				s.mu.Lock()
+				s.lastRevision = lastRevision
+				s.mu.Unlock()
+			}
+		case "close":
+			return
+		case "error":
+			return
+		default:
+			s.setResult(nil, lastRevision, fmt.Errorf("unknown event type: %s", event))
+			return
+		}
+	}
+}
+
+func (s *WatchInformer[K, T]) copyItems(items map[string]T) map[K]T {
+	data := make(map[K]T, len(items))
+	for _, v := range items {
+		data[s.key(v)] = v
+	}
+	return data
+}
+
+func (s *WatchInformer[K, T]) setResult(data map[K]T, lastRevision int32, err error) {
+	s.mu.Lock()
+	s.data = data
+	s.err = err
+	s.lastRevision = lastRevision
+	s.mu.Unlock()
+
+	select {
+	// try to signal...
+	case s.modifiedSignal <- struct{}{}:
+	default: // so we don't block if a signal is pending.
+	}
+}
@@ -0,0 +1,270 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: internal/api/syncv1/sync.proto
+
+package syncv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	DeviceSync_WatchDevices_FullMethodName        = "/nexodus.sync.v1.DeviceSync/WatchDevices"
+	DeviceSync_WatchDeviceMetadata_FullMethodName = "/nexodus.sync.v1.DeviceSync/WatchDeviceMetadata"
+	DeviceSync_WatchSecurityGroups_FullMethodName = "/nexodus.sync.v1.DeviceSync/WatchSecurityGroups"
+)
+
+// DeviceSyncClient is the client API for DeviceSync service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DeviceSyncClient interface {
+	// WatchDevices streams the changes to the devices of an organization.
+	WatchDevices(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (DeviceSync_WatchDevicesClient, error)
+	// WatchDeviceMetadata streams the changes to the metadata of the devices of an organization.
+	WatchDeviceMetadata(ctx context.Context, in *WatchDeviceMetadataRequest, opts ...grpc.CallOption) (DeviceSync_WatchDeviceMetadataClient, error)
+	// WatchSecurityGroups streams the changes to the security groups of an organization.
+	WatchSecurityGroups(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (DeviceSync_WatchSecurityGroupsClient, error)
+}
+
+type deviceSyncClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDeviceSyncClient(cc grpc.ClientConnInterface) DeviceSyncClient {
+	return &deviceSyncClient{cc}
+}
+
+func (c *deviceSyncClient) WatchDevices(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (DeviceSync_WatchDevicesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DeviceSync_ServiceDesc.Streams[0], DeviceSync_WatchDevices_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &deviceSyncWatchDevicesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DeviceSync_WatchDevicesClient interface {
+	Recv() (*DeviceEvent, error)
+	grpc.ClientStream
+}
+
+type deviceSyncWatchDevicesClient struct {
+	grpc.ClientStream
+}
+
+func (x *deviceSyncWatchDevicesClient) Recv() (*DeviceEvent, error) {
+	m := new(DeviceEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *deviceSyncClient) WatchDeviceMetadata(ctx context.Context, in *WatchDeviceMetadataRequest, opts ...grpc.CallOption) (DeviceSync_WatchDeviceMetadataClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DeviceSync_ServiceDesc.Streams[1], DeviceSync_WatchDeviceMetadata_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &deviceSyncWatchDeviceMetadataClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DeviceSync_WatchDeviceMetadataClient interface {
+	Recv() (*DeviceMetadataEvent, error)
+	grpc.ClientStream
+}
+
+type deviceSyncWatchDeviceMetadataClient struct {
+	grpc.ClientStream
+}
+
+func (x *deviceSyncWatchDeviceMetadataClient) Recv() (*DeviceMetadataEvent, error) {
+	m := new(DeviceMetadataEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *deviceSyncClient) WatchSecurityGroups(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (DeviceSync_WatchSecurityGroupsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DeviceSync_ServiceDesc.Streams[2], DeviceSync_WatchSecurityGroups_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &deviceSyncWatchSecurityGroupsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DeviceSync_WatchSecurityGroupsClient interface {
+	Recv() (*SecurityGroupEvent, error)
+	grpc.ClientStream
+}
+
+type deviceSyncWatchSecurityGroupsClient struct {
+	grpc.ClientStream
+}
+
+func (x *deviceSyncWatchSecurityGroupsClient) Recv() (*SecurityGroupEvent, error) {
+	m := new(SecurityGroupEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DeviceSyncServer is the server API for DeviceSync service.
+// All implementations must embed UnimplementedDeviceSyncServer
+// for forward compatibility
+type DeviceSyncServer interface {
+	// WatchDevices streams the changes to the devices of an organization.
+	WatchDevices(*WatchRequest, DeviceSync_WatchDevicesServer) error
+	// WatchDeviceMetadata streams the changes to the metadata of the devices of an organization.
+	WatchDeviceMetadata(*WatchDeviceMetadataRequest, DeviceSync_WatchDeviceMetadataServer) error
+	// WatchSecurityGroups streams the changes to the security groups of an organization.
+	WatchSecurityGroups(*WatchRequest, DeviceSync_WatchSecurityGroupsServer) error
+	mustEmbedUnimplementedDeviceSyncServer()
+}
+
+// UnimplementedDeviceSyncServer must be embedded to have forward compatible implementations.
+type UnimplementedDeviceSyncServer struct {
+}
+
+func (UnimplementedDeviceSyncServer) WatchDevices(*WatchRequest, DeviceSync_WatchDevicesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDevices not implemented")
+}
+func (UnimplementedDeviceSyncServer) WatchDeviceMetadata(*WatchDeviceMetadataRequest, DeviceSync_WatchDeviceMetadataServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDeviceMetadata not implemented")
+}
+func (UnimplementedDeviceSyncServer) WatchSecurityGroups(*WatchRequest, DeviceSync_WatchSecurityGroupsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchSecurityGroups not implemented")
+}
+func (UnimplementedDeviceSyncServer) mustEmbedUnimplementedDeviceSyncServer() {}
+
+// UnsafeDeviceSyncServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DeviceSyncServer will
+// result in compilation errors.
+type UnsafeDeviceSyncServer interface {
+	mustEmbedUnimplementedDeviceSyncServer()
+}
+
+func RegisterDeviceSyncServer(s grpc.ServiceRegistrar, srv DeviceSyncServer) {
+	s.RegisterService(&DeviceSync_ServiceDesc, srv)
+}
+
+func _DeviceSync_WatchDevices_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DeviceSyncServer).WatchDevices(m, &deviceSyncWatchDevicesServer{stream})
+}
+
+type DeviceSync_WatchDevicesServer interface {
+	Send(*DeviceEvent) error
+	grpc.ServerStream
+}
+
+type deviceSyncWatchDevicesServer struct {
+	grpc.ServerStream
+}
+
+func (x *deviceSyncWatchDevicesServer) Send(m *DeviceEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DeviceSync_WatchDeviceMetadata_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchDeviceMetadataRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DeviceSyncServer).WatchDeviceMetadata(m, &deviceSyncWatchDeviceMetadataServer{stream})
+}
+
+type DeviceSync_WatchDeviceMetadataServer interface {
+	Send(*DeviceMetadataEvent) error
+	grpc.ServerStream
+}
+
+type deviceSyncWatchDeviceMetadataServer struct {
+	grpc.ServerStream
+}
+
+func (x *deviceSyncWatchDeviceMetadataServer) Send(m *DeviceMetadataEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DeviceSync_WatchSecurityGroups_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DeviceSyncServer).WatchSecurityGroups(m, &deviceSyncWatchSecurityGroupsServer{stream})
+}
+
+type DeviceSync_WatchSecurityGroupsServer interface {
+	Send(*SecurityGroupEvent) error
+	grpc.ServerStream
+}
+
+type deviceSyncWatchSecurityGroupsServer struct {
+	grpc.ServerStream
+}
+
+func (x *deviceSyncWatchSecurityGroupsServer) Send(m *SecurityGroupEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// DeviceSync_ServiceDesc is the grpc.ServiceDesc for DeviceSync service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DeviceSync_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "nexodus.sync.v1.DeviceSync",
+	HandlerType: (*DeviceSyncServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchDevices",
+			Handler:       _DeviceSync_WatchDevices_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchDeviceMetadata",
+			Handler:       _DeviceSync_WatchDeviceMetadata_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchSecurityGroups",
+			Handler:       _DeviceSync_WatchSecurityGroups_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "internal/api/syncv1/sync.proto",
+}
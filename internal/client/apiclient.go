@@ -45,29 +45,48 @@ func NewAPIClient(ctx context.Context, addr string, authcb func(string), options
 	clientConfig.Host = baseURL.Host
 	clientConfig.Scheme = baseURL.Scheme
 
+	if opts.bearerToken != "" {
+		source := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: opts.bearerToken})
+		clientConfig.HTTPClient = oauth2.NewClient(ctx, source)
+		return public.NewAPIClient(clientConfig), nil
+	}
+
 	apiClient := public.NewAPIClient(clientConfig)
 
-	resp, _, err := apiClient.AuthApi.DeviceStart(ctx).Execute()
-	if err != nil {
-		return nil, err
+	deviceStart := apiClient.AuthApi.DeviceStart(ctx)
+	if opts.identityProvider != "" {
+		deviceStart = deviceStart.Provider(opts.identityProvider)
 	}
-
-	provider, err := oidc.NewProvider(ctx, resp.Issuer)
+	resp, _, err := deviceStart.Execute()
 	if err != nil {
 		return nil, err
 	}
 
-	oidcConfig := &oidc.Config{
-		ClientID: resp.ClientId,
+	scopes := resp.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email", "offline_access", "read:organizations", "write:organizations", "read:users", "write:users", "read:devices", "write:devices"}
 	}
-
-	verifier := provider.Verifier(oidcConfig)
-
 	config := &oauth2.Config{
 		ClientID:     resp.ClientId,
 		ClientSecret: opts.clientSecret,
-		Endpoint:     provider.Endpoint(),
-		Scopes:       []string{"openid", "profile", "email", "offline_access", "read:organizations", "write:organizations", "read:users", "write:users", "read:devices", "write:devices"},
+		Endpoint: oauth2.Endpoint{
+			TokenURL: resp.TokenEndpoint,
+		},
+		Scopes: scopes,
+	}
+
+	// plain oauth2 providers have no issuer to discover the endpoints from, and don't issue
+	// id tokens to verify.
+	var verifier *oidc.IDTokenVerifier
+	if resp.Issuer != "" {
+		provider, err := oidc.NewProvider(ctx, resp.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		verifier = provider.Verifier(&oidc.Config{
+			ClientID: resp.ClientId,
+		})
+		config.Endpoint = provider.Endpoint()
 	}
 
 	var token *oauth2.Token
@@ -78,10 +97,12 @@ func NewAPIClient(ctx context.Context, addr string, authcb func(string), options
 	}
 	if token == nil {
 		if opts.deviceFlow {
-			token, rawIdToken, err = newDeviceFlowToken(ctx, resp.DeviceAuthorizationEndpoint, provider.Endpoint().TokenURL, resp.ClientId, authcb)
+			token, rawIdToken, err = newDeviceFlowToken(ctx, resp.DeviceAuthorizationEndpoint, config.Endpoint.TokenURL, resp.ClientId, scopes, authcb)
 			if err != nil {
 				return nil, err
 			}
+		} else if verifier == nil {
+			return nil, fmt.Errorf("the %s identity provider only supports the device flow", resp.Provider)
 		} else if opts.username != "" && opts.password != "" {
 			token, err = config.PasswordCredentialsToken(ctx, opts.username, opts.password)
 			if err != nil {
@@ -91,11 +112,13 @@ func NewAPIClient(ctx context.Context, addr string, authcb func(string), options
 		} else {
 			return nil, fmt.Errorf("no authentication method provided")
 		}
-		if rawIdToken == nil {
-			return nil, fmt.Errorf("no id_token in response")
-		}
-		if _, err = verifier.Verify(ctx, rawIdToken.(string)); err != nil {
-			return nil, err
+		if verifier != nil {
+			if rawIdToken == nil {
+				return nil, fmt.Errorf("no id_token in response")
+			}
+			if _, err = verifier.Verify(ctx, rawIdToken.(string)); err != nil {
+				return nil, err
+			}
 		}
 
 		if opts.tokenStore != nil {
@@ -113,6 +136,12 @@ func NewAPIClient(ctx context.Context, addr string, authcb func(string), options
 			source:     source,
 		})
 	}
+	if resp.TokenPrefix != "" {
+		source = &prefixedTokenSource{
+			prefix: resp.TokenPrefix,
+			source: source,
+		}
+	}
 
 	clientConfig.HTTPClient = oauth2.NewClient(ctx, source)
 	return public.NewAPIClient(clientConfig), nil
@@ -140,3 +169,22 @@ func (s *storeOnChangeSource) Token() (*oauth2.Token, error) {
 	}
 	return next, nil
 }
+
+// prefixedTokenSource prepends the prefix that the api server expects to the access tokens
+// of the provider.
+type prefixedTokenSource struct {
+	prefix string
+	source oauth2.TokenSource
+}
+
+var _ oauth2.TokenSource = &prefixedTokenSource{}
+
+func (s *prefixedTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	prefixed := *token
+	prefixed.AccessToken = s.prefix + token.AccessToken
+	return &prefixed, nil
+}
@@ -86,6 +86,68 @@ func TestWithTokenFileOption(t *testing.T) {
 	assert.NotEqual(*originalToken, *nextToken)
 }
 
+func TestOAuth2DeviceFlow(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	mockRouter := http.NewServeMux()
+	mockServer := httptest.NewServer(mockRouter)
+	defer mockServer.Close()
+
+	mockRouter.HandleFunc("/device/login/start", func(resp http.ResponseWriter, req *http.Request) {
+		assert.Equal("github", req.URL.Query().Get("provider"))
+		sendJson(resp, http.StatusOK, models.DeviceStartResponse{
+			ClientID:      "github-client",
+			DeviceAuthURL: mockServer.URL + "/login/device/code",
+			TokenEndpoint: mockServer.URL + "/login/oauth/access_token",
+			Scopes:        []string{"read:user", "user:email"},
+			TokenPrefix:   "OA:github:",
+			Provider:      "github",
+		})
+	})
+	mockRouter.HandleFunc("/login/device/code", func(resp http.ResponseWriter, req *http.Request) {
+		assert.Equal("application/json", req.Header.Get("Accept"))
+		assert.Equal("read:user user:email", req.FormValue("scope"))
+		sendJson(resp, http.StatusOK, map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-1234",
+			"verification_uri": "https://github.com/login/device",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	polls := int64(0)
+	mockRouter.HandleFunc("/login/oauth/access_token", func(resp http.ResponseWriter, req *http.Request) {
+		// github reports the pending authorization with a 200 status, and its tokens don't expire
+		if atomic.AddInt64(&polls, 1) == 1 {
+			sendJson(resp, http.StatusOK, map[string]interface{}{"error": "authorization_pending"})
+			return
+		}
+		sendJson(resp, http.StatusOK, map[string]interface{}{
+			"access_token": "gho_token",
+			"token_type":   "bearer",
+			"scope":        "read:user,user:email",
+		})
+	})
+	mockRouter.HandleFunc("/api/users/me", func(resp http.ResponseWriter, req *http.Request) {
+		assert.Equal("Bearer OA:github:gho_token", req.Header.Get("Authorization"))
+		sendJson(resp, http.StatusOK, "{}")
+	})
+
+	store := &testTokenStore{}
+	c, err := client.NewAPIClient(context.Background(), mockServer.URL, nil,
+		client.WithDeviceFlow(),
+		client.WithIdentityProvider("github"),
+		client.WithTokenStore(store),
+	)
+	require.NoError(err)
+	assert.Equal(int64(2), polls)
+	assert.Equal("gho_token", store.token.AccessToken, "the token is stored without the prefix")
+
+	_, _, err = c.UsersApi.GetUser(context.Background(), "me").Execute()
+	require.NoError(err)
+}
+
 func sendJson(resp http.ResponseWriter, status int, body interface{}) {
 	resp.Header().Add("Content-Type", "application/json")
 	resp.WriteHeader(status)
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -21,17 +22,22 @@ type deviceFlowResponse struct {
 	Interval                int    `json:"interval"`
 }
 
-func newDeviceFlowToken(ctx context.Context, deviceEndpoint, tokenEndpoint, clientID string, authcb func(string)) (*oauth2.Token, interface{}, error) {
+func newDeviceFlowToken(ctx context.Context, deviceEndpoint, tokenEndpoint, clientID string, scopes []string, authcb func(string)) (*oauth2.Token, interface{}, error) {
 	requestTime := time.Now()
-	d, err := startDeviceFlow(deviceEndpoint, clientID)
+	d, err := startDeviceFlow(deviceEndpoint, clientID, scopes)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// some providers, like github, only return the uri where the user enters the code
+	verificationURI := d.VerificationURIComplete
+	if verificationURI == "" {
+		verificationURI = d.VerificationURI
+	}
 	msg := fmt.Sprintf("Your device must be registered with Nexodus.\n"+
 		"Your one-time code is: %s\n"+
 		"Please open the following URL in your browser to sign in:\n%s\n",
-		d.UserCode, d.VerificationURIComplete)
+		d.UserCode, verificationURI)
 	fmt.Print(msg)
 	if authcb != nil {
 		authcb(msg)
@@ -57,12 +63,11 @@ func newDeviceFlowToken(ctx context.Context, deviceEndpoint, tokenEndpoint, clie
 	return token, idToken, nil
 }
 
-func startDeviceFlow(deviceEndpoint string, clientID string) (*deviceFlowResponse, error) {
+func startDeviceFlow(deviceEndpoint string, clientID string, scopes []string) (*deviceFlowResponse, error) {
 	v := url.Values{}
 	v.Set("client_id", clientID)
-	v.Set("scope", "openid profile email offline_access read:organizations write:organizations read:users write:users read:devices write:devices")
-	// #nosec -- G107: Potential HTTP request made with variable url (gosec)
-	res, err := http.PostForm(deviceEndpoint, v)
+	v.Set("scope", strings.Join(scopes, " "))
+	res, err := postForm(deviceEndpoint, v)
 	if err != nil {
 		return nil, err
 	}
@@ -111,8 +116,7 @@ func pollForResponse(ctx context.Context, clientID string, tokenURL string, t *d
 			return nil, nil, ctx.Err()
 		case <-ticker.C:
 			requestTime := time.Now()
-			// #nosec -- G107: Potential HTTP request made with variable url (gosec)
-			res, err := http.PostForm(tokenURL, v)
+			res, err := postForm(tokenURL, v)
 			if err != nil {
 				// possible transient connection error, continue retrying
 				continue
@@ -123,27 +127,26 @@ func pollForResponse(ctx context.Context, clientID string, tokenURL string, t *d
 				// possible transient connection error, continue retrying
 				continue
 			}
-			if res.StatusCode != http.StatusOK {
-				type errorResponse struct {
-					Error string `json:"error"`
-				}
-				var r errorResponse
-				if err := json.Unmarshal(body, &r); err != nil {
-					return nil, "", err
-				}
-				if r.Error != "" {
-					if r.Error == errSlowDown {
-						// adjust interval and continue retrying
-						interval += 5
-						ticker.Reset(time.Duration(interval) * time.Second)
-						continue
-					} else if r.Error == errAccessDenied || r.Error == errExpiredToken {
-						return nil, nil, fmt.Errorf("failed to get token: %s", r.Error)
-					}
-					// error was either authorization_pending or something else
-					// continue to poll for a token
+			// some providers, like github, report the errors with a 200 status
+			type errorResponse struct {
+				Error string `json:"error"`
+			}
+			var r errorResponse
+			if err := json.Unmarshal(body, &r); err != nil && res.StatusCode != http.StatusOK {
+				return nil, "", err
+			}
+			if r.Error != "" {
+				if r.Error == errSlowDown {
+					// adjust interval and continue retrying
+					interval += 5
+					ticker.Reset(time.Duration(interval) * time.Second)
 					continue
+				} else if r.Error == errAccessDenied || r.Error == errExpiredToken {
+					return nil, nil, fmt.Errorf("failed to get token: %s", r.Error)
 				}
+				// error was either authorization_pending or something else
+				// continue to poll for a token
+				continue
 			}
 			// This will only give us AccessToken, RefreshToken and TokenType
 			if err := json.Unmarshal(body, &token); err != nil {
@@ -157,7 +160,8 @@ func pollForResponse(ctx context.Context, clientID string, tokenURL string, t *d
 
 			expiresRaw, ok := tokenRaw["expires_in"]
 			if !ok {
-				return nil, "", fmt.Errorf("expires_in is not contained in the access token")
+				// the tokens of some oauth2 providers, like github, don't expire
+				return &token, tokenRaw["id_token"], nil
 			}
 
 			expires, ok := expiresRaw.(float64)
@@ -174,3 +178,16 @@ func pollForResponse(ctx context.Context, clientID string, tokenURL string, t *d
 		}
 	}
 }
+
+// postForm posts the form asking for a json response, which some providers, like github,
+// only return when asked.
+func postForm(endpoint string, v url.Values) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// #nosec -- G107: Potential HTTP request made with variable url (gosec)
+	return http.DefaultClient.Do(req)
+}
@@ -0,0 +1,314 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/nexodus-io/nexodus/internal/api/syncv1"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// DeviceSyncClient watches the devices, device metadata and security groups of an organization
+// with the gRPC DeviceSync service of the apiserver. Its informers can be used in place of the
+// informers of the http watch apis.
+type DeviceSyncClient struct {
+	conn   *grpc.ClientConn
+	client syncv1.DeviceSyncClient
+}
+
+// NewDeviceSyncClient connects to the DeviceSync service at grpcURL, a http:// URL uses an
+// unencrypted connection. The calls are authenticated with the token used by apiClient.
+func NewDeviceSyncClient(apiClient *APIClient, grpcURL string, tlsConfig *tls.Config) (*DeviceSyncClient, error) {
+	u, err := url.Parse(grpcURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var transportCredentials credentials.TransportCredentials
+	port := u.Port()
+	switch u.Scheme {
+	case "http":
+		transportCredentials = insecure.NewCredentials()
+		if port == "" {
+			port = "80"
+		}
+	case "https":
+		transportCredentials = credentials.NewTLS(tlsConfig)
+		if port == "" {
+			port = "443"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported grpc url scheme: %s", u.Scheme)
+	}
+
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCredentials),
+	}
+	if transport, ok := apiClient.GetConfig().HTTPClient.Transport.(*oauth2.Transport); ok {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(&tokenCredentials{
+			source: transport.Source,
+			secure: u.Scheme == "https",
+		}))
+	}
+
+	conn, err := grpc.Dial(net.JoinHostPort(u.Hostname(), port), dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return &DeviceSyncClient{
+		conn:   conn,
+		client: syncv1.NewDeviceSyncClient(conn),
+	}, nil
+}
+
+func (c *DeviceSyncClient) Close() error {
+	return c.conn.Close()
+}
+
+// DevicesInformer returns an informer of the devices of the organization, keyed by public key.
+func (c *DeviceSyncClient) DevicesInformer(ctx context.Context, orgId string) *public.ApiListDevicesInOrganizationInformer {
+	return &public.ApiListDevicesInOrganizationInformer{
+		WatchInformer: public.NewWatchInformer(ctx, func(gtRevision int32) (public.WatchEventStream[public.ModelsDevice], *http.Response, error) {
+			return watchGrpc(ctx, func(ctx context.Context) (grpcEventStream[*syncv1.DeviceEvent], error) {
+				return c.client.WatchDevices(ctx, &syncv1.WatchRequest{
+					OrganizationId: orgId,
+					GtRevision:     uint64(gtRevision),
+				})
+			}, deviceFromProto)
+		}, func(item public.ModelsDevice) string {
+			return item.Id
+		}, func(item public.ModelsDevice) string {
+			return item.PublicKey
+		}, func(item public.ModelsDevice) int32 {
+			return item.Revision
+		}),
+	}
+}
+
+// MetadataInformer returns an informer of the metadata of the devices of the organization, limited
+// to the keys that start with one of the prefixes.
+func (c *DeviceSyncClient) MetadataInformer(ctx context.Context, orgId string, prefixes []string) *public.ApiListOrganizationMetadataInformer {
+	return &public.ApiListOrganizationMetadataInformer{
+		WatchInformer: public.NewWatchInformer(ctx, func(gtRevision int32) (public.WatchEventStream[public.ModelsDeviceMetadata], *http.Response, error) {
+			return watchGrpc(ctx, func(ctx context.Context) (grpcEventStream[*syncv1.DeviceMetadataEvent], error) {
+				return c.client.WatchDeviceMetadata(ctx, &syncv1.WatchDeviceMetadataRequest{
+					OrganizationId: orgId,
+					GtRevision:     uint64(gtRevision),
+					Prefixes:       prefixes,
+				})
+			}, deviceMetadataFromProto)
+		}, func(item public.ModelsDeviceMetadata) string {
+			return item.DeviceId + "/" + item.Key
+		}, func(item public.ModelsDeviceMetadata) public.ModelsDeviceMetadataKey {
+			return public.ModelsDeviceMetadataKey{
+				DeviceId: item.DeviceId,
+				Key:      item.Key,
+			}
+		}, func(item public.ModelsDeviceMetadata) int32 {
+			return item.Revision
+		}),
+	}
+}
+
+// SecurityGroupsInformer returns an informer of the security groups of the organization, keyed by id.
+func (c *DeviceSyncClient) SecurityGroupsInformer(ctx context.Context, orgId string) *public.ApiListSecurityGroupsInformer {
+	return &public.ApiListSecurityGroupsInformer{
+		WatchInformer: public.NewWatchInformer(ctx, func(gtRevision int32) (public.WatchEventStream[public.ModelsSecurityGroup], *http.Response, error) {
+			return watchGrpc(ctx, func(ctx context.Context) (grpcEventStream[*syncv1.SecurityGroupEvent], error) {
+				return c.client.WatchSecurityGroups(ctx, &syncv1.WatchRequest{
+					OrganizationId: orgId,
+					GtRevision:     uint64(gtRevision),
+				})
+			}, securityGroupFromProto)
+		}, func(item public.ModelsSecurityGroup) string {
+			return item.Id
+		}, func(item public.ModelsSecurityGroup) string {
+			return item.Id
+		}, func(item public.ModelsSecurityGroup) int32 {
+			return item.Revision
+		}),
+	}
+}
+
+type grpcEventStream[E any] interface {
+	Recv() (E, error)
+}
+
+// grpcWatchStream adapts a DeviceSync event stream to a public.WatchEventStream.
+type grpcWatchStream[E any, T any] struct {
+	stream  grpcEventStream[E]
+	convert func(E) (syncv1.EventType, T)
+	cancel  context.CancelFunc
+	first   *E
+}
+
+func watchGrpc[E any, T any](ctx context.Context, call func(ctx context.Context) (grpcEventStream[E], error), convert func(E) (syncv1.EventType, T)) (public.WatchEventStream[T], *http.Response, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := call(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, grpcWatchError(err)
+	}
+	// the server reports errors, such as a compacted revision, before the first event.
+	first, err := stream.Recv()
+	if err != nil {
+		cancel()
+		return nil, nil, grpcWatchError(err)
+	}
+	return &grpcWatchStream[E, T]{
+		stream:  stream,
+		convert: convert,
+		cancel:  cancel,
+		first:   &first,
+	}, nil, nil
+}
+
+func (s *grpcWatchStream[E, T]) Receive() (string, T, error) {
+	var event E
+	if s.first != nil {
+		event = *s.first
+		s.first = nil
+	} else {
+		var err error
+		event, err = s.stream.Recv()
+		if err != nil {
+			var item T
+			return "", item, grpcWatchError(err)
+		}
+	}
+	eventType, item := s.convert(event)
+	switch eventType {
+	case syncv1.EventType_EVENT_TYPE_CHANGE:
+		return "change", item, nil
+	case syncv1.EventType_EVENT_TYPE_DELETE:
+		return "delete", item, nil
+	case syncv1.EventType_EVENT_TYPE_BOOKMARK:
+		return "bookmark", item, nil
+	}
+	return eventType.String(), item, nil
+}
+
+func (s *grpcWatchStream[E, T]) Close() error {
+	s.cancel()
+	return nil
+}
+
+func grpcWatchError(err error) error {
+	if status.Code(err) == codes.OutOfRange {
+		return fmt.Errorf("%w: %s", public.ErrResyncRequired, status.Convert(err).Message())
+	}
+	return err
+}
+
+// tokenCredentials adds the oauth2 token to the metadata of the gRPC calls.
+type tokenCredentials struct {
+	source oauth2.TokenSource
+	secure bool
+}
+
+func (t *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := t.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"authorization": token.Type() + " " + token.AccessToken,
+	}, nil
+}
+
+func (t *tokenCredentials) RequireTransportSecurity() bool {
+	return t.secure
+}
+
+func deviceFromProto(event *syncv1.DeviceEvent) (syncv1.EventType, public.ModelsDevice) {
+	device := event.Device
+	if device == nil {
+		return event.Type, public.ModelsDevice{Revision: int32(event.Revision)}
+	}
+	endpoints := make([]public.ModelsEndpoint, 0, len(device.Endpoints))
+	for _, endpoint := range device.Endpoints {
+		endpoints = append(endpoints, public.ModelsEndpoint{
+			Source:   endpoint.Source,
+			Address:  endpoint.Address,
+			Distance: int32(endpoint.Distance),
+		})
+	}
+	return event.Type, public.ModelsDevice{
+		Id:                      device.Id,
+		UserId:                  device.UserId,
+		OrganizationId:          device.OrganizationId,
+		PublicKey:               device.PublicKey,
+		AllowedIps:              device.AllowedIps,
+		TunnelIp:                device.TunnelIp,
+		TunnelIpV6:              device.TunnelIpV6,
+		ChildPrefix:             device.ChildPrefix,
+		Relay:                   device.Relay,
+		Discovery:               device.Discovery,
+		OrganizationPrefix:      device.OrganizationPrefix,
+		OrganizationPrefixV6:    device.OrganizationPrefixV6,
+		EndpointLocalAddressIp4: device.EndpointLocalAddressIp4,
+		SymmetricNat:            device.SymmetricNat,
+		Hostname:                device.Hostname,
+		Os:                      device.Os,
+		Endpoints:               endpoints,
+		Revision:                int32(device.Revision),
+		SecurityGroupId:         device.SecurityGroupId,
+		Ephemeral:               device.Ephemeral,
+		Labels:                  device.Labels,
+	}
+}
+
+func deviceMetadataFromProto(event *syncv1.DeviceMetadataEvent) (syncv1.EventType, public.ModelsDeviceMetadata) {
+	metadata := event.Metadata
+	if metadata == nil {
+		return event.Type, public.ModelsDeviceMetadata{Revision: int32(event.Revision)}
+	}
+	result := public.ModelsDeviceMetadata{
+		DeviceId: metadata.DeviceId,
+		Key:      metadata.Key,
+		Revision: int32(metadata.Revision),
+	}
+	// like the http api client, only object values are decoded.
+	_ = json.Unmarshal(metadata.Value, &result.Value)
+	return event.Type, result
+}
+
+func securityGroupFromProto(event *syncv1.SecurityGroupEvent) (syncv1.EventType, public.ModelsSecurityGroup) {
+	group := event.SecurityGroup
+	if group == nil {
+		return event.Type, public.ModelsSecurityGroup{Revision: int32(event.Revision)}
+	}
+	return event.Type, public.ModelsSecurityGroup{
+		Id:               group.Id,
+		GroupName:        group.GroupName,
+		GroupDescription: group.GroupDescription,
+		OrgId:            group.OrganizationId,
+		InboundRules:     securityRulesFromProto(group.InboundRules),
+		OutboundRules:    securityRulesFromProto(group.OutboundRules),
+		Revision:         int32(group.Revision),
+	}
+}
+
+func securityRulesFromProto(rules []*syncv1.SecurityRule) []public.ModelsSecurityRule {
+	result := make([]public.ModelsSecurityRule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, public.ModelsSecurityRule{
+			IpProtocol: rule.IpProtocol,
+			FromPort:   int32(rule.FromPort),
+			ToPort:     int32(rule.ToPort),
+			IpRanges:   rule.IpRanges,
+		})
+	}
+	return result
+}
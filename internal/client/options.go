@@ -6,12 +6,14 @@ import (
 )
 
 type options struct {
-	deviceFlow   bool
-	clientSecret string
-	username     string
-	password     string
-	tokenStore   TokenStore
-	tlsConfig    *tls.Config
+	deviceFlow       bool
+	clientSecret     string
+	username         string
+	password         string
+	bearerToken      string
+	tokenStore       TokenStore
+	tlsConfig        *tls.Config
+	identityProvider string
 }
 
 type TokenStore interface {
@@ -67,3 +69,25 @@ func WithTokenStore(
 		return nil
 	}
 }
+
+// WithBearerToken authenticates with a pre-issued token, such as a registration key,
+// instead of performing an OIDC login.
+func WithBearerToken(
+	token string,
+) Option {
+	return func(o *options) error {
+		o.bearerToken = token
+		return nil
+	}
+}
+
+// WithIdentityProvider logs in with the named identity provider of the api server instead
+// of its default one.
+func WithIdentityProvider(
+	name string,
+) Option {
+	return func(o *options) error {
+		o.identityProvider = name
+		return nil
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230113_0000"
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230126_0000"
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230314_0000"
@@ -21,6 +22,24 @@ import (
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230428_0000"
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230509_0000"
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230610_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230620_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230621_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230622_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230623_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230624_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230625_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230626_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230627_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230628_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230629_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230630_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230701_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230702_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230703_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230704_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230705_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230706_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230707_0000"
 	"github.com/nexodus-io/nexodus/internal/database/migrations"
 	"github.com/uptrace/opentelemetry-go-extra/otelgorm"
 	"go.opentelemetry.io/otel"
@@ -73,6 +92,7 @@ func NewDatabase(
 	return db, dsn, nil
 }
 
+// NewTestDatabase returns a new in-memory database, that is not shared with the other test databases.
 func NewTestDatabase() (*gorm.DB, error) {
 	logger, err := zap.NewDevelopment()
 	if err != nil {
@@ -82,7 +102,7 @@ func NewTestDatabase() (*gorm.DB, error) {
 	config := &gorm.Config{
 		Logger: gormLogger,
 	}
-	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), config)
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", uuid.NewString())), config)
 	if err != nil {
 		return nil, err
 	}
@@ -118,6 +138,24 @@ func Migrations() *migrations.Migrations {
 			migration_20230428_0000.Migrate(),
 			migration_20230509_0000.Migrate(),
 			migration_20230610_0000.Migrate(),
+			migration_20230620_0000.Migrate(),
+			migration_20230621_0000.Migrate(),
+			migration_20230622_0000.Migrate(),
+			migration_20230623_0000.Migrate(),
+			migration_20230624_0000.Migrate(),
+			migration_20230625_0000.Migrate(),
+			migration_20230626_0000.Migrate(),
+			migration_20230627_0000.Migrate(),
+			migration_20230628_0000.Migrate(),
+			migration_20230629_0000.Migrate(),
+			migration_20230630_0000.Migrate(),
+			migration_20230701_0000.Migrate(),
+			migration_20230702_0000.Migrate(),
+			migration_20230703_0000.Migrate(),
+			migration_20230704_0000.Migrate(),
+			migration_20230705_0000.Migrate(),
+			migration_20230706_0000.Migrate(),
+			migration_20230707_0000.Migrate(),
 		},
 	}
 }
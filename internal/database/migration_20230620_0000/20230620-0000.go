@@ -0,0 +1,36 @@
+package migration_20230620_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+type RegKey struct {
+	models.Base
+	OwnerID        string
+	OrganizationID uuid.UUID
+	Description    string
+	TokenHash      string `gorm:"uniqueIndex"`
+	SingleUse      bool
+	DeviceID       uuid.UUID
+	Ephemeral      bool
+	Tags           pq.StringArray `gorm:"type:text[]"`
+	ExpiresAt      *time.Time
+}
+
+type Device struct {
+	Ephemeral bool
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230620-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&RegKey{}),
+		AddTableColumnsAction(&Device{}),
+	)
+}
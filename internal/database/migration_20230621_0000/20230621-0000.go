@@ -0,0 +1,36 @@
+package migration_20230621_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+type ServiceAccount struct {
+	models.Base
+	OrganizationID uuid.UUID `gorm:"index"`
+	Name           string
+	Description    string
+}
+
+type ApiToken struct {
+	models.Base
+	ServiceAccountID uuid.UUID `gorm:"index"`
+	Description      string
+	TokenHash        string         `gorm:"uniqueIndex"`
+	Scopes           pq.StringArray `gorm:"type:text[]"`
+	ExpiresAt        *time.Time
+	LastUsedAt       *time.Time
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230621-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&ServiceAccount{}),
+		CreateTableAction(&ApiToken{}),
+	)
+}
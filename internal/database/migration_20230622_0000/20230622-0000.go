@@ -0,0 +1,25 @@
+package migration_20230622_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type UserOrganization struct {
+	Role string `gorm:"default:member"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230622-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&UserOrganization{}),
+		// organization owners get the owner role on their membership
+		ExecAction(`
+			UPDATE user_organizations SET role='owner'
+			WHERE EXISTS (
+				SELECT 1 FROM organizations
+				WHERE organizations.id=user_organizations.organization_id AND organizations.owner_id=user_organizations.user_id
+			);
+			`, ``),
+	)
+}
@@ -0,0 +1,39 @@
+package migration_20230623_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type AuditEvent struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key"`
+	CreatedAt      time.Time `gorm:"index"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;index"`
+	ActorID        string
+	ActorName      string
+	Action         string
+	ResourceType   string
+	ResourceID     string
+	Before         interface{} `gorm:"type:JSONB; serializer:json"`
+	After          interface{} `gorm:"type:JSONB; serializer:json"`
+	SourceIP       string
+	RequestID      string
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230623-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&AuditEvent{}),
+		// the audit log is append only
+		ExecActionIf(`
+			CREATE RULE audit_events_no_update AS ON UPDATE TO audit_events DO INSTEAD NOTHING;
+			CREATE RULE audit_events_no_delete AS ON DELETE TO audit_events DO INSTEAD NOTHING;
+			`, `
+			DROP RULE IF EXISTS audit_events_no_update ON audit_events;
+			DROP RULE IF EXISTS audit_events_no_delete ON audit_events;
+			`, NotOnSqlLite),
+	)
+}
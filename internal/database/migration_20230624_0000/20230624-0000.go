@@ -0,0 +1,41 @@
+package migration_20230624_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+type Webhook struct {
+	models.Base
+	OrganizationID uuid.UUID `gorm:"index"`
+	URL            string
+	Description    string
+	Events         pq.StringArray `gorm:"type:text[]"`
+	Secret         string
+}
+
+type WebhookDelivery struct {
+	models.Base
+	WebhookID     uuid.UUID `gorm:"type:uuid;index"`
+	Event         string
+	Payload       string
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+	ResponseCode  int
+	LastError     string
+	DeliveredAt   *time.Time
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230624-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&Webhook{}),
+		CreateTableAction(&WebhookDelivery{}),
+	)
+}
@@ -0,0 +1,60 @@
+package migration_20230625_0000
+
+import (
+	"fmt"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type SecurityGroup struct {
+	Revision uint64 `gorm:"type:bigserial;index:"`
+}
+
+type Organization struct {
+	Revision uint64 `gorm:"type:bigserial;index:"`
+}
+
+type User struct {
+	Revision uint64 `gorm:"type:bigserial;index:"`
+}
+
+type Invitation struct {
+	Revision uint64 `gorm:"type:bigserial;index:"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230625-0000"
+	actions := []MigrationAction{
+		AddTableColumnsAction(&SecurityGroup{}),
+		AddTableColumnsAction(&Organization{}),
+		AddTableColumnsAction(&User{}),
+		AddTableColumnsAction(&Invitation{}),
+	}
+	for _, table := range []string{"security_groups", "organizations", "users", "invitations"} {
+		actions = append(actions, revisionTriggerActions(table)...)
+	}
+	return CreateMigrationFromActions(migrationId, actions...)
+}
+
+// revisionTriggerActions bumps the revision of a row on every insert and update, the same way
+// it is done for devices, so that the rows can be watched.
+func revisionTriggerActions(table string) []MigrationAction {
+	return []MigrationAction{
+		ExecActionIf(fmt.Sprintf(`
+			CREATE OR REPLACE FUNCTION %[1]s_revision_trigger() RETURNS TRIGGER LANGUAGE plpgsql AS '
+			BEGIN
+			NEW.revision := nextval(''%[1]s_revision_seq'');
+			RETURN NEW;
+			END;'
+		`, table), fmt.Sprintf(`
+			DROP FUNCTION IF EXISTS %s_revision_trigger
+		`, table), NotOnSqlLite),
+		ExecActionIf(fmt.Sprintf(`
+			CREATE OR REPLACE TRIGGER %[1]s_revision_trigger BEFORE INSERT OR UPDATE ON %[1]s
+			FOR EACH ROW EXECUTE PROCEDURE %[1]s_revision_trigger();
+		`, table), fmt.Sprintf(`
+			DROP TRIGGER IF EXISTS %[1]s_revision_trigger ON %[1]s
+		`, table), NotOnSqlLite),
+	}
+}
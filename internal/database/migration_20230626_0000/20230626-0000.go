@@ -0,0 +1,21 @@
+package migration_20230626_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type CompactedRevision struct {
+	Name      string `gorm:"primary_key"`
+	Revision  uint64
+	UpdatedAt time.Time
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230626-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&CompactedRevision{}),
+	)
+}
@@ -0,0 +1,25 @@
+package migration_20230627_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type Device struct {
+	Labels map[string]string `gorm:"type:JSONB; serializer:json"`
+}
+
+type DeviceLabel struct {
+	DeviceID uuid.UUID `gorm:"type:uuid;primary_key"`
+	Key      string    `gorm:"primary_key;index:idx_device_labels_key_value,priority:1"`
+	Value    string    `gorm:"index:idx_device_labels_key_value,priority:2"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230627-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&Device{}),
+		CreateTableAction(&DeviceLabel{}),
+	)
+}
@@ -0,0 +1,24 @@
+package migration_20230628_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+type OrganizationPeering struct {
+	models.Base
+	OrganizationID     uuid.UUID `gorm:"type:uuid;index"`
+	Selector           string
+	PeerOrganizationID uuid.UUID `gorm:"type:uuid;index"`
+	PeerSelector       string
+	Status             string
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230628-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&OrganizationPeering{}),
+	)
+}
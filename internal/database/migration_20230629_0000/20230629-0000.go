@@ -0,0 +1,26 @@
+package migration_20230629_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type IpamPrefix struct {
+	Namespace uuid.UUID `gorm:"type:uuid;primary_key"`
+	Cidr      string    `gorm:"primary_key"`
+}
+
+type IpamAddress struct {
+	Namespace uuid.UUID `gorm:"type:uuid;primary_key"`
+	IP        string    `gorm:"primary_key"`
+	Prefix    string    `gorm:"index"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230629-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&IpamPrefix{}),
+		CreateTableAction(&IpamAddress{}),
+	)
+}
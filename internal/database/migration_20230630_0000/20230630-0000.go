@@ -0,0 +1,27 @@
+package migration_20230630_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+type OrganizationRenumbering struct {
+	models.Base
+	OrganizationID   uuid.UUID `gorm:"type:uuid;index"`
+	PreviousCidr     string
+	PreviousCidrV6   string
+	Cidr             string
+	CidrV6           string
+	BatchSize        int
+	DevicesRemaining int
+	Status           string
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230630-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&OrganizationRenumbering{}),
+	)
+}
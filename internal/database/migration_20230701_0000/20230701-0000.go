@@ -0,0 +1,26 @@
+package migration_20230701_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+type IpReservation struct {
+	models.Base
+	OrganizationID    uuid.UUID `gorm:"type:uuid;index"`
+	Hostname          string
+	PublicKey         string
+	RegistrationKeyID uuid.UUID `gorm:"type:uuid"`
+	TunnelIP          string
+	TunnelIpV6        string
+	Description       string
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230701-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&IpReservation{}),
+	)
+}
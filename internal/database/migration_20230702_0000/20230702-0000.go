@@ -0,0 +1,36 @@
+package migration_20230702_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+type OrganizationGroupMapping struct {
+	models.Base
+	OrganizationID uuid.UUID `gorm:"type:uuid;index"`
+	Group          string    `gorm:"column:group_name"`
+	Role           string
+}
+
+type User struct {
+	Groups         pq.StringArray `gorm:"type:text[]"`
+	GroupsSyncedAt *time.Time
+}
+
+type UserOrganization struct {
+	GroupManaged bool `gorm:"default:false"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230702-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&OrganizationGroupMapping{}),
+		AddTableColumnsAction(&User{}),
+		AddTableColumnsAction(&UserOrganization{}),
+	)
+}
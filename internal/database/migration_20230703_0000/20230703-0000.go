@@ -0,0 +1,32 @@
+package migration_20230703_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+type ScimGroup struct {
+	models.Base
+	DisplayName string `gorm:"index"`
+	ExternalID  string
+}
+
+type ScimGroupMember struct {
+	GroupID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID  string    `gorm:"primaryKey;index"`
+}
+
+type User struct {
+	Deactivated bool `gorm:"default:false"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230703-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&ScimGroup{}),
+		CreateTableAction(&ScimGroupMember{}),
+		AddTableColumnsAction(&User{}),
+	)
+}
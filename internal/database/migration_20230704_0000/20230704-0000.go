@@ -0,0 +1,27 @@
+package migration_20230704_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type Invitation struct {
+	Email     string
+	Role      string `gorm:"default:member"`
+	TokenHash string
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230704-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&Invitation{}),
+		ExecAction(
+			`CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations (email)`,
+			`DROP INDEX IF EXISTS idx_invitations_email`,
+		),
+		ExecAction(
+			`CREATE INDEX IF NOT EXISTS idx_invitations_token_hash ON invitations (token_hash)`,
+			`DROP INDEX IF EXISTS idx_invitations_token_hash`,
+		),
+	)
+}
@@ -0,0 +1,22 @@
+package migration_20230705_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type DeviceHeartbeat struct {
+	DeviceID uuid.UUID `gorm:"type:uuid;primary_key"`
+	SeenAt   time.Time `gorm:"index"`
+	Down     bool
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230705-0000"
+	return CreateMigrationFromActions(migrationId,
+		CreateTableAction(&DeviceHeartbeat{}),
+	)
+}
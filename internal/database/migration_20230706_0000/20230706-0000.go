@@ -0,0 +1,19 @@
+package migration_20230706_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/lib/pq"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type OrganizationPeering struct {
+	Prefixes     pq.StringArray `gorm:"type:text[]"`
+	PeerPrefixes pq.StringArray `gorm:"type:text[]"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230706-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&OrganizationPeering{}),
+	)
+}
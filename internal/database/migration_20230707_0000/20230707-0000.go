@@ -0,0 +1,24 @@
+package migration_20230707_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type Device struct {
+	RegKeyID uuid.UUID `gorm:"index"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230707-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&Device{}),
+		// the devices of the single use keys are known, the devices registered with the other keys
+		// were not recorded and can only be managed by their owner.
+		ExecAction(`
+			UPDATE devices SET reg_key_id=(SELECT reg_keys.id FROM reg_keys WHERE reg_keys.device_id=devices.id)
+			WHERE EXISTS (SELECT 1 FROM reg_keys WHERE reg_keys.device_id=devices.id);
+			`, ``),
+	)
+}
@@ -37,6 +37,26 @@ const docTemplate = `{
                 ],
                 "summary": "List Devices",
                 "operationId": "ListDevices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example hostname^=lab-,created_at\u003e=2023-06-01",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -47,6 +67,12 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
@@ -56,7 +82,7 @@ const docTemplate = `{
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     }
                 }
@@ -104,6 +130,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.QuotaExceededError"
+                        }
+                    },
                     "409": {
                         "description": "Conflict",
                         "schema": {
@@ -113,7 +145,7 @@ const docTemplate = `{
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -125,9 +157,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/devices/{id}": {
-            "get": {
-                "description": "Gets a device by ID",
+        "/api/devices/batch/delete": {
+            "post": {
+                "description": "Deletes the selected devices and their IPAM leases in a single transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -137,22 +169,24 @@ const docTemplate = `{
                 "tags": [
                     "Devices"
                 ],
-                "summary": "Get Devices",
-                "operationId": "GetDevice",
+                "summary": "Batch Delete Devices",
+                "operationId": "BatchDeleteDevices",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Device ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Devices to delete",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceBatchDelete"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Device"
+                            "$ref": "#/definitions/models.DeviceBatchResult"
                         }
                     },
                     "400": {
@@ -167,22 +201,30 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "422": {
+                        "description": "Unprocessable Entity",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.DeviceBatchResult"
                         }
                     },
                     "429": {
                         "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Deletes an existing device and associated IPAM lease",
+            }
+        },
+        "/api/devices/batch/metadata": {
+            "post": {
+                "description": "Sets a metadata key on the selected devices in a single transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -192,22 +234,24 @@ const docTemplate = `{
                 "tags": [
                     "Devices"
                 ],
-                "summary": "Delete Device",
-                "operationId": "DeleteDevice",
+                "summary": "Batch Update Devices Metadata",
+                "operationId": "BatchUpdateDevicesMetadata",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Device ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Devices to update",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceBatchMetadata"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Device"
+                            "$ref": "#/definitions/models.DeviceBatchResult"
                         }
                     },
                     "400": {
@@ -216,10 +260,22 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceBatchResult"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -229,9 +285,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "Updates a device by ID",
+            }
+        },
+        "/api/devices/batch/move": {
+            "post": {
+                "description": "Moves the selected devices to an organization in a single transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -241,23 +299,16 @@ const docTemplate = `{
                 "tags": [
                     "Devices"
                 ],
-                "summary": "Update Devices",
-                "operationId": "UpdateDevice",
+                "summary": "Batch Move Devices",
+                "operationId": "BatchMoveDevices",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Device ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Device Update",
-                        "name": "update",
+                        "description": "Devices to move",
+                        "name": "batch",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateDevice"
+                            "$ref": "#/definitions/models.DeviceBatchMove"
                         }
                     }
                 ],
@@ -265,7 +316,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Device"
+                            "$ref": "#/definitions/models.DeviceBatchResult"
                         }
                     },
                     "400": {
@@ -286,8 +337,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceBatchResult"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -295,9 +358,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/devices/{id}/metadata": {
-            "get": {
-                "description": "Lists metadata for a device",
+        "/api/devices/batch/security_group": {
+            "post": {
+                "description": "Sets the security group of the selected devices in a single transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -307,31 +370,54 @@ const docTemplate = `{
                 "tags": [
                     "Devices"
                 ],
-                "summary": "List Device Metadata",
-                "operationId": "ListDeviceMetadata",
+                "summary": "Batch Update Devices Security Group",
+                "operationId": "BatchUpdateDevicesSecurityGroup",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Device ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "greater than revision",
-                        "name": "gt_revision",
-                        "in": "query"
+                        "description": "Devices to update",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceBatchSecurityGroup"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.DeviceMetadata"
-                            }
+                            "$ref": "#/definitions/models.DeviceBatchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceBatchResult"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -341,14 +427,22 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete all metadata for a device",
+            }
+        },
+        "/api/devices/{id}": {
+            "get": {
+                "description": "Gets a device by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
                     "Devices"
                 ],
-                "summary": "Delete all Device metadata",
-                "operationId": "DeleteDeviceMetadata",
+                "summary": "Get Devices",
+                "operationId": "GetDevice",
                 "parameters": [
                     {
                         "type": "string",
@@ -359,21 +453,40 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Device"
+                        }
                     },
-                    "501": {
-                        "description": "Not Implemented",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
                     }
                 }
-            }
-        },
-        "/api/devices/{id}/metadata/{key}": {
-            "get": {
-                "description": "Get metadata for a device",
+            },
+            "delete": {
+                "description": "Deletes an existing device and associated IPAM lease",
                 "consumes": [
                     "application/json"
                 ],
@@ -383,8 +496,8 @@ const docTemplate = `{
                 "tags": [
                     "Devices"
                 ],
-                "summary": "Get Device Metadata",
-                "operationId": "GetDeviceMetadataKey",
+                "summary": "Delete Device",
+                "operationId": "DeleteDevice",
                 "parameters": [
                     {
                         "type": "string",
@@ -392,32 +505,37 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Metadata Key",
-                        "name": "key",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
-                            "$ref": "#/definitions/models.DeviceMetadata"
+                            "$ref": "#/definitions/models.Device"
                         }
                     },
-                    "501": {
-                        "description": "Not Implemented",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     }
                 }
             },
-            "put": {
-                "description": "Set metadata key for a device",
+            "patch": {
+                "description": "Updates a device by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -427,8 +545,8 @@ const docTemplate = `{
                 "tags": [
                     "Devices"
                 ],
-                "summary": "Set Device Metadata by key",
-                "operationId": "UpdateDeviceMetadataKey",
+                "summary": "Update Devices",
+                "operationId": "UpdateDevice",
                 "parameters": [
                     {
                         "type": "string",
@@ -438,64 +556,2625 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Metadata Key",
-                        "name": "key",
+                        "description": "Device Update",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateDevice"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Device"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.QuotaExceededError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/devices/{id}/heartbeat": {
+            "post": {
+                "description": "Records a heartbeat of the device. The relays that stop sending heartbeats are reported down with the relay.down webhook event, and the ephemeral devices that stop sending heartbeats are deleted.",
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Report that a device is up",
+                "operationId": "DeviceHeartbeat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/devices/{id}/metadata": {
+            "get": {
+                "description": "Lists metadata for a device",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "List Device Metadata",
+                "operationId": "ListDeviceMetadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "greater than revision",
+                        "name": "gt_revision",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example key^=lab,revision\u003e100",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.DeviceMetadata"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete all metadata for a device",
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Delete all Device metadata",
+                "operationId": "DeleteDeviceMetadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/devices/{id}/metadata/{key}": {
+            "get": {
+                "description": "Get metadata for a device",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Get Device Metadata",
+                "operationId": "GetDeviceMetadataKey",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metadata Key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceMetadata"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Set metadata key for a device",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Set Device Metadata by key",
+                "operationId": "UpdateDeviceMetadataKey",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metadata Key",
+                        "name": "key",
                         "in": "path"
                     },
-                    {
-                        "description": "Metadata Value",
-                        "name": "value",
-                        "in": "body",
-                        "required": true,
+                    {
+                        "description": "Metadata Value",
+                        "name": "value",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceMetadata"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.QuotaExceededError"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a metadata key for a device",
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Delete a Device metadata key",
+                "operationId": "DeleteDeviceMetadataKey",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Metadata Key",
+                        "name": "key",
+                        "in": "path"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/devices/{id}/peered_devices": {
+            "get": {
+                "description": "Lists the devices of other organizations that the device can reach through the active peerings of its organization.\nDevices that are only peered by their child prefixes are listed with the peered child prefixes and without allowed ips.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "List peered devices",
+                "operationId": "ListPeeredDevices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Device"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/models.GoneError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/fflags": {
+            "get": {
+                "description": "Lists all feature flags",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "FFlag"
+                ],
+                "summary": "List Feature Flags",
+                "operationId": "ListFeatureFlags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/fflags/{name}": {
+            "get": {
+                "description": "Gets a Feature Flag by name",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "FFlag"
+                ],
+                "summary": "Get Feature Flag",
+                "operationId": "GetFeatureFlag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "feature flag name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations": {
+            "get": {
+                "description": "Lists all invitations",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitation"
+                ],
+                "summary": "List Invitations",
+                "operationId": "ListInvitations",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "greater than revision",
+                        "name": "gt_revision",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example role=member,expiry\u003c2023-07-01",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Invitation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/models.GoneError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create an invitation to an organization, for a user or an email address. The link of the invitation is only returned once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitation"
+                ],
+                "summary": "Create an invitation",
+                "operationId": "CreateInvitation",
+                "parameters": [
+                    {
+                        "description": "Add Invitation",
+                        "name": "Invitation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddInvitation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Invitation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations/accept": {
+            "post": {
+                "description": "Accept an invitation to an organization with the token of its link, the link can only be used once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitation"
+                ],
+                "summary": "Accept an invitation link",
+                "operationId": "AcceptInvitationLink",
+                "parameters": [
+                    {
+                        "description": "Accept Invitation",
+                        "name": "AcceptInvitation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AcceptInvitation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations/{invitation}": {
+            "delete": {
+                "description": "Deletes an existing invitation",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitation"
+                ],
+                "summary": "Delete Invitation",
+                "operationId": "DeleteInvitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invitation ID",
+                        "name": "invitation",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations/{invitation}/accept": {
+            "post": {
+                "description": "Accept an invitation to an organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invitation"
+                ],
+                "summary": "Accept an invitation",
+                "operationId": "AcceptInvitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invitation ID",
+                        "name": "invitation",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ipam_drift": {
+            "get": {
+                "description": "Lists the IPAM allocations that don't match the devices and organizations found by the last IPAM reconciliation, including the drift of the namespace shared by the organizations without a private CIDR. Only the admins of the apiserver can list it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "List IPAM drift",
+                "operationId": "ListIpamDrift",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.IpamDriftReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations": {
+            "get": {
+                "description": "Lists all Organizations",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "List Organizations",
+                "operationId": "ListOrganizations",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "greater than revision",
+                        "name": "gt_revision",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example name^=team-,private_cidr=true",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Organization"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/models.GoneError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a named organization with the given CIDR",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Create an Organization",
+                "operationId": "CreateOrganization",
+                "parameters": [
+                    {
+                        "description": "Add Organization",
+                        "name": "Organization",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddOrganization"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.QuotaExceededError"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{id}": {
+            "get": {
+                "description": "Gets a Organization by Organization ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Get Organizations",
+                "operationId": "GetOrganizations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes an existing organization and associated IPAM prefix",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Delete Organization",
+                "operationId": "DeleteOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{id}/devices": {
+            "get": {
+                "description": "Lists all users for this Organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "List Users",
+                "operationId": "ListUsersInOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example user_name^=alice,created_at\u003e=2023-06-01",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.User"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/audit": {
+            "get": {
+                "description": "Lists the audit events of an organization, oldest first. Use format=jsonl to export them as JSON lines.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "List Audit Events",
+                "operationId": "ListAuditEvents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events with this action",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events for this resource type",
+                        "name": "resource_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events for this resource",
+                        "name": "resource_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events made by this user",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events at or after this RFC3339 time",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events before this RFC3339 time",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to jsonl to export the events as JSON lines",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example action=delete,resource_type=device,created_at\u003e=2023-06-01",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AuditEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/devices": {
+            "get": {
+                "description": "Lists all devices for this Organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "List Devices",
+                "operationId": "ListDevicesInOrganization",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "greater than revision",
+                        "name": "gt_revision",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "label selector, for example env=prod,tier!=db",
+                        "name": "selector",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example hostname^=lab-,created_at\u003e=2023-06-01",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Device"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/models.GoneError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/devices/{device_id}": {
+            "get": {
+                "description": "Gets a device in a organization by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Get Device",
+                "operationId": "GetDeviceInOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Device"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/group_mappings": {
+            "get": {
+                "description": "Lists the identity provider groups whose users are members of the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "List group mappings",
+                "operationId": "ListGroupMappings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example group^=eng-,role=admin",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.OrganizationGroupMapping"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Makes the users of an identity provider group members of the organization with a role. The members are added and removed as the groups of the users change",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Map a group to organization membership",
+                "operationId": "CreateGroupMapping",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Add Group Mapping",
+                        "name": "GroupMapping",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddOrganizationGroupMapping"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrganizationGroupMapping"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/group_mappings/{id}": {
+            "delete": {
+                "description": "Deletes a group mapping, the users that are members of the organization only through the group are removed from it along with their devices",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Delete a group mapping",
+                "operationId": "DeleteGroupMapping",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Group Mapping ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrganizationGroupMapping"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/ip_reservations": {
+            "get": {
+                "description": "Lists the tunnel addresses reserved for device identities in an organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "List IP reservations",
+                "operationId": "ListIpReservations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example tunnel_ip^=100.100.0.,hostname^=lab-",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.IpReservation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Reserves tunnel addresses of an organization for the device with a hostname, public key or registration key. The addresses are given to the device whenever it joins the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Reserve tunnel addresses",
+                "operationId": "CreateIpReservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Add IP Reservation",
+                        "name": "Reservation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddIpReservation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.IpReservation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/ip_reservations/{id}": {
+            "delete": {
+                "description": "Deletes a reservation, the addresses are released unless a device uses them",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Delete an IP reservation",
+                "operationId": "DeleteIpReservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IP Reservation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.IpReservation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/ipam_drift": {
+            "get": {
+                "description": "Gets the IPAM allocations of an organization that don't match its devices, found by the last IPAM reconciliation",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Get IPAM drift",
+                "operationId": "GetIpamDrift",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.IpamDriftReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/members": {
+            "get": {
+                "description": "Lists the members of an organization and their roles",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "List Organization Members",
+                "operationId": "ListOrganizationMembers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.OrganizationMember"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/members/{id}": {
+            "patch": {
+                "description": "Changes the role of an organization member",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Update Organization Member",
+                "operationId": "UpdateOrganizationMember",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Member Update",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateOrganizationMember"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrganizationMember"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/peerings": {
+            "get": {
+                "description": "Lists the peerings that an organization requested or was asked to accept",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "List organization peerings",
+                "operationId": "ListOrganizationPeerings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example status=pending",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.OrganizationPeering"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Requests a peering with another organization, the owner of the other organization has to accept it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Request an organization peering",
+                "operationId": "CreateOrganizationPeering",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Add Organization Peering",
+                        "name": "Peering",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddOrganizationPeering"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrganizationPeering"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/peerings/{id}": {
+            "delete": {
+                "description": "Deletes a peering, either organization of the peering can delete it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Delete an organization peering",
+                "operationId": "DeleteOrganizationPeering",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Peering ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrganizationPeering"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/peerings/{id}/accept": {
+            "post": {
+                "description": "Accepts a peering that another organization requested, which connects the selected devices of both organizations",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Accept an organization peering",
+                "operationId": "AcceptOrganizationPeering",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Peering ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Accept Organization Peering",
+                        "name": "Peering",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AcceptOrganizationPeering"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrganizationPeering"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/reg_keys": {
+            "get": {
+                "description": "Lists all registration keys in an organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RegKey"
+                ],
+                "summary": "List registration keys",
+                "operationId": "ListRegKeys",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example single_use=true,expires_at\u003c2023-07-01",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.RegKey"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.BaseError"
                         }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.DeviceMetadata"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
-                    "501": {
-                        "description": "Not Implemented",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     }
                 }
             },
-            "delete": {
-                "description": "Delete a metadata key for a device",
+            "post": {
+                "description": "Creates a registration key that devices can use to join the organization without an interactive login",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Devices"
+                    "RegKey"
                 ],
-                "summary": "Delete a Device metadata key",
-                "operationId": "DeleteDeviceMetadataKey",
+                "summary": "Create a registration key",
+                "operationId": "CreateRegKey",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Device ID",
-                        "name": "id",
+                        "description": "Organization ID",
+                        "name": "organization_id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Metadata Key",
-                        "name": "key",
-                        "in": "path"
+                        "description": "Add RegKey",
+                        "name": "RegKey",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddRegKey"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.RegKey"
+                        }
                     },
-                    "501": {
-                        "description": "Not Implemented",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -503,9 +3182,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/fflags": {
-            "get": {
-                "description": "Lists all feature flags",
+        "/api/organizations/{organization_id}/reg_keys/{id}": {
+            "delete": {
+                "description": "Revokes a registration key so it can no longer be used by devices",
                 "consumes": [
                     "application/json"
                 ],
@@ -513,22 +3192,59 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "FFlag"
+                    "RegKey"
+                ],
+                "summary": "Delete a registration key",
+                "operationId": "DeleteRegKey",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RegKey ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List Feature Flags",
-                "operationId": "ListFeatureFlags",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "boolean"
-                            }
+                            "$ref": "#/definitions/models.RegKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
                         }
                     },
                     "429": {
                         "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -536,9 +3252,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/fflags/{name}": {
+        "/api/organizations/{organization_id}/renumberings": {
             "get": {
-                "description": "Gets a Feature Flag by name",
+                "description": "Lists the changes of the CIDRs of an organization, and the progress of the devices being given new addresses",
                 "consumes": [
                     "application/json"
                 ],
@@ -546,26 +3262,44 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "FFlag"
+                    "Organizations"
                 ],
-                "summary": "Get Feature Flag",
-                "operationId": "GetFeatureFlag",
+                "summary": "List organization renumberings",
+                "operationId": "ListOrganizationRenumberings",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "feature flag name",
-                        "name": "name",
+                        "description": "Organization ID",
+                        "name": "organization_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example status=in_progress,created_at\u003e=2023-06-01",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "boolean"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.OrganizationRenumbering"
                             }
                         }
                     },
@@ -575,6 +3309,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -583,6 +3323,12 @@ const docTemplate = `{
                     },
                     "429": {
                         "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -590,9 +3336,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/invitations": {
-            "get": {
-                "description": "Lists all invitations",
+        "/api/organizations/{organization_id}/resize": {
+            "post": {
+                "description": "Changes the CIDRs of an organization with a private CIDR. A CIDR that contains the current one is grown in place, otherwise the devices are given addresses of the new CIDR in batches",
                 "consumes": [
                     "application/json"
                 ],
@@ -600,18 +3346,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Invitation"
+                    "Organizations"
+                ],
+                "summary": "Resize an Organization",
+                "operationId": "ResizeOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Resize Organization",
+                        "name": "Resize",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ResizeOrganization"
+                        }
+                    }
                 ],
-                "summary": "List Invitations",
-                "operationId": "ListInvitations",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Invitation"
-                            }
+                            "$ref": "#/definitions/models.OrganizationRenumbering"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
                         }
                     },
                     "401": {
@@ -620,43 +3387,65 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Create an invitation to an organization",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/api/organizations/{organization_id}/security_group/{id}": {
+            "get": {
+                "description": "Gets a security group in an organization by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Invitation"
+                    "SecurityGroup"
                 ],
-                "summary": "Create an invitation",
-                "operationId": "CreateInvitation",
+                "summary": "Get SecurityGroup",
+                "operationId": "GetSecurityGroup",
                 "parameters": [
                     {
-                        "description": "Add Invitation",
-                        "name": "Invitation",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.AddInvitation"
-                        }
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Invitation"
+                            "$ref": "#/definitions/models.SecurityGroup"
                         }
                     },
                     "400": {
@@ -665,6 +3454,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -674,38 +3469,65 @@ const docTemplate = `{
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     }
                 }
             }
         },
-        "/api/invitations/:invitation/accept": {
-            "post": {
-                "description": "Accept an invitation to an organization",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/organizations/{organization_id}/security_groups": {
+            "get": {
+                "description": "Lists all Security Groups",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Invitation"
+                    "SecurityGroup"
                 ],
-                "summary": "Accept an invitation",
-                "operationId": "AcceptInvitation",
+                "summary": "List Security Groups",
+                "operationId": "ListSecurityGroups",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "greater than revision",
+                        "name": "gt_revision",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Invitation ID",
-                        "name": "invitation",
+                        "description": "Organization ID",
+                        "name": "organization_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example group_name^=web-",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SecurityGroup"
+                            }
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -713,49 +3535,59 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/models.GoneError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     }
                 }
-            }
-        },
-        "/api/invitations/{invitation}": {
-            "delete": {
-                "description": "Deletes an existing invitation",
-                "consumes": [
-                    "application/json"
-                ],
+            },
+            "post": {
+                "description": "Adds a new Security Group",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Invitation"
+                    "SecurityGroup"
                 ],
-                "summary": "Delete Invitation",
-                "operationId": "DeleteInvitation",
+                "summary": "Add SecurityGroup",
+                "operationId": "CreateSecurityGroup",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Invitation ID",
-                        "name": "invitation",
+                        "description": "Organization ID",
+                        "name": "organization_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Add SecurityGroup",
+                        "name": "SecurityGroup",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddSecurityGroup"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Organization"
+                            "$ref": "#/definitions/models.SecurityGroup"
                         }
                     },
                     "400": {
@@ -764,16 +3596,22 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "405": {
-                        "description": "Method Not Allowed",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -785,32 +3623,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations": {
-            "get": {
-                "description": "Lists all Organizations",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/organizations/{organization_id}/security_groups/{security_group_id}": {
+            "delete": {
+                "description": "Deletes an existing SecurityGroup",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "SecurityGroup"
+                ],
+                "summary": "Delete SecurityGroup",
+                "operationId": "DeleteSecurityGroup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security Group ID",
+                        "name": "security_group_id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List Organizations",
-                "operationId": "ListOrganizations",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Organization"
-                            }
+                            "$ref": "#/definitions/models.SecurityGroup"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -818,7 +3666,7 @@ const docTemplate = `{
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -829,35 +3677,46 @@ const docTemplate = `{
                     }
                 }
             },
-            "post": {
-                "description": "Creates a named organization with the given CIDR",
-                "consumes": [
-                    "application/json"
-                ],
+            "patch": {
+                "description": "Updates a Security Group by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "SecurityGroup"
                 ],
-                "summary": "Create an Organization",
-                "operationId": "CreateOrganization",
+                "summary": "Update Security Group",
+                "operationId": "UpdateSecurityGroup",
                 "parameters": [
                     {
-                        "description": "Add Organization",
-                        "name": "Organization",
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security Group ID",
+                        "name": "security_group_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Security Group Update",
+                        "name": "update",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.AddOrganization"
+                            "$ref": "#/definitions/models.UpdateSecurityGroup"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Organization"
+                            "$ref": "#/definitions/models.SecurityGroup"
                         }
                     },
                     "400": {
@@ -872,36 +3731,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "405": {
-                        "description": "Method Not Allowed",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
-                        "schema": {
-                            "$ref": "#/definitions/models.ConflictsError"
-                        }
-                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     }
                 }
             }
         },
-        "/api/organizations/{id}": {
+        "/api/organizations/{organization_id}/service_accounts": {
             "get": {
-                "description": "Gets a Organization by Organization ID",
+                "description": "Lists all service accounts in an organization",
                 "consumes": [
                     "application/json"
                 ],
@@ -909,24 +3756,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "ServiceAccount"
                 ],
-                "summary": "Get Organizations",
-                "operationId": "GetOrganizations",
+                "summary": "List service accounts",
+                "operationId": "ListServiceAccounts",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Organization ID",
-                        "name": "id",
+                        "name": "organization_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example name^=ci-,created_at\u003e=2023-06-01",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Organization"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ServiceAccount"
+                            }
                         }
                     },
                     "400": {
@@ -949,14 +3817,20 @@ const docTemplate = `{
                     },
                     "429": {
                         "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     }
                 }
             },
-            "delete": {
-                "description": "Deletes an existing organization and associated IPAM prefix",
+            "post": {
+                "description": "Creates a service account owned by the organization",
                 "consumes": [
                     "application/json"
                 ],
@@ -964,24 +3838,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "ServiceAccount"
                 ],
-                "summary": "Delete Organization",
-                "operationId": "DeleteOrganization",
+                "summary": "Create a service account",
+                "operationId": "CreateServiceAccount",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Organization ID",
-                        "name": "id",
+                        "name": "organization_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Add ServiceAccount",
+                        "name": "ServiceAccount",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddServiceAccount"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Organization"
+                            "$ref": "#/definitions/models.ServiceAccount"
                         }
                     },
                     "400": {
@@ -990,8 +3873,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "405": {
-                        "description": "Method Not Allowed",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -999,7 +3888,7 @@ const docTemplate = `{
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -1011,9 +3900,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{id}/devices": {
-            "get": {
-                "description": "Lists all users for this Organization",
+        "/api/organizations/{organization_id}/service_accounts/{id}": {
+            "delete": {
+                "description": "Deletes a service account and revokes all of its api tokens",
                 "consumes": [
                     "application/json"
                 ],
@@ -1021,14 +3910,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "ServiceAccount"
                 ],
-                "summary": "List Users",
-                "operationId": "ListUsersInOrganization",
+                "summary": "Delete a service account",
+                "operationId": "DeleteServiceAccount",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ServiceAccount ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1038,10 +3934,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.User"
-                            }
+                            "$ref": "#/definitions/models.ServiceAccount"
                         }
                     },
                     "400": {
@@ -1056,10 +3949,16 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -1071,9 +3970,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{organization_id}/devices": {
+        "/api/organizations/{organization_id}/service_accounts/{id}/tokens": {
             "get": {
-                "description": "Lists all devices for this Organization",
+                "description": "Lists the api tokens issued to a service account",
                 "consumes": [
                     "application/json"
                 ],
@@ -1081,23 +3980,42 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
+                    "ServiceAccount"
                 ],
-                "summary": "List Devices",
-                "operationId": "ListDevicesInOrganization",
+                "summary": "List api tokens",
+                "operationId": "ListApiTokens",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "greater than revision",
-                        "name": "gt_revision",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
                         "description": "Organization ID",
                         "name": "organization_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ServiceAccount ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example last_used_at\u003c2023-06-01",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1106,7 +4024,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Device"
+                                "$ref": "#/definitions/models.ApiToken"
                             }
                         }
                     },
@@ -1122,10 +4040,16 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -1135,11 +4059,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/organizations/{organization_id}/devices/{device_id}": {
-            "get": {
-                "description": "Gets a device in a organization by ID",
+            },
+            "post": {
+                "description": "Issues a new api token for a service account",
                 "consumes": [
                     "application/json"
                 ],
@@ -1147,10 +4069,10 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
+                    "ServiceAccount"
                 ],
-                "summary": "Get Device",
-                "operationId": "GetDeviceInOrganization",
+                "summary": "Create an api token",
+                "operationId": "CreateApiToken",
                 "parameters": [
                     {
                         "type": "string",
@@ -1161,17 +4083,26 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Device ID",
-                        "name": "device_id",
+                        "description": "ServiceAccount ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Add ApiToken",
+                        "name": "ApiToken",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddApiToken"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Device"
+                            "$ref": "#/definitions/models.ApiToken"
                         }
                     },
                     "400": {
@@ -1195,7 +4126,7 @@ const docTemplate = `{
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -1207,17 +4138,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{organization_id}/security_group/{id}": {
-            "get": {
-                "description": "Gets a security group in an organization by ID",
+        "/api/organizations/{organization_id}/service_accounts/{id}/tokens/{token_id}": {
+            "delete": {
+                "description": "Revokes an api token so it can no longer be used",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "ServiceAccount"
                 ],
-                "summary": "Get SecurityGroup",
-                "operationId": "GetSecurityGroup",
+                "summary": "Delete an api token",
+                "operationId": "DeleteApiToken",
                 "parameters": [
                     {
                         "type": "string",
@@ -1228,17 +4162,24 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Security Group ID",
+                        "description": "ServiceAccount ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ApiToken ID",
+                        "name": "token_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
+                            "$ref": "#/definitions/models.ApiToken"
                         }
                     },
                     "400": {
@@ -1261,6 +4202,12 @@ const docTemplate = `{
                     },
                     "429": {
                         "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -1268,17 +4215,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{organization_id}/security_groups": {
+        "/api/organizations/{organization_id}/webhooks": {
             "get": {
-                "description": "Lists all Security Groups",
+                "description": "Lists the webhooks of an organization",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "Webhook"
                 ],
-                "summary": "List Security Groups",
-                "operationId": "ListSecurityGroups",
+                "summary": "List webhooks",
+                "operationId": "ListWebhooks",
                 "parameters": [
                     {
                         "type": "string",
@@ -1286,6 +4236,24 @@ const docTemplate = `{
                         "name": "organization_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example description^=ci,created_at\u003e=2023-06-01",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1294,18 +4262,36 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.SecurityGroup"
+                                "$ref": "#/definitions/models.Webhook"
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -1313,15 +4299,18 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Adds a new Security Group",
+                "description": "Subscribes a URL to the events of an organization",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "Webhook"
                 ],
-                "summary": "Add SecurityGroup",
-                "operationId": "CreateSecurityGroup",
+                "summary": "Create a webhook",
+                "operationId": "CreateWebhook",
                 "parameters": [
                     {
                         "type": "string",
@@ -1331,12 +4320,12 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Add SecurityGroup",
-                        "name": "SecurityGroup",
+                        "description": "Add Webhook",
+                        "name": "Webhook",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.AddSecurityGroup"
+                            "$ref": "#/definitions/models.AddWebhook"
                         }
                     }
                 ],
@@ -1344,7 +4333,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
+                            "$ref": "#/definitions/models.Webhook"
                         }
                     },
                     "400": {
@@ -1359,16 +4348,16 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/models.ConflictsError"
+                            "$ref": "#/definitions/models.BaseError"
                         }
                     },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -1380,17 +4369,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{organization_id}/security_groups/{security_group_id}": {
+        "/api/organizations/{organization_id}/webhooks/{id}": {
             "delete": {
-                "description": "Deletes an existing SecurityGroup",
+                "description": "Deletes a webhook and its delivery log",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "Webhook"
                 ],
-                "summary": "Delete SecurityGroup",
-                "operationId": "DeleteSecurityGroup",
+                "summary": "Delete a webhook",
+                "operationId": "DeleteWebhook",
                 "parameters": [
                     {
                         "type": "string",
@@ -1401,17 +4393,17 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Security Group ID",
-                        "name": "security_group_id",
+                        "description": "Webhook ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
+                            "$ref": "#/definitions/models.Webhook"
                         }
                     },
                     "400": {
@@ -1420,10 +4412,22 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -1433,17 +4437,22 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "Updates a Security Group by ID",
+            }
+        },
+        "/api/organizations/{organization_id}/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Lists the delivery attempts of a webhook, newest first",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "Webhook"
                 ],
-                "summary": "Update Security Group",
-                "operationId": "UpdateSecurityGroup",
+                "summary": "List webhook deliveries",
+                "operationId": "ListWebhookDeliveries",
                 "parameters": [
                     {
                         "type": "string",
@@ -1454,26 +4463,38 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Security Group ID",
-                        "name": "security_group_id",
+                        "description": "Webhook ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Security Group Update",
-                        "name": "update",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.UpdateSecurityGroup"
-                        }
+                        "type": "string",
+                        "description": "conditions on the fields, for example status=failed,attempts\u003e=3",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDelivery"
+                            }
                         }
                     },
                     "400": {
@@ -1496,6 +4517,12 @@ const docTemplate = `{
                     },
                     "429": {
                         "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.TooManyRequestsError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -1541,6 +4568,24 @@ const docTemplate = `{
                         "name": "prefix",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example key^=lab,revision\u003e100",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1553,6 +4598,18 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/models.GoneError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1576,6 +4633,32 @@ const docTemplate = `{
                 ],
                 "summary": "List Users",
                 "operationId": "ListUsers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "greater than revision",
+                        "name": "gt_revision",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "conditions on the fields, for example user_name^=alice",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the cursor of the page to list",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -1586,16 +4669,28 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/models.GoneError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     }
                 }
@@ -1652,7 +4747,7 @@ const docTemplate = `{
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -1701,7 +4796,7 @@ const docTemplate = `{
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.TooManyRequestsError"
                         }
                     },
                     "500": {
@@ -1756,6 +4851,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1767,7 +4874,7 @@ const docTemplate = `{
         },
         "/device/login/start": {
             "post": {
-                "description": "Starts a device login request",
+                "description": "Starts a device login request with an identity provider",
                 "produces": [
                     "application/json"
                 ],
@@ -1776,12 +4883,23 @@ const docTemplate = `{
                 ],
                 "summary": "Start Login",
                 "operationId": "DeviceStart",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the identity provider, the default provider is used when not set",
+                        "name": "provider",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/models.DeviceStartResponse"
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request"
                     }
                 }
             }
@@ -1923,6 +5041,56 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "models.AcceptInvitation": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "description": "The token of the invitation, from its link",
+                    "type": "string"
+                }
+            }
+        },
+        "models.AcceptOrganizationPeering": {
+            "type": "object",
+            "properties": {
+                "prefixes": {
+                    "description": "Prefixes are the child prefixes of the devices of the accepting organization that the requesting\norganization can reach. A selector or prefixes are required.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "172.16.20.0/24"
+                    ]
+                },
+                "selector": {
+                    "description": "Selector selects the devices of the accepting organization that the requesting organization can reach.",
+                    "type": "string",
+                    "example": "team=ci"
+                }
+            }
+        },
+        "models.AddApiToken": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "ci pipeline"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read:devices"
+                    ]
+                }
+            }
+        },
         "models.AddDevice": {
             "type": "object",
             "properties": {
@@ -1952,6 +5120,12 @@ const docTemplate = `{
                     "type": "string",
                     "example": "myhost"
                 },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "organization_id": {
                     "type": "string",
                     "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
@@ -1977,85 +5151,303 @@ const docTemplate = `{
                 },
                 "tunnel_ip_v6": {
                     "type": "string",
-                    "example": "200::1"
+                    "example": "200::1"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
+                }
+            }
+        },
+        "models.AddInvitation": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "description": "The email address to send the invitation to, the user doesn't need to have logged in before\n(one of username, user_id or email is required)",
+                    "type": "string"
+                },
+                "expires_in": {
+                    "description": "How long the invitation is valid, for example 72h, the default of the api server when empty",
+                    "type": "string",
+                    "example": "72h"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "The role of the user in the organization, member by default",
+                    "type": "string",
+                    "example": "member"
+                },
+                "user_id": {
+                    "description": "The user id to invite (one of username, user_id or email is required)",
+                    "type": "string"
+                },
+                "user_name": {
+                    "description": "The username to invite (one of username, user_id or email is required)",
+                    "type": "string"
+                }
+            }
+        },
+        "models.AddIpReservation": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "referenced by the datacenter firewall"
+                },
+                "hostname": {
+                    "description": "Hostname, PublicKey and RegistrationKeyID are the identity of the device, only one of them can be set.",
+                    "type": "string",
+                    "example": "build-cache"
+                },
+                "public_key": {
+                    "type": "string"
+                },
+                "registration_key_id": {
+                    "type": "string"
+                },
+                "tunnel_ip": {
+                    "description": "TunnelIP and TunnelIpV6 are the addresses to reserve, at least one of them has to be set.",
+                    "type": "string",
+                    "example": "100.100.0.10"
+                },
+                "tunnel_ip_v6": {
+                    "type": "string",
+                    "example": "200::a"
+                }
+            }
+        },
+        "models.AddOrganization": {
+            "type": "object",
+            "properties": {
+                "cidr": {
+                    "type": "string",
+                    "example": "172.16.42.0/24"
+                },
+                "cidr_v6": {
+                    "type": "string",
+                    "example": "0200::/8"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "The Red Zone"
+                },
+                "hub_zone": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "zone-red"
+                },
+                "private_cidr": {
+                    "type": "boolean"
+                },
+                "security_group_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AddOrganizationGroupMapping": {
+            "type": "object",
+            "properties": {
+                "group": {
+                    "type": "string",
+                    "example": "engineering"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "member"
+                }
+            }
+        },
+        "models.AddOrganizationPeering": {
+            "type": "object",
+            "properties": {
+                "peer_organization_id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "prefixes": {
+                    "description": "Prefixes are the child prefixes of the devices of the requesting organization that the peer\norganization can reach. A selector or prefixes are required.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "172.16.10.0/24"
+                    ]
+                },
+                "selector": {
+                    "description": "Selector selects the devices of the requesting organization that the peer organization can reach.",
+                    "type": "string",
+                    "example": "role=build-cache"
+                }
+            }
+        },
+        "models.AddRegKey": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "autoscaling group workers"
+                },
+                "ephemeral": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "single_use": {
+                    "type": "boolean"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "ci"
+                    ]
+                }
+            }
+        },
+        "models.AddSecurityGroup": {
+            "type": "object",
+            "properties": {
+                "group_description": {
+                    "type": "string",
+                    "example": "group_description"
+                },
+                "group_name": {
+                    "type": "string",
+                    "example": "group_name"
+                },
+                "inbound_rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SecurityRule"
+                    }
+                },
+                "org_id": {
+                    "type": "string"
+                },
+                "outbound_rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SecurityRule"
+                    }
+                }
+            }
+        },
+        "models.AddServiceAccount": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "provisions the staging environment"
                 },
-                "user_id": {
+                "name": {
                     "type": "string",
-                    "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
+                    "example": "terraform"
                 }
             }
         },
-        "models.AddInvitation": {
+        "models.AddWebhook": {
             "type": "object",
             "properties": {
-                "organization_id": {
-                    "type": "string"
+                "description": {
+                    "type": "string",
+                    "example": "cmdb sync"
                 },
-                "user_id": {
-                    "description": "The user id to invite (one of username or user_id is required)",
-                    "type": "string"
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "device.joined"
+                    ]
                 },
-                "user_name": {
-                    "description": "The username to invite (one of username or user_id is required)",
-                    "type": "string"
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/nexodus"
                 }
             }
         },
-        "models.AddOrganization": {
+        "models.ApiToken": {
             "type": "object",
             "properties": {
-                "cidr": {
-                    "type": "string",
-                    "example": "172.16.42.0/24"
-                },
-                "cidr_v6": {
-                    "type": "string",
-                    "example": "0200::/8"
+                "bearer_token": {
+                    "description": "BearerToken is only returned when the token is created, only a hash of it is stored.",
+                    "type": "string"
                 },
                 "description": {
-                    "type": "string",
-                    "example": "The Red Zone"
+                    "type": "string"
                 },
-                "hub_zone": {
-                    "type": "boolean"
+                "expires_at": {
+                    "type": "string"
                 },
-                "name": {
+                "id": {
                     "type": "string",
-                    "example": "zone-red"
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
                 },
-                "private_cidr": {
-                    "type": "boolean"
+                "last_used_at": {
+                    "type": "string"
                 },
-                "security_group_id": {
+                "scopes": {
+                    "description": "Scopes limits what the token can be used for, for example read:devices.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "service_account_id": {
                     "type": "string"
                 }
             }
         },
-        "models.AddSecurityGroup": {
+        "models.AuditEvent": {
             "type": "object",
             "properties": {
-                "group_description": {
+                "action": {
                     "type": "string",
-                    "example": "group_description"
+                    "example": "update"
                 },
-                "group_name": {
+                "actor_id": {
+                    "type": "string"
+                },
+                "actor_name": {
+                    "type": "string"
+                },
+                "after": {
+                    "description": "After is the resource after the change, it is not set for deletes."
+                },
+                "before": {
+                    "description": "Before is the resource before the change, it is not set for creates."
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "string",
-                    "example": "group_name"
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
                 },
-                "inbound_rules": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.SecurityRule"
-                    }
+                "organization_id": {
+                    "type": "string"
                 },
-                "org_id": {
+                "request_id": {
                     "type": "string"
                 },
-                "outbound_rules": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.SecurityRule"
-                    }
+                "resource_id": {
+                    "type": "string"
+                },
+                "resource_type": {
+                    "type": "string",
+                    "example": "device"
+                },
+                "source_ip": {
+                    "type": "string"
                 }
             }
         },
@@ -2108,6 +5500,9 @@ const docTemplate = `{
                         "$ref": "#/definitions/models.Endpoint"
                     }
                 },
+                "ephemeral": {
+                    "type": "boolean"
+                },
                 "hostname": {
                     "type": "string"
                 },
@@ -2115,6 +5510,13 @@ const docTemplate = `{
                     "type": "string",
                     "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
                 },
+                "labels": {
+                    "description": "Labels can be used to select the device with a label selector.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "organization_id": {
                     "type": "string"
                 },
@@ -2130,6 +5532,10 @@ const docTemplate = `{
                 "public_key": {
                     "type": "string"
                 },
+                "reg_key_id": {
+                    "description": "RegKeyID is the registration key the device was registered with, registration keys can only\nmanage the devices they registered.",
+                    "type": "string"
+                },
                 "relay": {
                     "type": "boolean"
                 },
@@ -2153,6 +5559,81 @@ const docTemplate = `{
                 }
             }
         },
+        "models.DeviceBatchDelete": {
+            "type": "object",
+            "properties": {
+                "selector": {
+                    "$ref": "#/definitions/models.DeviceSelector"
+                }
+            }
+        },
+        "models.DeviceBatchItemResult": {
+            "type": "object",
+            "properties": {
+                "device_id": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is the http status code of the operation on the device.",
+                    "type": "integer",
+                    "example": 200
+                }
+            }
+        },
+        "models.DeviceBatchMetadata": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string",
+                    "example": "lab"
+                },
+                "selector": {
+                    "$ref": "#/definitions/models.DeviceSelector"
+                },
+                "value": {}
+            }
+        },
+        "models.DeviceBatchMove": {
+            "type": "object",
+            "properties": {
+                "organization_id": {
+                    "type": "string",
+                    "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
+                },
+                "selector": {
+                    "$ref": "#/definitions/models.DeviceSelector"
+                }
+            }
+        },
+        "models.DeviceBatchResult": {
+            "type": "object",
+            "properties": {
+                "committed": {
+                    "type": "boolean"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DeviceBatchItemResult"
+                    }
+                }
+            }
+        },
+        "models.DeviceBatchSecurityGroup": {
+            "type": "object",
+            "properties": {
+                "security_group_id": {
+                    "type": "string",
+                    "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
+                },
+                "selector": {
+                    "$ref": "#/definitions/models.DeviceSelector"
+                }
+            }
+        },
         "models.DeviceMetadata": {
             "type": "object",
             "properties": {
@@ -2168,6 +5649,39 @@ const docTemplate = `{
                 "value": {}
             }
         },
+        "models.DeviceSelector": {
+            "type": "object",
+            "properties": {
+                "hostname": {
+                    "description": "Hostname selects the devices with a matching hostname, * matches any characters and ? a single one.",
+                    "type": "string",
+                    "example": "lab-*"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "labels": {
+                    "description": "Labels selects the devices with labels that match the label selector.",
+                    "type": "string",
+                    "example": "env=prod,tier!=db"
+                },
+                "metadata_key": {
+                    "description": "MetadataKey selects the devices that have the metadata key.",
+                    "type": "string",
+                    "example": "lab"
+                },
+                "metadata_value": {
+                    "description": "MetadataValue also requires the value of the metadata key to be equal."
+                },
+                "organization_id": {
+                    "type": "string",
+                    "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
+                }
+            }
+        },
         "models.DeviceStartResponse": {
             "type": "object",
             "properties": {
@@ -2180,6 +5694,25 @@ const docTemplate = `{
                 },
                 "issuer": {
                     "type": "string"
+                },
+                "provider": {
+                    "description": "Provider is the name of the identity provider, when the device can choose one.",
+                    "type": "string"
+                },
+                "scopes": {
+                    "description": "Scopes are requested by the device.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token_endpoint": {
+                    "description": "TokenEndpoint is set when the provider has no issuer to discover it from.",
+                    "type": "string"
+                },
+                "token_prefix": {
+                    "description": "TokenPrefix is prepended to the access tokens sent to the api.",
+                    "type": "string"
                 }
             }
         },
@@ -2202,9 +5735,25 @@ const docTemplate = `{
                 }
             }
         },
+        "models.GoneError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "something bad"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
         "models.Invitation": {
             "type": "object",
             "properties": {
+                "email": {
+                    "description": "Email is the address the invitation was sent to.",
+                    "type": "string"
+                },
                 "expiry": {
                     "type": "string"
                 },
@@ -2212,11 +5761,119 @@ const docTemplate = `{
                     "type": "string",
                     "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
                 },
+                "link": {
+                    "description": "Link accepts the invitation, it's the token of the invitation when the api server has no link url.\nIt's only returned when the invitation is created.",
+                    "type": "string"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "role": {
+                    "description": "Role is the role of the user in the organization once they accept the invitation.",
+                    "type": "string",
+                    "example": "member"
+                },
+                "user_id": {
+                    "description": "UserID is the invited user, it's empty for the invitations by email of users that never logged in.",
+                    "type": "string"
+                }
+            }
+        },
+        "models.IpReservation": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "referenced by the datacenter firewall"
+                },
+                "hostname": {
+                    "description": "Hostname, PublicKey and RegistrationKeyID are the identity of the device, only one of them is set.",
+                    "type": "string",
+                    "example": "build-cache"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "public_key": {
+                    "type": "string"
+                },
+                "registration_key_id": {
+                    "type": "string"
+                },
+                "tunnel_ip": {
+                    "description": "TunnelIP and TunnelIpV6 are the reserved addresses, at least one of them is set.",
+                    "type": "string",
+                    "example": "100.100.0.10"
+                },
+                "tunnel_ip_v6": {
+                    "type": "string",
+                    "example": "200::a"
+                }
+            }
+        },
+        "models.IpamDrift": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "description": "Address is empty for a prefix.",
+                    "type": "string",
+                    "example": "100.64.0.1"
+                },
+                "allocated_addresses": {
+                    "description": "AllocatedAddresses and UsedAddresses are the number of addresses allocated from the prefix and used\nby the devices, for the addresses kind.",
+                    "type": "integer"
+                },
+                "device_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "kind": {
+                    "description": "Kind is leaked for an allocation that no device or organization uses, missing for a prefix or\naddress of a device or organization that isn't allocated, conflict for an address used by\nseveral devices, and addresses for a prefix that doesn't have as many addresses allocated as\nthe devices use.",
+                    "type": "string",
+                    "example": "leaked"
+                },
+                "namespace": {
+                    "description": "Namespace is the IPAM namespace, the ID of the organization for an organization with a private CIDR.",
+                    "type": "string"
+                },
                 "organization_id": {
+                    "description": "OrganizationID is the organization that the allocation belongs to, it's not set for the leaks and the\nprefixes of the namespace shared by the organizations without a private CIDR.",
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "100.64.0.0/10"
+                },
+                "repaired": {
+                    "description": "Repaired is true when the reconciliation released the leaked allocation or allocated the missing one.",
+                    "type": "boolean"
+                },
+                "used_addresses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.IpamDriftReport": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "description": "CheckedAt is not set until the IPAM has been reconciled.",
                     "type": "string"
                 },
-                "user_id": {
-                    "type": "string"
+                "drift": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.IpamDrift"
+                    }
                 }
             }
         },
@@ -2289,11 +5946,232 @@ const docTemplate = `{
                 "private_cidr": {
                     "type": "boolean"
                 },
+                "revision": {
+                    "type": "integer"
+                },
                 "security_group_id": {
                     "type": "string"
                 }
             }
         },
+        "models.OrganizationGroupMapping": {
+            "type": "object",
+            "properties": {
+                "group": {
+                    "description": "Group is the name of the group in the groups claim of the users. The groups of the providers\nother than the first one are prefixed with the name of their provider and a ':'.",
+                    "type": "string",
+                    "example": "engineering"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Role is the role of the members of the group in the organization.",
+                    "type": "string",
+                    "example": "member"
+                }
+            }
+        },
+        "models.OrganizationMember": {
+            "type": "object",
+            "properties": {
+                "group_managed": {
+                    "description": "GroupManaged is true when the user is a member through an organization group mapping.",
+                    "type": "boolean"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "member"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "models.OrganizationPeering": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "organization_id": {
+                    "description": "OrganizationID is the organization that requested the peering.",
+                    "type": "string"
+                },
+                "peer_organization_id": {
+                    "description": "PeerOrganizationID is the organization that has to accept the peering.",
+                    "type": "string"
+                },
+                "peer_prefixes": {
+                    "description": "PeerPrefixes are the child prefixes of the devices of the peer organization that are peered, they\nare set when the peering is accepted.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "172.16.20.0/24"
+                    ]
+                },
+                "peer_selector": {
+                    "description": "PeerSelector selects the devices of the peer organization, it is set when the peering is accepted.",
+                    "type": "string",
+                    "example": "team=ci"
+                },
+                "prefixes": {
+                    "description": "Prefixes are the child prefixes of the devices of the requesting organization that are peered.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "172.16.10.0/24"
+                    ]
+                },
+                "selector": {
+                    "description": "Selector selects the devices of the requesting organization, no devices when empty.",
+                    "type": "string",
+                    "example": "role=build-cache"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "active"
+                }
+            }
+        },
+        "models.OrganizationRenumbering": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "description": "BatchSize is the number of devices that are given new addresses at a time, all of them when 0.",
+                    "type": "integer",
+                    "example": 10
+                },
+                "cidr": {
+                    "type": "string",
+                    "example": "172.16.40.0/22"
+                },
+                "cidr_v6": {
+                    "type": "string",
+                    "example": "200::/64"
+                },
+                "devices_remaining": {
+                    "description": "DevicesRemaining is the number of devices that still have an address of a previous CIDR.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "previous_cidr": {
+                    "type": "string",
+                    "example": "172.16.42.0/24"
+                },
+                "previous_cidr_v6": {
+                    "type": "string",
+                    "example": "200::/64"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "in_progress"
+                }
+            }
+        },
+        "models.QuotaExceededError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "something bad"
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "quota": {
+                    "type": "string",
+                    "example": "devices_per_organization"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RegKey": {
+            "type": "object",
+            "properties": {
+                "bearer_token": {
+                    "description": "BearerToken is only returned when the key is created, only a hash of it is stored.",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "device_id": {
+                    "description": "DeviceID is the device that consumed a single use key.",
+                    "type": "string"
+                },
+                "ephemeral": {
+                    "description": "Ephemeral devices are removed when the agent using the key shuts down, or stops sending heartbeats.",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "string"
+                },
+                "single_use": {
+                    "description": "SingleUse keys can only be used to register one device.",
+                    "type": "boolean"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ResizeOrganization": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "description": "BatchSize is the number of devices that are given new addresses at a time, all of them when 0.",
+                    "type": "integer",
+                    "example": 10
+                },
+                "cidr": {
+                    "description": "Cidr is the new IPv4 CIDR, the CIDR doesn't change when it's not set.",
+                    "type": "string",
+                    "example": "172.16.40.0/22"
+                },
+                "cidr_v6": {
+                    "description": "CidrV6 is the new IPv6 CIDR, the CIDR doesn't change when it's not set.",
+                    "type": "string",
+                    "example": "200::/64"
+                }
+            }
+        },
         "models.SecurityGroup": {
             "type": "object",
             "properties": {
@@ -2321,6 +6199,9 @@ const docTemplate = `{
                     "items": {
                         "$ref": "#/definitions/models.SecurityRule"
                     }
+                },
+                "revision": {
+                    "type": "integer"
                 }
             }
         },
@@ -2344,6 +6225,46 @@ const docTemplate = `{
                 }
             }
         },
+        "models.ServiceAccount": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "terraform"
+                },
+                "organization_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TooManyRequestsError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "something bad"
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "retry_after": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "scope": {
+                    "type": "string",
+                    "example": "user"
+                }
+            }
+        },
         "models.UpdateDevice": {
             "type": "object",
             "properties": {
@@ -2370,6 +6291,13 @@ const docTemplate = `{
                     "type": "string",
                     "example": "myhost"
                 },
+                "labels": {
+                    "description": "Labels replaces all the labels of the device when set.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "organization_id": {
                     "type": "string",
                     "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
@@ -2382,6 +6310,15 @@ const docTemplate = `{
                 }
             }
         },
+        "models.UpdateOrganizationMember": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "example": "read-only"
+                }
+            }
+        },
         "models.UpdateSecurityGroup": {
             "type": "object",
             "properties": {
@@ -2416,6 +6353,9 @@ const docTemplate = `{
                     "type": "string",
                     "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
                 },
+                "revision": {
+                    "type": "integer"
+                },
                 "security_group_id": {
                     "type": "string"
                 },
@@ -2449,6 +6389,76 @@ const docTemplate = `{
                     "type": "integer"
                 }
             }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "events": {
+                    "description": "Events the webhook is subscribed to, all events when empty.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Secret is used to sign the payloads, it is only returned when the webhook is created.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/nexodus"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string",
+                    "example": "device.joined"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "last_error": {
+                    "description": "LastError describes why the last attempt failed.",
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "string"
+                },
+                "response_code": {
+                    "description": "ResponseCode is the HTTP status of the last attempt.",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "delivered"
+                },
+                "webhook_id": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
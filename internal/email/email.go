@@ -0,0 +1,47 @@
+// The email package sends emails to the users, through a pluggable Sender.
+package email
+
+import (
+	"context"
+	"sync"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	// Send delivers the message, it returns once the message was accepted for delivery.
+	Send(ctx context.Context, msg Message) error
+}
+
+var _ Sender = &Recorder{} // type check the interface is implemented.
+
+// Recorder is a Sender that keeps the messages in memory instead of delivering them, for tests.
+type Recorder struct {
+	mu       sync.Mutex
+	messages []Message
+	// Err is returned by Send when it's set, to test failed deliveries.
+	Err error
+}
+
+// Send records the message.
+func (r *Recorder) Send(_ context.Context, msg Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return r.Err
+	}
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (r *Recorder) Messages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Message{}, r.messages...)
+}
@@ -0,0 +1,128 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPOptions configures the SMTP server the emails are sent through.
+type SMTPOptions struct {
+	// Address is the host:port of the SMTP server.
+	Address string
+	// From is the sender of the emails, for example "Nexodus <noreply@example.com>".
+	From string
+	// Username and Password authenticate with the server when the username is set.
+	Username string
+	Password string
+	// InsecureTLS doesn't verify the certificate of the server.
+	InsecureTLS bool
+}
+
+var _ Sender = &SMTPSender{} // type check the interface is implemented.
+
+// SMTPSender sends the emails through an SMTP server. STARTTLS is used when the server supports it,
+// and is required to authenticate.
+type SMTPSender struct {
+	options SMTPOptions
+	from    *mail.Address
+}
+
+// NewSMTPSender creates a SMTPSender
+func NewSMTPSender(options SMTPOptions) (*SMTPSender, error) {
+	if _, _, err := net.SplitHostPort(options.Address); err != nil {
+		return nil, fmt.Errorf("invalid SMTP server address %q: %w", options.Address, err)
+	}
+	from, err := mail.ParseAddress(options.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", options.From, err)
+	}
+	return &SMTPSender{options: options, from: from}, nil
+}
+
+// Send sends the message through the SMTP server.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address %q: %w", msg.To, err)
+	}
+	data, err := s.format(to, msg)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.options.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(s.options.Address)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host, InsecureSkipVerify: s.options.InsecureTLS}); err != nil {
+			return err
+		}
+	}
+	if s.options.Username != "" {
+		// smtp.PlainAuth refuses to send the password without TLS, except to localhost.
+		if err := client.Auth(smtp.PlainAuth("", s.options.Username, s.options.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// format formats the message with its headers. The subject is encoded, so that it can't add headers.
+func (s *SMTPSender) format(to *mail.Address, msg Message) ([]byte, error) {
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, fmt.Errorf("invalid subject: it can't contain line breaks")
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes(), nil
+}
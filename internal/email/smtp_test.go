@@ -0,0 +1,101 @@
+package email
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// serveSMTP accepts one connection and replies to it like an SMTP server, it returns the
+// commands and the data it received.
+func serveSMTP(t *testing.T) (string, <-chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	received := make(chan []string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var lines []string
+		defer func() { received <- lines }()
+		text := textproto.NewConn(conn)
+		_ = text.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			lines = append(lines, line)
+			command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch command {
+			case "EHLO":
+				_ = text.PrintfLine("250-localhost")
+				_ = text.PrintfLine("250 AUTH PLAIN")
+			case "AUTH":
+				_ = text.PrintfLine("235 authenticated")
+			case "DATA":
+				_ = text.PrintfLine("354 go ahead")
+				data, err := text.ReadDotLines()
+				if err != nil {
+					return
+				}
+				lines = append(lines, data...)
+				_ = text.PrintfLine("250 queued")
+			case "QUIT":
+				_ = text.PrintfLine("221 bye")
+				return
+			default:
+				_ = text.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return l.Addr().String(), received
+}
+
+func TestSMTPSender(t *testing.T) {
+	require := require.New(t)
+	address, received := serveSMTP(t)
+
+	sender, err := NewSMTPSender(SMTPOptions{
+		Address:  address,
+		From:     "Nexodus <noreply@example.com>",
+		Username: "nexodus",
+		Password: "secret",
+	})
+	require.NoError(err)
+	err = sender.Send(context.Background(), Message{
+		To:      "jane@example.com",
+		Subject: "You're invited to join Acme",
+		Body:    "Accept the invitation:\nhttps://example.com/invitations/1",
+	})
+	require.NoError(err)
+
+	lines := <-received
+	require.Contains(lines, "MAIL FROM:<noreply@example.com>")
+	require.Contains(lines, "RCPT TO:<jane@example.com>")
+	require.Contains(lines, `From: "Nexodus" <noreply@example.com>`)
+	require.Contains(lines, "To: <jane@example.com>")
+	require.Contains(lines, "Subject: You're invited to join Acme")
+	require.Contains(lines, "https://example.com/invitations/1")
+}
+
+func TestSMTPSenderRefusesHeaderInjection(t *testing.T) {
+	sender, err := NewSMTPSender(SMTPOptions{Address: "localhost:25", From: "noreply@example.com"})
+	require.NoError(t, err)
+	err = sender.Send(context.Background(), Message{
+		To:      "jane@example.com",
+		Subject: "Hello\r\nBcc: eve@example.com",
+	})
+	require.Error(t, err)
+	err = sender.Send(context.Background(), Message{
+		To: "jane@example.com\r\nBcc: eve@example.com",
+	})
+	require.Error(t, err)
+}
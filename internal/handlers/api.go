@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"github.com/gin-gonic/gin"
 	"github.com/go-session/session/v3"
 	"github.com/nexodus-io/nexodus/internal/signalbus"
 	"github.com/redis/go-redis/v9"
@@ -38,6 +39,15 @@ type API struct {
 	signalBus      signalbus.SignalBus
 	redis          *redis.Client
 	sessionManager *session.Manager
+	quotas         Quotas
+	ipamDrift      ipamDriftState
+	tombstones     tombstoneState
+	scim           SCIMConfig
+	invitations    InvitationConfig
+	// admins are the ids of the users that administer the apiserver.
+	admins map[string]bool
+	// groupSyncRequests wakes up the group sync worker.
+	groupSyncRequests chan struct{}
 }
 
 func NewAPI(
@@ -60,18 +70,23 @@ func NewAPI(
 		return nil, err
 	}
 
+	if err := registerQueryCursorCallback(db); err != nil {
+		return nil, err
+	}
+
 	api := &API{
-		logger:         logger,
-		db:             db,
-		ipam:           ipam,
-		defaultZoneID:  uuid.Nil,
-		fflags:         fflags,
-		transaction:    transactionFunc,
-		dialect:        dialect,
-		store:          store,
-		signalBus:      signalBus,
-		redis:          redis,
-		sessionManager: sessionManager,
+		logger:            logger,
+		db:                db,
+		ipam:              ipam,
+		defaultZoneID:     uuid.Nil,
+		fflags:            fflags,
+		transaction:       transactionFunc,
+		dialect:           dialect,
+		store:             store,
+		signalBus:         signalBus,
+		redis:             redis,
+		sessionManager:    sessionManager,
+		groupSyncRequests: make(chan struct{}, 1),
 	}
 
 	if err := api.populateStore(ctx); err != nil {
@@ -84,3 +99,16 @@ func NewAPI(
 func (api *API) Logger(ctx context.Context) *zap.SugaredLogger {
 	return util.WithTrace(ctx, api.logger)
 }
+
+// SetAdmins sets the ids of the users that administer the apiserver, who can see the state of all
+// the organizations.
+func (api *API) SetAdmins(userIDs []string) {
+	api.admins = make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		api.admins[id] = true
+	}
+}
+
+func (api *API) isAdmin(c *gin.Context) bool {
+	return api.admins[c.GetString(gin.AuthUserKey)]
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// RequestIDHeader can be set by clients or proxies to correlate audit events with their requests,
+// the trace id of the request is used when it is not set.
+const RequestIDHeader = "X-Request-Id"
+
+// recordAuditEvent appends an audit event for a change to a resource. It should be called with the
+// transaction that made the change so that the change and its audit event are committed together.
+func (api *API) recordAuditEvent(c *gin.Context, tx *gorm.DB, orgId uuid.UUID, action string, resourceType string, resourceId string, before interface{}, after interface{}) error {
+	requestId := c.GetHeader(RequestIDHeader)
+	if requestId == "" {
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+			requestId = sc.TraceID().String()
+		}
+	}
+	event := models.AuditEvent{
+		OrganizationID: orgId,
+		ActorID:        c.GetString(gin.AuthUserKey),
+		ActorName:      c.GetString(AuthUserName),
+		Action:         action,
+		ResourceType:   resourceType,
+		ResourceID:     resourceId,
+		Before:         before,
+		After:          after,
+		SourceIP:       c.ClientIP(),
+		RequestID:      requestId,
+	}
+	return tx.Create(&event).Error
+}
+
+// recordSystemAuditEvent appends an audit event for a change made by the apiserver itself rather than
+// by a request, the actor names the part of the apiserver that made the change.
+func recordSystemAuditEvent(tx *gorm.DB, orgId uuid.UUID, actor string, action string, resourceType string, resourceId string, before interface{}, after interface{}) error {
+	event := models.AuditEvent{
+		OrganizationID: orgId,
+		ActorName:      actor,
+		Action:         action,
+		ResourceType:   resourceType,
+		ResourceID:     resourceId,
+		Before:         before,
+		After:          after,
+	}
+	return tx.Create(&event).Error
+}
+
+// ListAuditEvents lists the audit events of an organization
+// @Summary      List Audit Events
+// @Description  Lists the audit events of an organization, oldest first. Use format=jsonl to export them as JSON lines.
+// @Id           ListAuditEvents
+// @Tags         Audit
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true   "Organization ID"
+// @Param        action           query  string  false  "Only events with this action"
+// @Param        resource_type    query  string  false  "Only events for this resource type"
+// @Param        resource_id      query  string  false  "Only events for this resource"
+// @Param        actor_id         query  string  false  "Only events made by this user"
+// @Param        since            query  string  false  "Only events at or after this RFC3339 time"
+// @Param        until            query  string  false  "Only events before this RFC3339 time"
+// @Param        format           query  string  false  "Set to jsonl to export the events as JSON lines"
+// @Param        q               query  string  false  "conditions on the fields, for example action=delete,resource_type=device,created_at>=2023-06-01"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
+// @Success      200  {object}  []models.AuditEvent
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/audit [get]
+func (api *API) ListAuditEvents(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListAuditEvents", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	db := api.db.WithContext(ctx).Model(&models.AuditEvent{}).Where("organization_id = ?", org.ID)
+	for _, field := range []string{"action", "resource_type", "resource_id", "actor_id"} {
+		if value := c.Query(field); value != "" {
+			db = db.Where(field+" = ?", value)
+		}
+	}
+	if value := c.Query("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("since", "must be an RFC3339 time"))
+			return
+		}
+		db = db.Where("created_at >= ?", since)
+	}
+	if value := c.Query("until"); value != "" {
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("until", "must be an RFC3339 time"))
+			return
+		}
+		db = db.Where("created_at < ?", until)
+	}
+
+	if c.Query("format") == "jsonl" {
+		rows, err := db.Order("created_at").Rows()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+			return
+		}
+		defer rows.Close()
+
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		encoder := json.NewEncoder(c.Writer)
+		for rows.Next() {
+			var event models.AuditEvent
+			if err := db.ScanRows(rows, &event); err != nil {
+				api.logger.Errorf("failed to read audit event: %v", err)
+				return
+			}
+			if err := encoder.Encode(event); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	events := make([]models.AuditEvent, 0)
+	result := db.Scopes(FilterAndPaginate(&models.AuditEvent{}, c, "created_at")).Find(&events)
+	if result.Error != nil {
+		sendListError(c, result.Error)
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
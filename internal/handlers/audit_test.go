@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestAuditEvents() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	reqBody, err := json.Marshal(models.AddRegKey{Description: "audited"})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(
+		http.MethodPost,
+		"/:organization/reg_keys", fmt.Sprintf("/%s/reg_keys", suite.testOrganizationID),
+		suite.api.CreateRegKey, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code)
+
+	var regKey models.RegKey
+	require.NoError(json.NewDecoder(res.Body).Decode(&regKey))
+
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete,
+		"/:organization/reg_keys/:id", fmt.Sprintf("/%s/reg_keys/%s", suite.testOrganizationID, regKey.ID),
+		suite.api.DeleteRegKey, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet,
+		"/:organization/audit", fmt.Sprintf("/%s/audit?resource_type=reg_key", suite.testOrganizationID),
+		suite.api.ListAuditEvents, nil,
+	)
+	require.NoError(err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", string(body))
+
+	var events []models.AuditEvent
+	require.NoError(json.Unmarshal(body, &events))
+	require.Len(events, 2)
+	assert.Equal(models.AuditActionCreate, events[0].Action)
+	assert.Equal(regKey.ID.String(), events[0].ResourceID)
+	assert.Equal(TestUserID, events[0].ActorID)
+	assert.Nil(events[0].Before)
+	assert.NotNil(events[0].After)
+	assert.Equal(models.AuditActionDelete, events[1].Action)
+	assert.NotNil(events[1].Before)
+	assert.Nil(events[1].After)
+
+	// the bearer token is never recorded
+	assert.NotContains(string(body), "bearer_token")
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet,
+		"/:organization/audit", fmt.Sprintf("/%s/audit?resource_type=reg_key&action=delete&format=jsonl", suite.testOrganizationID),
+		suite.api.ListAuditEvents, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+	assert.Equal("application/x-ndjson", res.Header().Get("Content-Type"))
+
+	var lines []models.AuditEvent
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		var event models.AuditEvent
+		require.NoError(json.Unmarshal(scanner.Bytes(), &event))
+		lines = append(lines, event)
+	}
+	require.Len(lines, 1)
+	assert.Equal(events[1].ID, lines[0].ID)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet,
+		"/:organization/audit", fmt.Sprintf("/%s/audit?since=yesterday", suite.testOrganizationID),
+		suite.api.ListAuditEvents, nil,
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusBadRequest, res.Code)
+}
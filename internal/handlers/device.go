@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database"
+	"github.com/nexodus-io/nexodus/internal/ipam"
 	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/nexodus-io/nexodus/internal/util"
 	"go.opentelemetry.io/otel/attribute"
@@ -22,6 +25,7 @@ var (
 	errDeviceNotFound        = errors.New("device not found")
 	errInvitationNotFound    = errors.New("invitation not found")
 	errSecurityGroupNotFound = errors.New("security group not found")
+	errOrgOwnerRemoval       = errors.New("the organization owner cannot be removed")
 )
 
 type errDuplicateDevice struct {
@@ -39,9 +43,13 @@ func (e errDuplicateDevice) Error() string {
 // @Tags         Devices
 // @Accept       json
 // @Produce      json
+// @Param        q               query  string  false  "conditions on the fields, for example hostname^=lab-,created_at>=2023-06-01"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
 // @Success      200  {object}  []models.Device
+// @Failure		 400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Router       /api/devices [get]
 func (api *API) ListDevices(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "ListDevices")
@@ -54,27 +62,66 @@ func (api *API) ListDevices(c *gin.Context) {
 	).Find(&devices)
 
 	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "error fetching keys from db"})
+		sendListError(c, result.Error)
 		return
 	}
 	c.JSON(http.StatusOK, devices)
 }
 
+// DeviceIsOwnedByCurrentUser limits the query to devices owned by the current user, in the
+// organizations they are still a member of.
 func (api *API) DeviceIsOwnedByCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	return api.deviceIsOwnedByCurrentUser(c, models.RoleReadOnly)
+}
+
+// deviceIsOwnedByCurrentUser limits the query to devices owned by the current user, in the
+// organizations where they have at least the role.
+func (api *API) deviceIsOwnedByCurrentUser(c *gin.Context, role string) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		userId := c.Value(gin.AuthUserKey).(string)
 
 		// this could potentially be driven by rego output
 
-		return db.Where("user_id = ?", userId)
-		//if api.dialect == database.DialectSqlLite {
-		//	return db.Where("user_id = ? OR organization_id in (SELECT organization_id FROM user_organizations where user_id=?)", userId, userId)
-		//} else {
-		//	return db.Where("user_id = ? OR organization_id::text in (SELECT organization_id::text FROM user_organizations where user_id=?)", userId, userId)
-		//}
+		condition, args := api.organizationRoleCondition(userId, role)
+		db = db.Where("user_id = ?", userId).Where(condition, args...)
+		if regKey, ok := RegKeyFromContext(c); ok {
+			// a registration key can't manage the other devices of its owner, like the ones they
+			// registered with an OIDC login or with another key.
+			db = db.Where("organization_id = ? AND reg_key_id = ?", regKey.OrganizationID, regKey.ID)
+			if regKey.SingleUse && regKey.DeviceID != uuid.Nil {
+				db = db.Where("id = ?", regKey.DeviceID)
+			}
+		}
+		return db
+	}
+}
+
+// DeviceIsManageableByCurrentUser limits the query to devices owned by the current user in the
+// organizations where they are at least a member, or that belong to an organization the current
+// user administers.
+func (api *API) DeviceIsManageableByCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if _, ok := RegKeyFromContext(c); ok {
+			return api.deviceIsOwnedByCurrentUser(c, models.RoleMember)(db)
+		}
+		userId := c.Value(gin.AuthUserKey).(string)
+		owned, args := api.organizationRoleCondition(userId, models.RoleMember)
+		administered, adminArgs := api.organizationRoleCondition(userId, models.RoleAdmin)
+		args = append([]interface{}{userId}, args...)
+		return db.Where("(user_id = ? AND "+owned+") OR "+administered, append(args, adminArgs...)...)
 	}
 }
 
+// organizationRoleCondition returns the condition on the organization_id column that matches the
+// organizations where the user has at least the role.
+func (api *API) organizationRoleCondition(userId string, role string) (string, []interface{}) {
+	args := []interface{}{userId, userId, models.RolesAtLeast(role)}
+	if api.dialect == database.DialectSqlLite {
+		return "(organization_id in (SELECT id FROM organizations where owner_id=?) OR organization_id in (SELECT organization_id FROM user_organizations where user_id=? AND role in ?))", args
+	}
+	return "(organization_id::text in (SELECT id::text FROM organizations where owner_id=?) OR organization_id::text in (SELECT organization_id::text FROM user_organizations where user_id=? AND role in ?))", args
+}
+
 // GetDevice gets a device by ID
 // @Summary      Get Devices
 // @Description  Gets a device by ID
@@ -87,7 +134,7 @@ func (api *API) DeviceIsOwnedByCurrentUser(c *gin.Context) func(db *gorm.DB) *go
 // @Failure		 401  {object}  models.BaseError
 // @Failure      400  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Router       /api/devices/{id} [get]
 func (api *API) GetDevice(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "GetDevice", trace.WithAttributes(
@@ -122,8 +169,9 @@ func (api *API) GetDevice(c *gin.Context) {
 // @Success      200  {object}  models.Device
 // @Failure		 401  {object}  models.BaseError
 // @Failure      400  {object}  models.BaseError
+// @Failure      403  {object}  models.QuotaExceededError
 // @Failure      404  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Router       /api/devices/{id} [patch]
 func (api *API) UpdateDevice(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "UpdateDevice", trace.WithAttributes(
@@ -141,15 +189,21 @@ func (api *API) UpdateDevice(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
 		return
 	}
+	if err := models.ValidateLabels(request.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("labels", err.Error()))
+		return
+	}
 
 	var device models.Device
 	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		ctx := ipam.WithTransaction(ctx, tx)
 		result := tx.
-			Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+			Scopes(api.DeviceIsManageableByCurrentUser(c)).
 			First(&device, "id = ?", k)
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return errDeviceNotFound
 		}
+		before := device
 
 		var org models.Organization
 		if result = tx.First(&org, "id = ?", device.OrganizationID); result.Error != nil {
@@ -173,53 +227,26 @@ func (api *API) UpdateDevice(c *gin.Context) {
 			device.Endpoints = request.Endpoints
 		}
 
+		if request.Labels != nil {
+			device.Labels = request.Labels
+			if err := setDeviceLabels(tx, device); err != nil {
+				return err
+			}
+		}
+
 		if request.OrganizationID != uuid.Nil && request.OrganizationID != device.OrganizationID {
 			userId := c.GetString(gin.AuthUserKey)
 
 			var org models.Organization
 			if res := tx.Model(&org).
-				Joins("inner join user_organizations on user_organizations.organization_id=organizations.id").
-				Where("user_organizations.user_id=? AND organizations.id=?", userId, request.OrganizationID).
-				First(&org); res.Error != nil {
+				Scopes(organizationHasMember(userId, models.RoleMember)).
+				First(&org, "organizations.id = ?", request.OrganizationID); res.Error != nil {
 				return errUserOrOrgNotFound
 			}
 
-			newIpamNamespace := defaultIPAMNamespace
-			if org.PrivateCidr {
-				newIpamNamespace = org.ID
-			}
-
-			// We can reuse the ip address if the ipam namespace is not changing.
-			if originalIpamNamespace != newIpamNamespace {
-				if err := api.ipam.ReleaseToPool(c.Request.Context(), originalIpamNamespace, device.TunnelIP, device.OrganizationPrefix); err != nil {
-					c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the v4 address to pool: %w", err)))
-					return err
-				}
-
-				if err := api.ipam.ReleaseToPool(c.Request.Context(), originalIpamNamespace, device.TunnelIpV6, device.OrganizationPrefixV6); err != nil {
-					c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the v6 address to pool: %w", err)))
-					return err
-				}
-
-				device.TunnelIP, err = api.ipam.AssignFromPool(ctx, newIpamNamespace, org.IpCidr)
-				if err != nil {
-					return fmt.Errorf("failed to request ipam address: %w", err)
-				}
-				device.OrganizationPrefix = org.IpCidr
-
-				device.TunnelIpV6, err = api.ipam.AssignFromPool(ctx, newIpamNamespace, org.IpCidrV6)
-				if err != nil {
-					return fmt.Errorf("failed to request ipam v6 address: %w", err)
-				}
-				device.OrganizationPrefixV6 = org.IpCidrV6
-			}
-
-			device.AllowedIPs, err = getAllowedIPs(device.TunnelIP, device.TunnelIpV6, device.Relay)
-			if err != nil {
+			if err := api.moveDevice(ctx, tx, &device, originalIpamNamespace, org); err != nil {
 				return err
 			}
-
-			device.OrganizationID = request.OrganizationID
 		}
 
 		device.SymmetricNat = request.SymmetricNat
@@ -261,12 +288,23 @@ func (api *API) UpdateDevice(c *gin.Context) {
 			return res.Error
 		}
 
-		return nil
+		if before.OrganizationID != device.OrganizationID {
+			if err := api.queueWebhookEvent(tx, before.OrganizationID, models.WebhookEventDeviceLeft, before); err != nil {
+				return err
+			}
+			if err := api.queueWebhookEvent(tx, device.OrganizationID, models.WebhookEventDeviceJoined, device); err != nil {
+				return err
+			}
+		}
+		return api.recordAuditEvent(c, tx, device.OrganizationID, models.AuditActionUpdate, "device", device.ID.String(), before, device)
 	})
 
 	if err != nil {
+		var quotaExceeded errQuotaExceeded
 		if errors.Is(err, errDeviceNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else if errors.As(err, &quotaExceeded) {
+			c.JSON(http.StatusForbidden, quotaExceeded.Response())
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
@@ -274,9 +312,64 @@ func (api *API) UpdateDevice(c *gin.Context) {
 	}
 
 	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", device.OrganizationID.String()))
+	api.signalBus.Notify(webhooksSignal)
 	c.JSON(http.StatusOK, device)
 }
 
+// organizationHasMember limits the query to the organizations the user is a member of with at least the role.
+func organizationHasMember(userId string, role string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Joins("inner join user_organizations on user_organizations.organization_id=organizations.id").
+			Where("user_organizations.user_id=? AND user_organizations.role in ?", userId, models.RolesAtLeast(role))
+	}
+}
+
+// moveDevice moves the device to the organization. The device keeps its addresses if the
+// ipam namespace of the organization is the same, otherwise new ones are assigned.
+func (api *API) moveDevice(ctx context.Context, tx *gorm.DB, device *models.Device, ipamNamespace uuid.UUID, org models.Organization) error {
+	if err := checkQuota(tx, quotaDevicesPerOrganization, api.quotas.DevicesPerOrganization, &models.Organization{}, org.ID, &models.Device{}, "organization_id = ?", org.ID); err != nil {
+		return err
+	}
+
+	var err error
+	newIpamNamespace := defaultIPAMNamespace
+	if org.PrivateCidr {
+		newIpamNamespace = org.ID
+	}
+
+	// We can reuse the ip address if the ipam namespace is not changing.
+	if ipamNamespace != newIpamNamespace {
+		if err := api.releaseUnreservedAddress(ctx, tx, ipamNamespace, device.OrganizationID, device.TunnelIP, device.OrganizationPrefix); err != nil {
+			return fmt.Errorf("failed to release the v4 address to pool: %w", err)
+		}
+
+		if err := api.releaseUnreservedAddress(ctx, tx, ipamNamespace, device.OrganizationID, device.TunnelIpV6, device.OrganizationPrefixV6); err != nil {
+			return fmt.Errorf("failed to release the v6 address to pool: %w", err)
+		}
+
+		device.TunnelIP, err = api.ipam.AssignFromPool(ctx, newIpamNamespace, org.IpCidr)
+		if err != nil {
+			return fmt.Errorf("failed to request ipam address: %w", err)
+		}
+		device.OrganizationPrefix = org.IpCidr
+
+		device.TunnelIpV6, err = api.ipam.AssignFromPool(ctx, newIpamNamespace, org.IpCidrV6)
+		if err != nil {
+			return fmt.Errorf("failed to request ipam v6 address: %w", err)
+		}
+		device.OrganizationPrefixV6 = org.IpCidrV6
+	}
+
+	device.AllowedIPs, err = getAllowedIPs(device.TunnelIP, device.TunnelIpV6, device.Relay)
+	if err != nil {
+		return err
+	}
+
+	device.OrganizationID = org.ID
+	return nil
+}
+
 func getAllowedIPs(ip string, ip6 string, relay bool) ([]string, error) {
 	var err error
 
@@ -317,8 +410,9 @@ func getAllowedIPs(ip string, ip6 string, relay bool) ([]string, error) {
 // @Success      201  {object}  models.Device
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.QuotaExceededError
 // @Failure      409  {object}  models.ConflictsError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/devices [post]
 func (api *API) CreateDevice(c *gin.Context) {
@@ -335,16 +429,30 @@ func (api *API) CreateDevice(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("public_key"))
 		return
 	}
+	if err := models.ValidateLabels(request.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("labels", err.Error()))
+		return
+	}
 
 	userId := c.GetString(gin.AuthUserKey)
+	regKey, usingRegKey := RegKeyFromContext(c)
+	if usingRegKey {
+		if request.OrganizationID == uuid.Nil {
+			request.OrganizationID = regKey.OrganizationID
+		} else if request.OrganizationID != regKey.OrganizationID {
+			c.JSON(http.StatusNotFound, models.NewNotAllowedError("user or organization"))
+			return
+		}
+	}
 	var device models.Device
 
 	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		ctx := ipam.WithTransaction(ctx, tx)
 
 		var org models.Organization
 		if res := tx.Model(&org).
 			Joins("inner join user_organizations on user_organizations.organization_id=organizations.id").
-			Where("user_organizations.user_id=? AND organizations.id=?", userId, request.OrganizationID).
+			Where("user_organizations.user_id=? AND organizations.id=? AND user_organizations.role in ?", userId, request.OrganizationID, models.RolesAtLeast(models.RoleMember)).
 			First(&org); res.Error != nil {
 			return errUserOrOrgNotFound
 		}
@@ -357,6 +465,14 @@ func (api *API) CreateDevice(c *gin.Context) {
 			return res.Error
 		}
 
+		if usingRegKey && regKey.SingleUse && regKey.DeviceID != uuid.Nil {
+			return errRegKeyUsed
+		}
+
+		if err := checkQuota(tx, quotaDevicesPerOrganization, api.quotas.DevicesPerOrganization, &models.Organization{}, org.ID, &models.Device{}, "organization_id = ?", org.ID); err != nil {
+			return err
+		}
+
 		ipamNamespace := defaultIPAMNamespace
 		if org.PrivateCidr {
 			ipamNamespace = org.ID
@@ -368,12 +484,20 @@ func (api *API) CreateDevice(c *gin.Context) {
 			relay = true
 		}
 
-		var ipamIP string
-		var ipamIPv6 string
-		var err error
+		// the reserved addresses of the device are already allocated by the reservation.
+		var regKeyId uuid.UUID
+		if usingRegKey {
+			regKeyId = regKey.ID
+		}
+		ipamIP, ipamIPv6, err := reservedDeviceAddresses(tx, org, request.PublicKey, request.Hostname, regKeyId)
+		if err != nil {
+			return err
+		}
 		// If this was a static address request
 		// TODO: handle a user requesting an IP not in the IPAM prefix
-		if request.TunnelIP != "" {
+		if ipamIP != "" {
+			api.logger.Debugf("assigning the reserved address %s to device %s", ipamIP, request.PublicKey)
+		} else if request.TunnelIP != "" {
 			ipamIP, err = api.ipam.AssignSpecificTunnelIP(ctx, ipamNamespace, org.IpCidr, request.TunnelIP)
 			if err != nil {
 				return fmt.Errorf("failed to request specific ipam address: %w", err)
@@ -385,9 +509,11 @@ func (api *API) CreateDevice(c *gin.Context) {
 			}
 		}
 		// Currently only support v4 requesting of specific addresses
-		ipamIPv6, err = api.ipam.AssignFromPool(ctx, ipamNamespace, org.IpCidrV6)
-		if err != nil {
-			return fmt.Errorf("failed to request ipam v6 address: %w", err)
+		if ipamIPv6 == "" {
+			ipamIPv6, err = api.ipam.AssignFromPool(ctx, ipamNamespace, org.IpCidrV6)
+			if err != nil {
+				return fmt.Errorf("failed to request ipam v6 address: %w", err)
+			}
 		}
 
 		// allocate a child prefix if requested
@@ -426,6 +552,11 @@ func (api *API) CreateDevice(c *gin.Context) {
 			Hostname:                 request.Hostname,
 			Os:                       request.Os,
 			SecurityGroupId:          org.SecurityGroupId,
+			Labels:                   request.Labels,
+		}
+		if usingRegKey {
+			device.Ephemeral = regKey.Ephemeral
+			device.RegKeyID = regKey.ID
 		}
 
 		if res := tx.
@@ -433,6 +564,21 @@ func (api *API) CreateDevice(c *gin.Context) {
 			Create(&device); res.Error != nil {
 			return res.Error
 		}
+		if err := setDeviceLabels(tx, device); err != nil {
+			return err
+		}
+
+		if usingRegKey {
+			if err := api.applyRegKeyToDevice(tx, regKey, device); err != nil {
+				return err
+			}
+		}
+		if err := api.recordAuditEvent(c, tx, device.OrganizationID, models.AuditActionCreate, "device", device.ID.String(), nil, device); err != nil {
+			return err
+		}
+		if err := api.queueWebhookEvent(tx, device.OrganizationID, models.WebhookEventDeviceJoined, device); err != nil {
+			return err
+		}
 		span.SetAttributes(
 			attribute.String("id", device.ID.String()),
 		)
@@ -442,10 +588,15 @@ func (api *API) CreateDevice(c *gin.Context) {
 
 	if err != nil {
 		var duplicate errDuplicateDevice
+		var quotaExceeded errQuotaExceeded
 		if errors.Is(err, errUserOrOrgNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotAllowedError("user or organization"))
 		} else if errors.As(err, &duplicate) {
 			c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+		} else if errors.As(err, &quotaExceeded) {
+			c.JSON(http.StatusForbidden, quotaExceeded.Response())
+		} else if errors.Is(err, errRegKeyUsed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
@@ -453,6 +604,10 @@ func (api *API) CreateDevice(c *gin.Context) {
 	}
 
 	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", device.OrganizationID.String()))
+	api.signalBus.Notify(webhooksSignal)
+	if usingRegKey && len(regKey.Tags) > 0 {
+		api.signalBus.Notify(fmt.Sprintf("/metadata/org=%s", device.OrganizationID.String()))
+	}
 	c.JSON(http.StatusCreated, device)
 }
 
@@ -466,7 +621,7 @@ func (api *API) CreateDevice(c *gin.Context) {
 // @Param        id   path      string  true "Device ID"
 // @Success      204  {object}  models.Device
 // @Failure      400  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/devices/{id} [delete]
 func (api *API) DeleteDevice(c *gin.Context) {
@@ -480,7 +635,7 @@ func (api *API) DeleteDevice(c *gin.Context) {
 
 	device := models.Device{}
 	if res := api.db.
-		Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+		Scopes(api.DeviceIsManageableByCurrentUser(c)).
 		First(&device, "id = ?", deviceID); res.Error != nil {
 
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
@@ -503,44 +658,148 @@ func (api *API) DeleteDevice(c *gin.Context) {
 		ipamNamespace = org.ID
 	}
 
-	ipamAddress := device.TunnelIP
-	orgPrefix := device.OrganizationPrefix
-	childPrefix := device.ChildPrefix
-
-	if res := api.db.WithContext(ctx).
-		Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
-		Delete(&device, "id = ?", device.Base.ID); res.Error != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiInternalError(res.Error))
+	var releases ipamReleases
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		releases = ipamReleases{}
+		if err := api.deleteDevice(c, tx, &device); err != nil {
+			return err
+		}
+		return api.releaseDeviceAddresses(withIPAMReleases(ctx, tx, &releases), tx, ipamNamespace, device)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiInternalError(err))
 		return
 	}
 
 	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", device.OrganizationID.String()))
+	api.signalBus.Notify(webhooksSignal)
 
-	if ipamAddress != "" && orgPrefix != "" {
-		if err := api.ipam.ReleaseToPool(c.Request.Context(), ipamNamespace, ipamAddress, orgPrefix); err != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the v4 address to pool: %w", err)))
-			return
+	if err := releases.run(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// deleteDevice deletes the device and records the events of the deletion.
+func (api *API) deleteDevice(c *gin.Context, tx *gorm.DB, device *models.Device) error {
+	if err := api.removeDevice(tx, device); err != nil {
+		return err
+	}
+	return api.recordAuditEvent(c, tx, device.OrganizationID, models.AuditActionDelete, "device", device.ID.String(), *device, nil)
+}
+
+// removeDevice deletes the device and queues the webhook events of the deletion.
+func (api *API) removeDevice(tx *gorm.DB, device *models.Device) error {
+	if res := tx.
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+		Delete(device, "id = ?", device.Base.ID); res.Error != nil {
+		return res.Error
+	}
+	if res := tx.Delete(&models.DeviceLabel{}, "device_id = ?", device.Base.ID); res.Error != nil {
+		return res.Error
+	}
+	if err := api.queueWebhookEvent(tx, device.OrganizationID, models.WebhookEventDeviceLeft, *device); err != nil {
+		return err
+	}
+	// a relay that was already reported down isn't reported again.
+	heartbeat := tx.Where("device_id = ? AND down = ?", device.Base.ID, true).Delete(&models.DeviceHeartbeat{})
+	if heartbeat.Error != nil {
+		return heartbeat.Error
+	}
+	if res := tx.Delete(&models.DeviceHeartbeat{}, "device_id = ?", device.Base.ID); res.Error != nil {
+		return res.Error
+	}
+	if device.Relay && heartbeat.RowsAffected == 0 {
+		return api.queueWebhookEvent(tx, device.OrganizationID, models.WebhookEventRelayDown, *device)
+	}
+	return nil
+}
+
+// ipamReleases are the releases of the addresses and prefixes of the devices deleted in a transaction.
+// The database ipam releases them in the transaction, but the other ipams can't roll back a release:
+// if the transaction failed, or was retried, the addresses could be allocated again while the devices
+// still use them. Their releases are deferred until the transaction committed.
+type ipamReleases struct {
+	deferred []func(ctx context.Context) error
+}
+
+type ipamReleasesKey struct{}
+
+// withIPAMReleases returns the context of the transaction, see ipam.WithTransaction, in which the
+// releases that can't be rolled back are deferred to releases. A retried transaction should start
+// with empty releases.
+func withIPAMReleases(ctx context.Context, tx *gorm.DB, releases *ipamReleases) context.Context {
+	return context.WithValue(ipam.WithTransaction(ctx, tx), ipamReleasesKey{}, releases)
+}
+
+// release releases with the ipam, unless the ipam isn't transactional and the context defers the
+// releases, see withIPAMReleases.
+func (api *API) release(ctx context.Context, release func(ctx context.Context) error) error {
+	if releases, ok := ctx.Value(ipamReleasesKey{}).(*ipamReleases); ok {
+		if _, transactional := api.ipam.(*ipam.DatabaseIPAM); !transactional {
+			releases.deferred = append(releases.deferred, release)
+			return nil
 		}
 	}
+	return release(ctx)
+}
 
-	for _, prefix := range childPrefix {
-		if err := api.ipam.ReleasePrefix(c.Request.Context(), ipamNamespace, prefix); err != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release child prefix: %w", err)))
-			return
+// run makes the deferred releases, once the transaction committed.
+func (r *ipamReleases) run(ctx context.Context) error {
+	var errs []error
+	for _, release := range r.deferred {
+		if err := release(ctx); err != nil {
+			errs = append(errs, err)
 		}
 	}
+	r.deferred = nil
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to release the addresses of the deleted devices: %w", errors.Join(errs...))
+	}
+	return nil
+}
 
-	ipamAddressV6 := device.TunnelIpV6
-	orgPrefixV6 := device.OrganizationPrefixV6
+// releaseDeviceAddresses releases the addresses and child prefixes of a deleted device, it should be
+// called with the context of the transaction that deletes the device, see withIPAMReleases.
+// The reserved addresses stay allocated for the next device with the identity of the reservation.
+func (api *API) releaseDeviceAddresses(ctx context.Context, tx *gorm.DB, ipamNamespace uuid.UUID, device models.Device) error {
+	if device.TunnelIP != "" && device.OrganizationPrefix != "" {
+		if err := api.releaseUnreservedAddress(ctx, tx, ipamNamespace, device.OrganizationID, device.TunnelIP, device.OrganizationPrefix); err != nil {
+			return fmt.Errorf("failed to release the v4 address to pool: %w", err)
+		}
+	}
 
-	if ipamAddressV6 != "" && orgPrefixV6 != "" {
-		if err := api.ipam.ReleaseToPool(c.Request.Context(), ipamNamespace, ipamAddressV6, orgPrefixV6); err != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the v6 address to pool: %w", err)))
-			return
+	for _, prefix := range device.ChildPrefix {
+		prefix := prefix
+		if err := api.release(ctx, func(ctx context.Context) error {
+			if err := api.ipam.ReleasePrefix(ctx, ipamNamespace, prefix); err != nil {
+				return fmt.Errorf("failed to release child prefix: %w", err)
+			}
+			return nil
+		}); err != nil {
+			return err
 		}
 	}
 
-	c.JSON(http.StatusOK, device)
+	if device.TunnelIpV6 != "" && device.OrganizationPrefixV6 != "" {
+		if err := api.releaseUnreservedAddress(ctx, tx, ipamNamespace, device.OrganizationID, device.TunnelIpV6, device.OrganizationPrefixV6); err != nil {
+			return fmt.Errorf("failed to release the v6 address to pool: %w", err)
+		}
+	}
+	return nil
+}
+
+// releaseUnreservedAddress releases the address of a device of the organization unless it is reserved.
+func (api *API) releaseUnreservedAddress(ctx context.Context, tx *gorm.DB, ipamNamespace uuid.UUID, orgId uuid.UUID, address string, prefix string) error {
+	reserved, err := isReservedAddress(tx, orgId, address)
+	if err != nil || reserved {
+		return err
+	}
+	return api.release(ctx, func(ctx context.Context) error {
+		return api.ipam.ReleaseToPool(ctx, ipamNamespace, address, prefix)
+	})
 }
 
 func childPrefixEquals(existingPrefix, newPrefix []string) bool {
@@ -0,0 +1,486 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxDeviceBatchSize limits the number of devices that a batch operation can select.
+const maxDeviceBatchSize = 1000
+
+var errDeviceBatchFailed = errors.New("the batch operation failed on some devices")
+
+// errDeviceBatchItem fails the batch operation on one device.
+type errDeviceBatchItem struct {
+	Status int
+	Reason string
+}
+
+func (e errDeviceBatchItem) Error() string {
+	return e.Reason
+}
+
+// deviceBatch is an operation applied to all the selected devices in a single transaction.
+type deviceBatch struct {
+	// scope limits the devices that can be selected.
+	scope func(db *gorm.DB) *gorm.DB
+	// validate checks that the operation can be applied to the device. The batch is only applied
+	// once all the devices are valid, so validate must not have side effects.
+	validate func(tx *gorm.DB, device *models.Device) error
+	// apply is called with the context of the transaction, see withIPAMReleases.
+	apply func(ctx context.Context, tx *gorm.DB, device *models.Device) error
+	// committed is called with the updated devices after the transaction has been committed.
+	committed func(devices []models.Device)
+}
+
+// BatchDeleteDevices deletes many devices
+// @Summary      Batch Delete Devices
+// @Description  Deletes the selected devices and their IPAM leases in a single transaction
+// @Id           BatchDeleteDevices
+// @Tags         Devices
+// @Accept       json
+// @Produce      json
+// @Param        batch  body      models.DeviceBatchDelete  true  "Devices to delete"
+// @Success      200    {object}  models.DeviceBatchResult
+// @Failure      400    {object}  models.BaseError
+// @Failure		 401    {object}  models.BaseError
+// @Failure      422    {object}  models.DeviceBatchResult
+// @Failure		 429    {object}  models.TooManyRequestsError
+// @Failure      500    {object}  models.BaseError
+// @Router       /api/devices/batch/delete [post]
+func (api *API) BatchDeleteDevices(c *gin.Context) {
+	var request models.DeviceBatchDelete
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	ipamNamespaces := map[uuid.UUID]uuid.UUID{}
+	api.runDeviceBatch(c, "BatchDeleteDevices", request.Selector, deviceBatch{
+		scope: api.DeviceIsManageableByCurrentUser(c),
+		apply: func(ctx context.Context, tx *gorm.DB, device *models.Device) error {
+			if err := api.deleteDevice(c, tx, device); err != nil {
+				return err
+			}
+			ipamNamespace, ok := ipamNamespaces[device.OrganizationID]
+			if !ok {
+				var org models.Organization
+				if res := tx.Unscoped().First(&org, "id = ?", device.OrganizationID); res.Error != nil {
+					return res.Error
+				}
+				ipamNamespace = defaultIPAMNamespace
+				if org.PrivateCidr {
+					ipamNamespace = org.ID
+				}
+				ipamNamespaces[device.OrganizationID] = ipamNamespace
+			}
+			return api.releaseDeviceAddresses(ctx, tx, ipamNamespace, *device)
+		},
+		committed: func(devices []models.Device) {
+			for orgId := range ipamNamespaces {
+				api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", orgId.String()))
+			}
+		},
+	})
+}
+
+// BatchMoveDevices moves many devices to an organization
+// @Summary      Batch Move Devices
+// @Description  Moves the selected devices to an organization in a single transaction
+// @Id           BatchMoveDevices
+// @Tags         Devices
+// @Accept       json
+// @Produce      json
+// @Param        batch  body      models.DeviceBatchMove  true  "Devices to move"
+// @Success      200    {object}  models.DeviceBatchResult
+// @Failure      400    {object}  models.BaseError
+// @Failure		 401    {object}  models.BaseError
+// @Failure      404    {object}  models.BaseError
+// @Failure      422    {object}  models.DeviceBatchResult
+// @Failure		 429    {object}  models.TooManyRequestsError
+// @Failure      500    {object}  models.BaseError
+// @Router       /api/devices/batch/move [post]
+func (api *API) BatchMoveDevices(c *gin.Context) {
+	var request models.DeviceBatchMove
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.OrganizationID == uuid.Nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("organization_id"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(c.Request.Context()).
+		Model(&org).
+		Scopes(organizationHasMember(c.GetString(gin.AuthUserKey), models.RoleMember)).
+		First(&org, "organizations.id = ?", request.OrganizationID); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		}
+		return
+	}
+
+	orgs := map[uuid.UUID]models.Organization{}
+	api.runDeviceBatch(c, "BatchMoveDevices", request.Selector, deviceBatch{
+		scope: api.deviceIsOwnedByCurrentUser(c, models.RoleMember),
+		validate: func(tx *gorm.DB, device *models.Device) error {
+			if _, ok := orgs[device.OrganizationID]; !ok {
+				var from models.Organization
+				if res := tx.First(&from, "id = ?", device.OrganizationID); res.Error != nil {
+					return res.Error
+				}
+				orgs[from.ID] = from
+			}
+			return nil
+		},
+		apply: func(ctx context.Context, tx *gorm.DB, device *models.Device) error {
+			if device.OrganizationID == org.ID {
+				return nil
+			}
+			before := *device
+			from := orgs[device.OrganizationID]
+			ipamNamespace := defaultIPAMNamespace
+			if from.PrivateCidr {
+				ipamNamespace = from.ID
+			}
+			if err := api.moveDevice(ctx, tx, device, ipamNamespace, org); err != nil {
+				var quotaExceeded errQuotaExceeded
+				if errors.As(err, &quotaExceeded) {
+					return errDeviceBatchItem{Status: http.StatusForbidden, Reason: quotaExceeded.Error()}
+				}
+				return err
+			}
+			if res := tx.
+				Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+				Save(device); res.Error != nil {
+				return res.Error
+			}
+			if err := api.queueWebhookEvent(tx, before.OrganizationID, models.WebhookEventDeviceLeft, before); err != nil {
+				return err
+			}
+			if err := api.queueWebhookEvent(tx, device.OrganizationID, models.WebhookEventDeviceJoined, *device); err != nil {
+				return err
+			}
+			return api.recordAuditEvent(c, tx, device.OrganizationID, models.AuditActionUpdate, "device", device.ID.String(), before, *device)
+		},
+		committed: func(devices []models.Device) {
+			for orgId := range orgs {
+				if orgId != org.ID {
+					api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", orgId.String()))
+				}
+			}
+			api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", org.ID.String()))
+		},
+	})
+}
+
+// BatchUpdateDevicesSecurityGroup sets the security group of many devices
+// @Summary      Batch Update Devices Security Group
+// @Description  Sets the security group of the selected devices in a single transaction
+// @Id           BatchUpdateDevicesSecurityGroup
+// @Tags         Devices
+// @Accept       json
+// @Produce      json
+// @Param        batch  body      models.DeviceBatchSecurityGroup  true  "Devices to update"
+// @Success      200    {object}  models.DeviceBatchResult
+// @Failure      400    {object}  models.BaseError
+// @Failure		 401    {object}  models.BaseError
+// @Failure      404    {object}  models.BaseError
+// @Failure      422    {object}  models.DeviceBatchResult
+// @Failure		 429    {object}  models.TooManyRequestsError
+// @Failure      500    {object}  models.BaseError
+// @Router       /api/devices/batch/security_group [post]
+func (api *API) BatchUpdateDevicesSecurityGroup(c *gin.Context) {
+	var request models.DeviceBatchSecurityGroup
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.SecurityGroupID == uuid.Nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("security_group_id"))
+		return
+	}
+
+	// the security groups of the organizations that the user can't read are not found, like the
+	// security groups that don't exist.
+	var sg models.SecurityGroup
+	db := api.db.WithContext(c.Request.Context())
+	if res := db.
+		Where("organization_id IN (?)", db.Model(&models.Organization{}).Select("id").Scopes(api.OrganizationIsReadableByCurrentUser(c))).
+		First(&sg, "id = ?", request.SecurityGroupID); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("security group"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		}
+		return
+	}
+
+	api.runDeviceBatch(c, "BatchUpdateDevicesSecurityGroup", request.Selector, deviceBatch{
+		scope: api.DeviceIsManageableByCurrentUser(c),
+		validate: func(tx *gorm.DB, device *models.Device) error {
+			if sg.OrganizationId != device.OrganizationID {
+				return errDeviceBatchItem{Status: http.StatusBadRequest, Reason: "the security group does not belong to the organization of the device"}
+			}
+			return nil
+		},
+		apply: func(ctx context.Context, tx *gorm.DB, device *models.Device) error {
+			before := *device
+			device.SecurityGroupId = sg.ID
+			if res := tx.
+				Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+				Save(device); res.Error != nil {
+				return res.Error
+			}
+			return api.recordAuditEvent(c, tx, device.OrganizationID, models.AuditActionUpdate, "device", device.ID.String(), before, *device)
+		},
+		committed: func(devices []models.Device) {
+			api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", sg.OrganizationId.String()))
+		},
+	})
+}
+
+// BatchUpdateDevicesMetadata sets a metadata key on many devices
+// @Summary      Batch Update Devices Metadata
+// @Description  Sets a metadata key on the selected devices in a single transaction
+// @Id           BatchUpdateDevicesMetadata
+// @Tags         Devices
+// @Accept       json
+// @Produce      json
+// @Param        batch  body      models.DeviceBatchMetadata  true  "Devices to update"
+// @Success      200    {object}  models.DeviceBatchResult
+// @Failure      400    {object}  models.BaseError
+// @Failure		 401    {object}  models.BaseError
+// @Failure      422    {object}  models.DeviceBatchResult
+// @Failure		 429    {object}  models.TooManyRequestsError
+// @Failure      500    {object}  models.BaseError
+// @Router       /api/devices/batch/metadata [post]
+func (api *API) BatchUpdateDevicesMetadata(c *gin.Context) {
+	var request models.DeviceBatchMetadata
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.Key == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("key"))
+		return
+	}
+
+	api.runDeviceBatch(c, "BatchUpdateDevicesMetadata", request.Selector, deviceBatch{
+		scope: api.DeviceIsManageableByCurrentUser(c),
+		validate: func(tx *gorm.DB, device *models.Device) error {
+			// only adding a new key counts against the quota.
+			err := checkQuota(tx, quotaMetadataKeysPerDevice, api.quotas.MetadataKeysPerDevice, &models.Device{}, device.ID, &models.DeviceMetadata{}, "device_id = ? AND key <> ?", device.ID, request.Key)
+			var quotaExceeded errQuotaExceeded
+			if errors.As(err, &quotaExceeded) {
+				return errDeviceBatchItem{Status: http.StatusForbidden, Reason: quotaExceeded.Error()}
+			}
+			return err
+		},
+		apply: func(ctx context.Context, tx *gorm.DB, device *models.Device) error {
+			metadata := models.DeviceMetadata{
+				DeviceID: device.ID,
+				Key:      request.Key,
+				Value:    request.Value,
+			}
+			return tx.
+				Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+				Save(&metadata).Error
+		},
+		committed: func(devices []models.Device) {
+			orgIds := map[uuid.UUID]struct{}{}
+			for _, device := range devices {
+				orgIds[device.OrganizationID] = struct{}{}
+			}
+			for orgId := range orgIds {
+				api.signalBus.Notify(fmt.Sprintf("/metadata/org=%s", orgId.String()))
+			}
+		},
+	})
+}
+
+// runDeviceBatch selects the devices and applies the batch operation to them in a single transaction.
+// The transaction is rolled back if the operation fails on any of the devices.
+func (api *API) runDeviceBatch(c *gin.Context, name string, selector models.DeviceSelector, batch deviceBatch) {
+	ctx, span := tracer.Start(c.Request.Context(), name, trace.WithAttributes(
+		attribute.Int("ids", len(selector.IDs)),
+		attribute.String("hostname", selector.Hostname),
+		attribute.String("metadata_key", selector.MetadataKey),
+		attribute.String("labels", selector.Labels),
+	))
+	defer span.End()
+
+	if len(selector.IDs) == 0 && selector.OrganizationID == uuid.Nil && selector.Hostname == "" && selector.MetadataKey == "" && selector.Labels == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("selector", "at least one of ids, organization_id, hostname, metadata_key or labels is required"))
+		return
+	}
+	if len(selector.IDs) > maxDeviceBatchSize {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("selector.ids", fmt.Sprintf("at most %d devices can be selected", maxDeviceBatchSize)))
+		return
+	}
+	if selector.MetadataKey == "" && selector.MetadataValue != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("selector.metadata_key"))
+		return
+	}
+	if _, err := models.ParseLabelSelector(selector.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("selector.labels", err.Error()))
+		return
+	}
+
+	var devices []models.Device
+	var releases ipamReleases
+	result := models.DeviceBatchResult{}
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		releases = ipamReleases{}
+		ctx := withIPAMReleases(ctx, tx, &releases)
+		db, err := api.deviceSelectorScope(tx.Scopes(batch.scope), selector)
+		if err != nil {
+			return err
+		}
+		if res := db.Limit(maxDeviceBatchSize + 1).Find(&devices); res.Error != nil {
+			return res.Error
+		}
+		if len(devices) > maxDeviceBatchSize {
+			return errDeviceBatchItem{Status: http.StatusBadRequest, Reason: fmt.Sprintf("at most %d devices can be selected", maxDeviceBatchSize)}
+		}
+
+		failed := false
+		found := map[uuid.UUID]struct{}{}
+		result.Results = make([]models.DeviceBatchItemResult, 0, len(devices))
+		for i := range devices {
+			found[devices[i].ID] = struct{}{}
+			item := models.DeviceBatchItemResult{DeviceID: devices[i].ID, Status: http.StatusOK}
+			if batch.validate != nil {
+				if err := batch.validate(tx, &devices[i]); err != nil {
+					var itemErr errDeviceBatchItem
+					if !errors.As(err, &itemErr) {
+						return err
+					}
+					item.Status = itemErr.Status
+					item.Error = itemErr.Reason
+					failed = true
+				}
+			}
+			result.Results = append(result.Results, item)
+		}
+		for _, id := range selector.IDs {
+			if _, ok := found[id]; !ok {
+				found[id] = struct{}{}
+				result.Results = append(result.Results, models.DeviceBatchItemResult{DeviceID: id, Status: http.StatusNotFound, Error: errDeviceNotFound.Error()})
+				failed = true
+			}
+		}
+		if failed {
+			return errDeviceBatchFailed
+		}
+
+		for i := range devices {
+			if err := batch.apply(ctx, tx, &devices[i]); err != nil {
+				var itemErr errDeviceBatchItem
+				if !errors.As(err, &itemErr) {
+					return err
+				}
+				result.Results[i].Status = itemErr.Status
+				result.Results[i].Error = itemErr.Reason
+				return errDeviceBatchFailed
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		var itemErr errDeviceBatchItem
+		switch {
+		case errors.Is(err, errDeviceBatchFailed):
+			// the devices that did not fail were not changed either.
+			for i := range result.Results {
+				if result.Results[i].Status == http.StatusOK {
+					result.Results[i].Status = http.StatusFailedDependency
+				}
+			}
+			c.JSON(http.StatusUnprocessableEntity, result)
+		case errors.As(err, &itemErr):
+			c.JSON(itemErr.Status, models.NewFieldValidationError("selector", itemErr.Reason))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+
+	result.Committed = true
+	// the batch is committed, the ipam reconciler reports the addresses that failed to be released.
+	if err := releases.run(ctx); err != nil {
+		api.Logger(ctx).Errorf("%s: %v", name, err)
+	}
+	if batch.committed != nil && len(devices) > 0 {
+		batch.committed(devices)
+		api.signalBus.Notify(webhooksSignal)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// deviceSelectorScope limits the query to the devices matched by the selector.
+func (api *API) deviceSelectorScope(db *gorm.DB, selector models.DeviceSelector) (*gorm.DB, error) {
+	if len(selector.IDs) > 0 {
+		db = db.Where("id in ?", selector.IDs)
+	}
+	if selector.OrganizationID != uuid.Nil {
+		db = db.Where("organization_id = ?", selector.OrganizationID)
+	}
+	if selector.Hostname != "" {
+		db = db.Where(`hostname LIKE ? ESCAPE '\'`, hostnamePatternToLike(selector.Hostname))
+	}
+	if selector.MetadataKey != "" {
+		if selector.MetadataValue == nil {
+			db = db.Where("id in (SELECT device_id FROM device_metadata WHERE deleted_at IS NULL AND key = ?)", selector.MetadataKey)
+		} else {
+			value, err := json.Marshal(selector.MetadataValue)
+			if err != nil {
+				return nil, err
+			}
+			if api.dialect == database.DialectSqlLite {
+				// the values are stored as compact json text.
+				db = db.Where("id in (SELECT device_id FROM device_metadata WHERE deleted_at IS NULL AND key = ? AND value = ?)", selector.MetadataKey, string(value))
+			} else {
+				db = db.Where("id in (SELECT device_id FROM device_metadata WHERE deleted_at IS NULL AND key = ? AND value = CAST(? AS jsonb))", selector.MetadataKey, string(value))
+			}
+		}
+	}
+	if selector.Labels != "" {
+		labels, err := models.ParseLabelSelector(selector.Labels)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Scopes(labelSelectorScope(labels))
+	}
+	return db, nil
+}
+
+// hostnamePatternToLike converts a hostname pattern where * matches any characters
+// and ? matches a single character to a LIKE pattern.
+func hostnamePatternToLike(pattern string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`%`, `\%`,
+		`_`, `\_`,
+		`*`, `%`,
+		`?`, `_`,
+	)
+	return replacer.Replace(pattern)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestDeviceBatch() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	devices := map[string]models.Device{}
+	for hostname, env := range map[string]string{"lab-1": "lab", "lab-2": "lab", "prod-1": "prod"} {
+		reqBody, err := json.Marshal(models.AddDevice{
+			OrganizationID: suite.testOrganizationID,
+			PublicKey:      "batch-" + hostname,
+			Hostname:       hostname,
+			Labels:         map[string]string{"env": env},
+		})
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		require.Equal(http.StatusCreated, res.Code, res.Body.String())
+		var device models.Device
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+		devices[hostname] = device
+	}
+
+	batch := func(handler func(c *gin.Context), request interface{}) (int, models.DeviceBatchResult) {
+		reqBody, err := json.Marshal(request)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", handler, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		var result models.DeviceBatchResult
+		_ = json.Unmarshal(res.Body.Bytes(), &result)
+		return res.Code, result
+	}
+
+	// an empty selector would select all the devices.
+	code, _ := batch(suite.api.BatchDeleteDevices, models.DeviceBatchDelete{})
+	assert.Equal(http.StatusBadRequest, code)
+	code, _ = batch(suite.api.BatchDeleteDevices, models.DeviceBatchDelete{
+		Selector: models.DeviceSelector{Labels: "env in (lab"},
+	})
+	assert.Equal(http.StatusBadRequest, code)
+
+	// a device that does not exist fails the whole batch.
+	missing := uuid.New()
+	code, result := batch(suite.api.BatchUpdateDevicesMetadata, models.DeviceBatchMetadata{
+		Selector: models.DeviceSelector{IDs: []uuid.UUID{devices["lab-1"].ID, missing}},
+		Key:      "lab",
+		Value:    "teardown",
+	})
+	require.Equal(http.StatusUnprocessableEntity, code)
+	assert.False(result.Committed)
+	require.Len(result.Results, 2)
+	assert.Equal(models.DeviceBatchItemResult{DeviceID: devices["lab-1"].ID, Status: http.StatusFailedDependency}, result.Results[0])
+	assert.Equal(missing, result.Results[1].DeviceID)
+	assert.Equal(http.StatusNotFound, result.Results[1].Status)
+
+	var count int64
+	require.NoError(suite.api.db.Model(&models.DeviceMetadata{}).Where("key = ?", "lab").Count(&count).Error)
+	assert.Equal(int64(0), count)
+
+	code, result = batch(suite.api.BatchUpdateDevicesMetadata, models.DeviceBatchMetadata{
+		Selector: models.DeviceSelector{Hostname: "lab-?"},
+		Key:      "lab",
+		Value:    "teardown",
+	})
+	require.Equal(http.StatusOK, code)
+	assert.True(result.Committed)
+	assert.Len(result.Results, 2)
+
+	// the security groups of the organizations that the user can't read are not found, like the
+	// security groups that don't exist.
+	var org models.Organization
+	require.NoError(suite.api.db.First(&org, "id = ?", suite.testOrganizationID).Error)
+	var otherOrg models.Organization
+	require.NoError(suite.api.db.First(&otherOrg, "id = ?", suite.testUser2OrgID).Error)
+	for _, sgId := range []uuid.UUID{otherOrg.SecurityGroupId, uuid.New()} {
+		code, _ = batch(suite.api.BatchUpdateDevicesSecurityGroup, models.DeviceBatchSecurityGroup{
+			Selector:        models.DeviceSelector{MetadataKey: "lab", MetadataValue: "teardown"},
+			SecurityGroupID: sgId,
+		})
+		assert.Equal(http.StatusNotFound, code)
+	}
+
+	// the security group of another organization can't be used.
+	require.NoError(suite.api.db.Create(&UserOrganization{
+		UserID:         TestUserID,
+		OrganizationID: suite.testUser2OrgID,
+		Role:           models.RoleReadOnly,
+	}).Error)
+	code, result = batch(suite.api.BatchUpdateDevicesSecurityGroup, models.DeviceBatchSecurityGroup{
+		Selector:        models.DeviceSelector{MetadataKey: "lab", MetadataValue: "teardown"},
+		SecurityGroupID: otherOrg.SecurityGroupId,
+	})
+	require.Equal(http.StatusUnprocessableEntity, code)
+	require.Len(result.Results, 2)
+	assert.Equal(http.StatusBadRequest, result.Results[0].Status)
+
+	code, result = batch(suite.api.BatchUpdateDevicesSecurityGroup, models.DeviceBatchSecurityGroup{
+		Selector:        models.DeviceSelector{MetadataKey: "lab", MetadataValue: "teardown"},
+		SecurityGroupID: org.SecurityGroupId,
+	})
+	require.Equal(http.StatusOK, code)
+	assert.Len(result.Results, 2)
+
+	code, result = batch(suite.api.BatchDeleteDevices, models.DeviceBatchDelete{
+		Selector: models.DeviceSelector{OrganizationID: suite.testOrganizationID, Labels: "env in (lab,staging)"},
+	})
+	require.Equal(http.StatusOK, code)
+	assert.True(result.Committed)
+	assert.ElementsMatch([]uuid.UUID{devices["lab-1"].ID, devices["lab-2"].ID}, []uuid.UUID{result.Results[0].DeviceID, result.Results[1].DeviceID})
+
+	var remaining []models.Device
+	require.NoError(suite.api.db.Find(&remaining, "organization_id = ?", suite.testOrganizationID).Error)
+	require.Len(remaining, 1)
+	assert.Equal("prod-1", remaining[0].Hostname)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"gorm.io/gorm"
+)
+
+// setDeviceLabels replaces the indexed labels of the device with device.Labels.
+func setDeviceLabels(tx *gorm.DB, device models.Device) error {
+	if res := tx.Delete(&models.DeviceLabel{}, "device_id = ?", device.ID); res.Error != nil {
+		return res.Error
+	}
+	if len(device.Labels) == 0 {
+		return nil
+	}
+	labels := make([]models.DeviceLabel, 0, len(device.Labels))
+	for key, value := range device.Labels {
+		labels = append(labels, models.DeviceLabel{DeviceID: device.ID, Key: key, Value: value})
+	}
+	return tx.Create(&labels).Error
+}
+
+// labelSelectorScope limits the query to the devices with labels that match the selector.
+func labelSelectorScope(selector models.LabelSelector) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, r := range selector {
+			switch r.Operator {
+			case models.LabelExists:
+				db = db.Where("devices.id IN (SELECT device_id FROM device_labels WHERE key = ?)", r.Key)
+			case models.LabelDoesNotExist:
+				db = db.Where("devices.id NOT IN (SELECT device_id FROM device_labels WHERE key = ?)", r.Key)
+			case models.LabelEquals, models.LabelIn:
+				db = db.Where("devices.id IN (SELECT device_id FROM device_labels WHERE key = ? AND value IN ?)", r.Key, r.Values)
+			case models.LabelNotEquals, models.LabelNotIn:
+				db = db.Where("devices.id NOT IN (SELECT device_id FROM device_labels WHERE key = ? AND value IN ?)", r.Key, r.Values)
+			}
+		}
+		return db
+	}
+}
+
+// getSelectedDeviceList returns a getList function for watching the devices that match the selector.
+// The first list is filtered by the selector, the later ones send the devices that stop matching it
+// as deleted so that watchers drop them after a label change. A resumed watch doesn't know which
+// devices the watcher has, so all the changed devices that don't match are sent as deleted.
+func getSelectedDeviceList(selector models.LabelSelector, resumed bool) func(db *gorm.DB) (WatchableList, error) {
+	if len(selector) == 0 {
+		return getDeviceList
+	}
+	var matched map[uuid.UUID]bool
+	return func(db *gorm.DB) (WatchableList, error) {
+		first := matched == nil
+		if first {
+			matched = map[uuid.UUID]bool{}
+			if !resumed {
+				db = db.Scopes(labelSelectorScope(selector))
+			}
+		}
+		list, err := getDeviceList(db)
+		if err != nil {
+			return nil, err
+		}
+		devices := list.(deviceList)
+		result := selectedDeviceList{deviceList: devices, events: make([]selectedDeviceEvent, len(devices))}
+		for i, device := range devices {
+			switch {
+			case !device.DeletedAt.Valid && selector.Matches(device.Labels):
+				matched[device.ID] = true
+			case matched[device.ID] || first && resumed:
+				delete(matched, device.ID)
+				if !device.DeletedAt.Valid {
+					result.events[i] = selectedDeviceDeleted
+				}
+			default:
+				result.events[i] = selectedDeviceSkipped
+			}
+		}
+		return result, nil
+	}
+}
+
+type selectedDeviceEvent int
+
+const (
+	selectedDeviceChanged selectedDeviceEvent = iota
+	selectedDeviceDeleted
+	selectedDeviceSkipped
+)
+
+// selectedDeviceList is a watched device list where the devices that stopped matching the selector
+// are sent as deleted, and the devices that never matched it are skipped.
+type selectedDeviceList struct {
+	deviceList
+	events []selectedDeviceEvent
+}
+
+func (d selectedDeviceList) Item(i int) (any, uint64, gorm.DeletedAt) {
+	item, revision, deletedAt := d.deviceList.Item(i)
+	switch d.events[i] {
+	case selectedDeviceDeleted:
+		deletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	case selectedDeviceSkipped:
+		return nil, revision, deletedAt
+	}
+	return item, revision, deletedAt
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+	"gorm.io/gorm"
+)
+
+func (suite *HandlerTestSuite) TestDeviceLabels() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	web := suite.createDevice(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "labels-web",
+		Hostname:       "web",
+		Labels:         map[string]string{"env": "prod", "tier": "web"},
+	})
+	db := suite.createDevice(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "labels-db",
+		Hostname:       "db",
+		Labels:         map[string]string{"env": "prod", "tier": "db"},
+	})
+	dev := suite.createDevice(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "labels-dev",
+		Hostname:       "dev",
+		Labels:         map[string]string{"env": "dev"},
+	})
+	assert.Equal(map[string]string{"env": "dev"}, dev.Labels)
+
+	reqBody, err := json.Marshal(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "labels-invalid",
+		Hostname:       "invalid",
+		Labels:         map[string]string{"env": "not valid"},
+	})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	assert.Equal(http.StatusBadRequest, res.Code)
+
+	listDevices := func(selector string) ([]string, int) {
+		_, res, err := suite.ServeRequest(
+			http.MethodGet, "/organizations/:organization/devices",
+			fmt.Sprintf("/organizations/%s/devices?selector=%s", suite.testOrganizationID, url.QueryEscape(selector)),
+			suite.api.ListDevicesInOrganization, nil,
+		)
+		require.NoError(err)
+		var devices []models.Device
+		_ = json.Unmarshal(res.Body.Bytes(), &devices)
+		var hostnames []string
+		for _, device := range devices {
+			hostnames = append(hostnames, device.Hostname)
+		}
+		return hostnames, res.Code
+	}
+
+	for selector, expected := range map[string][]string{
+		"":                       {"db", "dev", "web"},
+		"env=prod":               {"db", "web"},
+		"env=prod,tier!=db":      {"web"},
+		"tier":                   {"db", "web"},
+		"!tier":                  {"dev"},
+		"env in (dev, staging)":  {"dev"},
+		"tier notin (db)":        {"dev", "web"},
+		"env==prod,tier notin()": {"db", "web"},
+	} {
+		hostnames, code := listDevices(selector)
+		require.Equal(http.StatusOK, code, selector)
+		assert.ElementsMatch(expected, hostnames, selector)
+	}
+	_, code := listDevices("env=prod,")
+	assert.Equal(http.StatusBadRequest, code)
+
+	// updating the labels replaces all of them.
+	_, res, err = suite.ServeRequest(
+		http.MethodPatch, "/:id", fmt.Sprintf("/%s", db.ID),
+		suite.api.UpdateDevice, bytes.NewBufferString(`{"labels": {"env": "dev"}}`),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, res.Body.String())
+	hostnames, _ := listDevices("env=dev")
+	assert.ElementsMatch([]string{"db", "dev"}, hostnames)
+	hostnames, _ = listDevices("tier")
+	assert.ElementsMatch([]string{"web"}, hostnames)
+
+	// a watch only lists the matching devices, and then sends the devices that stop matching as deletes.
+	// sqlite does not maintain the revisions, so set them here.
+	for i, device := range []models.Device{web, db, dev} {
+		require.NoError(suite.api.db.Model(&device).UpdateColumn("revision", i+1).Error)
+	}
+	selector, err := models.ParseLabelSelector("env=prod")
+	require.NoError(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scopes := []func(*gorm.DB) *gorm.DB{
+		func(db *gorm.DB) *gorm.DB {
+			return db.Where("organization_id = ?", suite.testOrganizationID).Order("revision")
+		},
+	}
+	signals := []string{"/devices/org=" + suite.testOrganizationID.String()}
+	nextEvent, closeWatch := suite.api.watchEvents(ctx, signals, "devices", 0, scopes, getSelectedDeviceList(selector, false))
+	defer closeWatch()
+	type watchEvent struct {
+		eventType string
+		hostname  string
+	}
+	nextEvents := func(nextEvent func() models.WatchEvent) []watchEvent {
+		var events []watchEvent
+		for event := nextEvent(); event.Type != "bookmark"; event = nextEvent() {
+			require.Contains([]string{"change", "delete"}, event.Type, event.Value)
+			events = append(events, watchEvent{event.Type, event.Value.(*models.Device).Hostname})
+		}
+		return events
+	}
+	assert.Equal([]watchEvent{{"change", "web"}}, nextEvents(nextEvent))
+
+	for i, update := range []struct {
+		device models.Device
+		labels string
+	}{{dev, `{"env": "test"}`}, {web, `{"env": "dev"}`}, {db, `{"env": "prod"}`}} {
+		_, res, err := suite.ServeRequest(
+			http.MethodPatch, "/:id", fmt.Sprintf("/%s", update.device.ID),
+			suite.api.UpdateDevice, bytes.NewBufferString(`{"labels": `+update.labels+`}`),
+		)
+		require.NoError(err)
+		require.Equal(http.StatusOK, res.Code, res.Body.String())
+		require.NoError(suite.api.db.Model(&update.device).UpdateColumn("revision", i+4).Error)
+	}
+	// the dev device never matched, so it is not sent.
+	for _, expected := range []watchEvent{{"delete", "web"}, {"change", "db"}} {
+		event := nextEvent()
+		require.Equal(expected.eventType, event.Type)
+		assert.Equal(expected.hostname, event.Value.(*models.Device).Hostname)
+	}
+
+	// a resumed watch sends all the changed devices that don't match as deletes.
+	resumedEvent, closeResumed := suite.api.watchEvents(ctx, signals, "devices", 3, scopes, getSelectedDeviceList(selector, true))
+	defer closeResumed()
+	assert.Equal([]watchEvent{{"delete", "dev"}, {"delete", "web"}, {"change", "db"}}, nextEvents(resumedEvent))
+}
@@ -29,7 +29,11 @@ func metadataForDevice(deviceId string) func(db *gorm.DB) *gorm.DB {
 // @Param		 gt_revision query  uint64  false "greater than revision"
 // @Accept	     json
 // @Produce      json
+// @Param        q               query  string  false  "conditions on the fields, for example key^=lab,revision>100"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
 // @Success      200  {object}  []models.DeviceMetadata
+// @Failure		 400  {object}  models.BaseError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/devices/{id}/metadata [get]
 func (api *API) ListDeviceMetadata(c *gin.Context) {
@@ -112,7 +116,12 @@ func getDeviceMetadataList(db *gorm.DB) (WatchableList, error) {
 // @Param        prefix          path   []string true  "used to filter down to the specified key prefixes"
 // @Accept	     json
 // @Produce      json
+// @Param        q               query  string  false  "conditions on the fields, for example key^=lab,revision>100"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
 // @Success      200  {object}  []models.DeviceMetadata
+// @Failure		 400  {object}  models.BaseError
+// @Failure		 410  {object}  models.GoneError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/organizations/{organization}/metadata [get]
 func (api *API) ListOrganizationMetadata(c *gin.Context) {
@@ -159,27 +168,7 @@ func (api *API) ListOrganizationMetadata(c *gin.Context) {
 	}
 
 	scopes := []func(*gorm.DB) *gorm.DB{
-		func(db *gorm.DB) *gorm.DB {
-			result := db.Model(&models.DeviceMetadata{}).
-				Joins("inner join devices on devices.id=device_metadata.device_id").
-				Where( // extra wrapping Where needed to group the SQL expressions
-					db.Where("devices.organization_id = ?", orgId.String()),
-				)
-			if len(query.Prefixes) > 0 {
-				oredExpressions := db
-				for i, prefix := range query.Prefixes {
-					if i == 0 {
-						oredExpressions = oredExpressions.Where("key LIKE ?", prefix+"%")
-					} else {
-						oredExpressions = oredExpressions.Or("key LIKE ?", prefix+"%")
-					}
-				}
-				result = result.Where( // extra wrapping Where needed to group the SQL expressions
-					oredExpressions,
-				)
-			}
-			return result
-		},
+		deviceMetadataInOrganization(orgId, query.Prefixes),
 		FilterAndPaginateWithQuery(&models.DeviceMetadata{}, c, query.Query, defaultOrderBy),
 	}
 
@@ -191,7 +180,33 @@ func (api *API) ListOrganizationMetadata(c *gin.Context) {
 			return db
 		})
 	}
-	api.sendListOrWatch(c, ctx, signalChannel, "device_metadata.revision", scopes, getDeviceMetadataList)
+	api.sendListOrWatch(c, ctx, []string{signalChannel}, "device_metadata", scopes, getDeviceMetadataList)
+}
+
+// deviceMetadataInOrganization limits the query to the metadata of the devices of the organization
+// with keys that start with one of the prefixes.
+func deviceMetadataInOrganization(orgId uuid.UUID, prefixes []string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		result := db.Model(&models.DeviceMetadata{}).
+			Joins("inner join devices on devices.id=device_metadata.device_id").
+			Where( // extra wrapping Where needed to group the SQL expressions
+				db.Where("devices.organization_id = ?", orgId.String()),
+			)
+		if len(prefixes) > 0 {
+			oredExpressions := db
+			for i, prefix := range prefixes {
+				if i == 0 {
+					oredExpressions = oredExpressions.Where("key LIKE ?", prefix+"%")
+				} else {
+					oredExpressions = oredExpressions.Or("key LIKE ?", prefix+"%")
+				}
+			}
+			result = result.Where( // extra wrapping Where needed to group the SQL expressions
+				oredExpressions,
+			)
+		}
+		return result
+	}
 }
 
 type deviceMetadataList []*models.DeviceMetadata
@@ -272,6 +287,7 @@ func (api *API) GetDeviceMetadataKey(c *gin.Context) {
 // @Accept	     json
 // @Produce      json
 // @Success      200  {object}  models.DeviceMetadata
+// @Failure      403  {object}  models.QuotaExceededError
 // @Failure      501  {object}  models.BaseError
 // @Router       /api/devices/{id}/metadata/{key} [put]
 func (api *API) UpdateDeviceMetadataKey(c *gin.Context) {
@@ -303,12 +319,17 @@ func (api *API) UpdateDeviceMetadataKey(c *gin.Context) {
 	var device models.Device
 	err = api.transaction(ctx, func(tx *gorm.DB) error {
 		result := api.db.WithContext(ctx).
-			Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+			Scopes(api.DeviceIsManageableByCurrentUser(c)).
 			First(&device, "id = ?", deviceId)
 		if result.Error != nil {
 			return result.Error
 		}
 
+		// only adding a new key counts against the quota.
+		if err := checkQuota(tx, quotaMetadataKeysPerDevice, api.quotas.MetadataKeysPerDevice, &models.Device{}, deviceId, &models.DeviceMetadata{}, "device_id = ? AND key <> ?", deviceId, key); err != nil {
+			return err
+		}
+
 		result = tx.
 			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
 			Save(&metadataInstance)
@@ -316,12 +337,18 @@ func (api *API) UpdateDeviceMetadataKey(c *gin.Context) {
 	})
 
 	if err != nil {
+		var quotaExceeded errQuotaExceeded
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.Status(http.StatusNotFound)
 			return
 		}
+		if errors.As(err, &quotaExceeded) {
+			c.JSON(http.StatusForbidden, quotaExceeded.Response())
+			return
+		}
 		api.logger.Errorf("error updating metadata: %s", err)
 		c.JSON(http.StatusInternalServerError, err)
+		return
 	}
 
 	signalChannel := fmt.Sprintf("/metadata/org=%s", device.OrganizationID.String())
@@ -354,7 +381,7 @@ func (api *API) DeleteDeviceMetadata(c *gin.Context) {
 	var device models.Device
 	err = api.transaction(ctx, func(tx *gorm.DB) error {
 		result := api.db.WithContext(ctx).
-			Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+			Scopes(api.DeviceIsManageableByCurrentUser(c)).
 			First(&device, "id = ?", deviceId)
 		if result.Error != nil {
 			return result.Error
@@ -404,7 +431,7 @@ func (api *API) DeleteDeviceMetadataKey(c *gin.Context) {
 	var device models.Device
 	err = api.transaction(ctx, func(tx *gorm.DB) error {
 		result := api.db.WithContext(ctx).
-			Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+			Scopes(api.DeviceIsManageableByCurrentUser(c)).
 			First(&device, "id = ?", deviceId)
 		if result.Error != nil {
 			return result.Error
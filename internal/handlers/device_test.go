@@ -2,14 +2,18 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 func (suite *HandlerTestSuite) TestCreateGetDevice() {
@@ -60,6 +64,122 @@ func (suite *HandlerTestSuite) TestCreateGetDevice() {
 	assert.Equal(actual, device)
 }
 
+func (suite *HandlerTestSuite) TestDeleteDeviceReleasesAddressesOnCommit() {
+	require := suite.Require()
+	ctx := context.Background()
+
+	_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice,
+		bytes.NewBuffer(suite.jsonMarshal(models.AddDevice{OrganizationID: suite.testOrganizationID, PublicKey: "release-on-commit"})))
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, res.Body.String())
+	var device models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+
+	deleteDevice := func(fail bool) *ipamReleases {
+		var releases ipamReleases
+		err := suite.api.transaction(ctx, func(tx *gorm.DB) error {
+			releases = ipamReleases{}
+			if err := suite.api.removeDevice(tx, &device); err != nil {
+				return err
+			}
+			if err := suite.api.releaseDeviceAddresses(withIPAMReleases(ctx, tx, &releases), tx, defaultIPAMNamespace, device); err != nil {
+				return err
+			}
+			if fail {
+				return errors.New("rolled back")
+			}
+			return nil
+		})
+		if fail {
+			require.Error(err)
+		} else {
+			require.NoError(err)
+		}
+		return &releases
+	}
+	addressAllocated := func() bool {
+		if err := suite.api.ipam.AcquireIP(ctx, defaultIPAMNamespace, device.OrganizationPrefix, device.TunnelIP); err != nil {
+			return true
+		}
+		require.NoError(suite.api.ipam.ReleaseToPool(ctx, defaultIPAMNamespace, device.TunnelIP, device.OrganizationPrefix))
+		return false
+	}
+
+	// a rolled back deletion keeps the device and its address.
+	deleteDevice(true)
+	require.NoError(suite.api.db.First(&models.Device{}, "id = ?", device.ID).Error)
+	require.True(addressAllocated())
+
+	releases := deleteDevice(false)
+	require.NoError(releases.run(ctx))
+	require.False(addressAllocated())
+}
+
+func (suite *HandlerTestSuite) TestDeviceRoles() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	membership := UserOrganization{UserID: TestUser2ID, OrganizationID: suite.testOrganizationID, Role: models.RoleReadOnly}
+	require.NoError(suite.api.db.Create(&membership).Error)
+	setRole := func(role string) {
+		require.NoError(setOrganizationRole(suite.api.db, suite.testOrganizationID, TestUser2ID, role))
+	}
+	owned := models.Device{UserID: TestUser2ID, OrganizationID: suite.testOrganizationID, Hostname: "owned"}
+	other := models.Device{UserID: TestUserID, OrganizationID: suite.testOrganizationID, Hostname: "other"}
+	require.NoError(suite.api.db.Create(&owned).Error)
+	require.NoError(suite.api.db.Create(&other).Error)
+
+	asUser2 := func(method, path string, uri string, handler func(*gin.Context), body string) int {
+		_, res, err := suite.ServeRequest(method, path, uri, func(c *gin.Context) {
+			c.Set(gin.AuthUserKey, TestUser2ID)
+			handler(c)
+		}, bytes.NewBufferString(body))
+		require.NoError(err)
+		return res.Code
+	}
+	get := func(device models.Device) int {
+		return asUser2(http.MethodGet, "/:id", fmt.Sprintf("/%s", device.ID), suite.api.GetDevice, "")
+	}
+	getMetadata := func(device models.Device) int {
+		return asUser2(http.MethodGet, "/:id/metadata", fmt.Sprintf("/%s/metadata", device.ID), suite.api.ListDeviceMetadata, "")
+	}
+	update := func(device models.Device) int {
+		return asUser2(http.MethodPatch, "/:id", fmt.Sprintf("/%s", device.ID), suite.api.UpdateDevice, `{"hostname": "renamed"}`)
+	}
+	setMetadata := func(device models.Device) int {
+		return asUser2(http.MethodPut, "/:id/metadata/:key", fmt.Sprintf("/%s/metadata/role", device.ID), suite.api.UpdateDeviceMetadataKey, `{"value": 1}`)
+	}
+	deleteDevice := func(device models.Device) int {
+		return asUser2(http.MethodDelete, "/:id", fmt.Sprintf("/%s", device.ID), suite.api.DeleteDevice, "")
+	}
+
+	// a read-only member can view their devices, but not change them.
+	assert.Equal(http.StatusOK, get(owned))
+	assert.Equal(http.StatusOK, getMetadata(owned))
+	assert.Equal(http.StatusNotFound, update(owned))
+	assert.Equal(http.StatusNotFound, setMetadata(owned))
+	assert.Equal(http.StatusNotFound, deleteDevice(owned))
+
+	// a member manages their devices, but not the devices of the other members.
+	setRole(models.RoleMember)
+	assert.Equal(http.StatusOK, update(owned))
+	assert.Equal(http.StatusOK, setMetadata(owned))
+	assert.Equal(http.StatusNotFound, update(other))
+	assert.Equal(http.StatusNotFound, setMetadata(other))
+	assert.Equal(http.StatusNotFound, deleteDevice(other))
+
+	// an admin manages all the devices of the organization.
+	setRole(models.RoleAdmin)
+	assert.Equal(http.StatusOK, update(other))
+	assert.Equal(http.StatusOK, setMetadata(other))
+	assert.Equal(http.StatusOK, deleteDevice(other))
+
+	// a user that is no longer a member can't view the devices they registered in the organization.
+	require.NoError(suite.api.db.Where("user_id = ? AND organization_id = ?", TestUser2ID, suite.testOrganizationID).Delete(&UserOrganization{}).Error)
+	assert.Equal(http.StatusNotFound, get(owned))
+	assert.Equal(http.StatusNotFound, getMetadata(owned))
+}
+
 func TestChildPrefixEquals(t *testing.T) {
 	tests := []struct {
 		name         string
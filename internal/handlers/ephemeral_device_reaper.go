@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ephemeralDeviceCheckInterval is how often the heartbeats of the ephemeral devices are checked.
+const ephemeralDeviceCheckInterval = time.Minute
+
+const ephemeralDeviceReaperActor = "ephemeral-device-reaper"
+
+// StartEphemeralDeviceReaper starts the background worker that deletes the ephemeral devices that did
+// not send a heartbeat for longer than timeout. nexd deletes its ephemeral device when it stops, this
+// deletes the devices of the agents that crashed or were killed.
+func (api *API) StartEphemeralDeviceReaper(ctx context.Context, wg *sync.WaitGroup, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	util.GoWithWaitGroup(wg, func() {
+		ticker := time.NewTicker(ephemeralDeviceCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := api.reapEphemeralDevices(ctx, time.Now().Add(-timeout)); err != nil {
+				api.logger.Errorf("failed to delete the ephemeral devices without heartbeats: %v", err)
+			}
+		}
+	})
+}
+
+// reapEphemeralDevices deletes the ephemeral devices that were created, and did not send a heartbeat,
+// since the given time.
+func (api *API) reapEphemeralDevices(ctx context.Context, before time.Time) error {
+	var orgIds []uuid.UUID
+	var releases ipamReleases
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		releases = ipamReleases{}
+		// the devices that the other apiserver replicas are deleting are skipped.
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("ephemeral = ? AND created_at < ?", true, before).
+			Where("NOT EXISTS (SELECT 1 FROM device_heartbeats WHERE device_heartbeats.device_id = devices.id AND device_heartbeats.seen_at >= ?)", before)
+		var err error
+		orgIds, err = api.deleteUserDevices(withIPAMReleases(ctx, tx, &releases), tx, ephemeralDeviceReaperActor, query)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := releases.run(ctx); err != nil {
+		api.logger.Errorf("ephemeral device reaper: %v", err)
+	}
+	for _, orgId := range orgIds {
+		api.logger.Infof("deleted the ephemeral devices without heartbeats of organization %s", orgId)
+		api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", orgId.String()))
+	}
+	if len(orgIds) > 0 {
+		api.signalBus.Notify(webhooksSignal)
+	}
+	return nil
+}
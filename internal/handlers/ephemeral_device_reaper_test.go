@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestEphemeralDeviceReaper() {
+	require := suite.Require()
+	assert := suite.Assert()
+	ctx := context.Background()
+
+	reporting := models.Device{UserID: TestUserID, OrganizationID: suite.testOrganizationID, Hostname: "reporting", Ephemeral: true}
+	silent := models.Device{UserID: TestUserID, OrganizationID: suite.testOrganizationID, Hostname: "silent", Ephemeral: true}
+	persistent := models.Device{UserID: TestUserID, OrganizationID: suite.testOrganizationID, Hostname: "persistent"}
+	for _, device := range []*models.Device{&reporting, &silent, &persistent} {
+		require.NoError(suite.api.db.Create(device).Error)
+	}
+	_, res, err := suite.ServeRequest(
+		http.MethodPost, "/devices/:id/heartbeat", fmt.Sprintf("/devices/%s/heartbeat", reporting.ID),
+		suite.api.DeviceHeartbeat, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusNoContent, res.Code, "HTTP error: %s", res.Body.String())
+
+	exists := func(device models.Device) bool {
+		var count int64
+		require.NoError(suite.api.db.Model(&models.Device{}).Where("id = ?", device.ID).Count(&count).Error)
+		return count == 1
+	}
+
+	// the devices that were just registered are kept, even before their first heartbeat.
+	require.NoError(suite.api.reapEphemeralDevices(ctx, time.Now().Add(-time.Minute)))
+	assert.True(exists(reporting))
+	assert.True(exists(silent))
+
+	// the ephemeral devices without a recent heartbeat are deleted, the other devices are kept.
+	require.NoError(suite.api.db.Model(&models.DeviceHeartbeat{}).
+		Where("device_id = ?", reporting.ID).
+		Update("seen_at", time.Now().Add(2*time.Minute)).Error)
+	require.NoError(suite.api.reapEphemeralDevices(ctx, time.Now().Add(time.Minute)))
+	assert.True(exists(reporting))
+	assert.False(exists(silent))
+	assert.True(exists(persistent))
+
+	require.NoError(suite.api.reapEphemeralDevices(ctx, time.Now().Add(3*time.Minute)))
+	assert.False(exists(reporting))
+	assert.True(exists(persistent))
+
+	var events []models.AuditEvent
+	require.NoError(suite.api.db.Where("organization_id = ? AND actor_name = ?", suite.testOrganizationID, ephemeralDeviceReaperActor).Find(&events).Error)
+	assert.Len(events, 2)
+}
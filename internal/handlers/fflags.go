@@ -15,7 +15,7 @@ import (
 // @Accept       json
 // @Produce      json
 // @Success      200  {object} map[string]bool
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Router       /api/fflags [get]
 func (api *API) ListFeatureFlags(c *gin.Context) {
 	c.JSON(http.StatusOK, api.fflags.ListFlags())
@@ -32,7 +32,7 @@ func (api *API) ListFeatureFlags(c *gin.Context) {
 // @Success      200  {object} map[string]bool
 // @Failure      400  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Router       /api/fflags/{name} [get]
 func (api *API) GetFeatureFlag(c *gin.Context) {
 	flagName := c.Param("name")
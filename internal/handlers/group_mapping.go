@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var errGroupMappingNotFound = errors.New("group mapping not found")
+
+type errDuplicateGroupMapping struct {
+	ID string
+}
+
+func (e errDuplicateGroupMapping) Error() string {
+	return "duplicate group mapping"
+}
+
+// CreateGroupMapping maps an identity provider group to membership of an organization
+// @Summary      Map a group to organization membership
+// @Description  Makes the users of an identity provider group members of the organization with a role. The members are added and removed as the groups of the users change
+// @Id           CreateGroupMapping
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string                              true  "Organization ID"
+// @Param        GroupMapping     body   models.AddOrganizationGroupMapping  true  "Add Group Mapping"
+// @Success      201  {object}  models.OrganizationGroupMapping
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      409  {object}  models.ConflictsError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/group_mappings [post]
+func (api *API) CreateGroupMapping(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateGroupMapping", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.AddOrganizationGroupMapping
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.Group == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("group"))
+		return
+	}
+	if request.Role == "" {
+		request.Role = models.RoleMember
+	}
+	if !models.IsValidRole(request.Role) || request.Role == models.RoleOwner {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("role", fmt.Sprintf("must be one of '%s', '%s' or '%s'", models.RoleAdmin, models.RoleMember, models.RoleReadOnly)))
+		return
+	}
+
+	var mapping models.OrganizationGroupMapping
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			return errOrgNotFound
+		}
+		if request.Role == models.RoleAdmin {
+			role, err := currentUserRole(c, tx, org)
+			if err != nil {
+				return err
+			}
+			if role != models.RoleOwner {
+				return errAdminRoleRequired
+			}
+		}
+
+		var existing models.OrganizationGroupMapping
+		res := tx.First(&existing, "organization_id = ? AND group_name = ?", org.ID, request.Group)
+		if res.Error == nil {
+			return errDuplicateGroupMapping{ID: existing.ID.String()}
+		}
+		if !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return res.Error
+		}
+
+		mapping = models.OrganizationGroupMapping{
+			OrganizationID: org.ID,
+			Group:          request.Group,
+			Role:           request.Role,
+		}
+		if res := tx.Create(&mapping); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionCreate, "group_mapping", mapping.ID.String(), nil, mapping)
+	})
+	if err != nil {
+		api.sendGroupMappingError(c, err)
+		return
+	}
+	api.requestGroupSync()
+	span.SetAttributes(attribute.String("id", mapping.ID.String()))
+	c.JSON(http.StatusCreated, mapping)
+}
+
+// ListGroupMappings lists the group mappings of an organization
+// @Summary      List group mappings
+// @Description  Lists the identity provider groups whose users are members of the organization
+// @Id           ListGroupMappings
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        q               query  string  false  "conditions on the fields, for example group^=eng-,role=admin"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
+// @Success      200  {object}  []models.OrganizationGroupMapping
+// @Failure		 400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/group_mappings [get]
+func (api *API) ListGroupMappings(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListGroupMappings", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	mappings := make([]models.OrganizationGroupMapping, 0)
+	result := api.db.WithContext(ctx).
+		Where("organization_id = ?", org.ID).
+		Scopes(FilterAndPaginate(&models.OrganizationGroupMapping{}, c, "created_at")).
+		Find(&mappings)
+	if result.Error != nil {
+		sendListError(c, result.Error)
+		return
+	}
+	c.JSON(http.StatusOK, mappings)
+}
+
+// DeleteGroupMapping deletes a group mapping
+// @Summary      Delete a group mapping
+// @Description  Deletes a group mapping, the users that are members of the organization only through the group are removed from it along with their devices
+// @Id           DeleteGroupMapping
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        id               path   string  true  "Group Mapping ID"
+// @Success      200  {object}  models.OrganizationGroupMapping
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/group_mappings/{id} [delete]
+func (api *API) DeleteGroupMapping(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteGroupMapping", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var mapping models.OrganizationGroupMapping
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			return errOrgNotFound
+		}
+		if res := tx.Where("organization_id = ?", org.ID).
+			First(&mapping, "id = ?", id); res.Error != nil {
+			return errGroupMappingNotFound
+		}
+		if mapping.Role == models.RoleAdmin {
+			role, err := currentUserRole(c, tx, org)
+			if err != nil {
+				return err
+			}
+			if role != models.RoleOwner {
+				return errAdminRoleRequired
+			}
+		}
+		if res := tx.Delete(&mapping); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionDelete, "group_mapping", mapping.ID.String(), mapping, nil)
+	})
+	if err != nil {
+		api.sendGroupMappingError(c, err)
+		return
+	}
+	api.requestGroupSync()
+	c.JSON(http.StatusOK, mapping)
+}
+
+func (api *API) sendGroupMappingError(c *gin.Context, err error) {
+	var duplicate errDuplicateGroupMapping
+	if errors.As(err, &duplicate) {
+		c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+	} else if errors.Is(err, errOrgNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+	} else if errors.Is(err, errGroupMappingNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("group mapping"))
+	} else if errors.Is(err, errAdminRoleRequired) {
+		c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+	} else {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+	}
+}
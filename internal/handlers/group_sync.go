@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// key for the groups of the user in gin.Context
+const AuthUserGroups string = "_nexodus.UserGroups"
+
+// groupSyncActor is the actor of the audit events of the memberships changed by the group sync.
+const groupSyncActor = "group-sync"
+
+// GroupsCacheExp is how long the groups of a user are cached. The memberships of a user are synced
+// when their groups change, and at least this often while they use the api, so that the time their
+// groups were last seen stays fresh.
+const GroupsCacheExp = 5 * time.Minute
+
+var groupSyncCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "apiserver_group_sync_memberships_total",
+	Help: "The organization memberships changed by the group sync, by action.",
+}, []string{"action"})
+
+// groupMembershipChanges are the organizations whose members or devices were changed by a sync.
+type groupMembershipChanges struct {
+	organizations []uuid.UUID
+	devices       []uuid.UUID
+}
+
+// syncUserGroups syncs the group managed memberships of the user of the request with the groups of
+// their token, unless they were synced with the same groups recently.
+func (api *API) syncUserGroups(c *gin.Context, userId string) error {
+	ctx := c.Request.Context()
+	groups := append([]string{}, c.GetStringSlice(AuthUserGroups)...)
+	sort.Strings(groups)
+	// the value is never empty, so a user without groups is synced when the key expires.
+	value := "groups:" + strings.Join(groups, "\n")
+	key := fmt.Sprintf("%s:groups:%s", CachePrefix, userId)
+	cached, err := api.redis.Get(ctx, key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		api.logger.Warnf("failed to find the user groups in the cache:%s", err)
+	}
+	if cached == value {
+		return nil
+	}
+
+	// the memberships can be added concurrently by another request of the user or by the resync.
+	err = util.RetryOperationForErrors(ctx, time.Millisecond*10, 1, []error{gorm.ErrDuplicatedKey}, func() error {
+		return api.syncGroupMemberships(ctx, userId, func(tx *gorm.DB, user *models.User) ([]string, error) {
+			now := time.Now()
+			if res := tx.Model(user).UpdateColumns(map[string]interface{}{
+				"groups":           pq.StringArray(groups),
+				"groups_synced_at": &now,
+			}); res.Error != nil {
+				return nil, res.Error
+			}
+			return groups, nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("can't sync the group memberships of user %s: %w", userId, err)
+	}
+	api.redis.Set(ctx, key, value, GroupsCacheExp)
+	return nil
+}
+
+// StartGroupSync starts the background worker that resyncs the group managed memberships of the users
+// with their last seen groups every interval, and whenever the group mappings change, so that the
+// mappings apply to the users that are not using the api. The groups of the users that haven't been
+// seen for maxAge are dropped, which removes the users that can no longer log in, like the ones
+// offboarded from the identity provider, from the organizations their groups made them members of.
+// A zero maxAge keeps the groups of the users until they are seen again.
+func (api *API) StartGroupSync(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, maxAge time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	util.GoWithWaitGroup(wg, func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			api.resyncGroupMemberships(ctx, maxAge)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-api.groupSyncRequests:
+			}
+		}
+	})
+}
+
+// requestGroupSync asks the group sync worker to resync the memberships, it doesn't wait for it.
+func (api *API) requestGroupSync() {
+	select {
+	case api.groupSyncRequests <- struct{}{}:
+	default:
+	}
+}
+
+// resyncGroupMemberships resyncs the memberships of the users whose groups were seen, who are members
+// of a SCIM group, or who are members of an organization through a group mapping.
+func (api *API) resyncGroupMemberships(ctx context.Context, maxAge time.Duration) {
+	ctx, span := tracer.Start(ctx, "resyncGroupMemberships")
+	defer span.End()
+
+	var userIds []string
+	if res := api.db.WithContext(ctx).Model(&models.User{}).
+		Where("groups_synced_at IS NOT NULL").
+		Or("id IN (?)", api.db.Model(&models.ScimGroupMember{}).Select("user_id")).
+		Or("id IN (?)", api.db.Model(&UserOrganization{}).Select("user_id").Where("group_managed = ?", true)).
+		Pluck("id", &userIds); res.Error != nil {
+		api.logger.Errorf("failed to list the users to resync the group memberships of: %v", res.Error)
+		return
+	}
+	span.SetAttributes(attribute.Int("users", len(userIds)))
+
+	for _, userId := range userIds {
+		if ctx.Err() != nil {
+			return
+		}
+		err := api.syncGroupMemberships(ctx, userId, func(tx *gorm.DB, user *models.User) ([]string, error) {
+			if user.GroupsSyncedAt == nil {
+				return nil, nil
+			}
+			if maxAge > 0 && time.Since(*user.GroupsSyncedAt) > maxAge {
+				if res := tx.Model(user).UpdateColumns(map[string]interface{}{
+					"groups":           pq.StringArray{},
+					"groups_synced_at": nil,
+				}); res.Error != nil {
+					return nil, res.Error
+				}
+				return nil, nil
+			}
+			return user.Groups, nil
+		})
+		if err != nil {
+			api.logger.Errorf("failed to resync the group memberships of user %s: %v", userId, err)
+		}
+	}
+}
+
+// syncGroupMemberships makes the group managed memberships of the user match the group mappings of
+// the groups returned by groupsOf, which can update the groups stored for the user, and of the SCIM
+// groups of the user. Deactivated users have no groups.
+func (api *API) syncGroupMemberships(ctx context.Context, userId string, groupsOf func(tx *gorm.DB, user *models.User) ([]string, error)) error {
+	var changes groupMembershipChanges
+	var releases ipamReleases
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		changes = groupMembershipChanges{}
+		releases = ipamReleases{}
+		var user models.User
+		if res := tx.First(&user, "id = ?", userId); res.Error != nil {
+			return res.Error
+		}
+		groups, err := groupsOf(tx, &user)
+		if err != nil {
+			return err
+		}
+		if user.Deactivated {
+			groups = nil
+		} else {
+			scimGroups, err := api.scimGroupsOf(tx, user.ID)
+			if err != nil {
+				return err
+			}
+			groups = append(groups, scimGroups...)
+		}
+		changes, err = api.applyGroupMemberships(withIPAMReleases(ctx, tx, &releases), tx, user, groups)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := releases.run(ctx); err != nil {
+		api.logger.Errorf("group sync of user %s: %v", userId, err)
+	}
+	if len(changes.organizations) > 0 {
+		api.signalBus.Notify(organizationsSignal(userId))
+		api.notifyOrganizationsChanged(ctx, changes.organizations...)
+	}
+	for _, orgId := range changes.devices {
+		api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", orgId.String()))
+		api.signalBus.Notify(webhooksSignal)
+	}
+	return nil
+}
+
+// applyGroupMemberships adds the user to the organizations mapped to their groups with the most
+// privileged role of their groups, and removes them from the organizations they are members of only
+// through groups they no longer have. The devices of the user in the organizations they are removed
+// from are deleted, so that they leave the mesh. The memberships that were not added by a group
+// mapping, like the ones of invited users and of the owner, are left as they are.
+func (api *API) applyGroupMemberships(ctx context.Context, tx *gorm.DB, user models.User, groups []string) (groupMembershipChanges, error) {
+	var changes groupMembershipChanges
+	roles := map[uuid.UUID]string{}
+	if len(groups) > 0 {
+		var mappings []models.OrganizationGroupMapping
+		if res := tx.Where("group_name IN ?", groups).Find(&mappings); res.Error != nil {
+			return changes, res.Error
+		}
+		for _, mapping := range mappings {
+			if role, found := roles[mapping.OrganizationID]; found {
+				roles[mapping.OrganizationID] = models.HigherRole(role, mapping.Role)
+			} else {
+				roles[mapping.OrganizationID] = mapping.Role
+			}
+		}
+	}
+
+	var memberships []UserOrganization
+	if res := tx.Where("user_id = ?", user.ID).Find(&memberships); res.Error != nil {
+		return changes, res.Error
+	}
+	members := map[uuid.UUID]bool{}
+	for _, membership := range memberships {
+		members[membership.OrganizationID] = true
+		role, mapped := roles[membership.OrganizationID]
+		if !membership.GroupManaged || (mapped && role == membership.Role) {
+			continue
+		}
+		before := models.OrganizationMember{UserID: user.ID, UserName: user.UserName, Role: membership.Role, GroupManaged: true}
+		if mapped {
+			if err := setOrganizationRole(tx, membership.OrganizationID, user.ID, role); err != nil {
+				return changes, err
+			}
+			after := before
+			after.Role = role
+			if err := recordSystemAuditEvent(tx, membership.OrganizationID, groupSyncActor, models.AuditActionUpdate, "organization_member", user.ID, before, after); err != nil {
+				return changes, err
+			}
+			groupSyncCounter.WithLabelValues("updated").Inc()
+		} else {
+			removedDevices, err := api.removeGroupMember(ctx, tx, membership.OrganizationID, user.ID)
+			if err != nil {
+				return changes, err
+			}
+			if err := recordSystemAuditEvent(tx, membership.OrganizationID, groupSyncActor, models.AuditActionDelete, "organization_member", user.ID, before, nil); err != nil {
+				return changes, err
+			}
+			if removedDevices {
+				changes.devices = append(changes.devices, membership.OrganizationID)
+			}
+			groupSyncCounter.WithLabelValues("removed").Inc()
+		}
+		changes.organizations = append(changes.organizations, membership.OrganizationID)
+	}
+
+	for orgId, role := range roles {
+		if members[orgId] {
+			continue
+		}
+		var org models.Organization
+		if res := tx.First(&org, "id = ?", orgId); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return changes, res.Error
+		}
+		if res := tx.Create(&UserOrganization{
+			UserID:         user.ID,
+			OrganizationID: org.ID,
+			Role:           role,
+			GroupManaged:   true,
+		}); res.Error != nil {
+			return changes, res.Error
+		}
+		after := models.OrganizationMember{UserID: user.ID, UserName: user.UserName, Role: role, GroupManaged: true}
+		if err := recordSystemAuditEvent(tx, org.ID, groupSyncActor, models.AuditActionCreate, "organization_member", user.ID, nil, after); err != nil {
+			return changes, err
+		}
+		groupSyncCounter.WithLabelValues("added").Inc()
+		changes.organizations = append(changes.organizations, org.ID)
+	}
+
+	for _, orgId := range changes.organizations {
+		if err := touchOrganization(tx, orgId); err != nil {
+			return changes, err
+		}
+	}
+	return changes, nil
+}
+
+// removeGroupMember removes the user from the organization and deletes their devices in it, it
+// returns true if devices were deleted. It should be called with the context of the transaction,
+// see withIPAMReleases.
+func (api *API) removeGroupMember(ctx context.Context, tx *gorm.DB, orgId uuid.UUID, userId string) (bool, error) {
+	if res := tx.
+		Where("user_id = ? AND organization_id = ?", userId, orgId).
+		Delete(&UserOrganization{}); res.Error != nil {
+		return false, fmt.Errorf("failed to remove the association from the user_organizations table: %w", res.Error)
+	}
+	orgIds, err := api.deleteUserDevices(ctx, tx, groupSyncActor, tx.Where("user_id = ? AND organization_id = ?", userId, orgId))
+	if err != nil {
+		return false, err
+	}
+	return len(orgIds) > 0, nil
+}
+
+// deleteUserDevices deletes the devices found by the query, releases their addresses and records the
+// audit events of the deletions with the actor. It returns the organizations of the deleted devices,
+// and should be called with the context of the transaction, see withIPAMReleases.
+func (api *API) deleteUserDevices(ctx context.Context, tx *gorm.DB, actor string, query *gorm.DB) ([]uuid.UUID, error) {
+	var devices []models.Device
+	if res := query.Find(&devices); res.Error != nil {
+		return nil, res.Error
+	}
+	orgs := map[uuid.UUID]models.Organization{}
+	var orgIds []uuid.UUID
+	for i := range devices {
+		device := &devices[i]
+		org, found := orgs[device.OrganizationID]
+		if !found {
+			if res := tx.First(&org, "id = ?", device.OrganizationID); res.Error != nil {
+				return nil, res.Error
+			}
+			orgs[org.ID] = org
+			orgIds = append(orgIds, org.ID)
+		}
+		ipamNamespace := defaultIPAMNamespace
+		if org.PrivateCidr {
+			ipamNamespace = org.ID
+		}
+		if err := api.removeDevice(tx, device); err != nil {
+			return nil, err
+		}
+		if err := recordSystemAuditEvent(tx, org.ID, actor, models.AuditActionDelete, "device", device.ID.String(), *device, nil); err != nil {
+			return nil, err
+		}
+		if err := api.releaseDeviceAddresses(ctx, tx, ipamNamespace, *device); err != nil {
+			return nil, err
+		}
+	}
+	return orgIds, nil
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+// serveAsUserWithGroups sends a request as the second test user with the groups in their token.
+func (suite *HandlerTestSuite) serveAsUserWithGroups(groups ...string) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, TestUser2ID)
+		c.Set(AuthUserName, "testuser2")
+		c.Set(AuthUserGroups, groups)
+		c.Next()
+	})
+	r.Use(suite.api.CreateUserIfNotExists())
+	r.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	res := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	suite.Require().NoError(err)
+	r.ServeHTTP(res, req)
+	suite.Require().Equal(http.StatusOK, res.Code)
+}
+
+func (suite *HandlerTestSuite) groupMembership(orgId uuid.UUID) *UserOrganization {
+	var memberships []UserOrganization
+	suite.Require().NoError(suite.api.db.Where("user_id = ? AND organization_id = ?", TestUser2ID, orgId).Find(&memberships).Error)
+	if len(memberships) == 0 {
+		return nil
+	}
+	return &memberships[0]
+}
+
+func (suite *HandlerTestSuite) TestGroupMappings() {
+	require := suite.Require()
+	assert := suite.Assert()
+	suite.api.db.Exec("DELETE FROM organization_group_mappings")
+
+	createMapping := func(group string, role string) *httptest.ResponseRecorder {
+		reqBody, err := json.Marshal(models.AddOrganizationGroupMapping{Group: group, Role: role})
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(
+			http.MethodPost,
+			"/:organization/group_mappings", fmt.Sprintf("/%s/group_mappings", suite.testOrganizationID),
+			suite.api.CreateGroupMapping, bytes.NewBuffer(reqBody),
+		)
+		require.NoError(err)
+		return res
+	}
+
+	res := createMapping("engineering", models.RoleOwner)
+	assert.Equal(http.StatusBadRequest, res.Code)
+	res = createMapping("", models.RoleMember)
+	assert.Equal(http.StatusBadRequest, res.Code)
+
+	res = createMapping("engineering", "")
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var mapping models.OrganizationGroupMapping
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &mapping))
+	assert.Equal("engineering", mapping.Group)
+	assert.Equal(models.RoleMember, mapping.Role)
+
+	res = createMapping("engineering", models.RoleReadOnly)
+	assert.Equal(http.StatusConflict, res.Code)
+	res = createMapping("sre", models.RoleAdmin)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var adminMapping models.OrganizationGroupMapping
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &adminMapping))
+
+	_, res, err := suite.ServeRequest(
+		http.MethodGet,
+		"/:organization/group_mappings", fmt.Sprintf("/%s/group_mappings", suite.testOrganizationID),
+		suite.api.ListGroupMappings, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var mappings []models.OrganizationGroupMapping
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &mappings))
+	assert.Len(mappings, 2)
+
+	// the users of a mapped group become members with the most privileged role of their groups
+	suite.serveAsUserWithGroups("engineering")
+	membership := suite.groupMembership(suite.testOrganizationID)
+	require.NotNil(membership)
+	assert.True(membership.GroupManaged)
+	assert.Equal(models.RoleMember, membership.Role)
+
+	suite.serveAsUserWithGroups("engineering", "sre")
+	membership = suite.groupMembership(suite.testOrganizationID)
+	require.NotNil(membership)
+	assert.Equal(models.RoleAdmin, membership.Role)
+
+	// the role of a group managed member is set by the mappings
+	reqBody, err := json.Marshal(models.UpdateOrganizationMember{Role: models.RoleReadOnly})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPatch,
+		"/:organization/members/:id", fmt.Sprintf("/%s/members/%s", suite.testOrganizationID, TestUser2ID),
+		suite.api.UpdateOrganizationMember, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusForbidden, res.Code)
+
+	// deleting the mapping of a group lowers the role of its members to the one of their other groups
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete,
+		"/:organization/group_mappings/:id", fmt.Sprintf("/%s/group_mappings/%s", suite.testOrganizationID, adminMapping.ID),
+		suite.api.DeleteGroupMapping, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	suite.api.resyncGroupMemberships(context.Background(), 0)
+	membership = suite.groupMembership(suite.testOrganizationID)
+	require.NotNil(membership)
+	assert.Equal(models.RoleMember, membership.Role)
+}
+
+func (suite *HandlerTestSuite) TestGroupSyncRemovesMembersAndDevices() {
+	require := suite.Require()
+	assert := suite.Assert()
+	suite.api.db.Exec("DELETE FROM organization_group_mappings")
+
+	require.NoError(suite.api.db.Create(&models.OrganizationGroupMapping{
+		OrganizationID: suite.testOrganizationID,
+		Group:          "engineering",
+		Role:           models.RoleMember,
+	}).Error)
+
+	suite.serveAsUserWithGroups("engineering")
+	require.NotNil(suite.groupMembership(suite.testOrganizationID))
+	device := models.Device{
+		UserID:         TestUser2ID,
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "group-sync-device",
+	}
+	require.NoError(suite.api.db.Create(&device).Error)
+
+	// leaving the group removes the membership and the devices of the user in the organization
+	suite.serveAsUserWithGroups()
+	assert.Nil(suite.groupMembership(suite.testOrganizationID))
+	var count int64
+	require.NoError(suite.api.db.Model(&models.Device{}).Where("id = ?", device.ID).Count(&count).Error)
+	assert.Equal(int64(0), count)
+
+	var events []models.AuditEvent
+	require.NoError(suite.api.db.Where("organization_id = ? AND actor_name = ?", suite.testOrganizationID, groupSyncActor).Find(&events).Error)
+	assert.Len(events, 3)
+
+	// the memberships that were not added by a mapping are kept
+	require.NoError(suite.api.db.Model(&UserOrganization{}).Create(&UserOrganization{
+		UserID:         TestUser2ID,
+		OrganizationID: suite.testOrganizationID,
+		Role:           models.RoleReadOnly,
+	}).Error)
+	suite.serveAsUserWithGroups()
+	membership := suite.groupMembership(suite.testOrganizationID)
+	require.NotNil(membership)
+	assert.False(membership.GroupManaged)
+	require.NoError(suite.api.db.Where("user_id = ? AND organization_id = ?", TestUser2ID, suite.testOrganizationID).Delete(&UserOrganization{}).Error)
+
+	// the users whose groups weren't seen for the max age are removed by the resync
+	suite.serveAsUserWithGroups("engineering")
+	require.NotNil(suite.groupMembership(suite.testOrganizationID))
+	suite.api.resyncGroupMemberships(context.Background(), time.Hour)
+	require.NotNil(suite.groupMembership(suite.testOrganizationID))
+
+	require.NoError(suite.api.db.Model(&models.User{}).Where("id = ?", TestUser2ID).
+		UpdateColumn("groups_synced_at", time.Now().Add(-2*time.Hour)).Error)
+	suite.api.resyncGroupMemberships(context.Background(), time.Hour)
+	assert.Nil(suite.groupMembership(suite.testOrganizationID))
+	var user models.User
+	require.NoError(suite.api.db.First(&user, "id = ?", TestUser2ID).Error)
+	assert.Nil(user.GroupsSyncedAt)
+}
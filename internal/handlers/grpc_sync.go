@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/syncv1"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// GrpcAuthenticator authenticates the authorization header of a gRPC call as if it had been sent
+// with an http GET of path, params are the path parameters of the matching http route. It returns
+// the gin.Context of the authenticated request, or a gRPC status error.
+type GrpcAuthenticator func(ctx context.Context, authorization string, path string, params gin.Params) (*gin.Context, error)
+
+type deviceSyncServer struct {
+	syncv1.UnimplementedDeviceSyncServer
+	api          *API
+	authenticate GrpcAuthenticator
+}
+
+// NewDeviceSyncServer returns the gRPC DeviceSync service, it streams the same changes as the
+// watch=true list apis, encoded as protobuf messages.
+func NewDeviceSyncServer(api *API, authenticate GrpcAuthenticator) syncv1.DeviceSyncServer {
+	return &deviceSyncServer{
+		api:          api,
+		authenticate: authenticate,
+	}
+}
+
+func (s *deviceSyncServer) WatchDevices(req *syncv1.WatchRequest, stream syncv1.DeviceSync_WatchDevicesServer) error {
+	ctx, span := tracer.Start(stream.Context(), "WatchDevices", trace.WithAttributes(
+		attribute.String("organization", req.OrganizationId),
+	))
+	defer span.End()
+
+	orgId, err := s.authorizeOrganization(ctx, req.OrganizationId, "/api/organizations/%s/devices")
+	if err != nil {
+		return err
+	}
+
+	selector, err := models.ParseLabelSelector(req.Selector)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	scopes := []func(*gorm.DB) *gorm.DB{
+		func(db *gorm.DB) *gorm.DB {
+			return db.Where("organization_id = ?", orgId.String()).Order("revision")
+		},
+	}
+	return s.watch(ctx, fmt.Sprintf("/devices/org=%s", orgId.String()), "devices", req.GtRevision, scopes, getSelectedDeviceList(selector, req.GtRevision > 0), func(event models.WatchEvent) error {
+		result := &syncv1.DeviceEvent{Type: watchEventType(event.Type)}
+		switch value := event.Value.(type) {
+		case *models.Device:
+			result.Revision = value.Revision
+			result.Device = deviceToProto(value)
+		case models.WatchBookmark:
+			result.Revision = value.Revision
+		}
+		return stream.Send(result)
+	})
+}
+
+func (s *deviceSyncServer) WatchDeviceMetadata(req *syncv1.WatchDeviceMetadataRequest, stream syncv1.DeviceSync_WatchDeviceMetadataServer) error {
+	ctx, span := tracer.Start(stream.Context(), "WatchDeviceMetadata", trace.WithAttributes(
+		attribute.String("organization", req.OrganizationId),
+	))
+	defer span.End()
+
+	orgId, err := s.authorizeOrganization(ctx, req.OrganizationId, "/api/organizations/%s/metadata")
+	if err != nil {
+		return err
+	}
+
+	scopes := []func(*gorm.DB) *gorm.DB{
+		deviceMetadataInOrganization(orgId, req.Prefixes),
+		func(db *gorm.DB) *gorm.DB {
+			return db.Order("device_metadata.revision")
+		},
+	}
+	return s.watch(ctx, fmt.Sprintf("/metadata/org=%s", orgId.String()), "device_metadata", req.GtRevision, scopes, getDeviceMetadataList, func(event models.WatchEvent) error {
+		result := &syncv1.DeviceMetadataEvent{Type: watchEventType(event.Type)}
+		switch value := event.Value.(type) {
+		case *models.DeviceMetadata:
+			data, err := json.Marshal(value.Value)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			result.Revision = value.Revision
+			result.Metadata = &syncv1.DeviceMetadata{
+				DeviceId: value.DeviceID.String(),
+				Key:      value.Key,
+				Value:    data,
+				Revision: value.Revision,
+			}
+		case models.WatchBookmark:
+			result.Revision = value.Revision
+		}
+		return stream.Send(result)
+	})
+}
+
+func (s *deviceSyncServer) WatchSecurityGroups(req *syncv1.WatchRequest, stream syncv1.DeviceSync_WatchSecurityGroupsServer) error {
+	ctx, span := tracer.Start(stream.Context(), "WatchSecurityGroups", trace.WithAttributes(
+		attribute.String("organization", req.OrganizationId),
+	))
+	defer span.End()
+
+	orgId, err := s.authorizeOrganization(ctx, req.OrganizationId, "/api/organizations/%s/security_groups")
+	if err != nil {
+		return err
+	}
+
+	scopes := []func(*gorm.DB) *gorm.DB{
+		func(db *gorm.DB) *gorm.DB {
+			return db.Where("organization_id = ?", orgId).Order("revision")
+		},
+	}
+	return s.watch(ctx, securityGroupsSignal(orgId), "security_groups", req.GtRevision, scopes, getSecurityGroupList, func(event models.WatchEvent) error {
+		result := &syncv1.SecurityGroupEvent{Type: watchEventType(event.Type)}
+		switch value := event.Value.(type) {
+		case *models.SecurityGroup:
+			result.Revision = value.Revision
+			result.SecurityGroup = securityGroupToProto(value)
+		case models.WatchBookmark:
+			result.Revision = value.Revision
+		}
+		return stream.Send(result)
+	})
+}
+
+// authorizeOrganization authenticates the call, and checks that the organization can be read by
+// the caller. pathFormat is the path of the equivalent http api.
+func (s *deviceSyncServer) authorizeOrganization(ctx context.Context, organizationId string, pathFormat string) (uuid.UUID, error) {
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+
+	orgId, err := uuid.Parse(organizationId)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "organization_id is invalid")
+	}
+
+	// the organization parameter applies the rate limit of the organization to the call.
+	c, err := s.authenticate(ctx, authorization, fmt.Sprintf(pathFormat, orgId.String()), gin.Params{
+		{Key: "organization", Value: orgId.String()},
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var org models.Organization
+	if res := s.api.db.WithContext(ctx).
+		Scopes(s.api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", orgId.String()); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return uuid.Nil, status.Error(codes.NotFound, "organization not found")
+		}
+		return uuid.Nil, status.Error(codes.Internal, res.Error.Error())
+	}
+	return org.ID, nil
+}
+
+// watch sends the watch events of the table until the call is canceled.
+func (s *deviceSyncServer) watch(ctx context.Context, signal string, table string, gtRevision uint64, scopes []func(*gorm.DB) *gorm.DB, getList func(db *gorm.DB) (WatchableList, error), send func(event models.WatchEvent) error) error {
+	compacted, err := s.api.isRevisionCompacted(ctx, table, gtRevision)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if compacted {
+		return status.Error(codes.OutOfRange, revisionCompactedReason(gtRevision))
+	}
+
+	nextEvent, closeWatch := s.api.watchEvents(ctx, []string{signal}, table, gtRevision, scopes, getList)
+	defer closeWatch()
+	for {
+		event := nextEvent()
+		switch event.Type {
+		case "close":
+			return nil
+		case "error":
+			return status.Error(codes.Internal, fmt.Sprint(event.Value))
+		}
+		if err := send(event); err != nil {
+			return err
+		}
+	}
+}
+
+func watchEventType(eventType string) syncv1.EventType {
+	switch eventType {
+	case "change":
+		return syncv1.EventType_EVENT_TYPE_CHANGE
+	case "delete":
+		return syncv1.EventType_EVENT_TYPE_DELETE
+	case "bookmark":
+		return syncv1.EventType_EVENT_TYPE_BOOKMARK
+	}
+	return syncv1.EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func deviceToProto(device *models.Device) *syncv1.Device {
+	endpoints := make([]*syncv1.Endpoint, 0, len(device.Endpoints))
+	for _, endpoint := range device.Endpoints {
+		endpoints = append(endpoints, &syncv1.Endpoint{
+			Source:   endpoint.Source,
+			Address:  endpoint.Address,
+			Distance: int64(endpoint.Distance),
+		})
+	}
+	return &syncv1.Device{
+		Id:                      device.ID.String(),
+		UserId:                  device.UserID,
+		OrganizationId:          device.OrganizationID.String(),
+		PublicKey:               device.PublicKey,
+		AllowedIps:              device.AllowedIPs,
+		TunnelIp:                device.TunnelIP,
+		TunnelIpV6:              device.TunnelIpV6,
+		ChildPrefix:             device.ChildPrefix,
+		Relay:                   device.Relay,
+		Discovery:               device.Discovery,
+		OrganizationPrefix:      device.OrganizationPrefix,
+		OrganizationPrefixV6:    device.OrganizationPrefixV6,
+		EndpointLocalAddressIp4: device.EndpointLocalAddressIPv4,
+		SymmetricNat:            device.SymmetricNat,
+		Hostname:                device.Hostname,
+		Os:                      device.Os,
+		Endpoints:               endpoints,
+		Revision:                device.Revision,
+		SecurityGroupId:         device.SecurityGroupId.String(),
+		Ephemeral:               device.Ephemeral,
+		Labels:                  device.Labels,
+	}
+}
+
+func securityGroupToProto(group *models.SecurityGroup) *syncv1.SecurityGroup {
+	return &syncv1.SecurityGroup{
+		Id:               group.ID.String(),
+		GroupName:        group.GroupName,
+		GroupDescription: group.GroupDescription,
+		OrganizationId:   group.OrganizationId.String(),
+		InboundRules:     securityRulesToProto(group.InboundRules),
+		OutboundRules:    securityRulesToProto(group.OutboundRules),
+		Revision:         group.Revision,
+	}
+}
+
+func securityRulesToProto(rules []models.SecurityRule) []*syncv1.SecurityRule {
+	result := make([]*syncv1.SecurityRule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, &syncv1.SecurityRule{
+			IpProtocol: rule.IpProtocol,
+			FromPort:   rule.FromPort,
+			ToPort:     rule.ToPort,
+			IpRanges:   rule.IpRanges,
+		})
+	}
+	return result
+}
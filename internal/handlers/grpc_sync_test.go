@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/api/syncv1"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func (suite *HandlerTestSuite) newDeviceSyncClient(ctx context.Context) syncv1.DeviceSyncClient {
+	require := suite.Require()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	syncv1.RegisterDeviceSyncServer(server, NewDeviceSyncServer(suite.api, func(ctx context.Context, authorization string, path string, params gin.Params) (*gin.Context, error) {
+		if authorization != "Bearer test" {
+			return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+		}
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set(gin.AuthUserKey, TestUserID)
+		return c, nil
+	}))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	suite.T().Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(err)
+	suite.T().Cleanup(func() {
+		_ = conn.Close()
+	})
+	return syncv1.NewDeviceSyncClient(conn)
+}
+
+func (suite *HandlerTestSuite) TestGrpcWatchSecurityGroups() {
+	require := suite.Require()
+	assert := suite.Assert()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	group := models.SecurityGroup{
+		GroupName:      "grpc",
+		OrganizationId: suite.testOrganizationID,
+		InboundRules: []models.SecurityRule{
+			{IpProtocol: "tcp", FromPort: 22, ToPort: 22, IpRanges: []string{"10.0.0.0/8"}},
+		},
+	}
+	require.NoError(suite.api.db.Create(&group).Error)
+
+	// sqlite does not maintain the revisions, so set them here.
+	require.NoError(suite.api.db.Model(&models.SecurityGroup{}).
+		Where("organization_id = ?", suite.testOrganizationID).
+		UpdateColumn("revision", 1).Error)
+	require.NoError(suite.api.db.Model(&group).UpdateColumn("revision", 2).Error)
+
+	client := suite.newDeviceSyncClient(ctx)
+	stream, err := client.WatchSecurityGroups(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer test"), &syncv1.WatchRequest{
+		OrganizationId: suite.testOrganizationID.String(),
+		GtRevision:     1,
+	})
+	require.NoError(err)
+
+	event, err := stream.Recv()
+	require.NoError(err)
+	assert.Equal(syncv1.EventType_EVENT_TYPE_CHANGE, event.Type)
+	assert.Equal(uint64(2), event.Revision)
+	require.NotNil(event.SecurityGroup)
+	assert.Equal(group.ID.String(), event.SecurityGroup.Id)
+	assert.Equal("grpc", event.SecurityGroup.GroupName)
+	require.Len(event.SecurityGroup.InboundRules, 1)
+	assert.Equal(int64(22), event.SecurityGroup.InboundRules[0].FromPort)
+	assert.Equal([]string{"10.0.0.0/8"}, event.SecurityGroup.InboundRules[0].IpRanges)
+
+	event, err = stream.Recv()
+	require.NoError(err)
+	assert.Equal(syncv1.EventType_EVENT_TYPE_BOOKMARK, event.Type)
+	assert.Equal(uint64(2), event.Revision)
+	assert.Nil(event.SecurityGroup)
+}
+
+func (suite *HandlerTestSuite) TestGrpcWatchErrors() {
+	require := suite.Require()
+	assert := suite.Assert()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := suite.newDeviceSyncClient(ctx)
+
+	// the authenticator rejects the call.
+	stream, err := client.WatchDevices(ctx, &syncv1.WatchRequest{
+		OrganizationId: suite.testOrganizationID.String(),
+	})
+	require.NoError(err)
+	_, err = stream.Recv()
+	assert.Equal(codes.Unauthenticated, status.Code(err))
+
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer test")
+
+	// the caller is not a member of the organization.
+	stream, err = client.WatchDevices(authCtx, &syncv1.WatchRequest{
+		OrganizationId: "8c1a6a55-f2d4-4a4c-9a4d-8e0f5a1c2b3d",
+	})
+	require.NoError(err)
+	_, err = stream.Recv()
+	assert.Equal(codes.NotFound, status.Code(err))
+
+	// a watch can't resume from before the compacted revision.
+	require.NoError(suite.api.db.Create(&models.CompactedRevision{Name: "devices", Revision: 42}).Error)
+	stream, err = client.WatchDevices(authCtx, &syncv1.WatchRequest{
+		OrganizationId: suite.testOrganizationID.String(),
+		GtRevision:     10,
+	})
+	require.NoError(err)
+	_, err = stream.Recv()
+	assert.Equal(codes.OutOfRange, status.Code(err))
+}
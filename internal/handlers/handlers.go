@@ -8,6 +8,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
 const (
@@ -18,6 +20,12 @@ type Query struct {
 	Sort   string `form:"sort"`
 	Filter string `form:"filter"`
 	Range  string `form:"range"`
+	// Q is a comma separated list of conditions on the fields of the items, see parseQueryTerms.
+	Q string `form:"q"`
+	// Limit and Cursor paginate the list with a cursor, the X-Next-Cursor response header holds
+	// the cursor of the next page.
+	Limit  string `form:"limit"`
+	Cursor string `form:"cursor"`
 }
 
 func (q *Query) GetSort() (string, error) {
@@ -66,17 +74,60 @@ func FilterAndPaginate(model interface{}, c *gin.Context, orderBy string) func(d
 
 func FilterAndPaginateWithQuery(model interface{}, c *gin.Context, query Query, defaultOrderBy string) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
+		s, err := schema.Parse(model, querySchemaCache, db.NamingStrategy)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
 
+		var orderField *schema.Field
+		desc := false
 		if order, err := query.GetSort(); err == nil {
-			db = db.Order(order)
+			orderField, desc, err = queryOrder(s, order)
+			if err != nil {
+				_ = db.AddError(errInvalidQuery{Param: "sort", Reason: err.Error()})
+				return db
+			}
 		} else if defaultOrderBy != "" {
-			db = db.Order(defaultOrderBy)
+			orderField, desc, _ = queryOrder(s, defaultOrderBy)
+		}
+
+		if query.Q != "" {
+			exprs, err := queryTermsScope(s, query.Q)
+			if err != nil {
+				_ = db.AddError(err)
+				return db
+			}
+			db = db.Clauses(clause.Where{Exprs: exprs})
 		}
 
 		if filter, err := query.GetFilter(); err == nil {
 			db = db.Where(filter)
 		}
 
+		// watches send all the changes in revision order, they are not paginated with a cursor.
+		if (query.Limit != "" || query.Cursor != "") && c.Query("watch") != "true" {
+			if query.Range != "" {
+				_ = db.AddError(errInvalidQuery{Param: "range", Reason: "can't be used with a limit or a cursor"})
+				return db
+			}
+			if orderField == nil {
+				_ = db.AddError(errInvalidQuery{Param: "cursor", Reason: "the list can't be paginated with a cursor"})
+				return db
+			}
+			db, err = queryCursorScope(db, c, s, orderField, desc, query.Limit, query.Cursor)
+			if err != nil {
+				_ = db.AddError(err)
+			}
+			return db
+		}
+
+		if orderField != nil {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: orderField.DBName}, Desc: desc})
+		} else if defaultOrderBy != "" {
+			db = db.Order(defaultOrderBy)
+		}
+
 		if pageSize, offset, err := query.GetRange(); err == nil {
 			var totalCount int64
 			countDBSession := db.Session(&gorm.Session{Initialized: true})
@@ -84,7 +135,7 @@ func FilterAndPaginateWithQuery(model interface{}, c *gin.Context, query Query,
 			if res.Error != nil {
 				return db
 			}
-			c.Header("Access-Control-Expose-Headers", TotalCountHeader)
+			c.Header("Access-Control-Expose-Headers", exposedListHeaders)
 			c.Header(TotalCountHeader, strconv.Itoa(int(totalCount)))
 			db = db.Offset(offset).Limit(pageSize)
 		}
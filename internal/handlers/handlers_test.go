@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -19,6 +20,7 @@ import (
 	"github.com/nexodus-io/nexodus/internal/database"
 	"github.com/nexodus-io/nexodus/internal/fflags"
 	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/open-policy-agent/opa/storage/inmem"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -34,6 +36,8 @@ const (
 
 type HandlerTestSuite struct {
 	suite.Suite
+	// ipamBackend is the ipam.BackendDatabase or ipam.BackendRemote backend the handlers use.
+	ipamBackend        string
 	logger             *zap.SugaredLogger
 	ipam               *http.Server
 	wg                 *sync.WaitGroup
@@ -48,21 +52,25 @@ func (suite *HandlerTestSuite) SetupSuite() {
 		suite.T().Fatal(err)
 	}
 	suite.logger = zaptest.NewLogger(suite.T()).Sugar()
-	suite.ipam = ipam.NewTestIPAMServer()
 	suite.wg = &sync.WaitGroup{}
-	suite.wg.Add(1)
 
-	listener, err := net.Listen("tcp", "[::1]:49090")
-	suite.Require().NoError(err)
+	var ipamClient ipam.IPAM = ipam.NewDatabaseIPAM(suite.logger, db)
+	if suite.ipamBackend == ipam.BackendRemote {
+		suite.ipam = ipam.NewTestIPAMServer()
+		suite.wg.Add(1)
+
+		listener, err := net.Listen("tcp", "[::1]:49090")
+		suite.Require().NoError(err)
 
-	go func() {
-		defer suite.wg.Done()
-		if err := suite.ipam.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
-			suite.T().Logf("unexpected error starting ipam server: %s", err)
-		}
-	}()
+		go func() {
+			defer suite.wg.Done()
+			if err := suite.ipam.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				suite.T().Logf("unexpected error starting ipam server: %s", err)
+			}
+		}()
 
-	ipamClient := ipam.NewIPAM(suite.logger, ipamClientAddr)
+		ipamClient = ipam.NewRemoteIPAM(suite.logger, ipamClientAddr)
+	}
 
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     "localhost:6379",
@@ -77,11 +85,20 @@ func (suite *HandlerTestSuite) SetupSuite() {
 	}
 }
 
+func (suite *HandlerTestSuite) TearDownSuite() {
+	if suite.ipam != nil {
+		suite.Require().NoError(suite.ipam.Shutdown(context.Background()))
+	}
+	suite.wg.Wait()
+}
+
 func (suite *HandlerTestSuite) BeforeTest(_, _ string) {
 	suite.api.db.Exec("DELETE FROM users")
 	suite.api.db.Exec("DELETE FROM organizations")
 	suite.api.db.Exec("DELETE FROM user_organizations")
 	suite.api.db.Exec("DELETE FROM devices")
+	suite.api.db.Exec("DELETE FROM ipam_addresses")
+	suite.api.db.Exec("DELETE FROM ipam_prefixes")
 	var err error
 	suite.testOrganizationID, err = suite.api.createUserIfNotExists(context.Background(), TestUserID, "testuser")
 	suite.Require().NoError(err)
@@ -108,8 +125,22 @@ func (suite *HandlerTestSuite) ServeRequest(method, path string, uri string, han
 	return req, res, nil
 }
 
+// createDevice creates the device with the CreateDevice api, and requires it to succeed.
+func (suite *HandlerTestSuite) createDevice(request models.AddDevice) models.Device {
+	_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(suite.jsonMarshal(request)))
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusCreated, res.Code, res.Body.String())
+	var device models.Device
+	suite.Require().NoError(json.Unmarshal(res.Body.Bytes(), &device))
+	return device
+}
+
 func TestHandlerTestSuite(t *testing.T) {
-	suite.Run(t, new(HandlerTestSuite))
+	suite.Run(t, &HandlerTestSuite{ipamBackend: ipam.BackendDatabase})
+}
+
+func TestHandlerTestSuiteWithRemoteIPAM(t *testing.T) {
+	suite.Run(t, &HandlerTestSuite{ipamBackend: ipam.BackendRemote})
 }
 
 func TestQuerySort(t *testing.T) {
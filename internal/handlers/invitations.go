@@ -1,11 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/nexodus-io/nexodus/internal/database"
+	"github.com/nexodus-io/nexodus/internal/email"
+	"github.com/nexodus-io/nexodus/internal/util"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,9 +22,78 @@ import (
 	"gorm.io/gorm"
 )
 
+// invitationsSignal is notified when the invitations of a user, or of the organizations they
+// administer, are created, accepted or deleted.
+func invitationsSignal(userId string) string {
+	return fmt.Sprintf("/invitations/user=%s", userId)
+}
+
+// invitationsEmailSignal is notified when the invitations sent to an email address are created,
+// accepted or deleted.
+func invitationsEmailSignal(address string) string {
+	return fmt.Sprintf("/invitations/email=%s", strings.ToLower(address))
+}
+
+// notifyInvitationsChanged notifies the watchers of the invitations of the invited users and of the
+// admins of the organizations of the invitations.
+func (api *API) notifyInvitationsChanged(ctx context.Context, invitations ...models.Invitation) {
+	var orgIds []uuid.UUID
+	for _, invitation := range invitations {
+		if invitation.UserID != "" {
+			api.signalBus.Notify(invitationsSignal(invitation.UserID))
+		}
+		if invitation.Email != "" {
+			api.signalBus.Notify(invitationsEmailSignal(invitation.Email))
+		}
+		orgIds = append(orgIds, invitation.OrganizationID)
+	}
+	userIds, err := organizationUserIDs(api.db.WithContext(ctx), models.RoleAdmin, orgIds...)
+	if err != nil {
+		api.logger.Warnf("failed to find the admins of the organizations of the changed invitations: %v", err)
+		return
+	}
+	for _, userId := range userIds {
+		api.signalBus.Notify(invitationsSignal(userId))
+	}
+}
+
+// key for the email of the user in gin.Context
+const AuthUserEmail string = "_nexodus.UserEmail"
+
+// key for whether the identity provider verified the email of the user in gin.Context
+const AuthUserEmailVerified string = "_nexodus.UserEmailVerified"
+
+// maxInvitationExpiry is the longest expiry an invitation can be created with.
+const maxInvitationExpiry = 30 * 24 * time.Hour
+
+// invitationCleanupInterval is how often the expired invitations are deleted.
+const invitationCleanupInterval = 10 * time.Minute
+
+const invitationCleanupActor = "invitation-cleanup"
+
+var errAlreadyMember = errors.New("user is already in requested org")
+
+// InvitationConfig configures the invitations.
+type InvitationConfig struct {
+	// Expiry is how long the invitations are valid when they are created without an expiry.
+	Expiry time.Duration
+	// LinkURL is the url of the invitation links, the token of the invitation is added to its query.
+	LinkURL string
+	// Sender delivers the invitations by email, the invitations are only returned as links when it's nil.
+	Sender email.Sender
+}
+
+// SetInvitations sets how the invitations are created and delivered.
+func (api *API) SetInvitations(config InvitationConfig) {
+	if config.Expiry <= 0 {
+		config.Expiry = models.DefaultInvitationExpiry
+	}
+	api.invitations = config
+}
+
 // CreateInvitation creates an invitation
 // @Summary      Create an invitation
-// @Description  Create an invitation to an organization
+// @Description  Create an invitation to an organization, for a user or an email address. The link of the invitation is only returned once.
 // @Id           CreateInvitation
 // @Tags         Invitation
 // @Accept       json
@@ -24,8 +101,10 @@ import (
 // @Param        Invitation  body     models.AddInvitation  true  "Add Invitation"
 // @Success      201  {object}  models.Invitation
 // @Failure      400  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure      409  {object}  models.ConflictsError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Router       /api/invitations [post]
 func (api *API) CreateInvitation(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "InviteUserToOrganization")
@@ -46,16 +125,45 @@ func (api *API) CreateInvitation(c *gin.Context) {
 		return
 	}
 
-	// Only allow org owners to create invites...
+	if request.Role == "" {
+		request.Role = models.RoleMember
+	}
+	if !models.IsValidRole(request.Role) || request.Role == models.RoleOwner {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("role", fmt.Sprintf("must be one of '%s', '%s' or '%s'", models.RoleAdmin, models.RoleMember, models.RoleReadOnly)))
+		return
+	}
+	expiry := api.invitationExpiry()
+	if request.ExpiresIn != "" {
+		expiry, err = time.ParseDuration(request.ExpiresIn)
+		if err != nil || expiry <= 0 || expiry > maxInvitationExpiry {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("expires_in", fmt.Sprintf("must be a duration between 1s and %s", maxInvitationExpiry)))
+			return
+		}
+	}
+
+	// Only allow org admins to create invites...
 	var org models.Organization
 	if res := api.db.WithContext(ctx).
-		Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+		Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
 		First(&org, "id = ?", request.OrganizationID); res.Error != nil {
 		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
 		return
 	}
+	// ...and the owner to invite admins, like the owner is the only one who can grant the admin role.
+	if request.Role == models.RoleAdmin {
+		role, err := currentUserRole(c, api.db.WithContext(ctx), org)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+			return
+		}
+		if role != models.RoleOwner {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(errAdminRoleRequired.Error()))
+			return
+		}
+	}
 
 	var user models.User
+	var address string
 	if request.UserID != "" {
 		if res := api.db.WithContext(ctx).
 			Preload("Organizations").
@@ -72,14 +180,21 @@ func (api *API) CreateInvitation(c *gin.Context) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("user"))
 			return
 		}
+	} else if request.Email != "" {
+		parsed, err := mail.ParseAddress(request.Email)
+		if err != nil || parsed.Address != request.Email {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("email", "must be an email address"))
+			return
+		}
+		address = strings.ToLower(parsed.Address)
 	} else {
-		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("username or user_id"))
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("username, user_id or email"))
 		return
 	}
 
 	for _, org := range user.Organizations {
 		if org.ID == request.OrganizationID {
-			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("organization", "user is already in requested org"))
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("organization", errAlreadyMember.Error()))
 			return
 		}
 	}
@@ -89,15 +204,101 @@ func (api *API) CreateInvitation(c *gin.Context) {
 			return
 		}
 	}
+	if address != "" {
+		var inv models.Invitation
+		res := api.db.WithContext(ctx).
+			First(&inv, "email = ? AND organization_id = ? AND expiry > ?", address, request.OrganizationID, time.Now())
+		if res.Error == nil {
+			c.JSON(http.StatusConflict, models.NewConflictsError(inv.ID.String()))
+			return
+		} else if !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+			return
+		}
+	}
 
-	invite := models.NewInvitation(user.ID, request.OrganizationID)
-	if res := api.db.WithContext(ctx).Create(&invite); res.Error != nil {
+	token, err := newBearerToken("")
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return
 	}
+	invite := models.NewInvitation(user.ID, request.OrganizationID, request.Role, expiry)
+	invite.Email = address
+	invite.TokenHash = hashBearerToken(token)
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Create(&invite); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, invite.OrganizationID, models.AuditActionCreate, "invitation", invite.ID.String(), nil, invite)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	api.notifyInvitationsChanged(ctx, invite)
+
+	invite.Link = api.invitationLink(token)
+	if address != "" && api.invitations.Sender != nil {
+		// the invitation is kept when it can't be delivered, its link can still be shared.
+		if err := api.invitations.Sender.Send(ctx, invitationEmail(invite, org, c.GetString(AuthUserName))); err != nil {
+			api.Logger(ctx).Errorf("failed to send the invitation %s by email: %v", invite.ID, err)
+		}
+	}
 	c.JSON(http.StatusCreated, invite)
 }
 
+func (api *API) invitationExpiry() time.Duration {
+	if api.invitations.Expiry <= 0 {
+		return models.DefaultInvitationExpiry
+	}
+	return api.invitations.Expiry
+}
+
+// invitationLink returns the link that accepts the invitation with the token, or the token itself
+// when no link url is configured.
+func (api *API) invitationLink(token string) string {
+	if api.invitations.LinkURL == "" {
+		return token
+	}
+	link, err := url.Parse(api.invitations.LinkURL)
+	if err != nil {
+		return token
+	}
+	query := url.Values{"token": {token}}.Encode()
+	if link.Fragment != "" {
+		// the SPA routes with the fragment of the url, so the token is a query of the fragment.
+		separator := "?"
+		if strings.Contains(link.Fragment, "?") {
+			separator = "&"
+		}
+		link.Fragment += separator + query
+	} else if link.RawQuery != "" {
+		link.RawQuery += "&" + query
+	} else {
+		link.RawQuery = query
+	}
+	return link.String()
+}
+
+func invitationEmail(invite models.Invitation, org models.Organization, inviter string) email.Message {
+	from := "You have"
+	if inviter != "" {
+		from = inviter + " has"
+	}
+	return email.Message{
+		To:      invite.Email,
+		Subject: fmt.Sprintf("You're invited to join the %s organization on Nexodus", org.Name),
+		Body: fmt.Sprintf(`%s been invited to join the %s organization on Nexodus, as a %s.
+
+Accept the invitation with this link, it can only be used once:
+
+%s
+
+The invitation expires on %s.
+`, from, org.Name, invite.Role, invite.Link, invite.Expiry.UTC().Format(time.RFC1123)),
+	}
+}
+
 // ListInvitations lists invitations
 // @Summary      List Invitations
 // @Description  Lists all invitations
@@ -105,23 +306,60 @@ func (api *API) CreateInvitation(c *gin.Context) {
 // @Tags         Invitation
 // @Accept       json
 // @Produce      json
+// @Param		 gt_revision     query  uint64 false "greater than revision"
+// @Param        q               query  string  false  "conditions on the fields, for example role=member,expiry<2023-07-01"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
 // @Success      200  {object}  []models.Invitation
+// @Failure		 400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 410  {object}  models.GoneError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Router       /api/invitations [get]
 func (api *API) ListInvitations(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "ListInvitations")
 	defer span.End()
-	invitations := make([]*models.Invitation, 0)
-	result := api.db.WithContext(ctx).
-		Scopes(api.InvitationIsForCurrentUserOrOrgOwner(c)).
-		Scopes(FilterAndPaginate(&models.Invitation{}, c, "id")).
-		Find(&invitations)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "error fetching keys from db"})
+
+	var query Query
+	if err := c.BindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiInternalError(err))
 		return
 	}
-	c.JSON(http.StatusOK, invitations)
+
+	defaultOrderBy := "id"
+	if v := c.Query("watch"); v == "true" {
+		query.Sort = ""
+		defaultOrderBy = "revision"
+	}
+
+	scopes := []func(*gorm.DB) *gorm.DB{
+		api.InvitationIsForCurrentUserOrOrgAdmin(c),
+		FilterAndPaginateWithQuery(&models.Invitation{}, c, query, defaultOrderBy),
+	}
+
+	signals := []string{invitationsSignal(c.Value(gin.AuthUserKey).(string))}
+	if address := currentUserEmail(c); address != "" {
+		signals = append(signals, invitationsEmailSignal(address))
+	}
+	api.sendListOrWatch(c, ctx, signals, "invitations", scopes, func(db *gorm.DB) (WatchableList, error) {
+		var items invitationList
+		result := db.Find(&items)
+		if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, result.Error
+		}
+		return items, nil
+	})
+}
+
+type invitationList []*models.Invitation
+
+func (d invitationList) Item(i int) (any, uint64, gorm.DeletedAt) {
+	item := d[i]
+	return item, item.Revision, item.DeletedAt
+}
+
+func (d invitationList) Len() int {
+	return len(d)
 }
 
 // GetInvitation gets a specific Invitation
@@ -135,7 +373,7 @@ func (api *API) ListInvitations(c *gin.Context) {
 // @Success      200  {object}  models.Organization
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure      404  {object}  models.BaseError
 // @Router       /api/organizations/{id} [get]
 func (api *API) GetInvitation(c *gin.Context) {
@@ -151,7 +389,7 @@ func (api *API) GetInvitation(c *gin.Context) {
 	}
 	var org models.Invitation
 	result := api.db.WithContext(ctx).
-		Scopes(api.InvitationIsForCurrentUserOrOrgOwner(c)).
+		Scopes(api.InvitationIsForCurrentUserOrOrgAdmin(c)).
 		First(&org, "id = ?", k.String())
 
 	if result.Error != nil {
@@ -171,23 +409,38 @@ func (api *API) InvitationIsForCurrentUser(c *gin.Context) func(db *gorm.DB) *go
 		userId := c.Value(gin.AuthUserKey).(string)
 
 		// this could potentially be driven by rego output
+		if address := currentUserEmail(c); address != "" {
+			return db.Where("user_id = ? OR (user_id = '' AND email = ?)", userId, address)
+		}
 		return db.Where("user_id = ?", userId)
 	}
 }
 
-func (api *API) InvitationIsForCurrentUserOrOrgOwner(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+func (api *API) InvitationIsForCurrentUserOrOrgAdmin(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		userId := c.Value(gin.AuthUserKey).(string)
+		address := currentUserEmail(c)
+		roles := models.RolesAtLeast(models.RoleAdmin)
 
 		// this could potentially be driven by rego output
 		if api.dialect == database.DialectSqlLite {
-			return db.Where("user_id = ? OR organization_id in (SELECT id FROM organizations where owner_id=?)", userId, userId)
+			return db.Where("user_id = ? OR (user_id = '' AND email <> '' AND email = ?) OR organization_id in (SELECT id FROM organizations where owner_id=?) OR organization_id in (SELECT organization_id FROM user_organizations where user_id=? AND role in ?)", userId, address, userId, userId, roles)
 		} else {
-			return db.Where("user_id = ? OR organization_id::text in (SELECT id::text FROM organizations where owner_id=?)", userId, userId)
+			return db.Where("user_id = ? OR (user_id = '' AND email <> '' AND email = ?) OR organization_id::text in (SELECT id::text FROM organizations where owner_id=?) OR organization_id::text in (SELECT organization_id::text FROM user_organizations where user_id=? AND role in ?)", userId, address, userId, userId, roles)
 		}
 	}
 }
 
+// currentUserEmail returns the email of the current user, from the claims of their token. It's
+// empty when the identity provider didn't verify it, the invitations for the email can then only
+// be accepted with their link.
+func currentUserEmail(c *gin.Context) string {
+	if !c.GetBool(AuthUserEmailVerified) {
+		return ""
+	}
+	return strings.ToLower(c.GetString(AuthUserEmail))
+}
+
 // AcceptInvitation accepts an invitation
 // @Summary      Accept an invitation
 // @Description  Accept an invitation to an organization
@@ -199,8 +452,8 @@ func (api *API) InvitationIsForCurrentUserOrOrgOwner(c *gin.Context) func(db *go
 // @Success      204
 // @Failure      400  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
-// @Router       /api/invitations/:invitation/accept [post]
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Router       /api/invitations/{invitation}/accept [post]
 func (api *API) AcceptInvitation(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "InviteUserToOrganization")
 	defer span.End()
@@ -220,15 +473,69 @@ func (api *API) AcceptInvitation(c *gin.Context) {
 		return
 	}
 
+	api.acceptInvitation(ctx, c, func(tx *gorm.DB) *gorm.DB {
+		return tx.Scopes(api.InvitationIsForCurrentUser(c)).Where("id = ?", k)
+	})
+}
+
+// AcceptInvitationLink accepts an invitation with the token of its link
+// @Summary      Accept an invitation link
+// @Description  Accept an invitation to an organization with the token of its link, the link can only be used once
+// @Id           AcceptInvitationLink
+// @Tags         Invitation
+// @Accept		 json
+// @Produce      json
+// @Param        AcceptInvitation  body     models.AcceptInvitation  true  "Accept Invitation"
+// @Success      204
+// @Failure      400  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Router       /api/invitations/accept [post]
+func (api *API) AcceptInvitationLink(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AcceptInvitationLink")
+	defer span.End()
+	multiOrganizationEnabled, err := api.fflags.GetFlag("multi-organization")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	allowForTests := c.GetString("_apex.testCreateOrganization")
+	if !multiOrganizationEnabled && allowForTests != "true" {
+		c.JSON(http.StatusMethodNotAllowed, models.NewNotAllowedError("multi-organization support is disabled"))
+		return
+	}
+	var request models.AcceptInvitation
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.Token == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("token"))
+		return
+	}
+
+	// the link of an invitation for a user can only be used by that user.
+	userId := c.Value(gin.AuthUserKey).(string)
+	api.acceptInvitation(ctx, c, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("token_hash = ?", hashBearerToken(request.Token)).Where("user_id = '' OR user_id = ?", userId)
+	})
+}
+
+// acceptInvitation adds the current user to the organization of the invitation found by the scope,
+// with the role of the invitation, and deletes the invitation.
+func (api *API) acceptInvitation(ctx context.Context, c *gin.Context, scope func(*gorm.DB) *gorm.DB) {
+	userId := c.Value(gin.AuthUserKey).(string)
 	var invitation models.Invitation
-	err = api.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	var role string
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
 		if res := tx.
-			Scopes(api.InvitationIsForCurrentUser(c)).
-			First(&invitation, "id = ?", k); res.Error != nil {
+			Scopes(scope).
+			First(&invitation, "expiry > ?", time.Now()); res.Error != nil {
 			return errInvitationNotFound
 		}
 		var user models.User
-		if res := tx.Preload("Organizations").First(&user, "id = ?", invitation.UserID); res.Error != nil {
+		if res := tx.First(&user, "id = ?", userId); res.Error != nil {
 			return errUserNotFound
 		}
 
@@ -236,14 +543,42 @@ func (api *API) AcceptInvitation(c *gin.Context) {
 		if res := tx.First(&org, "id = ?", invitation.OrganizationID); res.Error != nil {
 			return errOrgNotFound
 		}
-		user.Organizations = append(user.Organizations, &org)
-		if res := tx.Save(&user); res.Error != nil {
+		var count int64
+		if res := tx.Model(&UserOrganization{}).Where("user_id = ? AND organization_id = ?", user.ID, org.ID).Count(&count); res.Error != nil {
 			return res.Error
 		}
+		if count > 0 || org.OwnerID == user.ID {
+			return errAlreadyMember
+		}
+		role = invitation.Role
+		if role == "" {
+			role = models.RoleMember
+		}
+		if res := tx.Create(&UserOrganization{
+			UserID:         user.ID,
+			OrganizationID: org.ID,
+			Role:           role,
+		}); res.Error != nil {
+			return res.Error
+		}
+		// a concurrent accept of the same invitation deletes no row, so that it's only used once.
 		if res := tx.Delete(&invitation); res.Error != nil {
 			return res.Error
+		} else if res.RowsAffected != 1 {
+			return errInvitationNotFound
 		}
-		return nil
+		if err := touchOrganization(tx, org.ID); err != nil {
+			return err
+		}
+		if err := api.queueWebhookEvent(tx, org.ID, models.WebhookEventInvitationAccepted, invitation); err != nil {
+			return err
+		}
+		// accepting the invitation adds the user to the organization
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionCreate, "organization_member", user.ID, nil, models.OrganizationMember{
+			UserID:   user.ID,
+			UserName: user.UserName,
+			Role:     role,
+		})
 	})
 
 	if err != nil {
@@ -253,12 +588,18 @@ func (api *API) AcceptInvitation(c *gin.Context) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("user"))
 		} else if errors.Is(err, errOrgNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errAlreadyMember) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("organization", err.Error()))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
 		return
 	}
 
+	api.notifyInvitationsChanged(ctx, invitation)
+	api.signalBus.Notify(invitationsSignal(userId))
+	api.notifyOrganizationsChanged(ctx, invitation.OrganizationID)
+	api.signalBus.Notify(webhooksSignal)
 	c.Status(http.StatusNoContent)
 }
 
@@ -273,7 +614,7 @@ func (api *API) AcceptInvitation(c *gin.Context) {
 // @Success      204  {object}  models.Organization
 // @Failure      400  {object}  models.BaseError
 // @Failure      405  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/invitations/{invitation} [delete]
 func (api *API) DeleteInvitation(c *gin.Context) {
@@ -297,15 +638,68 @@ func (api *API) DeleteInvitation(c *gin.Context) {
 
 	var invitation models.Invitation
 	if res := api.db.WithContext(ctx).
-		Scopes(api.InvitationIsForCurrentUserOrOrgOwner(c)).
+		Scopes(api.InvitationIsForCurrentUserOrOrgAdmin(c)).
 		First(&invitation, "id = ?", k); res.Error != nil {
 		c.JSON(http.StatusNotFound, models.NewNotFoundError("invitation"))
 		return
 	}
 
-	if res := api.db.WithContext(ctx).Delete(&models.Invitation{}, k); res.Error != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Delete(&models.Invitation{}, k); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, invitation.OrganizationID, models.AuditActionDelete, "invitation", invitation.ID.String(), invitation, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return
 	}
+	api.notifyInvitationsChanged(ctx, invitation)
 	c.Status(http.StatusNoContent)
 }
+
+// StartInvitationCleanup starts the background worker that deletes the expired invitations.
+func (api *API) StartInvitationCleanup(ctx context.Context, wg *sync.WaitGroup) {
+	util.GoWithWaitGroup(wg, func() {
+		ticker := time.NewTicker(invitationCleanupInterval)
+		defer ticker.Stop()
+		for {
+			if err := api.deleteExpiredInvitations(ctx, time.Now()); err != nil {
+				api.logger.Errorf("failed to delete the expired invitations: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// deleteExpiredInvitations deletes the invitations that expired before the given time.
+func (api *API) deleteExpiredInvitations(ctx context.Context, before time.Time) error {
+	var invitations []models.Invitation
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		invitations = nil
+		if res := tx.Find(&invitations, "expiry < ?", before); res.Error != nil {
+			return res.Error
+		}
+		for _, invitation := range invitations {
+			if res := tx.Delete(&invitation); res.Error != nil {
+				return res.Error
+			}
+			if err := recordSystemAuditEvent(tx, invitation.OrganizationID, invitationCleanupActor, models.AuditActionDelete, "invitation", invitation.ID.String(), invitation, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(invitations) > 0 {
+		api.logger.Infof("deleted %d expired invitations", len(invitations))
+		api.notifyInvitationsChanged(ctx, invitations...)
+	}
+	return nil
+}
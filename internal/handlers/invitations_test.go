@@ -2,14 +2,18 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/email"
 	"github.com/nexodus-io/nexodus/internal/models"
 )
 
@@ -146,3 +150,140 @@ func (suite *HandlerTestSuite) TestCreateAcceptRefuseInvitation() {
 		}
 	}
 }
+
+// serveInvitationRequest sends a request to an invitation handler as the user.
+func (suite *HandlerTestSuite) serveInvitationRequest(method, path string, uri string, handler func(*gin.Context), userID string, userEmail string, body any) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(suite.jsonMarshal(body))
+	}
+	_, res, err := suite.ServeRequest(method, path, uri, func(c *gin.Context) {
+		c.Set("_apex.testCreateOrganization", "true")
+		c.Set(gin.AuthUserKey, userID)
+		if userEmail != "" {
+			c.Set(AuthUserEmail, userEmail)
+			c.Set(AuthUserEmailVerified, true)
+		}
+		handler(c)
+	}, reader)
+	suite.Require().NoError(err)
+	return res
+}
+
+func (suite *HandlerTestSuite) TestInvitationByEmail() {
+	require := suite.Require()
+	assert := suite.Assert()
+	suite.api.db.Exec("DELETE FROM invitations")
+	sender := &email.Recorder{}
+	suite.api.SetInvitations(InvitationConfig{
+		LinkURL: "https://nexodus.example.com/#/invitations/accept",
+		Sender:  sender,
+	})
+	defer suite.api.SetInvitations(InvitationConfig{})
+
+	invite := func(request models.AddInvitation) *httptest.ResponseRecorder {
+		return suite.serveInvitationRequest(http.MethodPost, "/", "/", suite.api.CreateInvitation, TestUser2ID, "", request)
+	}
+
+	res := invite(models.AddInvitation{Email: "jane@example.com", OrganizationID: suite.testUser2OrgID, Role: models.RoleOwner})
+	assert.Equal(http.StatusBadRequest, res.Code)
+	res = invite(models.AddInvitation{Email: "jane@example.com", OrganizationID: suite.testUser2OrgID, ExpiresIn: "1000h"})
+	assert.Equal(http.StatusBadRequest, res.Code)
+	res = invite(models.AddInvitation{Email: "Jane <jane@example.com>", OrganizationID: suite.testUser2OrgID})
+	assert.Equal(http.StatusBadRequest, res.Code)
+	assert.Empty(sender.Messages())
+
+	res = invite(models.AddInvitation{Email: "Jane@Example.com", OrganizationID: suite.testUser2OrgID, Role: models.RoleReadOnly, ExpiresIn: "48h"})
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var invitation models.Invitation
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &invitation))
+	assert.Equal("jane@example.com", invitation.Email)
+	assert.Empty(invitation.UserID)
+	assert.Equal(models.RoleReadOnly, invitation.Role)
+	assert.WithinDuration(time.Now().Add(48*time.Hour), invitation.Expiry, time.Minute)
+	require.True(strings.HasPrefix(invitation.Link, "https://nexodus.example.com/#/invitations/accept?token="), invitation.Link)
+	token := strings.TrimPrefix(invitation.Link, "https://nexodus.example.com/#/invitations/accept?token=")
+
+	messages := sender.Messages()
+	require.Len(messages, 1)
+	assert.Equal("jane@example.com", messages[0].To)
+	assert.Contains(messages[0].Body, invitation.Link)
+
+	res = invite(models.AddInvitation{Email: "jane@example.com", OrganizationID: suite.testUser2OrgID})
+	assert.Equal(http.StatusConflict, res.Code)
+
+	// the link is not returned again
+	res = suite.serveInvitationRequest(http.MethodGet, "/:invitation", fmt.Sprintf("/%s", invitation.ID), suite.api.GetInvitation, TestUser2ID, "", nil)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	assert.NotContains(res.Body.String(), token)
+
+	// the invitee sees the invitations for their email
+	res = suite.serveInvitationRequest(http.MethodGet, "/", "/", suite.api.ListInvitations, TestUserID, "JANE@example.com", nil)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var invitations []models.Invitation
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &invitations))
+	require.Len(invitations, 1)
+	res = suite.serveInvitationRequest(http.MethodGet, "/", "/", suite.api.ListInvitations, TestUserID, "", nil)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &invitations))
+	assert.Len(invitations, 0)
+
+	// an email that the identity provider didn't verify doesn't match the invitations
+	_, res, err := suite.ServeRequest(http.MethodGet, "/", "/", func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, TestUserID)
+		c.Set(AuthUserEmail, "jane@example.com")
+		suite.api.ListInvitations(c)
+	}, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &invitations))
+	assert.Len(invitations, 0)
+
+	// the link can only be used once
+	accept := func(token string) *httptest.ResponseRecorder {
+		return suite.serveInvitationRequest(http.MethodPost, "/accept", "/accept", suite.api.AcceptInvitationLink, TestUserID, "", models.AcceptInvitation{Token: token})
+	}
+	res = accept("not-the-token")
+	assert.Equal(http.StatusNotFound, res.Code)
+	res = accept(token)
+	require.Equal(http.StatusNoContent, res.Code, "HTTP error: %s", res.Body.String())
+	var membership UserOrganization
+	require.NoError(suite.api.db.First(&membership, "user_id = ? AND organization_id = ?", TestUserID, suite.testUser2OrgID).Error)
+	assert.Equal(models.RoleReadOnly, membership.Role)
+	res = accept(token)
+	assert.Equal(http.StatusNotFound, res.Code)
+}
+
+func (suite *HandlerTestSuite) TestInvitationExpiry() {
+	require := suite.Require()
+	assert := suite.Assert()
+	suite.api.db.Exec("DELETE FROM invitations")
+	suite.api.SetInvitations(InvitationConfig{Expiry: time.Hour})
+	defer suite.api.SetInvitations(InvitationConfig{})
+
+	res := suite.serveInvitationRequest(http.MethodPost, "/", "/", suite.api.CreateInvitation, TestUser2ID, "", models.AddInvitation{
+		UserID:         TestUserID,
+		OrganizationID: suite.testUser2OrgID,
+	})
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var invitation models.Invitation
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &invitation))
+	assert.Equal(models.RoleMember, invitation.Role)
+	assert.WithinDuration(time.Now().Add(time.Hour), invitation.Expiry, time.Minute)
+	// without a link url, the link is the token of the invitation
+	require.NotEmpty(invitation.Link)
+
+	// the link of an invitation for a user can't be used by another user
+	res = suite.serveInvitationRequest(http.MethodPost, "/accept", "/accept", suite.api.AcceptInvitationLink, TestUser2ID, "", models.AcceptInvitation{Token: invitation.Link})
+	assert.Equal(http.StatusNotFound, res.Code)
+
+	// expired invitations can't be accepted, and are deleted
+	require.NoError(suite.api.db.Model(&models.Invitation{}).Where("id = ?", invitation.ID).Update("expiry", time.Now().Add(-time.Minute)).Error)
+	res = suite.serveInvitationRequest(http.MethodPost, "/:invitation", fmt.Sprintf("/%s", invitation.ID), suite.api.AcceptInvitation, TestUserID, "", nil)
+	assert.Equal(http.StatusNotFound, res.Code)
+
+	require.NoError(suite.api.deleteExpiredInvitations(context.Background(), time.Now()))
+	var count int64
+	require.NoError(suite.api.db.Model(&models.Invitation{}).Where("id = ?", invitation.ID).Count(&count).Error)
+	assert.Equal(int64(0), count)
+}
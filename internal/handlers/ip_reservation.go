@@ -0,0 +1,444 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var errIpReservationNotFound = errors.New("ip reservation not found")
+
+type errDuplicateIpReservation struct {
+	ID string
+}
+
+func (e errDuplicateIpReservation) Error() string {
+	return "duplicate ip reservation"
+}
+
+// errIpAddressUnavailable is returned when a reserved address is allocated to something else.
+type errIpAddressUnavailable struct {
+	Field string
+}
+
+func (e errIpAddressUnavailable) Error() string {
+	return "the address is already allocated"
+}
+
+// CreateIpReservation reserves tunnel addresses for a device identity
+// @Summary      Reserve tunnel addresses
+// @Description  Reserves tunnel addresses of an organization for the device with a hostname, public key or registration key. The addresses are given to the device whenever it joins the organization
+// @Id           CreateIpReservation
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string                   true  "Organization ID"
+// @Param        Reservation      body   models.AddIpReservation  true  "Add IP Reservation"
+// @Success      201  {object}  models.IpReservation
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      409  {object}  models.ConflictsError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/ip_reservations [post]
+func (api *API) CreateIpReservation(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateIpReservation", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.AddIpReservation
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	identities := 0
+	for _, set := range []bool{request.Hostname != "", request.PublicKey != "", request.RegistrationKeyID != uuid.Nil} {
+		if set {
+			identities++
+		}
+	}
+	if identities == 0 {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("hostname"))
+		return
+	}
+	if identities > 1 {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("hostname", "only one of hostname, public_key and registration_key_id can be set"))
+		return
+	}
+	if request.TunnelIP == "" && request.TunnelIpV6 == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("tunnel_ip"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleOwner)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+	reservation := models.IpReservation{
+		OrganizationID:    org.ID,
+		Hostname:          request.Hostname,
+		PublicKey:         request.PublicKey,
+		RegistrationKeyID: request.RegistrationKeyID,
+		Description:       request.Description,
+	}
+	if request.TunnelIP != "" {
+		if reservation.TunnelIP, err = reservableAddress(request.TunnelIP, org.IpCidr, false); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("tunnel_ip", err.Error()))
+			return
+		}
+	}
+	if request.TunnelIpV6 != "" {
+		if reservation.TunnelIpV6, err = reservableAddress(request.TunnelIpV6, org.IpCidrV6, true); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("tunnel_ip_v6", err.Error()))
+			return
+		}
+	}
+
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		ctx := ipam.WithTransaction(ctx, tx)
+		var regKey models.RegKey
+		if reservation.RegistrationKeyID != uuid.Nil {
+			if res := tx.First(&regKey, "id = ? AND organization_id = ?", reservation.RegistrationKeyID, org.ID); res.Error != nil {
+				return errRegKeyNotFound
+			}
+		}
+
+		var existing models.IpReservation
+		res := tx.Where("organization_id = ?", org.ID).
+			Where(tx.Where("hostname = ? AND public_key = ? AND registration_key_id = ?", reservation.Hostname, reservation.PublicKey, reservation.RegistrationKeyID).
+				Or("tunnel_ip <> '' AND tunnel_ip = ?", reservation.TunnelIP).
+				Or("tunnel_ip_v6 <> '' AND tunnel_ip_v6 = ?", reservation.TunnelIpV6)).
+			First(&existing)
+		if res.Error == nil {
+			return errDuplicateIpReservation{ID: existing.ID.String()}
+		}
+		if !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return res.Error
+		}
+
+		ipamNamespace := defaultIPAMNamespace
+		if org.PrivateCidr {
+			ipamNamespace = org.ID
+		}
+		for _, address := range []struct{ field, ip, prefix string }{
+			{"tunnel_ip", reservation.TunnelIP, org.IpCidr},
+			{"tunnel_ip_v6", reservation.TunnelIpV6, org.IpCidrV6},
+		} {
+			if address.ip == "" {
+				continue
+			}
+			// the address of a device with the identity is reserved as it is, the device already holds it.
+			device, err := deviceWithAddress(tx, org, address.ip)
+			if err != nil {
+				return err
+			}
+			if device != nil {
+				matches := (reservation.Hostname != "" && reservation.Hostname == device.Hostname) ||
+					(reservation.PublicKey != "" && reservation.PublicKey == device.PublicKey) ||
+					(regKey.DeviceID != uuid.Nil && regKey.DeviceID == device.ID)
+				if device.OrganizationID != org.ID || !matches {
+					return errDuplicateIpReservation{ID: device.ID.String()}
+				}
+				continue
+			}
+			if err := api.ipam.AcquireIP(ctx, ipamNamespace, address.prefix, address.ip); err != nil {
+				return errIpAddressUnavailable{Field: address.field}
+			}
+		}
+
+		if res := tx.Create(&reservation); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionCreate, "ip_reservation", reservation.ID.String(), nil, reservation)
+	})
+	if err != nil {
+		api.sendIpReservationError(c, err)
+		return
+	}
+	span.SetAttributes(attribute.String("id", reservation.ID.String()))
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// ListIpReservations lists the ip reservations of an organization
+// @Summary      List IP reservations
+// @Description  Lists the tunnel addresses reserved for device identities in an organization
+// @Id           ListIpReservations
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        q               query  string  false  "conditions on the fields, for example tunnel_ip^=100.100.0.,hostname^=lab-"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
+// @Success      200  {object}  []models.IpReservation
+// @Failure		 400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/ip_reservations [get]
+func (api *API) ListIpReservations(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListIpReservations", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	reservations := make([]models.IpReservation, 0)
+	result := api.db.WithContext(ctx).
+		Where("organization_id = ?", org.ID).
+		Scopes(FilterAndPaginate(&models.IpReservation{}, c, "created_at")).
+		Find(&reservations)
+	if result.Error != nil {
+		sendListError(c, result.Error)
+		return
+	}
+	c.JSON(http.StatusOK, reservations)
+}
+
+// DeleteIpReservation deletes an ip reservation
+// @Summary      Delete an IP reservation
+// @Description  Deletes a reservation, the addresses are released unless a device uses them
+// @Id           DeleteIpReservation
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        id               path   string  true  "IP Reservation ID"
+// @Success      200  {object}  models.IpReservation
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/ip_reservations/{id} [delete]
+func (api *API) DeleteIpReservation(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteIpReservation", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var reservation models.IpReservation
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleOwner)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			return errOrgNotFound
+		}
+		if res := tx.Where("organization_id = ?", org.ID).
+			First(&reservation, "id = ?", id); res.Error != nil {
+			return errIpReservationNotFound
+		}
+		if res := tx.Delete(&reservation); res.Error != nil {
+			return res.Error
+		}
+		if err := api.releaseReservedAddresses(ipam.WithTransaction(ctx, tx), tx, org, reservation); err != nil {
+			return err
+		}
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionDelete, "ip_reservation", reservation.ID.String(), reservation, nil)
+	})
+	if err != nil {
+		api.sendIpReservationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, reservation)
+}
+
+func (api *API) sendIpReservationError(c *gin.Context, err error) {
+	var duplicate errDuplicateIpReservation
+	var unavailable errIpAddressUnavailable
+	if errors.As(err, &duplicate) {
+		c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+	} else if errors.As(err, &unavailable) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError(unavailable.Field, unavailable.Error()))
+	} else if errors.Is(err, errOrgNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+	} else if errors.Is(err, errRegKeyNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("registration key"))
+	} else if errors.Is(err, errIpReservationNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("ip reservation"))
+	} else {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+	}
+}
+
+// reservedDeviceAddresses returns the reserved addresses of a device joining the organization with the
+// public key, hostname and registration key. The reservation of the public key is preferred over the
+// one of the registration key, which is preferred over the one of the hostname. An address that another
+// device already uses, such as a second device with the same hostname, is not returned.
+func reservedDeviceAddresses(tx *gorm.DB, org models.Organization, publicKey string, hostname string, regKeyId uuid.UUID) (string, string, error) {
+	var reservations []models.IpReservation
+	query := tx.Where("organization_id = ?", org.ID).
+		Where(tx.Where("public_key <> '' AND public_key = ?", publicKey).
+			Or("hostname <> '' AND hostname = ?", hostname).
+			Or("registration_key_id <> ? AND registration_key_id = ?", uuid.Nil, regKeyId))
+	if res := query.Find(&reservations); res.Error != nil {
+		return "", "", res.Error
+	}
+	var reservation *models.IpReservation
+	for i, r := range reservations {
+		if reservation == nil || identityPrecedence(r) < identityPrecedence(*reservation) {
+			reservation = &reservations[i]
+		}
+	}
+	if reservation == nil {
+		return "", "", nil
+	}
+
+	var addresses [2]string
+	for i, address := range []string{reservation.TunnelIP, reservation.TunnelIpV6} {
+		if address == "" {
+			continue
+		}
+		device, err := deviceWithAddress(tx, org, address)
+		if err != nil {
+			return "", "", err
+		}
+		if device == nil {
+			addresses[i] = address
+		}
+	}
+	return addresses[0], addresses[1], nil
+}
+
+func identityPrecedence(r models.IpReservation) int {
+	switch {
+	case r.PublicKey != "":
+		return 0
+	case r.RegistrationKeyID != uuid.Nil:
+		return 1
+	}
+	return 2
+}
+
+// deviceWithAddress returns the device of the IPAM namespace of the organization that uses the address,
+// or nil if no device uses it.
+func deviceWithAddress(tx *gorm.DB, org models.Organization, address string) (*models.Device, error) {
+	query := tx.Where("tunnel_ip = ? OR tunnel_ip_v6 = ?", address, address)
+	if org.PrivateCidr {
+		query = query.Where("organization_id = ?", org.ID)
+	} else {
+		query = query.Where("organization_id IN (?)", tx.Model(&models.Organization{}).Select("id").Where("private_cidr = ?", false))
+	}
+	var devices []models.Device
+	if res := query.Limit(1).Find(&devices); res.Error != nil {
+		return nil, res.Error
+	}
+	if len(devices) == 0 {
+		return nil, nil
+	}
+	return &devices[0], nil
+}
+
+// isReservedAddress returns true if the address is reserved in the organization, the address then stays
+// allocated when the device that uses it is deleted or moved.
+func isReservedAddress(tx *gorm.DB, orgId uuid.UUID, address string) (bool, error) {
+	if address == "" {
+		return false, nil
+	}
+	var count int64
+	if res := tx.Model(&models.IpReservation{}).
+		Where("organization_id = ? AND (tunnel_ip = ? OR tunnel_ip_v6 = ?)", orgId, address, address).
+		Count(&count); res.Error != nil {
+		return false, res.Error
+	}
+	return count > 0, nil
+}
+
+// releaseReservedAddresses releases the addresses of a deleted reservation that no device uses, it should
+// be called with the context of the transaction that deletes the reservation, see ipam.WithTransaction.
+func (api *API) releaseReservedAddresses(ctx context.Context, tx *gorm.DB, org models.Organization, reservation models.IpReservation) error {
+	ipamNamespace := defaultIPAMNamespace
+	if org.PrivateCidr {
+		ipamNamespace = org.ID
+	}
+	for _, address := range []struct{ ip, prefix string }{
+		{reservation.TunnelIP, org.IpCidr},
+		{reservation.TunnelIpV6, org.IpCidrV6},
+	} {
+		if address.ip == "" {
+			continue
+		}
+		device, err := deviceWithAddress(tx, org, address.ip)
+		if err != nil {
+			return err
+		}
+		if device != nil {
+			continue
+		}
+		if err := api.ipam.ReleaseToPool(ctx, ipamNamespace, address.ip, address.prefix); err != nil {
+			return fmt.Errorf("failed to release the reserved address %s to pool: %w", address.ip, err)
+		}
+	}
+	return nil
+}
+
+// reservableAddress validates an address to reserve in the CIDR of an organization, and returns it
+// in its canonical form.
+func reservableAddress(address string, cidr string, ipv6 bool) (string, error) {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return "", errors.New("must be a valid address")
+	}
+	if addr.Is6() != ipv6 {
+		if ipv6 {
+			return "", errors.New("must be an IPv6 address")
+		}
+		return "", errors.New("must be an IPv4 address")
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil || !prefix.Masked().Contains(addr) {
+		return "", fmt.Errorf("must be an address of the organization cidr %s", cidr)
+	}
+	prefix = prefix.Masked()
+	if addr == prefix.Addr() || (addr.Is4() && prefix.Bits() < 31 && !prefix.Contains(addr.Next())) {
+		return "", errors.New("must not be the network or broadcast address")
+	}
+	return addr.String(), nil
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestIpReservations() {
+	require := suite.Require()
+	assert := suite.Assert()
+	ctx := context.Background()
+	suite.api.db.Exec("DELETE FROM ip_reservations")
+	suite.api.db.Exec("DELETE FROM reg_keys")
+
+	reserve := func(request models.AddIpReservation) (int, models.IpReservation) {
+		reqBody, err := json.Marshal(request)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/organizations/:organization/ip_reservations",
+			fmt.Sprintf("/organizations/%s/ip_reservations", suite.testOrganizationID),
+			suite.api.CreateIpReservation, bytes.NewBuffer(reqBody),
+		)
+		require.NoError(err)
+		var reservation models.IpReservation
+		if res.Code == http.StatusCreated {
+			require.NoError(json.Unmarshal(res.Body.Bytes(), &reservation))
+		}
+		return res.Code, reservation
+	}
+	createDevice := func(publicKey string, hostname string) models.Device {
+		return suite.createDevice(models.AddDevice{
+			OrganizationID: suite.testOrganizationID,
+			PublicKey:      publicKey,
+			Hostname:       hostname,
+		})
+	}
+	deleteDevice := func(id uuid.UUID) {
+		_, res, err := suite.ServeRequest(http.MethodDelete, "/:id", fmt.Sprintf("/%s", id), suite.api.DeleteDevice, nil)
+		require.NoError(err)
+		require.Equal(http.StatusOK, res.Code, res.Body.String())
+	}
+	allocated := func(ip string) bool {
+		if err := suite.api.ipam.AcquireIP(ctx, defaultIPAMNamespace, defaultIPAMv4Cidr, ip); err != nil {
+			return true
+		}
+		require.NoError(suite.api.ipam.ReleaseToPool(ctx, defaultIPAMNamespace, ip, defaultIPAMv4Cidr))
+		return false
+	}
+
+	for _, request := range []models.AddIpReservation{
+		{TunnelIP: "100.100.200.10"},
+		{Hostname: "firewalled", PublicKey: "reserved-key", TunnelIP: "100.100.200.10"},
+		{Hostname: "firewalled"},
+		{Hostname: "firewalled", TunnelIP: "10.0.0.1"},
+		{Hostname: "firewalled", TunnelIP: "100.64.0.0"},
+		{Hostname: "firewalled", TunnelIP: "200::10"},
+		{Hostname: "firewalled", TunnelIpV6: "100.100.200.10"},
+	} {
+		code, _ := reserve(request)
+		assert.Equal(http.StatusBadRequest, code, request)
+	}
+	code, _ := reserve(models.AddIpReservation{RegistrationKeyID: uuid.New(), TunnelIP: "100.100.200.10"})
+	assert.Equal(http.StatusNotFound, code)
+
+	// the device with the hostname is given the reserved address, even after it is recreated.
+	code, byHostname := reserve(models.AddIpReservation{Hostname: "firewalled", TunnelIP: "100.100.200.10", Description: "datacenter firewall"})
+	require.Equal(http.StatusCreated, code)
+	assert.True(allocated("100.100.200.10"), "the reservation allocates the address")
+	code, _ = reserve(models.AddIpReservation{Hostname: "firewalled", TunnelIP: "100.100.200.11"})
+	assert.Equal(http.StatusConflict, code, "an identity has one reservation")
+	code, _ = reserve(models.AddIpReservation{Hostname: "other", TunnelIP: "100.100.200.10"})
+	assert.Equal(http.StatusConflict, code, "an address has one reservation")
+
+	device := createDevice("reserved-key-1", "firewalled")
+	assert.Equal("100.100.200.10", device.TunnelIP)
+	second := createDevice("reserved-key-2", "firewalled")
+	assert.NotEqual("100.100.200.10", second.TunnelIP, "the address is only given to one device")
+	deleteDevice(device.ID)
+	assert.True(allocated("100.100.200.10"), "the address stays reserved")
+	deleteDevice(second.ID)
+	device = createDevice("reserved-key-3", "firewalled")
+	assert.Equal("100.100.200.10", device.TunnelIP)
+
+	// the address of a device can only be reserved for the identity of the device.
+	code, _ = reserve(models.AddIpReservation{PublicKey: "other", TunnelIpV6: device.TunnelIpV6})
+	assert.Equal(http.StatusConflict, code)
+	code, byPublicKey := reserve(models.AddIpReservation{PublicKey: "reserved-key-3", TunnelIpV6: device.TunnelIpV6})
+	require.Equal(http.StatusCreated, code)
+	deleteDevice(device.ID)
+	device = createDevice("reserved-key-3", "renamed")
+	assert.Equal(byPublicKey.TunnelIpV6, device.TunnelIpV6)
+	assert.NotEqual("100.100.200.10", device.TunnelIP, "the hostname changed")
+
+	// the devices that join with a registration key are given its reservation.
+	reqBody, err := json.Marshal(models.AddRegKey{Description: "reserved"})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(
+		http.MethodPost, "/:organization/reg_keys", fmt.Sprintf("/%s/reg_keys", suite.testOrganizationID),
+		suite.api.CreateRegKey, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, res.Body.String())
+	var regKey models.RegKey
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &regKey))
+	code, _ = reserve(models.AddIpReservation{RegistrationKeyID: regKey.ID, TunnelIP: "100.100.200.20"})
+	require.Equal(http.StatusCreated, code)
+	reqBody, err = json.Marshal(models.AddDevice{PublicKey: "reserved-key-4"})
+	require.NoError(err)
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodPost, "/api/devices", "/api/devices", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+	require.Equal(http.StatusCreated, res.Code, res.Body.String())
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+	assert.Equal("100.100.200.20", device.TunnelIP)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet, "/organizations/:organization/ip_reservations",
+		fmt.Sprintf("/organizations/%s/ip_reservations", suite.testOrganizationID),
+		suite.api.ListIpReservations, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, res.Body.String())
+	var reservations []models.IpReservation
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &reservations))
+	assert.Len(reservations, 3)
+
+	// deleting a reservation releases its address unless a device uses it.
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete, "/organizations/:organization/ip_reservations/:id",
+		fmt.Sprintf("/organizations/%s/ip_reservations/%s", suite.testOrganizationID, byHostname.ID),
+		suite.api.DeleteIpReservation, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, res.Body.String())
+	assert.False(allocated("100.100.200.10"))
+
+	var count int64
+	require.NoError(suite.api.db.Model(&models.IpReservation{}).Where("id = ?", byHostname.ID).Count(&count).Error)
+	assert.Zero(count)
+}
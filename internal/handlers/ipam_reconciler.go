@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ipamDriftGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "apiserver_ipam_drift",
+		Help: "The IPAM allocations that don't match the devices and organizations found by the last reconciliation, by kind.",
+	}, []string{"kind"})
+	ipamRepairsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "apiserver_ipam_repairs_total",
+		Help: "The IPAM allocations released or allocated by the reconciliation, by kind.",
+	}, []string{"kind"})
+)
+
+// ipamDriftState holds the drift found by the IPAM reconciliation.
+type ipamDriftState struct {
+	mu sync.Mutex
+	// found is the drift found by the last check, which the next check confirms.
+	found  []models.IpamDrift
+	report models.IpamDriftReport
+}
+
+// StartIPAMReconciler starts the background worker that compares the addresses and prefixes of the
+// devices and organizations with the IPAM allocations every interval. When repair is true, the leaked
+// allocations are released and the missing ones are allocated.
+func (api *API) StartIPAMReconciler(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, repair bool) {
+	if interval <= 0 {
+		return
+	}
+	_, lists := api.ipam.(ipam.AllocationLister)
+	_, counts := api.ipam.(ipam.AddressCounter)
+	if !lists && !counts {
+		api.logger.Warn("the ipam backend can't list its allocations, the ipam reconciliation is disabled")
+		return
+	}
+	util.GoWithWaitGroup(wg, func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			api.reconcileIPAM(ctx, repair)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// reconcileIPAM checks the IPAM for drift and records the drift that the previous check also found,
+// so that the allocations of the devices that were being created or deleted during a check are not
+// reported.
+func (api *API) reconcileIPAM(ctx context.Context, repair bool) {
+	found, err := api.checkIPAMDrift(ctx)
+	if err != nil {
+		api.logger.Errorf("failed to check the ipam for drift: %v", err)
+		return
+	}
+
+	api.ipamDrift.mu.Lock()
+	previous := make(map[ipamDriftKey]bool, len(api.ipamDrift.found))
+	for _, d := range api.ipamDrift.found {
+		previous[driftKey(d)] = true
+	}
+	api.ipamDrift.found = found
+	api.ipamDrift.mu.Unlock()
+
+	drift := make([]models.IpamDrift, 0)
+	counts := map[string]int{
+		models.IpamDriftLeaked:    0,
+		models.IpamDriftMissing:   0,
+		models.IpamDriftConflict:  0,
+		models.IpamDriftAddresses: 0,
+	}
+	for _, d := range found {
+		if previous[driftKey(d)] {
+			drift = append(drift, d)
+			counts[d.Kind]++
+		}
+	}
+	for kind, count := range counts {
+		ipamDriftGauge.WithLabelValues(kind).Set(float64(count))
+	}
+	if len(drift) > 0 {
+		api.logger.Warnf("found %d leaked, %d missing and %d conflicting ipam allocations, and %d prefixes with a wrong number of addresses",
+			counts[models.IpamDriftLeaked], counts[models.IpamDriftMissing], counts[models.IpamDriftConflict], counts[models.IpamDriftAddresses])
+	}
+	if repair {
+		api.repairIPAMDrift(ctx, drift)
+	}
+
+	checkedAt := time.Now()
+	api.ipamDrift.mu.Lock()
+	api.ipamDrift.report = models.IpamDriftReport{CheckedAt: &checkedAt, Drift: drift}
+	api.ipamDrift.mu.Unlock()
+}
+
+type ipamDriftKey struct {
+	kind       string
+	allocation ipam.Allocation
+}
+
+func driftKey(d models.IpamDrift) ipamDriftKey {
+	return ipamDriftKey{
+		kind:       d.Kind,
+		allocation: ipam.Allocation{Namespace: d.Namespace, Prefix: d.Prefix, Address: d.Address},
+	}
+}
+
+// ipamUse is what uses an allocation.
+type ipamUse struct {
+	organizationID uuid.UUID
+	deviceIDs      []uuid.UUID
+}
+
+// checkIPAMDrift compares the allocations that the organizations and devices use with the IPAM allocations.
+func (api *API) checkIPAMDrift(parent context.Context) ([]models.IpamDrift, error) {
+	ctx, span := tracer.Start(parent, "checkIPAMDrift")
+	defer span.End()
+
+	var orgs []models.Organization
+	if res := api.db.WithContext(ctx).Select("id, private_cidr, ip_cidr, ip_cidr_v6").Find(&orgs); res.Error != nil {
+		return nil, res.Error
+	}
+	var devices []models.Device
+	if res := api.db.WithContext(ctx).Select("id, organization_id, tunnel_ip, tunnel_ip_v6, organization_prefix, organization_prefix_v6, child_prefix").Find(&devices); res.Error != nil {
+		return nil, res.Error
+	}
+	var reservations []models.IpReservation
+	if res := api.db.WithContext(ctx).Select("organization_id, tunnel_ip, tunnel_ip_v6").Find(&reservations); res.Error != nil {
+		return nil, res.Error
+	}
+
+	used := map[ipam.Allocation]*ipamUse{}
+	use := func(a ipam.Allocation, orgId uuid.UUID) *ipamUse {
+		u := used[a]
+		if u == nil {
+			u = &ipamUse{}
+			// the prefixes of the shared namespace are used by several organizations.
+			if a.Namespace != defaultIPAMNamespace || a.Address != "" {
+				u.organizationID = orgId
+			}
+			used[a] = u
+		}
+		return u
+	}
+
+	type orgPrefixes struct {
+		namespace uuid.UUID
+		v4, v6    string
+	}
+	orgsById := map[uuid.UUID]orgPrefixes{}
+	for _, org := range orgs {
+		namespace := defaultIPAMNamespace
+		if org.PrivateCidr {
+			namespace = org.ID
+		}
+		prefixes := orgPrefixes{namespace: namespace, v4: cleanPrefix(org.IpCidr), v6: cleanPrefix(org.IpCidrV6)}
+		for _, prefix := range []string{prefixes.v4, prefixes.v6} {
+			if prefix != "" {
+				use(ipam.Allocation{Namespace: namespace, Prefix: prefix}, org.ID)
+			}
+		}
+		orgsById[org.ID] = prefixes
+	}
+
+	for _, device := range devices {
+		org, ok := orgsById[device.OrganizationID]
+		if !ok {
+			continue
+		}
+		for _, address := range []struct{ ip, prefix, orgPrefix string }{
+			{device.TunnelIP, device.OrganizationPrefix, org.v4},
+			{device.TunnelIpV6, device.OrganizationPrefixV6, org.v6},
+		} {
+			// the devices of an organization being renumbered still use the prefix of their address.
+			if address.prefix = cleanPrefix(address.prefix); address.prefix == "" {
+				address.prefix = address.orgPrefix
+			} else if address.prefix != address.orgPrefix {
+				use(ipam.Allocation{Namespace: org.namespace, Prefix: address.prefix}, device.OrganizationID)
+			}
+			addr, err := netip.ParseAddr(address.ip)
+			if err != nil || address.prefix == "" {
+				continue
+			}
+			u := use(ipam.Allocation{Namespace: org.namespace, Prefix: address.prefix, Address: addr.String()}, device.OrganizationID)
+			u.deviceIDs = append(u.deviceIDs, device.ID)
+		}
+		for _, prefix := range device.ChildPrefix {
+			if util.IsDefaultIPv4Route(prefix) || util.IsDefaultIPv6Route(prefix) {
+				continue
+			}
+			if prefix = cleanPrefix(prefix); prefix == "" {
+				continue
+			}
+			u := use(ipam.Allocation{Namespace: org.namespace, Prefix: prefix}, device.OrganizationID)
+			u.deviceIDs = append(u.deviceIDs, device.ID)
+		}
+	}
+
+	// the reserved addresses stay allocated while no device uses them.
+	for _, reservation := range reservations {
+		org, ok := orgsById[reservation.OrganizationID]
+		if !ok {
+			continue
+		}
+		for _, address := range []struct{ ip, prefix string }{
+			{reservation.TunnelIP, org.v4},
+			{reservation.TunnelIpV6, org.v6},
+		} {
+			if addr, err := netip.ParseAddr(address.ip); err == nil && address.prefix != "" {
+				use(ipam.Allocation{Namespace: org.namespace, Prefix: address.prefix, Address: addr.String()}, reservation.OrganizationID)
+			}
+		}
+	}
+
+	var drift []models.IpamDrift
+	var err error
+	switch backend := api.ipam.(type) {
+	case ipam.AllocationLister:
+		drift, err = listIPAMDrift(ctx, backend, used)
+	case ipam.AddressCounter:
+		drift, err = countIPAMDrift(ctx, backend, used)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for a, u := range used {
+		if a.Address != "" && len(u.deviceIDs) > 1 {
+			drift = append(drift, models.IpamDrift{
+				Kind: models.IpamDriftConflict, Namespace: a.Namespace, Prefix: a.Prefix, Address: a.Address,
+				OrganizationID: u.organizationID, DeviceIDs: u.deviceIDs,
+			})
+		}
+	}
+	sort.Slice(drift, func(i, j int) bool {
+		a, b := drift[i], drift[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace.String() < b.Namespace.String()
+		}
+		if a.Prefix != b.Prefix {
+			return a.Prefix < b.Prefix
+		}
+		return a.Address < b.Address
+	})
+	return drift, nil
+}
+
+// listIPAMDrift finds the leaked and missing allocations by listing the IPAM allocations.
+func listIPAMDrift(ctx context.Context, lister ipam.AllocationLister, used map[ipam.Allocation]*ipamUse) ([]models.IpamDrift, error) {
+	allocations, err := lister.ListAllocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	drift := make([]models.IpamDrift, 0)
+	allocated := make(map[ipam.Allocation]bool, len(allocations))
+	for _, a := range allocations {
+		allocated[a] = true
+		if used[a] != nil {
+			continue
+		}
+		d := models.IpamDrift{Kind: models.IpamDriftLeaked, Namespace: a.Namespace, Prefix: a.Prefix, Address: a.Address}
+		if a.Namespace != defaultIPAMNamespace {
+			d.OrganizationID = a.Namespace
+		}
+		drift = append(drift, d)
+	}
+	for a, u := range used {
+		if !allocated[a] {
+			drift = append(drift, models.IpamDrift{
+				Kind: models.IpamDriftMissing, Namespace: a.Namespace, Prefix: a.Prefix, Address: a.Address,
+				OrganizationID: u.organizationID, DeviceIDs: u.deviceIDs,
+			})
+		}
+	}
+	return drift, nil
+}
+
+// countIPAMDrift finds the missing prefixes, and the prefixes that don't have as many addresses allocated
+// as are used, with an IPAM that can't list its allocations. The leaked prefixes are not found.
+func countIPAMDrift(ctx context.Context, counter ipam.AddressCounter, used map[ipam.Allocation]*ipamUse) ([]models.IpamDrift, error) {
+	addresses := map[ipam.Allocation]int{}
+	for a := range used {
+		if a.Address != "" {
+			addresses[ipam.Allocation{Namespace: a.Namespace, Prefix: a.Prefix}]++
+		}
+	}
+	drift := make([]models.IpamDrift, 0)
+	for a, u := range used {
+		if a.Address != "" {
+			continue
+		}
+		count, found, err := counter.CountAddresses(ctx, a.Namespace, a.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		d := models.IpamDrift{Namespace: a.Namespace, Prefix: a.Prefix, OrganizationID: u.organizationID, DeviceIDs: u.deviceIDs}
+		if !found {
+			d.Kind = models.IpamDriftMissing
+			drift = append(drift, d)
+		} else if count != addresses[a] {
+			d.Kind = models.IpamDriftAddresses
+			d.AllocatedAddresses = count
+			d.UsedAddresses = addresses[a]
+			drift = append(drift, d)
+		}
+	}
+	return drift, nil
+}
+
+// repairIPAMDrift allocates the missing prefixes and addresses, then releases the leaked ones. The
+// conflicts are left for an administrator to resolve, since one of the devices has to be given
+// another address.
+func (api *API) repairIPAMDrift(ctx context.Context, drift []models.IpamDrift) {
+	repair := func(kind string, prefixes bool, fn func(d models.IpamDrift) error) {
+		for i, d := range drift {
+			if d.Kind != kind || (d.Address == "") != prefixes {
+				continue
+			}
+			if err := fn(d); err != nil {
+				api.logger.Errorf("failed to repair the %s ipam allocation %s %s %s: %v", kind, d.Namespace, d.Prefix, d.Address, err)
+				continue
+			}
+			drift[i].Repaired = true
+			ipamRepairsCounter.WithLabelValues(kind).Inc()
+		}
+	}
+	repair(models.IpamDriftMissing, true, func(d models.IpamDrift) error {
+		return api.ipam.AssignPrefix(ctx, d.Namespace, d.Prefix)
+	})
+	repair(models.IpamDriftMissing, false, func(d models.IpamDrift) error {
+		return api.ipam.AcquireIP(ctx, d.Namespace, d.Prefix, d.Address)
+	})
+	repair(models.IpamDriftLeaked, false, func(d models.IpamDrift) error {
+		return api.ipam.ReleaseToPool(ctx, d.Namespace, d.Address, d.Prefix)
+	})
+	repair(models.IpamDriftLeaked, true, func(d models.IpamDrift) error {
+		return api.ipam.ReleasePrefix(ctx, d.Namespace, d.Prefix)
+	})
+}
+
+// cleanPrefix returns the prefix in its canonical form, or an empty string when it's not valid.
+func cleanPrefix(cidr string) string {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return ""
+	}
+	return prefix.Masked().String()
+}
+
+// GetIpamDrift gets the IPAM drift of an organization
+// @Summary      Get IPAM drift
+// @Description  Gets the IPAM allocations of an organization that don't match its devices, found by the last IPAM reconciliation
+// @Id           GetIpamDrift
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Success      200  {object}  models.IpamDriftReport
+// @Failure		 400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Router       /api/organizations/{organization_id}/ipam_drift [get]
+func (api *API) GetIpamDrift(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetIpamDrift", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	api.ipamDrift.mu.Lock()
+	report := api.ipamDrift.report
+	api.ipamDrift.mu.Unlock()
+
+	result := models.IpamDriftReport{CheckedAt: report.CheckedAt, Drift: make([]models.IpamDrift, 0)}
+	for _, d := range report.Drift {
+		if d.OrganizationID == org.ID {
+			result.Drift = append(result.Drift, d)
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ListIpamDrift lists the IPAM drift of all the organizations
+// @Summary      List IPAM drift
+// @Description  Lists the IPAM allocations that don't match the devices and organizations found by the last IPAM reconciliation, including the drift of the namespace shared by the organizations without a private CIDR. Only the admins of the apiserver can list it.
+// @Id           ListIpamDrift
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.IpamDriftReport
+// @Failure		 401  {object}  models.BaseError
+// @Failure		 403  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Router       /api/ipam_drift [get]
+func (api *API) ListIpamDrift(c *gin.Context) {
+	_, span := tracer.Start(c.Request.Context(), "ListIpamDrift")
+	defer span.End()
+
+	if !api.isAdmin(c) {
+		c.JSON(http.StatusForbidden, models.NewNotAllowedError("only the admins of the apiserver can list the ipam drift"))
+		return
+	}
+
+	api.ipamDrift.mu.Lock()
+	report := api.ipamDrift.report
+	api.ipamDrift.mu.Unlock()
+
+	if report.Drift == nil {
+		report.Drift = make([]models.IpamDrift, 0)
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestIPAMReconciler() {
+	require := suite.Require()
+	assert := suite.Assert()
+	ctx := context.Background()
+	suite.api.db.Exec("DELETE FROM ipam_prefixes")
+	suite.api.db.Exec("DELETE FROM ipam_addresses")
+
+	databaseIPAM := ipam.NewDatabaseIPAM(suite.logger, suite.api.db)
+	previousIPAM := suite.api.ipam
+	suite.api.ipam = databaseIPAM
+	defer func() {
+		suite.api.ipam = previousIPAM
+		suite.api.ipamDrift = ipamDriftState{}
+	}()
+	require.NoError(databaseIPAM.AssignPrefix(ctx, defaultIPAMNamespace, defaultIPAMv4Cidr))
+	require.NoError(databaseIPAM.AssignPrefix(ctx, defaultIPAMNamespace, defaultIPAMv6Cidr))
+
+	getDrift := func() models.IpamDriftReport {
+		_, res, err := suite.ServeRequest(
+			http.MethodGet, "/organizations/:organization/ipam_drift",
+			fmt.Sprintf("/organizations/%s/ipam_drift", suite.testOrganizationID),
+			suite.api.GetIpamDrift, nil,
+		)
+		require.NoError(err)
+		require.Equal(http.StatusOK, res.Code, res.Body.String())
+		var report models.IpamDriftReport
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &report))
+		return report
+	}
+
+	device1 := suite.createDevice(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "ipam-device1",
+		Hostname:       "device1",
+	})
+	device2 := suite.createDevice(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "ipam-device2",
+		Hostname:       "device2",
+	})
+	drift, err := suite.api.checkIPAMDrift(ctx)
+	require.NoError(err)
+	assert.Empty(drift)
+	assert.Nil(getDrift().CheckedAt)
+
+	// leak an address, lose the address of device1, and give device2 the v6 address of device1.
+	leaked, err := databaseIPAM.AssignFromPool(ctx, defaultIPAMNamespace, defaultIPAMv4Cidr)
+	require.NoError(err)
+	require.NoError(databaseIPAM.ReleaseToPool(ctx, defaultIPAMNamespace, device1.TunnelIP, defaultIPAMv4Cidr))
+	require.NoError(suite.api.db.Model(&models.Device{}).Where("id = ?", device2.ID).Update("tunnel_ip_v6", device1.TunnelIpV6).Error)
+
+	// the drift is only reported once a second check confirms it.
+	suite.api.reconcileIPAM(ctx, false)
+	report := getDrift()
+	require.NotNil(report.CheckedAt)
+	assert.Empty(report.Drift)
+
+	suite.api.reconcileIPAM(ctx, false)
+	report = getDrift()
+	require.Len(report.Drift, 2, report.Drift)
+	assert.Equal(models.IpamDriftConflict, report.Drift[0].Kind)
+	assert.Equal(device1.TunnelIpV6, report.Drift[0].Address)
+	assert.ElementsMatch([]uuid.UUID{device1.ID, device2.ID}, report.Drift[0].DeviceIDs)
+	assert.Equal(models.IpamDriftMissing, report.Drift[1].Kind)
+	assert.Equal(device1.TunnelIP, report.Drift[1].Address)
+	assert.Equal([]uuid.UUID{device1.ID}, report.Drift[1].DeviceIDs)
+	assert.False(report.Drift[1].Repaired)
+
+	// the leaks of the shared namespace can't be attributed to an organization, only the admins see them.
+	_, res, err := suite.ServeRequest(http.MethodGet, "/ipam_drift", "/ipam_drift", suite.api.ListIpamDrift, nil)
+	require.NoError(err)
+	assert.Equal(http.StatusForbidden, res.Code)
+	suite.api.SetAdmins([]string{TestUserID})
+	defer suite.api.SetAdmins(nil)
+	_, res, err = suite.ServeRequest(http.MethodGet, "/ipam_drift", "/ipam_drift", suite.api.ListIpamDrift, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, res.Body.String())
+	var all models.IpamDriftReport
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &all))
+	var leaks []string
+	for _, d := range all.Drift {
+		if d.Kind == models.IpamDriftLeaked {
+			assert.Equal(uuid.Nil, d.OrganizationID)
+			leaks = append(leaks, d.Address)
+		}
+	}
+	assert.ElementsMatch([]string{leaked, device2.TunnelIpV6}, leaks)
+
+	// the repair allocates the missing address and releases the leaked ones, but leaves the conflict.
+	suite.api.reconcileIPAM(ctx, true)
+	report = getDrift()
+	require.Len(report.Drift, 2, report.Drift)
+	assert.False(report.Drift[0].Repaired)
+	assert.True(report.Drift[1].Repaired)
+
+	drift, err = suite.api.checkIPAMDrift(ctx)
+	require.NoError(err)
+	require.Len(drift, 1, drift)
+	assert.Equal(models.IpamDriftConflict, drift[0].Kind)
+}
+
+func (suite *HandlerTestSuite) TestIPAMReconcilerCountsAddresses() {
+	require := suite.Require()
+	assert := suite.Assert()
+	ctx := context.Background()
+	if suite.ipamBackend != ipam.BackendRemote {
+		suite.T().Skip("only the remote ipam counts the addresses of its prefixes")
+	}
+	defer func() {
+		suite.api.ipamDrift = ipamDriftState{}
+	}()
+
+	// the remote ipam can't list its addresses, the drift is found by counting them.
+	_, ok := suite.api.ipam.(ipam.AddressCounter)
+	require.True(ok)
+	extraAddresses := func() int {
+		drift, err := suite.api.checkIPAMDrift(ctx)
+		require.NoError(err)
+		for _, d := range drift {
+			if d.Kind == models.IpamDriftAddresses && d.Prefix == defaultIPAMv4Cidr {
+				assert.Equal(uuid.Nil, d.OrganizationID)
+				return d.AllocatedAddresses - d.UsedAddresses
+			}
+		}
+		return 0
+	}
+	before := extraAddresses()
+	leaked, err := suite.api.ipam.AssignFromPool(ctx, defaultIPAMNamespace, defaultIPAMv4Cidr)
+	require.NoError(err)
+	assert.Equal(before+1, extraAddresses())
+	require.NoError(suite.api.ipam.ReleaseToPool(ctx, defaultIPAMNamespace, leaked, defaultIPAMv4Cidr))
+	assert.Equal(before, extraAddresses())
+
+	// a missing prefix is found by looking it up.
+	var org models.Organization
+	require.NoError(suite.api.db.First(&org, "id = ?", suite.testOrganizationID).Error)
+	defer suite.api.db.Model(&models.Organization{}).Where("id = ?", org.ID).Update("ip_cidr_v6", org.IpCidrV6)
+	require.NoError(suite.api.db.Model(&models.Organization{}).Where("id = ?", org.ID).Update("ip_cidr_v6", "fd00:99::/64").Error)
+	drift, err := suite.api.checkIPAMDrift(ctx)
+	require.NoError(err)
+	var missing []string
+	for _, d := range drift {
+		if d.Kind == models.IpamDriftMissing {
+			missing = append(missing, d.Prefix)
+		}
+	}
+	assert.Equal([]string{"fd00:99::/64"}, missing)
+}
@@ -1,14 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/database"
+	"github.com/nexodus-io/nexodus/internal/ipam"
 	"github.com/nexodus-io/nexodus/internal/models"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -23,6 +26,52 @@ const (
 	defaultIPAMv6Cidr = "200::/64"
 )
 
+// organizationsSignal is notified when the organizations of a user, or their memberships, change.
+func organizationsSignal(userId string) string {
+	return fmt.Sprintf("/organizations/user=%s", userId)
+}
+
+// notifyOrganizationsChanged notifies the watchers of the organizations of each of their members.
+func (api *API) notifyOrganizationsChanged(ctx context.Context, orgIds ...uuid.UUID) {
+	userIds, err := organizationUserIDs(api.db.WithContext(ctx), models.RoleMember, orgIds...)
+	if err != nil {
+		api.logger.Warnf("failed to find the members of the changed organizations: %v", err)
+		return
+	}
+	for _, userId := range userIds {
+		api.signalBus.Notify(organizationsSignal(userId))
+	}
+}
+
+// organizationUserIDs returns the ids of the owners of the organizations and of their members that
+// have at least the role.
+func organizationUserIDs(db *gorm.DB, role string, orgIds ...uuid.UUID) ([]string, error) {
+	if len(orgIds) == 0 {
+		return nil, nil
+	}
+	var members []string
+	if res := db.Model(&UserOrganization{}).
+		Where("organization_id IN ? AND role IN ?", orgIds, models.RolesAtLeast(role)).
+		Pluck("user_id", &members); res.Error != nil {
+		return nil, res.Error
+	}
+	var owners []string
+	if res := db.Model(&models.Organization{}).
+		Where("id IN ?", orgIds).
+		Pluck("owner_id", &owners); res.Error != nil {
+		return nil, res.Error
+	}
+	seen := map[string]bool{}
+	var userIds []string
+	for _, userId := range append(owners, members...) {
+		if userId != "" && !seen[userId] {
+			seen[userId] = true
+			userIds = append(userIds, userId)
+		}
+	}
+	return userIds, nil
+}
+
 type errDuplicateOrganization struct {
 	ID string
 }
@@ -42,9 +91,10 @@ func (e errDuplicateOrganization) Error() string {
 // @Success      201  {object}  models.Organization
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.QuotaExceededError
 // @Failure		 405  {object}  models.BaseError
 // @Failure      409  {object}  models.ConflictsError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/organizations [post]
 func (api *API) CreateOrganization(c *gin.Context) {
@@ -99,11 +149,16 @@ func (api *API) CreateOrganization(c *gin.Context) {
 
 	var org models.Organization
 	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		ctx := ipam.WithTransaction(ctx, tx)
 		var user models.User
 		if res := tx.First(&user, "id = ?", userId); res.Error != nil {
 			return errUserNotFound
 		}
 
+		if err := checkQuota(tx, quotaOrganizationsPerUser, api.quotas.OrganizationsPerUser, &models.User{}, userId, &models.Organization{}, "owner_id = ?", userId); err != nil {
+			return err
+		}
+
 		org = models.Organization{
 			Name:        request.Name,
 			OwnerID:     userId,
@@ -122,6 +177,9 @@ func (api *API) CreateOrganization(c *gin.Context) {
 			api.logger.Error("Failed to create organization: ", res.Error)
 			return res.Error
 		}
+		if err := setOrganizationRole(tx, org.ID, userId, models.RoleOwner); err != nil {
+			return err
+		}
 
 		ipamNamespace := defaultIPAMNamespace
 		if org.PrivateCidr {
@@ -155,6 +213,10 @@ func (api *API) CreateOrganization(c *gin.Context) {
 		}
 		org.SecurityGroupId = sg.ID
 
+		if err := api.recordAuditEvent(c, tx, org.ID, models.AuditActionCreate, "organization", org.ID.String(), nil, org); err != nil {
+			return err
+		}
+
 		span.SetAttributes(attribute.String("id", org.ID.String()))
 		api.logger.Infof("New organization request [ %s ] ipam v4 [ %s ] ipam v6 [ %s ] request", org.Name, org.IpCidr, org.IpCidrV6)
 		return nil
@@ -162,29 +224,61 @@ func (api *API) CreateOrganization(c *gin.Context) {
 
 	if err != nil {
 		var duplicate errDuplicateOrganization
+		var quotaExceeded errQuotaExceeded
 		if errors.Is(err, errUserNotFound) {
 			c.JSON(http.StatusNotFound, models.NewApiInternalError(err))
 		} else if errors.As(err, &duplicate) {
 			c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+		} else if errors.As(err, &quotaExceeded) {
+			c.JSON(http.StatusForbidden, quotaExceeded.Response())
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
 		return
 	}
 
+	api.signalBus.Notify(organizationsSignal(org.OwnerID))
 	c.JSON(http.StatusCreated, org)
 }
 
+// touchOrganization bumps the revision of an organization so that watchers see a change
+// that was not made to the organization row itself, like a new member.
+func touchOrganization(tx *gorm.DB, orgId uuid.UUID) error {
+	return tx.Model(&models.Organization{}).Where("id = ?", orgId).Update("updated_at", time.Now()).Error
+}
+
 func (api *API) OrganizationIsReadableByCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		userId := c.Value(gin.AuthUserKey).(string)
 
 		// this could potentially be driven by rego output
 		if api.dialect == database.DialectSqlLite {
-			return db.Where("owner_id = ? OR id in (SELECT organization_id FROM user_organizations where user_id=?)", userId, userId)
+			db = db.Where("owner_id = ? OR id in (SELECT organization_id FROM user_organizations where user_id=?)", userId, userId)
+		} else {
+			db = db.Where("owner_id = ? OR id::text in (SELECT organization_id::text FROM user_organizations where user_id=?)", userId, userId)
+		}
+		if regKey, ok := RegKeyFromContext(c); ok {
+			db = db.Where("id = ?", regKey.OrganizationID)
+		}
+		return db
+	}
+}
+
+// OrganizationHasCurrentUserRole limits the query to organizations where the current user
+// has at least the given role.
+func (api *API) OrganizationHasCurrentUserRole(c *gin.Context, role string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		userId := c.Value(gin.AuthUserKey).(string)
+		roles := models.RolesAtLeast(role)
+		if api.dialect == database.DialectSqlLite {
+			db = db.Where("owner_id = ? OR id in (SELECT organization_id FROM user_organizations where user_id=? AND role in ?)", userId, userId, roles)
 		} else {
-			return db.Where("owner_id = ? OR id::text in (SELECT organization_id::text FROM user_organizations where user_id=?)", userId, userId)
+			db = db.Where("owner_id = ? OR id::text in (SELECT organization_id::text FROM user_organizations where user_id=? AND role in ?)", userId, userId, roles)
 		}
+		if regKey, ok := RegKeyFromContext(c); ok {
+			db = db.Where("id = ?", regKey.OrganizationID)
+		}
+		return db
 	}
 }
 
@@ -203,30 +297,113 @@ func (api *API) OrganizationIsOwnedByCurrentUser(c *gin.Context) func(db *gorm.D
 // @Tags         Organizations
 // @Accept       json
 // @Produce      json
+// @Param		 gt_revision     query  uint64 false "greater than revision"
+// @Param        q               query  string  false  "conditions on the fields, for example name^=team-,private_cidr=true"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
 // @Success      200  {object}  []models.Organization
+// @Failure		 400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 410  {object}  models.GoneError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure		 500  {object}  models.BaseError
 // @Router       /api/organizations [get]
 func (api *API) ListOrganizations(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "ListOrganizations")
 	defer span.End()
-	var orgs []models.Organization
-	result := api.db.WithContext(ctx).
-		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
-		Scopes(FilterAndPaginate(&models.Organization{}, c, "name")).
-		Find(&orgs)
 
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
-		} else {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(result.Error))
-		}
+	var query Query
+	if err := c.BindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiInternalError(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, orgs)
+	defaultOrderBy := "name"
+	if v := c.Query("watch"); v == "true" {
+		query.Sort = ""
+		defaultOrderBy = "revision"
+	}
+
+	scopes := []func(*gorm.DB) *gorm.DB{
+		api.OrganizationIsReadableByCurrentUser(c),
+		FilterAndPaginateWithQuery(&models.Organization{}, c, query, defaultOrderBy),
+	}
+
+	userId := c.Value(gin.AuthUserKey).(string)
+	getList := func(db *gorm.DB) (WatchableList, error) {
+		var items organizationList
+		result := db.Find(&items)
+		if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, result.Error
+		}
+		return items, nil
+	}
+	if c.Query("watch") == "true" {
+		getList = api.watchedOrganizationList(ctx, api.OrganizationIsReadableByCurrentUser(c), getList)
+	}
+	api.sendListOrWatch(c, ctx, []string{organizationsSignal(userId)}, "organizations", scopes, getList)
+}
+
+// watchedOrganizationList wraps the getList of an organizations watch to send a delete for the
+// organizations that it sent and that the user can't read anymore, since removing a membership
+// doesn't change the organization.
+func (api *API) watchedOrganizationList(ctx context.Context, readable func(*gorm.DB) *gorm.DB, getList func(db *gorm.DB) (WatchableList, error)) func(db *gorm.DB) (WatchableList, error) {
+	watched := map[uuid.UUID]*models.Organization{}
+	var watchedRevision uint64
+	return func(db *gorm.DB) (WatchableList, error) {
+		list, err := getList(db)
+		if err != nil {
+			return nil, err
+		}
+		items := list.(organizationList)
+
+		var readableIds []uuid.UUID
+		if res := api.db.WithContext(ctx).Model(&models.Organization{}).
+			Scopes(readable).
+			Pluck("id", &readableIds); res.Error != nil {
+			return nil, res.Error
+		}
+		keep := map[uuid.UUID]bool{}
+		for _, id := range readableIds {
+			keep[id] = true
+		}
+		for _, item := range items {
+			keep[item.ID] = true
+		}
+		var removed organizationList
+		for id, org := range watched {
+			if !keep[id] {
+				// the delete keeps the revision of the watch, the organization itself did not change.
+				deleted := *org
+				deleted.Revision = watchedRevision
+				deleted.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+				removed = append(removed, &deleted)
+				delete(watched, id)
+			}
+		}
+		for _, item := range items {
+			if item.DeletedAt.Valid {
+				delete(watched, item.ID)
+			} else {
+				watched[item.ID] = item
+			}
+			if item.Revision > watchedRevision {
+				watchedRevision = item.Revision
+			}
+		}
+		return append(removed, items...), nil
+	}
+}
+
+type organizationList []*models.Organization
+
+func (d organizationList) Item(i int) (any, uint64, gorm.DeletedAt) {
+	item := d[i]
+	return item, item.Revision, item.DeletedAt
+}
+
+func (d organizationList) Len() int {
+	return len(d)
 }
 
 // GetOrganizations gets a specific Organization
@@ -240,7 +417,7 @@ func (api *API) ListOrganizations(c *gin.Context) {
 // @Success      200  {object}  models.Organization
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure      404  {object}  models.BaseError
 // @Router       /api/organizations/{id} [get]
 func (api *API) GetOrganizations(c *gin.Context) {
@@ -279,11 +456,16 @@ func (api *API) GetOrganizations(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param		 gt_revision     query  uint64 false "greater than revision"
+// @Param		 selector        query  string false "label selector, for example env=prod,tier!=db"
 // @Param		 organization_id path   string true "Organization ID"
+// @Param        q               query  string  false  "conditions on the fields, for example hostname^=lab-,created_at>=2023-06-01"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
 // @Success      200  {object}  []models.Device
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 410  {object}  models.GoneError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure		 500  {object}  models.BaseError
 // @Router       /api/organizations/{organization_id}/devices [get]
 func (api *API) ListDevicesInOrganization(c *gin.Context) {
@@ -316,9 +498,16 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 		return
 	}
 
+	selector, err := models.ParseLabelSelector(c.Query("selector"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("selector", err.Error()))
+		return
+	}
+
 	signalChannel := fmt.Sprintf("/devices/org=%s", k.String())
 	defaultOrderBy := "hostname"
-	if v := c.Query("watch"); v == "true" {
+	watch := c.Query("watch") == "true"
+	if watch {
 		query.Sort = ""
 		defaultOrderBy = "revision"
 	}
@@ -330,15 +519,26 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 		FilterAndPaginateWithQuery(&models.Device{}, c, query, defaultOrderBy),
 	}
 
-	api.sendListOrWatch(c, ctx, signalChannel, "revision", scopes, func(db *gorm.DB) (WatchableList, error) {
-		var items deviceList
-		result := db.Find(&items)
-		if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, result.Error
-		}
-		return items, nil
-	})
+	getList := getDeviceList
+	if watch {
+		// devices that stop matching the selector are sent as deletes, so they can't be filtered out by the query.
+		gtRevision := c.Query("gt_revision")
+		getList = getSelectedDeviceList(selector, gtRevision != "" && gtRevision != "0")
+	} else {
+		scopes = append(scopes, labelSelectorScope(selector))
+	}
 
+	api.sendListOrWatch(c, ctx, []string{signalChannel}, "devices", scopes, getList)
+
+}
+
+func getDeviceList(db *gorm.DB) (WatchableList, error) {
+	var items deviceList
+	result := db.Find(&items)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, result.Error
+	}
+	return items, nil
 }
 
 type deviceList []*models.Device
@@ -365,7 +565,7 @@ func (d deviceList) Len() int {
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure		 500  {object}  models.BaseError
 // @Router       /api/organizations/{organization_id}/devices/{device_id} [get]
 func (api *API) GetDeviceInOrganization(c *gin.Context) {
@@ -423,10 +623,13 @@ func (api *API) GetDeviceInOrganization(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param		 id   path       string true "Organization ID"
+// @Param        q               query  string  false  "conditions on the fields, for example user_name^=alice,created_at>=2023-06-01"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
 // @Success      200  {object}  []models.User
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure		 500  {object}  models.BaseError
 // @Router       /api/organizations/{id}/devices [get]
 func (api *API) ListUsersInOrganization(c *gin.Context) {
@@ -459,13 +662,15 @@ func (api *API) ListUsersInOrganization(c *gin.Context) {
 		Find(&users)
 
 	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(result.Error))
+		sendListError(c, result.Error)
 		return
 	}
 
-	// For pagination
-	c.Header("Access-Control-Expose-Headers", TotalCountHeader)
-	c.Header(TotalCountHeader, strconv.Itoa(len(users)))
+	// For pagination, FilterAndPaginate sets the total count when a range was requested.
+	if c.Writer.Header().Get(TotalCountHeader) == "" {
+		c.Header("Access-Control-Expose-Headers", exposedListHeaders)
+		c.Header(TotalCountHeader, strconv.Itoa(len(users)))
+	}
 	c.JSON(http.StatusOK, users)
 }
 
@@ -480,7 +685,7 @@ func (api *API) ListUsersInOrganization(c *gin.Context) {
 // @Success      204  {object}  models.Organization
 // @Failure      400  {object}  models.BaseError
 // @Failure      405  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/organizations/{id} [delete]
 func (api *API) DeleteOrganization(c *gin.Context) {
@@ -508,7 +713,7 @@ func (api *API) DeleteOrganization(c *gin.Context) {
 
 	var org models.Organization
 	result := api.db.WithContext(ctx).
-		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
 		First(&org, "id = ?", orgID)
 
 	if result.Error != nil {
@@ -535,10 +740,54 @@ func (api *API) DeleteOrganization(c *gin.Context) {
 		return
 	}
 
-	if res := api.db.Select(clause.Associations).Delete(&org); res.Error != nil {
+	var renumberings []models.OrganizationRenumbering
+	var invitations []models.Invitation
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Where("organization_id = ?", org.ID).Find(&invitations); res.Error != nil {
+			return res.Error
+		}
+		if err := deleteOrganizationPeerings(tx, org.ID); err != nil {
+			return err
+		}
+		// the private prefixes are released with their addresses, the shared namespace needs the
+		// reserved addresses released one by one.
+		var reservations []models.IpReservation
+		if res := tx.Where("organization_id = ?", org.ID).Find(&reservations); res.Error != nil {
+			return res.Error
+		}
+		if !org.PrivateCidr {
+			for _, reservation := range reservations {
+				if err := api.releaseReservedAddresses(ipam.WithTransaction(ctx, tx), tx, org, reservation); err != nil {
+					return err
+				}
+			}
+		}
+		if res := tx.Where("organization_id = ?", org.ID).Delete(&models.IpReservation{}); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.Where("organization_id = ? AND status = ?", org.ID, models.OrganizationRenumberingInProgress).Find(&renumberings); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.Where("organization_id = ?", org.ID).Delete(&models.OrganizationRenumbering{}); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.Select(clause.Associations).Delete(&org); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionDelete, "organization", org.ID.String(), org, nil)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to delete the organization: %w", err)))
 		return
 	}
+	// the memberships and the invitations were deleted with the organization.
+	api.signalBus.Notify(organizationsSignal(org.OwnerID))
+	api.signalBus.Notify(invitationsSignal(org.OwnerID))
+	for _, userOrg := range usersInOrg {
+		api.signalBus.Notify(organizationsSignal(userOrg.UserID))
+		api.signalBus.Notify(invitationsSignal(userOrg.UserID))
+	}
+	api.notifyInvitationsChanged(ctx, invitations...)
 
 	orgCIDR := org.IpCidr
 	orgCIDRV6 := org.IpCidrV6
@@ -553,6 +802,19 @@ func (api *API) DeleteOrganization(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release ipam organization prefix: %w", err)))
 			return
 		}
+
+		// the prefixes that the devices were being moved from.
+		for _, renumbering := range renumberings {
+			for _, previous := range []string{renumbering.PreviousCidr, renumbering.PreviousCidrV6} {
+				if previous == orgCIDR || previous == orgCIDRV6 {
+					continue
+				}
+				if err := api.ipam.ReleasePrefix(c.Request.Context(), ipamNamespace, previous); err != nil {
+					c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release ipam organization prefix: %w", err)))
+					return
+				}
+			}
+		}
 	}
 
 	c.JSON(http.StatusOK, org)
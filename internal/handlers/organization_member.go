@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var (
+	errMemberNotFound    = errors.New("member not found")
+	errOwnerRoleChange   = errors.New("the role of the organization owner cannot be changed")
+	errAdminRoleRequired = errors.New("only the organization owner can grant or revoke the admin role")
+	errGroupManagedRole  = errors.New("the role of the member is set by the group mappings of the organization")
+)
+
+// setOrganizationRole sets the role of a user in an organization they are a member of.
+func setOrganizationRole(tx *gorm.DB, orgId uuid.UUID, userId string, role string) error {
+	return tx.Model(&UserOrganization{}).
+		Where("user_id = ? AND organization_id = ?", userId, orgId).
+		Update("role", role).Error
+}
+
+// currentUserRole returns the role the current user has in the organization.
+func currentUserRole(c *gin.Context, tx *gorm.DB, org models.Organization) (string, error) {
+	userId := c.Value(gin.AuthUserKey).(string)
+	if org.OwnerID == userId {
+		return models.RoleOwner, nil
+	}
+	var membership UserOrganization
+	if res := tx.First(&membership, "user_id = ? AND organization_id = ?", userId, org.ID); res.Error != nil {
+		return "", res.Error
+	}
+	return membership.Role, nil
+}
+
+// ListOrganizationMembers lists the members of an organization and their roles
+// @Summary      List Organization Members
+// @Description  Lists the members of an organization and their roles
+// @Id           ListOrganizationMembers
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Success      200  {object}  []models.OrganizationMember
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/members [get]
+func (api *API) ListOrganizationMembers(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListOrganizationMembers", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	members := make([]models.OrganizationMember, 0)
+	result := api.db.WithContext(ctx).
+		Table("user_organizations").
+		Select("user_organizations.user_id, users.user_name, user_organizations.role, user_organizations.group_managed").
+		Joins("inner join users on users.id=user_organizations.user_id").
+		Where("user_organizations.organization_id = ?", org.ID).
+		Order("users.user_name").
+		Scan(&members)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(result.Error))
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// UpdateOrganizationMember changes the role of an organization member
+// @Summary      Update Organization Member
+// @Description  Changes the role of an organization member
+// @Id           UpdateOrganizationMember
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        id               path   string  true  "User ID"
+// @Param        update  body   models.UpdateOrganizationMember  true  "Member Update"
+// @Success      200  {object}  models.OrganizationMember
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/members/{id} [patch]
+func (api *API) UpdateOrganizationMember(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "UpdateOrganizationMember", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	userId := c.Param("id")
+	if userId == "" {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var request models.UpdateOrganizationMember
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.Role == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("role"))
+		return
+	}
+	if !models.IsValidRole(request.Role) || request.Role == models.RoleOwner {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("role", fmt.Sprintf("must be one of '%s', '%s' or '%s'", models.RoleAdmin, models.RoleMember, models.RoleReadOnly)))
+		return
+	}
+
+	var member models.OrganizationMember
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			return errOrgNotFound
+		}
+
+		var membership UserOrganization
+		if res := tx.First(&membership, "user_id = ? AND organization_id = ?", userId, org.ID); res.Error != nil {
+			return errMemberNotFound
+		}
+		if org.OwnerID == userId {
+			return errOwnerRoleChange
+		}
+		if membership.GroupManaged {
+			return errGroupManagedRole
+		}
+
+		if request.Role == models.RoleAdmin || membership.Role == models.RoleAdmin {
+			role, err := currentUserRole(c, tx, org)
+			if err != nil {
+				return err
+			}
+			if role != models.RoleOwner {
+				return errAdminRoleRequired
+			}
+		}
+
+		if err := setOrganizationRole(tx, org.ID, userId, request.Role); err != nil {
+			return err
+		}
+		if err := touchOrganization(tx, org.ID); err != nil {
+			return err
+		}
+
+		var user models.User
+		if res := tx.First(&user, "id = ?", userId); res.Error != nil {
+			return errUserNotFound
+		}
+		member = models.OrganizationMember{
+			UserID:   user.ID,
+			UserName: user.UserName,
+			Role:     request.Role,
+		}
+		before := member
+		before.Role = membership.Role
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionUpdate, "organization_member", user.ID, before, member)
+	})
+	if err != nil {
+		if errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errMemberNotFound) || errors.Is(err, errUserNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("user"))
+		} else if errors.Is(err, errOwnerRoleChange) || errors.Is(err, errAdminRoleRequired) || errors.Is(err, errGroupManagedRole) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+	// the member may be read-only before or after the change, they are notified either way.
+	api.signalBus.Notify(organizationsSignal(userId))
+	api.notifyOrganizationsChanged(ctx, orgId)
+	c.JSON(http.StatusOK, member)
+}
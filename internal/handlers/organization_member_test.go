@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestOrganizationMemberRoles() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	require.NoError(suite.api.db.Create(&UserOrganization{
+		UserID:         TestUser2ID,
+		OrganizationID: suite.testOrganizationID,
+		Role:           models.RoleReadOnly,
+	}).Error)
+
+	_, res, err := suite.ServeRequest(
+		http.MethodGet,
+		"/:organization/members", fmt.Sprintf("/%s/members", suite.testOrganizationID),
+		suite.api.ListOrganizationMembers, nil,
+	)
+	require.NoError(err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", string(body))
+
+	var members []models.OrganizationMember
+	require.NoError(json.Unmarshal(body, &members))
+	require.Len(members, 2)
+	roles := map[string]string{}
+	for _, m := range members {
+		roles[m.UserID] = m.Role
+	}
+	assert.Equal(models.RoleOwner, roles[TestUserID])
+	assert.Equal(models.RoleReadOnly, roles[TestUser2ID])
+
+	// the owner role cannot be granted
+	reqBody, err := json.Marshal(models.UpdateOrganizationMember{Role: models.RoleOwner})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPatch,
+		"/:organization/members/:id", fmt.Sprintf("/%s/members/%s", suite.testOrganizationID, TestUser2ID),
+		suite.api.UpdateOrganizationMember, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusBadRequest, res.Code)
+
+	// the organization is touched and the member is notified when their role changes.
+	var org models.Organization
+	require.NoError(suite.api.db.First(&org, "id = ?", suite.testOrganizationID).Error)
+	sub := suite.api.signalBus.Subscribe(organizationsSignal(TestUser2ID))
+	defer sub.Close()
+
+	reqBody, err = json.Marshal(models.UpdateOrganizationMember{Role: models.RoleAdmin})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPatch,
+		"/:organization/members/:id", fmt.Sprintf("/%s/members/%s", suite.testOrganizationID, TestUser2ID),
+		suite.api.UpdateOrganizationMember, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", string(body))
+
+	var member models.OrganizationMember
+	require.NoError(json.Unmarshal(body, &member))
+	assert.Equal(models.RoleAdmin, member.Role)
+	assert.True(sub.IsSignaled())
+	var updated models.Organization
+	require.NoError(suite.api.db.First(&updated, "id = ?", suite.testOrganizationID).Error)
+	assert.True(updated.UpdatedAt.After(org.UpdatedAt))
+
+	// the owner's role cannot be changed
+	reqBody, err = json.Marshal(models.UpdateOrganizationMember{Role: models.RoleMember})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPatch,
+		"/:organization/members/:id", fmt.Sprintf("/%s/members/%s", suite.testOrganizationID, TestUserID),
+		suite.api.UpdateOrganizationMember, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusForbidden, res.Code)
+
+	// and the owner cannot be removed from the organization
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete,
+		"/:id/organizations/:organization", fmt.Sprintf("/%s/organizations/%s", TestUserID, suite.testOrganizationID),
+		suite.api.DeleteUserFromOrganization, nil,
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusForbidden, res.Code)
+
+	// users without a membership role cannot administer the organization
+	_, res, err = suite.ServeRequest(
+		http.MethodPatch,
+		"/:organization/members/:id", fmt.Sprintf("/%s/members/%s", suite.testUser2OrgID, TestUser2ID),
+		suite.api.UpdateOrganizationMember, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusNotFound, res.Code)
+}
@@ -0,0 +1,674 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/signalbus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var (
+	errPeeringNotFound     = errors.New("organization peering not found")
+	errPeeringCidrsOverlap = errors.New("the organizations have overlapping cidrs")
+)
+
+// peeringsSignal is notified when the peerings of an organization change.
+func peeringsSignal(orgId uuid.UUID) string {
+	return fmt.Sprintf("/peerings/org=%s", orgId.String())
+}
+
+// CreateOrganizationPeering requests a peering with another organization
+// @Summary      Request an organization peering
+// @Description  Requests a peering with another organization, the owner of the other organization has to accept it
+// @Id           CreateOrganizationPeering
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string                         true  "Organization ID"
+// @Param        Peering          body   models.AddOrganizationPeering  true  "Add Organization Peering"
+// @Success      201  {object}  models.OrganizationPeering
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      409  {object}  models.ConflictsError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/peerings [post]
+func (api *API) CreateOrganizationPeering(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateOrganizationPeering", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.AddOrganizationPeering
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.PeerOrganizationID == uuid.Nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("peer_organization_id"))
+		return
+	}
+	if request.PeerOrganizationID == orgId {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("peer_organization_id", "an organization can't peer with itself"))
+		return
+	}
+	prefixes, validationErr := parsePeeringSide(request.Selector, request.Prefixes)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, validationErr)
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleOwner)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+	// the peer organization is not looked up, the requester has no access to it and the response
+	// must not tell whether it exists. Its cidrs are checked when it accepts the peering.
+	peerOrgId := request.PeerOrganizationID
+
+	var peering models.OrganizationPeering
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var existing models.OrganizationPeering
+		res := tx.Where("(organization_id = ? AND peer_organization_id = ?) OR (organization_id = ? AND peer_organization_id = ?)",
+			org.ID, peerOrgId, peerOrgId, org.ID).
+			First(&existing)
+		if res.Error == nil {
+			return errDuplicateOrganizationPeering{ID: existing.ID.String()}
+		}
+		if !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return res.Error
+		}
+
+		peering = models.OrganizationPeering{
+			OrganizationID:     org.ID,
+			Selector:           request.Selector,
+			Prefixes:           prefixes,
+			PeerOrganizationID: peerOrgId,
+			Status:             models.OrganizationPeeringPending,
+		}
+		if res := tx.Create(&peering); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionCreate, "organization_peering", peering.ID.String(), nil, peering)
+	})
+	if err != nil {
+		api.sendOrganizationPeeringError(c, err)
+		return
+	}
+	api.notifyPeeringChanged(peering)
+	span.SetAttributes(attribute.String("id", peering.ID.String()))
+	c.JSON(http.StatusCreated, peering)
+}
+
+// ListOrganizationPeerings lists the peerings of an organization
+// @Summary      List organization peerings
+// @Description  Lists the peerings that an organization requested or was asked to accept
+// @Id           ListOrganizationPeerings
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        q               query  string  false  "conditions on the fields, for example status=pending"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
+// @Success      200  {object}  []models.OrganizationPeering
+// @Failure		 400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/peerings [get]
+func (api *API) ListOrganizationPeerings(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListOrganizationPeerings", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	peerings := make([]models.OrganizationPeering, 0)
+	result := api.db.WithContext(ctx).
+		Where("organization_id = ? OR peer_organization_id = ?", org.ID, org.ID).
+		Scopes(FilterAndPaginate(&models.OrganizationPeering{}, c, "created_at")).
+		Find(&peerings)
+	if result.Error != nil {
+		sendListError(c, result.Error)
+		return
+	}
+	c.JSON(http.StatusOK, peerings)
+}
+
+// AcceptOrganizationPeering accepts a peering request
+// @Summary      Accept an organization peering
+// @Description  Accepts a peering that another organization requested, which connects the selected devices of both organizations
+// @Id           AcceptOrganizationPeering
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string                            true  "Organization ID"
+// @Param        id               path   string                            true  "Peering ID"
+// @Param        Peering          body   models.AcceptOrganizationPeering  true  "Accept Organization Peering"
+// @Success      200  {object}  models.OrganizationPeering
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/peerings/{id}/accept [post]
+func (api *API) AcceptOrganizationPeering(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "AcceptOrganizationPeering", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var request models.AcceptOrganizationPeering
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	prefixes, validationErr := parsePeeringSide(request.Selector, request.Prefixes)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, validationErr)
+		return
+	}
+
+	var peering models.OrganizationPeering
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleOwner)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			return errOrgNotFound
+		}
+		// only the organization that was asked to peer can accept the peering.
+		if res := tx.Where("peer_organization_id = ?", org.ID).
+			First(&peering, "id = ?", id); res.Error != nil {
+			return errPeeringNotFound
+		}
+		var requester models.Organization
+		if res := tx.First(&requester, "id = ?", peering.OrganizationID); res.Error != nil {
+			return errPeeringNotFound
+		}
+		if organizationCidrsOverlap(requester, org) {
+			return errPeeringCidrsOverlap
+		}
+		before := peering
+		peering.PeerSelector = request.Selector
+		peering.PeerPrefixes = prefixes
+		peering.Status = models.OrganizationPeeringActive
+		if res := tx.Save(&peering); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionUpdate, "organization_peering", peering.ID.String(), before, peering)
+	})
+	if err != nil {
+		api.sendOrganizationPeeringError(c, err)
+		return
+	}
+	api.notifyPeeringChanged(peering)
+	c.JSON(http.StatusOK, peering)
+}
+
+// DeleteOrganizationPeering deletes an organization peering
+// @Summary      Delete an organization peering
+// @Description  Deletes a peering, either organization of the peering can delete it
+// @Id           DeleteOrganizationPeering
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        id               path   string  true  "Peering ID"
+// @Success      200  {object}  models.OrganizationPeering
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/peerings/{id} [delete]
+func (api *API) DeleteOrganizationPeering(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteOrganizationPeering", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var peering models.OrganizationPeering
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleOwner)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			return errOrgNotFound
+		}
+		if res := tx.Where("organization_id = ? OR peer_organization_id = ?", org.ID, org.ID).
+			First(&peering, "id = ?", id); res.Error != nil {
+			return errPeeringNotFound
+		}
+		if res := tx.Delete(&peering); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionDelete, "organization_peering", peering.ID.String(), peering, nil)
+	})
+	if err != nil {
+		api.sendOrganizationPeeringError(c, err)
+		return
+	}
+	api.notifyPeeringChanged(peering)
+	c.JSON(http.StatusOK, peering)
+}
+
+// ListPeeredDevices lists the devices of peered organizations that a device can reach
+// @Summary      List peered devices
+// @Description  Lists the devices of other organizations that the device can reach through the active peerings of its organization.
+// @Description  Devices that are only peered by their child prefixes are listed with the peered child prefixes and without allowed ips.
+// @Id           ListPeeredDevices
+// @Tags         Devices
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true "Device ID"
+// @Success      200  {object}  []models.Device
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      410  {object}  models.GoneError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/devices/{id}/peered_devices [get]
+func (api *API) ListPeeredDevices(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListPeeredDevices", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	db := api.db.WithContext(ctx)
+	var device models.Device
+	if res := db.Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+		First(&device, "id = ?", id); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		return
+	}
+
+	if c.Query("watch") == "true" {
+		// the peered devices are computed from several tables, so a watch can't be resumed from a
+		// revision, the client has to start over with the full list.
+		if c.Query("gt_revision") != "" && c.Query("gt_revision") != "0" {
+			c.JSON(http.StatusGone, models.NewGoneError("peered devices can't be resumed from a revision, resync required"))
+			return
+		}
+		nextEvent, closeWatch := api.watchPeeredDevices(ctx, device.ID)
+		defer closeWatch()
+
+		c.Header("Content-Type", "application/json;stream=watch")
+		c.Status(http.StatusOK)
+		stream(c, nextEvent)
+		return
+	}
+
+	devices, _, err := api.peeredDevices(db, device)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, devices)
+}
+
+// peeredDevices returns the devices of other organizations that the device can reach through the
+// active peerings of its organization, and the ids of the organizations it is peered with.
+func (api *API) peeredDevices(db *gorm.DB, device models.Device) ([]models.Device, []uuid.UUID, error) {
+	var peerings []models.OrganizationPeering
+	if res := db.Where("status = ?", models.OrganizationPeeringActive).
+		Where("organization_id = ? OR peer_organization_id = ?", device.OrganizationID, device.OrganizationID).
+		Find(&peerings); res.Error != nil {
+		return nil, nil, res.Error
+	}
+
+	devices := make([]models.Device, 0)
+	var orgIds []uuid.UUID
+	for _, peering := range peerings {
+		otherOrgId := peering.OtherOrganizationID(device.OrganizationID)
+		orgIds = append(orgIds, otherOrgId)
+
+		local, remote := peering.Sides(device.OrganizationID)
+		if !peeringSelects(local.Selector, device.Labels) && len(peeredPrefixes(device.ChildPrefix, local.Prefixes)) == 0 {
+			continue
+		}
+
+		// relays only relay for their own organization, so they are never reachable through a peering.
+		peered := map[uuid.UUID]models.Device{}
+		if remoteSelector, err := models.ParseLabelSelector(remote.Selector); err == nil && len(remoteSelector) > 0 {
+			var selected []models.Device
+			if res := db.Where("organization_id = ? AND relay = ?", otherOrgId, false).
+				Scopes(labelSelectorScope(remoteSelector)).
+				Find(&selected); res.Error != nil {
+				return nil, nil, res.Error
+			}
+			for _, d := range selected {
+				peered[d.ID] = d
+			}
+		}
+		if len(remote.Prefixes) > 0 {
+			var routers []models.Device
+			if res := db.Where("organization_id = ? AND relay = ?", otherOrgId, false).
+				Find(&routers); res.Error != nil {
+				return nil, nil, res.Error
+			}
+			for _, d := range routers {
+				if _, ok := peered[d.ID]; ok {
+					continue
+				}
+				// only the peered child prefixes of the device can be reached, not its addresses.
+				if prefixes := peeredPrefixes(d.ChildPrefix, remote.Prefixes); len(prefixes) > 0 {
+					d.ChildPrefix = prefixes
+					d.AllowedIPs = pq.StringArray{}
+					peered[d.ID] = d
+				}
+			}
+		}
+		for _, d := range peered {
+			devices = append(devices, d)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].Hostname != devices[j].Hostname {
+			return devices[i].Hostname < devices[j].Hostname
+		}
+		return devices[i].ID.String() < devices[j].ID.String()
+	})
+	return devices, orgIds, nil
+}
+
+// watchPeeredDevices returns a function that blocks until the next change to the peered devices
+// of a device, and a function that must be called to release the watch. The peered devices are
+// recomputed when the devices or the peerings of the organizations change, the changed devices
+// are sent as "change" events and the devices that are no longer peered as "delete" events.
+func (api *API) watchPeeredDevices(ctx context.Context, deviceId uuid.UUID) (func() models.WatchEvent, func()) {
+	subs := map[string]*signalbus.Subscription{}
+	// subscribe subscribes to the signals and unsubscribes from the others, it returns true if
+	// it subscribed to a new signal.
+	subscribe := func(signals []string) bool {
+		added := false
+		wanted := map[string]bool{}
+		for _, signal := range signals {
+			wanted[signal] = true
+			if _, ok := subs[signal]; !ok {
+				subs[signal] = api.signalBus.Subscribe(signal)
+				added = true
+			}
+		}
+		for signal, sub := range subs {
+			if !wanted[signal] {
+				sub.Close()
+				delete(subs, signal)
+			}
+		}
+		return added
+	}
+	closeWatch := func() {
+		subscribe(nil)
+	}
+
+	sent := map[uuid.UUID]models.Device{}
+	var pending []models.WatchEvent
+	var bookmarkSentAt time.Time
+	lastEventAt := time.Now()
+	waitForChanges := false
+
+	return func() models.WatchEvent {
+		for {
+			if len(pending) > 0 {
+				event := pending[0]
+				pending = pending[1:]
+				lastEventAt = time.Now()
+				return event
+			}
+
+			if waitForChanges {
+				var waitFor []*signalbus.Subscription
+				for _, sub := range subs {
+					waitFor = append(waitFor, sub)
+				}
+				if waitForCancelOrTimeoutOrNotifications(ctx, watchBookmarkInterval, waitFor) {
+					return models.WatchEvent{
+						Type: "close",
+					}
+				}
+			}
+			waitForChanges = true
+
+			db := api.db.WithContext(ctx)
+			var devices []models.Device
+			for {
+				var device models.Device
+				if res := db.First(&device, "id = ?", deviceId); res.Error != nil {
+					return models.WatchEvent{
+						Type:  "error",
+						Value: res.Error.Error(),
+					}
+				}
+				var orgIds []uuid.UUID
+				var err error
+				devices, orgIds, err = api.peeredDevices(db, device)
+				if err != nil {
+					return models.WatchEvent{
+						Type:  "error",
+						Value: err.Error(),
+					}
+				}
+				signals := []string{
+					fmt.Sprintf("/devices/org=%s", device.OrganizationID.String()),
+					peeringsSignal(device.OrganizationID),
+				}
+				for _, orgId := range orgIds {
+					signals = append(signals, fmt.Sprintf("/devices/org=%s", orgId.String()))
+				}
+				// the changes made before we subscribed to a signal could have been missed,
+				// so compute the devices again.
+				if !subscribe(signals) {
+					break
+				}
+			}
+
+			current := map[uuid.UUID]models.Device{}
+			for _, d := range devices {
+				current[d.ID] = d
+				if previous, ok := sent[d.ID]; !ok || !reflect.DeepEqual(previous, d) {
+					pending = append(pending, models.WatchEvent{Type: "change", Value: d})
+				}
+			}
+			for id, d := range sent {
+				if _, ok := current[id]; !ok {
+					pending = append(pending, models.WatchEvent{Type: "delete", Value: d})
+				}
+			}
+			sent = current
+			if len(pending) > 0 {
+				continue
+			}
+
+			// the first bookmark tells the client it has received the full list.
+			if bookmarkSentAt.IsZero() || time.Since(lastEventAt) >= watchBookmarkInterval && time.Since(bookmarkSentAt) >= watchBookmarkInterval {
+				bookmarkSentAt = time.Now()
+				return models.WatchEvent{
+					Type:  "bookmark",
+					Value: models.WatchBookmark{},
+				}
+			}
+		}
+	}, closeWatch
+}
+
+// notifyPeeringChanged wakes up the watches of the peered devices of both organizations of the peering.
+func (api *API) notifyPeeringChanged(peering models.OrganizationPeering) {
+	api.signalBus.Notify(peeringsSignal(peering.OrganizationID))
+	api.signalBus.Notify(peeringsSignal(peering.PeerOrganizationID))
+}
+
+// parsePeeringSide validates the selector and the prefixes of one organization of a peering, at
+// least one of them has to be set so that a peering never exposes all the devices of an organization.
+// It returns the prefixes in their canonical form.
+func parsePeeringSide(selector string, prefixes []string) (pq.StringArray, *models.ValidationError) {
+	parsed, err := models.ParseLabelSelector(selector)
+	if err != nil {
+		validationErr := models.NewFieldValidationError("selector", err.Error())
+		return nil, &validationErr
+	}
+	if len(parsed) == 0 && len(prefixes) == 0 {
+		validationErr := models.NewFieldValidationError("selector", "a selector or prefixes are required")
+		return nil, &validationErr
+	}
+	result := pq.StringArray{}
+	for _, p := range prefixes {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			validationErr := models.NewFieldValidationError("prefixes", fmt.Sprintf("invalid prefix %q", p))
+			return nil, &validationErr
+		}
+		result = append(result, prefix.Masked().String())
+	}
+	return result, nil
+}
+
+// peeringSelects returns true if the labels match the selector of a peering, an empty selector
+// selects no devices.
+func peeringSelects(selector string, labels map[string]string) bool {
+	parsed, err := models.ParseLabelSelector(selector)
+	if err != nil || len(parsed) == 0 {
+		return false
+	}
+	return parsed.Matches(labels)
+}
+
+// peeredPrefixes returns the child prefixes that are within the prefixes of a peering.
+func peeredPrefixes(childPrefixes []string, prefixes []string) []string {
+	var result []string
+	for _, c := range childPrefixes {
+		child, err := netip.ParsePrefix(c)
+		if err != nil {
+			continue
+		}
+		for _, p := range prefixes {
+			prefix, err := netip.ParsePrefix(p)
+			if err != nil {
+				continue
+			}
+			if prefix.Bits() <= child.Bits() && prefix.Contains(child.Addr()) {
+				result = append(result, c)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// deleteOrganizationPeerings deletes the peerings of an organization that is being deleted.
+func deleteOrganizationPeerings(tx *gorm.DB, orgId uuid.UUID) error {
+	return tx.Where("organization_id = ? OR peer_organization_id = ?", orgId, orgId).
+		Delete(&models.OrganizationPeering{}).Error
+}
+
+// organizationCidrsOverlap returns true if the addresses of the devices of the organizations could
+// collide. Organizations without private cidrs share an IPAM namespace, so their addresses are unique.
+func organizationCidrsOverlap(a models.Organization, b models.Organization) bool {
+	if !a.PrivateCidr && !b.PrivateCidr {
+		return false
+	}
+	return cidrsOverlap(a.IpCidr, b.IpCidr) || cidrsOverlap(a.IpCidrV6, b.IpCidrV6)
+}
+
+func cidrsOverlap(a string, b string) bool {
+	_, aNet, err := net.ParseCIDR(a)
+	if err != nil {
+		return false
+	}
+	_, bNet, err := net.ParseCIDR(b)
+	if err != nil {
+		return false
+	}
+	return aNet.Contains(bNet.IP) || bNet.Contains(aNet.IP)
+}
+
+type errDuplicateOrganizationPeering struct {
+	ID string
+}
+
+func (e errDuplicateOrganizationPeering) Error() string {
+	return "duplicate organization peering"
+}
+
+func (api *API) sendOrganizationPeeringError(c *gin.Context, err error) {
+	var duplicate errDuplicateOrganizationPeering
+	if errors.As(err, &duplicate) {
+		c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+	} else if errors.Is(err, errOrgNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+	} else if errors.Is(err, errPeeringNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization peering"))
+	} else if errors.Is(err, errPeeringCidrsOverlap) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("id", err.Error()))
+	} else {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+	}
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestOrganizationPeering() {
+	require := suite.Require()
+	assert := suite.Assert()
+	suite.api.db.Exec("DELETE FROM organization_peerings")
+
+	createDevice := func(orgId uuid.UUID, hostname string, labels map[string]string) models.Device {
+		return suite.createDevice(models.AddDevice{
+			OrganizationID: orgId,
+			PublicKey:      "peering-" + hostname,
+			Hostname:       hostname,
+			Labels:         labels,
+		})
+	}
+	acceptPeering := func(orgId uuid.UUID, id uuid.UUID, selector string, prefixes ...string) int {
+		reqBody, err := json.Marshal(models.AcceptOrganizationPeering{Selector: selector, Prefixes: prefixes})
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/organizations/:organization/peerings/:id/accept",
+			fmt.Sprintf("/organizations/%s/peerings/%s/accept", orgId, id),
+			suite.api.AcceptOrganizationPeering, bytes.NewBuffer(reqBody),
+		)
+		require.NoError(err)
+		return res.Code
+	}
+	listPeeredDevices := func(device models.Device) []string {
+		_, res, err := suite.ServeRequest(
+			http.MethodGet, "/devices/:id/peered_devices", fmt.Sprintf("/devices/%s/peered_devices", device.ID),
+			suite.api.ListPeeredDevices, nil,
+		)
+		require.NoError(err)
+		require.Equal(http.StatusOK, res.Code, res.Body.String())
+		var devices []models.Device
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &devices))
+		var hostnames []string
+		for _, d := range devices {
+			hostnames = append(hostnames, d.Hostname)
+		}
+		return hostnames
+	}
+
+	// a peering without a selector or prefixes would expose all the devices.
+	reqBody, err := json.Marshal(models.AddOrganizationPeering{
+		PeerOrganizationID: suite.testUser2OrgID,
+	})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(
+		http.MethodPost, "/organizations/:organization/peerings",
+		fmt.Sprintf("/organizations/%s/peerings", suite.testOrganizationID),
+		suite.api.CreateOrganizationPeering, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusBadRequest, res.Code)
+
+	reqBody, err = json.Marshal(models.AddOrganizationPeering{
+		PeerOrganizationID: suite.testUser2OrgID,
+		Selector:           "role=build-cache",
+	})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPost, "/organizations/:organization/peerings",
+		fmt.Sprintf("/organizations/%s/peerings", suite.testOrganizationID),
+		suite.api.CreateOrganizationPeering, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, res.Body.String())
+	var peering models.OrganizationPeering
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &peering))
+	assert.Equal(models.OrganizationPeeringPending, peering.Status)
+
+	// there can only be one peering between two organizations.
+	_, res, err = suite.ServeRequest(
+		http.MethodPost, "/organizations/:organization/peerings",
+		fmt.Sprintf("/organizations/%s/peerings", suite.testOrganizationID),
+		suite.api.CreateOrganizationPeering, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusConflict, res.Code)
+
+	// the response doesn't tell whether the peer organization exists.
+	reqBody, err = json.Marshal(models.AddOrganizationPeering{
+		PeerOrganizationID: uuid.New(),
+		Selector:           "role=build-cache",
+	})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPost, "/organizations/:organization/peerings",
+		fmt.Sprintf("/organizations/%s/peerings", suite.testOrganizationID),
+		suite.api.CreateOrganizationPeering, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusCreated, res.Code, res.Body.String())
+
+	// only the owner of the peer organization can accept the peering.
+	assert.Equal(http.StatusNotFound, acceptPeering(suite.testOrganizationID, peering.ID, "team=ci"))
+	assert.Equal(http.StatusNotFound, acceptPeering(suite.testUser2OrgID, peering.ID, "team=ci"))
+	require.NoError(suite.api.db.Create(&UserOrganization{
+		UserID:         TestUserID,
+		OrganizationID: suite.testUser2OrgID,
+		Role:           models.RoleOwner,
+	}).Error)
+
+	cache := createDevice(suite.testOrganizationID, "cache", map[string]string{"role": "build-cache"})
+	web := createDevice(suite.testOrganizationID, "web", map[string]string{"role": "web"})
+	ci := createDevice(suite.testUser2OrgID, "ci", map[string]string{"team": "ci"})
+	laptop := createDevice(suite.testUser2OrgID, "laptop", nil)
+
+	// pending peerings don't connect any devices.
+	assert.Empty(listPeeredDevices(cache))
+
+	assert.Equal(http.StatusBadRequest, acceptPeering(suite.testUser2OrgID, peering.ID, "team=ci,"))
+	assert.Equal(http.StatusBadRequest, acceptPeering(suite.testUser2OrgID, peering.ID, ""))
+	assert.Equal(http.StatusBadRequest, acceptPeering(suite.testUser2OrgID, peering.ID, "", "10.0.0.0/33"))
+
+	// the cidrs of the organizations are checked when the peering is accepted.
+	var peerOrg models.Organization
+	require.NoError(suite.api.db.First(&peerOrg, "id = ?", suite.testUser2OrgID).Error)
+	var org models.Organization
+	require.NoError(suite.api.db.First(&org, "id = ?", suite.testOrganizationID).Error)
+	require.NoError(suite.api.db.Model(&models.Organization{}).Where("id = ?", peerOrg.ID).
+		Updates(map[string]interface{}{"private_cidr": true, "ip_cidr": org.IpCidr}).Error)
+	assert.Equal(http.StatusBadRequest, acceptPeering(suite.testUser2OrgID, peering.ID, "team=ci"))
+	require.NoError(suite.api.db.Model(&models.Organization{}).Where("id = ?", peerOrg.ID).
+		Updates(map[string]interface{}{"private_cidr": peerOrg.PrivateCidr, "ip_cidr": peerOrg.IpCidr}).Error)
+
+	require.Equal(http.StatusOK, acceptPeering(suite.testUser2OrgID, peering.ID, "team=ci", "172.16.20.1/24"))
+
+	assert.Equal([]string{"ci"}, listPeeredDevices(cache))
+	assert.Empty(listPeeredDevices(web))
+	assert.Equal([]string{"cache"}, listPeeredDevices(ci))
+	assert.Empty(listPeeredDevices(laptop))
+
+	// a device that advertises a peered child prefix is reachable by that prefix only.
+	router := createDevice(suite.testUser2OrgID, "router", nil)
+	require.NoError(suite.api.db.Model(&models.Device{}).Where("id = ?", router.ID).
+		Update("child_prefix", pq.StringArray{"172.16.20.0/25", "192.168.1.0/24"}).Error)
+	assert.Equal([]string{"ci", "router"}, listPeeredDevices(cache))
+	assert.Equal([]string{"cache"}, listPeeredDevices(router))
+	_, res, err = suite.ServeRequest(
+		http.MethodGet, "/devices/:id/peered_devices", fmt.Sprintf("/devices/%s/peered_devices", cache.ID),
+		suite.api.ListPeeredDevices, nil,
+	)
+	require.NoError(err)
+	var peered []models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &peered))
+	require.Len(peered, 2)
+	assert.Equal(pq.StringArray{"172.16.20.0/25"}, peered[1].ChildPrefix)
+	assert.Empty(peered[1].AllowedIPs)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet, "/organizations/:organization/peerings",
+		fmt.Sprintf("/organizations/%s/peerings", suite.testUser2OrgID),
+		suite.api.ListOrganizationPeerings, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, res.Body.String())
+	var peerings []models.OrganizationPeering
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &peerings))
+	require.Len(peerings, 1)
+	assert.Equal(models.OrganizationPeeringActive, peerings[0].Status)
+	assert.Equal("team=ci", peerings[0].PeerSelector)
+	assert.Equal(pq.StringArray{"172.16.20.0/24"}, peerings[0].PeerPrefixes)
+
+	// a watch of the peered devices sends the devices that stop being peered as deletes.
+	ctx, cancel := context.WithCancel(context.Background())
+	nextEvent, closeWatch := suite.api.watchPeeredDevices(ctx, cache.ID)
+	var watched []string
+	for event := nextEvent(); event.Type != "bookmark"; event = nextEvent() {
+		require.Equal("change", event.Type, event.Value)
+		watched = append(watched, event.Value.(models.Device).Hostname)
+	}
+	assert.Equal([]string{"ci", "router"}, watched)
+	require.NoError(suite.api.db.Model(&models.Device{}).Where("id = ?", router.ID).
+		Update("child_prefix", pq.StringArray{"192.168.1.0/24"}).Error)
+	suite.api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", suite.testUser2OrgID))
+	event := nextEvent()
+	assert.Equal("delete", event.Type)
+	assert.Equal("router", event.Value.(models.Device).Hostname)
+	cancel()
+	assert.Equal("close", nextEvent().Type)
+	closeWatch()
+
+	// a watch can't be resumed from a revision.
+	_, res, err = suite.ServeRequest(
+		http.MethodGet, "/devices/:id/peered_devices", fmt.Sprintf("/devices/%s/peered_devices?watch=true&gt_revision=10", cache.ID),
+		suite.api.ListPeeredDevices, nil,
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusGone, res.Code)
+
+	// either organization can delete the peering.
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete, "/organizations/:organization/peerings/:id",
+		fmt.Sprintf("/organizations/%s/peerings/%s", suite.testUser2OrgID, peering.ID),
+		suite.api.DeleteOrganizationPeering, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, res.Body.String())
+	assert.Empty(listPeeredDevices(cache))
+}
+
+func (suite *HandlerTestSuite) TestOrganizationCidrsOverlap() {
+	assert := suite.Assert()
+	shared := models.Organization{IpCidr: defaultIPAMv4Cidr, IpCidrV6: defaultIPAMv6Cidr}
+	assert.False(organizationCidrsOverlap(shared, shared))
+
+	private := models.Organization{PrivateCidr: true, IpCidr: "100.100.0.0/16", IpCidrV6: "0200:0100::/64"}
+	assert.True(organizationCidrsOverlap(shared, private))
+	other := models.Organization{PrivateCidr: true, IpCidr: "10.10.0.0/16", IpCidrV6: "0200:0200::/64"}
+	assert.False(organizationCidrsOverlap(other, private))
+	assert.False(organizationCidrsOverlap(shared, other))
+}
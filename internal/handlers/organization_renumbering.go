@@ -0,0 +1,643 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// renumberingInterval is how often a batch of the devices of the organizations being renumbered
+// are given addresses of the new CIDRs.
+const renumberingInterval = 30 * time.Second
+
+// cidrChange is how a resize changes a CIDR of an organization.
+type cidrChange int
+
+const (
+	cidrUnchanged cidrChange = iota
+	// cidrGrown is a CIDR that contains the previous one, the devices keep their addresses.
+	cidrGrown
+	// cidrMoved is a CIDR that doesn't overlap the previous one, the devices are given new addresses.
+	cidrMoved
+)
+
+type errDuplicateOrganizationRenumbering struct {
+	ID string
+}
+
+func (e errDuplicateOrganizationRenumbering) Error() string {
+	return "the organization is already being renumbered"
+}
+
+// ResizeOrganization changes the CIDRs of an organization
+// @Summary      Resize an Organization
+// @Description  Changes the CIDRs of an organization with a private CIDR. A CIDR that contains the current one is grown in place, otherwise the devices are given addresses of the new CIDR in batches
+// @Id           ResizeOrganization
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string                     true  "Organization ID"
+// @Param        Resize           body   models.ResizeOrganization  true  "Resize Organization"
+// @Success      202  {object}  models.OrganizationRenumbering
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      409  {object}  models.ConflictsError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/resize [post]
+func (api *API) ResizeOrganization(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ResizeOrganization", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.ResizeOrganization
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.BatchSize < 0 {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("batch_size", "must not be negative"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleOwner)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+	if !org.PrivateCidr {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("cidr", "only the organizations with a private cidr can be resized"))
+		return
+	}
+
+	cidr, change, err := resizeCidr(org.IpCidr, request.Cidr, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("cidr", err.Error()))
+		return
+	}
+	cidrV6, changeV6, err := resizeCidr(org.IpCidrV6, request.CidrV6, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("cidr_v6", err.Error()))
+		return
+	}
+	if change == cidrUnchanged && changeV6 == cidrUnchanged {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("cidr", "the cidr or the cidr_v6 has to change"))
+		return
+	}
+
+	var devices []models.Device
+	if res := api.db.WithContext(ctx).Where("organization_id = ?", org.ID).Find(&devices); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	for _, check := range []struct {
+		field  string
+		cidr   string
+		change cidrChange
+		// addressColumn is the device and ip reservation column of the addresses of the cidr.
+		addressColumn string
+	}{
+		{"cidr", cidr, change, "tunnel_ip"},
+		{"cidr_v6", cidrV6, changeV6, "tunnel_ip_v6"},
+	} {
+		if check.change == cidrUnchanged {
+			continue
+		}
+		if check.change == cidrMoved && prefixCapacity(check.cidr) < uint64(len(devices)) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError(check.field, fmt.Sprintf("does not have enough addresses for the %d devices of the organization", len(devices))))
+			return
+		}
+		// the reserved addresses are referenced outside the organization, so they have to be deleted first.
+		if check.change == cidrMoved {
+			var reservations int64
+			if res := api.db.WithContext(ctx).Model(&models.IpReservation{}).
+				Where(fmt.Sprintf("organization_id = ? AND %s <> ''", check.addressColumn), org.ID).
+				Count(&reservations); res.Error != nil {
+				c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+				return
+			}
+			if reservations > 0 {
+				c.JSON(http.StatusBadRequest, models.NewFieldValidationError(check.field, fmt.Sprintf("the organization has %d ip reservations in the current cidr, delete them before moving it", reservations)))
+				return
+			}
+		}
+		for _, device := range devices {
+			for _, prefix := range device.ChildPrefix {
+				if !util.IsDefaultIPv4Route(prefix) && !util.IsDefaultIPv6Route(prefix) && cidrsOverlap(check.cidr, prefix) {
+					c.JSON(http.StatusBadRequest, models.NewFieldValidationError(check.field, fmt.Sprintf("overlaps the child prefix %s of device %s", prefix, device.ID)))
+					return
+				}
+			}
+		}
+	}
+
+	before := org
+	resized := org
+	resized.IpCidr = cidr
+	resized.IpCidrV6 = cidrV6
+	var peerings []models.OrganizationPeering
+	if res := api.db.WithContext(ctx).Where("organization_id = ? OR peer_organization_id = ?", org.ID, org.ID).Find(&peerings); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	for _, peering := range peerings {
+		var peerOrg models.Organization
+		if res := api.db.WithContext(ctx).First(&peerOrg, "id = ?", peering.OtherOrganizationID(org.ID)); res.Error != nil {
+			continue
+		}
+		if organizationCidrsOverlap(resized, peerOrg) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("cidr", fmt.Sprintf("overlaps the cidr of the peered organization %s", peerOrg.ID)))
+			return
+		}
+	}
+
+	// the allocations of an ipam that isn't part of the transaction are restored when it fails.
+	var restores []func()
+	restore := func() {
+		api.restoreIPAMAllocations(restores)
+		restores = nil
+	}
+
+	var renumbering models.OrganizationRenumbering
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		ctx := ipam.WithTransaction(ctx, tx)
+		// a retried transaction starts from the previous allocations.
+		restore()
+		var existing models.OrganizationRenumbering
+		res := tx.Where("organization_id = ? AND status = ?", org.ID, models.OrganizationRenumberingInProgress).First(&existing)
+		if res.Error == nil {
+			return errDuplicateOrganizationRenumbering{ID: existing.ID.String()}
+		}
+		if !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return res.Error
+		}
+
+		if change == cidrGrown {
+			restoreGrown, err := api.growOrganizationCidr(ctx, tx, org.ID, org.IpCidr, cidr, "organization_prefix", "tunnel_ip")
+			restores = append(restores, restoreGrown)
+			if err != nil {
+				return err
+			}
+		} else if change == cidrMoved {
+			if err := api.ipam.AssignPrefix(ctx, org.ID, cidr); err != nil {
+				return fmt.Errorf("failed to assign the new ipam v4 prefix: %w", err)
+			}
+			restores = append(restores, func() {
+				api.logRestoreError(org.ID, api.ipam.ReleasePrefix(ctx, org.ID, cidr))
+			})
+		}
+		if changeV6 == cidrGrown {
+			restoreGrown, err := api.growOrganizationCidr(ctx, tx, org.ID, org.IpCidrV6, cidrV6, "organization_prefix_v6", "tunnel_ip_v6")
+			restores = append(restores, restoreGrown)
+			if err != nil {
+				return err
+			}
+		} else if changeV6 == cidrMoved {
+			if err := api.ipam.AssignPrefix(ctx, org.ID, cidrV6); err != nil {
+				return fmt.Errorf("failed to assign the new ipam v6 prefix: %w", err)
+			}
+			restores = append(restores, func() {
+				api.logRestoreError(org.ID, api.ipam.ReleasePrefix(ctx, org.ID, cidrV6))
+			})
+		}
+
+		if res := tx.Model(&org).Updates(map[string]interface{}{"ip_cidr": cidr, "ip_cidr_v6": cidrV6}); res.Error != nil {
+			return res.Error
+		}
+
+		renumbering = models.OrganizationRenumbering{
+			OrganizationID: org.ID,
+			PreviousCidr:   before.IpCidr,
+			PreviousCidrV6: before.IpCidrV6,
+			Cidr:           cidr,
+			CidrV6:         cidrV6,
+			BatchSize:      request.BatchSize,
+			Status:         models.OrganizationRenumberingInProgress,
+		}
+		if res := tx.Create(&renumbering); res.Error != nil {
+			return res.Error
+		}
+		if err := api.recordAuditEvent(c, tx, org.ID, models.AuditActionUpdate, "organization", org.ID.String(), before, resized); err != nil {
+			return err
+		}
+		// completes the renumbering right away when there are no devices to move.
+		_, restoreRenumbered, err := api.renumberDevices(ctx, tx, &renumbering, 0)
+		restores = append(restores, restoreRenumbered)
+		return err
+	})
+	if err != nil {
+		restore()
+		var duplicate errDuplicateOrganizationRenumbering
+		if errors.As(err, &duplicate) {
+			c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+
+	span.SetAttributes(attribute.String("id", renumbering.ID.String()))
+	api.notifyOrganizationsChanged(ctx, org.ID)
+	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", org.ID.String()))
+	c.JSON(http.StatusAccepted, renumbering)
+}
+
+// ListOrganizationRenumberings lists the renumberings of an organization
+// @Summary      List organization renumberings
+// @Description  Lists the changes of the CIDRs of an organization, and the progress of the devices being given new addresses
+// @Id           ListOrganizationRenumberings
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        q               query  string  false  "conditions on the fields, for example status=in_progress,created_at>=2023-06-01"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
+// @Success      200  {object}  []models.OrganizationRenumbering
+// @Failure		 400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/renumberings [get]
+func (api *API) ListOrganizationRenumberings(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListOrganizationRenumberings", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	renumberings := make([]models.OrganizationRenumbering, 0)
+	result := api.db.WithContext(ctx).
+		Where("organization_id = ?", org.ID).
+		Scopes(FilterAndPaginate(&models.OrganizationRenumbering{}, c, "created_at")).
+		Find(&renumberings)
+	if result.Error != nil {
+		sendListError(c, result.Error)
+		return
+	}
+	c.JSON(http.StatusOK, renumberings)
+}
+
+// StartOrganizationRenumbering starts the background worker that gives the devices of the organizations
+// being renumbered addresses of their new CIDRs, a batch of devices of each organization at a time.
+func (api *API) StartOrganizationRenumbering(ctx context.Context, wg *sync.WaitGroup) {
+	util.GoWithWaitGroup(wg, func() {
+		ticker := time.NewTicker(renumberingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			api.renumberOrganizations(ctx)
+		}
+	})
+}
+
+// renumberOrganizations moves a batch of the devices of each organization being renumbered.
+func (api *API) renumberOrganizations(ctx context.Context) {
+	var renumberings []models.OrganizationRenumbering
+	if res := api.db.WithContext(ctx).Where("status = ?", models.OrganizationRenumberingInProgress).Find(&renumberings); res.Error != nil {
+		api.logger.Errorf("failed to list the organization renumberings: %v", res.Error)
+		return
+	}
+	for _, renumbering := range renumberings {
+		if ctx.Err() != nil {
+			return
+		}
+		var moved int
+		var restores []func()
+		restore := func() {
+			api.restoreIPAMAllocations(restores)
+			restores = nil
+		}
+		err := api.transaction(ctx, func(tx *gorm.DB) error {
+			ctx := ipam.WithTransaction(ctx, tx)
+			// a retried transaction starts from the previous allocations.
+			restore()
+			// the renumberings that another apiserver replica is working on are skipped.
+			if res := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				First(&renumbering, "id = ? AND status = ?", renumbering.ID, models.OrganizationRenumberingInProgress); res.Error != nil {
+				return res.Error
+			}
+			limit := renumbering.BatchSize
+			if limit == 0 {
+				limit = -1
+			}
+			var restoreRenumbered func()
+			var err error
+			moved, restoreRenumbered, err = api.renumberDevices(ctx, tx, &renumbering, limit)
+			restores = append(restores, restoreRenumbered)
+			return err
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			restore()
+			api.logger.Errorf("failed to renumber the devices of organization %s: %v", renumbering.OrganizationID, err)
+			continue
+		}
+		if moved > 0 {
+			api.logger.Infof("renumbered %d devices of organization %s, %d remaining", moved, renumbering.OrganizationID, renumbering.DevicesRemaining)
+			api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", renumbering.OrganizationID.String()))
+		}
+	}
+}
+
+// renumberDevices gives up to limit devices that have an address of a previous CIDR of the organization
+// an address of its current CIDR, no devices when limit is 0 and all of them when it's negative. Once no
+// device is left, the previous CIDRs are released and the renumbering is completed.
+// The returned function restores the ipam allocations of the devices and of the previous CIDRs, even when it fails.
+func (api *API) renumberDevices(ctx context.Context, tx *gorm.DB, renumbering *models.OrganizationRenumbering, limit int) (int, func(), error) {
+	var restores []func()
+	restore := func() {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+	}
+	var org models.Organization
+	if res := tx.First(&org, "id = ?", renumbering.OrganizationID); res.Error != nil {
+		return 0, restore, res.Error
+	}
+	outdated := func() *gorm.DB {
+		return tx.Model(&models.Device{}).
+			Where("organization_id = ? AND (organization_prefix <> ? OR organization_prefix_v6 <> ?)", org.ID, org.IpCidr, org.IpCidrV6)
+	}
+
+	var devices []models.Device
+	if limit != 0 {
+		query := outdated().Order("created_at")
+		if limit > 0 {
+			query = query.Limit(limit)
+		}
+		if res := query.Find(&devices); res.Error != nil {
+			return 0, restore, res.Error
+		}
+	}
+	for i := range devices {
+		restoreDevice, err := api.renumberDevice(ctx, tx, &devices[i], org)
+		restores = append(restores, restoreDevice)
+		if err != nil {
+			return 0, restore, err
+		}
+	}
+
+	var remaining int64
+	if res := outdated().Count(&remaining); res.Error != nil {
+		return 0, restore, res.Error
+	}
+	renumbering.DevicesRemaining = int(remaining)
+	if remaining == 0 {
+		for _, previous := range []struct{ prefix, current string }{
+			{renumbering.PreviousCidr, org.IpCidr},
+			{renumbering.PreviousCidrV6, org.IpCidrV6},
+		} {
+			if cidrsOverlap(previous.prefix, previous.current) {
+				continue
+			}
+			if err := api.ipam.ReleasePrefix(ctx, org.ID, previous.prefix); err != nil {
+				return 0, restore, fmt.Errorf("failed to release the previous ipam prefix: %w", err)
+			}
+			prefix := previous.prefix
+			restores = append(restores, func() {
+				api.logRestoreError(org.ID, api.ipam.AssignPrefix(ctx, org.ID, prefix))
+			})
+		}
+		renumbering.Status = models.OrganizationRenumberingCompleted
+	}
+	if res := tx.Model(renumbering).Updates(map[string]interface{}{
+		"devices_remaining": renumbering.DevicesRemaining,
+		"status":            renumbering.Status,
+	}); res.Error != nil {
+		return 0, restore, res.Error
+	}
+	return len(devices), restore, nil
+}
+
+// renumberDevice gives the device addresses of the current CIDRs of the organization.
+// The returned function restores the ipam allocations of the previous addresses, even when it fails.
+func (api *API) renumberDevice(ctx context.Context, tx *gorm.DB, device *models.Device, org models.Organization) (func(), error) {
+	var assigned, released []ipam.Allocation
+	restore := func() {
+		for _, address := range assigned {
+			api.logRestoreError(org.ID, api.ipam.ReleaseToPool(ctx, org.ID, address.Address, address.Prefix))
+		}
+		for _, address := range released {
+			api.logRestoreError(org.ID, api.ipam.AcquireIP(ctx, org.ID, address.Prefix, address.Address))
+		}
+	}
+	var err error
+	if device.OrganizationPrefix != org.IpCidr {
+		ip, err := api.ipam.AssignFromPool(ctx, org.ID, org.IpCidr)
+		if err != nil {
+			return restore, fmt.Errorf("failed to request ipam address: %w", err)
+		}
+		assigned = append(assigned, ipam.Allocation{Prefix: org.IpCidr, Address: ip})
+		if err := api.ipam.ReleaseToPool(ctx, org.ID, device.TunnelIP, device.OrganizationPrefix); err != nil {
+			return restore, fmt.Errorf("failed to release the v4 address to pool: %w", err)
+		}
+		released = append(released, ipam.Allocation{Prefix: device.OrganizationPrefix, Address: device.TunnelIP})
+		device.TunnelIP = ip
+		device.OrganizationPrefix = org.IpCidr
+	}
+	if device.OrganizationPrefixV6 != org.IpCidrV6 {
+		ip, err := api.ipam.AssignFromPool(ctx, org.ID, org.IpCidrV6)
+		if err != nil {
+			return restore, fmt.Errorf("failed to request ipam v6 address: %w", err)
+		}
+		assigned = append(assigned, ipam.Allocation{Prefix: org.IpCidrV6, Address: ip})
+		if err := api.ipam.ReleaseToPool(ctx, org.ID, device.TunnelIpV6, device.OrganizationPrefixV6); err != nil {
+			return restore, fmt.Errorf("failed to release the v6 address to pool: %w", err)
+		}
+		released = append(released, ipam.Allocation{Prefix: device.OrganizationPrefixV6, Address: device.TunnelIpV6})
+		device.TunnelIpV6 = ip
+		device.OrganizationPrefixV6 = org.IpCidrV6
+	}
+	device.AllowedIPs, err = getAllowedIPs(device.TunnelIP, device.TunnelIpV6, device.Relay)
+	if err != nil {
+		return restore, err
+	}
+	return restore, tx.Model(device).
+		Select("tunnel_ip", "tunnel_ip_v6", "organization_prefix", "organization_prefix_v6", "allowed_ips").
+		Updates(device).Error
+}
+
+// growOrganizationCidr replaces the ipam prefix of an organization with a prefix that contains it, the
+// devices keep their addresses. prefixColumn and addressColumn are the device columns of the address family.
+// The returned function restores the ipam allocations of the previous prefix, even when it fails.
+func (api *API) growOrganizationCidr(ctx context.Context, tx *gorm.DB, orgId uuid.UUID, previous string, cidr string, prefixColumn string, addressColumn string) (func(), error) {
+	var released, acquired []string
+	var prefixReleased, prefixAssigned bool
+	restore := func() {
+		for _, address := range acquired {
+			api.logRestoreError(orgId, api.ipam.ReleaseToPool(ctx, orgId, address, cidr))
+		}
+		if prefixAssigned {
+			api.logRestoreError(orgId, api.ipam.ReleasePrefix(ctx, orgId, cidr))
+		}
+		if prefixReleased {
+			api.logRestoreError(orgId, api.ipam.AssignPrefix(ctx, orgId, previous))
+		}
+		for _, address := range released {
+			api.logRestoreError(orgId, api.ipam.AcquireIP(ctx, orgId, previous, address))
+		}
+	}
+
+	var addresses []string
+	if res := tx.Model(&models.Device{}).
+		Where(fmt.Sprintf("organization_id = ? AND %s = ?", prefixColumn), orgId, previous).
+		Pluck(addressColumn, &addresses); res.Error != nil {
+		return restore, res.Error
+	}
+	// the reserved addresses that no device uses are allocated too.
+	var reserved []string
+	if res := tx.Model(&models.IpReservation{}).
+		Where(fmt.Sprintf("organization_id = ? AND %s <> ''", addressColumn), orgId).
+		Pluck(addressColumn, &reserved); res.Error != nil {
+		return restore, res.Error
+	}
+	used := map[string]bool{}
+	for _, address := range addresses {
+		used[address] = true
+	}
+	for _, address := range reserved {
+		if !used[address] {
+			addresses = append(addresses, address)
+		}
+	}
+	for _, address := range addresses {
+		if err := api.ipam.ReleaseToPool(ctx, orgId, address, previous); err != nil {
+			return restore, fmt.Errorf("failed to release the address to pool: %w", err)
+		}
+		released = append(released, address)
+	}
+	if err := api.ipam.ReleasePrefix(ctx, orgId, previous); err != nil {
+		return restore, fmt.Errorf("failed to release the previous ipam prefix: %w", err)
+	}
+	prefixReleased = true
+	if err := api.ipam.AssignPrefix(ctx, orgId, cidr); err != nil {
+		return restore, fmt.Errorf("failed to assign the new ipam prefix: %w", err)
+	}
+	prefixAssigned = true
+	for _, address := range addresses {
+		if err := api.ipam.AcquireIP(ctx, orgId, cidr, address); err != nil {
+			return restore, fmt.Errorf("failed to acquire the address %s: %w", address, err)
+		}
+		acquired = append(acquired, address)
+	}
+	return restore, tx.Model(&models.Device{}).
+		Where(fmt.Sprintf("organization_id = ? AND %s = ?", prefixColumn), orgId, previous).
+		Update(prefixColumn, cidr).Error
+}
+
+// restoreIPAMAllocations runs the functions that restore the allocations the ipam made in a failed
+// transaction, in reverse order. The database ipam made them in the transaction, they are already rolled back.
+func (api *API) restoreIPAMAllocations(restores []func()) {
+	if _, ok := api.ipam.(*ipam.DatabaseIPAM); ok {
+		return
+	}
+	for i := len(restores) - 1; i >= 0; i-- {
+		restores[i]()
+	}
+}
+
+func (api *API) logRestoreError(orgId uuid.UUID, err error) {
+	if err != nil {
+		api.logger.Errorf("failed to restore the ipam allocations of organization %s: %v", orgId, err)
+	}
+}
+
+// resizeCidr validates the requested CIDR of an organization, and returns it in its canonical form
+// with how it changes the current CIDR. The current CIDR is kept when none is requested.
+func resizeCidr(current string, requested string, ipv6 bool) (string, cidrChange, error) {
+	if requested == "" {
+		return current, cidrUnchanged, nil
+	}
+	prefix, err := netip.ParsePrefix(requested)
+	if err != nil {
+		return "", cidrUnchanged, errors.New("must be a valid cidr")
+	}
+	if prefix.Addr().Is6() != ipv6 {
+		if ipv6 {
+			return "", cidrUnchanged, errors.New("must be an IPv6 cidr")
+		}
+		return "", cidrUnchanged, errors.New("must be an IPv4 cidr")
+	}
+	prefix = prefix.Masked()
+	currentPrefix, err := netip.ParsePrefix(current)
+	if err != nil {
+		return prefix.String(), cidrMoved, nil
+	}
+	currentPrefix = currentPrefix.Masked()
+	switch {
+	case prefix == currentPrefix:
+		return current, cidrUnchanged, nil
+	case prefix.Bits() < currentPrefix.Bits() && prefix.Contains(currentPrefix.Addr()):
+		return prefix.String(), cidrGrown, nil
+	case prefix.Overlaps(currentPrefix):
+		return "", cidrUnchanged, fmt.Errorf("must contain the current cidr %s or not overlap it", current)
+	}
+	return prefix.String(), cidrMoved, nil
+}
+
+// prefixCapacity returns the number of addresses of the prefix that can be allocated.
+func prefixCapacity(cidr string) uint64 {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return 0
+	}
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits >= 63 {
+		return 1 << 63
+	}
+	capacity := uint64(1) << hostBits
+	// the network address, and the broadcast address of IPv4 prefixes, are never allocated.
+	reserved := uint64(1)
+	if prefix.Addr().Is4() {
+		reserved = 2
+	}
+	if capacity <= reserved {
+		return 0
+	}
+	return capacity - reserved
+}
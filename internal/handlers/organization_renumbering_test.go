@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"gorm.io/gorm"
+)
+
+func (suite *HandlerTestSuite) TestResizeOrganization() {
+	require := suite.Require()
+	assert := suite.Assert()
+	ctx := context.Background()
+	suite.api.db.Exec("DELETE FROM organization_renumberings")
+
+	reqBody, err := json.Marshal(models.AddOrganization{
+		Name:        "renumbered",
+		PrivateCidr: true,
+		IpCidr:      "10.30.0.0/24",
+		IpCidrV6:    "fc30::/64",
+	})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateOrganization, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, res.Body.String())
+	var org models.Organization
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &org))
+
+	createDevice := func(hostname string) models.Device {
+		return suite.createDevice(models.AddDevice{
+			OrganizationID: org.ID,
+			PublicKey:      "renumbered-" + hostname,
+			Hostname:       hostname,
+		})
+	}
+	resize := func(orgId uuid.UUID, request models.ResizeOrganization) (int, models.OrganizationRenumbering) {
+		reqBody, err := json.Marshal(request)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/organizations/:organization/resize", fmt.Sprintf("/organizations/%s/resize", orgId),
+			suite.api.ResizeOrganization, bytes.NewBuffer(reqBody),
+		)
+		require.NoError(err)
+		var renumbering models.OrganizationRenumbering
+		if res.Code == http.StatusAccepted {
+			require.NoError(json.Unmarshal(res.Body.Bytes(), &renumbering))
+		}
+		return res.Code, renumbering
+	}
+	devicesOf := func() map[uuid.UUID]models.Device {
+		var devices []models.Device
+		require.NoError(suite.api.db.Where("organization_id = ?", org.ID).Find(&devices).Error)
+		result := map[uuid.UUID]models.Device{}
+		for _, device := range devices {
+			result[device.ID] = device
+		}
+		return result
+	}
+	inPrefix := func(cidr string, ip string) bool {
+		return netip.MustParsePrefix(cidr).Contains(netip.MustParseAddr(ip))
+	}
+
+	device1 := createDevice("device1")
+	device2 := createDevice("device2")
+	device3 := createDevice("device3")
+
+	code, _ := resize(suite.testOrganizationID, models.ResizeOrganization{Cidr: "10.0.0.0/8"})
+	assert.Equal(http.StatusBadRequest, code, "organizations without a private cidr can't be resized")
+	for _, request := range []models.ResizeOrganization{
+		{Cidr: "10.30.0.0/25"},
+		{Cidr: "10.30.0.0/16", BatchSize: -1},
+		{Cidr: "not-a-cidr"},
+		{Cidr: "fc40::/64"},
+		{CidrV6: "10.40.0.0/24"},
+		{Cidr: "10.30.0.0/24"},
+		{Cidr: "10.40.0.0/31"},
+	} {
+		code, _ := resize(org.ID, request)
+		assert.Equal(http.StatusBadRequest, code, request)
+	}
+
+	// growing the cidr keeps the addresses of the devices.
+	code, renumbering := resize(org.ID, models.ResizeOrganization{Cidr: "10.30.1.0/22"})
+	require.Equal(http.StatusAccepted, code)
+	assert.Equal(models.OrganizationRenumberingCompleted, renumbering.Status)
+	assert.Equal("10.30.0.0/24", renumbering.PreviousCidr)
+	assert.Equal("10.30.0.0/22", renumbering.Cidr)
+	for _, device := range []models.Device{device1, device2, device3} {
+		grown := devicesOf()[device.ID]
+		assert.Equal(device.TunnelIP, grown.TunnelIP)
+		assert.Equal("10.30.0.0/22", grown.OrganizationPrefix)
+	}
+	// the addresses of the devices are still allocated.
+	assert.Error(suite.api.ipam.AcquireIP(ctx, org.ID, "10.30.0.0/22", device1.TunnelIP))
+	// a resize that fails restores the allocations that the ipam made outside of the transaction.
+	require.NoError(suite.api.ipam.AssignPrefix(ctx, org.ID, "fc30:0:0:1::/64"))
+	code, _ = resize(org.ID, models.ResizeOrganization{Cidr: "10.30.0.0/21", CidrV6: "fc30::/48"})
+	assert.Equal(http.StatusInternalServerError, code)
+	require.NoError(suite.api.ipam.ReleasePrefix(ctx, org.ID, "fc30:0:0:1::/64"))
+	for _, prefix := range []string{"10.30.0.0/22", "fc30::/64"} {
+		count, found := suite.countAddresses(org.ID, prefix)
+		assert.True(found, prefix)
+		assert.Equal(3, count, prefix)
+	}
+	_, found := suite.countAddresses(org.ID, "10.30.0.0/21")
+	assert.False(found)
+	assert.Equal("10.30.0.0/22", devicesOf()[device1.ID].OrganizationPrefix)
+
+	device4 := createDevice("device4")
+	assert.True(inPrefix("10.30.0.0/22", device4.TunnelIP))
+
+	// moving the cidr gives the devices new addresses in batches.
+	before := devicesOf()
+	code, renumbering = resize(org.ID, models.ResizeOrganization{Cidr: "10.40.0.0/24", BatchSize: 3})
+	require.Equal(http.StatusAccepted, code)
+	assert.Equal(models.OrganizationRenumberingInProgress, renumbering.Status)
+	assert.Equal(4, renumbering.DevicesRemaining)
+	code, _ = resize(org.ID, models.ResizeOrganization{Cidr: "10.50.0.0/24"})
+	assert.Equal(http.StatusConflict, code, "an organization is renumbered once at a time")
+
+	// a batch that fails restores the allocations that the ipam made outside of the transaction.
+	allocated, _ := suite.countAddresses(org.ID, "10.30.0.0/22")
+	failUpdates := errors.New("update failed")
+	require.NoError(suite.api.db.Callback().Update().Before("gorm:update").Register("test:fail_updates", func(db *gorm.DB) {
+		if db.Statement.Table == "devices" {
+			_ = db.AddError(failUpdates)
+		}
+	}))
+	suite.api.renumberOrganizations(ctx)
+	require.NoError(suite.api.db.Callback().Update().Remove("test:fail_updates"))
+	count, _ := suite.countAddresses(org.ID, "10.30.0.0/22")
+	assert.Equal(allocated, count)
+	count, _ = suite.countAddresses(org.ID, "10.40.0.0/24")
+	assert.Equal(0, count)
+	assert.Equal(before, devicesOf())
+
+	suite.api.renumberOrganizations(ctx)
+	moved := 0
+	for _, device := range devicesOf() {
+		if inPrefix("10.40.0.0/24", device.TunnelIP) {
+			assert.Equal("10.40.0.0/24", device.OrganizationPrefix)
+			assert.Contains(device.AllowedIPs, device.TunnelIP+"/32")
+			moved++
+		}
+	}
+	assert.Equal(3, moved)
+	suite.api.renumberOrganizations(ctx)
+
+	addresses := map[string]bool{}
+	for _, device := range devicesOf() {
+		assert.True(inPrefix("10.40.0.0/24", device.TunnelIP), device.TunnelIP)
+		assert.False(addresses[device.TunnelIP], "the addresses are unique")
+		addresses[device.TunnelIP] = true
+		assert.Equal(before[device.ID].TunnelIpV6, device.TunnelIpV6, "the v6 cidr didn't change")
+	}
+	// the previous prefix was released.
+	assert.Error(suite.api.ipam.AcquireIP(ctx, org.ID, "10.30.0.0/22", "10.30.0.10"))
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet, "/organizations/:organization/renumberings", fmt.Sprintf("/organizations/%s/renumberings", org.ID),
+		suite.api.ListOrganizationRenumberings, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, res.Body.String())
+	var renumberings []models.OrganizationRenumbering
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &renumberings))
+	require.Len(renumberings, 2)
+	for _, r := range renumberings {
+		assert.Equal(models.OrganizationRenumberingCompleted, r.Status)
+		assert.Equal(0, r.DevicesRemaining)
+	}
+}
+
+// countAddresses returns the number of addresses allocated from the prefix, and false if the prefix
+// is not allocated, with either ipam backend.
+func (suite *HandlerTestSuite) countAddresses(namespace uuid.UUID, cidr string) (int, bool) {
+	ctx := context.Background()
+	if counter, ok := suite.api.ipam.(ipam.AddressCounter); ok {
+		count, found, err := counter.CountAddresses(ctx, namespace, cidr)
+		suite.Require().NoError(err)
+		return count, found
+	}
+	allocations, err := suite.api.ipam.(ipam.AllocationLister).ListAllocations(ctx)
+	suite.Require().NoError(err)
+	count, found := 0, false
+	for _, allocation := range allocations {
+		if allocation.Namespace != namespace || allocation.Prefix != cidr {
+			continue
+		}
+		if allocation.Address == "" {
+			found = true
+		} else {
+			count++
+		}
+	}
+	return count, found
+}
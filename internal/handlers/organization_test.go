@@ -2,12 +2,17 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/models"
+	"gorm.io/gorm"
 )
 
 func (suite *HandlerTestSuite) TestListOrganizations() {
@@ -197,3 +202,50 @@ func (suite *HandlerTestSuite) TestListOrganizations() {
 
 	}
 }
+
+func (suite *HandlerTestSuite) TestWatchOrganizationsSendsRemovedMemberships() {
+	assert := suite.Assert()
+	require := suite.Require()
+
+	require.NoError(suite.api.db.Create(&UserOrganization{
+		UserID:         TestUserID,
+		OrganizationID: suite.testUser2OrgID,
+		Role:           models.RoleMember,
+	}).Error)
+
+	// sqlite does not maintain the revisions, so set them here.
+	for i, orgId := range []uuid.UUID{suite.testOrganizationID, suite.testUser2OrgID} {
+		require.NoError(suite.api.db.Model(&models.Organization{}).Where("id = ?", orgId).UpdateColumn("revision", i+1).Error)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(gin.AuthUserKey, TestUserID)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scopes := []func(*gorm.DB) *gorm.DB{suite.api.OrganizationIsReadableByCurrentUser(c)}
+	getList := suite.api.watchedOrganizationList(ctx, suite.api.OrganizationIsReadableByCurrentUser(c), func(db *gorm.DB) (WatchableList, error) {
+		var items organizationList
+		return items, db.Find(&items).Error
+	})
+	nextEvent, closeWatch := suite.api.watchEvents(ctx, []string{organizationsSignal(TestUserID)}, "organizations", 0, scopes, getList)
+	defer closeWatch()
+
+	var watched []uuid.UUID
+	for event := nextEvent(); event.Type != "bookmark"; event = nextEvent() {
+		require.Equal("change", event.Type, event.Value)
+		watched = append(watched, event.Value.(*models.Organization).ID)
+	}
+	assert.ElementsMatch([]uuid.UUID{suite.testOrganizationID, suite.testUser2OrgID}, watched)
+
+	// leaving an organization doesn't delete it, but the watch sends it as deleted.
+	_, res, err := suite.ServeRequest(
+		http.MethodDelete,
+		"/:id/organizations/:organization", fmt.Sprintf("/%s/organizations/%s", TestUserID, suite.testUser2OrgID),
+		suite.api.DeleteUserFromOrganization, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, res.Body.String())
+	event := nextEvent()
+	assert.Equal("delete", event.Type)
+	assert.Equal(suite.testUser2OrgID, event.Value.(*models.Organization).ID)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	NextCursorHeader = "X-Next-Cursor"
+	// maxQueryLimit limits the number of items of a cursor paginated list.
+	maxQueryLimit = 1000
+	// queryCursorSetting is the gorm setting that holds the queryCursorPage of a query.
+	queryCursorSetting = "nexodus:cursor"
+)
+
+// exposedListHeaders are the list response headers that are exposed to browser clients.
+var exposedListHeaders = strings.Join([]string{TotalCountHeader, NextCursorHeader}, ", ")
+
+// queryCursor is the position in a list after which the next page starts. It holds the values
+// of the order and primary key columns of the last item of the previous page.
+type queryCursor struct {
+	Order  string        `json:"o"`
+	Values []interface{} `json:"v"`
+}
+
+// queryCursorPage tracks a cursor paginated query so that the next cursor can be set once the
+// items have been queried.
+type queryCursorPage struct {
+	c      *gin.Context
+	order  string
+	fields []*schema.Field
+	limit  int
+}
+
+func encodeQueryCursor(cursor queryCursor) (string, error) {
+	b, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeQueryCursor(s string) (queryCursor, error) {
+	cursor := queryCursor{}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, err
+	}
+	// decode the numbers as json.Number so that large integers keep their precision.
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	err = decoder.Decode(&cursor)
+	return cursor, err
+}
+
+// queryCursorScope orders the query by the order field and then the primary keys, so that the
+// items have a stable order even when new items are inserted. It limits the query to the items
+// after the cursor, and to at most limit items.
+func queryCursorScope(db *gorm.DB, c *gin.Context, s *schema.Schema, orderField *schema.Field, desc bool, limit string, cursor string) (*gorm.DB, error) {
+	pageSize := maxQueryLimit
+	if limit != "" {
+		var err error
+		pageSize, err = strconv.Atoi(limit)
+		if err != nil || pageSize < 1 || pageSize > maxQueryLimit {
+			return db, errInvalidQuery{Param: "limit", Reason: fmt.Sprintf("must be a number between 1 and %d", maxQueryLimit)}
+		}
+	}
+
+	fields := []*schema.Field{orderField}
+	for _, field := range s.PrimaryFields {
+		if field != orderField {
+			fields = append(fields, field)
+		}
+	}
+	order := orderField.DBName
+	if desc {
+		order += " DESC"
+	}
+
+	var columns []interface{}
+	for _, field := range fields {
+		column := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+		db = db.Order(clause.OrderByColumn{Column: column, Desc: desc})
+		columns = append(columns, column)
+	}
+
+	if cursor != "" {
+		position, err := decodeQueryCursor(cursor)
+		if err != nil || len(position.Values) != len(fields) {
+			return db, errInvalidQuery{Param: "cursor", Reason: "invalid cursor"}
+		}
+		if position.Order != order {
+			return db, errInvalidQuery{Param: "cursor", Reason: "the cursor is for a different sort order"}
+		}
+		values := make([]interface{}, len(fields))
+		for i, field := range fields {
+			v, err := parseQueryValueOf(field, fmt.Sprint(position.Values[i]))
+			if err != nil {
+				return db, errInvalidQuery{Param: "cursor", Reason: "invalid cursor"}
+			}
+			values[i] = v
+		}
+		// compare the rows, (a, b) > (x, y) is a > x OR (a = x AND b > y).
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(fields)), ", ")
+		db = db.Where(clause.Expr{
+			SQL:  fmt.Sprintf("(%s) %s (%s)", placeholders, op, placeholders),
+			Vars: append(columns, values...),
+		})
+	}
+
+	return db.Limit(pageSize).Set(queryCursorSetting, &queryCursorPage{
+		c:      c,
+		order:  order,
+		fields: fields,
+		limit:  pageSize,
+	}), nil
+}
+
+// registerQueryCursorCallback sets the next cursor header after the queries of full pages.
+func registerQueryCursorCallback(db *gorm.DB) error {
+	if db.Callback().Query().Get(queryCursorSetting) != nil {
+		return nil
+	}
+	return db.Callback().Query().After("gorm:query").Register(queryCursorSetting, setNextQueryCursor)
+}
+
+func setNextQueryCursor(db *gorm.DB) {
+	v, ok := db.Get(queryCursorSetting)
+	if !ok || db.Error != nil {
+		return
+	}
+	page := v.(*queryCursorPage)
+	items := db.Statement.ReflectValue
+	if items.Kind() != reflect.Slice || items.Len() < page.limit {
+		return
+	}
+	last := items.Index(items.Len() - 1)
+	cursor := queryCursor{Order: page.order}
+	for _, field := range page.fields {
+		value, _ := field.ValueOf(db.Statement.Context, last)
+		if s, ok := value.(fmt.Stringer); ok && queryFieldKind(field) != reflect.Struct {
+			value = s.String()
+		}
+		cursor.Values = append(cursor.Values, value)
+	}
+	next, err := encodeQueryCursor(cursor)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	page.c.Header("Access-Control-Expose-Headers", exposedListHeaders)
+	page.c.Header(NextCursorHeader, next)
+}
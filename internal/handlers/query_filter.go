@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// errInvalidQuery is returned by the list queries when a query parameter is invalid.
+type errInvalidQuery struct {
+	Param  string
+	Reason string
+}
+
+func (e errInvalidQuery) Error() string {
+	return fmt.Sprintf("invalid %s query parameter: %s", e.Param, e.Reason)
+}
+
+// sendListError responds to an error of a list query, invalid query parameters are bad requests.
+func sendListError(c *gin.Context, err error) {
+	var queryErr errInvalidQuery
+	if errors.As(err, &queryErr) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError(queryErr.Param, queryErr.Reason))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+}
+
+// queryOperators are the comparison operators of the q query parameter, longest first.
+var queryOperators = []string{">=", "<=", "!=", "^=", "=", ">", "<"}
+
+// queryTerm is one of the comma separated terms of the q query parameter.
+type queryTerm struct {
+	Field    string
+	Operator string
+	Values   []string
+}
+
+// parseQueryTerms parses the q query parameter, a comma separated list of terms that must all match:
+//
+//	hostname^=lab-,revision>100,os in (linux,darwin),created_at>=2023-06-01T00:00:00Z
+//
+// The operators are =, !=, >, >=, <, <=, ^= (starts with), in (...) and notin (...). Values
+// must be double quoted to include commas, parentheses or spaces.
+func parseQueryTerms(q string) ([]queryTerm, error) {
+	var terms []queryTerm
+	rest := strings.TrimSpace(q)
+	for rest != "" {
+		term, remaining, err := parseQueryTerm(rest)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		rest = strings.TrimSpace(remaining)
+		if rest == "" {
+			break
+		}
+		if rest[0] != ',' {
+			return nil, fmt.Errorf("expected a comma after %s", term.Field)
+		}
+		rest = strings.TrimSpace(rest[1:])
+		if rest == "" {
+			return nil, fmt.Errorf("expected a term after the last comma")
+		}
+	}
+	return terms, nil
+}
+
+func parseQueryTerm(s string) (queryTerm, string, error) {
+	term := queryTerm{}
+	i := 0
+	for i < len(s) && (s[i] == '_' || s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z' || s[i] >= '0' && s[i] <= '9') {
+		i++
+	}
+	if i == 0 {
+		return term, "", fmt.Errorf("expected a field name at %q", s)
+	}
+	term.Field = s[:i]
+	s = strings.TrimLeft(s[i:], " ")
+
+	for _, op := range queryOperators {
+		if strings.HasPrefix(s, op) {
+			term.Operator = op
+			value, rest, err := parseQueryValue(strings.TrimLeft(s[len(op):], " "), ",")
+			if err != nil {
+				return term, "", err
+			}
+			term.Values = []string{value}
+			return term, rest, nil
+		}
+	}
+
+	for _, op := range []string{"notin", "in"} {
+		if strings.HasPrefix(s, op) {
+			s = strings.TrimLeft(s[len(op):], " ")
+			if !strings.HasPrefix(s, "(") {
+				return term, "", fmt.Errorf("expected a list of values after %s %s", term.Field, op)
+			}
+			term.Operator = op
+			s = s[1:]
+			for {
+				value, rest, err := parseQueryValue(strings.TrimLeft(s, " "), ",)")
+				if err != nil {
+					return term, "", err
+				}
+				term.Values = append(term.Values, value)
+				rest = strings.TrimLeft(rest, " ")
+				if strings.HasPrefix(rest, ")") {
+					return term, rest[1:], nil
+				}
+				if !strings.HasPrefix(rest, ",") {
+					return term, "", fmt.Errorf("unterminated list of values for %s", term.Field)
+				}
+				s = rest[1:]
+			}
+		}
+	}
+	return term, "", fmt.Errorf("expected an operator after %s", term.Field)
+}
+
+// parseQueryValue parses a double quoted or a bare value that ends at a space or one of the terminators.
+func parseQueryValue(s string, terminators string) (string, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		end := 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			return "", "", fmt.Errorf("unterminated quoted value %s", s)
+		}
+		var value string
+		if err := json.Unmarshal([]byte(s[:end+1]), &value); err != nil {
+			return "", "", fmt.Errorf("invalid quoted value %s", s[:end+1])
+		}
+		return value, s[end+1:], nil
+	}
+	end := strings.IndexAny(s, terminators+" ")
+	if end < 0 {
+		end = len(s)
+	}
+	return s[:end], s[end:], nil
+}
+
+var querySchemaCache = &sync.Map{}
+
+// queryField returns the field of the model that can be used in a query, by its json or column name.
+// The fields excluded from the json of the model can't be used, except the creation and update times.
+func queryField(s *schema.Schema, name string) (*schema.Field, error) {
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		jsonName, tagged := field.Tag.Lookup("json")
+		jsonName = strings.Split(jsonName, ",")[0]
+		matches := tagged && jsonName == name
+		if field.DBName == name && (!tagged || field.Name == "CreatedAt" || field.Name == "UpdatedAt") {
+			matches = true
+		}
+		if matches {
+			if field.Serializer == nil && queryFieldKind(field) != reflect.Invalid {
+				return field, nil
+			}
+			return nil, fmt.Errorf("%s can't be used in a query", name)
+		}
+	}
+	return nil, fmt.Errorf("unknown field %s", name)
+}
+
+var (
+	uuidType = reflect.TypeOf(uuid.UUID{})
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// queryFieldKind returns the kind of the values of the field that can be used in a query, uuids and
+// times are reported as reflect.Array and reflect.Struct, other fields as reflect.Invalid.
+func queryFieldKind(field *schema.Field) reflect.Kind {
+	t := field.IndirectFieldType
+	switch {
+	case t == uuidType:
+		return reflect.Array
+	case t == timeType:
+		return reflect.Struct
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64:
+		return t.Kind()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int64
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.Uint64
+	}
+	return reflect.Invalid
+}
+
+// parseQueryValueOf converts the value to the type of the field.
+func parseQueryValueOf(field *schema.Field, value string) (interface{}, error) {
+	switch queryFieldKind(field) {
+	case reflect.Array:
+		return uuid.Parse(value)
+	case reflect.Struct:
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02", value)
+	case reflect.Bool:
+		return strconv.ParseBool(value)
+	case reflect.Int64:
+		return strconv.ParseInt(value, 10, 64)
+	case reflect.Uint64:
+		return strconv.ParseUint(value, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(value, 64)
+	}
+	return value, nil
+}
+
+// queryTermsScope returns the conditions of the terms of the q query parameter.
+func queryTermsScope(s *schema.Schema, q string) ([]clause.Expression, error) {
+	terms, err := parseQueryTerms(q)
+	if err != nil {
+		return nil, errInvalidQuery{Param: "q", Reason: err.Error()}
+	}
+	var exprs []clause.Expression
+	for _, term := range terms {
+		field, err := queryField(s, term.Field)
+		if err != nil {
+			return nil, errInvalidQuery{Param: "q", Reason: err.Error()}
+		}
+		var values []interface{}
+		for _, value := range term.Values {
+			v, err := parseQueryValueOf(field, value)
+			if err != nil {
+				return nil, errInvalidQuery{Param: "q", Reason: fmt.Sprintf("invalid value %q for %s", value, term.Field)}
+			}
+			values = append(values, v)
+		}
+		column := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+		switch term.Operator {
+		case "=":
+			exprs = append(exprs, clause.Eq{Column: column, Value: values[0]})
+		case "!=":
+			exprs = append(exprs, clause.Neq{Column: column, Value: values[0]})
+		case ">":
+			exprs = append(exprs, clause.Gt{Column: column, Value: values[0]})
+		case ">=":
+			exprs = append(exprs, clause.Gte{Column: column, Value: values[0]})
+		case "<":
+			exprs = append(exprs, clause.Lt{Column: column, Value: values[0]})
+		case "<=":
+			exprs = append(exprs, clause.Lte{Column: column, Value: values[0]})
+		case "in":
+			exprs = append(exprs, clause.IN{Column: column, Values: values})
+		case "notin":
+			exprs = append(exprs, clause.Not(clause.IN{Column: column, Values: values}))
+		case "^=":
+			if queryFieldKind(field) != reflect.String {
+				return nil, errInvalidQuery{Param: "q", Reason: fmt.Sprintf("%s is not a string", term.Field)}
+			}
+			exprs = append(exprs, clause.Expr{
+				SQL:  `? LIKE ? ESCAPE '\'`,
+				Vars: []interface{}{column, escapeLike(term.Values[0]) + "%"},
+			})
+		}
+	}
+	return exprs, nil
+}
+
+// escapeLike escapes the characters that have a special meaning in a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// queryOrder parses an order like "hostname" or "created_at DESC" of the fields of the model.
+func queryOrder(s *schema.Schema, order string) (*schema.Field, bool, error) {
+	parts := strings.Fields(order)
+	if len(parts) == 0 || len(parts) > 2 {
+		return nil, false, fmt.Errorf("invalid order %q", order)
+	}
+	desc := false
+	if len(parts) == 2 {
+		switch strings.ToUpper(parts[1]) {
+		case "ASC":
+		case "DESC":
+			desc = true
+		default:
+			return nil, false, fmt.Errorf("invalid order direction %q", parts[1])
+		}
+	}
+	field, err := queryField(s, parts[0])
+	if err != nil {
+		return nil, false, err
+	}
+	return field, desc, nil
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQueryTerms(t *testing.T) {
+	terms, err := parseQueryTerms(`hostname^=lab-, revision>100,os in (linux, "dar,win"),created_at>=2023-06-01,name notin ( a )`)
+	assert.NoError(t, err)
+	assert.Equal(t, []queryTerm{
+		{Field: "hostname", Operator: "^=", Values: []string{"lab-"}},
+		{Field: "revision", Operator: ">", Values: []string{"100"}},
+		{Field: "os", Operator: "in", Values: []string{"linux", "dar,win"}},
+		{Field: "created_at", Operator: ">=", Values: []string{"2023-06-01"}},
+		{Field: "name", Operator: "notin", Values: []string{"a"}},
+	}, terms)
+
+	for _, invalid := range []string{"hostname=a,", "hostname", "=a", "os in linux", "os in (a,b", `hostname="a`, "a=b c=d"} {
+		_, err = parseQueryTerms(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestListQuery() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	// sqlite does not maintain the revisions, so set them here.
+	for i, d := range []struct{ hostname, os string }{{"h1", "linux"}, {"h2", "darwin"}, {"h3", "linux"}, {"h4", "windows"}, {"h5", "linux"}} {
+		device := suite.createDevice(models.AddDevice{
+			OrganizationID: suite.testOrganizationID,
+			PublicKey:      "query-" + d.hostname,
+			Hostname:       d.hostname,
+			Os:             d.os,
+		})
+		require.NoError(suite.api.db.Model(&device).UpdateColumn("revision", i+1).Error)
+	}
+
+	listDevices := func(params url.Values) ([]string, string, int) {
+		_, res, err := suite.ServeRequest(
+			http.MethodGet, "/organizations/:organization/devices",
+			fmt.Sprintf("/organizations/%s/devices?%s", suite.testOrganizationID, params.Encode()),
+			suite.api.ListDevicesInOrganization, nil,
+		)
+		require.NoError(err)
+		var devices []models.Device
+		_ = json.Unmarshal(res.Body.Bytes(), &devices)
+		var hostnames []string
+		for _, device := range devices {
+			hostnames = append(hostnames, device.Hostname)
+		}
+		return hostnames, res.Header().Get(NextCursorHeader), res.Code
+	}
+
+	for q, expected := range map[string][]string{
+		"hostname^=h":                  {"h1", "h2", "h3", "h4", "h5"},
+		"os in (darwin, windows)":      {"h2", "h4"},
+		"os notin (linux)":             {"h2", "h4"},
+		"revision>2,revision<=4":       {"h3", "h4"},
+		"hostname!=h1,os=linux":        {"h3", "h5"},
+		"created_at>=2023-06-01":       {"h1", "h2", "h3", "h4", "h5"},
+		"created_at<2023-06-01":        nil,
+		`hostname="h2"`:                {"h2"},
+		"hostname^=h_":                 nil,
+		"os=linux,hostname in (h1,h2)": {"h1"},
+	} {
+		hostnames, _, code := listDevices(url.Values{"q": {q}})
+		require.Equal(http.StatusOK, code, q)
+		assert.Equal(expected, hostnames, q)
+	}
+	for _, q := range []string{"unknown=1", "revision>abc", "labels=a", "revision^=1", "hostname=h1,"} {
+		_, _, code := listDevices(url.Values{"q": {q}})
+		assert.Equal(http.StatusBadRequest, code, q)
+	}
+	_, _, code := listDevices(url.Values{"sort": {`["unknown","ASC"]`}})
+	assert.Equal(http.StatusBadRequest, code)
+
+	// the cursor pages stay stable when devices are added between the requests.
+	hostnames, cursor, code := listDevices(url.Values{"limit": {"2"}})
+	require.Equal(http.StatusOK, code)
+	assert.Equal([]string{"h1", "h2"}, hostnames)
+	require.NotEmpty(cursor)
+	for _, hostname := range []string{"h0", "h9"} {
+		suite.createDevice(models.AddDevice{
+			OrganizationID: suite.testOrganizationID,
+			PublicKey:      "query-" + hostname,
+			Hostname:       hostname,
+			Os:             "linux",
+		})
+	}
+	hostnames, cursor, code = listDevices(url.Values{"limit": {"2"}, "cursor": {cursor}})
+	require.Equal(http.StatusOK, code)
+	assert.Equal([]string{"h3", "h4"}, hostnames)
+	hostnames, cursor, code = listDevices(url.Values{"limit": {"2"}, "cursor": {cursor}})
+	require.Equal(http.StatusOK, code)
+	assert.Equal([]string{"h5", "h9"}, hostnames)
+	hostnames, cursor, code = listDevices(url.Values{"limit": {"2"}, "cursor": {cursor}})
+	require.Equal(http.StatusOK, code)
+	assert.Empty(hostnames)
+	assert.Empty(cursor)
+
+	// the cursor also works with a descending sort on a time field and a query.
+	params := url.Values{"limit": {"2"}, "sort": {`["created_at","DESC"]`}, "q": {"os=linux"}}
+	hostnames, cursor, code = listDevices(params)
+	require.Equal(http.StatusOK, code)
+	assert.Equal([]string{"h9", "h0"}, hostnames)
+	params.Set("cursor", cursor)
+	hostnames, cursor, code = listDevices(params)
+	require.Equal(http.StatusOK, code)
+	assert.Equal([]string{"h5", "h3"}, hostnames)
+
+	_, _, code = listDevices(url.Values{"limit": {"2"}, "cursor": {cursor}})
+	assert.Equal(http.StatusBadRequest, code, "the cursor of a different sort order")
+	_, _, code = listDevices(url.Values{"cursor": {"invalid"}})
+	assert.Equal(http.StatusBadRequest, code)
+	_, _, code = listDevices(url.Values{"limit": {"0"}})
+	assert.Equal(http.StatusBadRequest, code)
+	_, _, code = listDevices(url.Values{"limit": {"2"}, "range": {"[0,1]"}})
+	assert.Equal(http.StatusBadRequest, code)
+}
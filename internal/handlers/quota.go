@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	quotaDevicesPerOrganization = "devices_per_organization"
+	quotaOrganizationsPerUser   = "organizations_per_user"
+	quotaMetadataKeysPerDevice  = "metadata_keys_per_device"
+)
+
+// Quotas limit the number of resources that can be created, a quota of 0 is unlimited.
+type Quotas struct {
+	DevicesPerOrganization int
+	// OrganizationsPerUser limits the organizations owned by each user.
+	OrganizationsPerUser  int
+	MetadataKeysPerDevice int
+}
+
+// SetQuotas sets the quotas that are enforced when resources are created.
+func (api *API) SetQuotas(quotas Quotas) {
+	api.quotas = quotas
+}
+
+type errQuotaExceeded struct {
+	Quota string
+	Limit int
+}
+
+func (e errQuotaExceeded) Error() string {
+	return fmt.Sprintf("the %s quota of %d has been reached", e.Quota, e.Limit)
+}
+
+func (e errQuotaExceeded) Response() models.QuotaExceededError {
+	return models.NewQuotaExceededError(e.Quota, e.Limit)
+}
+
+// checkQuota returns an errQuotaExceeded if the number of rows of model that match the query
+// has reached the limit. The row of the owner of the rows is locked before they are counted, so
+// that concurrent transactions adding rows for the same owner can't all pass the check.
+func checkQuota(tx *gorm.DB, quota string, limit int, owner interface{}, ownerId interface{}, model interface{}, query string, args ...interface{}) error {
+	if limit <= 0 {
+		return nil
+	}
+	if res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(owner, "id = ?", ownerId); res.Error != nil {
+		return res.Error
+	}
+	var count int64
+	if res := tx.Model(model).Where(query, args...).Count(&count); res.Error != nil {
+		return res.Error
+	}
+	if count >= int64(limit) {
+		return errQuotaExceeded{Quota: quota, Limit: limit}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestQuotas() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	suite.api.SetQuotas(Quotas{
+		DevicesPerOrganization: 1,
+		OrganizationsPerUser:   1,
+		MetadataKeysPerDevice:  1,
+	})
+	defer suite.api.SetQuotas(Quotas{})
+
+	createDeviceIn := func(orgId uuid.UUID, publicKey string) (*models.Device, int, []byte) {
+		reqBody, err := json.Marshal(models.AddDevice{
+			OrganizationID: orgId,
+			PublicKey:      publicKey,
+		})
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		var device models.Device
+		_ = json.Unmarshal(res.Body.Bytes(), &device)
+		return &device, res.Code, res.Body.Bytes()
+	}
+	createDevice := func(publicKey string) (*models.Device, int, []byte) {
+		return createDeviceIn(suite.testOrganizationID, publicKey)
+	}
+	assertQuotaExceeded := func(code int, body []byte, quota string) {
+		require.Equal(http.StatusForbidden, code, string(body))
+		var quotaErr models.QuotaExceededError
+		require.NoError(json.Unmarshal(body, &quotaErr))
+		assert.Equal("quota exceeded", quotaErr.Error)
+		assert.Equal(quota, quotaErr.Quota)
+		assert.Equal(1, quotaErr.Limit)
+	}
+
+	device, code, body := createDevice("quota-device-1")
+	require.Equal(http.StatusCreated, code, string(body))
+	_, code, body = createDevice("quota-device-2")
+	assertQuotaExceeded(code, body, quotaDevicesPerOrganization)
+
+	// moving a device into the organization counts against the quota too.
+	require.NoError(suite.api.db.Create(&UserOrganization{
+		UserID:         TestUserID,
+		OrganizationID: suite.testUser2OrgID,
+		Role:           models.RoleMember,
+	}).Error)
+	other, code, body := createDeviceIn(suite.testUser2OrgID, "quota-device-3")
+	require.Equal(http.StatusCreated, code, string(body))
+	reqBody, err := json.Marshal(models.UpdateDevice{OrganizationID: suite.testOrganizationID})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(
+		http.MethodPatch, "/:id", fmt.Sprintf("/%s", other.ID),
+		suite.api.UpdateDevice, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	assertQuotaExceeded(res.Code, res.Body.Bytes(), quotaDevicesPerOrganization)
+
+	reqBody, err = json.Marshal(models.DeviceBatchMove{
+		Selector:       models.DeviceSelector{IDs: []uuid.UUID{other.ID}},
+		OrganizationID: suite.testOrganizationID,
+	})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(http.MethodPost, "/", "/", suite.api.BatchMoveDevices, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	require.Equal(http.StatusUnprocessableEntity, res.Code, res.Body.String())
+	var batchResult models.DeviceBatchResult
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &batchResult))
+	require.Len(batchResult.Results, 1)
+	assert.Equal(http.StatusForbidden, batchResult.Results[0].Status)
+
+	// the test user already owns its default organization.
+	reqBody, err = json.Marshal(models.AddOrganization{
+		Name:        "quota-organization",
+		PrivateCidr: true,
+		IpCidr:      "10.1.1.0/24",
+		IpCidrV6:    "fc00::/20",
+	})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateOrganization, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	assertQuotaExceeded(res.Code, res.Body.Bytes(), quotaOrganizationsPerUser)
+
+	setMetadata := func(key string) *httptest.ResponseRecorder {
+		_, res, err := suite.ServeRequest(
+			http.MethodPut, "/:id/metadata/:key", fmt.Sprintf("/%s/metadata/%s", device.ID, key),
+			suite.api.UpdateDeviceMetadataKey, bytes.NewBufferString(`{"value": 1}`),
+		)
+		require.NoError(err)
+		return res
+	}
+	res = setMetadata("a")
+	require.Equal(http.StatusOK, res.Code, res.Body.String())
+	// updating an existing key does not count against the quota.
+	res = setMetadata("a")
+	require.Equal(http.StatusOK, res.Code, res.Body.String())
+	res = setMetadata("b")
+	assertQuotaExceeded(res.Code, res.Body.Bytes(), quotaMetadataKeysPerDevice)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+// rateLimitWindow is the length of the fixed windows that the requests are counted in.
+const rateLimitWindow = time.Minute
+
+// RateLimits configures the RateLimit middleware, a limit of 0 disables it.
+type RateLimits struct {
+	// UserRequestsPerMinute limits the requests of each user.
+	UserRequestsPerMinute int
+	// OrganizationRequestsPerMinute limits the requests to the apis of each organization, across all its users.
+	OrganizationRequestsPerMinute int
+}
+
+// RateLimit returns a middleware that responds with a 429 to the requests that exceed the limits.
+// The requests are counted in redis so that the limits are shared by all the apiserver replicas.
+func (api *API) RateLimit(limits RateLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		if limits.UserRequestsPerMinute > 0 {
+			if userId := c.GetString(gin.AuthUserKey); userId != "" {
+				if !api.allowRequest(c, now, "user", userId, limits.UserRequestsPerMinute) {
+					return
+				}
+			}
+		}
+		if limits.OrganizationRequestsPerMinute > 0 {
+			if orgId := requestOrganizationId(c); orgId != uuid.Nil {
+				if !api.allowRequest(c, now, "organization", orgId.String(), limits.OrganizationRequestsPerMinute) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// requestOrganizationId returns the organization that the request is made to, or uuid.Nil if it is not known.
+func requestOrganizationId(c *gin.Context) uuid.UUID {
+	if orgId, err := uuid.Parse(c.Param("organization")); err == nil {
+		return orgId
+	}
+	if regKey, ok := RegKeyFromContext(c); ok {
+		return regKey.OrganizationID
+	}
+	return uuid.Nil
+}
+
+// allowRequest counts the request against the current window of the scope, and aborts it with a 429 if
+// the limit has been exceeded.
+func (api *API) allowRequest(c *gin.Context, now time.Time, scope string, id string, limit int) bool {
+	ctx := c.Request.Context()
+	window := now.Truncate(rateLimitWindow)
+	key := fmt.Sprintf("ratelimit:%s:%s:%d", scope, id, window.Unix())
+
+	pipe := api.redis.TxPipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, 2*rateLimitWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		// don't fail the requests when redis is unavailable.
+		api.Logger(ctx).Warnf("failed to check the %s rate limit: %s", scope, err)
+		return true
+	}
+
+	if count.Val() <= int64(limit) {
+		return true
+	}
+	retryAfter := int(math.Ceil(window.Add(rateLimitWindow).Sub(now).Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, models.NewTooManyRequestsError(scope, limit, retryAfter))
+	return false
+}
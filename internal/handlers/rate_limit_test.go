@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+func (suite *HandlerTestSuite) TestRateLimit() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	server := miniredis.RunT(suite.T())
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	previous := suite.api.redis
+	suite.api.redis = client
+	defer func() {
+		suite.api.redis = previous
+	}()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+	r.Use(suite.api.RateLimit(RateLimits{
+		UserRequestsPerMinute:         2,
+		OrganizationRequestsPerMinute: 3,
+	}))
+	r.GET("/organizations/:organization", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	serve := func(userId string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/organizations/%s", suite.testOrganizationID), nil)
+		require.NoError(err)
+		req.Header.Set("X-Test-User", userId)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+	assertLimited := func(res *httptest.ResponseRecorder, scope string, limit int) {
+		require.Equal(http.StatusTooManyRequests, res.Code)
+		assert.NotEmpty(res.Header().Get("Retry-After"))
+		var limitErr models.TooManyRequestsError
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &limitErr))
+		assert.Equal("too many requests", limitErr.Error)
+		assert.Equal(scope, limitErr.Scope)
+		assert.Equal(limit, limitErr.Limit)
+		assert.Greater(limitErr.RetryAfter, 0)
+	}
+
+	require.Equal(http.StatusOK, serve(TestUserID).Code)
+	require.Equal(http.StatusOK, serve(TestUserID).Code)
+	assertLimited(serve(TestUserID), "user", 2)
+
+	// other users share the limit of the organization.
+	require.Equal(http.StatusOK, serve(TestUser2ID).Code)
+	assertLimited(serve(TestUser2ID), "organization", 3)
+
+	// the counts expire after their window.
+	server.FastForward(2 * rateLimitWindow)
+	require.Equal(http.StatusOK, serve(TestUserID).Code)
+}
@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// RegKeyPrefix is prepended to registration key bearer tokens so they can be told apart from OIDC tokens
+const RegKeyPrefix = "RK:"
+
+// key for the registration key used to authenticate the request in gin.Context
+const AuthRegKey string = "_nexodus.RegKey"
+
+var errRegKeyNotFound = errors.New("registration key not found")
+var errRegKeyUsed = errors.New("registration key has already been used")
+
+// CreateRegKey creates a registration key
+// @Summary      Create a registration key
+// @Description  Creates a registration key that devices can use to join the organization without an interactive login
+// @Id           CreateRegKey
+// @Tags         RegKey
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string            true  "Organization ID"
+// @Param        RegKey           body   models.AddRegKey  true  "Add RegKey"
+// @Success      201  {object}  models.RegKey
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/reg_keys [post]
+func (api *API) CreateRegKey(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateRegKey", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.AddRegKey
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.ExpiresAt != nil && request.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("expires_at", "must be in the future"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	token, err := newBearerToken(RegKeyPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+
+	regKey := models.RegKey{
+		OwnerID:        c.GetString(gin.AuthUserKey),
+		OrganizationID: org.ID,
+		Description:    request.Description,
+		TokenHash:      hashBearerToken(token),
+		SingleUse:      request.SingleUse,
+		Ephemeral:      request.Ephemeral,
+		Tags:           request.Tags,
+		ExpiresAt:      request.ExpiresAt,
+	}
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Create(&regKey); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionCreate, "reg_key", regKey.ID.String(), nil, regKey)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	span.SetAttributes(attribute.String("id", regKey.ID.String()))
+
+	// The token is only ever returned in this response.
+	regKey.BearerToken = token
+	c.JSON(http.StatusCreated, regKey)
+}
+
+// ListRegKeys lists the registration keys of an organization
+// @Summary      List registration keys
+// @Description  Lists all registration keys in an organization
+// @Id           ListRegKeys
+// @Tags         RegKey
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        q               query  string  false  "conditions on the fields, for example single_use=true,expires_at<2023-07-01"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
+// @Success      200  {object}  []models.RegKey
+// @Failure		 400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/reg_keys [get]
+func (api *API) ListRegKeys(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListRegKeys", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	regKeys := make([]models.RegKey, 0)
+	result := api.db.WithContext(ctx).
+		Where("organization_id = ?", org.ID).
+		Scopes(FilterAndPaginate(&models.RegKey{}, c, "created_at")).
+		Find(&regKeys)
+	if result.Error != nil {
+		sendListError(c, result.Error)
+		return
+	}
+	c.JSON(http.StatusOK, regKeys)
+}
+
+// DeleteRegKey revokes a registration key
+// @Summary      Delete a registration key
+// @Description  Revokes a registration key so it can no longer be used by devices
+// @Id           DeleteRegKey
+// @Tags         RegKey
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        id               path   string  true  "RegKey ID"
+// @Success      200  {object}  models.RegKey
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/reg_keys/{id} [delete]
+func (api *API) DeleteRegKey(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteRegKey", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var regKey models.RegKey
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			return errOrgNotFound
+		}
+		if res := tx.Where("organization_id = ?", org.ID).
+			First(&regKey, "id = ?", id); res.Error != nil {
+			return errRegKeyNotFound
+		}
+		if res := tx.Delete(&regKey); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionDelete, "reg_key", regKey.ID.String(), regKey, nil)
+	})
+	if err != nil {
+		if errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errRegKeyNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("reg_key"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, regKey)
+}
+
+// RegKeyAuth authenticates a request that uses a registration key as its bearer token.  The
+// request is handled on behalf of the key owner, but is restricted to the key's organization
+// and to the operations a device agent needs.
+func (api *API) RegKeyAuth(c *gin.Context, token string) {
+	ctx, span := tracer.Start(c.Request.Context(), "RegKeyAuth")
+	defer span.End()
+
+	var regKey models.RegKey
+	if res := api.db.WithContext(ctx).
+		First(&regKey, "token_hash = ?", hashBearerToken(token)); res.Error != nil {
+		if !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			api.Logger(ctx).Error(res.Error)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if regKey.IsExpired() {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	// the keys of the users deactivated by the identity provider stop working with them.
+	var deactivated int64
+	if res := api.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND deactivated = ?", regKey.OwnerID, true).
+		Count(&deactivated); res.Error != nil {
+		api.Logger(ctx).Error(res.Error)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if deactivated > 0 {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.Split(strings.Trim(c.Request.URL.Path, "/"), "/")
+	if !regKeyAllowed(&regKey, c.Request.Method, path) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	c.Set(gin.AuthUserKey, regKey.OwnerID)
+	c.Set(AuthRegKey, &regKey)
+}
+
+// regKeyRoute is an api call that registration keys can make. The ":organization" segment only
+// matches the organization of the key, and ":user" only matches "me" or the owner of the key.
+type regKeyRoute struct {
+	method string
+	path   []string
+}
+
+// regKeyRoutes are the api calls made by nexd, the only ones allowed with registration keys.
+var regKeyRoutes = []regKeyRoute{
+	{http.MethodPost, []string{"api", "devices"}},
+	{http.MethodGet, []string{"api", "devices", ":id"}},
+	{http.MethodPatch, []string{"api", "devices", ":id"}},
+	{http.MethodDelete, []string{"api", "devices", ":id"}},
+	{http.MethodGet, []string{"api", "devices", ":id", "peered_devices"}},
+	{http.MethodPost, []string{"api", "devices", ":id", "heartbeat"}},
+	{http.MethodGet, []string{"api", "organizations"}},
+	{http.MethodGet, []string{"api", "organizations", ":organization", "devices"}},
+	{http.MethodGet, []string{"api", "organizations", ":organization", "security_groups"}},
+	{http.MethodGet, []string{"api", "users", ":user"}},
+}
+
+// regKeyAllowed limits registration keys to the api calls used by nexd, in their organization.
+func regKeyAllowed(regKey *models.RegKey, method string, path []string) bool {
+	for _, route := range regKeyRoutes {
+		if route.method == method && regKeyRouteMatches(regKey, route.path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func regKeyRouteMatches(regKey *models.RegKey, route []string, path []string) bool {
+	if len(route) != len(path) {
+		return false
+	}
+	for i, segment := range route {
+		switch segment {
+		case ":id":
+			if path[i] == "" {
+				return false
+			}
+		case ":organization":
+			if path[i] != regKey.OrganizationID.String() {
+				return false
+			}
+		case ":user":
+			if path[i] != "me" && path[i] != regKey.OwnerID {
+				return false
+			}
+		default:
+			if path[i] != segment {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// applyRegKeyToDevice records the settings of the registration key used to create a device.
+func (api *API) applyRegKeyToDevice(tx *gorm.DB, regKey *models.RegKey, device models.Device) error {
+	if len(regKey.Tags) > 0 {
+		metadata := models.DeviceMetadata{
+			DeviceID: device.ID,
+			Key:      "tags",
+			Value:    []string(regKey.Tags),
+		}
+		if res := tx.Create(&metadata); res.Error != nil {
+			return res.Error
+		}
+	}
+	if regKey.SingleUse {
+		// claim the key, the device_id check guards against a concurrent registration.
+		res := tx.Model(&models.RegKey{}).
+			Where("id = ? AND device_id = ?", regKey.ID, uuid.Nil).
+			Update("device_id", device.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errRegKeyUsed
+		}
+		regKey.DeviceID = device.ID
+	}
+	return nil
+}
+
+// RegKeyFromContext returns the registration key the request was authenticated with, if any.
+func RegKeyFromContext(c *gin.Context) (*models.RegKey, bool) {
+	value, ok := c.Get(AuthRegKey)
+	if !ok {
+		return nil, false
+	}
+	regKey, ok := value.(*models.RegKey)
+	return regKey, ok
+}
+
+// newBearerToken generates a random opaque bearer token.
+func newBearerToken(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashBearerToken returns the hash of a bearer token that is stored in the database.
+func hashBearerToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+// serveRegKeyRequest serves a request that is authenticated with a registration key.
+func (suite *HandlerTestSuite) serveRegKeyRequest(token, method, path string, uri string, handler func(*gin.Context), body io.Reader) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		suite.api.RegKeyAuth(c, token)
+	})
+	r.Use(suite.api.CreateUserIfNotExists())
+	r.Any(path, handler)
+	req, err := http.NewRequest(method, uri, body)
+	suite.Require().NoError(err)
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	return res
+}
+
+func (suite *HandlerTestSuite) TestRegKeys() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	reqBody, err := json.Marshal(models.AddRegKey{
+		Description: "test key",
+		SingleUse:   true,
+		Ephemeral:   true,
+		Tags:        []string{"ci"},
+	})
+	require.NoError(err)
+
+	_, res, err := suite.ServeRequest(
+		http.MethodPost,
+		"/:organization/reg_keys", fmt.Sprintf("/%s/reg_keys", suite.testOrganizationID),
+		suite.api.CreateRegKey, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+
+	var regKey models.RegKey
+	require.NoError(json.Unmarshal(body, &regKey))
+	require.NotEmpty(regKey.BearerToken)
+	assert.Equal(suite.testOrganizationID, regKey.OrganizationID)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet,
+		"/:organization/reg_keys", fmt.Sprintf("/%s/reg_keys", suite.testOrganizationID),
+		suite.api.ListRegKeys, nil,
+	)
+	require.NoError(err)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", string(body))
+
+	var regKeys []models.RegKey
+	require.NoError(json.Unmarshal(body, &regKeys))
+	require.Len(regKeys, 1)
+	assert.Equal(regKey.ID, regKeys[0].ID)
+	assert.Empty(regKeys[0].BearerToken)
+
+	// register a device using the key
+	reqBody, err = json.Marshal(models.AddDevice{PublicKey: "regkeypubkey"})
+	require.NoError(err)
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodPost, "/api/devices", "/api/devices", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+	body, err = io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+
+	var device models.Device
+	require.NoError(json.Unmarshal(body, &device))
+	assert.Equal(suite.testOrganizationID, device.OrganizationID)
+	assert.True(device.Ephemeral)
+
+	var metadata models.DeviceMetadata
+	require.NoError(suite.api.db.First(&metadata, "device_id = ? AND key = ?", device.ID, "tags").Error)
+
+	// a single use key cannot register a second device
+	reqBody, err = json.Marshal(models.AddDevice{PublicKey: "otherpubkey"})
+	require.NoError(err)
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodPost, "/api/devices", "/api/devices", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+	assert.Equal(http.StatusForbidden, res.Code)
+
+	// keys are limited to the api calls nexd needs
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodPost, "/api/organizations", "/api/organizations", suite.api.CreateOrganization, nil)
+	assert.Equal(http.StatusForbidden, res.Code)
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodGet, "/api/organizations/:organization/reg_keys", fmt.Sprintf("/api/organizations/%s/reg_keys", suite.testOrganizationID), suite.api.ListRegKeys, nil)
+	assert.Equal(http.StatusForbidden, res.Code)
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodGet, "/api/organizations/:organization/devices", fmt.Sprintf("/api/organizations/%s/devices", uuid.New()), suite.api.ListDevicesInOrganization, nil)
+	assert.Equal(http.StatusForbidden, res.Code)
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodGet, "/api/organizations/:organization/devices", fmt.Sprintf("/api/organizations/%s/devices", suite.testOrganizationID), suite.api.ListDevicesInOrganization, nil)
+	assert.Equal(http.StatusOK, res.Code)
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodGet, "/api/users/:id", "/api/users/other-user", suite.api.GetUser, nil)
+	assert.Equal(http.StatusForbidden, res.Code)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete,
+		"/:organization/reg_keys/:id", fmt.Sprintf("/%s/reg_keys/%s", suite.testOrganizationID, regKey.ID),
+		suite.api.DeleteRegKey, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+
+	// revoked keys can no longer be used
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodGet, "/api/devices/:id", fmt.Sprintf("/api/devices/%s", device.ID), suite.api.GetDevice, nil)
+	assert.Equal(http.StatusUnauthorized, res.Code)
+}
+
+func (suite *HandlerTestSuite) TestRegKeyOnlyManagesItsDevices() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	_, res, err := suite.ServeRequest(
+		http.MethodPost,
+		"/:organization/reg_keys", fmt.Sprintf("/%s/reg_keys", suite.testOrganizationID),
+		suite.api.CreateRegKey, bytes.NewBuffer(suite.jsonMarshal(models.AddRegKey{Description: "autoscaling group"})),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, res.Body.String())
+	var regKey models.RegKey
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &regKey))
+
+	// the owner of the key registers their laptop with their login.
+	_, res, err = suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice,
+		bytes.NewBuffer(suite.jsonMarshal(models.AddDevice{OrganizationID: suite.testOrganizationID, PublicKey: "laptop"})))
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, res.Body.String())
+	var laptop models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &laptop))
+	assert.Equal(uuid.Nil, laptop.RegKeyID)
+
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodPost, "/api/devices", "/api/devices", suite.api.CreateDevice,
+		bytes.NewBuffer(suite.jsonMarshal(models.AddDevice{PublicKey: "worker"})))
+	require.Equal(http.StatusCreated, res.Code, res.Body.String())
+	var worker models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &worker))
+	assert.Equal(regKey.ID, worker.RegKeyID)
+
+	// the key can't read, update or delete the laptop.
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodGet, "/api/devices/:id", fmt.Sprintf("/api/devices/%s", laptop.ID), suite.api.GetDevice, nil)
+	assert.Equal(http.StatusNotFound, res.Code)
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodPatch, "/api/devices/:id", fmt.Sprintf("/api/devices/%s", laptop.ID), suite.api.UpdateDevice,
+		bytes.NewBuffer(suite.jsonMarshal(models.UpdateDevice{Hostname: "taken-over"})))
+	assert.Equal(http.StatusNotFound, res.Code)
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodDelete, "/api/devices/:id", fmt.Sprintf("/api/devices/%s", laptop.ID), suite.api.DeleteDevice, nil)
+	assert.Equal(http.StatusNotFound, res.Code)
+	require.NoError(suite.api.db.First(&models.Device{}, "id = ?", laptop.ID).Error)
+
+	// it still manages the devices it registered.
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodGet, "/api/devices/:id", fmt.Sprintf("/api/devices/%s", worker.ID), suite.api.GetDevice, nil)
+	assert.Equal(http.StatusOK, res.Code)
+	res = suite.serveRegKeyRequest(regKey.BearerToken, http.MethodDelete, "/api/devices/:id", fmt.Sprintf("/api/devices/%s", worker.ID), suite.api.DeleteDevice, nil)
+	assert.Equal(http.StatusOK, res.Code)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// relayCheckInterval is how often the heartbeats of the relays are checked.
+const relayCheckInterval = 30 * time.Second
+
+// DeviceHeartbeat reports that a device is up
+// @Summary      Report that a device is up
+// @Description  Records a heartbeat of the device. The relays that stop sending heartbeats are reported down with the relay.down webhook event, and the ephemeral devices that stop sending heartbeats are deleted.
+// @Id           DeviceHeartbeat
+// @Tags         Devices
+// @Param        id   path      string  true "Device ID"
+// @Success      204
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/devices/{id}/heartbeat [post]
+func (api *API) DeviceHeartbeat(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeviceHeartbeat", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	k, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var device models.Device
+		if res := tx.Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+			First(&device, "id = ?", k); res.Error != nil {
+			return errDeviceNotFound
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "device_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"seen_at", "down"}),
+		}).Create(&models.DeviceHeartbeat{
+			DeviceID: device.ID,
+			SeenAt:   time.Now(),
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, errDeviceNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// StartRelayMonitor starts the background worker that reports the relays whose last heartbeat is
+// older than timeout down, with the relay.down webhook event. The relays that never sent a heartbeat
+// are not monitored.
+func (api *API) StartRelayMonitor(ctx context.Context, wg *sync.WaitGroup, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	util.GoWithWaitGroup(wg, func() {
+		ticker := time.NewTicker(relayCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := api.checkRelays(ctx, time.Now().Add(-timeout)); err != nil {
+				api.logger.Errorf("failed to check the heartbeats of the relays: %v", err)
+			}
+		}
+	})
+}
+
+// checkRelays reports the relays whose last heartbeat is before the given time down.
+func (api *API) checkRelays(ctx context.Context, before time.Time) error {
+	var relays []models.Device
+	if res := api.db.WithContext(ctx).
+		Joins("JOIN device_heartbeats ON device_heartbeats.device_id = devices.id").
+		Where("devices.relay = ? AND device_heartbeats.down = ? AND device_heartbeats.seen_at < ?", true, false, before).
+		Find(&relays); res.Error != nil {
+		return res.Error
+	}
+	reported := false
+	for _, relay := range relays {
+		err := api.transaction(ctx, func(tx *gorm.DB) error {
+			// the other apiserver replicas, or a heartbeat, may have updated the heartbeat since.
+			res := tx.Model(&models.DeviceHeartbeat{}).
+				Where("device_id = ? AND down = ? AND seen_at < ?", relay.ID, false, before).
+				Update("down", true)
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+			api.logger.Infof("relay %s of organization %s is down", relay.ID, relay.OrganizationID)
+			reported = true
+			return api.queueWebhookEvent(tx, relay.OrganizationID, models.WebhookEventRelayDown, relay)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if reported {
+		api.signalBus.Notify(webhooksSignal)
+	}
+	return nil
+}
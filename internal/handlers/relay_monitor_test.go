@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"gorm.io/gorm"
+)
+
+func (suite *HandlerTestSuite) TestRelayMonitor() {
+	require := suite.Require()
+	assert := suite.Assert()
+	ctx := context.Background()
+
+	webhook := suite.createWebhook("https://hooks.example.com", models.WebhookEventRelayDown)
+	relay := models.Device{UserID: TestUserID, OrganizationID: suite.testOrganizationID, Hostname: "relay", Relay: true}
+	peer := models.Device{UserID: TestUserID, OrganizationID: suite.testOrganizationID, Hostname: "peer"}
+	require.NoError(suite.api.db.Create(&relay).Error)
+	require.NoError(suite.api.db.Create(&peer).Error)
+
+	for _, device := range []models.Device{relay, peer} {
+		_, res, err := suite.ServeRequest(
+			http.MethodPost, "/devices/:id/heartbeat", fmt.Sprintf("/devices/%s/heartbeat", device.ID),
+			suite.api.DeviceHeartbeat, nil,
+		)
+		require.NoError(err)
+		require.Equal(http.StatusNoContent, res.Code, "HTTP error: %s", res.Body.String())
+	}
+
+	deliveries := func() int64 {
+		var count int64
+		require.NoError(suite.api.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID).Count(&count).Error)
+		return count
+	}
+
+	// the relays are up while their heartbeats are recent.
+	require.NoError(suite.api.checkRelays(ctx, time.Now().Add(-time.Minute)))
+	assert.Equal(int64(0), deliveries())
+
+	// a relay whose heartbeats stopped is reported down once.
+	require.NoError(suite.api.checkRelays(ctx, time.Now().Add(time.Minute)))
+	assert.Equal(int64(1), deliveries())
+	require.NoError(suite.api.checkRelays(ctx, time.Now().Add(time.Minute)))
+	assert.Equal(int64(1), deliveries())
+
+	// a heartbeat brings it back up, and deleting a relay that was reported down doesn't report it again.
+	_, res, err := suite.ServeRequest(
+		http.MethodPost, "/devices/:id/heartbeat", fmt.Sprintf("/devices/%s/heartbeat", relay.ID),
+		suite.api.DeviceHeartbeat, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusNoContent, res.Code)
+	var heartbeat models.DeviceHeartbeat
+	require.NoError(suite.api.db.First(&heartbeat, "device_id = ?", relay.ID).Error)
+	assert.False(heartbeat.Down)
+
+	require.NoError(suite.api.checkRelays(ctx, time.Now().Add(time.Minute)))
+	assert.Equal(int64(2), deliveries())
+	require.NoError(suite.api.db.Transaction(func(tx *gorm.DB) error {
+		return suite.api.removeDevice(tx, &relay)
+	}))
+	assert.Equal(int64(2), deliveries())
+
+	// the heartbeats of other users' devices are refused.
+	_, res, err = suite.ServeRequest(
+		http.MethodPost, "/devices/:id/heartbeat", fmt.Sprintf("/devices/%s/heartbeat", uuid.New()),
+		suite.api.DeviceHeartbeat, nil,
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusNotFound, res.Code)
+}
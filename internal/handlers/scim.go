@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"gorm.io/gorm"
+)
+
+// scimActor is the actor of the audit events of the changes made by SCIM provisioning.
+const scimActor = "scim"
+
+const (
+	scimDefaultCount = 100
+	scimMaxCount     = 1000
+)
+
+var (
+	errUserDeactivated   = errors.New("the user is deactivated")
+	errScimGroupNotFound = errors.New("group not found")
+	errScimUniqueness    = errors.New("the resource already exists")
+)
+
+// scimBadRequest is a request that is invalid for the reason of the SCIM error type.
+type scimBadRequest struct {
+	scimType string
+	detail   string
+}
+
+func (e scimBadRequest) Error() string {
+	return e.detail
+}
+
+// SCIMConfig configures the SCIM 2.0 provisioning endpoints.
+type SCIMConfig struct {
+	// Token authenticates the identity provider, the endpoints are disabled when it is empty.
+	Token string
+	// UserIDPrefix is prepended to the externalId of the provisioned users and to the names of the
+	// provisioned groups, like it is to the subjects and groups of the tokens of the provider.
+	UserIDPrefix string
+}
+
+// SetSCIM configures the SCIM provisioning endpoints.
+func (api *API) SetSCIM(config SCIMConfig) {
+	api.scim = config
+}
+
+// SCIMAuth authenticates the requests of the identity provider to the SCIM endpoints with the
+// bearer token of the SCIM configuration.
+func (api *API) SCIMAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if api.scim.Token == "" {
+			scimError(c, http.StatusNotFound, "", "SCIM provisioning is not enabled")
+			c.Abort()
+			return
+		}
+		token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(api.scim.Token)) != 1 {
+			scimError(c, http.StatusUnauthorized, "", "invalid bearer token")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetScimServiceProviderConfig describes the SCIM features supported by the apiserver.
+func (api *API) GetScimServiceProviderConfig(c *gin.Context) {
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":          []string{models.ScimSchemaServiceProviderConfig},
+		"patch":            gin.H{"supported": true},
+		"bulk":             gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           gin.H{"supported": true, "maxResults": scimMaxCount},
+		"changePassword":   gin.H{"supported": false},
+		"sort":             gin.H{"supported": false},
+		"etag":             gin.H{"supported": false},
+		"documentationUri": "https://docs.nexodus.io/deployment/scim/",
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "The token configured with the --scim-token flag of the apiserver",
+			"primary":     true,
+		}},
+	})
+}
+
+func scimJSON(c *gin.Context, status int, obj interface{}) {
+	c.Header("Content-Type", "application/scim+json")
+	c.JSON(status, obj)
+}
+
+func scimError(c *gin.Context, status int, scimType string, detail string) {
+	scimJSON(c, status, models.NewScimError(status, scimType, detail))
+}
+
+func scimMeta(resourceType string, location string, created, modified time.Time) *models.ScimMeta {
+	return &models.ScimMeta{
+		ResourceType: resourceType,
+		Created:      created.UTC().Format(time.RFC3339),
+		LastModified: modified.UTC().Format(time.RFC3339),
+		Location:     location,
+	}
+}
+
+// scimPage returns the 1-based start index and the count of the page requested by a list request.
+func scimPage(c *gin.Context) (int, int, error) {
+	startIndex, count := 1, scimDefaultCount
+	var err error
+	if value := c.Query("startIndex"); value != "" {
+		if startIndex, err = strconv.Atoi(value); err != nil {
+			return 0, 0, fmt.Errorf("invalid startIndex")
+		}
+		if startIndex < 1 {
+			startIndex = 1
+		}
+	}
+	if value := c.Query("count"); value != "" {
+		if count, err = strconv.Atoi(value); err != nil {
+			return 0, 0, fmt.Errorf("invalid count")
+		}
+		if count < 0 {
+			count = 0
+		}
+		if count > scimMaxCount {
+			count = scimMaxCount
+		}
+	}
+	return startIndex, count, nil
+}
+
+var scimFilterPattern = regexp.MustCompile(`^\s*([A-Za-z.]+)\s+(?i:eq)\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// scimFilter parses the filters that identity providers use to look up a resource, an attribute
+// equal to a value, and returns the attribute and the value. Only the attributes in the map are
+// supported, they are mapped to their columns.
+func scimFilter(filter string, columns map[string]string) (string, string, error) {
+	match := scimFilterPattern.FindStringSubmatch(filter)
+	if match == nil {
+		return "", "", fmt.Errorf("only the filters of the form 'attribute eq \"value\"' are supported")
+	}
+	for attribute, column := range columns {
+		if strings.EqualFold(attribute, match[1]) {
+			value, err := strconv.Unquote(`"` + match[2] + `"`)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid filter value")
+			}
+			return column, value, nil
+		}
+	}
+	return "", "", fmt.Errorf("filtering on %s is not supported", match[1])
+}
+
+// scimBool reads a boolean patch value, some identity providers send them as strings.
+func scimBool(value interface{}) (bool, bool) {
+	switch value := value.(type) {
+	case bool:
+		return value, true
+	case string:
+		b, err := strconv.ParseBool(value)
+		return b, err == nil
+	}
+	return false, false
+}
+
+// scimGroupsOf returns the names of the SCIM groups of the user, prefixed like the groups of the
+// tokens of the provider.
+func (api *API) scimGroupsOf(tx *gorm.DB, userId string) ([]string, error) {
+	var names []string
+	if res := tx.Model(&models.ScimGroup{}).
+		Joins("inner join scim_group_members on scim_group_members.group_id=scim_groups.id").
+		Where("scim_group_members.user_id = ?", userId).
+		Pluck("scim_groups.display_name", &names); res.Error != nil {
+		return nil, res.Error
+	}
+	for i := range names {
+		names[i] = api.scim.UserIDPrefix + names[i]
+	}
+	return names, nil
+}
+
+// storedGroups returns the groups of the last token of the user, for the syncs that are not made
+// with a token of the user.
+func storedGroups(_ *gorm.DB, user *models.User) ([]string, error) {
+	if user.GroupsSyncedAt == nil {
+		return nil, nil
+	}
+	return user.Groups, nil
+}
+
+// syncScimMembers syncs the memberships of the users after their SCIM groups changed. The changes are
+// already committed, so the users that fail to sync are left to the group sync worker.
+func (api *API) syncScimMembers(ctx context.Context, userIds []string) {
+	for _, userId := range userIds {
+		err := util.RetryOperationForErrors(ctx, time.Millisecond*10, 1, []error{gorm.ErrDuplicatedKey}, func() error {
+			return api.syncGroupMemberships(ctx, userId, storedGroups)
+		})
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			api.logger.Errorf("failed to sync the group memberships of user %s: %v", userId, err)
+			api.requestGroupSync()
+		}
+	}
+}
+
+// deactivateUser blocks the user from using the api, deletes all their devices and removes them
+// from the organizations of their groups. It should be called with the context of the transaction,
+// see withIPAMReleases.
+func (api *API) deactivateUser(ctx context.Context, tx *gorm.DB, user *models.User) (groupMembershipChanges, error) {
+	var changes groupMembershipChanges
+	user.Deactivated = true
+	if res := tx.Model(user).UpdateColumn("deactivated", true); res.Error != nil {
+		return changes, res.Error
+	}
+	orgIds, err := api.deleteUserDevices(ctx, tx, scimActor, tx.Where("user_id = ?", user.ID))
+	if err != nil {
+		return changes, err
+	}
+	changes, err = api.applyGroupMemberships(ctx, tx, *user, nil)
+	if err != nil {
+		return changes, err
+	}
+	changes.devices = append(changes.devices, orgIds...)
+	return changes, nil
+}
+
+// notifyUserChanges notifies the watchers of the changes made to a user by SCIM, and drops the user
+// from the caches so that their next request sees the changes.
+func (api *API) notifyUserChanges(ctx context.Context, userId string, changes groupMembershipChanges) {
+	api.signalBus.Notify(usersSignal(userId))
+	api.signalBus.Notify(organizationsSignal(userId))
+	api.notifyOrganizationsChanged(ctx, changes.organizations...)
+	for _, orgId := range changes.devices {
+		api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", orgId.String()))
+		api.signalBus.Notify(webhooksSignal)
+	}
+	for _, key := range []string{
+		fmt.Sprintf("%s:%s", CachePrefix, userId),
+		fmt.Sprintf("%s:groups:%s", CachePrefix, userId),
+	} {
+		if _, err := api.redis.Del(ctx, key).Result(); err != nil {
+			api.logger.Warnf("failed to delete the cache user:%s", err)
+		}
+	}
+}
+
+func sendScimError(c *gin.Context, err error) {
+	var badRequest scimBadRequest
+	if errors.As(err, &badRequest) {
+		scimError(c, http.StatusBadRequest, badRequest.scimType, badRequest.detail)
+	} else if errors.Is(err, errUserNotFound) {
+		scimError(c, http.StatusNotFound, "", "user not found")
+	} else if errors.Is(err, errScimGroupNotFound) {
+		scimError(c, http.StatusNotFound, "", "group not found")
+	} else if errors.Is(err, errScimUniqueness) {
+		scimError(c, http.StatusConflict, "uniqueness", err.Error())
+	} else {
+		scimError(c, http.StatusInternalServerError, "", err.Error())
+	}
+}
@@ -0,0 +1,473 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var scimMemberPathPattern = regexp.MustCompile(`^(?i:members)\[(?i:value)\s+(?i:eq)\s+"([^"]*)"\]$`)
+
+// ListScimGroups lists the groups of the identity provider
+func (api *API) ListScimGroups(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListScimGroups")
+	defer span.End()
+
+	startIndex, count, err := scimPage(c)
+	if err != nil {
+		scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+
+	db := api.db.WithContext(ctx).Model(&models.ScimGroup{})
+	if filter := c.Query("filter"); filter != "" {
+		column, value, err := scimFilter(filter, map[string]string{
+			"id":          "id",
+			"externalId":  "external_id",
+			"displayName": "display_name",
+		})
+		if err != nil {
+			scimError(c, http.StatusBadRequest, "invalidFilter", err.Error())
+			return
+		}
+		if column == "id" {
+			if _, err := uuid.Parse(value); err != nil {
+				value = uuid.Nil.String()
+			}
+		}
+		db = db.Where(column+" = ?", value)
+	}
+
+	var total int64
+	if res := db.Count(&total); res.Error != nil {
+		sendScimError(c, res.Error)
+		return
+	}
+	var groups []models.ScimGroup
+	if res := db.Order("created_at").Offset(startIndex - 1).Limit(count).Find(&groups); res.Error != nil {
+		sendScimError(c, res.Error)
+		return
+	}
+	// identity providers exclude the members of large groups from the lists.
+	withMembers := !strings.Contains(strings.ToLower(c.Query("excludedAttributes")), "members")
+	resources := make([]models.ScimGroupResource, 0, len(groups))
+	for _, group := range groups {
+		resource, err := scimGroup(api.db.WithContext(ctx), group, withMembers)
+		if err != nil {
+			sendScimError(c, err)
+			return
+		}
+		resources = append(resources, resource)
+	}
+	scimJSON(c, http.StatusOK, models.ScimListResponse{
+		Schemas:      []string{models.ScimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// GetScimGroup gets a group of the identity provider
+func (api *API) GetScimGroup(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetScimGroup", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	group, _, err := findScimGroup(api.db.WithContext(ctx), c.Param("id"))
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	withMembers := !strings.Contains(strings.ToLower(c.Query("excludedAttributes")), "members")
+	resource, err := scimGroup(api.db.WithContext(ctx), group, withMembers)
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, resource)
+}
+
+// CreateScimGroup provisions a group of the identity provider. The members of the group become
+// members of the organizations its display name is mapped to.
+func (api *API) CreateScimGroup(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateScimGroup")
+	defer span.End()
+
+	var request models.ScimGroupResource
+	if err := c.ShouldBindJSON(&request); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	group := models.ScimGroup{
+		DisplayName: request.DisplayName,
+		ExternalID:  request.ExternalID,
+	}
+	members := map[string]bool{}
+	for _, member := range request.Members {
+		members[member.Value] = true
+	}
+	var synced []string
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		var err error
+		synced, err = saveScimGroup(tx, &group, nil, members)
+		return err
+	})
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	span.SetAttributes(attribute.String("id", group.ID.String()))
+	api.syncScimMembers(ctx, synced)
+
+	resource, err := scimGroup(api.db.WithContext(ctx), group, true)
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusCreated, resource)
+}
+
+// ReplaceScimGroup replaces the display name and the members of a group of the identity provider
+func (api *API) ReplaceScimGroup(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ReplaceScimGroup", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	var request models.ScimGroupResource
+	if err := c.ShouldBindJSON(&request); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	api.updateScimGroup(ctx, c, func(group *models.ScimGroup, members map[string]bool) error {
+		group.DisplayName = request.DisplayName
+		group.ExternalID = request.ExternalID
+		for member := range members {
+			delete(members, member)
+		}
+		for _, member := range request.Members {
+			members[member.Value] = true
+		}
+		return nil
+	})
+}
+
+// PatchScimGroup adds, removes or replaces the members of a group of the identity provider, or
+// renames it
+func (api *API) PatchScimGroup(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "PatchScimGroup", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	var request models.ScimPatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	api.updateScimGroup(ctx, c, func(group *models.ScimGroup, members map[string]bool) error {
+		for _, operation := range request.Operations {
+			if err := patchScimGroup(group, members, operation); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// patchScimGroup applies a patch operation to the group and its members.
+func patchScimGroup(group *models.ScimGroup, members map[string]bool, operation models.ScimPatchOperation) error {
+	op := strings.ToLower(operation.Op)
+	if op != "add" && op != "remove" && op != "replace" {
+		return scimBadRequest{scimType: "invalidSyntax", detail: fmt.Sprintf("unsupported operation %s", operation.Op)}
+	}
+
+	if match := scimMemberPathPattern.FindStringSubmatch(operation.Path); match != nil {
+		if op != "remove" {
+			return scimBadRequest{scimType: "invalidPath", detail: "members can only be removed by value"}
+		}
+		delete(members, match[1])
+		return nil
+	}
+
+	values := map[string]interface{}{operation.Path: operation.Value}
+	if operation.Path == "" {
+		var ok bool
+		if values, ok = operation.Value.(map[string]interface{}); !ok {
+			return scimBadRequest{scimType: "invalidValue", detail: "the value of an operation without a path must be an object"}
+		}
+	}
+	for path, value := range values {
+		switch strings.ToLower(path) {
+		case "members":
+			ids, err := scimMemberIds(value)
+			if err != nil {
+				return err
+			}
+			switch {
+			case op == "remove" && value == nil:
+				for member := range members {
+					delete(members, member)
+				}
+			case op == "remove":
+				for _, id := range ids {
+					delete(members, id)
+				}
+			case op == "replace":
+				for member := range members {
+					delete(members, member)
+				}
+				fallthrough
+			default:
+				for _, id := range ids {
+					members[id] = true
+				}
+			}
+		case "displayname", "externalid":
+			name, ok := value.(string)
+			if op == "remove" || !ok {
+				return scimBadRequest{scimType: "invalidValue", detail: fmt.Sprintf("%s must be a string", path)}
+			}
+			if strings.ToLower(path) == "displayname" {
+				group.DisplayName = name
+			} else {
+				group.ExternalID = name
+			}
+		default:
+			return scimBadRequest{scimType: "invalidPath", detail: fmt.Sprintf("unsupported path %s", path)}
+		}
+	}
+	return nil
+}
+
+// scimMemberIds returns the ids of the users referred to by the value of a members operation.
+func scimMemberIds(value interface{}) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var references []models.ScimReference
+	if err := json.Unmarshal(data, &references); err != nil {
+		var reference models.ScimReference
+		if err := json.Unmarshal(data, &reference); err != nil {
+			return nil, scimBadRequest{scimType: "invalidValue", detail: "members must be a list of references to users"}
+		}
+		references = []models.ScimReference{reference}
+	}
+	var ids []string
+	for _, reference := range references {
+		ids = append(ids, reference.Value)
+	}
+	return ids, nil
+}
+
+// updateScimGroup applies the changes made by update to the group of the request, and syncs the
+// memberships of the users that joined or left it.
+func (api *API) updateScimGroup(ctx context.Context, c *gin.Context, update func(group *models.ScimGroup, members map[string]bool) error) {
+	var group models.ScimGroup
+	var synced []string
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		var before []string
+		var err error
+		group, before, err = findScimGroup(tx, c.Param("id"))
+		if err != nil {
+			return err
+		}
+		members := map[string]bool{}
+		for _, member := range before {
+			members[member] = true
+		}
+		if err := update(&group, members); err != nil {
+			return err
+		}
+		synced, err = saveScimGroup(tx, &group, before, members)
+		return err
+	})
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	api.syncScimMembers(ctx, synced)
+
+	resource, err := scimGroup(api.db.WithContext(ctx), group, true)
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, resource)
+}
+
+// DeleteScimGroup deprovisions a group of the identity provider, its members are removed from the
+// organizations they were members of only through the group.
+func (api *API) DeleteScimGroup(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteScimGroup", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	var members []string
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		group, before, err := findScimGroup(tx, c.Param("id"))
+		if err != nil {
+			return err
+		}
+		members = before
+		if res := tx.Where("group_id = ?", group.ID).Delete(&models.ScimGroupMember{}); res.Error != nil {
+			return res.Error
+		}
+		return tx.Delete(&group).Error
+	})
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	api.syncScimMembers(ctx, members)
+	c.Status(http.StatusNoContent)
+}
+
+// findScimGroup finds the group and the ids of its members.
+func findScimGroup(tx *gorm.DB, id string) (models.ScimGroup, []string, error) {
+	var group models.ScimGroup
+	groupId, err := uuid.Parse(id)
+	if err != nil {
+		return group, nil, errScimGroupNotFound
+	}
+	if res := tx.First(&group, "id = ?", groupId); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return group, nil, errScimGroupNotFound
+		}
+		return group, nil, res.Error
+	}
+	var members []string
+	if res := tx.Model(&models.ScimGroupMember{}).
+		Where("group_id = ?", group.ID).
+		Pluck("user_id", &members); res.Error != nil {
+		return group, nil, res.Error
+	}
+	return group, members, nil
+}
+
+// saveScimGroup saves the group with the members, and returns the users whose groups changed.
+func saveScimGroup(tx *gorm.DB, group *models.ScimGroup, before []string, members map[string]bool) ([]string, error) {
+	if group.DisplayName == "" {
+		return nil, scimBadRequest{scimType: "invalidValue", detail: "displayName is required"}
+	}
+	var duplicates int64
+	if res := tx.Model(&models.ScimGroup{}).
+		Where("display_name = ? AND id <> ?", group.DisplayName, group.ID).
+		Count(&duplicates); res.Error != nil {
+		return nil, res.Error
+	}
+	if duplicates > 0 {
+		return nil, errScimUniqueness
+	}
+
+	ids := make([]string, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if len(ids) > 0 {
+		var found []string
+		if res := tx.Model(&models.User{}).Where("id IN ?", ids).Pluck("id", &found); res.Error != nil {
+			return nil, res.Error
+		}
+		if len(found) != len(ids) {
+			exists := map[string]bool{}
+			for _, id := range found {
+				exists[id] = true
+			}
+			for _, id := range ids {
+				if !exists[id] {
+					return nil, scimBadRequest{scimType: "invalidValue", detail: fmt.Sprintf("user %s not found", id)}
+				}
+			}
+		}
+	}
+
+	renamed := true
+	if group.ID == uuid.Nil {
+		if res := tx.Create(group); res.Error != nil {
+			return nil, res.Error
+		}
+	} else {
+		var existing models.ScimGroup
+		if res := tx.First(&existing, "id = ?", group.ID); res.Error != nil {
+			return nil, res.Error
+		}
+		renamed = existing.DisplayName != group.DisplayName
+		if res := tx.Model(group).Updates(map[string]interface{}{
+			"display_name": group.DisplayName,
+			"external_id":  group.ExternalID,
+		}); res.Error != nil {
+			return nil, res.Error
+		}
+	}
+
+	var synced []string
+	previous := map[string]bool{}
+	for _, id := range before {
+		previous[id] = true
+		if !members[id] {
+			if res := tx.Where("group_id = ? AND user_id = ?", group.ID, id).Delete(&models.ScimGroupMember{}); res.Error != nil {
+				return nil, res.Error
+			}
+			synced = append(synced, id)
+		}
+	}
+	for _, id := range ids {
+		if !previous[id] {
+			if res := tx.Create(&models.ScimGroupMember{GroupID: group.ID, UserID: id}); res.Error != nil {
+				return nil, res.Error
+			}
+			synced = append(synced, id)
+		} else if renamed {
+			synced = append(synced, id)
+		}
+	}
+	return synced, nil
+}
+
+// scimGroup returns the SCIM representation of the group.
+func scimGroup(tx *gorm.DB, group models.ScimGroup, withMembers bool) (models.ScimGroupResource, error) {
+	resource := models.ScimGroupResource{
+		Schemas:     []string{models.ScimSchemaGroup},
+		ID:          group.ID.String(),
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Meta:        scimMeta("Group", fmt.Sprintf("/scim/v2/Groups/%s", group.ID), group.CreatedAt, group.UpdatedAt),
+	}
+	if !withMembers {
+		return resource, nil
+	}
+	var users []models.User
+	if res := tx.
+		Joins("inner join scim_group_members on scim_group_members.user_id=users.id").
+		Where("scim_group_members.group_id = ?", group.ID).
+		Order("users.id").
+		Find(&users); res.Error != nil {
+		return resource, res.Error
+	}
+	for _, user := range users {
+		resource.Members = append(resource.Members, models.ScimReference{Value: user.ID, Display: user.UserName})
+	}
+	return resource, nil
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+const testScimToken = "scim-test-token"
+
+// serveScim sends a request to a SCIM handler as the identity provider.
+func (suite *HandlerTestSuite) serveScim(method, path string, uri string, handler func(*gin.Context), body any) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(suite.api.SCIMAuth())
+	r.Any(path, handler)
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewBuffer(suite.jsonMarshal(body))
+	}
+	req, err := http.NewRequest(method, uri, reader)
+	suite.Require().NoError(err)
+	req.Header.Set("Content-Type", "application/scim+json")
+	req.Header.Set("Authorization", "Bearer "+testScimToken)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	return res
+}
+
+func (suite *HandlerTestSuite) TestScimAuth() {
+	assert := suite.Assert()
+	defer suite.api.SetSCIM(SCIMConfig{})
+
+	suite.api.SetSCIM(SCIMConfig{})
+	res := suite.serveScim(http.MethodGet, "/Users", "/Users", suite.api.ListScimUsers, nil)
+	assert.Equal(http.StatusNotFound, res.Code)
+
+	suite.api.SetSCIM(SCIMConfig{Token: "another-token"})
+	res = suite.serveScim(http.MethodGet, "/Users", "/Users", suite.api.ListScimUsers, nil)
+	assert.Equal(http.StatusUnauthorized, res.Code)
+	assert.Equal("application/scim+json", res.Header().Get("Content-Type"))
+}
+
+func (suite *HandlerTestSuite) TestScimUsers() {
+	require := suite.Require()
+	assert := suite.Assert()
+	suite.api.SetSCIM(SCIMConfig{Token: testScimToken, UserIDPrefix: "corp:"})
+	defer suite.api.SetSCIM(SCIMConfig{})
+
+	res := suite.serveScim(http.MethodPost, "/Users", "/Users", suite.api.CreateScimUser, models.ScimUser{
+		Schemas:  []string{models.ScimSchemaUser},
+		UserName: "jdoe",
+	})
+	assert.Equal(http.StatusBadRequest, res.Code)
+
+	res = suite.serveScim(http.MethodPost, "/Users", "/Users", suite.api.CreateScimUser, models.ScimUser{
+		Schemas:    []string{models.ScimSchemaUser},
+		ExternalID: "jdoe-1",
+		UserName:   "jdoe",
+	})
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var user models.ScimUser
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &user))
+	assert.Equal("corp:jdoe-1", user.ID)
+	assert.Equal("jdoe-1", user.ExternalID)
+	require.NotNil(user.Active)
+	assert.True(*user.Active)
+	uri := "/Users/" + url.PathEscape(user.ID)
+
+	res = suite.serveScim(http.MethodPost, "/Users", "/Users", suite.api.CreateScimUser, models.ScimUser{
+		Schemas:    []string{models.ScimSchemaUser},
+		ExternalID: "jdoe-1",
+		UserName:   "jdoe",
+	})
+	assert.Equal(http.StatusConflict, res.Code)
+
+	res = suite.serveScim(http.MethodGet, "/Users", "/Users?filter="+url.QueryEscape(`userName eq "jdoe"`), suite.api.ListScimUsers, nil)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var list struct {
+		TotalResults int64             `json:"totalResults"`
+		Resources    []models.ScimUser `json:"Resources"`
+	}
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &list))
+	assert.Equal(int64(1), list.TotalResults)
+	require.Len(list.Resources, 1)
+	assert.Equal(user.ID, list.Resources[0].ID)
+
+	res = suite.serveScim(http.MethodGet, "/Users", "/Users?filter="+url.QueryEscape(`emails co "example.com"`), suite.api.ListScimUsers, nil)
+	assert.Equal(http.StatusBadRequest, res.Code)
+
+	// deactivating a user deletes their devices and blocks them from the api
+	device := models.Device{
+		UserID:         user.ID,
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "scim-device",
+	}
+	require.NoError(suite.api.db.Create(&device).Error)
+	res = suite.serveScim(http.MethodPatch, "/Users/:id", uri, suite.api.PatchScimUser, models.ScimPatchRequest{
+		Schemas:    []string{models.ScimSchemaPatchOp},
+		Operations: []models.ScimPatchOperation{{Op: "Replace", Path: "active", Value: "False"}},
+	})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &user))
+	assert.False(*user.Active)
+	var count int64
+	require.NoError(suite.api.db.Model(&models.Device{}).Where("id = ?", device.ID).Count(&count).Error)
+	assert.Equal(int64(0), count)
+	_, err := suite.api.createUserIfNotExists(context.Background(), user.ID, "jdoe")
+	assert.ErrorIs(err, errUserDeactivated)
+
+	res = suite.serveScim(http.MethodPut, "/Users/:id", uri, suite.api.ReplaceScimUser, models.ScimUser{
+		Schemas:  []string{models.ScimSchemaUser},
+		UserName: "john.doe",
+	})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &user))
+	assert.True(*user.Active)
+	assert.Equal("john.doe", user.UserName)
+	_, err = suite.api.createUserIfNotExists(context.Background(), user.ID, "john.doe")
+	assert.NoError(err)
+
+	// deleted users can't log in again until they are provisioned again
+	res = suite.serveScim(http.MethodDelete, "/Users/:id", uri, suite.api.DeleteScimUser, nil)
+	require.Equal(http.StatusNoContent, res.Code, "HTTP error: %s", res.Body.String())
+	res = suite.serveScim(http.MethodGet, "/Users/:id", uri, suite.api.GetScimUser, nil)
+	assert.Equal(http.StatusNotFound, res.Code)
+	_, err = suite.api.createUserIfNotExists(context.Background(), user.ID, "john.doe")
+	assert.ErrorIs(err, errUserDeactivated)
+
+	res = suite.serveScim(http.MethodPost, "/Users", "/Users", suite.api.CreateScimUser, models.ScimUser{
+		Schemas:    []string{models.ScimSchemaUser},
+		ExternalID: "jdoe-1",
+		UserName:   "jdoe",
+	})
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	_, err = suite.api.createUserIfNotExists(context.Background(), user.ID, "jdoe")
+	assert.NoError(err)
+}
+
+func (suite *HandlerTestSuite) TestScimGroups() {
+	require := suite.Require()
+	assert := suite.Assert()
+	suite.api.SetSCIM(SCIMConfig{Token: testScimToken})
+	defer suite.api.SetSCIM(SCIMConfig{})
+	suite.api.db.Exec("DELETE FROM organization_group_mappings")
+	suite.api.db.Exec("DELETE FROM scim_group_members")
+	suite.api.db.Exec("DELETE FROM scim_groups")
+
+	require.NoError(suite.api.db.Create(&models.OrganizationGroupMapping{
+		OrganizationID: suite.testOrganizationID,
+		Group:          "engineering",
+		Role:           models.RoleMember,
+	}).Error)
+
+	// the members of a group are members of the organizations it's mapped to
+	res := suite.serveScim(http.MethodPost, "/Groups", "/Groups", suite.api.CreateScimGroup, models.ScimGroupResource{
+		Schemas:     []string{models.ScimSchemaGroup},
+		DisplayName: "engineering",
+		Members:     []models.ScimReference{{Value: TestUser2ID}},
+	})
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var group models.ScimGroupResource
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &group))
+	require.Len(group.Members, 1)
+	assert.Equal(TestUser2ID, group.Members[0].Value)
+	membership := suite.groupMembership(suite.testOrganizationID)
+	require.NotNil(membership)
+	assert.True(membership.GroupManaged)
+	uri := fmt.Sprintf("/Groups/%s", group.ID)
+
+	res = suite.serveScim(http.MethodPost, "/Groups", "/Groups", suite.api.CreateScimGroup, models.ScimGroupResource{
+		Schemas:     []string{models.ScimSchemaGroup},
+		DisplayName: "engineering",
+	})
+	assert.Equal(http.StatusConflict, res.Code)
+	res = suite.serveScim(http.MethodPost, "/Groups", "/Groups", suite.api.CreateScimGroup, models.ScimGroupResource{
+		Schemas:     []string{models.ScimSchemaGroup},
+		DisplayName: "sre",
+		Members:     []models.ScimReference{{Value: "unknown-user"}},
+	})
+	assert.Equal(http.StatusBadRequest, res.Code)
+
+	patch := func(operations ...models.ScimPatchOperation) {
+		res := suite.serveScim(http.MethodPatch, "/Groups/:id", uri, suite.api.PatchScimGroup, models.ScimPatchRequest{
+			Schemas:    []string{models.ScimSchemaPatchOp},
+			Operations: operations,
+		})
+		require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	}
+
+	patch(models.ScimPatchOperation{Op: "remove", Path: fmt.Sprintf(`members[value eq "%s"]`, TestUser2ID)})
+	assert.Nil(suite.groupMembership(suite.testOrganizationID))
+
+	patch(models.ScimPatchOperation{Op: "add", Path: "members", Value: []models.ScimReference{{Value: TestUser2ID}}})
+	assert.NotNil(suite.groupMembership(suite.testOrganizationID))
+
+	// renaming the group changes the organizations of its members
+	patch(models.ScimPatchOperation{Op: "replace", Value: map[string]interface{}{"displayName": "operations"}})
+	assert.Nil(suite.groupMembership(suite.testOrganizationID))
+
+	res = suite.serveScim(http.MethodGet, "/Groups", "/Groups?filter="+url.QueryEscape(`displayName eq "operations"`), suite.api.ListScimGroups, nil)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var list struct {
+		TotalResults int64 `json:"totalResults"`
+	}
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &list))
+	assert.Equal(int64(1), list.TotalResults)
+
+	// the memberships of the group are synced with the groups of the tokens of the users
+	patch(models.ScimPatchOperation{Op: "replace", Path: "displayName", Value: "engineering"})
+	require.NotNil(suite.groupMembership(suite.testOrganizationID))
+	suite.serveAsUserWithGroups()
+	assert.NotNil(suite.groupMembership(suite.testOrganizationID))
+
+	res = suite.serveScim(http.MethodDelete, "/Groups/:id", uri, suite.api.DeleteScimGroup, nil)
+	require.Equal(http.StatusNoContent, res.Code, "HTTP error: %s", res.Body.String())
+	assert.Nil(suite.groupMembership(suite.testOrganizationID))
+	res = suite.serveScim(http.MethodGet, "/Groups/:id", uri, suite.api.GetScimGroup, nil)
+	assert.Equal(http.StatusNotFound, res.Code)
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/ipam"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// scimUserChanges are the attributes of a user set by a SCIM request, nil attributes are unchanged.
+type scimUserChanges struct {
+	userName *string
+	active   *bool
+}
+
+// ListScimUsers lists the users of the identity provider
+func (api *API) ListScimUsers(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListScimUsers")
+	defer span.End()
+
+	startIndex, count, err := scimPage(c)
+	if err != nil {
+		scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+
+	db := api.db.WithContext(ctx).Model(&models.User{})
+	if api.scim.UserIDPrefix != "" {
+		db = db.Where("id LIKE ?", api.scim.UserIDPrefix+"%")
+	}
+	if filter := c.Query("filter"); filter != "" {
+		column, value, err := scimFilter(filter, map[string]string{
+			"id":         "id",
+			"externalId": "externalId",
+			"userName":   "user_name",
+		})
+		if err != nil {
+			scimError(c, http.StatusBadRequest, "invalidFilter", err.Error())
+			return
+		}
+		if column == "externalId" {
+			column, value = "id", api.scim.UserIDPrefix+value
+		}
+		db = db.Where(column+" = ?", value)
+	}
+
+	var total int64
+	if res := db.Count(&total); res.Error != nil {
+		sendScimError(c, res.Error)
+		return
+	}
+	var users []models.User
+	if res := db.Order("created_at").Offset(startIndex - 1).Limit(count).Find(&users); res.Error != nil {
+		sendScimError(c, res.Error)
+		return
+	}
+	resources := make([]models.ScimUser, 0, len(users))
+	for _, user := range users {
+		resource, err := api.scimUser(api.db.WithContext(ctx), user)
+		if err != nil {
+			sendScimError(c, err)
+			return
+		}
+		resources = append(resources, resource)
+	}
+	scimJSON(c, http.StatusOK, models.ScimListResponse{
+		Schemas:      []string{models.ScimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// GetScimUser gets a user of the identity provider
+func (api *API) GetScimUser(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetScimUser", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	var user models.User
+	if res := api.db.WithContext(ctx).First(&user, "id = ?", c.Param("id")); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			res.Error = errUserNotFound
+		}
+		sendScimError(c, res.Error)
+		return
+	}
+	resource, err := api.scimUser(api.db.WithContext(ctx), user)
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, resource)
+}
+
+// CreateScimUser provisions a user of the identity provider, before they log in. The users that were
+// deleted or deactivated are restored.
+func (api *API) CreateScimUser(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateScimUser")
+	defer span.End()
+
+	var request models.ScimUser
+	if err := c.ShouldBindJSON(&request); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	if request.ExternalID == "" {
+		scimError(c, http.StatusBadRequest, "invalidValue", "externalId is required")
+		return
+	}
+	if request.UserName == "" {
+		scimError(c, http.StatusBadRequest, "invalidValue", "userName is required")
+		return
+	}
+	id := api.scim.UserIDPrefix + request.ExternalID
+	span.SetAttributes(attribute.String("id", id))
+
+	var user models.User
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		user = models.User{}
+		res := tx.Unscoped().First(&user, "id = ?", id)
+		if res.Error == nil {
+			if !user.DeletedAt.Valid && !user.Deactivated {
+				return errScimUniqueness
+			}
+			user.DeletedAt = gorm.DeletedAt{}
+			user.Deactivated = request.Active != nil && !*request.Active
+			user.UserName = request.UserName
+			if res := tx.Unscoped().Model(&user).Updates(map[string]interface{}{
+				"deleted_at":  nil,
+				"deactivated": user.Deactivated,
+				"user_name":   user.UserName,
+			}); res.Error != nil {
+				return res.Error
+			}
+		} else if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			user = models.User{
+				ID:          id,
+				UserName:    request.UserName,
+				Deactivated: request.Active != nil && !*request.Active,
+			}
+			if res := tx.Create(&user); res.Error != nil {
+				return res.Error
+			}
+		} else {
+			return res.Error
+		}
+		_, err := api.createUserOrgIfNotExists(ipam.WithTransaction(ctx, tx), tx, user.ID, user.UserName)
+		return err
+	})
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	api.notifyUserChanges(ctx, user.ID, groupMembershipChanges{})
+	api.syncScimMembers(ctx, []string{user.ID})
+
+	resource, err := api.scimUser(api.db.WithContext(ctx), user)
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusCreated, resource)
+}
+
+// ReplaceScimUser replaces the attributes of a user of the identity provider
+func (api *API) ReplaceScimUser(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ReplaceScimUser", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	var request models.ScimUser
+	if err := c.ShouldBindJSON(&request); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	if request.UserName == "" {
+		scimError(c, http.StatusBadRequest, "invalidValue", "userName is required")
+		return
+	}
+	active := request.Active == nil || *request.Active
+	api.updateScimUser(ctx, c, scimUserChanges{userName: &request.UserName, active: &active})
+}
+
+// PatchScimUser modifies the attributes of a user of the identity provider, the attributes that
+// are not stored by Nexodus are ignored
+func (api *API) PatchScimUser(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "PatchScimUser", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	var request models.ScimPatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	var changes scimUserChanges
+	for _, operation := range request.Operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" {
+			continue
+		}
+		values := map[string]interface{}{operation.Path: operation.Value}
+		if operation.Path == "" {
+			values, _ = operation.Value.(map[string]interface{})
+		}
+		for path, value := range values {
+			switch strings.ToLower(path) {
+			case "active":
+				active, ok := scimBool(value)
+				if !ok {
+					scimError(c, http.StatusBadRequest, "invalidValue", "active must be a boolean")
+					return
+				}
+				changes.active = &active
+			case "username":
+				userName, ok := value.(string)
+				if !ok || userName == "" {
+					scimError(c, http.StatusBadRequest, "invalidValue", "userName must be a string")
+					return
+				}
+				changes.userName = &userName
+			}
+		}
+	}
+	api.updateScimUser(ctx, c, changes)
+}
+
+// updateScimUser applies the changes to the user of the request. Deactivating a user deletes their
+// devices and removes them from the organizations of their groups, reactivating them adds them back
+// to the organizations of their groups.
+func (api *API) updateScimUser(ctx context.Context, c *gin.Context, changes scimUserChanges) {
+	userId := c.Param("id")
+
+	var user models.User
+	var membershipChanges groupMembershipChanges
+	var releases ipamReleases
+	reactivated := false
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		user = models.User{}
+		membershipChanges = groupMembershipChanges{}
+		releases = ipamReleases{}
+		reactivated = false
+		if res := tx.First(&user, "id = ?", userId); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errUserNotFound
+			}
+			return res.Error
+		}
+		if changes.userName != nil && *changes.userName != user.UserName {
+			user.UserName = *changes.userName
+			if res := tx.Model(&user).Update("user_name", user.UserName); res.Error != nil {
+				return res.Error
+			}
+		}
+		if changes.active == nil || *changes.active != user.Deactivated {
+			return nil
+		}
+		if *changes.active {
+			user.Deactivated = false
+			reactivated = true
+			return tx.Model(&user).UpdateColumn("deactivated", false).Error
+		}
+		var err error
+		membershipChanges, err = api.deactivateUser(withIPAMReleases(ctx, tx, &releases), tx, &user)
+		return err
+	})
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	if err := releases.run(ctx); err != nil {
+		api.Logger(ctx).Errorf("deactivating user %s: %v", user.ID, err)
+	}
+	api.notifyUserChanges(ctx, user.ID, membershipChanges)
+	if reactivated {
+		api.syncScimMembers(ctx, []string{user.ID})
+	}
+
+	resource, err := api.scimUser(api.db.WithContext(ctx), user)
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, resource)
+}
+
+// DeleteScimUser deprovisions a user of the identity provider. The user is deactivated, removed from
+// the organizations they don't own, and deleted. They can't log in again until they are provisioned again.
+func (api *API) DeleteScimUser(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteScimUser", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	var user models.User
+	var changes groupMembershipChanges
+	var releases ipamReleases
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		user = models.User{}
+		releases = ipamReleases{}
+		if res := tx.First(&user, "id = ?", c.Param("id")); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errUserNotFound
+			}
+			return res.Error
+		}
+		var err error
+		changes, err = api.deactivateUser(withIPAMReleases(ctx, tx, &releases), tx, &user)
+		if err != nil {
+			return err
+		}
+
+		var memberships []UserOrganization
+		if res := tx.Where("user_id = ? AND role <> ?", user.ID, models.RoleOwner).Find(&memberships); res.Error != nil {
+			return res.Error
+		}
+		for _, membership := range memberships {
+			if res := tx.Where("user_id = ? AND organization_id = ?", user.ID, membership.OrganizationID).
+				Delete(&UserOrganization{}); res.Error != nil {
+				return res.Error
+			}
+			before := models.OrganizationMember{UserID: user.ID, UserName: user.UserName, Role: membership.Role, GroupManaged: membership.GroupManaged}
+			if err := recordSystemAuditEvent(tx, membership.OrganizationID, scimActor, models.AuditActionDelete, "organization_member", user.ID, before, nil); err != nil {
+				return err
+			}
+			if err := touchOrganization(tx, membership.OrganizationID); err != nil {
+				return err
+			}
+			changes.organizations = append(changes.organizations, membership.OrganizationID)
+		}
+		if res := tx.Where("user_id = ?", user.ID).Delete(&models.ScimGroupMember{}); res.Error != nil {
+			return res.Error
+		}
+		return tx.Delete(&user).Error
+	})
+	if err != nil {
+		sendScimError(c, err)
+		return
+	}
+	if err := releases.run(ctx); err != nil {
+		api.Logger(ctx).Errorf("deleting user %s: %v", user.ID, err)
+	}
+	api.notifyUserChanges(ctx, user.ID, changes)
+	c.Status(http.StatusNoContent)
+}
+
+// scimUser returns the SCIM representation of the user.
+func (api *API) scimUser(tx *gorm.DB, user models.User) (models.ScimUser, error) {
+	var groups []models.ScimGroup
+	if res := tx.
+		Joins("inner join scim_group_members on scim_group_members.group_id=scim_groups.id").
+		Where("scim_group_members.user_id = ?", user.ID).
+		Order("scim_groups.display_name").
+		Find(&groups); res.Error != nil {
+		return models.ScimUser{}, res.Error
+	}
+	active := !user.Deactivated
+	resource := models.ScimUser{
+		Schemas:    []string{models.ScimSchemaUser},
+		ID:         user.ID,
+		ExternalID: strings.TrimPrefix(user.ID, api.scim.UserIDPrefix),
+		UserName:   user.UserName,
+		Active:     &active,
+		Meta:       scimMeta("User", fmt.Sprintf("/scim/v2/Users/%s", url.PathEscape(user.ID)), user.CreatedAt, user.UpdatedAt),
+	}
+	for _, group := range groups {
+		resource.Groups = append(resource.Groups, models.ScimReference{Value: group.ID.String(), Display: group.DisplayName})
+	}
+	return resource, nil
+}
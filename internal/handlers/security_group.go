@@ -21,10 +21,16 @@ import (
 // @Tags         SecurityGroup
 // @Accepts		 json
 // @Produce      json
+// @Param		 gt_revision       query     uint64  false "greater than revision"
 // @Param        organization_id   path      string  true "Organization ID"
+// @Param        q               query  string  false  "conditions on the fields, for example group_name^=web-"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
 // @Success      200  {object}  []models.SecurityGroup
+// @Failure		 400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 410  {object}  models.GoneError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Router       /api/organizations/{organization_id}/security_groups [get]
 func (api *API) ListSecurityGroups(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "ListSecurityGroups")
@@ -44,14 +50,51 @@ func (api *API) ListSecurityGroups(c *gin.Context) {
 		return
 	}
 
-	securityGroups := make([]models.SecurityGroup, 0)
-	result := api.db.WithContext(ctx).Where("organization_id = ?", orgId).Find(&securityGroups)
-
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "error fetching security groups from db"})
+	var query Query
+	if err := c.BindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiInternalError(err))
 		return
 	}
-	c.JSON(http.StatusOK, securityGroups)
+
+	defaultOrderBy := "group_name"
+	if v := c.Query("watch"); v == "true" {
+		query.Sort = ""
+		defaultOrderBy = "revision"
+	}
+
+	scopes := []func(*gorm.DB) *gorm.DB{
+		func(db *gorm.DB) *gorm.DB {
+			return db.Where("organization_id = ?", org.ID)
+		},
+		FilterAndPaginateWithQuery(&models.SecurityGroup{}, c, query, defaultOrderBy),
+	}
+
+	api.sendListOrWatch(c, ctx, []string{securityGroupsSignal(org.ID)}, "security_groups", scopes, getSecurityGroupList)
+}
+
+func getSecurityGroupList(db *gorm.DB) (WatchableList, error) {
+	var items securityGroupList
+	result := db.Find(&items)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, result.Error
+	}
+	return items, nil
+}
+
+type securityGroupList []*models.SecurityGroup
+
+func (d securityGroupList) Item(i int) (any, uint64, gorm.DeletedAt) {
+	item := d[i]
+	return item, item.Revision, item.DeletedAt
+}
+
+func (d securityGroupList) Len() int {
+	return len(d)
+}
+
+// securityGroupsSignal is notified when the security groups of an organization change.
+func securityGroupsSignal(orgId uuid.UUID) string {
+	return fmt.Sprintf("/security-groups/org=%s", orgId.String())
 }
 
 // GetSecurityGroup gets a Security Group by ID
@@ -67,7 +110,7 @@ func (api *API) ListSecurityGroups(c *gin.Context) {
 // @Failure		 401  {object}  models.BaseError
 // @Failure      400  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Router       /api/organizations/{organization_id}/security_group/{id} [get]
 func (api *API) GetSecurityGroup(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "GetSecurityGroup", trace.WithAttributes(
@@ -132,7 +175,7 @@ func (api *API) secGroupsEnabled(c *gin.Context) bool {
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
 // @Failure      409  {object}  models.ConflictsError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/organizations/{organization_id}/security_groups [post]
 func (api *API) CreateSecurityGroup(c *gin.Context) {
@@ -167,7 +210,7 @@ func (api *API) CreateSecurityGroup(c *gin.Context) {
 	err := api.transaction(ctx, func(tx *gorm.DB) error {
 		var org models.Organization
 		if res := api.db.WithContext(ctx).
-			Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+			Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
 			First(&org, "id = ?", request.OrganizationId); res.Error != nil {
 			return res.Error
 		}
@@ -189,6 +232,13 @@ func (api *API) CreateSecurityGroup(c *gin.Context) {
 			return res.Error
 		}
 
+		if err := api.recordAuditEvent(c, tx, org.ID, models.AuditActionCreate, "security_group", sg.ID.String(), nil, sg); err != nil {
+			return err
+		}
+		if err := api.queueWebhookEvent(tx, org.ID, models.WebhookEventSecurityGroupChanged, sg); err != nil {
+			return err
+		}
+
 		span.SetAttributes(attribute.String("id", sg.ID.String()))
 		api.logger.Infof("New security group created [ %s ] in organization [ %s ]", sg.GroupName, org.ID)
 		return nil
@@ -203,6 +253,9 @@ func (api *API) CreateSecurityGroup(c *gin.Context) {
 		return
 	}
 
+	api.signalBus.Notify(securityGroupsSignal(sg.OrganizationId))
+	api.notifyOrganizationsChanged(ctx, sg.OrganizationId)
+	api.signalBus.Notify(webhooksSignal)
 	c.JSON(http.StatusCreated, sg)
 }
 
@@ -217,7 +270,7 @@ func (api *API) CreateSecurityGroup(c *gin.Context) {
 // @Param        security_group_id   path      string  true "Security Group ID"
 // @Success      204  {object}  models.SecurityGroup
 // @Failure      400  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/organizations/{organization_id}/security_groups/{security_group_id} [delete]
 func (api *API) DeleteSecurityGroup(c *gin.Context) {
@@ -247,7 +300,7 @@ func (api *API) DeleteSecurityGroup(c *gin.Context) {
 
 	err = api.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var organization models.Organization
-		result := tx.Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+		result := tx.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
 			First(&organization, "id = ?", orgId.String())
 		if result.Error != nil {
 			return result.Error
@@ -275,7 +328,10 @@ func (api *API) DeleteSecurityGroup(c *gin.Context) {
 			return result.Error
 		}
 
-		return nil
+		if err := api.queueWebhookEvent(tx, organization.ID, models.WebhookEventSecurityGroupChanged, sg); err != nil {
+			return err
+		}
+		return api.recordAuditEvent(c, tx, organization.ID, models.AuditActionDelete, "security_group", sg.ID.String(), sg, nil)
 	})
 
 	if err != nil {
@@ -287,8 +343,10 @@ func (api *API) DeleteSecurityGroup(c *gin.Context) {
 		return
 	}
 
-	// TODO: signal bus notify of SecGroup deletes
-	//api.signalBus.Notify
+	api.signalBus.Notify(securityGroupsSignal(sg.OrganizationId))
+	api.notifyOrganizationsChanged(ctx, sg.OrganizationId)
+	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", sg.OrganizationId.String()))
+	api.signalBus.Notify(webhooksSignal)
 
 	c.JSON(http.StatusOK, sg)
 }
@@ -307,7 +365,7 @@ func (api *API) DeleteSecurityGroup(c *gin.Context) {
 // @Failure		 401  {object}  models.BaseError
 // @Failure      400  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Router       /api/organizations/{organization_id}/security_groups/{security_group_id} [patch]
 func (api *API) UpdateSecurityGroup(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "UpdateSecurityGroup", trace.WithAttributes(
@@ -341,7 +399,7 @@ func (api *API) UpdateSecurityGroup(c *gin.Context) {
 	err = api.transaction(ctx, func(tx *gorm.DB) error {
 		var org models.Organization
 		if res := tx.WithContext(ctx).
-			Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+			Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
 			First(&org, "id = ?", orgId); res.Error != nil {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("security_group"))
 			return res.Error
@@ -352,6 +410,7 @@ func (api *API) UpdateSecurityGroup(c *gin.Context) {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return errSecurityGroupNotFound
 		}
+		before := securityGroup
 
 		securityGroup.GroupName = request.GroupName
 		securityGroup.GroupDescription = request.GroupDescription
@@ -362,7 +421,10 @@ func (api *API) UpdateSecurityGroup(c *gin.Context) {
 			return res.Error
 		}
 
-		return nil
+		if err := api.queueWebhookEvent(tx, org.ID, models.WebhookEventSecurityGroupChanged, securityGroup); err != nil {
+			return err
+		}
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionUpdate, "security_group", securityGroup.ID.String(), before, securityGroup)
 	})
 
 	if err != nil {
@@ -376,6 +438,8 @@ func (api *API) UpdateSecurityGroup(c *gin.Context) {
 		return
 	}
 
+	api.signalBus.Notify(securityGroupsSignal(securityGroup.OrganizationId))
+	api.signalBus.Notify(webhooksSignal)
 	c.JSON(http.StatusOK, securityGroup)
 }
 
@@ -0,0 +1,521 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// ApiTokenPrefix is prepended to service account api tokens so they can be told apart from OIDC tokens
+const ApiTokenPrefix = "NT:"
+
+// key for the service account used to authenticate the request in gin.Context
+const AuthServiceAccount string = "_nexodus.ServiceAccount"
+
+// apiTokenLastUsedResolution limits how often the last used time of a token is written
+const apiTokenLastUsedResolution = time.Minute
+
+// ApiTokenScopes are the scopes that can be granted to an api token
+var ApiTokenScopes = []string{
+	"read:organizations",
+	"write:organizations",
+	"read:users",
+	"write:users",
+	"read:devices",
+	"write:devices",
+}
+
+var errServiceAccountNotFound = errors.New("service account not found")
+var errApiTokenNotFound = errors.New("api token not found")
+
+// CreateServiceAccount creates a service account
+// @Summary      Create a service account
+// @Description  Creates a service account owned by the organization
+// @Id           CreateServiceAccount
+// @Tags         ServiceAccount
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string                    true  "Organization ID"
+// @Param        ServiceAccount   body   models.AddServiceAccount  true  "Add ServiceAccount"
+// @Success      201  {object}  models.ServiceAccount
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/service_accounts [post]
+func (api *API) CreateServiceAccount(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateServiceAccount", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.AddServiceAccount
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.Name == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("name"))
+		return
+	}
+
+	var sa models.ServiceAccount
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			return errOrgNotFound
+		}
+
+		sa = models.ServiceAccount{
+			OrganizationID: org.ID,
+			Name:           request.Name,
+			Description:    request.Description,
+		}
+		if res := tx.Create(&sa); res.Error != nil {
+			return res.Error
+		}
+
+		// the service account acts as a member of the organization
+		user := models.User{
+			ID:            sa.ID.String(),
+			UserName:      serviceAccountUserName(sa),
+			Organizations: []*models.Organization{&org},
+		}
+		if res := tx.Create(&user); res.Error != nil {
+			return res.Error
+		}
+		if err := api.recordAuditEvent(c, tx, org.ID, models.AuditActionCreate, "service_account", sa.ID.String(), nil, sa); err != nil {
+			return err
+		}
+		span.SetAttributes(attribute.String("id", sa.ID.String()))
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, sa)
+}
+
+// ListServiceAccounts lists the service accounts of an organization
+// @Summary      List service accounts
+// @Description  Lists all service accounts in an organization
+// @Id           ListServiceAccounts
+// @Tags         ServiceAccount
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        q               query  string  false  "conditions on the fields, for example name^=ci-,created_at>=2023-06-01"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
+// @Success      200  {object}  []models.ServiceAccount
+// @Failure		 400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/service_accounts [get]
+func (api *API) ListServiceAccounts(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListServiceAccounts", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	serviceAccounts := make([]models.ServiceAccount, 0)
+	result := api.db.WithContext(ctx).
+		Where("organization_id = ?", org.ID).
+		Scopes(FilterAndPaginate(&models.ServiceAccount{}, c, "name")).
+		Find(&serviceAccounts)
+	if result.Error != nil {
+		sendListError(c, result.Error)
+		return
+	}
+	c.JSON(http.StatusOK, serviceAccounts)
+}
+
+// DeleteServiceAccount deletes a service account
+// @Summary      Delete a service account
+// @Description  Deletes a service account and revokes all of its api tokens
+// @Id           DeleteServiceAccount
+// @Tags         ServiceAccount
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        id               path   string  true  "ServiceAccount ID"
+// @Success      200  {object}  models.ServiceAccount
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/service_accounts/{id} [delete]
+func (api *API) DeleteServiceAccount(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteServiceAccount", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var sa models.ServiceAccount
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		sa, err = api.ownedServiceAccount(tx, c, orgId, id)
+		if err != nil {
+			return err
+		}
+		if res := tx.Where("service_account_id = ?", sa.ID).Delete(&models.ApiToken{}); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.Where("user_id = ?", sa.ID.String()).Delete(&UserOrganization{}); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.Delete(&models.User{}, "id = ?", sa.ID.String()); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.Delete(&sa); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, sa.OrganizationID, models.AuditActionDelete, "service_account", sa.ID.String(), sa, nil)
+	})
+	if err != nil {
+		api.sendServiceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sa)
+}
+
+// CreateApiToken issues an api token for a service account
+// @Summary      Create an api token
+// @Description  Issues a new api token for a service account
+// @Id           CreateApiToken
+// @Tags         ServiceAccount
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string              true  "Organization ID"
+// @Param        id               path   string              true  "ServiceAccount ID"
+// @Param        ApiToken         body   models.AddApiToken  true  "Add ApiToken"
+// @Success      201  {object}  models.ApiToken
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/service_accounts/{id}/tokens [post]
+func (api *API) CreateApiToken(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateApiToken", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var request models.AddApiToken
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.ExpiresAt != nil && request.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("expires_at", "must be in the future"))
+		return
+	}
+	if len(request.Scopes) == 0 {
+		request.Scopes = ApiTokenScopes
+	}
+	for _, scope := range request.Scopes {
+		if !isApiTokenScope(scope) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("scopes", fmt.Sprintf("unknown scope '%s'", scope)))
+			return
+		}
+	}
+
+	bearerToken, err := newBearerToken(ApiTokenPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+
+	var token models.ApiToken
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		sa, err := api.ownedServiceAccount(tx, c, orgId, id)
+		if err != nil {
+			return err
+		}
+		token = models.ApiToken{
+			ServiceAccountID: sa.ID,
+			Description:      request.Description,
+			TokenHash:        hashBearerToken(bearerToken),
+			Scopes:           request.Scopes,
+			ExpiresAt:        request.ExpiresAt,
+		}
+		if res := tx.Create(&token); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, sa.OrganizationID, models.AuditActionCreate, "api_token", token.ID.String(), nil, token)
+	})
+	if err != nil {
+		api.sendServiceAccountError(c, err)
+		return
+	}
+
+	// The token is only ever returned in this response.
+	token.BearerToken = bearerToken
+	c.JSON(http.StatusCreated, token)
+}
+
+// ListApiTokens lists the api tokens of a service account
+// @Summary      List api tokens
+// @Description  Lists the api tokens issued to a service account
+// @Id           ListApiTokens
+// @Tags         ServiceAccount
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        id               path   string  true  "ServiceAccount ID"
+// @Param        q               query  string  false  "conditions on the fields, for example last_used_at<2023-06-01"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
+// @Success      200  {object}  []models.ApiToken
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/service_accounts/{id}/tokens [get]
+func (api *API) ListApiTokens(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListApiTokens", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	sa, err := api.ownedServiceAccount(api.db.WithContext(ctx), c, orgId, id)
+	if err != nil {
+		api.sendServiceAccountError(c, err)
+		return
+	}
+
+	tokens := make([]models.ApiToken, 0)
+	result := api.db.WithContext(ctx).
+		Where("service_account_id = ?", sa.ID).
+		Scopes(FilterAndPaginate(&models.ApiToken{}, c, "created_at")).
+		Find(&tokens)
+	if result.Error != nil {
+		sendListError(c, result.Error)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// DeleteApiToken revokes an api token
+// @Summary      Delete an api token
+// @Description  Revokes an api token so it can no longer be used
+// @Id           DeleteApiToken
+// @Tags         ServiceAccount
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        id               path   string  true  "ServiceAccount ID"
+// @Param        token_id         path   string  true  "ApiToken ID"
+// @Success      200  {object}  models.ApiToken
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/service_accounts/{id}/tokens/{token_id} [delete]
+func (api *API) DeleteApiToken(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteApiToken", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+		attribute.String("token_id", c.Param("token_id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+	tokenId, err := uuid.Parse(c.Param("token_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("token_id"))
+		return
+	}
+
+	var token models.ApiToken
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		sa, err := api.ownedServiceAccount(tx, c, orgId, id)
+		if err != nil {
+			return err
+		}
+		if res := tx.Where("service_account_id = ?", sa.ID).
+			First(&token, "id = ?", tokenId); res.Error != nil {
+			return errApiTokenNotFound
+		}
+		if res := tx.Delete(&token); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(c, tx, sa.OrganizationID, models.AuditActionDelete, "api_token", token.ID.String(), token, nil)
+	})
+	if err != nil {
+		api.sendServiceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+// LookupApiToken returns the service account an api token was issued to and the token's scopes.
+// A nil service account is returned when the token is unknown, revoked or expired.
+func (api *API) LookupApiToken(ctx context.Context, bearerToken string) (*models.ServiceAccount, []string, error) {
+	ctx, span := tracer.Start(ctx, "LookupApiToken")
+	defer span.End()
+
+	var token models.ApiToken
+	if res := api.db.WithContext(ctx).
+		First(&token, "token_hash = ?", hashBearerToken(bearerToken)); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, res.Error
+	}
+	if token.IsExpired() {
+		return nil, nil, nil
+	}
+
+	var sa models.ServiceAccount
+	if res := api.db.WithContext(ctx).
+		First(&sa, "id = ?", token.ServiceAccountID); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, res.Error
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > apiTokenLastUsedResolution {
+		if res := api.db.WithContext(ctx).Model(&token).Update("last_used_at", now); res.Error != nil {
+			api.Logger(ctx).Warnf("failed to record api token use: %v", res.Error)
+		}
+	}
+	return &sa, token.Scopes, nil
+}
+
+// ServiceAccountFromContext returns the service account the request was authenticated as, if any.
+func ServiceAccountFromContext(c *gin.Context) (*models.ServiceAccount, bool) {
+	value, ok := c.Get(AuthServiceAccount)
+	if !ok {
+		return nil, false
+	}
+	sa, ok := value.(*models.ServiceAccount)
+	return sa, ok
+}
+
+func (api *API) ownedServiceAccount(db *gorm.DB, c *gin.Context, orgId uuid.UUID, id uuid.UUID) (models.ServiceAccount, error) {
+	var org models.Organization
+	if res := db.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		return models.ServiceAccount{}, errOrgNotFound
+	}
+	var sa models.ServiceAccount
+	if res := db.Where("organization_id = ?", org.ID).
+		First(&sa, "id = ?", id); res.Error != nil {
+		return models.ServiceAccount{}, errServiceAccountNotFound
+	}
+	return sa, nil
+}
+
+func (api *API) sendServiceAccountError(c *gin.Context, err error) {
+	if errors.Is(err, errOrgNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+	} else if errors.Is(err, errServiceAccountNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("service_account"))
+	} else if errors.Is(err, errApiTokenNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("api_token"))
+	} else {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+	}
+}
+
+func isApiTokenScope(scope string) bool {
+	for _, s := range ApiTokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func serviceAccountUserName(sa models.ServiceAccount) string {
+	return "service-account:" + sa.Name
+}
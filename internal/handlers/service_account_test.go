@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestServiceAccountTokens() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	reqBody, err := json.Marshal(models.AddServiceAccount{Name: "ci"})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(
+		http.MethodPost,
+		"/:organization/service_accounts", fmt.Sprintf("/%s/service_accounts", suite.testOrganizationID),
+		suite.api.CreateServiceAccount, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+
+	var sa models.ServiceAccount
+	require.NoError(json.Unmarshal(body, &sa))
+	assert.Equal(suite.testOrganizationID, sa.OrganizationID)
+
+	// the service account is a member of the organization
+	var count int64
+	require.NoError(suite.api.db.Model(&UserOrganization{}).
+		Where("user_id = ? AND organization_id = ?", sa.ID.String(), suite.testOrganizationID).
+		Count(&count).Error)
+	assert.Equal(int64(1), count)
+
+	reqBody, err = json.Marshal(models.AddApiToken{Scopes: []string{"bogus"}})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPost,
+		"/:organization/service_accounts/:id/tokens", fmt.Sprintf("/%s/service_accounts/%s/tokens", suite.testOrganizationID, sa.ID),
+		suite.api.CreateApiToken, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	assert.Equal(http.StatusBadRequest, res.Code)
+
+	reqBody, err = json.Marshal(models.AddApiToken{Scopes: []string{"read:devices"}})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPost,
+		"/:organization/service_accounts/:id/tokens", fmt.Sprintf("/%s/service_accounts/%s/tokens", suite.testOrganizationID, sa.ID),
+		suite.api.CreateApiToken, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+
+	var token models.ApiToken
+	require.NoError(json.Unmarshal(body, &token))
+	require.NotEmpty(token.BearerToken)
+
+	found, scopes, err := suite.api.LookupApiToken(context.Background(), token.BearerToken)
+	require.NoError(err)
+	require.NotNil(found)
+	assert.Equal(sa.ID, found.ID)
+	assert.Equal([]string{"read:devices"}, scopes)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet,
+		"/:organization/service_accounts/:id/tokens", fmt.Sprintf("/%s/service_accounts/%s/tokens", suite.testOrganizationID, sa.ID),
+		suite.api.ListApiTokens, nil,
+	)
+	require.NoError(err)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", string(body))
+
+	var tokens []models.ApiToken
+	require.NoError(json.Unmarshal(body, &tokens))
+	require.Len(tokens, 1)
+	assert.NotNil(tokens[0].LastUsedAt)
+	assert.Empty(tokens[0].BearerToken)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete,
+		"/:organization/service_accounts/:id/tokens/:token_id", fmt.Sprintf("/%s/service_accounts/%s/tokens/%s", suite.testOrganizationID, sa.ID, token.ID),
+		suite.api.DeleteApiToken, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+
+	found, _, err = suite.api.LookupApiToken(context.Background(), token.BearerToken)
+	require.NoError(err)
+	assert.Nil(found)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete,
+		"/:organization/service_accounts/:id", fmt.Sprintf("/%s/service_accounts/%s", suite.testOrganizationID, sa.ID),
+		suite.api.DeleteServiceAccount, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+}
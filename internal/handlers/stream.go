@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -14,115 +15,186 @@ import (
 	"gorm.io/gorm"
 )
 
+// watchBookmarkInterval is how often a bookmark event is sent to a watch that has not
+// been sent any other events.
+const watchBookmarkInterval = 30 * time.Second
+
 type WatchableList interface {
 	Len() int
+	// Item returns the item, its revision and when it was deleted. A nil item is not sent, it only
+	// advances the revision of the watch.
 	Item(i int) (any, uint64, gorm.DeletedAt)
 }
 
-func (api *API) sendListOrWatch(c *gin.Context, ctx context.Context, signal string, revisionCol string, scopes []func(*gorm.DB) *gorm.DB, getList func(db *gorm.DB) (WatchableList, error)) {
+// sendListOrWatch sends the list of items of the table, or when watch=true streams the changes to them
+// each time one of the signals is notified.
+func (api *API) sendListOrWatch(c *gin.Context, ctx context.Context, signals []string, table string, scopes []func(*gorm.DB) *gorm.DB, getList func(db *gorm.DB) (WatchableList, error)) {
 
 	gtRevision := uint64(0)
 	if v := c.Query("gt_revision"); v != "" {
 		gtRevision, _ = strconv.ParseUint(v, 10, 0)
 	}
+
+	if v := c.Query("watch"); v == "true" {
+
+		// the deletes of rows that have been compacted can't be sent, the client has to resync.
+		compacted, err := api.isRevisionCompacted(ctx, table, gtRevision)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+			return
+		}
+		if compacted {
+			c.JSON(http.StatusGone, models.NewGoneError(revisionCompactedReason(gtRevision)))
+			return
+		}
+
+		nextEvent, closeWatch := api.watchEvents(ctx, signals, table, gtRevision, scopes, getList)
+		defer closeWatch()
+
+		c.Header("Content-Type", "application/json;stream=watch")
+		c.Status(http.StatusOK)
+		stream(c, nextEvent)
+
+	} else {
+		scopes = append(scopes, gtRevisionScope(table, gtRevision))
+		api.sendList(c, ctx, getList, scopes)
+	}
+}
+
+func gtRevisionScope(table string, gtRevision uint64) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if gtRevision == 0 {
+			return db
+		}
+		return db.Where(table+".revision > ?", gtRevision)
+	}
+}
+
+// isRevisionCompacted returns true if soft deleted rows of the table that have a revision
+// greater than gtRevision have been purged.
+func (api *API) isRevisionCompacted(ctx context.Context, table string, gtRevision uint64) (bool, error) {
+	if gtRevision == 0 {
+		return false, nil
+	}
+	compacted, err := api.compactedRevision(ctx, table)
+	if err != nil {
+		return false, err
+	}
+	return gtRevision < compacted, nil
+}
+
+func revisionCompactedReason(gtRevision uint64) string {
+	return fmt.Sprintf("revision %d has been compacted, resync required", gtRevision)
+}
+
+// watchEvents returns a function that blocks until the next change to the items of the table
+// after gtRevision, and a function that must be called to release the watch. The events are
+// "change", "delete", "bookmark", "error", and "close" once ctx is canceled.
+func (api *API) watchEvents(ctx context.Context, signals []string, table string, gtRevision uint64, scopes []func(*gorm.DB) *gorm.DB, getList func(db *gorm.DB) (WatchableList, error)) (func() models.WatchEvent, func()) {
+
+	// fmt.Sprintf("/devices/org=%s", k.String())
+	var subs []*signalbus.Subscription
+	for _, signal := range signals {
+		subs = append(subs, api.signalBus.Subscribe(signal))
+	}
+	closeSubs := func() {
+		for _, sub := range subs {
+			sub.Close()
+		}
+	}
+
+	idx := 1
+	var list WatchableList
+	var bookmarkSentAt time.Time
+	lastEventAt := time.Now()
+	var err error
+
 	scopes = append(scopes,
 		func(db *gorm.DB) *gorm.DB {
-			if gtRevision == 0 {
-				return db
-			}
-			return db.Where(revisionCol+" > ?", gtRevision)
+			// gtRevision advances as the events are returned.
+			return gtRevisionScope(table, gtRevision)(db)
+		},
+		func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
 		},
 	)
 
-	if v := c.Query("watch"); v == "true" {
+	return func() models.WatchEvent {
+		// This function blocks until there is an event to return...
+		for {
+			if err != nil {
+				return models.WatchEvent{
+					Type:  "error",
+					Value: err.Error(),
+				}
+			}
+			if list != nil && idx < list.Len() {
 
-		// fmt.Sprintf("/devices/org=%s", k.String())
-		sub := api.signalBus.Subscribe(signal)
-		defer sub.Close()
+				result, revision, deletedAt := list.Item(idx)
+				gtRevision = revision
+				idx += 1
+				if result == nil {
+					continue
+				}
+				lastEventAt = time.Now()
 
-		idx := 1
-		var list WatchableList
-		bookmarkSent := false
-		var err error
+				if deletedAt.Valid {
+					return models.WatchEvent{
+						Type:  "delete",
+						Value: result,
+					}
+				} else {
+					return models.WatchEvent{
+						Type:  "change",
+						Value: result,
+					}
+				}
+			} else {
 
-		scopes = append(scopes,
-			func(db *gorm.DB) *gorm.DB {
-				return db.Unscoped()
-			},
-		)
+				// get the next list...
+				db := api.db.WithContext(ctx)
 
-		c.Header("Content-Type", "application/json;stream=watch")
-		c.Status(http.StatusOK)
-		stream(c, func() models.WatchEvent {
-			// This function blocks until there is an event to return...
-			for {
+				for _, scope := range scopes {
+					db = scope(db)
+				}
+				list, err = getList(db)
 				if err != nil {
 					return models.WatchEvent{
 						Type:  "error",
 						Value: err.Error(),
 					}
 				}
-				if list != nil && idx < list.Len() {
+				idx = 0
 
-					result, revision, deletedAt := list.Item(idx)
-					gtRevision = revision
-					idx += 1
+				// did we run out of items to send?
+				if list.Len() == 0 {
 
-					if deletedAt.Valid {
-						return models.WatchEvent{
-							Type:  "delete",
-							Value: result,
-						}
-					} else {
+					// bookmark idea taken from: https://kubernetes.io/docs/reference/using-api/api-concepts/#watch-bookmarks
+					// The first bookmark tells the client it has received the full list, the periodic
+					// ones let idle clients resume from a recent revision. The bookmark holds the last
+					// revision listed in the scope of the watch, not the latest revision of the table,
+					// which would skip the rows of the transactions that took a lower revision but have
+					// not committed yet.
+					if bookmarkSentAt.IsZero() || time.Since(lastEventAt) >= watchBookmarkInterval && time.Since(bookmarkSentAt) >= watchBookmarkInterval {
+						bookmarkSentAt = time.Now()
 						return models.WatchEvent{
-							Type:  "change",
-							Value: result,
+							Type:  "bookmark",
+							Value: models.WatchBookmark{Revision: gtRevision},
 						}
 					}
-				} else {
-
-					// get the next list...
-					db := api.db.WithContext(ctx)
 
-					for _, scope := range scopes {
-						db = scope(db)
-					}
-					list, err = getList(db)
-					if err != nil {
+					// Wait for some items to come into the list
+					if waitForCancelOrTimeoutOrNotifications(ctx, watchBookmarkInterval, subs) {
+						// ctx was canceled... likely due to the http connection being closed by
+						// the client.  Signal the event stream is done.
 						return models.WatchEvent{
-							Type:  "error",
-							Value: err.Error(),
-						}
-					}
-					idx = 0
-
-					// did we run out of items to send?
-					if list.Len() == 0 {
-
-						// bookmark idea taken from: https://kubernetes.io/docs/reference/using-api/api-concepts/#watch-bookmarks
-						if !bookmarkSent {
-							bookmarkSent = true
-							return models.WatchEvent{
-								Type: "bookmark",
-							}
-						}
-
-						// Wait for some items to come into the list
-						if waitForCancelOrTimeoutOrNotification(ctx, 30*time.Second, sub) {
-							// ctx was canceled... likely due to the http connection being closed by
-							// the client.  Signal the event stream is done.
-							return models.WatchEvent{
-								Type: "close",
-							}
+							Type: "close",
 						}
 					}
 				}
 			}
-		})
-
-	} else {
-		api.sendList(c, ctx, getList, scopes)
-	}
+		}
+	}, closeSubs
 }
 
 func (api *API) sendList(c *gin.Context, ctx context.Context, getList func(db *gorm.DB) (WatchableList, error), scopes []func(*gorm.DB) *gorm.DB) {
@@ -132,13 +204,15 @@ func (api *API) sendList(c *gin.Context, ctx context.Context, getList func(db *g
 	}
 	items, err := getList(db)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		sendListError(c, err)
 		return
 	}
 
-	// For pagination
-	c.Header("Access-Control-Expose-Headers", TotalCountHeader)
-	c.Header(TotalCountHeader, strconv.Itoa(items.Len()))
+	// For pagination, FilterAndPaginate sets the total count when a range was requested.
+	if c.Writer.Header().Get(TotalCountHeader) == "" {
+		c.Header("Access-Control-Expose-Headers", exposedListHeaders)
+		c.Header(TotalCountHeader, strconv.Itoa(items.Len()))
+	}
 	c.JSON(http.StatusOK, items)
 }
 
@@ -175,3 +249,22 @@ func waitForCancelOrTimeoutOrNotification(ctx context.Context, timeout time.Dura
 		return true
 	}
 }
+
+// waitForCancelOrTimeoutOrNotifications returns true if the context has been canceled or false after
+// the timeout or a signal of any of the subscriptions.
+func waitForCancelOrTimeoutOrNotifications(ctx context.Context, timeout time.Duration, subs []*signalbus.Subscription) bool {
+	if len(subs) == 1 {
+		return waitForCancelOrTimeoutOrNotification(ctx, timeout, subs[0])
+	}
+	tc, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(tc.Done())},
+	}
+	for _, sub := range subs {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.Signal())})
+	}
+	chosen, _, _ := reflect.Select(cases)
+	return chosen == 0
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/ipam"
 	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/nexodus-io/nexodus/internal/util"
 	"go.opentelemetry.io/otel/attribute"
@@ -25,10 +26,24 @@ var noUUID = uuid.UUID{}
 
 // CacheExp Zero expiration means the key has no expiration time.
 const CacheExp time.Duration = 0
+
+// usersSignal is notified when a user is created, renamed or deleted.
+func usersSignal(userId string) string {
+	return fmt.Sprintf("/users/id=%s", userId)
+}
+
 const CachePrefix = "user:"
 
 func (api *API) CreateUserIfNotExists() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// registration keys are always owned by an existing user, and service accounts
+		// are created with their user.
+		if _, ok := RegKeyFromContext(c); ok {
+			return
+		}
+		if _, ok := ServiceAccountFromContext(c); ok {
+			return
+		}
 		id := c.GetString(gin.AuthUserKey)
 		username := c.GetString(AuthUserName)
 		prefixId := fmt.Sprintf("%s:%s", CachePrefix, id)
@@ -42,13 +57,20 @@ func (api *API) CreateUserIfNotExists() gin.HandlerFunc {
 		}
 		if cachedUsername == "" {
 			_, err := api.createUserIfNotExists(c.Request.Context(), id, username)
+			if errors.Is(err, errUserDeactivated) {
+				c.AbortWithStatusJSON(http.StatusForbidden, models.NewNotAllowedError(errUserDeactivated.Error()))
+				return
+			}
 			if err != nil {
 				_ = c.AbortWithError(http.StatusInternalServerError, err)
 				return
 			}
 			api.redis.Set(c.Request.Context(), prefixId, username, CacheExp)
 		}
-		c.Next()
+		if err := api.syncUserGroups(c, id); err != nil {
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
 	}
 }
 
@@ -70,6 +92,7 @@ func (api *API) createUserIfNotExists(ctx context.Context, id string, userName s
 	)
 	var user models.User
 	var uuid uuid.UUID
+	changed := false
 
 	// Retry the operation if we get a duplicate key error which can occur on concurrent requests when creating a user
 	err := util.RetryOperationForErrors(ctx, time.Millisecond*10, 1, []error{gorm.ErrDuplicatedKey}, func() error {
@@ -79,11 +102,15 @@ func (api *API) createUserIfNotExists(ctx context.Context, id string, userName s
 
 			// If the user exists, then lets restore their status in the database
 			if res.Error == nil {
+				if user.Deactivated {
+					return errUserDeactivated
+				}
 				if user.DeletedAt.Valid {
 					user.DeletedAt = gorm.DeletedAt{}
 					if res := tx.Unscoped().Model(&user).Update("DeletedAt", user.DeletedAt); res.Error != nil {
 						return res.Error
 					}
+					changed = true
 				}
 
 				// Check if the UserName has changed since the last time we saw this user
@@ -91,6 +118,7 @@ func (api *API) createUserIfNotExists(ctx context.Context, id string, userName s
 					if res := tx.Model(&user).Update("UserName", userName); res.Error != nil {
 						return res.Error
 					}
+					changed = true
 				}
 
 				var err error
@@ -111,6 +139,7 @@ func (api *API) createUserIfNotExists(ctx context.Context, id string, userName s
 			if res = tx.Create(&user); res.Error != nil {
 				return res.Error
 			}
+			changed = true
 			var err error
 			uuid, err = api.createUserOrgIfNotExists(ctx, tx, id, userName)
 			if err != nil {
@@ -124,6 +153,10 @@ func (api *API) createUserIfNotExists(ctx context.Context, id string, userName s
 	if err != nil {
 		return noUUID, fmt.Errorf("can't create user record: %w", err)
 	}
+	if changed {
+		api.signalBus.Notify(usersSignal(id))
+		api.signalBus.Notify(organizationsSignal(id))
+	}
 
 	if uuid != noUUID {
 		return uuid, nil
@@ -133,6 +166,7 @@ func (api *API) createUserIfNotExists(ctx context.Context, id string, userName s
 }
 
 func (api *API) createUserOrgIfNotExists(ctx context.Context, tx *gorm.DB, userId string, userName string) (uuid.UUID, error) {
+	ctx = ipam.WithTransaction(ctx, tx)
 	// Get the first org the use owns.
 	org := models.Organization{}
 	res := api.db.Where("owner_id = ?", userId).First(&org)
@@ -169,6 +203,9 @@ func (api *API) createUserOrgIfNotExists(ctx context.Context, tx *gorm.DB, userI
 
 		return noUUID, fmt.Errorf("can't create organization record: %w", res.Error)
 	}
+	if err := setOrganizationRole(tx, org.ID, userId, models.RoleOwner); err != nil {
+		return noUUID, fmt.Errorf("can't set organization owner role: %w", err)
+	}
 
 	ipamNamespace := defaultIPAMNamespace
 
@@ -208,7 +245,7 @@ func (api *API) createUserOrgIfNotExists(ctx context.Context, tx *gorm.DB, userI
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/users/{id} [get]
 func (api *API) GetUser(c *gin.Context) {
@@ -244,24 +281,56 @@ func (api *API) GetUser(c *gin.Context) {
 // @Tags         Users
 // @Accept       json
 // @Produce      json
+// @Param		 gt_revision     query  uint64 false "greater than revision"
+// @Param        q               query  string  false  "conditions on the fields, for example user_name^=alice"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
 // @Success      200  {object}  []models.User
+// @Failure		 400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 410  {object}  models.GoneError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Router       /api/users [get]
 func (api *API) ListUsers(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "ListUsers")
 	defer span.End()
-	users := make([]*models.User, 0)
-	result := api.db.WithContext(ctx).
-		Scopes(api.UserIsCurrentUser(c)).
-		Scopes(FilterAndPaginate(&models.User{}, c, "user_name")).
-		Find(&users)
 
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "error fetching keys from db"})
+	var query Query
+	if err := c.BindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiInternalError(err))
 		return
 	}
-	c.JSON(http.StatusOK, users)
+
+	defaultOrderBy := "user_name"
+	if v := c.Query("watch"); v == "true" {
+		query.Sort = ""
+		defaultOrderBy = "revision"
+	}
+
+	scopes := []func(*gorm.DB) *gorm.DB{
+		api.UserIsCurrentUser(c),
+		FilterAndPaginateWithQuery(&models.User{}, c, query, defaultOrderBy),
+	}
+
+	api.sendListOrWatch(c, ctx, []string{usersSignal(c.Value(gin.AuthUserKey).(string))}, "users", scopes, func(db *gorm.DB) (WatchableList, error) {
+		var items userList
+		result := db.Find(&items)
+		if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, result.Error
+		}
+		return items, nil
+	})
+}
+
+type userList []*models.User
+
+func (d userList) Item(i int) (any, uint64, gorm.DeletedAt) {
+	item := d[i]
+	return item, item.Revision, item.DeletedAt
+}
+
+func (d userList) Len() int {
+	return len(d)
 }
 
 // DeleteUser delete a user
@@ -275,7 +344,7 @@ func (api *API) ListUsers(c *gin.Context) {
 // @Success      200  {object}  models.User
 // @Failure		 400  {object}  models.BaseError
 // @Failure      400  {object}  models.BaseError
-// @Failure		 429  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/users/{id} [delete]
 func (api *API) DeleteUser(c *gin.Context) {
@@ -310,6 +379,10 @@ func (api *API) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	api.signalBus.Notify(usersSignal(userID))
+	api.signalBus.Notify(organizationsSignal(userID))
+	api.signalBus.Notify(invitationsSignal(userID))
+
 	// delete the cached user
 	prefixId := fmt.Sprintf("%s:%s", CachePrefix, userID)
 	_, err = api.redis.Del(c.Request.Context(), prefixId).Result()
@@ -322,6 +395,10 @@ func (api *API) DeleteUser(c *gin.Context) {
 type UserOrganization struct {
 	UserID         string    `json:"user_id"`
 	OrganizationID uuid.UUID `json:"organization_id"`
+	Role           string    `json:"role" gorm:"default:member"`
+	// GroupManaged is set on the memberships added by an organization group mapping, which are
+	// removed when the user leaves the groups mapped to the organization.
+	GroupManaged bool `json:"group_managed" gorm:"default:false"`
 }
 
 // DeleteUserFromOrganization removes a user from an organization
@@ -335,7 +412,8 @@ type UserOrganization struct {
 // @Param        organization   path      string  true "Organization ID"
 // @Success      204  {object}  models.User
 // @Failure      400  {object}  models.BaseError
-// @Failure      400  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/users/{id}/organizations/{organization} [delete]
 func (api *API) DeleteUserFromOrganization(c *gin.Context) {
@@ -357,33 +435,58 @@ func (api *API) DeleteUserFromOrganization(c *gin.Context) {
 	var user models.User
 	var organization models.Organization
 	err := api.transaction(ctx, func(tx *gorm.DB) error {
-		if res := api.db.First(&user, "id = ?", userID); res.Error != nil {
+		if res := tx.First(&user, "id = ?", userID); res.Error != nil {
 			return errUserNotFound
 		}
-		if res := api.db.First(&organization, "id = ?", orgID); res.Error != nil {
+		// users can leave an organization, otherwise only org admins can remove them.
+		orgScope := api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)
+		if userID == c.Value(gin.AuthUserKey).(string) {
+			orgScope = api.OrganizationIsReadableByCurrentUser(c)
+		}
+		if res := tx.Scopes(orgScope).First(&organization, "id = ?", orgID); res.Error != nil {
 			return errOrgNotFound
 		}
-		if res := api.db.
+		if organization.OwnerID == userID {
+			return errOrgOwnerRemoval
+		}
+		var membership UserOrganization
+		if res := tx.First(&membership, "user_id = ? AND organization_id = ?", userID, organization.ID); res.Error != nil {
+			return errUserNotFound
+		}
+		if res := tx.
 			Select(clause.Associations).
 			Where("user_id = ?", userID).
 			Where("organization_id = ?", orgID).
 			Delete(&UserOrganization{}); res.Error != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to remove the association from the user_organizations table: %w", res.Error)))
+			return fmt.Errorf("failed to remove the association from the user_organizations table: %w", res.Error)
 		}
-		return nil
+		if err := touchOrganization(tx, organization.ID); err != nil {
+			return err
+		}
+		return api.recordAuditEvent(c, tx, organization.ID, models.AuditActionDelete, "organization_member", user.ID, models.OrganizationMember{
+			UserID:   user.ID,
+			UserName: user.UserName,
+			Role:     membership.Role,
+		}, nil)
 	})
 
 	if err != nil {
 		if errors.Is(err, errUserNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("user"))
-		}
-		if errors.Is(err, errOrgNotFound) {
+		} else if errors.Is(err, errOrgNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errOrgOwnerRemoval) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
 		return
 	}
+	// the removed user no longer reads the organization, their watches send it as deleted.
+	api.signalBus.Notify(organizationsSignal(userID))
+	api.signalBus.Notify(invitationsSignal(userID))
+	api.notifyOrganizationsChanged(ctx, organization.ID)
+
 	// delete the cached user
 	prefixId := fmt.Sprintf("%s:%s", CachePrefix, userID)
 	_, err = api.redis.Del(c.Request.Context(), prefixId).Result()
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tombstoneCompactionInterval is how often the soft deleted rows are checked for compaction.
+const tombstoneCompactionInterval = 10 * time.Minute
+
+// watchableTables are the tables that can be watched, in the order their tombstones are compacted
+// so that rows are purged before the rows they reference.
+var watchableTables = []string{
+	"device_metadata",
+	"devices",
+	"invitations",
+	"security_groups",
+	"organizations",
+	"users",
+}
+
+// keptTombstones excludes the deleted rows that must not be purged from the compaction of a table.
+// The rows of the deactivated users block their logins until the identity provider restores them.
+var keptTombstones = map[string]string{
+	"users": "deactivated = false",
+}
+
+// tombstoneState holds the latest revisions of the watchable tables recorded by the compaction worker.
+type tombstoneState struct {
+	mu        sync.Mutex
+	snapshots []revisionSnapshot
+}
+
+// revisionSnapshot is the latest revision of each watchable table at a time.
+type revisionSnapshot struct {
+	at        time.Time
+	revisions map[string]uint64
+}
+
+// StartTombstoneCompaction starts the background worker that purges the rows that were soft deleted
+// more than retention ago. Watches resuming from a revision before the purged rows have to resync.
+func (api *API) StartTombstoneCompaction(ctx context.Context, wg *sync.WaitGroup, retention time.Duration) {
+	util.GoWithWaitGroup(wg, func() {
+		ticker := time.NewTicker(tombstoneCompactionInterval)
+		defer ticker.Stop()
+		for {
+			now := time.Now()
+			if err := api.recordRevisions(ctx, now); err != nil {
+				api.logger.Errorf("failed to record the revisions of the watchable tables: %v", err)
+			}
+			api.compactTombstones(ctx, now.Add(-retention))
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// recordRevisions records the latest revision of each watchable table. Revisions are taken from
+// sequences before the transactions that use them commit, so a revision is only known to be
+// committed, along with all the revisions below it, long after it was the latest one.
+func (api *API) recordRevisions(ctx context.Context, at time.Time) error {
+	snapshot := revisionSnapshot{at: at, revisions: map[string]uint64{}}
+	for _, table := range watchableTables {
+		var revision uint64
+		if res := api.db.WithContext(ctx).Table(table).Select("COALESCE(MAX(revision), 0)").Scan(&revision); res.Error != nil {
+			return res.Error
+		}
+		snapshot.revisions[table] = revision
+	}
+	api.tombstones.mu.Lock()
+	defer api.tombstones.mu.Unlock()
+	api.tombstones.snapshots = append(api.tombstones.snapshots, snapshot)
+	return nil
+}
+
+// revisionHorizon returns the latest revisions recorded at or before the given time, and forgets
+// the older snapshots. It returns nil when no revisions were recorded that long ago.
+func (api *API) revisionHorizon(before time.Time) map[string]uint64 {
+	api.tombstones.mu.Lock()
+	defer api.tombstones.mu.Unlock()
+	found := -1
+	for i, snapshot := range api.tombstones.snapshots {
+		if !snapshot.at.After(before) {
+			found = i
+		}
+	}
+	if found < 0 {
+		return nil
+	}
+	api.tombstones.snapshots = api.tombstones.snapshots[found:]
+	return api.tombstones.snapshots[0].revisions
+}
+
+// compactTombstones purges the rows of the watchable tables that were soft deleted before the given time,
+// and records the highest revision purged from each table. Only the rows whose revision was already
+// recorded before that time are purged, so that no transaction that could still commit a lower revision
+// is running, and the watches that resume past the compacted revision have seen every purged row.
+func (api *API) compactTombstones(ctx context.Context, before time.Time) {
+	horizon := api.revisionHorizon(before)
+	if horizon == nil {
+		return
+	}
+	for _, table := range watchableTables {
+		if ctx.Err() != nil {
+			return
+		}
+		err := api.transaction(ctx, func(tx *gorm.DB) error {
+			where := "deleted_at < ? AND revision <= ?"
+			if keep, ok := keptTombstones[table]; ok {
+				where += " AND " + keep
+			}
+			var revision uint64
+			if res := tx.Table(table).Where(where, before, horizon[table]).Select("COALESCE(MAX(revision), 0)").Scan(&revision); res.Error != nil {
+				return res.Error
+			}
+			if revision == 0 {
+				return nil
+			}
+			res := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", table, where), before, horizon[table])
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return nil
+			}
+			api.logger.Infof("compacted %d deleted rows of %s up to revision %d", res.RowsAffected, table, revision)
+
+			if res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.CompactedRevision{Name: table}); res.Error != nil {
+				return res.Error
+			}
+			// never move the compacted revision backwards.
+			return tx.Model(&models.CompactedRevision{}).
+				Where("name = ? AND revision < ?", table, revision).
+				Updates(map[string]interface{}{"revision": revision, "updated_at": time.Now()}).Error
+		})
+		if err != nil {
+			api.logger.Errorf("failed to compact the deleted rows of %s: %v", table, err)
+		}
+	}
+}
+
+// compactedRevision returns the highest revision that has been compacted from the table.
+func (api *API) compactedRevision(ctx context.Context, table string) (uint64, error) {
+	var compacted models.CompactedRevision
+	if res := api.db.WithContext(ctx).First(&compacted, "name = ?", table); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, res.Error
+	}
+	return compacted.Revision, nil
+}
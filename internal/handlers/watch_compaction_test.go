@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+	"gorm.io/gorm"
+)
+
+func (suite *HandlerTestSuite) TestTombstoneCompaction() {
+	require := suite.Require()
+	assert := suite.Assert()
+	ctx := context.Background()
+
+	purged := models.SecurityGroup{GroupName: "purged", OrganizationId: suite.testOrganizationID}
+	kept := models.SecurityGroup{GroupName: "kept", OrganizationId: suite.testOrganizationID}
+	require.NoError(suite.api.db.Create(&purged).Error)
+	require.NoError(suite.api.db.Create(&kept).Error)
+	require.NoError(suite.api.db.Delete(&purged).Error)
+	require.NoError(suite.api.db.Delete(&kept).Error)
+
+	// sqlite does not maintain the revisions, so set them here.
+	require.NoError(suite.api.db.Unscoped().Model(&purged).UpdateColumns(map[string]interface{}{
+		"deleted_at": time.Now().Add(-48 * time.Hour),
+		"revision":   42,
+	}).Error)
+	require.NoError(suite.api.db.Unscoped().Model(&kept).UpdateColumn("revision", 43).Error)
+
+	// nothing is compacted until the revisions were recorded before the retention.
+	suite.api.tombstones = tombstoneState{}
+	suite.api.compactTombstones(ctx, time.Now().Add(-24*time.Hour))
+	var count int64
+	require.NoError(suite.api.db.Unscoped().Model(&models.SecurityGroup{}).Where("id = ?", purged.ID).Count(&count).Error)
+	assert.Equal(int64(1), count)
+
+	require.NoError(suite.api.recordRevisions(ctx, time.Now().Add(-25*time.Hour)))
+	// a row deleted after the revisions were recorded isn't compacted.
+	late := models.SecurityGroup{GroupName: "late", OrganizationId: suite.testOrganizationID}
+	require.NoError(suite.api.db.Create(&late).Error)
+	require.NoError(suite.api.db.Delete(&late).Error)
+	require.NoError(suite.api.db.Unscoped().Model(&late).UpdateColumns(map[string]interface{}{
+		"deleted_at": time.Now().Add(-48 * time.Hour),
+		"revision":   44,
+	}).Error)
+
+	suite.api.compactTombstones(ctx, time.Now().Add(-24*time.Hour))
+
+	require.NoError(suite.api.db.Unscoped().Model(&models.SecurityGroup{}).Where("id = ?", purged.ID).Count(&count).Error)
+	assert.Equal(int64(0), count)
+	require.NoError(suite.api.db.Unscoped().Model(&models.SecurityGroup{}).Where("id = ?", kept.ID).Count(&count).Error)
+	assert.Equal(int64(1), count)
+	require.NoError(suite.api.db.Unscoped().Model(&models.SecurityGroup{}).Where("id = ?", late.ID).Count(&count).Error)
+	assert.Equal(int64(1), count)
+
+	compacted, err := suite.api.compactedRevision(ctx, "security_groups")
+	require.NoError(err)
+	assert.Equal(uint64(42), compacted)
+
+	// a watch can't resume from before the compacted revision.
+	_, res, err := suite.ServeRequest(
+		http.MethodGet, "/organizations/:organization/security_groups", fmt.Sprintf("/organizations/%s/security_groups?watch=true&gt_revision=10", suite.testOrganizationID),
+		suite.api.ListSecurityGroups, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusGone, res.Code)
+
+	var goneErr models.GoneError
+	require.NoError(json.NewDecoder(res.Body).Decode(&goneErr))
+	assert.Contains(goneErr.Reason, "resync required")
+
+	// the compacted revision never moves backwards.
+	require.NoError(suite.api.db.Unscoped().Model(&kept).UpdateColumns(map[string]interface{}{
+		"deleted_at": time.Now().Add(-48 * time.Hour),
+		"revision":   7,
+	}).Error)
+	suite.api.compactTombstones(ctx, time.Now().Add(-24*time.Hour))
+	compacted, err = suite.api.compactedRevision(ctx, "security_groups")
+	require.NoError(err)
+	assert.Equal(uint64(42), compacted)
+}
+
+func (suite *HandlerTestSuite) TestTombstoneCompactionKeepsDeactivatedUsers() {
+	require := suite.Require()
+	assert := suite.Assert()
+	ctx := context.Background()
+
+	deleted := models.User{ID: "compacted-user", UserName: "compacted"}
+	deactivated := models.User{ID: "deactivated-user", UserName: "deactivated", Deactivated: true}
+	require.NoError(suite.api.db.Create(&deleted).Error)
+	require.NoError(suite.api.db.Create(&deactivated).Error)
+	for _, user := range []models.User{deleted, deactivated} {
+		require.NoError(suite.api.db.Unscoped().Model(&user).UpdateColumns(map[string]interface{}{
+			"deleted_at": time.Now().Add(-48 * time.Hour),
+			"revision":   1,
+		}).Error)
+	}
+
+	suite.api.tombstones = tombstoneState{}
+	require.NoError(suite.api.recordRevisions(ctx, time.Now().Add(-25*time.Hour)))
+	suite.api.compactTombstones(ctx, time.Now().Add(-24*time.Hour))
+
+	var count int64
+	require.NoError(suite.api.db.Unscoped().Model(&models.User{}).Where("id = ?", deleted.ID).Count(&count).Error)
+	assert.Equal(int64(0), count)
+	// the deactivated users stay blocked.
+	require.NoError(suite.api.db.Unscoped().Model(&models.User{}).Where("id = ?", deactivated.ID).Count(&count).Error)
+	assert.Equal(int64(1), count)
+	_, err := suite.api.createUserIfNotExists(ctx, deactivated.ID, deactivated.UserName)
+	assert.ErrorIs(err, errUserDeactivated)
+}
+
+func (suite *HandlerTestSuite) TestWatchBookmarkRevision() {
+	require := suite.Require()
+	assert := suite.Assert()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watched := models.SecurityGroup{GroupName: "watched", OrganizationId: suite.testOrganizationID}
+	other := models.SecurityGroup{GroupName: "other", OrganizationId: suite.testUser2OrgID}
+	require.NoError(suite.api.db.Create(&watched).Error)
+	require.NoError(suite.api.db.Create(&other).Error)
+	// sqlite does not maintain the revisions, so set them here.
+	require.NoError(suite.api.db.Model(&watched).UpdateColumn("revision", 5).Error)
+	require.NoError(suite.api.db.Model(&other).UpdateColumn("revision", 9).Error)
+
+	scopes := []func(*gorm.DB) *gorm.DB{
+		func(db *gorm.DB) *gorm.DB {
+			return db.Where("organization_id = ?", suite.testOrganizationID).Order("revision")
+		},
+	}
+	signals := []string{securityGroupsSignal(suite.testOrganizationID)}
+
+	// the bookmark holds the last revision of the watch, not the higher revisions of other organizations.
+	nextEvent, closeWatch := suite.api.watchEvents(ctx, signals, "security_groups", 0, scopes, getSecurityGroupList)
+	defer closeWatch()
+	event := nextEvent()
+	for ; event.Type != "bookmark"; event = nextEvent() {
+		require.Equal("change", event.Type, event.Value)
+	}
+	assert.Equal(models.WatchBookmark{Revision: 5}, event.Value)
+
+	// a resumed watch without changes bookmarks the revision it resumed from.
+	resumedEvent, closeResumed := suite.api.watchEvents(ctx, signals, "security_groups", 7, scopes, getSecurityGroupList)
+	defer closeResumed()
+	event = resumedEvent()
+	require.Equal("bookmark", event.Type, event.Value)
+	assert.Equal(models.WatchBookmark{Revision: 7}, event.Value)
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var errWebhookNotFound = errors.New("webhook not found")
+
+// CreateWebhook creates a webhook
+// @Summary      Create a webhook
+// @Description  Subscribes a URL to the events of an organization
+// @Id           CreateWebhook
+// @Tags         Webhook
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string             true  "Organization ID"
+// @Param        Webhook          body   models.AddWebhook  true  "Add Webhook"
+// @Success      201  {object}  models.Webhook
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/webhooks [post]
+func (api *API) CreateWebhook(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateWebhook", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.AddWebhook
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.URL == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("url"))
+		return
+	}
+	if u, err := url.Parse(request.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("url", "must be an http or https url"))
+		return
+	}
+	for _, event := range request.Events {
+		if !isWebhookEvent(event) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("events", fmt.Sprintf("unknown event '%s'", event)))
+			return
+		}
+	}
+
+	secret, err := newBearerToken("")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+
+	var webhook models.Webhook
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			return errOrgNotFound
+		}
+
+		webhook = models.Webhook{
+			OrganizationID: org.ID,
+			URL:            request.URL,
+			Description:    request.Description,
+			Events:         request.Events,
+			Secret:         secret,
+		}
+		if res := tx.Create(&webhook); res.Error != nil {
+			return res.Error
+		}
+		audited := webhook
+		audited.Secret = ""
+		return api.recordAuditEvent(c, tx, org.ID, models.AuditActionCreate, "webhook", webhook.ID.String(), nil, audited)
+	})
+	if err != nil {
+		api.sendWebhookError(c, err)
+		return
+	}
+	span.SetAttributes(attribute.String("id", webhook.ID.String()))
+
+	// The secret is only ever returned in this response.
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListWebhooks lists the webhooks of an organization
+// @Summary      List webhooks
+// @Description  Lists the webhooks of an organization
+// @Id           ListWebhooks
+// @Tags         Webhook
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        q               query  string  false  "conditions on the fields, for example description^=ci,created_at>=2023-06-01"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
+// @Success      200  {object}  []models.Webhook
+// @Failure		 400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/webhooks [get]
+func (api *API) ListWebhooks(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListWebhooks", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	webhooks := make([]models.Webhook, 0)
+	result := api.db.WithContext(ctx).
+		Where("organization_id = ?", org.ID).
+		Scopes(FilterAndPaginate(&models.Webhook{}, c, "created_at")).
+		Find(&webhooks)
+	if result.Error != nil {
+		sendListError(c, result.Error)
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// DeleteWebhook deletes a webhook
+// @Summary      Delete a webhook
+// @Description  Deletes a webhook and its delivery log
+// @Id           DeleteWebhook
+// @Tags         Webhook
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        id               path   string  true  "Webhook ID"
+// @Success      200  {object}  models.Webhook
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/webhooks/{id} [delete]
+func (api *API) DeleteWebhook(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteWebhook", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var webhook models.Webhook
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		webhook, err = api.organizationWebhook(tx, c, orgId, id)
+		if err != nil {
+			return err
+		}
+		if res := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.Delete(&webhook); res.Error != nil {
+			return res.Error
+		}
+		webhook.Secret = ""
+		return api.recordAuditEvent(c, tx, webhook.OrganizationID, models.AuditActionDelete, "webhook", webhook.ID.String(), webhook, nil)
+	})
+	if err != nil {
+		api.sendWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+// ListWebhookDeliveries lists the delivery log of a webhook
+// @Summary      List webhook deliveries
+// @Description  Lists the delivery attempts of a webhook, newest first
+// @Id           ListWebhookDeliveries
+// @Tags         Webhook
+// @Accept       json
+// @Produce      json
+// @Param        organization_id  path   string  true  "Organization ID"
+// @Param        id               path   string  true  "Webhook ID"
+// @Param        q               query  string  false  "conditions on the fields, for example status=failed,attempts>=3"
+// @Param        limit           query  int     false  "the maximum number of items of a page, the X-Next-Cursor header holds the cursor of the next page"
+// @Param        cursor          query  string  false  "the cursor of the page to list"
+// @Success      200  {object}  []models.WebhookDelivery
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.TooManyRequestsError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/webhooks/{id}/deliveries [get]
+func (api *API) ListWebhookDeliveries(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListWebhookDeliveries", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	db := api.db.WithContext(ctx)
+	webhook, err := api.organizationWebhook(db, c, orgId, id)
+	if err != nil {
+		api.sendWebhookError(c, err)
+		return
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	result := db.Where("webhook_id = ?", webhook.ID).
+		Scopes(FilterAndPaginate(&models.WebhookDelivery{}, c, "created_at DESC")).
+		Find(&deliveries)
+	if result.Error != nil {
+		sendListError(c, result.Error)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (api *API) organizationWebhook(db *gorm.DB, c *gin.Context, orgId uuid.UUID, id uuid.UUID) (models.Webhook, error) {
+	var org models.Organization
+	if res := db.Scopes(api.OrganizationHasCurrentUserRole(c, models.RoleAdmin)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		return models.Webhook{}, errOrgNotFound
+	}
+	var webhook models.Webhook
+	if res := db.Where("organization_id = ?", org.ID).
+		First(&webhook, "id = ?", id); res.Error != nil {
+		return models.Webhook{}, errWebhookNotFound
+	}
+	return webhook, nil
+}
+
+func (api *API) sendWebhookError(c *gin.Context, err error) {
+	if errors.Is(err, errOrgNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+	} else if errors.Is(err, errWebhookNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("webhook"))
+	} else {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+	}
+}
+
+func isWebhookEvent(event string) bool {
+	for _, e := range models.WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
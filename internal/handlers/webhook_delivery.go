@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"gorm.io/gorm"
+)
+
+// webhooksSignal is notified when webhook deliveries have been queued.
+const webhooksSignal = "/webhooks"
+
+const (
+	// WebhookSignatureHeader holds the HMAC-SHA256 of the payload keyed with the webhook secret.
+	WebhookSignatureHeader = "X-Nexodus-Signature"
+	// WebhookEventHeader holds the name of the event being delivered.
+	WebhookEventHeader = "X-Nexodus-Event"
+	// WebhookDeliveryHeader holds the delivery id, it is the same for every retry of a delivery.
+	WebhookDeliveryHeader = "X-Nexodus-Delivery"
+)
+
+const (
+	webhookPollInterval   = 30 * time.Second
+	webhookBatchSize      = 50
+	webhookRequestTimeout = 10 * time.Second
+	webhookMaxAttempts    = 8
+	webhookInitialBackoff = 10 * time.Second
+	webhookMaxBackoff     = time.Hour
+	// webhookDeliveryRetention is how long the delivered and failed deliveries are kept.
+	webhookDeliveryRetention = 7 * 24 * time.Hour
+	webhookPruneInterval     = time.Hour
+)
+
+// errWebhookAddressNotAllowed is returned when a webhook url resolves to an address of the apiserver's networks.
+var errWebhookAddressNotAllowed = errors.New("the webhook address is not allowed")
+
+// queueWebhookEvent queues delivery of an event to the webhooks of the organization that are subscribed to it.
+// It should be called with the transaction that made the change, and webhooksSignal notified once it is committed.
+func (api *API) queueWebhookEvent(tx *gorm.DB, orgId uuid.UUID, event string, data interface{}) error {
+	var webhooks []models.Webhook
+	if res := tx.Where("organization_id = ?", orgId).Find(&webhooks); res.Error != nil {
+		return res.Error
+	}
+	now := time.Now()
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(event) {
+			continue
+		}
+		delivery := models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		}
+		delivery.ID = uuid.New()
+		payload, err := json.Marshal(models.WebhookPayload{
+			ID:             delivery.ID,
+			Event:          event,
+			OrganizationID: orgId,
+			CreatedAt:      now,
+			Data:           data,
+		})
+		if err != nil {
+			return err
+		}
+		delivery.Payload = string(payload)
+		if res := tx.Create(&delivery); res.Error != nil {
+			return res.Error
+		}
+	}
+	return nil
+}
+
+// StartWebhookDelivery starts the background worker that delivers queued webhook events. It wakes
+// up when webhooksSignal is notified, and periodically to retry failed deliveries.
+func (api *API) StartWebhookDelivery(ctx context.Context, wg *sync.WaitGroup) {
+	util.GoWithWaitGroup(wg, func() {
+		sub := api.signalBus.Subscribe(webhooksSignal)
+		defer sub.Close()
+
+		client := newWebhookClient()
+		var pruned time.Time
+		for {
+			if time.Since(pruned) >= webhookPruneInterval {
+				api.pruneWebhookDeliveries(ctx, time.Now().Add(-webhookDeliveryRetention))
+				pruned = time.Now()
+			}
+			api.deliverWebhooks(ctx, client)
+			if waitForCancelOrTimeoutOrNotification(ctx, webhookPollInterval, sub) {
+				return
+			}
+		}
+	})
+}
+
+// newWebhookClient returns the client the webhooks are delivered with. It only connects to public
+// addresses, so that the webhooks can't reach the services on the networks of the apiserver. The
+// addresses are checked when connecting, after the names resolved and for every redirect.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookRequestTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !isPublicAddress(ip) {
+				return fmt.Errorf("%w: %s", errWebhookAddressNotAllowed, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would connect to the addresses on behalf of the apiserver.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: webhookRequestTimeout, Transport: transport}
+}
+
+// webhookDeniedPrefixes are the special-purpose ranges of the IANA IPv4 and IPv6 registries, the
+// webhooks are not delivered to them. The ranges that embed IPv4 addresses are denied too, since
+// they can be translated to any of the IPv4 ranges.
+var webhookDeniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("10.0.0.0/8"),      // private
+	netip.MustParsePrefix("100.64.0.0/10"),   // shared address space (CGNAT)
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link local
+	netip.MustParsePrefix("172.16.0.0/12"),   // private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // private
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved and broadcast
+	netip.MustParsePrefix("::/128"),          // unspecified
+	netip.MustParsePrefix("::1/128"),         // loopback
+	netip.MustParsePrefix("64:ff9b::/96"),    // IPv4/IPv6 translation
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local IPv4/IPv6 translation
+	netip.MustParsePrefix("100::/64"),        // discard only
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments, includes Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+	netip.MustParsePrefix("3fff::/20"),       // documentation
+	netip.MustParsePrefix("fc00::/7"),        // unique local
+	netip.MustParsePrefix("fe80::/10"),       // link local
+	netip.MustParsePrefix("ff00::/8"),        // multicast
+}
+
+// isPublicAddress returns false for the addresses in webhookDeniedPrefixes.
+func isPublicAddress(ip netip.Addr) bool {
+	// the IPv4-mapped addresses are checked as IPv4 addresses, and the prefixes never contain the
+	// addresses with a zone.
+	ip = ip.Unmap().WithZone("")
+	for _, prefix := range webhookDeniedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// pruneWebhookDeliveries purges the deliveries that were delivered or failed before the given time,
+// and the deliveries of the deleted webhooks.
+func (api *API) pruneWebhookDeliveries(ctx context.Context, before time.Time) {
+	res := api.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL OR (status <> ? AND updated_at < ?)", models.WebhookDeliveryPending, before).
+		Delete(&models.WebhookDelivery{})
+	if res.Error != nil {
+		api.logger.Errorf("failed to prune webhook deliveries: %v", res.Error)
+		return
+	}
+	if res.RowsAffected > 0 {
+		api.logger.Infof("pruned %d webhook deliveries", res.RowsAffected)
+	}
+}
+
+// deliverWebhooks attempts all the deliveries that are due.
+func (api *API) deliverWebhooks(ctx context.Context, client *http.Client) {
+	for {
+		var due []models.WebhookDelivery
+		if res := api.db.WithContext(ctx).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(webhookBatchSize).
+			Find(&due); res.Error != nil {
+			api.logger.Errorf("failed to list webhook deliveries: %v", res.Error)
+			return
+		}
+		for _, delivery := range due {
+			if ctx.Err() != nil {
+				return
+			}
+			api.deliverWebhook(ctx, client, delivery)
+		}
+		if len(due) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+func (api *API) deliverWebhook(ctx context.Context, client *http.Client, delivery models.WebhookDelivery) {
+	db := api.db.WithContext(ctx)
+
+	// Claim the delivery so that other apiserver replicas skip it while it is being attempted.
+	res := db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND attempts = ?", delivery.ID, delivery.Attempts).
+		Updates(map[string]interface{}{
+			"attempts":        delivery.Attempts + 1,
+			"next_attempt_at": time.Now().Add(2 * webhookRequestTimeout),
+		})
+	if res.Error != nil {
+		api.logger.Errorf("failed to claim webhook delivery %s: %v", delivery.ID, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		return
+	}
+	delivery.Attempts += 1
+
+	var webhook models.Webhook
+	if res := db.First(&webhook, "id = ?", delivery.WebhookID); res.Error != nil {
+		api.logger.Warnf("dropping delivery %s of deleted webhook %s", delivery.ID, delivery.WebhookID)
+		return
+	}
+
+	code, err := sendWebhook(ctx, client, webhook, delivery)
+	updates := map[string]interface{}{
+		"response_code": code,
+		"last_error":    "",
+	}
+	if err == nil {
+		updates["status"] = models.WebhookDeliveryDelivered
+		updates["delivered_at"] = time.Now()
+	} else {
+		updates["last_error"] = err.Error()
+		if delivery.Attempts >= webhookMaxAttempts {
+			updates["status"] = models.WebhookDeliveryFailed
+		} else {
+			updates["next_attempt_at"] = time.Now().Add(webhookBackoff(delivery.Attempts))
+		}
+	}
+	if res := db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates); res.Error != nil {
+		api.logger.Errorf("failed to update webhook delivery %s: %v", delivery.ID, res.Error)
+	}
+}
+
+// webhookBackoff returns how long to wait before the next attempt, doubling with every attempt.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookInitialBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	return backoff
+}
+
+func sendWebhook(ctx context.Context, client *http.Client, webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(WebhookSignatureHeader, WebhookSignature(webhook.Secret, []byte(delivery.Payload)))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected response status: %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// WebhookSignature returns the value of the WebhookSignatureHeader for a payload, receivers
+// should compute it with their copy of the webhook secret and compare.
+func WebhookSignature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+	"gorm.io/gorm"
+)
+
+func (suite *HandlerTestSuite) createWebhook(url string, events ...string) models.Webhook {
+	require := suite.Require()
+	reqBody, err := json.Marshal(models.AddWebhook{URL: url, Events: events})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(
+		http.MethodPost,
+		"/:organization/webhooks", fmt.Sprintf("/%s/webhooks", suite.testOrganizationID),
+		suite.api.CreateWebhook, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+
+	var webhook models.Webhook
+	require.NoError(json.Unmarshal(body, &webhook))
+	require.NotEmpty(webhook.Secret)
+	return webhook
+}
+
+func (suite *HandlerTestSuite) queueWebhookEvent(event string, data interface{}) {
+	suite.Require().NoError(suite.api.db.Transaction(func(tx *gorm.DB) error {
+		return suite.api.queueWebhookEvent(tx, suite.testOrganizationID, event, data)
+	}))
+}
+
+func (suite *HandlerTestSuite) TestWebhookDelivery() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	received := make(chan *http.Request, 10)
+	bodies := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	webhook := suite.createWebhook(server.URL, models.WebhookEventSecurityGroupChanged)
+
+	// the webhook is not subscribed to this event
+	suite.queueWebhookEvent(models.WebhookEventDeviceJoined, map[string]string{"hostname": "ignored"})
+	suite.queueWebhookEvent(models.WebhookEventSecurityGroupChanged, map[string]string{"group_name": "default"})
+	suite.api.deliverWebhooks(context.Background(), server.Client())
+
+	require.Len(received, 1)
+	req := <-received
+	body := <-bodies
+	assert.Equal(models.WebhookEventSecurityGroupChanged, req.Header.Get(WebhookEventHeader))
+	assert.Equal(WebhookSignature(webhook.Secret, body), req.Header.Get(WebhookSignatureHeader))
+
+	var payload models.WebhookPayload
+	require.NoError(json.Unmarshal(body, &payload))
+	assert.Equal(suite.testOrganizationID, payload.OrganizationID)
+	assert.Equal(payload.ID.String(), req.Header.Get(WebhookDeliveryHeader))
+
+	_, res, err := suite.ServeRequest(
+		http.MethodGet,
+		"/:organization/webhooks/:id/deliveries", fmt.Sprintf("/%s/webhooks/%s/deliveries", suite.testOrganizationID, webhook.ID),
+		suite.api.ListWebhookDeliveries, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+
+	var deliveries []models.WebhookDelivery
+	require.NoError(json.NewDecoder(res.Body).Decode(&deliveries))
+	require.Len(deliveries, 1)
+	assert.Equal(models.WebhookDeliveryDelivered, deliveries[0].Status)
+	assert.Equal(1, deliveries[0].Attempts)
+	assert.Equal(http.StatusOK, deliveries[0].ResponseCode)
+
+	// secrets are not listed
+	_, res, err = suite.ServeRequest(
+		http.MethodGet,
+		"/:organization/webhooks", fmt.Sprintf("/%s/webhooks", suite.testOrganizationID),
+		suite.api.ListWebhooks, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+	var webhooks []models.Webhook
+	require.NoError(json.NewDecoder(res.Body).Decode(&webhooks))
+	require.Len(webhooks, 1)
+	assert.Empty(webhooks[0].Secret)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete,
+		"/:organization/webhooks/:id", fmt.Sprintf("/%s/webhooks/%s", suite.testOrganizationID, webhook.ID),
+		suite.api.DeleteWebhook, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code)
+}
+
+func (suite *HandlerTestSuite) TestWebhookRetry() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	webhook := suite.createWebhook(server.URL)
+	suite.queueWebhookEvent(models.WebhookEventRelayDown, map[string]string{"hostname": "relay"})
+	suite.api.deliverWebhooks(context.Background(), server.Client())
+
+	var delivery models.WebhookDelivery
+	require.NoError(suite.api.db.First(&delivery, "webhook_id = ?", webhook.ID).Error)
+	assert.Equal(models.WebhookDeliveryPending, delivery.Status)
+	assert.Equal(1, delivery.Attempts)
+	assert.Equal(http.StatusServiceUnavailable, delivery.ResponseCode)
+	assert.NotEmpty(delivery.LastError)
+	assert.True(delivery.NextAttemptAt.After(time.Now()))
+
+	// the delivery is not retried until the backoff has passed
+	suite.api.deliverWebhooks(context.Background(), server.Client())
+	require.NoError(suite.api.db.First(&delivery, "id = ?", delivery.ID).Error)
+	assert.Equal(1, delivery.Attempts)
+
+	assert.Equal(10*time.Second, webhookBackoff(1))
+	assert.Equal(40*time.Second, webhookBackoff(3))
+	assert.Equal(time.Hour, webhookBackoff(20))
+}
+
+func (suite *HandlerTestSuite) TestWebhookValidation() {
+	reqBody, err := json.Marshal(models.AddWebhook{URL: "ftp://example.com"})
+	suite.Require().NoError(err)
+	_, res, err := suite.ServeRequest(
+		http.MethodPost,
+		"/:organization/webhooks", fmt.Sprintf("/%s/webhooks", suite.testOrganizationID),
+		suite.api.CreateWebhook, bytes.NewBuffer(reqBody),
+	)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(http.StatusBadRequest, res.Code)
+
+	reqBody, err = json.Marshal(models.AddWebhook{URL: "https://example.com", Events: []string{"device.exploded"}})
+	suite.Require().NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPost,
+		"/:organization/webhooks", fmt.Sprintf("/%s/webhooks", suite.testOrganizationID),
+		suite.api.CreateWebhook, bytes.NewBuffer(reqBody),
+	)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(http.StatusBadRequest, res.Code)
+}
+
+func (suite *HandlerTestSuite) TestWebhookPrivateAddressRefused() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	received := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	webhook := suite.createWebhook(server.URL)
+	suite.queueWebhookEvent(models.WebhookEventDeviceJoined, map[string]string{"hostname": "internal"})
+	suite.api.deliverWebhooks(context.Background(), newWebhookClient())
+	assert.Len(received, 0)
+
+	var delivery models.WebhookDelivery
+	require.NoError(suite.api.db.First(&delivery, "webhook_id = ?", webhook.ID).Error)
+	assert.Equal(models.WebhookDeliveryPending, delivery.Status)
+	assert.Contains(delivery.LastError, errWebhookAddressNotAllowed.Error())
+
+	for _, address := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "0.0.0.0", "0.1.2.3",
+		"100.64.0.1", "100.127.255.254", "192.0.0.8", "198.18.0.1", "198.19.255.255", "203.0.113.10",
+		"224.0.0.1", "255.255.255.255", "::", "::1", "fe80::1", "fe80::1%eth0", "fd00::1", "ff02::1",
+		"::ffff:127.0.0.1", "::ffff:10.1.2.3", "64:ff9b::7f00:1", "64:ff9b::a01:203", "2001::1", "2002:a01:203::1",
+		"2001:db8::1",
+	} {
+		assert.False(isPublicAddress(netip.MustParseAddr(address)), address)
+	}
+	for _, address := range []string{"1.1.1.1", "100.128.0.1", "198.20.0.1", "2606:4700:4700::1111", "::ffff:1.1.1.1"} {
+		assert.True(isPublicAddress(netip.MustParseAddr(address)), address)
+	}
+}
+
+func (suite *HandlerTestSuite) TestWebhookDeliveryPruning() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	webhook := suite.createWebhook("https://hooks.example.com")
+	suite.queueWebhookEvent(models.WebhookEventDeviceJoined, map[string]string{"hostname": "old"})
+	suite.queueWebhookEvent(models.WebhookEventDeviceJoined, map[string]string{"hostname": "pending"})
+	var deliveries []models.WebhookDelivery
+	require.NoError(suite.api.db.Order("created_at").Find(&deliveries, "webhook_id = ?", webhook.ID).Error)
+	require.Len(deliveries, 2)
+	require.NoError(suite.api.db.Model(&deliveries[0]).UpdateColumns(map[string]interface{}{
+		"status":     models.WebhookDeliveryDelivered,
+		"updated_at": time.Now().Add(-8 * 24 * time.Hour),
+	}).Error)
+
+	suite.api.pruneWebhookDeliveries(context.Background(), time.Now().Add(-webhookDeliveryRetention))
+	var count int64
+	require.NoError(suite.api.db.Unscoped().Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID).Count(&count).Error)
+	assert.Equal(int64(1), count)
+
+	// the deliveries of deleted webhooks are purged.
+	require.NoError(suite.api.db.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error)
+	suite.api.pruneWebhookDeliveries(context.Background(), time.Now().Add(-webhookDeliveryRetention))
+	require.NoError(suite.api.db.Unscoped().Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID).Count(&count).Error)
+	assert.Equal(int64(0), count)
+}
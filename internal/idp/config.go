@@ -0,0 +1,228 @@
+package idp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// TypeOIDC is an OpenID Connect provider, its access tokens are JWTs verified with the
+	// keys of the issuer.
+	TypeOIDC = "oidc"
+	// TypeOAuth2 is a plain OAuth2 provider, like GitHub, its access tokens are opaque and are
+	// verified by fetching the user info of the token from the provider.
+	TypeOAuth2 = "oauth2"
+	// DefaultProviderName is the name of the provider configured with the --oidc-* flags
+	// of the apiserver.
+	DefaultProviderName = "default"
+	// TokenCheckGitHub checks that the access tokens of an oauth2 provider were issued to its
+	// client with the GitHub applications api.
+	TokenCheckGitHub = "github"
+	// TokenCheckIntrospection checks that the access tokens of an oauth2 provider were issued
+	// to its client with RFC 7662 token introspection.
+	TokenCheckIntrospection = "introspection"
+	// DefaultGitHubAPIURL is the api of github.com.
+	DefaultGitHubAPIURL = "https://api.github.com"
+)
+
+// ApiScopes are the scopes of the nexodus api.
+var ApiScopes = []string{"read:organizations", "write:organizations", "read:users", "write:users", "read:devices", "write:devices"}
+
+// Config is the configuration of the identity providers whose users can access the apiserver.
+type Config struct {
+	Providers []Provider `json:"providers"`
+}
+
+// Provider is an identity provider.
+type Provider struct {
+	// Name identifies the provider when logging in, it's unique and can't contain a ':'.
+	Name string `json:"name"`
+	// Type is either oidc (the default) or oauth2.
+	Type string `json:"type,omitempty"`
+	// IssuerURL is the issuer of an oidc provider, the endpoints are discovered from it.
+	IssuerURL string `json:"issuer_url,omitempty"`
+	// BackchannelURL is used by the apiserver instead of the IssuerURL to reach an oidc provider.
+	BackchannelURL string `json:"backchannel_url,omitempty"`
+	// Audience is the audience of the access tokens of an oidc provider, defaults to the ClientID.
+	Audience string `json:"audience,omitempty"`
+	// ClientID is the client that devices log in with.
+	ClientID string `json:"client_id"`
+	// DeviceAuthorizationURL is the device authorization endpoint of an oauth2 provider.
+	DeviceAuthorizationURL string `json:"device_authorization_url,omitempty"`
+	// TokenURL is the token endpoint of an oauth2 provider.
+	TokenURL string `json:"token_url,omitempty"`
+	// UserInfoURL is the endpoint that returns the claims of the user of an oauth2 access token.
+	UserInfoURL string `json:"userinfo_url,omitempty"`
+	// Scopes are requested when logging in.
+	Scopes []string `json:"scopes,omitempty"`
+	// ClientSecret authenticates the apiserver to an oauth2 provider, when it checks the tokens.
+	ClientSecret string `json:"client_secret,omitempty"`
+	// TokenCheck is how the access tokens of an oauth2 provider are checked to be issued to the
+	// ClientID, either github or introspection. Any token of the user would be accepted otherwise.
+	TokenCheck string `json:"token_check,omitempty"`
+	// TokenCheckURL is the GitHub api for the github token check, https://api.github.com by default,
+	// or the introspection endpoint for the introspection token check.
+	TokenCheckURL string `json:"token_check_url,omitempty"`
+	// GrantedScopes are the api scopes of the users of an oauth2 provider, whose tokens don't
+	// carry them. The users have no access to the api when it's empty.
+	GrantedScopes []string `json:"granted_scopes,omitempty"`
+	// Claims maps the claims of the provider to the user.
+	Claims ClaimMapping `json:"claims,omitempty"`
+	// AllowedEmailDomains restricts the users to the ones with an email of these domains
+	// or of their subdomains, every user is allowed when empty.
+	AllowedEmailDomains []string `json:"allowed_email_domains,omitempty"`
+	// EmailDomainClaim limits the AllowedEmailDomains check to the users whose token has
+	// this claim set to true.
+	EmailDomainClaim string `json:"email_domain_claim,omitempty"`
+}
+
+// ClaimMapping names the claims holding the user attributes.
+type ClaimMapping struct {
+	Subject  string `json:"subject,omitempty"`
+	Username string `json:"username,omitempty"`
+	FullName string `json:"full_name,omitempty"`
+	Email    string `json:"email,omitempty"`
+	// EmailVerified is the boolean claim telling whether the provider verified the Email, the
+	// emails of the users are considered unverified when it's empty.
+	EmailVerified string `json:"email_verified,omitempty"`
+	Groups        string `json:"groups,omitempty"`
+}
+
+// LoadConfig reads the provider configuration from a yaml or json file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("invalid identity provider config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Complete fills in the defaults of the providers and validates them.
+func (c *Config) Complete() error {
+	names := map[string]bool{}
+	for i := range c.Providers {
+		p := &c.Providers[i]
+		p.setDefaults()
+		if err := p.validate(); err != nil {
+			return err
+		}
+		if names[p.Name] {
+			return fmt.Errorf("identity provider %s is configured more than once", p.Name)
+		}
+		names[p.Name] = true
+	}
+	if len(c.Providers) == 0 {
+		return fmt.Errorf("no identity provider is configured")
+	}
+	return nil
+}
+
+// Provider returns the provider with the name, or nil if there is none.
+func (c *Config) Provider(name string) *Provider {
+	for i := range c.Providers {
+		if c.Providers[i].Name == name {
+			return &c.Providers[i]
+		}
+	}
+	return nil
+}
+
+// UserIDPrefix is prepended to the subject of the users of the provider to build their
+// user id. It's empty for the first provider, so that the ids of its users don't change,
+// and the provider name followed by a ':' for the others, so that the subjects of different
+// providers can't collide.
+func (c *Config) UserIDPrefix(p *Provider) string {
+	if len(c.Providers) > 0 && &c.Providers[0] == p {
+		return ""
+	}
+	return p.Name + ":"
+}
+
+func (p *Provider) setDefaults() {
+	if p.Type == "" {
+		p.Type = TypeOIDC
+	}
+	switch p.Type {
+	case TypeOIDC:
+		if p.Audience == "" {
+			p.Audience = p.ClientID
+		}
+		if len(p.Scopes) == 0 {
+			p.Scopes = append([]string{"openid", "profile", "email", "offline_access"}, ApiScopes...)
+		}
+		setDefault(&p.Claims.Subject, "sub")
+		setDefault(&p.Claims.Username, "preferred_username")
+		setDefault(&p.Claims.FullName, "name")
+		setDefault(&p.Claims.Email, "email")
+		setDefault(&p.Claims.EmailVerified, "email_verified")
+		setDefault(&p.Claims.Groups, "groups")
+	case TypeOAuth2:
+		if p.TokenCheck == TokenCheckGitHub {
+			setDefault(&p.TokenCheckURL, DefaultGitHubAPIURL)
+		}
+		setDefault(&p.Claims.Subject, "id")
+		setDefault(&p.Claims.Username, "login")
+		setDefault(&p.Claims.FullName, "name")
+		setDefault(&p.Claims.Email, "email")
+	}
+}
+
+func setDefault(value *string, def string) {
+	if *value == "" {
+		*value = def
+	}
+}
+
+func (p *Provider) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("an identity provider has no name")
+	}
+	if strings.Contains(p.Name, ":") {
+		return fmt.Errorf("identity provider %s: the name can't contain a ':'", p.Name)
+	}
+	if p.ClientID == "" {
+		return fmt.Errorf("identity provider %s: client_id is required", p.Name)
+	}
+	switch p.Type {
+	case TypeOIDC:
+		if p.IssuerURL == "" {
+			return fmt.Errorf("identity provider %s: issuer_url is required", p.Name)
+		}
+	case TypeOAuth2:
+		if p.DeviceAuthorizationURL == "" || p.TokenURL == "" || p.UserInfoURL == "" {
+			return fmt.Errorf("identity provider %s: device_authorization_url, token_url and userinfo_url are required", p.Name)
+		}
+		switch p.TokenCheck {
+		case TokenCheckGitHub, TokenCheckIntrospection:
+		default:
+			return fmt.Errorf("identity provider %s: token_check must be %s or %s", p.Name, TokenCheckGitHub, TokenCheckIntrospection)
+		}
+		if p.ClientSecret == "" || p.TokenCheckURL == "" {
+			return fmt.Errorf("identity provider %s: client_secret and token_check_url are required to check the tokens", p.Name)
+		}
+		for _, scope := range p.GrantedScopes {
+			if !isApiScope(scope) {
+				return fmt.Errorf("identity provider %s: invalid granted scope %s", p.Name, scope)
+			}
+		}
+	default:
+		return fmt.Errorf("identity provider %s: invalid type %s, it must be %s or %s", p.Name, p.Type, TypeOIDC, TypeOAuth2)
+	}
+	return nil
+}
+
+func isApiScope(scope string) bool {
+	for _, s := range ApiScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
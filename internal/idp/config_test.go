@@ -0,0 +1,115 @@
+package idp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "providers.yaml")
+	require.NoError(os.WriteFile(path, []byte(`
+providers:
+  - name: github
+    type: oauth2
+    client_id: github-client
+    device_authorization_url: https://github.com/login/device/code
+    token_url: https://github.com/login/oauth/access_token
+    userinfo_url: https://api.github.com/user
+    client_secret: github-secret
+    token_check: github
+    scopes: [read:user, user:email]
+    granted_scopes: [read:devices, write:devices]
+  - name: corp
+    issuer_url: https://sso.example.com
+    client_id: nexodus
+    claims:
+      username: email
+    allowed_email_domains: [example.com]
+`), 0600))
+
+	config, err := LoadConfig(path)
+	require.NoError(err)
+	require.NoError(config.Complete())
+	require.Len(config.Providers, 2)
+
+	github := config.Provider("github")
+	require.NotNil(github)
+	require.Equal([]string{"read:devices", "write:devices"}, github.GrantedScopes)
+	require.Equal(DefaultGitHubAPIURL, github.TokenCheckURL)
+	require.Equal(ClaimMapping{Subject: "id", Username: "login", FullName: "name", Email: "email"}, github.Claims)
+	require.Equal("", config.UserIDPrefix(github))
+
+	corp := config.Provider("corp")
+	require.NotNil(corp)
+	require.Equal(TypeOIDC, corp.Type)
+	require.Equal("nexodus", corp.Audience)
+	require.Equal(ClaimMapping{Subject: "sub", Username: "email", FullName: "name", Email: "email", EmailVerified: "email_verified", Groups: "groups"}, corp.Claims)
+	require.Equal([]string{"example.com"}, corp.AllowedEmailDomains)
+	require.Equal("corp:", config.UserIDPrefix(corp))
+
+	require.Nil(config.Provider("missing"))
+}
+
+func TestLoadConfigUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "providers.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+providers:
+  - name: corp
+    issuer: https://sso.example.com
+`), 0600))
+	_, err := LoadConfig(path)
+	require.Error(t, err)
+}
+
+func TestCompleteValidation(t *testing.T) {
+	for name, providers := range map[string][]Provider{
+		"no providers":      {},
+		"no name":           {{IssuerURL: "https://sso.example.com", ClientID: "nexodus"}},
+		"name with a colon": {{Name: "a:b", IssuerURL: "https://sso.example.com", ClientID: "nexodus"}},
+		"no client id":      {{Name: "corp", IssuerURL: "https://sso.example.com"}},
+		"no issuer":         {{Name: "corp", ClientID: "nexodus"}},
+		"oauth2 without endpoints": {{
+			Name: "github", Type: TypeOAuth2, ClientID: "github-client",
+			TokenURL: "https://github.com/login/oauth/access_token",
+		}},
+		"oauth2 without token check": {{
+			Name: "github", Type: TypeOAuth2, ClientID: "github-client", ClientSecret: "github-secret",
+			DeviceAuthorizationURL: "https://github.com/login/device/code",
+			TokenURL:               "https://github.com/login/oauth/access_token",
+			UserInfoURL:            "https://api.github.com/user",
+		}},
+		"oauth2 without client secret": {{
+			Name: "github", Type: TypeOAuth2, ClientID: "github-client", TokenCheck: TokenCheckGitHub,
+			DeviceAuthorizationURL: "https://github.com/login/device/code",
+			TokenURL:               "https://github.com/login/oauth/access_token",
+			UserInfoURL:            "https://api.github.com/user",
+		}},
+		"introspection without url": {{
+			Name: "corp", Type: TypeOAuth2, ClientID: "nexodus", ClientSecret: "secret", TokenCheck: TokenCheckIntrospection,
+			DeviceAuthorizationURL: "https://sso.example.com/device",
+			TokenURL:               "https://sso.example.com/token",
+			UserInfoURL:            "https://sso.example.com/userinfo",
+		}},
+		"invalid granted scope": {{
+			Name: "github", Type: TypeOAuth2, ClientID: "github-client", ClientSecret: "github-secret", TokenCheck: TokenCheckGitHub,
+			DeviceAuthorizationURL: "https://github.com/login/device/code",
+			TokenURL:               "https://github.com/login/oauth/access_token",
+			UserInfoURL:            "https://api.github.com/user",
+			GrantedScopes:          []string{"admin"},
+		}},
+		"invalid type": {{Name: "corp", Type: "saml", ClientID: "nexodus"}},
+		"duplicate names": {
+			{Name: "corp", IssuerURL: "https://sso.example.com", ClientID: "nexodus"},
+			{Name: "corp", IssuerURL: "https://sso2.example.com", ClientID: "nexodus"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := &Config{Providers: providers}
+			require.Error(t, config.Complete())
+		})
+	}
+}
@@ -14,8 +14,75 @@ import (
 
 var defaultIPAMNamespace = uuid.UUID{}
 
+// RebuildIfEmpty rebuilds the database IPAM when it has no prefixes yet, the first time the apiserver
+// runs with the database backend, so that it starts with the allocations of the existing devices.
+func RebuildIfEmpty(ctx context.Context, log *zap.Logger, db *gorm.DB, i ipam.IPAM) error {
+	if _, ok := i.(*ipam.DatabaseIPAM); !ok {
+		return nil
+	}
+	var prefixes int64
+	if res := db.WithContext(ctx).Model(&ipam.IpamPrefix{}).Count(&prefixes); res.Error != nil {
+		return res.Error
+	}
+	if prefixes > 0 {
+		return nil
+	}
+	log.Info("rebuilding the empty database ipam from the organizations and devices")
+	return Rebuild(ctx, log, db, i)
+}
+
 func Rebuild(ctx context.Context, log *zap.Logger, db *gorm.DB, ipam ipam.IPAM) error {
 
+	// the prefixes of the organizations without devices, and the reserved addresses, are allocated too.
+	var orgs []models.Organization
+	if res := db.Find(&orgs); res.Error != nil {
+		return res.Error
+	}
+	orgsByID := map[uuid.UUID]models.Organization{}
+	for _, org := range orgs {
+		orgsByID[org.ID] = org
+		ipamNamespace := defaultIPAMNamespace
+		if org.PrivateCidr {
+			ipamNamespace = org.ID
+		}
+		if err := ipam.CreateNamespace(ctx, ipamNamespace); err != nil {
+			return fmt.Errorf("failed to create ipam namespace: %w", err)
+		}
+		for _, cidr := range []string{org.IpCidr, org.IpCidrV6} {
+			if cidr == "" {
+				continue
+			}
+			if err := ipam.AssignPrefix(ctx, ipamNamespace, cidr); err != nil {
+				return fmt.Errorf("can't assign the prefix %s of organization %s: %w", cidr, org.ID, err)
+			}
+		}
+	}
+
+	var reservations []models.IpReservation
+	if res := db.Find(&reservations); res.Error != nil {
+		return res.Error
+	}
+	for _, reservation := range reservations {
+		org, found := orgsByID[reservation.OrganizationID]
+		if !found {
+			continue
+		}
+		ipamNamespace := defaultIPAMNamespace
+		if org.PrivateCidr {
+			ipamNamespace = org.ID
+		}
+		if reservation.TunnelIP != "" {
+			if err := ipam.AcquireIP(ctx, ipamNamespace, org.IpCidr, reservation.TunnelIP); err != nil {
+				log.Sugar().Warnf("Failed to allocate reserved ip %s for organization %s", reservation.TunnelIP, org.ID)
+			}
+		}
+		if reservation.TunnelIpV6 != "" {
+			if err := ipam.AcquireIP(ctx, ipamNamespace, org.IpCidrV6, reservation.TunnelIpV6); err != nil {
+				log.Sugar().Warnf("Failed to allocate reserved ip %s for organization %s", reservation.TunnelIpV6, org.ID)
+			}
+		}
+	}
+
 	type result struct {
 		ID             uuid.UUID      `gorm:"type:uuid;primary_key;" json:"id" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
 		TunnelIP       string         `json:"tunnel_ip"`
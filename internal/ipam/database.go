@@ -0,0 +1,263 @@
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IpamPrefix is a prefix of a namespace that addresses are allocated from.
+type IpamPrefix struct {
+	Namespace uuid.UUID `gorm:"type:uuid;primary_key"`
+	Cidr      string    `gorm:"primary_key"`
+}
+
+// IpamAddress is an address allocated from a prefix of a namespace.
+type IpamAddress struct {
+	Namespace uuid.UUID `gorm:"type:uuid;primary_key"`
+	IP        string    `gorm:"primary_key"`
+	Prefix    string    `gorm:"index"`
+}
+
+// DatabaseIPAM is an IPAM that stores the prefixes and addresses in the apiserver database. When
+// the context holds a transaction, see WithTransaction, the allocations are made in it.
+type DatabaseIPAM struct {
+	logger *zap.SugaredLogger
+	db     *gorm.DB
+}
+
+func NewDatabaseIPAM(logger *zap.SugaredLogger, db *gorm.DB) *DatabaseIPAM {
+	return &DatabaseIPAM{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (i *DatabaseIPAM) tx(ctx context.Context) *gorm.DB {
+	if tx, ok := transactionFromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return i.db.WithContext(ctx)
+}
+
+// CreateNamespace does nothing, the namespaces exist as soon as they have a prefix.
+func (i *DatabaseIPAM) CreateNamespace(parent context.Context, namespace uuid.UUID) error {
+	return nil
+}
+
+func (i *DatabaseIPAM) DeleteNamespace(parent context.Context, namespace uuid.UUID) error {
+	ctx, span := tracer.Start(parent, "DeleteNamespace")
+	defer span.End()
+	db := i.tx(ctx)
+	if res := db.Where("namespace = ?", namespace).Delete(&IpamAddress{}); res.Error != nil {
+		return res.Error
+	}
+	return db.Where("namespace = ?", namespace).Delete(&IpamPrefix{}).Error
+}
+
+func (i *DatabaseIPAM) AcquireIP(parent context.Context, namespace uuid.UUID, ipamPrefix string, TunnelIP string) error {
+	ctx, span := tracer.Start(parent, "AcquireIP")
+	defer span.End()
+	if err := validateIP(TunnelIP); err != nil {
+		return fmt.Errorf("Address %s is not valid", TunnelIP)
+	}
+	addr, err := netip.ParseAddr(TunnelIP)
+	if err != nil {
+		return fmt.Errorf("Address %s is not valid", TunnelIP)
+	}
+	db := i.tx(ctx)
+	prefix, err := i.prefix(db, namespace, ipamPrefix)
+	if err != nil {
+		return err
+	}
+	if !prefix.Contains(addr) || isReservedAddr(prefix, addr) {
+		return fmt.Errorf("address %s is not available in prefix %s", addr, prefix)
+	}
+	acquired, err := i.insertAddress(db, namespace, prefix, addr)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return fmt.Errorf("address %s is already allocated", addr)
+	}
+	return nil
+}
+
+func (i *DatabaseIPAM) AssignSpecificTunnelIP(parent context.Context, namespace uuid.UUID, ipamPrefix string, TunnelIP string) (string, error) {
+	ctx, span := tracer.Start(parent, "AssignSpecificTunnelIP")
+	defer span.End()
+	if err := validateIP(TunnelIP); err != nil {
+		return "", fmt.Errorf("Address %s is not valid", TunnelIP)
+	}
+	if err := i.AcquireIP(ctx, namespace, ipamPrefix, TunnelIP); err != nil {
+		i.logger.Errorf("failed to assign the requested address %s, assigning an address from the pool: %v\n", TunnelIP, err)
+		return i.AssignFromPool(ctx, namespace, ipamPrefix)
+	}
+	return TunnelIP, nil
+}
+
+func (i *DatabaseIPAM) AssignFromPool(parent context.Context, namespace uuid.UUID, ipamPrefix string) (string, error) {
+	ctx, span := tracer.Start(parent, "AssignFromPool")
+	defer span.End()
+	db := i.tx(ctx)
+	prefix, err := i.prefix(db, namespace, ipamPrefix)
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire an IPAM assigned address %w\n", err)
+	}
+
+	var ips []string
+	if res := db.Model(&IpamAddress{}).
+		Where("namespace = ? AND prefix = ?", namespace, prefix.String()).
+		Pluck("ip", &ips); res.Error != nil {
+		return "", res.Error
+	}
+	allocated := make(map[netip.Addr]bool, len(ips))
+	for _, ip := range ips {
+		if addr, err := netip.ParseAddr(ip); err == nil {
+			allocated[addr] = true
+		}
+	}
+
+	for addr := prefix.Addr().Next(); prefix.Contains(addr); addr = addr.Next() {
+		if allocated[addr] || isReservedAddr(prefix, addr) {
+			continue
+		}
+		// a concurrent transaction may have allocated the address since the list, then try the next one.
+		acquired, err := i.insertAddress(db, namespace, prefix, addr)
+		if err != nil {
+			return "", err
+		}
+		if acquired {
+			return addr.String(), nil
+		}
+	}
+	return "", fmt.Errorf("failed to acquire an IPAM assigned address, prefix %s has no available addresses", prefix)
+}
+
+func (i *DatabaseIPAM) AssignPrefix(parent context.Context, namespace uuid.UUID, cidr string) error {
+	ctx, span := tracer.Start(parent, "AssignPrefix")
+	defer span.End()
+	cidr, err := cleanCidr(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid prefix requested: %w", err)
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid prefix requested: %w", err)
+	}
+
+	db := i.tx(ctx)
+	var prefixes []IpamPrefix
+	if res := db.Where("namespace = ?", namespace).Find(&prefixes); res.Error != nil {
+		return res.Error
+	}
+	for _, p := range prefixes {
+		if p.Cidr == cidr {
+			return nil
+		}
+		if existing, err := netip.ParsePrefix(p.Cidr); err == nil && existing.Overlaps(prefix) {
+			return fmt.Errorf("prefix %s overlaps with existing prefix %s", cidr, p.Cidr)
+		}
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&IpamPrefix{Namespace: namespace, Cidr: cidr}).Error
+}
+
+// ReleaseToPool release the ipam address back to the specified prefix
+func (i *DatabaseIPAM) ReleaseToPool(parent context.Context, namespace uuid.UUID, address, cidr string) error {
+	ctx, span := tracer.Start(parent, "ReleaseToPool")
+	defer span.End()
+	if addr, err := netip.ParseAddr(address); err == nil {
+		address = addr.String()
+	}
+	if res := i.tx(ctx).Where("namespace = ? AND ip = ?", namespace, address).Delete(&IpamAddress{}); res.Error != nil {
+		return fmt.Errorf("failed to release IPAM address %w", res.Error)
+	}
+	return nil
+}
+
+// ReleasePrefix releases the prefix and the addresses allocated from it
+func (i *DatabaseIPAM) ReleasePrefix(parent context.Context, namespace uuid.UUID, cidr string) error {
+	ctx, span := tracer.Start(parent, "ReleasePrefix")
+	defer span.End()
+	if clean, err := cleanCidr(cidr); err == nil {
+		cidr = clean
+	}
+	db := i.tx(ctx)
+	if res := db.Where("namespace = ? AND prefix = ?", namespace, cidr).Delete(&IpamAddress{}); res.Error != nil {
+		return fmt.Errorf("failed to release IPAM prefix %w", res.Error)
+	}
+	if res := db.Where("namespace = ? AND cidr = ?", namespace, cidr).Delete(&IpamPrefix{}); res.Error != nil {
+		return fmt.Errorf("failed to release IPAM prefix %w", res.Error)
+	}
+	return nil
+}
+
+// ListAllocations lists the prefixes and addresses of all the namespaces.
+func (i *DatabaseIPAM) ListAllocations(parent context.Context) ([]Allocation, error) {
+	ctx, span := tracer.Start(parent, "ListAllocations")
+	defer span.End()
+	db := i.tx(ctx)
+	var prefixes []IpamPrefix
+	if res := db.Find(&prefixes); res.Error != nil {
+		return nil, res.Error
+	}
+	var addresses []IpamAddress
+	if res := db.Find(&addresses); res.Error != nil {
+		return nil, res.Error
+	}
+	allocations := make([]Allocation, 0, len(prefixes)+len(addresses))
+	for _, p := range prefixes {
+		allocations = append(allocations, Allocation{Namespace: p.Namespace, Prefix: p.Cidr})
+	}
+	for _, a := range addresses {
+		allocations = append(allocations, Allocation{Namespace: a.Namespace, Prefix: a.Prefix, Address: a.IP})
+	}
+	return allocations, nil
+}
+
+func (i *DatabaseIPAM) prefix(db *gorm.DB, namespace uuid.UUID, cidr string) (netip.Prefix, error) {
+	clean, err := cleanCidr(cidr)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid prefix %s: %w", cidr, err)
+	}
+	var p IpamPrefix
+	if res := db.First(&p, "namespace = ? AND cidr = ?", namespace, clean); res.Error != nil {
+		return netip.Prefix{}, fmt.Errorf("prefix %s not found: %w", clean, res.Error)
+	}
+	return netip.ParsePrefix(p.Cidr)
+}
+
+// insertAddress allocates the address, it returns false if the address was already allocated.
+func (i *DatabaseIPAM) insertAddress(db *gorm.DB, namespace uuid.UUID, prefix netip.Prefix, addr netip.Addr) (bool, error) {
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&IpamAddress{
+		Namespace: namespace,
+		IP:        addr.String(),
+		Prefix:    prefix.String(),
+	})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// isReservedAddr returns true for the network address of the prefix, and the broadcast address
+// of IPv4 prefixes, which are never allocated.
+func isReservedAddr(prefix netip.Prefix, addr netip.Addr) bool {
+	if addr == prefix.Addr() {
+		return true
+	}
+	if !addr.Is4() {
+		return false
+	}
+	b := prefix.Addr().As4()
+	for i := prefix.Bits(); i < 32; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	return addr == netip.AddrFrom4(b)
+}
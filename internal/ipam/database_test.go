@@ -0,0 +1,88 @@
+package ipam
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"gorm.io/gorm"
+)
+
+func TestDatabaseIPAM(t *testing.T) {
+	require := require.New(t)
+	db, err := database.NewTestDatabase()
+	require.NoError(err)
+	ipam := NewDatabaseIPAM(zaptest.NewLogger(t).Sugar(), db)
+	ctx := context.Background()
+	namespace := uuid.New()
+	prefix := "10.20.30.0/30"
+
+	require.NoError(ipam.CreateNamespace(ctx, namespace))
+	require.NoError(ipam.AssignPrefix(ctx, namespace, prefix))
+	// assigning the same prefix again is not an error, but an overlapping prefix is.
+	require.NoError(ipam.AssignPrefix(ctx, namespace, "10.20.30.1/30"))
+	require.Error(ipam.AssignPrefix(ctx, namespace, "10.20.0.0/16"))
+	// namespaces are independent.
+	require.NoError(ipam.AssignPrefix(ctx, uuid.New(), "10.20.0.0/16"))
+
+	_, err = ipam.AssignSpecificTunnelIP(ctx, namespace, prefix, "notanipaddress")
+	require.Error(err)
+	require.Error(ipam.AcquireIP(ctx, namespace, prefix, "10.20.30.0"), "the network address is reserved")
+	require.Error(ipam.AcquireIP(ctx, namespace, prefix, "10.20.30.3"), "the broadcast address is reserved")
+
+	ip, err := ipam.AssignSpecificTunnelIP(ctx, namespace, prefix, "10.20.30.2")
+	require.NoError(err)
+	require.Equal("10.20.30.2", ip)
+	// a conflicting address is assigned from the pool.
+	ip, err = ipam.AssignSpecificTunnelIP(ctx, namespace, prefix, "10.20.30.2")
+	require.NoError(err)
+	require.Equal("10.20.30.1", ip)
+	_, err = ipam.AssignFromPool(ctx, namespace, prefix)
+	require.Error(err, "the prefix is exhausted")
+
+	require.NoError(ipam.ReleaseToPool(ctx, namespace, "10.20.30.1", prefix))
+	ip, err = ipam.AssignFromPool(ctx, namespace, prefix)
+	require.NoError(err)
+	require.Equal("10.20.30.1", ip)
+
+	// the allocations of a rolled back transaction are rolled back too.
+	require.NoError(ipam.ReleaseToPool(ctx, namespace, "10.20.30.1", prefix))
+	errRollback := errors.New("rollback")
+	err = db.Transaction(func(tx *gorm.DB) error {
+		ip, err := ipam.AssignFromPool(WithTransaction(ctx, tx), namespace, prefix)
+		require.NoError(err)
+		require.Equal("10.20.30.1", ip)
+		return errRollback
+	})
+	require.ErrorIs(err, errRollback)
+	ip, err = ipam.AssignFromPool(ctx, namespace, prefix)
+	require.NoError(err)
+	require.Equal("10.20.30.1", ip)
+
+	v6Prefix := "200::/64"
+	require.NoError(ipam.AssignPrefix(ctx, namespace, v6Prefix))
+	ip, err = ipam.AssignFromPool(ctx, namespace, v6Prefix)
+	require.NoError(err)
+	require.Equal("200::1", ip)
+
+	// releasing a prefix releases its addresses.
+	require.NoError(ipam.ReleasePrefix(ctx, namespace, prefix))
+	require.NoError(ipam.AssignPrefix(ctx, namespace, prefix))
+	ip, err = ipam.AssignFromPool(ctx, namespace, prefix)
+	require.NoError(err)
+	require.Equal("10.20.30.1", ip)
+
+	allocations, err := ipam.ListAllocations(ctx)
+	require.NoError(err)
+	require.Contains(allocations, Allocation{Namespace: namespace, Prefix: prefix})
+	require.Contains(allocations, Allocation{Namespace: namespace, Prefix: prefix, Address: "10.20.30.1"})
+	require.Contains(allocations, Allocation{Namespace: namespace, Prefix: v6Prefix, Address: "200::1"})
+
+	require.NoError(ipam.DeleteNamespace(ctx, namespace))
+	_, err = ipam.AssignFromPool(ctx, namespace, prefix)
+	require.Error(err)
+}
@@ -4,16 +4,12 @@ import (
 	"context"
 	"fmt"
 	"net"
-	"net/http"
-	"strings"
 
-	"github.com/bufbuild/connect-go"
 	"github.com/google/uuid"
-	apiv1 "github.com/metal-stack/go-ipam/api/v1"
-	"github.com/metal-stack/go-ipam/api/v1/apiv1connect"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 var tracer trace.Tracer
@@ -22,141 +18,76 @@ func init() {
 	tracer = otel.Tracer("github.com/nexodus-io/nexodus/internal/ipam")
 }
 
-func uuidToNamespace(id uuid.UUID) string {
-	return strings.ReplaceAll(id.String(), "-", "_")
+// IPAM allocates the prefixes of organizations and devices, and the addresses of devices. The
+// prefixes and addresses are scoped to a namespace.
+type IPAM interface {
+	CreateNamespace(ctx context.Context, namespace uuid.UUID) error
+	DeleteNamespace(ctx context.Context, namespace uuid.UUID) error
+	// AcquireIP allocates the address from the prefix, it fails if the address is not available.
+	AcquireIP(ctx context.Context, namespace uuid.UUID, ipamPrefix string, TunnelIP string) error
+	// AssignSpecificTunnelIP allocates the address from the prefix, or the next available
+	// address of the prefix if the address is not available.
+	AssignSpecificTunnelIP(ctx context.Context, namespace uuid.UUID, ipamPrefix string, TunnelIP string) (string, error)
+	// AssignFromPool allocates the next available address of the prefix.
+	AssignFromPool(ctx context.Context, namespace uuid.UUID, ipamPrefix string) (string, error)
+	// AssignPrefix creates a prefix that addresses can be allocated from, it fails if the prefix
+	// overlaps a different prefix of the namespace.
+	AssignPrefix(ctx context.Context, namespace uuid.UUID, cidr string) error
+	ReleaseToPool(ctx context.Context, namespace uuid.UUID, address, cidr string) error
+	ReleasePrefix(ctx context.Context, namespace uuid.UUID, cidr string) error
 }
 
-type IPAM struct {
-	logger *zap.SugaredLogger
-	client apiv1connect.IpamServiceClient
+// Allocation is a prefix of a namespace, or an address allocated from a prefix of a namespace.
+type Allocation struct {
+	Namespace uuid.UUID
+	Prefix    string
+	// Address is empty for a prefix.
+	Address string
 }
 
-func NewIPAM(logger *zap.SugaredLogger, ipamAddress string) IPAM {
-	return IPAM{
-		logger: logger,
-		client: apiv1connect.NewIpamServiceClient(
-			http.DefaultClient,
-			ipamAddress,
-			connect.WithGRPC(),
-		)}
+// AllocationLister is implemented by the IPAMs that can list all their allocations.
+type AllocationLister interface {
+	ListAllocations(ctx context.Context) ([]Allocation, error)
 }
 
-func (i *IPAM) CreateNamespace(parent context.Context, namespace uuid.UUID) error {
-	ctx, span := tracer.Start(parent, "CreateNamespace")
-	defer span.End()
-	_, err := i.client.CreateNamespace(ctx, connect.NewRequest(&apiv1.CreateNamespaceRequest{
-		Namespace: uuidToNamespace(namespace),
-	}))
-	return err
+// AddressCounter is implemented by the IPAMs that can't list their allocations, but can look up a
+// prefix and count the addresses allocated from it. The go-ipam service only lists the prefixes of
+// its default namespace and never the addresses, so RemoteIPAM is one of them.
+type AddressCounter interface {
+	// CountAddresses returns the number of addresses allocated from the prefix, and false if the
+	// prefix is not allocated.
+	CountAddresses(ctx context.Context, namespace uuid.UUID, cidr string) (int, bool, error)
 }
 
-func (i *IPAM) DeleteNamespace(parent context.Context, namespace uuid.UUID) error {
-	ctx, span := tracer.Start(parent, "DeleteNamespace")
-	defer span.End()
-	_, err := i.client.DeleteNamespace(ctx, connect.NewRequest(&apiv1.DeleteNamespaceRequest{
-		Namespace: uuidToNamespace(namespace),
-	}))
-	return err
-}
-
-func (i *IPAM) AcquireIP(parent context.Context, namespace uuid.UUID, ipamPrefix string, TunnelIP string) error {
-	ctx, span := tracer.Start(parent, "AssignSpecificTunnelIP")
-	defer span.End()
-	if err := validateIP(TunnelIP); err != nil {
-		return fmt.Errorf("Address %s is not valid", TunnelIP)
-	}
-	ns := uuidToNamespace(namespace)
-	_, err := i.client.AcquireIP(ctx, connect.NewRequest(&apiv1.AcquireIPRequest{
-		PrefixCidr: ipamPrefix,
-		Ip:         &TunnelIP,
-		Namespace:  &ns,
-	}))
-	return err
-}
-
-func (i *IPAM) AssignSpecificTunnelIP(parent context.Context, namespace uuid.UUID, ipamPrefix string, TunnelIP string) (string, error) {
-	ctx, span := tracer.Start(parent, "AssignSpecificTunnelIP")
-	defer span.End()
-	if err := validateIP(TunnelIP); err != nil {
-		return "", fmt.Errorf("Address %s is not valid", TunnelIP)
-	}
-	ns := uuidToNamespace(namespace)
-	res, err := i.client.AcquireIP(ctx, connect.NewRequest(&apiv1.AcquireIPRequest{
-		PrefixCidr: ipamPrefix,
-		Ip:         &TunnelIP,
-		Namespace:  &ns,
-	}))
-	if err != nil {
-		i.logger.Errorf("failed to assign the requested address %s, assigning an address from the pool: %v\n", TunnelIP, err)
-		return i.AssignFromPool(ctx, namespace, ipamPrefix)
-	}
-	return res.Msg.Ip.Ip, nil
-}
-
-func (i *IPAM) AssignFromPool(parent context.Context, namespace uuid.UUID, ipamPrefix string) (string, error) {
-	ctx, span := tracer.Start(parent, "AssignFromPool")
-	defer span.End()
-	ns := uuidToNamespace(namespace)
-	res, err := i.client.AcquireIP(ctx, connect.NewRequest(&apiv1.AcquireIPRequest{
-		PrefixCidr: ipamPrefix,
-		Namespace:  &ns,
-	}))
-	if err != nil {
-		return "", fmt.Errorf("failed to acquire an IPAM assigned address %w\n", err)
-	}
-	return res.Msg.Ip.Ip, nil
-}
+// Backends that an IPAM can be created for.
+const (
+	BackendDatabase = "database"
+	BackendRemote   = "remote"
+)
 
-func (i *IPAM) AssignPrefix(parent context.Context, namespace uuid.UUID, cidr string) error {
-	println()
-	ctx, span := tracer.Start(parent, "AssignPrefix")
-	defer span.End()
-	cidr, err := cleanCidr(cidr)
-	if err != nil {
-		return fmt.Errorf("invalid prefix requested: %w", err)
-	}
-	ns := uuidToNamespace(namespace)
-	_, originalErr := i.client.CreatePrefix(ctx, connect.NewRequest(&apiv1.CreatePrefixRequest{Cidr: cidr, Namespace: &ns}))
-	if originalErr != nil {
-		// check to see if the prefix had been already created....
-		resp, err := i.client.GetPrefix(ctx, connect.NewRequest(&apiv1.GetPrefixRequest{Cidr: cidr, Namespace: &ns}))
-		if err == nil {
-			// it did exist... so ignore that create error since the prefix was created.
-			if resp.Msg.Prefix.Cidr == cidr && resp.Msg.Prefix.ParentCidr == "" {
-				originalErr = nil
-			}
-		}
+// NewIPAM creates an IPAM for the backend, ipamAddress is the address of the go-ipam service that
+// the remote backend uses.
+func NewIPAM(logger *zap.SugaredLogger, backend string, db *gorm.DB, ipamAddress string) (IPAM, error) {
+	switch backend {
+	case BackendDatabase:
+		return NewDatabaseIPAM(logger, db), nil
+	case BackendRemote:
+		return NewRemoteIPAM(logger, ipamAddress), nil
 	}
-	return originalErr
+	return nil, fmt.Errorf("unknown ipam backend '%s', must be '%s' or '%s'", backend, BackendDatabase, BackendRemote)
 }
 
-// ReleaseToPool release the ipam address back to the specified prefix
-func (i *IPAM) ReleaseToPool(ctx context.Context, namespace uuid.UUID, address, cidr string) error {
-	ns := uuidToNamespace(namespace)
-	_, err := i.client.ReleaseIP(ctx, connect.NewRequest(&apiv1.ReleaseIPRequest{
-		Ip:         address,
-		PrefixCidr: cidr,
-		Namespace:  &ns,
-	}))
+type transactionKey struct{}
 
-	if err != nil {
-		return fmt.Errorf("failed to release IPAM address %w", err)
-	}
-	return nil
+// WithTransaction returns a context that makes the allocations of a DatabaseIPAM part of the
+// transaction tx, so that they are committed or rolled back with it. A RemoteIPAM ignores it.
+func WithTransaction(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, transactionKey{}, tx)
 }
 
-// ReleasePrefix release the ipam address back to the specified prefix
-func (i *IPAM) ReleasePrefix(ctx context.Context, namespace uuid.UUID, cidr string) error {
-	ns := uuidToNamespace(namespace)
-	_, err := i.client.DeletePrefix(ctx, connect.NewRequest(&apiv1.DeletePrefixRequest{
-		Cidr:      cidr,
-		Namespace: &ns,
-	}))
-
-	if err != nil {
-		return fmt.Errorf("failed to release IPAM prefix %w", err)
-	}
-	return nil
+func transactionFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(transactionKey{}).(*gorm.DB)
+	return tx, ok
 }
 
 // cleanCidr ensures a valid IP4/IP6 address is provided and return a proper
@@ -19,7 +19,7 @@ import (
 type IpamTestSuite struct {
 	suite.Suite
 	logger *zap.SugaredLogger
-	ipam   IPAM
+	ipam   *RemoteIPAM
 	server *http.Server
 	wg     sync.WaitGroup
 }
@@ -27,7 +27,7 @@ type IpamTestSuite struct {
 func (suite *IpamTestSuite) SetupSuite() {
 	suite.server = NewTestIPAMServer()
 	suite.logger = zaptest.NewLogger(suite.T()).Sugar()
-	suite.ipam = NewIPAM(suite.logger, TestIPAMClientAddr)
+	suite.ipam = NewRemoteIPAM(suite.logger, TestIPAMClientAddr)
 	suite.wg = sync.WaitGroup{}
 	suite.wg.Add(1)
 	listener, err := net.Listen("tcp", "[::1]:9091")
@@ -91,6 +91,33 @@ func (suite *IpamTestSuite) TestAllocateTunnelIP() {
 	assert.Equal(suite.T(), "10.20.30.3", ip)
 }
 
+func (suite *IpamTestSuite) TestCountAddresses() {
+	require := suite.Require()
+	ctx := context.Background()
+	namespace := uuid.New()
+	require.NoError(suite.ipam.CreateNamespace(ctx, namespace))
+	require.NoError(suite.ipam.AssignPrefix(ctx, namespace, "10.30.0.0/24"))
+	require.NoError(suite.ipam.AssignPrefix(ctx, namespace, "fd00:30::/64"))
+	_, err := suite.ipam.AssignFromPool(ctx, namespace, "10.30.0.0/24")
+	require.NoError(err)
+	_, err = suite.ipam.AssignFromPool(ctx, namespace, "10.30.0.0/24")
+	require.NoError(err)
+	_, err = suite.ipam.AssignFromPool(ctx, namespace, "fd00:30::/64")
+	require.NoError(err)
+
+	count, found, err := suite.ipam.CountAddresses(ctx, namespace, "10.30.0.0/24")
+	require.NoError(err)
+	assert.True(suite.T(), found)
+	assert.Equal(suite.T(), 2, count)
+	count, found, err = suite.ipam.CountAddresses(ctx, namespace, "fd00:30::/64")
+	require.NoError(err)
+	assert.True(suite.T(), found)
+	assert.Equal(suite.T(), 1, count)
+	_, found, err = suite.ipam.CountAddresses(ctx, namespace, "10.40.0.0/24")
+	require.NoError(err)
+	assert.False(suite.T(), found)
+}
+
 func TestIpamTestSuite(t *testing.T) {
 	suite.Run(t, new(IpamTestSuite))
 }
@@ -0,0 +1,186 @@
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/google/uuid"
+	apiv1 "github.com/metal-stack/go-ipam/api/v1"
+	"github.com/metal-stack/go-ipam/api/v1/apiv1connect"
+	"go.uber.org/zap"
+)
+
+func uuidToNamespace(id uuid.UUID) string {
+	return strings.ReplaceAll(id.String(), "-", "_")
+}
+
+// RemoteIPAM is an IPAM backed by an external go-ipam service. Its allocations are not part of
+// the transactions of the apiserver database.
+type RemoteIPAM struct {
+	logger *zap.SugaredLogger
+	client apiv1connect.IpamServiceClient
+}
+
+func NewRemoteIPAM(logger *zap.SugaredLogger, ipamAddress string) *RemoteIPAM {
+	return &RemoteIPAM{
+		logger: logger,
+		client: apiv1connect.NewIpamServiceClient(
+			http.DefaultClient,
+			ipamAddress,
+			connect.WithGRPC(),
+		)}
+}
+
+func (i *RemoteIPAM) CreateNamespace(parent context.Context, namespace uuid.UUID) error {
+	ctx, span := tracer.Start(parent, "CreateNamespace")
+	defer span.End()
+	_, err := i.client.CreateNamespace(ctx, connect.NewRequest(&apiv1.CreateNamespaceRequest{
+		Namespace: uuidToNamespace(namespace),
+	}))
+	return err
+}
+
+func (i *RemoteIPAM) DeleteNamespace(parent context.Context, namespace uuid.UUID) error {
+	ctx, span := tracer.Start(parent, "DeleteNamespace")
+	defer span.End()
+	_, err := i.client.DeleteNamespace(ctx, connect.NewRequest(&apiv1.DeleteNamespaceRequest{
+		Namespace: uuidToNamespace(namespace),
+	}))
+	return err
+}
+
+func (i *RemoteIPAM) AcquireIP(parent context.Context, namespace uuid.UUID, ipamPrefix string, TunnelIP string) error {
+	ctx, span := tracer.Start(parent, "AssignSpecificTunnelIP")
+	defer span.End()
+	if err := validateIP(TunnelIP); err != nil {
+		return fmt.Errorf("Address %s is not valid", TunnelIP)
+	}
+	ns := uuidToNamespace(namespace)
+	_, err := i.client.AcquireIP(ctx, connect.NewRequest(&apiv1.AcquireIPRequest{
+		PrefixCidr: ipamPrefix,
+		Ip:         &TunnelIP,
+		Namespace:  &ns,
+	}))
+	return err
+}
+
+func (i *RemoteIPAM) AssignSpecificTunnelIP(parent context.Context, namespace uuid.UUID, ipamPrefix string, TunnelIP string) (string, error) {
+	ctx, span := tracer.Start(parent, "AssignSpecificTunnelIP")
+	defer span.End()
+	if err := validateIP(TunnelIP); err != nil {
+		return "", fmt.Errorf("Address %s is not valid", TunnelIP)
+	}
+	ns := uuidToNamespace(namespace)
+	res, err := i.client.AcquireIP(ctx, connect.NewRequest(&apiv1.AcquireIPRequest{
+		PrefixCidr: ipamPrefix,
+		Ip:         &TunnelIP,
+		Namespace:  &ns,
+	}))
+	if err != nil {
+		i.logger.Errorf("failed to assign the requested address %s, assigning an address from the pool: %v\n", TunnelIP, err)
+		return i.AssignFromPool(ctx, namespace, ipamPrefix)
+	}
+	return res.Msg.Ip.Ip, nil
+}
+
+func (i *RemoteIPAM) AssignFromPool(parent context.Context, namespace uuid.UUID, ipamPrefix string) (string, error) {
+	ctx, span := tracer.Start(parent, "AssignFromPool")
+	defer span.End()
+	ns := uuidToNamespace(namespace)
+	res, err := i.client.AcquireIP(ctx, connect.NewRequest(&apiv1.AcquireIPRequest{
+		PrefixCidr: ipamPrefix,
+		Namespace:  &ns,
+	}))
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire an IPAM assigned address %w\n", err)
+	}
+	return res.Msg.Ip.Ip, nil
+}
+
+func (i *RemoteIPAM) AssignPrefix(parent context.Context, namespace uuid.UUID, cidr string) error {
+	ctx, span := tracer.Start(parent, "AssignPrefix")
+	defer span.End()
+	cidr, err := cleanCidr(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid prefix requested: %w", err)
+	}
+	ns := uuidToNamespace(namespace)
+	_, originalErr := i.client.CreatePrefix(ctx, connect.NewRequest(&apiv1.CreatePrefixRequest{Cidr: cidr, Namespace: &ns}))
+	if originalErr != nil {
+		// check to see if the prefix had been already created....
+		resp, err := i.client.GetPrefix(ctx, connect.NewRequest(&apiv1.GetPrefixRequest{Cidr: cidr, Namespace: &ns}))
+		if err == nil {
+			// it did exist... so ignore that create error since the prefix was created.
+			if resp.Msg.Prefix.Cidr == cidr && resp.Msg.Prefix.ParentCidr == "" {
+				originalErr = nil
+			}
+		}
+	}
+	return originalErr
+}
+
+// ReleaseToPool release the ipam address back to the specified prefix
+func (i *RemoteIPAM) ReleaseToPool(ctx context.Context, namespace uuid.UUID, address, cidr string) error {
+	ns := uuidToNamespace(namespace)
+	_, err := i.client.ReleaseIP(ctx, connect.NewRequest(&apiv1.ReleaseIPRequest{
+		Ip:         address,
+		PrefixCidr: cidr,
+		Namespace:  &ns,
+	}))
+
+	if err != nil {
+		return fmt.Errorf("failed to release IPAM address %w", err)
+	}
+	return nil
+}
+
+// ReleasePrefix release the ipam address back to the specified prefix
+func (i *RemoteIPAM) ReleasePrefix(ctx context.Context, namespace uuid.UUID, cidr string) error {
+	ns := uuidToNamespace(namespace)
+	_, err := i.client.DeletePrefix(ctx, connect.NewRequest(&apiv1.DeletePrefixRequest{
+		Cidr:      cidr,
+		Namespace: &ns,
+	}))
+
+	if err != nil {
+		return fmt.Errorf("failed to release IPAM prefix %w", err)
+	}
+	return nil
+}
+
+// CountAddresses returns the number of addresses allocated from the prefix, without the network
+// and broadcast addresses that the go-ipam service allocates when it creates a prefix.
+func (i *RemoteIPAM) CountAddresses(parent context.Context, namespace uuid.UUID, cidr string) (int, bool, error) {
+	ctx, span := tracer.Start(parent, "CountAddresses")
+	defer span.End()
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid prefix %s: %w", cidr, err)
+	}
+	ns := uuidToNamespace(namespace)
+	if _, err := i.client.GetPrefix(ctx, connect.NewRequest(&apiv1.GetPrefixRequest{Cidr: cidr, Namespace: &ns})); err != nil {
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get IPAM prefix %w", err)
+	}
+	usage, err := i.client.PrefixUsage(ctx, connect.NewRequest(&apiv1.PrefixUsageRequest{
+		Cidr:      cidr,
+		Namespace: &ns,
+	}))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get IPAM prefix usage %w", err)
+	}
+	reserved := uint64(1)
+	if prefix.Addr().Is4() {
+		reserved = 2
+	}
+	if usage.Msg.AcquiredIps < reserved {
+		return 0, true, nil
+	}
+	return int(usage.Msg.AcquiredIps - reserved), true, nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Audit actions
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditEvent records a change made through the api. Audit events are append only,
+// they are never updated or deleted.
+type AuditEvent struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	CreatedAt      time.Time `json:"created_at" gorm:"index"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;index"`
+	ActorID        string    `json:"actor_id"`
+	ActorName      string    `json:"actor_name"`
+	Action         string    `json:"action" example:"update"`
+	ResourceType   string    `json:"resource_type" example:"device"`
+	ResourceID     string    `json:"resource_id"`
+	// Before is the resource before the change, it is not set for creates.
+	Before interface{} `json:"before,omitempty" gorm:"type:JSONB; serializer:json"`
+	// After is the resource after the change, it is not set for deletes.
+	After     interface{} `json:"after,omitempty" gorm:"type:JSONB; serializer:json"`
+	SourceIP  string      `json:"source_ip"`
+	RequestID string      `json:"request_id"`
+}
+
+// BeforeCreate populates the ID (if not set)
+func (e *AuditEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
@@ -27,24 +27,31 @@ type Device struct {
 	Endpoints                []Endpoint     `json:"endpoints" gorm:"type:JSONB; serializer:json"`
 	Revision                 uint64         `json:"revision" gorm:"type:bigserial;index:"`
 	SecurityGroupId          uuid.UUID      `json:"security_group_id"`
+	Ephemeral                bool           `json:"ephemeral"`
+	// RegKeyID is the registration key the device was registered with, registration keys can only
+	// manage the devices they registered.
+	RegKeyID uuid.UUID `json:"reg_key_id"`
+	// Labels can be used to select the device with a label selector.
+	Labels map[string]string `json:"labels" gorm:"type:JSONB; serializer:json"`
 }
 
 // AddDevice is the information needed to add a new Device.
 type AddDevice struct {
-	UserID                   string     `json:"user_id" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
-	OrganizationID           uuid.UUID  `json:"organization_id" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
-	PublicKey                string     `json:"public_key"`
-	TunnelIP                 string     `json:"tunnel_ip" example:"1.2.3.4"`
-	TunnelIpV6               string     `json:"tunnel_ip_v6" example:"200::1"`
-	ChildPrefix              []string   `json:"child_prefix" example:"172.16.42.0/24"`
-	Relay                    bool       `json:"relay"`
-	Discovery                bool       `json:"discovery"`
-	EndpointLocalAddressIPv4 string     `json:"endpoint_local_address_ip4" example:"1.2.3.4"`
-	SymmetricNat             bool       `json:"symmetric_nat"`
-	Hostname                 string     `json:"hostname" example:"myhost"`
-	Endpoints                []Endpoint `json:"endpoints" gorm:"type:JSONB; serializer:json"`
-	Os                       string     `json:"os"`
-	SecurityGroupId          uuid.UUID  `json:"security_group_id"`
+	UserID                   string            `json:"user_id" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+	OrganizationID           uuid.UUID         `json:"organization_id" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+	PublicKey                string            `json:"public_key"`
+	TunnelIP                 string            `json:"tunnel_ip" example:"1.2.3.4"`
+	TunnelIpV6               string            `json:"tunnel_ip_v6" example:"200::1"`
+	ChildPrefix              []string          `json:"child_prefix" example:"172.16.42.0/24"`
+	Relay                    bool              `json:"relay"`
+	Discovery                bool              `json:"discovery"`
+	EndpointLocalAddressIPv4 string            `json:"endpoint_local_address_ip4" example:"1.2.3.4"`
+	SymmetricNat             bool              `json:"symmetric_nat"`
+	Hostname                 string            `json:"hostname" example:"myhost"`
+	Endpoints                []Endpoint        `json:"endpoints" gorm:"type:JSONB; serializer:json"`
+	Os                       string            `json:"os"`
+	SecurityGroupId          uuid.UUID         `json:"security_group_id"`
+	Labels                   map[string]string `json:"labels"`
 }
 
 // UpdateDevice is the information needed to update a Device.
@@ -56,4 +63,6 @@ type UpdateDevice struct {
 	Hostname                 string     `json:"hostname" example:"myhost"`
 	Endpoints                []Endpoint `json:"endpoints" gorm:"type:JSONB; serializer:json"`
 	Revision                 *uint64    `json:"revision"`
+	// Labels replaces all the labels of the device when set.
+	Labels map[string]string `json:"labels"`
 }
@@ -0,0 +1,57 @@
+package models
+
+import "github.com/google/uuid"
+
+// DeviceSelector selects the devices of a batch operation, by id or by filter. The filters
+// are combined, and only select devices the current user is allowed to change.
+type DeviceSelector struct {
+	IDs            []uuid.UUID `json:"ids"`
+	OrganizationID uuid.UUID   `json:"organization_id" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+	// Hostname selects the devices with a matching hostname, * matches any characters and ? a single one.
+	Hostname string `json:"hostname" example:"lab-*"`
+	// MetadataKey selects the devices that have the metadata key.
+	MetadataKey string `json:"metadata_key" example:"lab"`
+	// MetadataValue also requires the value of the metadata key to be equal.
+	MetadataValue interface{} `json:"metadata_value"`
+	// Labels selects the devices with labels that match the label selector.
+	Labels string `json:"labels" example:"env=prod,tier!=db"`
+}
+
+// DeviceBatchDelete deletes the selected devices.
+type DeviceBatchDelete struct {
+	Selector DeviceSelector `json:"selector"`
+}
+
+// DeviceBatchMove moves the selected devices to an organization.
+type DeviceBatchMove struct {
+	Selector       DeviceSelector `json:"selector"`
+	OrganizationID uuid.UUID      `json:"organization_id" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+}
+
+// DeviceBatchSecurityGroup sets the security group of the selected devices.
+type DeviceBatchSecurityGroup struct {
+	Selector        DeviceSelector `json:"selector"`
+	SecurityGroupID uuid.UUID      `json:"security_group_id" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+}
+
+// DeviceBatchMetadata sets a metadata key on the selected devices.
+type DeviceBatchMetadata struct {
+	Selector DeviceSelector `json:"selector"`
+	Key      string         `json:"key" example:"lab"`
+	Value    interface{}    `json:"value"`
+}
+
+// DeviceBatchResult is the outcome of a batch operation. The batch is only committed if
+// the operation succeeded on all the selected devices.
+type DeviceBatchResult struct {
+	Committed bool                    `json:"committed"`
+	Results   []DeviceBatchItemResult `json:"results"`
+}
+
+// DeviceBatchItemResult is the outcome of a batch operation on one device.
+type DeviceBatchItemResult struct {
+	DeviceID uuid.UUID `json:"device_id"`
+	// Status is the http status code of the operation on the device.
+	Status int    `json:"status" example:"200"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceHeartbeat records when a device last reported that it is up. It's kept apart from the
+// device, so that the heartbeats don't change the revision of the device that is watched.
+type DeviceHeartbeat struct {
+	DeviceID uuid.UUID `gorm:"type:uuid;primary_key"`
+	SeenAt   time.Time `gorm:"index"`
+	// Down is set once the device has been reported down, until its next heartbeat.
+	Down bool
+}
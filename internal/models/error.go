@@ -1,5 +1,7 @@
 package models
 
+import "fmt"
+
 // BaseError is the base type for API errors
 type BaseError struct {
 	Error string `json:"error" example:"something bad"`
@@ -106,3 +108,57 @@ func NewNotAllowedError(reason string) NotAllowedError {
 		},
 	}
 }
+
+// GoneError is returned in the body of an HTTP 410
+type GoneError struct {
+	BaseError
+	Reason string `json:"reason,omitempty"`
+}
+
+func NewGoneError(reason string) GoneError {
+	return GoneError{
+		Reason: reason,
+		BaseError: BaseError{
+			Error: "resource is gone",
+		},
+	}
+}
+
+// QuotaExceededError is returned in the body of an HTTP 403 when creating a resource would exceed a quota
+type QuotaExceededError struct {
+	NotAllowedError
+	Quota string `json:"quota" example:"devices_per_organization"`
+	Limit int    `json:"limit" example:"100"`
+}
+
+func NewQuotaExceededError(quota string, limit int) QuotaExceededError {
+	return QuotaExceededError{
+		Quota: quota,
+		Limit: limit,
+		NotAllowedError: NotAllowedError{
+			Reason: fmt.Sprintf("the %s quota of %d has been reached", quota, limit),
+			BaseError: BaseError{
+				Error: "quota exceeded",
+			},
+		},
+	}
+}
+
+// TooManyRequestsError is returned in the body of an HTTP 429
+type TooManyRequestsError struct {
+	BaseError
+	Scope      string `json:"scope,omitempty" example:"user"`
+	Limit      int    `json:"limit,omitempty" example:"1200"`
+	RetryAfter int    `json:"retry_after,omitempty" example:"30"`
+}
+
+func NewTooManyRequestsError(scope string, limit int, retryAfter int) TooManyRequestsError {
+	return TooManyRequestsError{
+		Scope:      scope,
+		Limit:      limit,
+		RetryAfter: retryAfter,
+		BaseError: BaseError{
+			Error: "too many requests",
+		},
+	}
+}
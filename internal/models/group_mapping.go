@@ -0,0 +1,23 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// OrganizationGroupMapping makes the users of an identity provider group members of an organization.
+// The memberships are added and removed as the groups claim of the users changes.
+type OrganizationGroupMapping struct {
+	Base
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;index"`
+	// Group is the name of the group in the groups claim of the users. The groups of the providers
+	// other than the first one are prefixed with the name of their provider and a ':'.
+	Group string `json:"group" gorm:"column:group_name" example:"engineering"`
+	// Role is the role of the members of the group in the organization.
+	Role string `json:"role" example:"member"`
+}
+
+// AddOrganizationGroupMapping is the information needed to map a group to organization membership.
+type AddOrganizationGroupMapping struct {
+	Group string `json:"group" example:"engineering"`
+	Role  string `json:"role" example:"member"`
+}
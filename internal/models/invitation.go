@@ -6,28 +6,54 @@ import (
 	"github.com/google/uuid"
 )
 
+// DefaultInvitationExpiry is how long invitations are valid when the api server doesn't set another expiry.
+const DefaultInvitationExpiry = time.Hour * 24 * 7
+
 // Invitation is a request for a user to join an organization
 type Invitation struct {
 	Base
-	UserID         string    `json:"user_id"`
+	// UserID is the invited user, it's empty for the invitations by email of users that never logged in.
+	UserID string `json:"user_id"`
+	// Email is the address the invitation was sent to.
+	Email          string    `json:"email,omitempty"`
 	OrganizationID uuid.UUID `json:"organization_id"`
-	Expiry         time.Time `json:"expiry"`
+	// Role is the role of the user in the organization once they accept the invitation.
+	Role     string    `json:"role" example:"member"`
+	Expiry   time.Time `json:"expiry"`
+	Revision uint64    `json:"revision" gorm:"type:bigserial;index:"`
+	// TokenHash is the hash of the token of the invitation link, the token itself is not stored.
+	TokenHash string `json:"-"`
+	// Link accepts the invitation, it's the token of the invitation when the api server has no link url.
+	// It's only returned when the invitation is created.
+	Link string `json:"link,omitempty" gorm:"-"`
 }
 
-func NewInvitation(userID string, orgID uuid.UUID) Invitation {
-	// invitation expires after 1 week
-	expiry := time.Now().Add(time.Hour * 24 * 7)
+func NewInvitation(userID string, orgID uuid.UUID, role string, expiry time.Duration) Invitation {
 	return Invitation{
 		UserID:         userID,
 		OrganizationID: orgID,
-		Expiry:         expiry,
+		Role:           role,
+		Expiry:         time.Now().Add(expiry),
 	}
 }
 
 type AddInvitation struct {
-	// The username to invite (one of username or user_id is required)
+	// The username to invite (one of username, user_id or email is required)
 	UserName string `json:"user_name"`
-	// The user id to invite (one of username or user_id is required)
-	UserID         string    `json:"user_id"`
+	// The user id to invite (one of username, user_id or email is required)
+	UserID string `json:"user_id"`
+	// The email address to send the invitation to, the user doesn't need to have logged in before
+	// (one of username, user_id or email is required)
+	Email          string    `json:"email"`
 	OrganizationID uuid.UUID `json:"organization_id"`
+	// The role of the user in the organization, member by default
+	Role string `json:"role" example:"member"`
+	// How long the invitation is valid, for example 72h, the default of the api server when empty
+	ExpiresIn string `json:"expires_in" example:"72h"`
+}
+
+// AcceptInvitation accepts an invitation with the token of its link.
+type AcceptInvitation struct {
+	// The token of the invitation, from its link
+	Token string `json:"token"`
 }
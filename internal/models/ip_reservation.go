@@ -0,0 +1,33 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// IpReservation reserves tunnel addresses of an organization for a device identity. The addresses stay
+// allocated while no device uses them, and a device that joins the organization with the identity is
+// given them, so a device that is deleted and recreated keeps its addresses.
+type IpReservation struct {
+	Base
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;index"`
+	// Hostname, PublicKey and RegistrationKeyID are the identity of the device, only one of them is set.
+	Hostname          string    `json:"hostname,omitempty" example:"build-cache"`
+	PublicKey         string    `json:"public_key,omitempty"`
+	RegistrationKeyID uuid.UUID `json:"registration_key_id" gorm:"type:uuid"`
+	// TunnelIP and TunnelIpV6 are the reserved addresses, at least one of them is set.
+	TunnelIP    string `json:"tunnel_ip,omitempty" example:"100.100.0.10"`
+	TunnelIpV6  string `json:"tunnel_ip_v6,omitempty" example:"200::a"`
+	Description string `json:"description" example:"referenced by the datacenter firewall"`
+}
+
+// AddIpReservation is the information needed to reserve tunnel addresses for a device identity.
+type AddIpReservation struct {
+	// Hostname, PublicKey and RegistrationKeyID are the identity of the device, only one of them can be set.
+	Hostname          string    `json:"hostname" example:"build-cache"`
+	PublicKey         string    `json:"public_key"`
+	RegistrationKeyID uuid.UUID `json:"registration_key_id"`
+	// TunnelIP and TunnelIpV6 are the addresses to reserve, at least one of them has to be set.
+	TunnelIP    string `json:"tunnel_ip" example:"100.100.0.10"`
+	TunnelIpV6  string `json:"tunnel_ip_v6" example:"200::a"`
+	Description string `json:"description" example:"referenced by the datacenter firewall"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of IPAM drift
+const (
+	IpamDriftLeaked   = "leaked"
+	IpamDriftMissing  = "missing"
+	IpamDriftConflict = "conflict"
+	// IpamDriftAddresses is only found with an IPAM backend that can't list its addresses.
+	IpamDriftAddresses = "addresses"
+)
+
+// IpamDrift is an IPAM allocation that doesn't match the devices and organizations.
+type IpamDrift struct {
+	// Kind is leaked for an allocation that no device or organization uses, missing for a prefix or
+	// address of a device or organization that isn't allocated, conflict for an address used by
+	// several devices, and addresses for a prefix that doesn't have as many addresses allocated as
+	// the devices use.
+	Kind string `json:"kind" example:"leaked"`
+	// Namespace is the IPAM namespace, the ID of the organization for an organization with a private CIDR.
+	Namespace uuid.UUID `json:"namespace"`
+	Prefix    string    `json:"prefix" example:"100.64.0.0/10"`
+	// Address is empty for a prefix.
+	Address string `json:"address,omitempty" example:"100.64.0.1"`
+	// OrganizationID is the organization that the allocation belongs to, it's not set for the leaks and the
+	// prefixes of the namespace shared by the organizations without a private CIDR.
+	OrganizationID uuid.UUID   `json:"organization_id"`
+	DeviceIDs      []uuid.UUID `json:"device_ids,omitempty"`
+	// AllocatedAddresses and UsedAddresses are the number of addresses allocated from the prefix and used
+	// by the devices, for the addresses kind.
+	AllocatedAddresses int `json:"allocated_addresses,omitempty"`
+	UsedAddresses      int `json:"used_addresses,omitempty"`
+	// Repaired is true when the reconciliation released the leaked allocation or allocated the missing one.
+	Repaired bool `json:"repaired"`
+}
+
+// IpamDriftReport is the drift found by the last IPAM reconciliation.
+type IpamDriftReport struct {
+	// CheckedAt is not set until the IPAM has been reconciled.
+	CheckedAt *time.Time  `json:"checked_at,omitempty"`
+	Drift     []IpamDrift `json:"drift"`
+}
@@ -0,0 +1,218 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// DeviceLabel indexes the labels of the devices so that they can be selected efficiently.
+type DeviceLabel struct {
+	DeviceID uuid.UUID `gorm:"type:uuid;primary_key"`
+	Key      string    `gorm:"primary_key;index:idx_device_labels_key_value,priority:1"`
+	Value    string    `gorm:"index:idx_device_labels_key_value,priority:2"`
+}
+
+var (
+	labelKeyPrefixRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	labelNameRegex      = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+)
+
+// ValidateLabelKey checks the key is a name, optionally prefixed with a dns subdomain and a /,
+// like the keys of kubernetes labels.
+func ValidateLabelKey(key string) error {
+	name := key
+	if prefix, rest, ok := strings.Cut(key, "/"); ok {
+		if len(prefix) == 0 || len(prefix) > 253 || !labelKeyPrefixRegex.MatchString(prefix) {
+			return fmt.Errorf("invalid label key %q: the prefix must be a dns subdomain", key)
+		}
+		name = rest
+	}
+	if len(name) == 0 || len(name) > 63 || !labelNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid label key %q: the name must be at most 63 alphanumeric, '-', '_' or '.' characters", key)
+	}
+	return nil
+}
+
+// ValidateLabelValue checks the value is empty or at most 63 alphanumeric, '-', '_' or '.' characters.
+func ValidateLabelValue(value string) error {
+	if value != "" && (len(value) > 63 || !labelNameRegex.MatchString(value)) {
+		return fmt.Errorf("invalid label value %q: the value must be at most 63 alphanumeric, '-', '_' or '.' characters", value)
+	}
+	return nil
+}
+
+// ValidateLabels checks all the keys and values of the labels.
+func ValidateLabels(labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	// report the errors in a stable order.
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := ValidateLabelKey(key); err != nil {
+			return err
+		}
+		if err := ValidateLabelValue(labels[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LabelOperator is the operator of a LabelRequirement.
+type LabelOperator string
+
+const (
+	LabelExists       LabelOperator = "exists"
+	LabelDoesNotExist LabelOperator = "!"
+	LabelEquals       LabelOperator = "="
+	LabelNotEquals    LabelOperator = "!="
+	LabelIn           LabelOperator = "in"
+	LabelNotIn        LabelOperator = "notin"
+)
+
+// LabelRequirement is one of the comma separated terms of a LabelSelector.
+type LabelRequirement struct {
+	Key      string
+	Operator LabelOperator
+	Values   []string
+}
+
+// Matches returns true if the labels meet the requirement. Like in kubernetes, != and notin
+// match labels that don't have the key.
+func (r LabelRequirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case LabelExists:
+		return ok
+	case LabelDoesNotExist:
+		return !ok
+	case LabelEquals, LabelIn:
+		return ok && contains(r.Values, value)
+	case LabelNotEquals, LabelNotIn:
+		return !ok || !contains(r.Values, value)
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// LabelSelector selects the devices with labels that meet all the requirements.
+type LabelSelector []LabelRequirement
+
+// Matches returns true if the labels meet all the requirements of the selector.
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseLabelSelector parses a kubernetes style label selector, for example:
+// "env=prod,tier!=db,region in (us-east,us-west),!deprecated".
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var result LabelSelector
+	for _, term := range splitSelectorTerms(selector) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("invalid label selector %q: empty requirement", selector)
+		}
+		r, err := parseLabelRequirement(term)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// splitSelectorTerms splits the selector on the commas that are not in a set of values.
+func splitSelectorTerms(selector string) []string {
+	if strings.TrimSpace(selector) == "" {
+		return nil
+	}
+	var terms []string
+	depth := 0
+	start := 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+func parseLabelRequirement(term string) (LabelRequirement, error) {
+	r := LabelRequirement{}
+	switch {
+	case strings.HasPrefix(term, "!"):
+		r.Key = strings.TrimSpace(term[1:])
+		r.Operator = LabelDoesNotExist
+	case strings.Contains(term, "!="):
+		key, value, _ := strings.Cut(term, "!=")
+		r.Key = strings.TrimSpace(key)
+		r.Operator = LabelNotEquals
+		r.Values = []string{strings.TrimSpace(value)}
+	case strings.Contains(term, "="):
+		key, value, _ := strings.Cut(term, "=")
+		// == is the same as =
+		value = strings.TrimPrefix(value, "=")
+		r.Key = strings.TrimSpace(key)
+		r.Operator = LabelEquals
+		r.Values = []string{strings.TrimSpace(value)}
+	case strings.HasSuffix(term, ")"):
+		open := strings.Index(term, "(")
+		if open < 0 {
+			return r, fmt.Errorf("invalid label requirement %q", term)
+		}
+		fields := strings.Fields(term[:open])
+		if len(fields) != 2 {
+			return r, fmt.Errorf("invalid label requirement %q", term)
+		}
+		r.Key = fields[0]
+		switch LabelOperator(fields[1]) {
+		case LabelIn, LabelNotIn:
+			r.Operator = LabelOperator(fields[1])
+		default:
+			return r, fmt.Errorf("invalid label requirement %q: unknown operator %q", term, fields[1])
+		}
+		for _, value := range strings.Split(term[open+1:len(term)-1], ",") {
+			r.Values = append(r.Values, strings.TrimSpace(value))
+		}
+	default:
+		r.Key = strings.TrimSpace(term)
+		r.Operator = LabelExists
+	}
+
+	if err := ValidateLabelKey(r.Key); err != nil {
+		return r, err
+	}
+	for _, value := range r.Values {
+		if err := ValidateLabelValue(value); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
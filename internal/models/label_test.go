@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	selector, err := ParseLabelSelector("env=prod, tier!=db,region in (us-east, us-west),!deprecated,example.com/team,size==large")
+	require.NoError(t, err)
+	require.Equal(t, LabelSelector{
+		{Key: "env", Operator: LabelEquals, Values: []string{"prod"}},
+		{Key: "tier", Operator: LabelNotEquals, Values: []string{"db"}},
+		{Key: "region", Operator: LabelIn, Values: []string{"us-east", "us-west"}},
+		{Key: "deprecated", Operator: LabelDoesNotExist},
+		{Key: "example.com/team", Operator: LabelExists},
+		{Key: "size", Operator: LabelEquals, Values: []string{"large"}},
+	}, selector)
+
+	require.True(t, selector.Matches(map[string]string{"env": "prod", "region": "us-west", "example.com/team": "a", "size": "large"}))
+	// != matches devices without the label.
+	require.False(t, selector.Matches(map[string]string{"env": "prod", "tier": "db", "region": "us-west", "example.com/team": "a", "size": "large"}))
+	require.False(t, selector.Matches(map[string]string{"env": "prod", "region": "eu", "example.com/team": "a", "size": "large"}))
+	require.False(t, selector.Matches(map[string]string{"env": "prod", "region": "us-west", "example.com/team": "a", "size": "large", "deprecated": ""}))
+
+	selector, err = ParseLabelSelector("")
+	require.NoError(t, err)
+	require.True(t, selector.Matches(nil))
+
+	for _, invalid := range []string{"env=prod,", "env in prod", "env exists (a)", "-env=prod", "env=prod!", "env in (a,b"} {
+		_, err = ParseLabelSelector(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	require.NoError(t, ValidateLabels(map[string]string{"env": "prod", "example.com/tier": "", "a.b_c-d": "x.y_z-1"}))
+	require.Error(t, ValidateLabels(map[string]string{"env": "prod value"}))
+	require.Error(t, ValidateLabels(map[string]string{"/env": "prod"}))
+	require.Error(t, ValidateLabels(map[string]string{"Example.com/env": "prod"}))
+}
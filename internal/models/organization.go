@@ -21,6 +21,7 @@ type Organization struct {
 	HubZone         bool      `json:"hub_zone"`
 	Invitations     []*Invitation
 	SecurityGroupId uuid.UUID `json:"security_group_id"`
+	Revision        uint64    `json:"revision" gorm:"type:bigserial;index:"`
 }
 
 // Organization contains Users and their Devices
@@ -34,6 +35,7 @@ type OrganizationJSON struct {
 	IpCidrV6        string    `json:"cidr_v6" example:"200::/8"`
 	HubZone         bool      `json:"hub_zone"`
 	SecurityGroupId uuid.UUID `json:"security_group_id"`
+	Revision        uint64    `json:"revision"`
 }
 
 func (o Organization) MarshalJSON() ([]byte, error) {
@@ -47,6 +49,7 @@ func (o Organization) MarshalJSON() ([]byte, error) {
 		IpCidrV6:        o.IpCidrV6,
 		HubZone:         o.HubZone,
 		SecurityGroupId: o.SecurityGroupId,
+		Revision:        o.Revision,
 	}
 	return json.Marshal(org)
 }
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Organization peering statuses
+const (
+	OrganizationPeeringPending = "pending"
+	OrganizationPeeringActive  = "active"
+)
+
+// OrganizationPeering is an agreement between two organizations that makes the selected devices of
+// each organization reachable from the selected devices of the other.
+type OrganizationPeering struct {
+	Base
+	// OrganizationID is the organization that requested the peering.
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;index"`
+	// Selector selects the devices of the requesting organization, no devices when empty.
+	Selector string `json:"selector" example:"role=build-cache"`
+	// Prefixes are the child prefixes of the devices of the requesting organization that are peered.
+	Prefixes pq.StringArray `json:"prefixes" gorm:"type:text[]" swaggertype:"array,string" example:"172.16.10.0/24"`
+	// PeerOrganizationID is the organization that has to accept the peering.
+	PeerOrganizationID uuid.UUID `json:"peer_organization_id" gorm:"type:uuid;index"`
+	// PeerSelector selects the devices of the peer organization, it is set when the peering is accepted.
+	PeerSelector string `json:"peer_selector" example:"team=ci"`
+	// PeerPrefixes are the child prefixes of the devices of the peer organization that are peered, they
+	// are set when the peering is accepted.
+	PeerPrefixes pq.StringArray `json:"peer_prefixes" gorm:"type:text[]" swaggertype:"array,string" example:"172.16.20.0/24"`
+	Status       string         `json:"status" example:"active"`
+}
+
+// OrganizationPeeringSide is what an organization of a peering connects to the other organization.
+type OrganizationPeeringSide struct {
+	Selector string
+	Prefixes []string
+}
+
+// Sides returns the side of orgId and the side of the other organization of the peering.
+func (p OrganizationPeering) Sides(orgId uuid.UUID) (local OrganizationPeeringSide, remote OrganizationPeeringSide) {
+	requester := OrganizationPeeringSide{Selector: p.Selector, Prefixes: p.Prefixes}
+	peer := OrganizationPeeringSide{Selector: p.PeerSelector, Prefixes: p.PeerPrefixes}
+	if p.OrganizationID == orgId {
+		return requester, peer
+	}
+	return peer, requester
+}
+
+// OtherOrganizationID returns the ID of the organization of the peering that isn't orgId.
+func (p OrganizationPeering) OtherOrganizationID(orgId uuid.UUID) uuid.UUID {
+	if p.OrganizationID == orgId {
+		return p.PeerOrganizationID
+	}
+	return p.OrganizationID
+}
+
+// AddOrganizationPeering is the information needed to request a peering with another organization.
+type AddOrganizationPeering struct {
+	PeerOrganizationID uuid.UUID `json:"peer_organization_id" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	// Selector selects the devices of the requesting organization that the peer organization can reach.
+	Selector string `json:"selector" example:"role=build-cache"`
+	// Prefixes are the child prefixes of the devices of the requesting organization that the peer
+	// organization can reach. A selector or prefixes are required.
+	Prefixes []string `json:"prefixes" example:"172.16.10.0/24"`
+}
+
+// AcceptOrganizationPeering is the information needed to accept a peering request.
+type AcceptOrganizationPeering struct {
+	// Selector selects the devices of the accepting organization that the requesting organization can reach.
+	Selector string `json:"selector" example:"team=ci"`
+	// Prefixes are the child prefixes of the devices of the accepting organization that the requesting
+	// organization can reach. A selector or prefixes are required.
+	Prefixes []string `json:"prefixes" example:"172.16.20.0/24"`
+}
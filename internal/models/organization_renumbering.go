@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// Organization renumbering statuses
+const (
+	OrganizationRenumberingInProgress = "in_progress"
+	OrganizationRenumberingCompleted  = "completed"
+)
+
+// OrganizationRenumbering is a change of the CIDRs of an organization. When the new CIDR contains the
+// previous one, the devices keep their addresses. Otherwise the devices are given addresses of the new
+// CIDR in batches, and the previous CIDR is released once all the devices were moved.
+type OrganizationRenumbering struct {
+	Base
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;index"`
+	PreviousCidr   string    `json:"previous_cidr" example:"172.16.42.0/24"`
+	PreviousCidrV6 string    `json:"previous_cidr_v6" example:"200::/64"`
+	Cidr           string    `json:"cidr" example:"172.16.40.0/22"`
+	CidrV6         string    `json:"cidr_v6" example:"200::/64"`
+	// BatchSize is the number of devices that are given new addresses at a time, all of them when 0.
+	BatchSize int `json:"batch_size" example:"10"`
+	// DevicesRemaining is the number of devices that still have an address of a previous CIDR.
+	DevicesRemaining int    `json:"devices_remaining"`
+	Status           string `json:"status" example:"in_progress"`
+}
+
+// ResizeOrganization is the request to change the CIDRs of an organization.
+type ResizeOrganization struct {
+	// Cidr is the new IPv4 CIDR, the CIDR doesn't change when it's not set.
+	Cidr string `json:"cidr" example:"172.16.40.0/22"`
+	// CidrV6 is the new IPv6 CIDR, the CIDR doesn't change when it's not set.
+	CidrV6 string `json:"cidr_v6" example:"200::/64"`
+	// BatchSize is the number of devices that are given new addresses at a time, all of them when 0.
+	BatchSize int `json:"batch_size" example:"10"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// RegKey is a registration key that allows a headless device to join an organization
+// without an interactive OIDC login.
+type RegKey struct {
+	Base
+	OwnerID        string    `json:"owner_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Description    string    `json:"description"`
+	// BearerToken is only returned when the key is created, only a hash of it is stored.
+	BearerToken string `json:"bearer_token,omitempty" gorm:"-"`
+	TokenHash   string `json:"-" gorm:"uniqueIndex"`
+	// SingleUse keys can only be used to register one device.
+	SingleUse bool `json:"single_use"`
+	// DeviceID is the device that consumed a single use key.
+	DeviceID uuid.UUID `json:"device_id"`
+	// Ephemeral devices are removed when the agent using the key shuts down, or stops sending heartbeats.
+	Ephemeral bool           `json:"ephemeral"`
+	Tags      pq.StringArray `json:"tags" gorm:"type:text[]" swaggertype:"array,string"`
+	ExpiresAt *time.Time     `json:"expires_at,omitempty"`
+}
+
+// IsExpired returns true if the key has an expiry that has passed.
+func (k RegKey) IsExpired() bool {
+	return k.ExpiresAt != nil && k.ExpiresAt.Before(time.Now())
+}
+
+// AddRegKey is the information needed to add a new registration key.
+type AddRegKey struct {
+	Description string     `json:"description" example:"autoscaling group workers"`
+	SingleUse   bool       `json:"single_use"`
+	Ephemeral   bool       `json:"ephemeral"`
+	Tags        []string   `json:"tags" example:"ci"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
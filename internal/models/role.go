@@ -0,0 +1,59 @@
+package models
+
+// Roles a user can have in an organization, from most to least privileged.
+const (
+	// RoleOwner can do anything in the organization, including deleting it.
+	RoleOwner = "owner"
+	// RoleAdmin can invite users, manage roles, security groups and any device.
+	RoleAdmin = "admin"
+	// RoleMember can manage their own devices.
+	RoleMember = "member"
+	// RoleReadOnly can view the organization but not change it.
+	RoleReadOnly = "read-only"
+)
+
+var roleRanks = map[string]int{
+	RoleReadOnly: 0,
+	RoleMember:   1,
+	RoleAdmin:    2,
+	RoleOwner:    3,
+}
+
+// IsValidRole returns true if role is a known organization role.
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RolesAtLeast returns the roles that are at least as privileged as role.
+func RolesAtLeast(role string) []string {
+	var roles []string
+	for r, rank := range roleRanks {
+		if rank >= roleRanks[role] {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// OrganizationMember is a user's membership of an organization.
+type OrganizationMember struct {
+	UserID   string `json:"user_id" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	UserName string `json:"username" example:"admin"`
+	Role     string `json:"role" example:"member"`
+	// GroupManaged is true when the user is a member through an organization group mapping.
+	GroupManaged bool `json:"group_managed"`
+}
+
+// UpdateOrganizationMember is the information needed to change a member's role.
+type UpdateOrganizationMember struct {
+	Role string `json:"role" example:"read-only"`
+}
+
+// HigherRole returns the more privileged of two roles.
+func HigherRole(a, b string) string {
+	if roleRanks[b] > roleRanks[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// The schemas of the SCIM 2.0 resources and messages, see RFC 7643 and RFC 7644.
+const (
+	ScimSchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimSchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ScimSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ScimSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimSchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ScimSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// ScimGroup is a group of users provisioned by an identity provider with SCIM. The members of the
+// group are members of the organizations that its display name is mapped to, see OrganizationGroupMapping.
+type ScimGroup struct {
+	Base
+	DisplayName string `gorm:"index"`
+	ExternalID  string
+}
+
+// ScimGroupMember is the membership of a user in a ScimGroup.
+type ScimGroupMember struct {
+	GroupID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID  string    `gorm:"primaryKey;index"`
+}
+
+// ScimMeta is the metadata of a SCIM resource.
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+// ScimUser is the SCIM representation of a user. The id of a user is the id it has in the tokens of
+// the identity provider, which is its externalId, prefixed like the ids of the users of the provider.
+type ScimUser struct {
+	Schemas    []string        `json:"schemas"`
+	ID         string          `json:"id,omitempty"`
+	ExternalID string          `json:"externalId,omitempty"`
+	UserName   string          `json:"userName"`
+	Active     *bool           `json:"active,omitempty"`
+	Groups     []ScimReference `json:"groups,omitempty"`
+	Meta       *ScimMeta       `json:"meta,omitempty"`
+}
+
+// ScimGroupResource is the SCIM representation of a group.
+type ScimGroupResource struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	ExternalID  string          `json:"externalId,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Members     []ScimReference `json:"members,omitempty"`
+	Meta        *ScimMeta       `json:"meta,omitempty"`
+}
+
+// ScimReference refers to a user from a group, or to a group from a user.
+type ScimReference struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// ScimListResponse is a page of the resources matching a query.
+type ScimListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int64       `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// ScimPatchRequest modifies a resource with a list of operations.
+type ScimPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []ScimPatchOperation `json:"Operations"`
+}
+
+// ScimPatchOperation adds, removes or replaces the values of the attribute at the path, or the
+// attributes of the value when there is no path.
+type ScimPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ScimError is the body of the SCIM error responses.
+type ScimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func NewScimError(status int, scimType string, detail string) ScimError {
+	return ScimError{
+		Schemas:  []string{ScimSchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
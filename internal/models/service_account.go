@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ServiceAccount is a non-human identity owned by an organization, used by automation
+// such as CI pipelines.  The service account acts as a user whose ID is the service account ID.
+type ServiceAccount struct {
+	Base
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Name           string    `json:"name" example:"terraform"`
+	Description    string    `json:"description"`
+}
+
+// AddServiceAccount is the information needed to add a new service account.
+type AddServiceAccount struct {
+	Name        string `json:"name" example:"terraform"`
+	Description string `json:"description" example:"provisions the staging environment"`
+}
+
+// ApiToken is a long-lived bearer token issued to a service account.
+type ApiToken struct {
+	Base
+	ServiceAccountID uuid.UUID `json:"service_account_id"`
+	Description      string    `json:"description"`
+	// BearerToken is only returned when the token is created, only a hash of it is stored.
+	BearerToken string `json:"bearer_token,omitempty" gorm:"-"`
+	TokenHash   string `json:"-" gorm:"uniqueIndex"`
+	// Scopes limits what the token can be used for, for example read:devices.
+	Scopes     pq.StringArray `json:"scopes" gorm:"type:text[]" swaggertype:"array,string"`
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`
+}
+
+// IsExpired returns true if the token has an expiry that has passed.
+func (t ApiToken) IsExpired() bool {
+	return t.ExpiresAt != nil && t.ExpiresAt.Before(time.Now())
+}
+
+// AddApiToken is the information needed to issue a new api token.
+type AddApiToken struct {
+	Description string     `json:"description" example:"ci pipeline"`
+	Scopes      []string   `json:"scopes" example:"read:devices"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
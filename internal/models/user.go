@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
@@ -20,17 +21,25 @@ type User struct {
 	UserName        string
 	Invitations     []*Invitation `json:"-"`
 	SecurityGroupId uuid.UUID     `json:"security_group_id"`
+	Revision        uint64        `json:"revision" gorm:"type:bigserial;index:"`
+	// Groups are the groups of the user when their group memberships were last synced, at GroupsSyncedAt.
+	Groups         pq.StringArray `json:"-" gorm:"type:text[]"`
+	GroupsSyncedAt *time.Time     `json:"-"`
+	// Deactivated users can't use the api, they are deactivated by the identity provider with SCIM.
+	Deactivated bool `json:"-"`
 }
 
 type UserJSON struct {
 	ID       string `json:"id" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
 	UserName string `json:"username" example:"admin"`
+	Revision uint64 `json:"revision"`
 }
 
 func (u User) MarshalJSON() ([]byte, error) {
 	user := UserJSON{
 		ID:       u.ID,
 		UserName: u.UserName,
+		Revision: u.Revision,
 	}
 	return json.Marshal(user)
 }
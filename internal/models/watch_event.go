@@ -1,7 +1,23 @@
 package models
 
+import "time"
+
 // WatchEvent struct for WatchEvent
 type WatchEvent struct {
 	Type  string      `json:"type"`
 	Value interface{} `json:"value,omitempty"`
 }
+
+// WatchBookmark is the value of a bookmark event, it tells the client that it has been
+// sent all the changes up to Revision.
+type WatchBookmark struct {
+	Revision uint64 `json:"revision"`
+}
+
+// CompactedRevision records the highest revision of the soft deleted rows that have been
+// purged from a watchable table. Watches can not be resumed from an older revision.
+type CompactedRevision struct {
+	Name      string `gorm:"primary_key"`
+	Revision  uint64
+	UpdatedAt time.Time
+}
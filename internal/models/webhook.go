@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Webhook events
+const (
+	WebhookEventDeviceJoined         = "device.joined"
+	WebhookEventDeviceLeft           = "device.left"
+	WebhookEventRelayDown            = "relay.down"
+	WebhookEventSecurityGroupChanged = "security_group.changed"
+	WebhookEventInvitationAccepted   = "invitation.accepted"
+)
+
+// WebhookEvents are the events a webhook can subscribe to.
+var WebhookEvents = []string{
+	WebhookEventDeviceJoined,
+	WebhookEventDeviceLeft,
+	WebhookEventRelayDown,
+	WebhookEventSecurityGroupChanged,
+	WebhookEventInvitationAccepted,
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook is a subscription to the events of an organization, delivered as HTTP POSTs to URL.
+type Webhook struct {
+	Base
+	OrganizationID uuid.UUID `json:"organization_id"`
+	URL            string    `json:"url" example:"https://example.com/hooks/nexodus"`
+	Description    string    `json:"description"`
+	// Events the webhook is subscribed to, all events when empty.
+	Events pq.StringArray `json:"events" gorm:"type:text[]" swaggertype:"array,string"`
+	// Secret is used to sign the payloads, it is only returned when the webhook is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+// Subscribed returns true if the webhook is subscribed to the event.
+func (w Webhook) Subscribed(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// AddWebhook is the information needed to add a new webhook.
+type AddWebhook struct {
+	URL         string   `json:"url" example:"https://example.com/hooks/nexodus"`
+	Description string   `json:"description" example:"cmdb sync"`
+	Events      []string `json:"events" example:"device.joined"`
+}
+
+// WebhookDelivery is an attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	Base
+	WebhookID     uuid.UUID `json:"webhook_id" gorm:"type:uuid;index"`
+	Event         string    `json:"event" example:"device.joined"`
+	Payload       string    `json:"payload"`
+	Status        string    `json:"status" example:"delivered"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at" gorm:"index"`
+	// ResponseCode is the HTTP status of the last attempt.
+	ResponseCode int `json:"response_code"`
+	// LastError describes why the last attempt failed.
+	LastError   string     `json:"last_error,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookPayload is the body POSTed to a webhook.
+type WebhookPayload struct {
+	ID             uuid.UUID   `json:"id"`
+	Event          string      `json:"event"`
+	OrganizationID uuid.UUID   `json:"organization_id"`
+	CreatedAt      time.Time   `json:"created_at"`
+	Data           interface{} `json:"data"`
+}
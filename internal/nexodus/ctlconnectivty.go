@@ -16,7 +16,12 @@ const (
 
 // ConnectivityV4 pings all peers via IPv4
 func (ac *NexdCtl) ConnectivityV4(_ string, keepaliveResults *string) error {
-	res := ac.ax.connectivityProbe(v4)
+	res := make(map[string]KeepaliveStatus)
+	for _, nx := range ac.ax.agents() {
+		for key, status := range nx.connectivityProbe(v4) {
+			res[key] = status
+		}
+	}
 	var err error
 
 	// Marshal the map into a JSON string.
@@ -32,7 +37,12 @@ func (ac *NexdCtl) ConnectivityV4(_ string, keepaliveResults *string) error {
 
 // ConnectivityV6 pings all peers via IPv6
 func (ac *NexdCtl) ConnectivityV6(_ string, keepaliveResults *string) error {
-	res := ac.ax.connectivityProbe(v6)
+	res := make(map[string]KeepaliveStatus)
+	for _, nx := range ac.ax.agents() {
+		for key, status := range nx.connectivityProbe(v6) {
+			res[key] = status
+		}
+	}
 	var err error
 
 	// Marshal the map into a JSON string.
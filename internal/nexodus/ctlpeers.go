@@ -6,22 +6,28 @@ import (
 )
 
 func (ac *NexdCtl) ListPeers(_ string, result *string) error {
-	peers, err := ac.ax.DumpPeersDefault()
-	if err != nil {
-		return fmt.Errorf("error getting list of peers: %w", err)
-	}
-
-	ac.ax.deviceCacheIterRead(func(d deviceCacheEntry) {
-		if d.device.PublicKey == ac.ax.wireguardPubKey {
-			return
+	peers := map[string]WgSessions{}
+	for _, nx := range ac.ax.agents() {
+		orgPeers, err := nx.DumpPeersDefault()
+		if err != nil {
+			return fmt.Errorf("error getting list of peers: %w", err)
 		}
-		p, ok := peers[d.device.PublicKey]
-		if !ok {
-			return
+
+		nx.deviceCacheIterRead(func(d deviceCacheEntry) {
+			if d.device.PublicKey == nx.wireguardPubKey {
+				return
+			}
+			p, ok := orgPeers[d.device.PublicKey]
+			if !ok {
+				return
+			}
+			p.Healthy = d.peerHealthy
+			orgPeers[d.device.PublicKey] = p
+		})
+		for key, p := range orgPeers {
+			peers[key] = p
 		}
-		p.Healthy = d.peerHealthy
-		peers[d.device.PublicKey] = p
-	})
+	}
 
 	peersJSON, err := json.Marshal(peers)
 	if err != nil {
@@ -28,6 +28,15 @@ func (ac *NexdCtl) Status(_ string, result *string) error {
 	if len(ac.ax.statusMsg) > 0 {
 		res += ac.ax.statusMsg
 	}
+	if len(ac.ax.organizations) > 0 {
+		for _, nx := range ac.ax.agents() {
+			name := nx.orgId
+			if nx.org != nil {
+				name = fmt.Sprintf("%s (%s)", nx.org.Name, nx.org.Id)
+			}
+			res += fmt.Sprintf("Organization: %s Interface: %s IPv4: %s IPv6: %s\n", name, nx.tunnelIface, nx.TunnelIP, nx.TunnelIpV6)
+		}
+	}
 	*result = res
 	return nil
 }
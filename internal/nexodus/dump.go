@@ -24,7 +24,7 @@ type WgSessions struct {
 }
 
 func (nx *Nexodus) DumpPeersDefault() (map[string]WgSessions, error) {
-	return nx.DumpPeers(nx.defaultTunnelDev())
+	return nx.DumpPeers(nx.tunnelIface)
 }
 
 func (nx *Nexodus) DumpPeers(iface string) (map[string]WgSessions, error) {
@@ -2,6 +2,7 @@ package nexodus
 
 import (
 	"fmt"
+	"github.com/nexodus-io/nexodus/internal/state"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 	"os"
 	"runtime"
@@ -15,9 +16,16 @@ func (nx *Nexodus) handleKeys() error {
 	if err != nil {
 		return err
 	}
-	state := nx.stateStore.State()
+	current := nx.stateStore.State()
 
-	if state.PublicKey != "" && state.PrivateKey != "" {
+	// the devices of the additional organizations have their own key pairs
+	publicKey, privateKey := current.PublicKey, current.PrivateKey
+	if nx.organizationIndex > 0 {
+		keys := current.Organizations[nx.orgId]
+		publicKey, privateKey = keys.PublicKey, keys.PrivateKey
+	}
+
+	if publicKey != "" && privateKey != "" {
 		nx.logger.Infof("Existing key pair found in [ %s ]", nx.stateStore)
 	} else {
 		nx.logger.Infof("No existing public/private key pair found, generating a new pair")
@@ -25,8 +33,17 @@ func (nx *Nexodus) handleKeys() error {
 		if err != nil {
 			return fmt.Errorf("failed to generate private key: %w", err)
 		}
-		state.PublicKey = wgKey.PublicKey().String()
-		state.PrivateKey = wgKey.String()
+		publicKey = wgKey.PublicKey().String()
+		privateKey = wgKey.String()
+		if nx.organizationIndex > 0 {
+			if current.Organizations == nil {
+				current.Organizations = map[string]state.OrganizationState{}
+			}
+			current.Organizations[nx.orgId] = state.OrganizationState{PublicKey: publicKey, PrivateKey: privateKey}
+		} else {
+			current.PublicKey = publicKey
+			current.PrivateKey = privateKey
+		}
 
 		err = nx.stateStore.Store()
 		if err != nil {
@@ -35,8 +52,8 @@ func (nx *Nexodus) handleKeys() error {
 		nx.logger.Debugf("New keys were written to [ %s ]", nx.stateStore)
 	}
 
-	nx.wireguardPubKey = state.PublicKey
-	nx.wireguardPvtKey = state.PrivateKey
+	nx.wireguardPubKey = publicKey
+	nx.wireguardPvtKey = privateKey
 	return nil
 
 }
@@ -0,0 +1,54 @@
+package nexodus
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nexodus-io/nexodus/internal/state/fstore"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHandleKeysWithSeveralOrganizations(t *testing.T) {
+	require := require.New(t)
+	file := filepath.Join(t.TempDir(), "state.json")
+
+	agents := func() []*Nexodus {
+		store := fstore.New(file)
+		var agents []*Nexodus
+		for i, orgId := range []string{"org-a", "org-b", "org-c"} {
+			agents = append(agents, &Nexodus{
+				logger:            zap.NewNop().Sugar(),
+				stateStore:        store,
+				orgId:             orgId,
+				organizationIndex: i,
+			})
+		}
+		return agents
+	}
+
+	// each organization gets its own key pair.
+	created := agents()
+	seen := map[string]bool{}
+	for _, nx := range created {
+		require.NoError(nx.handleKeys())
+		require.NotEmpty(nx.wireguardPubKey)
+		require.NotEmpty(nx.wireguardPvtKey)
+		require.False(seen[nx.wireguardPubKey])
+		seen[nx.wireguardPubKey] = true
+	}
+
+	// the first organization uses the keys of a single organization nexd, the others are keyed by organization id.
+	current := created[0].stateStore.State()
+	require.Equal(created[0].wireguardPubKey, current.PublicKey)
+	require.Len(current.Organizations, 2)
+	require.Equal(created[1].wireguardPubKey, current.Organizations["org-b"].PublicKey)
+	require.Equal(created[2].wireguardPvtKey, current.Organizations["org-c"].PrivateKey)
+
+	// the keys are loaded again when nexd restarts.
+	for i, nx := range agents() {
+		require.NoError(nx.handleKeys())
+		require.Equal(created[i].wireguardPubKey, nx.wireguardPubKey)
+		require.Equal(created[i].wireguardPvtKey, nx.wireguardPvtKey)
+	}
+}
@@ -16,10 +16,12 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/api/public"
@@ -47,6 +49,11 @@ const (
 	wgOrgIPv6PrefixLen = "64"
 )
 
+// heartbeatInterval is how often a relay or an ephemeral device reports to the api-server that it is
+// up. The relays that stop reporting are reported down to the webhooks of the organization, and the
+// ephemeral devices that stop reporting are deleted.
+const heartbeatInterval = 30 * time.Second
+
 const (
 	// when nexd is first starting up
 	NexdStatusStarting = iota
@@ -152,13 +159,38 @@ type Nexodus struct {
 	version       string
 	username      string
 	password      string
+	authKey       string
 	skipTlsVerify bool
 	stateStore    state.Store
 	userspaceWG
 	informer     *public.ApiListDevicesInOrganizationInformer
+	informerCtx  context.Context
 	informerStop context.CancelFunc
-	nexCtx       context.Context
-	nexWg        *sync.WaitGroup
+	// peeredDevicesInformer watches the devices of other organizations that this device can reach
+	// through the peerings of its organization, it is started once the device is registered.
+	peeredDevicesInformer *public.ApiListPeeredDevicesInformer
+	// securityGroupsInformer watches the security groups of the organization
+	securityGroupsInformer *public.ApiListSecurityGroupsInformer
+	// identityProvider is the name of the identity provider that the agent logs in with,
+	// the default provider of the api server when empty.
+	identityProvider string
+	// grpcSyncURL is the url of the gRPC DeviceSync service, when set the informers use it
+	// instead of the http watch apis.
+	grpcSyncURL string
+	deviceSync  *client.DeviceSyncClient
+	nexCtx      context.Context
+	nexWg       *sync.WaitGroup
+	// device is the device registered for this agent.
+	device *public.ModelsDevice
+	// peeredDevices are the devices of other organizations that this device can reach through
+	// the peerings of its organization, by public key.
+	peeredDevices map[string]public.ModelsDevice
+	// organizations are the agents of the additional organizations this nexd joins, each of them
+	// has its own tunnel interface, keys, address, security group and peers.
+	organizations []*Nexodus
+	// organizationIndex is the position of the organization in the requested organization ids,
+	// the agents of the additional organizations have an index greater than 0.
+	organizationIndex int
 }
 
 type wgConfig struct {
@@ -184,6 +216,8 @@ func NewNexodus(
 	apiURL *url.URL,
 	username string,
 	password string,
+	authKey string,
+	identityProvider string,
 	wgListenPort int,
 	requestedIP string,
 	userProvidedLocalIP string,
@@ -199,7 +233,8 @@ func NewNexodus(
 	stateStore state.Store,
 	stateDir string,
 	ctx context.Context,
-	orgId string,
+	orgIds []string,
+	grpcSyncURL string,
 ) (*Nexodus, error) {
 
 	if err := binaryChecks(); err != nil {
@@ -211,6 +246,15 @@ func NewNexodus(
 		return nil, err
 	}
 
+	if err := checkMultipleOrganizations(orgIds, relay, networkRouterNode, requestedIP); err != nil {
+		return nil, err
+	}
+	orgId := ""
+	if len(orgIds) > 0 {
+		orgId = orgIds[0]
+	}
+
+	requestedListenPort := wgListenPort
 	if wgListenPort == 0 {
 		wgListenPort, err = getWgListenPort()
 		if err != nil {
@@ -237,9 +281,12 @@ func NewNexodus(
 		version:                 version,
 		username:                username,
 		password:                password,
+		authKey:                 authKey,
+		identityProvider:        identityProvider,
 		skipTlsVerify:           insecureSkipTlsVerify,
 		stateStore:              stateStore,
 		orgId:                   orgId,
+		grpcSyncURL:             grpcSyncURL,
 		userspaceWG: userspaceWG{
 			proxies: map[ProxyKey]*UsProxy{},
 		},
@@ -271,9 +318,96 @@ func NewNexodus(
 		return nil, err
 	}
 
+	for i := 1; i < len(orgIds); i++ {
+		org, err := ax.newOrganizationAgent(ctx, orgIds[i], i, requestedListenPort)
+		if err != nil {
+			return nil, err
+		}
+		ax.organizations = append(ax.organizations, org)
+	}
+
 	return ax, nil
 }
 
+// agents returns the agent of each organization nexd joins, starting with nx.
+func (nx *Nexodus) agents() []*Nexodus {
+	return append([]*Nexodus{nx}, nx.organizations...)
+}
+
+// checkMultipleOrganizations checks that the configuration can be used to join several organizations.
+func checkMultipleOrganizations(orgIds []string, relay bool, networkRouter bool, requestedIP string) error {
+	if len(orgIds) < 2 {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, id := range orgIds {
+		if seen[id] {
+			return fmt.Errorf("organization %s was requested more than once", id)
+		}
+		seen[id] = true
+	}
+	switch {
+	case runtime.GOOS == Windows.String():
+		return fmt.Errorf("joining multiple organizations is not supported on %s", runtime.GOOS)
+	case relay:
+		return fmt.Errorf("a relay node can only join a single organization")
+	case networkRouter:
+		return fmt.Errorf("a network router node can only join a single organization")
+	case requestedIP != "":
+		return fmt.Errorf("--request-ip can only be used when joining a single organization")
+	}
+	return nil
+}
+
+// newOrganizationAgent returns the agent of an additional organization, configured like nx except for
+// the tunnel interface and listen port of the organization.
+func (nx *Nexodus) newOrganizationAgent(ctx context.Context, orgId string, index int, requestedListenPort int) (*Nexodus, error) {
+	listenPort := requestedListenPort + index
+	if requestedListenPort == 0 {
+		var err error
+		if listenPort, err = getWgListenPort(); err != nil {
+			return nil, err
+		}
+	}
+	org := &Nexodus{
+		listenPort:          listenPort,
+		userProvidedLocalIP: nx.userProvidedLocalIP,
+		childPrefix:         nx.childPrefix,
+		stun:                nx.stun,
+		symmetricNat:        nx.symmetricNat,
+		deviceCache:         make(map[string]deviceCacheEntry),
+		apiURL:              nx.apiURL,
+		hostname:            nx.hostname,
+		ipv6Supported:       nx.ipv6Supported,
+		logger:              nx.logger.With("organization", orgId),
+		logLevel:            nx.logLevel,
+		status:              NexdStatusStarting,
+		version:             nx.version,
+		username:            nx.username,
+		password:            nx.password,
+		authKey:             nx.authKey,
+		identityProvider:    nx.identityProvider,
+		skipTlsVerify:       nx.skipTlsVerify,
+		stateStore:          nx.stateStore,
+		orgId:               orgId,
+		grpcSyncURL:         nx.grpcSyncURL,
+		organizationIndex:   index,
+		userspaceWG: userspaceWG{
+			userspaceMode: nx.userspaceMode,
+			proxies:       map[ProxyKey]*UsProxy{},
+		},
+	}
+	org.tunnelIface = org.tunnelDev(index)
+
+	// remove orphaned wg interfaces from previous node joins
+	org.removeExistingInterface()
+
+	if err := org.symmetricNatDisco(ctx); err != nil {
+		org.logger.Warn(err)
+	}
+	return org, nil
+}
+
 func (nx *Nexodus) SetStatus(status int, msg string) {
 	nx.statusMsg = msg
 	nx.status = status
@@ -370,7 +504,12 @@ func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 	if nx.stateStore != nil {
 		options = append(options, client.WithTokenStore(StateTokenStore{store: nx.stateStore}))
 	}
-	if nx.username == "" {
+	if nx.identityProvider != "" {
+		options = append(options, client.WithIdentityProvider(nx.identityProvider))
+	}
+	if nx.authKey != "" {
+		options = append(options, client.WithBearerToken(nx.authKey))
+	} else if nx.username == "" {
 		options = append(options, client.WithDeviceFlow())
 	} else if nx.username != "" && nx.password == "" {
 		fmt.Print("Enter nexodus account password: ")
@@ -453,14 +592,38 @@ func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 		return fmt.Errorf("get organizations error: %w", err)
 	}
 
+	if err := nx.join(ctx, wg, user, organizations, options); err != nil {
+		return err
+	}
+
+	for _, org := range nx.organizations {
+		org.nexCtx = ctx
+		org.nexWg = wg
+		org.client = nx.client
+		org.SetStatus(NexdStatusRunning, "")
+		if err := org.handleKeys(); err != nil {
+			return fmt.Errorf("handleKeys: %w", err)
+		}
+		if err := org.join(ctx, wg, user, organizations, options); err != nil {
+			return fmt.Errorf("failed to join organization %s: %w", org.orgId, err)
+		}
+	}
+
+	return nil
+}
+
+// join registers the device in the organization, and then keeps its tunnel interface configured
+// with the peers and the security group of the organization.
+func (nx *Nexodus) join(ctx context.Context, wg *sync.WaitGroup, user *public.ModelsUser, organizations []public.ModelsOrganization, options []client.Option) error {
+	var err error
 	nx.org, err = nx.chooseOrganization(organizations, *user)
 	if err != nil {
 		return fmt.Errorf("failed to choose an organization: %w", err)
 	}
 
-	informerCtx, informerCancel := context.WithCancel(ctx)
-	nx.informerStop = informerCancel
-	nx.informer = nx.client.DevicesApi.ListDevicesInOrganization(informerCtx, nx.org.Id).Informer()
+	if err := nx.startInformers(ctx); err != nil {
+		return err
+	}
 
 	var localIP string
 	var localEndpointPort int
@@ -545,6 +708,8 @@ func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 	if err != nil {
 		return fmt.Errorf("join error %w", err)
 	}
+	nx.device = &modelsDevice
+	nx.startPeeredDevicesInformer()
 	nx.logger.Debug(fmt.Sprintf("Device: %+v", modelsDevice))
 	nx.logger.Infof("%s with UUID: [ %+v ] into organization: [ %s (%s) ]",
 		deviceOperationLogMsg, modelsDevice.Id, nx.org.Name, nx.org.Id)
@@ -554,23 +719,30 @@ func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 		if err := nx.enableForwardingIP(); err != nil {
 			return err
 		}
-		if err := nfRelayTablesSetup(wgIface); err != nil {
+		if err := nfRelayTablesSetup(nx.tunnelIface); err != nil {
 			return err
 		}
 	}
 
 	util.GoWithWaitGroup(wg, func() {
 		// kick it off with an immediate reconcile
+		nx.reconcilePeeredDevices()
 		nx.reconcileDevices(ctx, options)
 		nx.reconcileSecurityGroups(ctx)
 		for _, proxy := range nx.proxies {
 			proxy.Start(ctx, wg, nx.userspaceNet)
 		}
 		stunTicker := time.NewTicker(time.Second * 20)
-		secGroupTicker := time.NewTicker(time.Second * 20)
 		defer stunTicker.Stop()
 		pollTicker := time.NewTicker(pollInterval)
 		defer pollTicker.Stop()
+		var heartbeat <-chan time.Time
+		if nx.relay || modelsDevice.Ephemeral {
+			nx.sendHeartbeat(ctx, modelsDevice.Id)
+			heartbeatTicker := time.NewTicker(heartbeatInterval)
+			defer heartbeatTicker.Stop()
+			heartbeat = heartbeatTicker.C
+		}
 		for {
 			select {
 			case <-ctx.Done():
@@ -583,12 +755,22 @@ func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 				}
 			case <-nx.informer.Changed():
 				nx.reconcileDevices(ctx, options)
+				// the security group of the local device may have changed
+				nx.reconcileSecurityGroups(ctx)
+			case <-nx.securityGroupsInformer.Changed():
+				nx.reconcileSecurityGroups(ctx)
+			case <-heartbeat:
+				nx.sendHeartbeat(ctx, modelsDevice.Id)
+			case <-nx.peeredDevicesInformer.Changed():
+				if nx.reconcilePeeredDevices() {
+					nx.reconcileDevices(ctx, options)
+				}
 			case <-pollTicker.C:
-				// This does not actually poll the API for changes. Peer configuration changes will only
-				// be processed when they come in on the informer. This periodic check is needed to
-				// re-establish our connection to the API if it is lost.
+				// This does not actually poll the API for changes. Peer configuration and security group
+				// changes will only be processed when they come in on the informers. This periodic check
+				// is needed to re-establish our connection to the API if it is lost.
+				nx.reconcilePeeredDevices()
 				nx.reconcileDevices(ctx, options)
-			case <-secGroupTicker.C:
 				nx.reconcileSecurityGroups(ctx)
 			}
 		}
@@ -598,10 +780,22 @@ func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 }
 
 func (nx *Nexodus) Stop() {
-	nx.logger.Info("Stopping nexd")
+	if nx.organizationIndex == 0 {
+		nx.logger.Info("Stopping nexd")
+	}
+	for _, org := range nx.organizations {
+		org.Stop()
+	}
 	for _, proxy := range nx.proxies {
 		proxy.Stop()
 	}
+	// devices registered with an ephemeral registration key do not outlive the agent
+	if nx.device != nil && nx.device.Ephemeral {
+		nx.logger.Infof("Removing ephemeral device %s", nx.device.Id)
+		if _, _, err := nx.client.DevicesApi.DeleteDevice(context.Background(), nx.device.Id).Execute(); err != nil {
+			nx.logger.Warnf("failed to remove ephemeral device: %v", err)
+		}
+	}
 }
 
 // reconcileSecurityGroups will check the security group and update it if necessary.
@@ -631,19 +825,24 @@ func (nx *Nexodus) reconcileSecurityGroups(ctx context.Context) {
 	}
 
 	// if the security group ID is not nil, lookup the ID and check for any changes
-	responseSecGroup, httpResp, err := nx.client.SecurityGroupApi.GetSecurityGroup(ctx, nx.org.Id, existing.device.SecurityGroupId).Execute()
+	securityGroups, _, err := nx.securityGroupsInformer.Execute()
 	if err != nil {
-		// if the group ID returns a 404, clear the current rules
-		if httpResp != nil && httpResp.StatusCode == http.StatusNotFound {
-			nx.securityGroup = nil
-			if err := nx.processSecurityGroupRules(); err != nil {
-				nx.logger.Error(err)
-			}
+		nx.logger.Errorf("Error retrieving the security group: %v", err)
+		return
+	}
+	securityGroup, ok := securityGroups[existing.device.SecurityGroupId]
+	if !ok {
+		// if the group no longer exists, clear the current rules
+		if nx.securityGroup == nil {
 			return
 		}
-		nx.logger.Errorf("Error retrieving the security group: %v", err)
+		nx.securityGroup = nil
+		if err := nx.processSecurityGroupRules(); err != nil {
+			nx.logger.Error(err)
+		}
 		return
 	}
+	responseSecGroup := &securityGroup
 
 	if nx.securityGroup != nil && reflect.DeepEqual(responseSecGroup, nx.securityGroup) {
 		// no changes to previously applied security group
@@ -688,7 +887,7 @@ func (nx *Nexodus) reconcileDevices(ctx context.Context, options []client.Option
 	}
 
 	// token grant has become invalid, if we are using a one-time auth token, exit
-	if nx.username == "" {
+	if nx.username == "" && nx.authKey == "" {
 		nx.logger.Fatalf("The token grant has expired due to an extended period offline, please " +
 			"restart the agent for a one-time auth or login with --username --password to automatically reconnect")
 		return
@@ -710,14 +909,22 @@ func (nx *Nexodus) reconcileDevices(ctx context.Context, options []client.Option
 	}
 
 	nx.client = c
-	informerCtx, informerCancel := context.WithCancel(ctx)
-	nx.informerStop = informerCancel
-	nx.informer = nx.client.DevicesApi.ListDevicesInOrganization(informerCtx, nx.org.Id).Informer()
+	if err := nx.startInformers(ctx); err != nil {
+		nx.logger.Errorf("Failed to restart the informers, retrying in %v seconds: %v", pollInterval, err)
+		return
+	}
 
 	nx.SetStatus(NexdStatusRunning, "")
 	nx.logger.Infoln("Nexodus agent has re-established a connection to the api-server")
 }
 
+// sendHeartbeat reports to the api-server that the device is up.
+func (nx *Nexodus) sendHeartbeat(ctx context.Context, deviceID string) {
+	if _, err := nx.client.DevicesApi.DeviceHeartbeat(ctx, deviceID).Execute(); err != nil {
+		nx.logger.Debugf("failed to send the heartbeat: %v", err)
+	}
+}
+
 func (nx *Nexodus) reconcileStun(deviceID string) error {
 	if nx.symmetricNat {
 		return nil
@@ -762,6 +969,48 @@ func (nx *Nexodus) reconcileStun(deviceID string) error {
 	return nil
 }
 
+// startInformers starts the informers that watch the devices and security groups of the organization,
+// using the gRPC DeviceSync service when a --grpc-sync-url was configured.
+func (nx *Nexodus) startInformers(ctx context.Context) error {
+	informerCtx, informerCancel := context.WithCancel(ctx)
+	nx.informerCtx = informerCtx
+	nx.informerStop = informerCancel
+	if nx.grpcSyncURL == "" {
+		nx.informer = nx.client.DevicesApi.ListDevicesInOrganization(informerCtx, nx.org.Id).Informer()
+		nx.securityGroupsInformer = nx.client.SecurityGroupApi.ListSecurityGroups(informerCtx, nx.org.Id).Informer()
+		nx.startPeeredDevicesInformer()
+		return nil
+	}
+
+	// the sync client sends the token of the current api client, so reconnect when the api client changes.
+	if nx.deviceSync != nil {
+		util.IgnoreError(nx.deviceSync.Close)
+	}
+	var err error
+	nx.deviceSync, err = client.NewDeviceSyncClient(nx.client, nx.grpcSyncURL, &tls.Config{
+		InsecureSkipVerify: nx.skipTlsVerify, // #nosec G402
+	})
+	if err != nil {
+		informerCancel()
+		nx.informerStop = nil
+		return fmt.Errorf("failed to connect to the device sync service: %w", err)
+	}
+	nx.informer = nx.deviceSync.DevicesInformer(informerCtx, nx.org.Id)
+	nx.securityGroupsInformer = nx.deviceSync.SecurityGroupsInformer(informerCtx, nx.org.Id)
+	// the DeviceSync service doesn't sync the peered devices, they are watched with the http api.
+	nx.startPeeredDevicesInformer()
+	return nil
+}
+
+// startPeeredDevicesInformer starts the informer that watches the peered devices, the devices
+// can only be watched once this device is registered.
+func (nx *Nexodus) startPeeredDevicesInformer() {
+	if nx.device == nil || nx.informerCtx == nil {
+		return
+	}
+	nx.peeredDevicesInformer = nx.client.DevicesApi.ListPeeredDevices(nx.informerCtx, nx.device.Id).Informer()
+}
+
 func (nx *Nexodus) chooseOrganization(organizations []public.ModelsOrganization, user public.ModelsUser) (*public.ModelsOrganization, error) {
 	if len(organizations) == 0 {
 		return nil, fmt.Errorf("user does not belong to any organizations")
@@ -894,7 +1143,7 @@ func (nx *Nexodus) addToDeviceCache(p public.ModelsDevice) {
 }
 
 func (nx *Nexodus) reconcileDeviceCache() error {
-	peerMap, resp, err := nx.informer.Execute()
+	devices, resp, err := nx.informer.Execute()
 	if err != nil {
 		if resp != nil {
 			return fmt.Errorf("error: %w header: %v", err, resp.Header)
@@ -902,6 +1151,15 @@ func (nx *Nexodus) reconcileDeviceCache() error {
 		return fmt.Errorf("error: %w", err)
 	}
 
+	// the devices of peered organizations are peers too, copy the map since the informer owns it.
+	peerMap := make(map[string]public.ModelsDevice, len(devices)+len(nx.peeredDevices))
+	for key, device := range nx.peeredDevices {
+		peerMap[key] = device
+	}
+	for key, device := range devices {
+		peerMap[key] = device
+	}
+
 	// Get the current peer configuration data from the wireguard interface
 	peerStats, err := nx.DumpPeersDefault()
 	if err != nil {
@@ -992,6 +1250,25 @@ func (nx *Nexodus) reconcileDeviceCache() error {
 	return nil
 }
 
+// reconcilePeeredDevices updates the devices of peered organizations that this device can reach
+// from the peered devices informer, it returns true if they changed.
+func (nx *Nexodus) reconcilePeeredDevices() bool {
+	if nx.peeredDevicesInformer == nil {
+		return false
+	}
+	peeredDevices, _, err := nx.peeredDevicesInformer.Execute()
+	if err != nil {
+		// keep the previous peers, the watch is restarted on the next poll.
+		nx.logger.Debugf("failed to watch the peered devices: %v", err)
+		return false
+	}
+	if reflect.DeepEqual(peeredDevices, nx.peeredDevices) {
+		return false
+	}
+	nx.peeredDevices = peeredDevices
+	return true
+}
+
 func (nx *Nexodus) isEqualIgnoreSecurityGroup(p1, p2 public.ModelsDevice) bool {
 	// create temporary copies of the instances
 	tmpDev1 := p1
@@ -1087,3 +1364,15 @@ func (nx *Nexodus) defaultTunnelDev() string {
 	}
 	return defaultTunnelDevOS()
 }
+
+// tunnelDev returns the name of the tunnel interface of the organization at the index, the default
+// device name with its number increased by the index, for example wg1 or utun9.
+func (nx *Nexodus) tunnelDev(index int) string {
+	dev := nx.defaultTunnelDev()
+	if index == 0 {
+		return dev
+	}
+	prefix := strings.TrimRightFunc(dev, unicode.IsDigit)
+	n, _ := strconv.Atoi(dev[len(prefix):])
+	return fmt.Sprintf("%s%d", prefix, n+index)
+}
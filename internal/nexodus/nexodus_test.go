@@ -0,0 +1,43 @@
+package nexodus
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMultipleOrganizations(t *testing.T) {
+	require := require.New(t)
+
+	// a single organization can be joined with any of the options.
+	require.NoError(checkMultipleOrganizations(nil, true, true, "100.64.0.10"))
+	require.NoError(checkMultipleOrganizations([]string{"org-a"}, true, true, "100.64.0.10"))
+
+	require.ErrorContains(checkMultipleOrganizations([]string{"org-a", "org-b", "org-a"}, false, false, ""), "more than once")
+
+	if runtime.GOOS == Windows.String() {
+		require.ErrorContains(checkMultipleOrganizations([]string{"org-a", "org-b"}, false, false, ""), "not supported")
+		return
+	}
+	require.NoError(checkMultipleOrganizations([]string{"org-a", "org-b"}, false, false, ""))
+	require.ErrorContains(checkMultipleOrganizations([]string{"org-a", "org-b"}, true, false, ""), "relay")
+	require.ErrorContains(checkMultipleOrganizations([]string{"org-a", "org-b"}, false, true, ""), "network router")
+	require.ErrorContains(checkMultipleOrganizations([]string{"org-a", "org-b"}, false, false, "100.64.0.10"), "--request-ip")
+}
+
+func TestTunnelDev(t *testing.T) {
+	require := require.New(t)
+
+	nx := &Nexodus{}
+	switch runtime.GOOS {
+	case Darwin.String():
+		require.Equal([]string{"utun8", "utun9", "utun10"}, []string{nx.tunnelDev(0), nx.tunnelDev(1), nx.tunnelDev(2)})
+	default:
+		require.Equal([]string{"wg0", "wg1", "wg2"}, []string{nx.tunnelDev(0), nx.tunnelDev(1), nx.tunnelDev(2)})
+	}
+
+	// the userspace device name has no number.
+	nx.userspaceMode = true
+	require.Equal([]string{"go", "go1", "go2"}, []string{nx.tunnelDev(0), nx.tunnelDev(1), nx.tunnelDev(2)})
+}
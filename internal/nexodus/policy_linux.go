@@ -44,9 +44,19 @@ const (
 	priorityFilter   = "filter"
 )
 
-var (
-	ruleInterface string
-)
+// sgTableName is the nftables table of the security group rules of the tunnel interface, the
+// organizations nexd joins after the first one have a table for each of their interfaces.
+func (nx *Nexodus) sgTableName() string {
+	if nx.organizationIndex == 0 {
+		return sgTableName
+	}
+	return sgTableName + "-" + nx.tunnelIface
+}
+
+// ruleInterface is the nftables interface match of the security group rules.
+func (nx *Nexodus) ruleInterface() string {
+	return fmt.Sprintf("iifname %s", nx.tunnelIface)
+}
 
 // processSecurityGroupRules processes a security group for a Linux node
 func (nx *Nexodus) processSecurityGroupRules() error {
@@ -54,12 +64,10 @@ func (nx *Nexodus) processSecurityGroupRules() error {
 	// Delete the table if the security group is empty and attempt to drop a table if one exists
 	if nx.securityGroup == nil {
 		// Drop the existing table and return nil if a group was not found to drop
-		_ = nx.nfTableDrop(sgTableName)
+		_ = nx.nfTableDrop(nx.sgTableName())
 		return nil
 	}
 
-	ruleInterface = fmt.Sprintf("iifname %s", wgIface)
-
 	inboundRules := nx.securityGroup.InboundRules
 	outboundRules := nx.securityGroup.OutboundRules
 
@@ -72,12 +80,12 @@ func (nx *Nexodus) processSecurityGroupRules() error {
 	}
 
 	// Drop the existing table
-	if err := nx.nfTableDrop(sgTableName); err != nil {
+	if err := nx.nfTableDrop(nx.sgTableName()); err != nil {
 		return fmt.Errorf("nftables setup error, failed to flush nftables: %w", err)
 	}
 
 	// Create the nftables table
-	if err := nx.nfCreateTable(sgTableName); err != nil {
+	if err := nx.nfCreateTable(nx.sgTableName()); err != nil {
 		return fmt.Errorf("nftables setup error, failed to create nftables inet table: %w", err)
 	}
 
@@ -151,7 +159,7 @@ func (nx *Nexodus) processSecurityGroupRules() error {
 	// connections. The state keyword is used to match traffic based on its connection state, in this case as
 	// established. The established state refers to traffic that is part of an existing connection that has
 	// already been established, and where both endpoints have exchanged packets.
-	nft := []string{"insert", "rule", tableFamily, sgTableName, ingressChain, "ct", "state", "established,related", ruleInterface, "counter", "accept"}
+	nft := []string{"insert", "rule", tableFamily, nx.sgTableName(), ingressChain, "ct", "state", "established,related", nx.ruleInterface(), "counter", "accept"}
 	if _, err := runNftCmd(nx.logger, nft); err != nil {
 		return err
 	}
@@ -196,7 +204,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
 				// v4 permits for L3 src or dst
-				nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, ruleInterface, counter, actionAccept}
+				nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv4, srcOrDstOption, nx.ruleInterface(), counter, actionAccept}
 				if _, err := runNftCmd(nx.logger, nft); err != nil {
 					return err
 				}
@@ -207,7 +215,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 		if rule.FromPort == 0 && rule.ToPort == 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoTCP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoTCP, destPort, "0-65535", nx.ruleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(nx.logger, nft); err != nil {
 					return err
 				}
@@ -217,7 +225,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 		if rule.FromPort != 0 && rule.ToPort != 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoTCP, dportOption, ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoTCP, dportOption, nx.ruleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(nx.logger, nft); err != nil {
 					return err
 				}
@@ -228,7 +236,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 		if rule.FromPort == 0 && rule.ToPort == 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoUDP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoUDP, destPort, "0-65535", nx.ruleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(nx.logger, nft); err != nil {
 					return err
 				}
@@ -238,7 +246,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 		if rule.FromPort != 0 && rule.ToPort != 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, rule.IpProtocol, dportOption, ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv4, srcOrDstOption, rule.IpProtocol, dportOption, nx.ruleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(nx.logger, nft); err != nil {
 					return err
 				}
@@ -248,7 +256,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecur
 		// icmpv4 permits to L3 src or dst
 		for _, ipRange := range rule.IpRanges {
 			srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-			nft = []string{"insert", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv4, "ip", "protocol", protoICMP, srcOrDstOption, ruleInterface, counter, actionAccept}
+			nft = []string{"insert", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv4, "ip", "protocol", protoICMP, srcOrDstOption, nx.ruleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(nx.logger, nft); err != nil {
 				return err
 			}
@@ -285,7 +293,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		if rule.FromPort == 0 && rule.ToPort == 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, ruleInterface, counter, actionAccept}
+				nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, nx.ruleInterface(), counter, actionAccept}
 				if _, err := runNftCmd(nx.logger, nft); err != nil {
 					return err
 				}
@@ -296,7 +304,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		if rule.FromPort == 0 && rule.ToPort == 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv6, srcOrDstOption, protoTCP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv6, srcOrDstOption, protoTCP, destPort, "0-65535", nx.ruleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(nx.logger, nft); err != nil {
 					return err
 				}
@@ -306,7 +314,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		if rule.FromPort != 0 && rule.ToPort != 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, rule.IpProtocol, dportOption, ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, rule.IpProtocol, dportOption, nx.ruleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(nx.logger, nft); err != nil {
 					return err
 				}
@@ -317,7 +325,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		if rule.FromPort == 0 && rule.ToPort == 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv6, srcOrDstOption, protoUDP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv6, srcOrDstOption, protoUDP, destPort, "0-65535", nx.ruleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(nx.logger, nft); err != nil {
 					return err
 				}
@@ -327,7 +335,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		if rule.FromPort != 0 && rule.ToPort != 0 {
 			for _, ipRange := range rule.IpRanges {
 				srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, protoUDP, dportOption, ruleInterface, "counter", actionAccept}
+				nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, protoUDP, dportOption, nx.ruleInterface(), "counter", actionAccept}
 				if _, err := runNftCmd(nx.logger, nft); err != nil {
 					return err
 				}
@@ -337,7 +345,7 @@ func (nx *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecur
 		// icmpv4 permits to L3 src or dst
 		for _, ipRange := range rule.IpRanges {
 			srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-			nft = []string{"insert", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv6, "ip6", "nexthdr", "ipv6-icmp", srcOrDstIpAddrOption, ruleInterface, counter, actionAccept}
+			nft = []string{"insert", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv6, "ip6", "nexthdr", "ipv6-icmp", srcOrDstIpAddrOption, nx.ruleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(nx.logger, nft); err != nil {
 				return err
 			}
@@ -364,20 +372,20 @@ func (nx *Nexodus) nfPermitProtoPort(chain string, rule public.ModelsSecurityRul
 			return nil
 		}
 		// tcp permits for ports to the specified dport for v4/v6
-		nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv4, protoTCP, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv4, protoTCP, dportOption, nx.ruleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(nx.logger, nft); err != nil {
 			return err
 		}
-		nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv6, protoTCP, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv6, protoTCP, dportOption, nx.ruleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(nx.logger, nft); err != nil {
 			return err
 		}
 		// udp permits for ports to the specified dport for v4/v6
-		nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv4, protoUDP, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv4, protoUDP, dportOption, nx.ruleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(nx.logger, nft); err != nil {
 			return err
 		}
-		nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv6, protoUDP, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv6, protoUDP, dportOption, nx.ruleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(nx.logger, nft); err != nil {
 			return err
 
@@ -387,11 +395,11 @@ func (nx *Nexodus) nfPermitProtoPort(chain string, rule public.ModelsSecurityRul
 		if dportOption == "" {
 			return nil
 		}
-		nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv4, rule.IpProtocol, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv4, rule.IpProtocol, dportOption, nx.ruleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(nx.logger, nft); err != nil {
 			return err
 		}
-		nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv6, rule.IpProtocol, dportOption, ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv6, rule.IpProtocol, dportOption, nx.ruleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(nx.logger, nft); err != nil {
 			return err
 		}
@@ -414,14 +422,14 @@ func (nx *Nexodus) nfPermitProtoAny(chain string, rule public.ModelsSecurityRule
 	case protoIPv4, protoIPv6:
 		// permit ipv6 any
 		if rule.IpProtocol == protoIPv4 {
-			nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", rule.IpProtocol, ruleInterface, counter, actionAccept}
+			nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", rule.IpProtocol, nx.ruleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(nx.logger, nft); err != nil {
 				return err
 			}
 		}
 		// permit ipv4 any
 		if rule.IpProtocol == protoIPv6 {
-			nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", rule.IpProtocol, ruleInterface, counter, actionAccept}
+			nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", rule.IpProtocol, nx.ruleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(nx.logger, nft); err != nil {
 				return err
 			}
@@ -430,7 +438,7 @@ func (nx *Nexodus) nfPermitProtoAny(chain string, rule public.ModelsSecurityRule
 	case "icmp", protoICMPv4, protoICMPv6:
 		// permit icmpv4 any
 		if rule.IpProtocol == protoICMPv4 || rule.IpProtocol == "icmp" {
-			nft = []string{"insert", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv4, "ip", "protocol", protoICMP, ruleInterface, counter, actionAccept}
+			nft = []string{"insert", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv4, "ip", "protocol", protoICMP, nx.ruleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(nx.logger, nft); err != nil {
 				return err
 			}
@@ -438,19 +446,19 @@ func (nx *Nexodus) nfPermitProtoAny(chain string, rule public.ModelsSecurityRule
 		// permit icmpv6 any
 		if rule.IpProtocol == protoICMPv6 {
 			// ip6 nexthdr is used instead of ip6 protocol for IPv6, because the protocol field is not directly in the IPv6 header.
-			nft = []string{"insert", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv6, "ip6", "nexthdr", "ipv6-icmp", ruleInterface, counter, actionAccept}
+			nft = []string{"insert", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv6, "ip6", "nexthdr", "ipv6-icmp", nx.ruleInterface(), counter, actionAccept}
 			if _, err := runNftCmd(nx.logger, nft); err != nil {
 				return err
 			}
 		}
 	case protoTCP, protoUDP:
 		// permit ip/ip6 tcp or udp any to all ports
-		nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv4, rule.IpProtocol, destPort, "0-65535", ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv4, rule.IpProtocol, destPort, "0-65535", nx.ruleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(nx.logger, nft); err != nil {
 			return err
 		}
 		// permit ipv6 tcp or udp any
-		nft = []string{"add", "rule", tableFamily, sgTableName, chain, "meta", "nfproto", protoIPv6, rule.IpProtocol, destPort, "0-65535", ruleInterface, counter, actionAccept}
+		nft = []string{"add", "rule", tableFamily, nx.sgTableName(), chain, "meta", "nfproto", protoIPv6, rule.IpProtocol, destPort, "0-65535", nx.ruleInterface(), counter, actionAccept}
 		if _, err := runNftCmd(nx.logger, nft); err != nil {
 			return err
 		}
@@ -481,7 +489,7 @@ func (nx *Nexodus) nftPortOption(rule public.ModelsSecurityRule) string {
 
 // nfIngressRuleDrop is used to append a drop rule to the ingress chain. Example rule handled by this method:
 func (nx *Nexodus) nfIngressRuleDrop() error {
-	nft := []string{"add", "rule", tableFamily, sgTableName, ingressChain, ruleInterface, "counter", actionDrop}
+	nft := []string{"add", "rule", tableFamily, nx.sgTableName(), ingressChain, nx.ruleInterface(), "counter", actionDrop}
 	if _, err := runNftCmd(nx.logger, nft); err != nil {
 		return err
 	}
@@ -491,7 +499,7 @@ func (nx *Nexodus) nfIngressRuleDrop() error {
 
 // nfEgressRuleDrop is used to append a drop rule to the egress chain
 func (nx *Nexodus) nfEgressRuleDrop() error {
-	nft := []string{"add", "rule", tableFamily, sgTableName, egressChain, ruleInterface, "counter", actionDrop}
+	nft := []string{"add", "rule", tableFamily, nx.sgTableName(), egressChain, nx.ruleInterface(), "counter", actionDrop}
 	if _, err := runNftCmd(nx.logger, nft); err != nil {
 		return err
 	}
@@ -552,7 +560,7 @@ func (nx *Nexodus) nfCreateTable(table string) error {
 
 // nfCreateChain is used to create the nftables chain in the nf table
 func (nx *Nexodus) nfCreateChain(chainName string) error {
-	if _, err := runNftCmd(nx.logger, []string{"add", "chain", tableFamily, sgTableName, chainName, "{", "type", "filter", "hook", "input", "priority", "0", ";", "policy", "accept", ";", "}"}); err != nil {
+	if _, err := runNftCmd(nx.logger, []string{"add", "chain", tableFamily, nx.sgTableName(), chainName, "{", "type", "filter", "hook", "input", "priority", "0", ";", "policy", "accept", ";", "}"}); err != nil {
 		return err
 	}
 
@@ -0,0 +1,21 @@
+package nexodus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSgTableName(t *testing.T) {
+	require := require.New(t)
+
+	// the first organization keeps the table of a single organization nexd.
+	nx := &Nexodus{tunnelIface: "wg0"}
+	require.Equal("nexodus", nx.sgTableName())
+
+	// the other organizations have a table per tunnel interface.
+	nx = &Nexodus{tunnelIface: "wg1", organizationIndex: 1}
+	require.Equal("nexodus-wg1", nx.sgTableName())
+	nx = &Nexodus{tunnelIface: "wg2", organizationIndex: 2}
+	require.Equal("nexodus-wg2", nx.sgTableName())
+}
@@ -4,6 +4,7 @@ import (
 	"net"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/nexodus-io/nexodus/internal/api/public"
@@ -36,10 +37,7 @@ func (nx *Nexodus) buildPeersConfig() map[string]public.ModelsDevice {
 
 	_, nx.wireguardPubKeyInConfig = nx.deviceCache[nx.wireguardPubKey]
 
-	relayAllowedIP := []string{
-		nx.org.Cidr,
-		nx.org.CidrV6,
-	}
+	relayAllowedIP := nx.relayAllowedIPs()
 
 	nx.buildLocalConfig()
 
@@ -191,6 +189,32 @@ func (nx *Nexodus) logPeerInfo(device public.ModelsDevice, endpointIP string) {
 		device.PublicKey)
 }
 
+// relayAllowedIPs returns the prefixes that are reached through a relay, the CIDRs of the organization
+// and, while the organization is renumbered, the previous CIDRs that devices still have addresses of.
+func (nx *Nexodus) relayAllowedIPs() []string {
+	seen := map[string]bool{
+		nx.org.Cidr:   true,
+		nx.org.CidrV6: true,
+	}
+	var previous []string
+	for _, d := range nx.deviceCache {
+		// the device cache holds the peered devices of other organizations too, their prefixes
+		// are not reached through our relay.
+		if d.device.OrganizationId != nx.org.Id {
+			continue
+		}
+		for _, prefix := range []string{d.device.OrganizationPrefix, d.device.OrganizationPrefixV6} {
+			if prefix != "" && !seen[prefix] {
+				seen[prefix] = true
+				previous = append(previous, prefix)
+			}
+		}
+	}
+	// the device cache is a map, sort the prefixes so that the peer configuration is stable.
+	sort.Strings(previous)
+	return append([]string{nx.org.Cidr, nx.org.CidrV6}, previous...)
+}
+
 // buildLocalConfig builds the configuration for the local interface
 func (nx *Nexodus) buildLocalConfig() {
 	var localInterface wgLocalConfig
@@ -202,7 +226,7 @@ func (nx *Nexodus) buildLocalConfig() {
 	}
 
 	// if the local node address changed replace it on wg0
-	if nx.TunnelIP != d.device.TunnelIp {
+	if nx.TunnelIP != d.device.TunnelIp || nx.TunnelIpV6 != d.device.TunnelIpV6 {
 		nx.logger.Infof("New local Wireguard interface addresses assigned IPv4 [ %s ] IPv6 [ %s ]", d.device.TunnelIp, d.device.TunnelIpV6)
 		if runtime.GOOS == Linux.String() && linkExists(nx.tunnelIface) {
 			if err := delLink(nx.tunnelIface); err != nil {
@@ -0,0 +1,42 @@
+package routers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticator authenticates the requests of the http and the gRPC apis. Both apis share its token
+// providers, authorization policy and rate limits.
+type Authenticator struct {
+	// engine allocates the gin.Context of the gRPC calls.
+	engine *gin.Engine
+	// steps are run in order until one of them aborts the request, they don't call c.Next() so
+	// that they can also be run outside of a gin handler chain.
+	steps []gin.HandlerFunc
+}
+
+func NewAuthenticator(ctx context.Context, o APIRouterOptions) (*Authenticator, error) {
+	validateJWT, err := newValidateJWT(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	return &Authenticator{
+		engine: gin.New(),
+		steps: []gin.HandlerFunc{
+			validateJWT,
+			o.Api.RateLimit(o.RateLimits),
+			o.Api.CreateUserIfNotExists(),
+		},
+	}, nil
+}
+
+// authenticate authenticates the request of c, c is aborted if the request is refused.
+func (a *Authenticator) authenticate(c *gin.Context) {
+	for _, step := range a.steps {
+		step(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+}
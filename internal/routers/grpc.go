@@ -0,0 +1,52 @@
+package routers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuthenticateGrpc is the handlers.GrpcAuthenticator of the gRPC apis, the calls are authenticated
+// like an http GET of path.
+func (a *Authenticator) AuthenticateGrpc(ctx context.Context, authorization string, path string, params gin.Params) (*gin.Context, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	req.Header.Set("Authorization", authorization)
+
+	c := gin.CreateTestContextOnly(discardResponseWriter{}, a.engine)
+	c.Request = req
+	c.Params = params
+	a.authenticate(c)
+	if !c.IsAborted() {
+		return c, nil
+	}
+	code := c.Writer.Status()
+	switch code {
+	case http.StatusUnauthorized:
+		return nil, status.Error(codes.Unauthenticated, http.StatusText(code))
+	case http.StatusForbidden:
+		return nil, status.Error(codes.PermissionDenied, http.StatusText(code))
+	case http.StatusTooManyRequests:
+		return nil, status.Error(codes.ResourceExhausted, http.StatusText(code))
+	}
+	return nil, status.Error(codes.Internal, http.StatusText(code))
+}
+
+// discardResponseWriter discards the responses of the refused gRPC calls, only their status code
+// is used.
+type discardResponseWriter struct{}
+
+func (discardResponseWriter) Header() http.Header {
+	return http.Header{}
+}
+
+func (discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (discardResponseWriter) WriteHeader(int) {}
@@ -0,0 +1,58 @@
+package routers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthenticateGrpc(t *testing.T) {
+	require := require.New(t)
+
+	var steps []string
+	a := &Authenticator{
+		engine: gin.New(),
+		steps: []gin.HandlerFunc{
+			func(c *gin.Context) {
+				steps = append(steps, "token")
+				require.Equal("/api/organizations/org-a/devices", c.Request.URL.Path)
+				if c.GetHeader("Authorization") != "Bearer test" {
+					c.AbortWithStatus(http.StatusUnauthorized)
+					return
+				}
+				c.Set(gin.AuthUserKey, "user-a")
+			},
+			func(c *gin.Context) {
+				steps = append(steps, "rate-limit")
+				if c.Param("organization") == "org-a" {
+					c.AbortWithStatus(http.StatusTooManyRequests)
+				}
+			},
+			func(c *gin.Context) {
+				steps = append(steps, "user")
+			},
+		},
+	}
+	ctx := context.Background()
+
+	// the steps after the one that refuses the call are not run.
+	_, err := a.AuthenticateGrpc(ctx, "Bearer other", "/api/organizations/org-a/devices", nil)
+	require.Equal(codes.Unauthenticated, status.Code(err))
+	require.Equal([]string{"token"}, steps)
+
+	steps = nil
+	_, err = a.AuthenticateGrpc(ctx, "Bearer test", "/api/organizations/org-a/devices", gin.Params{{Key: "organization", Value: "org-a"}})
+	require.Equal(codes.ResourceExhausted, status.Code(err))
+	require.Equal([]string{"token", "rate-limit"}, steps)
+
+	steps = nil
+	c, err := a.AuthenticateGrpc(ctx, "Bearer test", "/api/organizations/org-a/devices", gin.Params{{Key: "organization", Value: "org-b"}})
+	require.NoError(err)
+	require.Equal("user-a", c.GetString(gin.AuthUserKey))
+	require.Equal([]string{"token", "rate-limit", "user"}, steps)
+}
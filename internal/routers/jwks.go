@@ -0,0 +1,198 @@
+package routers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/idp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// jwksMinRefreshInterval limits how often a key set is fetched for the tokens signed with a key
+// that it doesn't have, so that tokens with made up key ids can't flood the identity provider.
+const jwksMinRefreshInterval = 10 * time.Second
+
+// verifiedTokenPurgeInterval is how often the expired tokens are dropped from the verified token cache.
+const verifiedTokenPurgeInterval = time.Minute
+
+var (
+	policyEvaluationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "apiserver_policy_evaluation_duration_seconds",
+		Help:    "The time taken to evaluate the authorization policy, by the kind of token.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5},
+	}, []string{"token"})
+	verifiedTokenCacheCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "apiserver_verified_token_cache_total",
+		Help: "The lookups of JWTs in the verified token cache, by result.",
+	}, []string{"result"})
+	jwksRefreshCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "apiserver_jwks_refresh_total",
+		Help: "The fetches of the key sets of the identity providers, by provider and result.",
+	}, []string{"provider", "result"})
+)
+
+// keySet is the JSON Web Key Set of an identity provider.
+type keySet struct {
+	text      string
+	keyIDs    map[string]bool
+	fetchedAt time.Time
+}
+
+// verifiedToken is a JWT that was verified with the key set of its provider.
+type verifiedToken struct {
+	provider *tokenProvider
+	keys     *keySet
+	claims   map[string]interface{}
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// cachedToken returns the claims of the token if it was verified with the current key set of the
+// provider. The tokens verified with a key set that was since rotated are verified again.
+func (t *tokenProviders) cachedToken(hash string, p *tokenProvider) (map[string]interface{}, bool) {
+	token, found := t.verifiedTokens.Get(hash)
+	if !found || token.provider != p || token.keys != p.keys.Load() {
+		verifiedTokenCacheCounter.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+	verifiedTokenCacheCounter.WithLabelValues("hit").Inc()
+	return token.claims, true
+}
+
+// cacheVerifiedToken caches the claims of a token that the policy verified with the key set, until
+// it expires. The tokens without an expiry are not cached.
+func (t *tokenProviders) cacheVerifiedToken(hash string, p *tokenProvider, keys *keySet, token string) {
+	var claims map[string]interface{}
+	if err := decodeTokenPart(token, 1, &claims); err != nil {
+		return
+	}
+	exp, ok := claims["exp"].(json.Number)
+	if !ok {
+		return
+	}
+	expiresAt, err := exp.Float64()
+	if err != nil {
+		return
+	}
+	ttl := time.Until(time.Unix(int64(expiresAt), 0))
+	if ttl <= 0 {
+		return
+	}
+	t.verifiedTokens.PutWithTTL(hash, &verifiedToken{provider: p, keys: keys, claims: claims}, ttl)
+}
+
+// keySet returns the key set of the provider. The key set is fetched again when it doesn't have the
+// key the token was signed with, since the provider may have rotated its keys.
+func (t *tokenProviders) keySet(p *tokenProvider, keyID string) (*keySet, error) {
+	keys := p.keys.Load()
+	if keys != nil && (keyID == "" || keys.keyIDs[keyID]) {
+		return keys, nil
+	}
+	keys, err := t.refreshKeySet(p, false)
+	if keys != nil {
+		return keys, nil
+	}
+	return nil, err
+}
+
+// refreshKeySet fetches the key set of the provider, unless it was fetched recently and force is
+// false. The current key set is kept, and returned with the error, when the fetch fails, so that
+// an outage of the provider doesn't reject the tokens that it signed.
+func (t *tokenProviders) refreshKeySet(p *tokenProvider, force bool) (*keySet, error) {
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+
+	current := p.keys.Load()
+	if !force && current != nil && time.Since(current.fetchedAt) < jwksMinRefreshInterval {
+		return current, nil
+	}
+	keys, err := fetchKeySet(t.ctx, p.jwksURI)
+	if err != nil {
+		jwksRefreshCounter.WithLabelValues(p.Name, "error").Inc()
+		return current, fmt.Errorf("identity provider %s: %w", p.Name, err)
+	}
+	if current != nil && current.text == keys.text {
+		current.fetchedAt = keys.fetchedAt
+		jwksRefreshCounter.WithLabelValues(p.Name, "unchanged").Inc()
+		return current, nil
+	}
+	p.keys.Store(keys)
+	jwksRefreshCounter.WithLabelValues(p.Name, "rotated").Inc()
+	return keys, nil
+}
+
+func fetchKeySet(ctx context.Context, jwksURI string) (*keySet, error) {
+	text, err := getURLAsText(ctx, jwksURI)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []struct {
+			KeyID string `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(text), &jwks); err != nil {
+		return nil, fmt.Errorf("invalid key set: %w", err)
+	}
+	if len(jwks.Keys) == 0 {
+		return nil, fmt.Errorf("invalid key set: no keys")
+	}
+	keys := &keySet{text: text, keyIDs: map[string]bool{}, fetchedAt: time.Now()}
+	for _, key := range jwks.Keys {
+		keys.keyIDs[key.KeyID] = true
+	}
+	return keys, nil
+}
+
+// startKeySetRefresh refreshes the key sets of the OIDC providers every interval, so that the
+// requests don't wait for them.
+func (t *tokenProviders) startKeySetRefresh(ctx context.Context, logger *zap.SugaredLogger, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			for _, p := range t.providers {
+				if p.Type != idp.TypeOIDC {
+					continue
+				}
+				if _, err := t.refreshKeySet(p, true); err != nil {
+					logger.Warnf("failed to refresh the key set: %v", err)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// startVerifiedTokenPurge drops the expired tokens from the verified token cache every interval,
+// which otherwise only replaces them when the same token is verified again.
+func (t *tokenProviders) startVerifiedTokenPurge(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				t.verifiedTokens.DeleteExpired()
+			}
+		}
+	}()
+}
@@ -0,0 +1,224 @@
+package routers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/idp"
+	"github.com/nexodus-io/nexodus/internal/util/cache"
+	"github.com/stretchr/testify/require"
+)
+
+// jwksTestServer serves a key set with the key ids, and counts how many times it was fetched.
+type jwksTestServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keyIDs  []string
+	fetches int
+}
+
+func newJWKSTestServer(t *testing.T, keyIDs ...string) *jwksTestServer {
+	s := &jwksTestServer{keyIDs: keyIDs}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetches++
+		if len(s.keyIDs) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var keys []map[string]string
+		for _, kid := range s.keyIDs {
+			keys = append(keys, map[string]string{"kid": kid, "kty": "RSA"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksTestServer) rotate(keyIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyIDs = keyIDs
+}
+
+func (s *jwksTestServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func newJWKSTestProviders(jwksURI string) (*tokenProviders, *tokenProvider) {
+	p := &tokenProvider{
+		Provider: &idp.Provider{Name: "test", Type: idp.TypeOIDC},
+		jwksURI:  jwksURI,
+	}
+	return &tokenProviders{
+		ctx:            context.Background(),
+		providers:      []*tokenProvider{p},
+		verifiedTokens: cache.NewRWMutexTTLCache[string, *verifiedToken](0),
+	}, p
+}
+
+// testJWT returns an unsigned JWT with the claims, the cache only decodes the claims of the tokens
+// that the policy verified.
+func testJWT(t *testing.T, claims map[string]interface{}) string {
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	encode := base64.RawURLEncoding.EncodeToString
+	return fmt.Sprintf("%s.%s.%s", encode([]byte(`{"alg":"RS256"}`)), encode(payload), encode([]byte("signature")))
+}
+
+// expireKeySet makes the key set old enough to be fetched again for an unknown key.
+func expireKeySet(p *tokenProvider) {
+	p.keys.Load().fetchedAt = time.Now().Add(-jwksMinRefreshInterval)
+}
+
+func TestKeySetRefreshesOnUnknownKeyID(t *testing.T) {
+	require := require.New(t)
+	server := newJWKSTestServer(t, "key-1")
+	providers, p := newJWKSTestProviders(server.URL)
+
+	keys, err := providers.keySet(p, "key-1")
+	require.NoError(err)
+	require.True(keys.keyIDs["key-1"])
+	require.Equal(1, server.fetchCount())
+
+	// a known key id uses the current key set.
+	same, err := providers.keySet(p, "key-1")
+	require.NoError(err)
+	require.Same(keys, same)
+	require.Equal(1, server.fetchCount())
+
+	// the provider rotated its keys, a token signed with the new key fetches the key set again.
+	server.rotate("key-1", "key-2")
+	expireKeySet(p)
+	rotated, err := providers.keySet(p, "key-2")
+	require.NoError(err)
+	require.NotSame(keys, rotated)
+	require.True(rotated.keyIDs["key-2"])
+	require.Same(rotated, p.keys.Load())
+	require.Equal(2, server.fetchCount())
+
+	// a key set that didn't change is kept.
+	expireKeySet(p)
+	unchanged, err := providers.keySet(p, "key-3")
+	require.NoError(err)
+	require.Same(rotated, unchanged)
+	require.Equal(3, server.fetchCount())
+}
+
+func TestKeySetRefreshIsThrottled(t *testing.T) {
+	require := require.New(t)
+	server := newJWKSTestServer(t, "key-1")
+	providers, p := newJWKSTestProviders(server.URL)
+
+	keys, err := providers.keySet(p, "key-1")
+	require.NoError(err)
+	require.Equal(1, server.fetchCount())
+
+	// tokens with made up key ids don't fetch the key set more than every jwksMinRefreshInterval.
+	for i := 0; i < 10; i++ {
+		current, err := providers.keySet(p, fmt.Sprintf("made-up-%d", i))
+		require.NoError(err)
+		require.Same(keys, current)
+	}
+	require.Equal(1, server.fetchCount())
+
+	// the periodic refresh is not throttled.
+	_, err = providers.refreshKeySet(p, true)
+	require.NoError(err)
+	require.Equal(2, server.fetchCount())
+
+	// the current key set is kept when the provider can't be reached.
+	server.rotate()
+	current, err := providers.refreshKeySet(p, true)
+	require.Error(err)
+	require.Same(keys, current)
+	require.Same(keys, p.keys.Load())
+}
+
+func TestCachedTokenIsInvalidatedByKeyRotation(t *testing.T) {
+	require := require.New(t)
+	server := newJWKSTestServer(t, "key-1")
+	providers, p := newJWKSTestProviders(server.URL)
+
+	keys, err := providers.keySet(p, "key-1")
+	require.NoError(err)
+	token := testJWT(t, map[string]interface{}{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()})
+	hash := hashToken(token)
+	providers.cacheVerifiedToken(hash, p, keys, token)
+
+	claims, found := providers.cachedToken(hash, p)
+	require.True(found)
+	require.Equal("user-1", claims["sub"])
+
+	// the token is only cached for the provider that verified it.
+	other := &tokenProvider{Provider: &idp.Provider{Name: "other", Type: idp.TypeOIDC}}
+	other.keys.Store(keys)
+	_, found = providers.cachedToken(hash, other)
+	require.False(found)
+
+	// once the keys are rotated the token has to be verified again.
+	server.rotate("key-2")
+	_, err = providers.refreshKeySet(p, true)
+	require.NoError(err)
+	_, found = providers.cachedToken(hash, p)
+	require.False(found)
+}
+
+func TestCacheVerifiedTokenExpiry(t *testing.T) {
+	require := require.New(t)
+	server := newJWKSTestServer(t, "key-1")
+	providers, p := newJWKSTestProviders(server.URL)
+	keys, err := providers.keySet(p, "key-1")
+	require.NoError(err)
+
+	cached := func(claims map[string]interface{}) bool {
+		token := testJWT(t, claims)
+		providers.cacheVerifiedToken(hashToken(token), p, keys, token)
+		_, found := providers.cachedToken(hashToken(token), p)
+		return found
+	}
+
+	// the tokens without an expiry, or that already expired, are not cached.
+	require.False(cached(map[string]interface{}{"sub": "no-exp"}))
+	require.False(cached(map[string]interface{}{"sub": "expired", "exp": time.Now().Add(-time.Minute).Unix()}))
+	require.False(cached(map[string]interface{}{"sub": "invalid-exp", "exp": "tomorrow"}))
+
+	// a token is cached until it expires.
+	token := testJWT(t, map[string]interface{}{"sub": "expiring", "exp": time.Now().Add(2 * time.Second).Unix()})
+	hash := hashToken(token)
+	providers.cacheVerifiedToken(hash, p, keys, token)
+	_, found := providers.cachedToken(hash, p)
+	require.True(found)
+	require.Eventually(func() bool {
+		_, found := providers.cachedToken(hash, p)
+		return !found
+	}, 3*time.Second, 100*time.Millisecond)
+}
+
+func TestVerifiedTokenPurge(t *testing.T) {
+	require := require.New(t)
+	providers, p := newJWKSTestProviders("")
+	providers.verifiedTokens.PutWithTTL("expired", &verifiedToken{provider: p}, time.Millisecond)
+	providers.verifiedTokens.PutWithTTL("valid", &verifiedToken{provider: p}, time.Hour)
+
+	// the expired tokens are purged even when the key sets are not refreshed.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	providers.startKeySetRefresh(ctx, nil, 0)
+	providers.startVerifiedTokenPurge(ctx, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(0, providers.verifiedTokens.DeleteExpired())
+	_, found := providers.verifiedTokens.Get("valid")
+	require.True(found)
+}
@@ -9,49 +9,37 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/handlers"
+	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/nexodus-io/nexodus/internal/util"
-	"github.com/nexodus-io/nexodus/internal/util/cache"
-	"github.com/open-policy-agent/opa/rego"
-	"golang.org/x/oauth2"
 )
 
 // key for username in gin.Context
 const AuthUserName string = "_nexodus.UserName"
 
+// key for the email of the user in gin.Context
+const AuthUserEmail string = "_nexodus.UserEmail"
+
+// key for the groups of the user in gin.Context
+const AuthUserGroups string = "_nexodus.UserGroups"
+
 //go:embed token.rego
 var policy string
 
-var jwksCache = cache.NewMemoizeCache[string, string](time.Second*30, time.Second*5)
-
 // Naive JWS Key validation
-func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(*gin.Context), error) {
-	query, err := rego.New(
-		rego.Query(`result = {
-			"authorized": data.token.valid_token,
-			"allow": data.token.allow,
-			"user_id": data.token.user_id,
-			"user_name": data.token.user_name,
-			"full_name": data.token.full_name,
-		}`),
-		rego.Store(o.Store),
-		rego.Module("policy.rego", policy),
-	).PrepareForEval(context.Background())
-	if err != nil {
-		return nil, err
+func ValidateJWT(ctx context.Context, o APIRouterOptions, providers *tokenProviders) (func(*gin.Context), error) {
+	authzPolicy := o.Policy
+	if authzPolicy == nil {
+		var err error
+		authzPolicy, err = NewPolicy(ctx, o.Logger, o.Store, PolicyOptions{})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return func(c *gin.Context) {
 		logger := util.WithTrace(c.Request.Context(), o.Logger)
 
-		keySet, err := jwksCache.MemoizeCanErr(jwksURI, func() (string, error) {
-			return getURLAsText(ctx, jwksURI)
-		})
-		if err != nil {
-			logger.Error(err)
-			c.AbortWithStatus(http.StatusInternalServerError)
-			return
-		}
-
 		authHeader := c.Request.Header.Get("Authorization")
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 {
@@ -64,15 +52,100 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 			return
 		}
 
+		// registration keys are not JWTs, they are validated against the database.
+		if strings.HasPrefix(parts[1], handlers.RegKeyPrefix) {
+			o.Api.RegKeyAuth(c, parts[1])
+			return
+		}
+
 		path := strings.Split(strings.TrimLeft(c.Request.URL.Path, "/"), "/")
 		input := map[string]interface{}{
-			"jwks":         keySet,
 			"access_token": parts[1],
 			"method":       c.Request.Method,
 			"path":         path,
 		}
 
-		results, err := query.Eval(c.Request.Context(), rego.EvalInput(input))
+		// service account api tokens are not JWTs, they are looked up in the database
+		// and the result is passed to the policy.
+		var serviceAccount *models.ServiceAccount
+		var provider *tokenProvider
+		var keys *keySet
+		var tokenHash string
+		tokenKind := "api_token"
+		if strings.HasPrefix(parts[1], handlers.ApiTokenPrefix) {
+			sa, scopes, err := o.Api.LookupApiToken(c.Request.Context(), parts[1])
+			if err != nil {
+				logger.Error(err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			if sa == nil {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			serviceAccount = sa
+			input["api_token"] = map[string]interface{}{
+				"user_id":   serviceAccount.ID.String(),
+				"user_name": serviceAccount.Name,
+				"scopes":    scopes,
+			}
+		} else if strings.HasPrefix(parts[1], OAuth2TokenPrefix) {
+			// the access tokens of OAuth2 providers are opaque, the claims of their user
+			// are fetched from the provider and passed to the policy.
+			tokenKind = "oauth2"
+			var accessToken string
+			provider, accessToken = providers.forOAuth2(parts[1])
+			if provider == nil {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			claims, err := providers.userInfo(provider, accessToken)
+			if err != nil {
+				logger.Error(err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			if claims == nil {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			input["provider"] = provider.input
+			input["oauth2"] = map[string]interface{}{
+				"claims": claims,
+				"scopes": provider.GrantedScopes,
+			}
+		} else {
+			provider = providers.forJWT(parts[1])
+			if provider == nil {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			input["provider"] = provider.input
+			// the signature of a token is only verified the first time it's seen, the claims of
+			// the verified tokens are passed to the policy until the tokens expire.
+			tokenHash = hashToken(parts[1])
+			if claims, found := providers.cachedToken(tokenHash, provider); found {
+				tokenKind = "cached_jwt"
+				input["verified_token"] = map[string]interface{}{
+					"claims": claims,
+				}
+			} else {
+				tokenKind = "jwt"
+				var err error
+				keys, err = providers.keySet(provider, unverifiedKeyID(parts[1]))
+				if err != nil {
+					logger.Error(err)
+					c.AbortWithStatus(http.StatusInternalServerError)
+					return
+				}
+				input["jwks"] = keys.text
+			}
+		}
+
+		start := time.Now()
+		results, revision, err := authzPolicy.eval(c.Request.Context(), input)
+		duration := time.Since(start)
+		policyEvaluationDuration.WithLabelValues(tokenKind).Observe(duration.Seconds())
 		if err != nil {
 			logger.Error(err)
 			c.AbortWithStatus(http.StatusUnauthorized)
@@ -94,6 +167,7 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 			return
 		}
 		logger = logger.With("result", result)
+		authzPolicy.logDecision(c.Request.Context(), c.Request.Method, c.Request.URL.Path, tokenKind, revision, result, duration)
 
 		authorized, ok := result["authorized"].(bool)
 		if !ok {
@@ -105,6 +179,9 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
+		if keys != nil {
+			providers.cacheVerifiedToken(tokenHash, provider, keys, parts[1])
+		}
 
 		allowed, ok := result["allow"].(bool)
 		if !ok {
@@ -117,6 +194,12 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
+		// the deny rules of the policy bundle refuse the requests that the policy allows.
+		if denied, _ := result["deny"].([]interface{}); len(denied) > 0 {
+			logger.Debug("denied by authz policy")
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
 
 		userID, ok := result["user_id"].(string)
 		if !ok {
@@ -139,7 +222,28 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 			return
 		}
 
+		email, _ := result["email"].(string)
+		emailVerified, _ := result["email_verified"].(bool)
+		var groups []string
+		if values, ok := result["groups"].([]interface{}); ok {
+			for _, value := range values {
+				if group, ok := value.(string); ok {
+					groups = append(groups, group)
+				}
+			}
+		}
+
 		c.Set(gin.AuthUserKey, userID)
+		if len(email) > 0 {
+			c.Set(AuthUserEmail, email)
+			c.Set(handlers.AuthUserEmailVerified, emailVerified)
+		}
+		if len(groups) > 0 {
+			c.Set(AuthUserGroups, groups)
+		}
+		if serviceAccount != nil {
+			c.Set(handlers.AuthServiceAccount, serviceAccount)
+		}
 		if len(username) > 0 {
 			c.Set(AuthUserName, username)
 		} else if len(fullName) > 0 {
@@ -149,20 +253,11 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 		}
 
 		logger.Debugf("user-id is %s", userID)
-		c.Next()
 	}, nil
 }
 
 func getURLAsText(ctx context.Context, jwksURL string) (string, error) {
-
-	httpClient := http.DefaultClient
-	if ctx != nil {
-		if ctxClient, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
-			httpClient = ctxClient
-		}
-	}
-
-	res, err := httpClient.Get(jwksURL)
+	res, err := httpClient(ctx).Get(jwksURL)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,395 @@
+package routers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/util"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/keys"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// policyQuery evaluates the decision of the policy for a request. The deny rules are only defined
+// by the modules of policy bundles, in their own package, they refuse the requests that the
+// embedded rules allow.
+const policyQuery = `result = {
+	"authorized": data.token.valid_token,
+	"allow": data.token.allow,
+	"deny": [reason | reason := data.nexodus.bundle.deny[_]],
+	"user_id": data.token.user_id,
+	"user_name": data.token.user_name,
+	"full_name": data.token.full_name,
+	"email": data.token.email,
+	"email_verified": data.token.email_verified,
+	"groups": data.token.groups,
+}`
+
+// bundlePackage is the package of the modules of policy bundles, they can't define the rules of
+// the embedded policy.
+const bundlePackage = "data.nexodus.bundle"
+
+// defaultVerificationKeyID is the id of the verification key, for the signatures without one.
+const defaultVerificationKeyID = "default"
+
+// embeddedRevision is the revision of the policy when no bundle is loaded.
+const embeddedRevision = "embedded"
+
+var policyBundleLoadCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "apiserver_policy_bundle_loads_total",
+	Help: "The loads of the policy bundle, by result.",
+}, []string{"result"})
+
+// PolicyOptions configures the policy bundle that extends the embedded policy.
+type PolicyOptions struct {
+	// Bundle is a bundle directory, a bundle tarball, or the https url of a bundle on a bundle
+	// server. The embedded policy is used alone when it's empty.
+	Bundle string
+	// VerificationKey is the public key, or the HMAC secret, that verifies the signature of the
+	// bundle, or the file holding it. It's required for bundles downloaded from a bundle server.
+	VerificationKey string
+	// VerificationKeyAlgorithm is the signing algorithm of the VerificationKey, RS256 by default.
+	VerificationKeyAlgorithm string
+	// VerificationKeyID is the id of the VerificationKey in the signatures of the bundle.
+	VerificationKeyID string
+	// PollInterval is how often the bundle is reloaded when it changes, 0 only loads it at startup.
+	PollInterval time.Duration
+	// DecisionLog logs the decisions of the policy.
+	DecisionLog bool
+}
+
+// Policy is the authorization policy of the api: the embedded token.rego, extended by the rego
+// modules of a policy bundle. The data of the bundle is written to the store of the policy.
+type Policy struct {
+	logger  *zap.SugaredLogger
+	store   storage.Store
+	options PolicyOptions
+	client  *http.Client
+	verify  *bundle.VerificationConfig
+	query   atomic.Pointer[preparedPolicy]
+	// mu serializes the loads of the bundle, the fields below are guarded by it.
+	mu          sync.Mutex
+	etag        string
+	fingerprint string
+	dataRoots   []string
+}
+
+type preparedPolicy struct {
+	query    rego.PreparedEvalQuery
+	revision string
+}
+
+// NewPolicy prepares the policy and loads its bundle. A bundle that fails to load at startup is an
+// error, while the reloads that fail keep the last loaded policy.
+func NewPolicy(ctx context.Context, logger *zap.SugaredLogger, store storage.Store, options PolicyOptions) (*Policy, error) {
+	p := &Policy{
+		logger:  logger,
+		store:   store,
+		options: options,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+	if strings.HasPrefix(options.Bundle, "http://") {
+		return nil, fmt.Errorf("the policy bundle must be downloaded with https: %s", options.Bundle)
+	}
+	if isBundleURL(options.Bundle) && options.VerificationKey == "" {
+		return nil, fmt.Errorf("a verification key is required for the policy bundle %s", options.Bundle)
+	}
+	if options.VerificationKey != "" {
+		verify, err := newVerificationConfig(options)
+		if err != nil {
+			return nil, err
+		}
+		p.verify = verify
+	}
+	if options.Bundle == "" {
+		prepared, err := p.prepare(ctx, nil, embeddedRevision)
+		if err != nil {
+			return nil, err
+		}
+		p.query.Store(prepared)
+		return p, nil
+	}
+	if err := p.Reload(ctx); err != nil {
+		return nil, err
+	}
+	if options.PollInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(options.PollInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				if err := p.Reload(ctx); err != nil {
+					p.logger.Errorf("failed to reload the policy bundle, keeping revision %s: %v", p.Revision(), err)
+				}
+			}
+		}()
+	}
+	return p, nil
+}
+
+// Revision is the revision of the loaded policy bundle.
+func (p *Policy) Revision() string {
+	return p.query.Load().revision
+}
+
+// Reload loads the bundle again, and replaces the policy and its data when the bundle changed.
+func (p *Policy) Reload(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b, changed, err := p.readBundle(ctx)
+	if err != nil {
+		policyBundleLoadCounter.WithLabelValues("error").Inc()
+		return err
+	}
+	if !changed {
+		policyBundleLoadCounter.WithLabelValues("unchanged").Inc()
+		return nil
+	}
+	fingerprint, err := bundleFingerprint(b)
+	if err != nil {
+		policyBundleLoadCounter.WithLabelValues("error").Inc()
+		return err
+	}
+	if fingerprint == p.fingerprint {
+		p.etag = b.Etag
+		policyBundleLoadCounter.WithLabelValues("unchanged").Inc()
+		return nil
+	}
+
+	revision := b.Manifest.Revision
+	if revision == "" {
+		revision = fingerprint[:12]
+	}
+	// the rules are compiled before the data is replaced, so that a bundle with invalid rules
+	// leaves the last loaded policy as it was.
+	prepared, err := p.prepare(ctx, b, revision)
+	if err != nil {
+		policyBundleLoadCounter.WithLabelValues("error").Inc()
+		return fmt.Errorf("policy bundle %s: %w", revision, err)
+	}
+	if err := p.writeData(ctx, b.Data); err != nil {
+		policyBundleLoadCounter.WithLabelValues("error").Inc()
+		return fmt.Errorf("policy bundle %s: %w", revision, err)
+	}
+	p.query.Store(prepared)
+	p.etag = b.Etag
+	p.fingerprint = fingerprint
+	policyBundleLoadCounter.WithLabelValues("loaded").Inc()
+	p.logger.Infof("loaded the policy bundle revision %s", revision)
+	return nil
+}
+
+// readBundle reads the bundle from its directory, file or url. It returns false when the bundle
+// server reports that the bundle didn't change since the last load.
+func (p *Policy) readBundle(ctx context.Context) (*bundle.Bundle, bool, error) {
+	source := p.options.Bundle
+	if isBundleURL(source) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, false, err
+		}
+		if p.etag != "" {
+			req.Header.Set("If-None-Match", p.etag)
+		}
+		res, err := p.client.Do(req)
+		if err != nil {
+			return nil, false, err
+		}
+		defer res.Body.Close()
+		switch res.StatusCode {
+		case http.StatusNotModified:
+			return nil, false, nil
+		case http.StatusOK:
+		default:
+			return nil, false, fmt.Errorf("failed to download the policy bundle: %s", res.Status)
+		}
+		b, err := p.verified(bundle.NewReader(res.Body)).WithBundleEtag(res.Header.Get("ETag")).Read()
+		if err != nil {
+			return nil, false, err
+		}
+		return &b, true, nil
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, false, err
+	}
+	var b bundle.Bundle
+	if info.IsDir() {
+		b, err = p.verified(bundle.NewCustomReader(bundle.NewDirectoryLoader(source))).Read()
+	} else {
+		var file *os.File
+		file, err = os.Open(source)
+		if err != nil {
+			return nil, false, err
+		}
+		defer file.Close()
+		b, err = p.verified(bundle.NewReader(file)).Read()
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &b, true, nil
+}
+
+// verified checks the signature of the bundle when a verification key is configured.
+func (p *Policy) verified(reader *bundle.Reader) *bundle.Reader {
+	if p.verify == nil {
+		return reader
+	}
+	return reader.WithBundleVerificationConfig(p.verify)
+}
+
+func newVerificationConfig(options PolicyOptions) (*bundle.VerificationConfig, error) {
+	alg := options.VerificationKeyAlgorithm
+	if alg == "" {
+		alg = "RS256"
+	}
+	id := options.VerificationKeyID
+	if id == "" {
+		id = defaultVerificationKeyID
+	}
+	key, err := keys.NewKeyConfig(options.VerificationKey, alg, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy bundle verification key: %w", err)
+	}
+	return bundle.NewVerificationConfig(map[string]*keys.Config{id: key}, id, "", nil), nil
+}
+
+func isBundleURL(source string) bool {
+	return strings.HasPrefix(source, "https://")
+}
+
+// prepare compiles the embedded policy with the modules of the bundle.
+func (p *Policy) prepare(ctx context.Context, b *bundle.Bundle, revision string) (*preparedPolicy, error) {
+	options := []func(*rego.Rego){
+		rego.Query(policyQuery),
+		rego.Store(p.store),
+		rego.Module("policy.rego", policy),
+	}
+	if b != nil {
+		for _, module := range b.Modules {
+			if err := checkBundlePackage(module); err != nil {
+				return nil, err
+			}
+			options = append(options, rego.Module("bundle/"+strings.TrimPrefix(module.Path, "/"), string(module.Raw)))
+		}
+	}
+	query, err := rego.New(options...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &preparedPolicy{query: query, revision: revision}, nil
+}
+
+// checkBundlePackage refuses the modules that aren't in the bundle package, which could replace or
+// extend the rules of the embedded policy.
+func checkBundlePackage(module bundle.ModuleFile) error {
+	parsed := module.Parsed
+	if parsed == nil {
+		var err error
+		parsed, err = ast.ParseModule(module.Path, string(module.Raw))
+		if err != nil {
+			return err
+		}
+	}
+	pkg := parsed.Package.Path.String()
+	if pkg != bundlePackage && !strings.HasPrefix(pkg, bundlePackage+".") {
+		return fmt.Errorf("module %s: the package %s isn't in %s", module.Path, strings.TrimPrefix(pkg, "data."), strings.TrimPrefix(bundlePackage, "data."))
+	}
+	return nil
+}
+
+// writeData replaces the data of the previous bundle in the store with the data of the bundle.
+func (p *Policy) writeData(ctx context.Context, data map[string]interface{}) error {
+	var roots []string
+	for root := range data {
+		if root == "token" || root == "nexodus" {
+			return fmt.Errorf("the data can't be written to data.%s, which holds the rules of the policy", root)
+		}
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	err := storage.Txn(ctx, p.store, storage.WriteParams, func(txn storage.Transaction) error {
+		for _, root := range p.dataRoots {
+			if err := p.store.Write(ctx, txn, storage.RemoveOp, storage.Path{root}, nil); err != nil && !storage.IsNotFound(err) {
+				return err
+			}
+		}
+		for _, root := range roots {
+			if err := p.store.Write(ctx, txn, storage.AddOp, storage.Path{root}, data[root]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	p.dataRoots = roots
+	return nil
+}
+
+// bundleFingerprint identifies the content of a bundle, to skip the reloads of unchanged bundles.
+func bundleFingerprint(b *bundle.Bundle) (string, error) {
+	hash := sha256.New()
+	modules := append([]bundle.ModuleFile{}, b.Modules...)
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Path < modules[j].Path
+	})
+	for _, module := range modules {
+		fmt.Fprintf(hash, "%s\n%d\n", module.Path, len(module.Raw))
+		hash.Write(module.Raw)
+	}
+	// maps are marshalled with sorted keys.
+	data, err := json.Marshal(b.Data)
+	if err != nil {
+		return "", err
+	}
+	hash.Write(data)
+	fmt.Fprintf(hash, "\n%s", b.Manifest.Revision)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// eval evaluates the decision of the policy for the input, and returns the revision of the policy
+// that made it.
+func (p *Policy) eval(ctx context.Context, input map[string]interface{}) (rego.ResultSet, string, error) {
+	prepared := p.query.Load()
+	results, err := prepared.query.Eval(ctx, rego.EvalInput(input))
+	return results, prepared.revision, err
+}
+
+// logDecision logs the decision of the policy for a request, without the token of the request.
+func (p *Policy) logDecision(ctx context.Context, method string, path string, tokenKind string, revision string, result map[string]interface{}, duration time.Duration) {
+	if !p.options.DecisionLog {
+		return
+	}
+	util.WithTrace(ctx, p.logger).Named("decisions").Infow("policy decision",
+		"method", method,
+		"path", path,
+		"token", tokenKind,
+		"revision", revision,
+		"result", result,
+		"duration", duration,
+	)
+}
@@ -0,0 +1,149 @@
+package routers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testDenyModule = `package nexodus.bundle
+
+import data.token
+import future.keywords
+
+deny contains "read-only scope" if {
+	input.method != "GET"
+	some scope in data.restrictions.read_only_scopes
+	token.has_scope(scope)
+}
+`
+
+func writeTestBundle(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+}
+
+func evalTestPolicy(t *testing.T, p *Policy, method string) map[string]interface{} {
+	results, _, err := p.eval(context.Background(), map[string]interface{}{
+		"method":       method,
+		"path":         []string{"api", "devices"},
+		"access_token": "device-jwt",
+		"provider":     map[string]interface{}{"user_id_prefix": "", "claims": map[string]interface{}{"subject": "sub"}, "allowed_email_domains": []string{}},
+		"verified_token": map[string]interface{}{
+			"claims": map[string]interface{}{"sub": "user", "scope": "read:devices write:devices lab:restricted"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	return results[0].Bindings["result"].(map[string]interface{})
+}
+
+func TestPolicyBundle(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	writeTestBundle(t, dir, map[string]string{
+		".manifest": `{"revision": "1"}`,
+		"deny.rego": testDenyModule,
+		"data.json": `{"restrictions": {"read_only_scopes": ["lab:restricted"]}}`,
+	})
+
+	p, err := NewPolicy(ctx, zap.NewNop().Sugar(), inmem.New(), PolicyOptions{Bundle: dir})
+	require.NoError(err)
+	require.Equal("1", p.Revision())
+
+	result := evalTestPolicy(t, p, "GET")
+	require.Equal(true, result["allow"])
+	require.Empty(result["deny"])
+	result = evalTestPolicy(t, p, "POST")
+	require.Equal(true, result["allow"])
+	require.Equal([]interface{}{"read-only scope"}, result["deny"])
+
+	// the data of the bundle is replaced when it changes
+	writeTestBundle(t, dir, map[string]string{
+		".manifest": `{"revision": "2"}`,
+		"data.json": `{"restrictions": {"read_only_scopes": []}}`,
+	})
+	require.NoError(p.Reload(ctx))
+	require.Equal("2", p.Revision())
+	require.Empty(evalTestPolicy(t, p, "POST")["deny"])
+
+	// a bundle that doesn't compile keeps the last loaded policy
+	writeTestBundle(t, dir, map[string]string{
+		".manifest": `{"revision": "3"}`,
+		"deny.rego": "package nexodus.bundle\n\ndeny contains",
+	})
+	require.Error(p.Reload(ctx))
+	require.Equal("2", p.Revision())
+
+	// the modules of the bundle can't change the rules of the policy
+	writeTestBundle(t, dir, map[string]string{
+		"deny.rego": "package token\n\nallow := true\n",
+	})
+	require.Error(p.Reload(ctx))
+	require.Equal("2", p.Revision())
+
+	// the data of the bundle can't replace the rules of the policy
+	writeTestBundle(t, dir, map[string]string{
+		"deny.rego": testDenyModule,
+		"data.json": `{"token": {"allow": true}}`,
+	})
+	require.Error(p.Reload(ctx))
+	require.Equal("2", p.Revision())
+}
+
+func TestPolicyBundleSignature(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: "signed"},
+		Modules: []bundle.ModuleFile{{
+			URL:  "/deny.rego",
+			Path: "/deny.rego",
+			Raw:  []byte(testDenyModule),
+		}},
+		Data: map[string]interface{}{
+			"restrictions": map[string]interface{}{"read_only_scopes": []interface{}{"lab:restricted"}},
+		},
+	}
+	require.NoError(b.GenerateSignature(bundle.NewSigningConfig("secret", "HS256", ""), "default", true))
+	signed := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	file, err := os.Create(signed)
+	require.NoError(err)
+	require.NoError(bundle.NewWriter(file).UseModulePath(true).Write(b))
+	require.NoError(file.Close())
+
+	options := PolicyOptions{Bundle: signed, VerificationKey: "secret", VerificationKeyAlgorithm: "HS256"}
+	p, err := NewPolicy(ctx, zap.NewNop().Sugar(), inmem.New(), options)
+	require.NoError(err)
+	require.Equal("signed", p.Revision())
+	require.Equal([]interface{}{"read-only scope"}, evalTestPolicy(t, p, "POST")["deny"])
+
+	// the signature is checked with the verification key
+	options.VerificationKey = "other"
+	_, err = NewPolicy(ctx, zap.NewNop().Sugar(), inmem.New(), options)
+	require.Error(err)
+
+	// unsigned bundles are refused when there is a verification key
+	dir := t.TempDir()
+	writeTestBundle(t, dir, map[string]string{"deny.rego": testDenyModule})
+	options = PolicyOptions{Bundle: dir, VerificationKey: "secret", VerificationKeyAlgorithm: "HS256"}
+	_, err = NewPolicy(ctx, zap.NewNop().Sugar(), inmem.New(), options)
+	require.Error(err)
+
+	// bundle servers must use https, with a verification key
+	_, err = NewPolicy(ctx, zap.NewNop().Sugar(), inmem.New(), PolicyOptions{Bundle: "http://bundles.example.com/bundle.tar.gz", VerificationKey: "secret"})
+	require.ErrorContains(err, "https")
+	_, err = NewPolicy(ctx, zap.NewNop().Sugar(), inmem.New(), PolicyOptions{Bundle: "https://bundles.example.com/bundle.tar.gz"})
+	require.ErrorContains(err, "verification key")
+}
@@ -0,0 +1,346 @@
+package routers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/nexodus-io/nexodus/internal/idp"
+	"github.com/nexodus-io/nexodus/internal/util/cache"
+	"golang.org/x/oauth2"
+)
+
+// OAuth2TokenPrefix is prepended by the clients to the access tokens of the OAuth2 identity
+// providers, followed by the name of the provider and a ':'. Unlike the tokens of the OIDC
+// providers they are opaque, so the prefix tells which provider can validate them.
+const OAuth2TokenPrefix = "OA:"
+
+// the user info of the OAuth2 access tokens, by the hash of the token. Only successful
+// lookups are cached, so invalid tokens can't grow it.
+var userInfoCache = cache.NewRWMutexTTLCache[string, map[string]interface{}](time.Minute)
+
+// tokenProvider is an identity provider that the access tokens are validated against.
+type tokenProvider struct {
+	*idp.Provider
+	// issuer and jwksURI are discovered from the OIDC providers.
+	issuer  string
+	jwksURI string
+	// input describes the provider to the policy.
+	input map[string]interface{}
+	// keys is the key set of the OIDC providers, it's replaced when the provider rotates its keys.
+	keys      atomic.Pointer[keySet]
+	refreshMu sync.Mutex
+}
+
+type tokenProviders struct {
+	// ctx holds the http client used to reach the providers.
+	ctx       context.Context
+	providers []*tokenProvider
+	// verifiedTokens are the JWTs that the policy verified, by the hash of the token, until they expire.
+	verifiedTokens *cache.RWMutexTTLCache[string, *verifiedToken]
+}
+
+func newTokenProviders(ctx context.Context, o APIRouterOptions) (*tokenProviders, error) {
+	if o.InsecureTLS {
+		transport := &http.Transport{
+			// #nosec -- G402: TLS InsecureSkipVerify set true.
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+		client := &http.Client{Transport: transport}
+		ctx = oidc.ClientContext(ctx, client)
+	}
+
+	result := &tokenProviders{
+		ctx:            ctx,
+		verifiedTokens: cache.NewRWMutexTTLCache[string, *verifiedToken](0),
+	}
+	for i := range o.IdentityProviders.Providers {
+		p := &o.IdentityProviders.Providers[i]
+		tp := &tokenProvider{
+			Provider: p,
+			input: map[string]interface{}{
+				"name":           p.Name,
+				"type":           p.Type,
+				"audience":       p.Audience,
+				"user_id_prefix": o.IdentityProviders.UserIDPrefix(p),
+				"claims": map[string]interface{}{
+					"subject":        p.Claims.Subject,
+					"username":       p.Claims.Username,
+					"full_name":      p.Claims.FullName,
+					"email":          p.Claims.Email,
+					"email_verified": p.Claims.EmailVerified,
+					"groups":         p.Claims.Groups,
+				},
+				"allowed_email_domains": append([]string{}, p.AllowedEmailDomains...),
+				"email_domain_claim":    p.EmailDomainClaim,
+			},
+		}
+		if p.Type == idp.TypeOIDC {
+			discoveryCtx := ctx
+			issuerURL := p.IssuerURL
+			if p.BackchannelURL != "" {
+				discoveryCtx = oidc.InsecureIssuerURLContext(ctx, p.IssuerURL)
+				issuerURL = p.BackchannelURL
+			}
+			provider, err := oidc.NewProvider(discoveryCtx, issuerURL)
+			if err != nil {
+				return nil, fmt.Errorf("identity provider %s: %w", p.Name, err)
+			}
+			var claims struct {
+				Issuer  string `json:"issuer"`
+				JWKSUri string `json:"jwks_uri"`
+			}
+			if err := provider.Claims(&claims); err != nil {
+				return nil, fmt.Errorf("identity provider %s: %w", p.Name, err)
+			}
+			tp.issuer = claims.Issuer
+			tp.jwksURI = claims.JWKSUri
+		}
+		result.providers = append(result.providers, tp)
+	}
+	return result, nil
+}
+
+// forJWT returns the OIDC provider of the issuer of the token, or the first OIDC provider
+// when no provider has that issuer, in which case the token fails the signature check
+// unless the provider signed it.
+func (t *tokenProviders) forJWT(token string) *tokenProvider {
+	issuer := unverifiedIssuer(token)
+	var first *tokenProvider
+	for _, p := range t.providers {
+		if p.Type != idp.TypeOIDC {
+			continue
+		}
+		if issuer != "" && p.issuer == issuer {
+			return p
+		}
+		if first == nil {
+			first = p
+		}
+	}
+	return first
+}
+
+// forOAuth2 returns the OAuth2 provider named by the prefix of the token, and the token
+// without the prefix.
+func (t *tokenProviders) forOAuth2(token string) (*tokenProvider, string) {
+	name, accessToken, found := strings.Cut(strings.TrimPrefix(token, OAuth2TokenPrefix), ":")
+	if !found {
+		return nil, ""
+	}
+	for _, p := range t.providers {
+		if p.Type == idp.TypeOAuth2 && p.Name == name {
+			return p, accessToken
+		}
+	}
+	return nil, ""
+}
+
+// userInfo fetches the claims of the user of an OAuth2 access token, it returns nil when
+// the provider rejects the token, or when the token wasn't issued to the client of the provider.
+func (t *tokenProviders) userInfo(p *tokenProvider, accessToken string) (map[string]interface{}, error) {
+	hash := sha256.Sum256([]byte(p.Name + ":" + accessToken))
+	key := hex.EncodeToString(hash[:])
+	if claims, found := userInfoCache.Get(key); found {
+		return claims, nil
+	}
+
+	// the user info of any token of the user is returned, including the tokens issued to other
+	// applications, so the token is checked to be issued to our client first.
+	issuedToClient, err := t.checkToken(p, accessToken)
+	if err != nil || !issuedToClient {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, p.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	res, err := httpClient(t.ctx).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return nil, nil
+	case res.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("identity provider %s: user info request failed: %s", p.Name, res.Status)
+	}
+
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("identity provider %s: invalid user info: %w", p.Name, err)
+	}
+	userInfoCache.Put(key, claims)
+	return claims, nil
+}
+
+// checkToken checks that an OAuth2 access token is valid and was issued to the client of the provider.
+func (t *tokenProviders) checkToken(p *tokenProvider, accessToken string) (bool, error) {
+	switch p.TokenCheck {
+	case idp.TokenCheckGitHub:
+		return t.checkGitHubToken(p, accessToken)
+	case idp.TokenCheckIntrospection:
+		return t.introspectToken(p, accessToken)
+	}
+	return false, fmt.Errorf("identity provider %s: unknown token check %s", p.Name, p.TokenCheck)
+}
+
+// checkGitHubToken checks the token with the GitHub applications api, which only knows the tokens
+// issued to the application that authenticates with its client secret.
+func (t *tokenProviders) checkGitHubToken(p *tokenProvider, accessToken string) (bool, error) {
+	body, err := json.Marshal(map[string]string{"access_token": accessToken})
+	if err != nil {
+		return false, err
+	}
+	checkURL := fmt.Sprintf("%s/applications/%s/token", strings.TrimSuffix(p.TokenCheckURL, "/"), url.PathEscape(p.ClientID))
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, checkURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.SetBasicAuth(p.ClientID, p.ClientSecret)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	var result struct {
+		App struct {
+			ClientID string `json:"client_id"`
+		} `json:"app"`
+	}
+	valid, err := t.postTokenCheck(p, req, &result)
+	if err != nil || !valid {
+		return false, err
+	}
+	return result.App.ClientID == p.ClientID, nil
+}
+
+// introspectToken checks the token with RFC 7662 token introspection, the token must be active and
+// its client or audience must be the client of the provider.
+func (t *tokenProviders) introspectToken(p *tokenProvider, accessToken string) (bool, error) {
+	form := url.Values{"token": {accessToken}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, p.TokenCheckURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var result struct {
+		Active   bool            `json:"active"`
+		ClientID string          `json:"client_id"`
+		Audience json.RawMessage `json:"aud"`
+	}
+	valid, err := t.postTokenCheck(p, req, &result)
+	if err != nil || !valid || !result.Active {
+		return false, err
+	}
+	if result.ClientID == p.ClientID {
+		return true, nil
+	}
+	// the audience is either a string or an array of strings.
+	var audiences []string
+	var audience string
+	if err := json.Unmarshal(result.Audience, &audience); err == nil {
+		audiences = []string{audience}
+	} else if err := json.Unmarshal(result.Audience, &audiences); err != nil {
+		return false, nil
+	}
+	for _, audience := range audiences {
+		if audience == p.ClientID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// postTokenCheck sends a token check request, and decodes its result. It returns false when the
+// provider doesn't know the token.
+func (t *tokenProviders) postTokenCheck(p *tokenProvider, req *http.Request, result interface{}) (bool, error) {
+	res, err := httpClient(t.ctx).Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusUnprocessableEntity:
+		// GitHub's answer for the tokens of other applications, or revoked tokens.
+		return false, nil
+	case res.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("identity provider %s: token check failed: %s", p.Name, res.Status)
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return false, fmt.Errorf("identity provider %s: invalid token check response: %w", p.Name, err)
+	}
+	return true, nil
+}
+
+// unverifiedIssuer returns the iss claim of a JWT without verifying it, the token is only
+// trusted once the keys of its provider verify it.
+func unverifiedIssuer(token string) string {
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := decodeTokenPart(token, 1, &claims); err != nil {
+		return ""
+	}
+	return claims.Issuer
+}
+
+// unverifiedKeyID returns the id of the key that signed a JWT, without verifying it.
+func unverifiedKeyID(token string) string {
+	var header struct {
+		KeyID string `json:"kid"`
+	}
+	if err := decodeTokenPart(token, 0, &header); err != nil {
+		return ""
+	}
+	return header.KeyID
+}
+
+// decodeTokenPart decodes the JSON of the header, part 0, or of the payload, part 1, of a JWT.
+// The numbers are decoded as json.Number so that they keep their precision.
+func decodeTokenPart(token string, part int, v interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid token")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[part])
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func httpClient(ctx context.Context) *http.Client {
+	if ctx != nil {
+		if ctxClient, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+			return ctxClient
+		}
+	}
+	return http.DefaultClient
+}
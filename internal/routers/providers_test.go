@@ -0,0 +1,91 @@
+package routers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nexodus-io/nexodus/internal/idp"
+	"github.com/stretchr/testify/require"
+)
+
+// newOAuth2TestServer serves the user info of every token, like GitHub does, and checks the
+// tokens issued to the client with the GitHub applications api and with token introspection.
+func newOAuth2TestServer(t *testing.T, clientTokens map[string]bool) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 42, "login": "octocat"})
+	})
+	mux.HandleFunc("/applications/nexodus-client/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "nexodus-client" || secret != "nexodus-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			AccessToken string `json:"access_token"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if !clientTokens[body.AccessToken] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"app": map[string]interface{}{"client_id": "nexodus-client"}})
+	})
+	mux.HandleFunc("/introspect", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "nexodus-client" || secret != "nexodus-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		token := r.PostFormValue("token")
+		switch {
+		case clientTokens[token]:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"active": true, "aud": []string{"nexodus-client"}})
+		case token == "inactive":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"active": false})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"active": true, "client_id": "another-client"})
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOAuth2TokenCheck(t *testing.T) {
+	server := newOAuth2TestServer(t, map[string]bool{"github-client-token": true, "introspected-client-token": true})
+	for _, tc := range []struct {
+		name       string
+		tokenCheck string
+		checkURL   string
+		token      string
+		accepted   bool
+	}{
+		{name: "github token of the client", tokenCheck: idp.TokenCheckGitHub, checkURL: server.URL, token: "github-client-token", accepted: true},
+		{name: "github token of another application", tokenCheck: idp.TokenCheckGitHub, checkURL: server.URL, token: "github-pat"},
+		{name: "introspected token of the client", tokenCheck: idp.TokenCheckIntrospection, checkURL: server.URL + "/introspect", token: "introspected-client-token", accepted: true},
+		{name: "introspected token of another client", tokenCheck: idp.TokenCheckIntrospection, checkURL: server.URL + "/introspect", token: "another-client-token"},
+		{name: "inactive introspected token", tokenCheck: idp.TokenCheckIntrospection, checkURL: server.URL + "/introspect", token: "inactive"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			providers := &tokenProviders{ctx: context.Background()}
+			p := &tokenProvider{Provider: &idp.Provider{
+				Name:          "github-" + tc.tokenCheck,
+				Type:          idp.TypeOAuth2,
+				ClientID:      "nexodus-client",
+				ClientSecret:  "nexodus-secret",
+				UserInfoURL:   server.URL + "/user",
+				TokenCheck:    tc.tokenCheck,
+				TokenCheckURL: tc.checkURL,
+			}}
+			claims, err := providers.userInfo(p, tc.token)
+			require.NoError(t, err)
+			if tc.accepted {
+				require.Equal(t, "octocat", claims["login"])
+			} else {
+				require.Nil(t, claims)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package routers
 
 import (
 	"context"
-	"crypto/tls"
 	"github.com/go-session/session/v3"
 	"github.com/nexodus-io/nexodus/pkg/ginsession"
 	"go.opentelemetry.io/otel/propagation"
@@ -10,11 +9,11 @@ import (
 	"strings"
 	"time"
 
-	"github.com/coreos/go-oidc/v3/oidc"
 	ginzap "github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
 	_ "github.com/nexodus-io/nexodus/internal/docs"
 	"github.com/nexodus-io/nexodus/internal/handlers"
+	"github.com/nexodus-io/nexodus/internal/idp"
 	agent "github.com/nexodus-io/nexodus/pkg/oidcagent"
 	"github.com/open-policy-agent/opa/storage"
 	swaggerFiles "github.com/swaggo/files"
@@ -27,20 +26,25 @@ import (
 const name = "github.com/nexodus-io/nexodus/internal/routers"
 
 type APIRouterOptions struct {
-	Logger          *zap.SugaredLogger
-	Api             *handlers.API
-	ClientIdWeb     string
-	ClientIdCli     string
-	OidcURL         string
-	OidcBackchannel string
-	InsecureTLS     bool
-	BrowserFlow     *agent.OidcAgent
-	DeviceFlow      *agent.OidcAgent
-	Store           storage.Store
-	SessionStore    session.ManagerStore
+	Logger      *zap.SugaredLogger
+	Api         *handlers.API
+	ClientIdWeb string
+	ClientIdCli string
+	InsecureTLS bool
+	BrowserFlow *agent.OidcAgent
+	DeviceFlows *agent.DeviceFlows
+	// IdentityProviders are the providers whose tokens are accepted.
+	IdentityProviders *idp.Config
+	Store             storage.Store
+	SessionStore      session.ManagerStore
+	RateLimits        handlers.RateLimits
+	// JWKSRefreshInterval is how often the key sets of the OIDC providers are refreshed.
+	JWKSRefreshInterval time.Duration
+	// Policy is the authorization policy, the embedded policy is used when it's nil.
+	Policy *Policy
 }
 
-func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error) {
+func NewAPIRouter(ctx context.Context, o APIRouterOptions, authenticator *Authenticator) (*gin.Engine, error) {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 
@@ -55,7 +59,7 @@ func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error)
 
 	device := r.Group("/device", loggerMiddleware)
 	{
-		device.POST("/login/start", o.DeviceFlow.DeviceStart)
+		device.POST("/login/start", o.DeviceFlows.DeviceStart)
 	}
 	web := r.Group("/web", loggerMiddleware)
 	{
@@ -72,13 +76,7 @@ func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error)
 	private := r.Group("/api", loggerMiddleware)
 	{
 		api := o.Api
-		validateJWT, err := newValidateJWT(ctx, o)
-		if err != nil {
-			return nil, err
-		}
-
-		private.Use(validateJWT)
-		private.Use(api.CreateUserIfNotExists())
+		private.Use(authenticator.authenticate)
 		// Organizations
 		private.GET("/organizations", api.ListOrganizations)
 		private.POST("/organizations", api.CreateOrganization)
@@ -87,10 +85,14 @@ func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error)
 		private.GET("/organizations/:organization/devices", api.ListDevicesInOrganization)
 		private.GET("/organizations/:organization/devices/:id", api.GetDeviceInOrganization)
 		private.GET("/organizations/:organization/users", api.ListUsersInOrganization)
+		private.GET("/organizations/:organization/members", api.ListOrganizationMembers)
+		private.PATCH("/organizations/:organization/members/:id", api.UpdateOrganizationMember)
+		private.GET("/organizations/:organization/audit", api.ListAuditEvents)
 		// Invitations
 		private.POST("/invitations", api.CreateInvitation)
 		private.GET("/invitations", api.ListInvitations)
 		private.GET("/invitations/:invitation", api.GetInvitation)
+		private.POST("/invitations/accept", api.AcceptInvitationLink)
 		private.POST("/invitations/:invitation/accept", api.AcceptInvitation)
 		private.DELETE("/invitations/:invitation", api.DeleteInvitation)
 		// Devices
@@ -99,6 +101,12 @@ func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error)
 		private.PATCH("/devices/:id", api.UpdateDevice)
 		private.POST("/devices", api.CreateDevice)
 		private.DELETE("/devices/:id", api.DeleteDevice)
+		private.POST("/devices/batch/delete", api.BatchDeleteDevices)
+		private.POST("/devices/batch/move", api.BatchMoveDevices)
+		private.POST("/devices/batch/security_group", api.BatchUpdateDevicesSecurityGroup)
+		private.POST("/devices/batch/metadata", api.BatchUpdateDevicesMetadata)
+		private.GET("/devices/:id/peered_devices", api.ListPeeredDevices)
+		private.POST("/devices/:id/heartbeat", api.DeviceHeartbeat)
 		// Device Metadata
 		private.GET("/devices/:id/metadata", api.ListDeviceMetadata)
 		private.GET("/devices/:id/metadata/:key", api.GetDeviceMetadataKey)
@@ -119,10 +127,61 @@ func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error)
 		private.DELETE("/organizations/:organization/security_groups/:id", api.DeleteSecurityGroup)
 		private.GET("/organizations/:organization/security_group/:id", api.GetSecurityGroup)
 		private.PATCH("/organizations/:organization/security_groups/:id", api.UpdateSecurityGroup)
+		// Registration Keys
+		private.POST("/organizations/:organization/reg_keys", api.CreateRegKey)
+		private.GET("/organizations/:organization/reg_keys", api.ListRegKeys)
+		private.DELETE("/organizations/:organization/reg_keys/:id", api.DeleteRegKey)
+		// Service Accounts
+		private.POST("/organizations/:organization/service_accounts", api.CreateServiceAccount)
+		private.GET("/organizations/:organization/service_accounts", api.ListServiceAccounts)
+		private.DELETE("/organizations/:organization/service_accounts/:id", api.DeleteServiceAccount)
+		private.POST("/organizations/:organization/service_accounts/:id/tokens", api.CreateApiToken)
+		private.GET("/organizations/:organization/service_accounts/:id/tokens", api.ListApiTokens)
+		private.DELETE("/organizations/:organization/service_accounts/:id/tokens/:token_id", api.DeleteApiToken)
+		// Webhooks
+		private.POST("/organizations/:organization/webhooks", api.CreateWebhook)
+		private.GET("/organizations/:organization/webhooks", api.ListWebhooks)
+		private.DELETE("/organizations/:organization/webhooks/:id", api.DeleteWebhook)
+		private.GET("/organizations/:organization/webhooks/:id/deliveries", api.ListWebhookDeliveries)
+		// Organization Peerings
+		private.POST("/organizations/:organization/peerings", api.CreateOrganizationPeering)
+		private.GET("/organizations/:organization/peerings", api.ListOrganizationPeerings)
+		private.POST("/organizations/:organization/peerings/:id/accept", api.AcceptOrganizationPeering)
+		private.DELETE("/organizations/:organization/peerings/:id", api.DeleteOrganizationPeering)
+		private.POST("/organizations/:organization/resize", api.ResizeOrganization)
+		private.GET("/organizations/:organization/renumberings", api.ListOrganizationRenumberings)
+		// IPAM
+		private.GET("/organizations/:organization/ipam_drift", api.GetIpamDrift)
+		private.GET("/ipam_drift", api.ListIpamDrift)
+		private.POST("/organizations/:organization/ip_reservations", api.CreateIpReservation)
+		private.GET("/organizations/:organization/ip_reservations", api.ListIpReservations)
+		private.DELETE("/organizations/:organization/ip_reservations/:id", api.DeleteIpReservation)
+		// Group Mappings
+		private.POST("/organizations/:organization/group_mappings", api.CreateGroupMapping)
+		private.GET("/organizations/:organization/group_mappings", api.ListGroupMappings)
+		private.DELETE("/organizations/:organization/group_mappings/:id", api.DeleteGroupMapping)
 		// Feature Flags
 		private.GET("fflags", api.ListFeatureFlags)
 		private.GET("fflags/:name", api.GetFeatureFlag)
 	}
+	// SCIM provisioning by the identity provider
+	scim := r.Group("/scim/v2", loggerMiddleware, o.Api.SCIMAuth())
+	{
+		api := o.Api
+		scim.GET("/ServiceProviderConfig", api.GetScimServiceProviderConfig)
+		scim.GET("/Users", api.ListScimUsers)
+		scim.POST("/Users", api.CreateScimUser)
+		scim.GET("/Users/:id", api.GetScimUser)
+		scim.PUT("/Users/:id", api.ReplaceScimUser)
+		scim.PATCH("/Users/:id", api.PatchScimUser)
+		scim.DELETE("/Users/:id", api.DeleteScimUser)
+		scim.GET("/Groups", api.ListScimGroups)
+		scim.POST("/Groups", api.CreateScimGroup)
+		scim.GET("/Groups/:id", api.GetScimGroup)
+		scim.PUT("/Groups/:id", api.ReplaceScimGroup)
+		scim.PATCH("/Groups/:id", api.PatchScimGroup)
+		scim.DELETE("/Groups/:id", api.DeleteScimGroup)
+	}
 
 	r.GET("/api/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler), loggerMiddleware)
 
@@ -142,34 +201,13 @@ func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error)
 }
 
 func newValidateJWT(ctx context.Context, o APIRouterOptions) (func(*gin.Context), error) {
-	if o.InsecureTLS {
-		transport := &http.Transport{
-			// #nosec -- G402: TLS InsecureSkipVerify set true.
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		client := &http.Client{Transport: transport}
-		ctx = oidc.ClientContext(ctx, client)
-	}
-
-	oidcURL := o.OidcURL
-	if o.OidcBackchannel != "" {
-		ctx = oidc.InsecureIssuerURLContext(ctx, o.OidcURL)
-		oidcURL = o.OidcBackchannel
-	}
-	provider, err := oidc.NewProvider(ctx, oidcURL)
+	providers, err := newTokenProviders(ctx, o)
 	if err != nil {
 		return nil, err
 	}
-
-	var claims struct {
-		JWKSUri string `json:"jwks_uri"`
-	}
-	err = provider.Claims(&claims)
-	if err != nil {
-		return nil, err
-	}
-
-	return ValidateJWT(ctx, o, claims.JWKSUri)
+	providers.startKeySetRefresh(ctx, o.Logger, o.JWKSRefreshInterval)
+	providers.startVerifiedTokenPurge(ctx, verifiedTokenPurgeInterval)
+	return ValidateJWT(providers.ctx, o, providers)
 }
 
 func newPrometheus() *ginprometheus.Prometheus {
@@ -6,91 +6,150 @@ default valid_token := false
 
 default allowed_email := false
 
+# input.provider is the identity provider of the token, with its audience, the names of the
+# claims of the user and the email domains its users are restricted to.
 allowed_email if {
-	token_payload.from_google
-	endswith(token_payload.email, "redhat.com")
+	count(input.provider.allowed_email_domains) == 0
 }
 
+# only the users whose token has the email_domain_claim are restricted, when it is set
 allowed_email if {
-	not token_payload.from_google
+	input.provider.email_domain_claim != ""
+	not token_payload[input.provider.email_domain_claim] == true
+}
+
+allowed_email if {
+	some domain in input.provider.allowed_email_domains
+	email_in_domain(lower(email), lower(domain))
+}
+
+email_in_domain(address, domain) if {
+	endswith(address, concat("", ["@", domain]))
+}
+
+email_in_domain(address, domain) if {
+	endswith(address, concat("", [".", domain]))
 }
 
 valid_token if {
-	[valid, _, _] := io.jwt.decode_verify(input.access_token, {"cert": input.jwks, "aud": "account"})
+	[valid, _, _] := io.jwt.decode_verify(input.access_token, {"cert": input.jwks, "aud": input.provider.audience})
 	valid == true
 	allowed_email
 }
 
+# the claims of the jwts that were verified by an earlier evaluation are cached by the api server,
+# which passes them instead of the key set until the token expires
+valid_token if {
+	input.verified_token.claims
+	allowed_email
+}
+
+# the access tokens of oauth2 providers are validated by the api server, which fetches the
+# claims of their user from the provider before the policy is evaluated
+valid_token if {
+	input.oauth2.claims
+	allowed_email
+}
+
+# service account api tokens are validated by the api server before the policy is evaluated
+valid_token if {
+	input.api_token.user_id
+}
+
+has_scope(scope) if {
+	contains(token_payload.scope, scope)
+}
+
+has_scope(scope) if {
+	scope in input.api_token.scopes
+}
+
+has_scope(scope) if {
+	scope in input.oauth2.scopes
+}
+
+# the modules of a policy bundle are in the nexodus.bundle package, they can't change these rules.
+# the reasons of their data.nexodus.bundle.deny rules refuse the requests that are allowed.
 default allow := false
 
+# the policy only checks the scopes of the token. the roles of the users are stored on their
+# organization memberships, the handlers check them when they look up the organization.
 allow if {
 	"organizations" = input.path[1]
 	action_is_read
 	valid_token
-	contains(token_payload.scope, "read:organizations")
+	has_scope("read:organizations")
 }
 
 allow if {
 	"organizations" = input.path[1]
 	action_is_write
 	valid_token
-	contains(token_payload.scope, "write:organizations")
+	has_scope("write:organizations")
 }
 
 allow if {
 	"invitations" = input.path[1]
 	action_is_read
 	valid_token
-	contains(token_payload.scope, "read:organizations")
+	has_scope("read:organizations")
 }
 
 allow if {
 	"invitations" = input.path[1]
 	action_is_write
 	valid_token
-	contains(token_payload.scope, "write:organizations")
+	has_scope("write:organizations")
 }
 
 allow if {
 	"devices" = input.path[1]
 	action_is_read
 	valid_token
-	contains(token_payload.scope, "read:devices")
+	has_scope("read:devices")
 }
 
 allow if {
 	"devices" = input.path[1]
 	action_is_write
 	valid_token
-	contains(token_payload.scope, "write:devices")
+	has_scope("write:devices")
 }
 
 allow if {
 	"users" = input.path[1]
 	action_is_read
 	valid_token
-	contains(token_payload.scope, "read:users")
+	has_scope("read:users")
 }
 
 allow if {
 	"users" = input.path[1]
 	action_is_write
 	valid_token
-	contains(token_payload.scope, "write:users")
+	has_scope("write:users")
 }
 
 allow if {
 	"security_groups" = input.path[1]
 	action_is_read
 	valid_token
-	contains(token_payload.scope, "read:organizations")
+	has_scope("read:organizations")
 }
 
 allow if {
 	"security_groups" = input.path[1]
 	action_is_write
 	valid_token
-	contains(token_payload.scope, "write:organizations")
+	has_scope("write:organizations")
+}
+
+# the handler only lets the admins of the apiserver read the drift of all the organizations
+allow if {
+	"ipam_drift" = input.path[1]
+	action_is_read
+	valid_token
+	has_scope("read:organizations")
 }
 
 allow if {
@@ -103,17 +162,58 @@ action_is_read if input.method in ["GET"]
 action_is_write := input.method in ["POST", "PATCH", "DELETE", "PUT"]
 
 token_payload := payload if {
+	not input.oauth2
+	not input.verified_token
 	[_, payload, _] = io.jwt.decode(input.access_token)
 }
 
+token_payload := input.oauth2.claims
+
+token_payload := input.verified_token.claims
+
+claim(name) := value if {
+	value := token_payload[name]
+	is_string(value)
+}
+
+# claims can be numbers, like the user ids of github
+claim(name) := format_int(value, 10) if {
+	value := token_payload[name]
+	is_number(value)
+}
+
 default user_id = ""
 
-user_id = token_payload.sub
+user_id = concat("", [input.provider.user_id_prefix, claim(input.provider.claims.subject)])
+
+user_id = input.api_token.user_id
 
 default user_name = ""
 
-user_name = token_payload.preferred_username
+user_name = claim(input.provider.claims.username)
+
+user_name = input.api_token.user_name
 
 default full_name = ""
 
-full_name = token_payload.name
+full_name = claim(input.provider.claims.full_name)
+
+default email = ""
+
+email = claim(input.provider.claims.email)
+
+default email_verified := false
+
+# the invitations for an email are only matched to the users whose provider verified it
+email_verified if {
+	input.provider.claims.email_verified != ""
+	token_payload[input.provider.claims.email_verified] == true
+}
+
+default groups = []
+
+# the groups are prefixed like the user ids, so the groups of different providers can't collide
+groups = [concat("", [input.provider.user_id_prefix, group]) |
+	some group in token_payload[input.provider.claims.groups]
+	is_string(group)
+]
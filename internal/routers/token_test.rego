@@ -13,6 +13,28 @@ valid_user(scopes) := s if {
 	s
 }
 
+provider := {
+	"name": "default",
+	"type": "oidc",
+	"audience": "account",
+	"user_id_prefix": "",
+	"claims": {
+		"subject": "sub",
+		"username": "preferred_username",
+		"full_name": "name",
+		"email": "email",
+		"email_verified": "email_verified",
+		"groups": "groups",
+	},
+	"allowed_email_domains": [],
+	"email_domain_claim": "",
+}
+
+restricted_provider := object.union(provider, {
+	"allowed_email_domains": ["example.com"],
+	"email_domain_claim": "from_google",
+})
+
 mock_decode_verify("org-write-jwt", _) := [true, {}, {}]
 
 mock_decode("org-write-jwt") := [{}, valid_user("openid profile email write:organizations"), {}]
@@ -39,10 +61,27 @@ mock_decode("user-read-jwt") := [{}, valid_user("openid profile email read:users
 
 mock_decode_verify("bad-jwt", _) := [false, {}, {}]
 
+mock_decode_verify("google-jwt", _) := [true, {}, {}]
+
+mock_decode("google-jwt") := [{}, object.union(valid_user("openid read:devices"), {"from_google": true, "email": "user@example.com"}), {}]
+
+mock_decode_verify("google-subdomain-jwt", _) := [true, {}, {}]
+
+mock_decode("google-subdomain-jwt") := [{}, object.union(valid_user("openid read:devices"), {"from_google": true, "email": "user@eng.Example.com"}), {}]
+
+mock_decode_verify("google-other-domain-jwt", _) := [true, {}, {}]
+
+mock_decode("google-other-domain-jwt") := [{}, object.union(valid_user("openid read:devices"), {"from_google": true, "email": "user@notexample.com"}), {}]
+
+mock_decode_verify("local-user-jwt", _) := [true, {}, {}]
+
+mock_decode("local-user-jwt") := [{}, object.union(valid_user("openid read:devices"), {"email": "user@other.com", "groups": ["admins"]}), {}]
+
 test_org_get_allowed if {
 	token.allow with input.path as ["api", "organizations"]
 		with input.method as "GET"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "org-read-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
@@ -52,6 +91,7 @@ test_org_get_member if {
 	token.allow with input.path as ["api", "organizations", "foo"]
 		with input.method as "GET"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "org-read-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
@@ -61,15 +101,37 @@ test_org_post_allowed if {
 	token.allow with input.path as ["api", "organizations"]
 		with input.method as "POST"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "org-write-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
 }
 
+test_org_member_role_change_allowed if {
+	token.allow with input.path as ["api", "organizations", "foo", "members", "bar"]
+		with input.method as "PATCH"
+		with input.jwks as "my-cert"
+		with input.provider as provider
+		with input.access_token as "org-write-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_org_member_role_change_with_read_scope_denied if {
+	not token.allow with input.path as ["api", "organizations", "foo", "members", "bar"]
+		with input.method as "PATCH"
+		with input.jwks as "my-cert"
+		with input.provider as provider
+		with input.access_token as "org-read-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
 test_org_post_with_read_scope_denied if {
 	not token.allow with input.path as ["api", "organizations"]
 		with input.method as "POST"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "org-read-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
@@ -86,6 +148,7 @@ test_org_get_bad_jwt_denied if {
 	not token.allow with input.path as ["api", "organizations"]
 		with input.method as "GET"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
 }
@@ -94,6 +157,7 @@ test_device_get_allowed if {
 	token.allow with input.path as ["api", "devices"]
 		with input.method as "GET"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "device-read-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
@@ -103,6 +167,7 @@ test_device_post_allowed if {
 	token.allow with input.path as ["api", "devices"]
 		with input.method as "POST"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "device-write-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
@@ -112,6 +177,7 @@ test_device_post_with_read_scope_denied if {
 	not token.allow with input.path as ["api", "devices"]
 		with input.method as "POST"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "device-read-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
@@ -128,6 +194,7 @@ test_device_get_bad_jwt_denied if {
 	not token.allow with input.path as ["api", "devices"]
 		with input.method as "GET"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "bad-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
@@ -137,6 +204,7 @@ test_user_get_allowed if {
 	token.allow with input.path as ["api", "users", "me"]
 		with input.method as "GET"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "user-read-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
@@ -146,6 +214,7 @@ test_user_post_allowed if {
 	token.allow with input.path as ["api", "users", "me"]
 		with input.method as "POST"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "user-write-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
@@ -155,6 +224,7 @@ test_user_post_with_read_scope_denied if {
 	not token.allow with input.path as ["api", "users", "me"]
 		with input.method as "POST"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "user-read-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
@@ -171,15 +241,37 @@ test_user_get_bad_jwt_denied if {
 	not token.allow with input.path as ["api", "users", "me"]
 		with input.method as "GET"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "bad-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
 }
 
+test_get_ipam_drift if {
+	token.allow with input.path as ["api", "ipam_drift"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.provider as provider
+		with input.access_token as "org-read-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_get_ipam_drift_without_scope_denied if {
+	not token.allow with input.path as ["api", "ipam_drift"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.provider as provider
+		with input.access_token as "user-read-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
 test_get_fflags if {
 	token.allow with input.path as ["api", "fflags"]
 		with input.method as "GET"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "user-read-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
@@ -196,7 +288,228 @@ test_get_fflags_bad_jwt_denied if {
 	not token.allow with input.path as ["api", "fflags"]
 		with input.method as "GET"
 		with input.jwks as "my-cert"
+		with input.provider as provider
 		with input.access_token as "bad-jwt"
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
 }
+
+test_api_token_get_devices_allowed if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.access_token as "NT:token"
+		with input.api_token as {"user_id": "3e6f0bf6-9f5b-4ca5-8c8e-5d2b3d8d4d52", "user_name": "ci", "scopes": ["read:devices"]}
+}
+
+test_api_token_post_devices_without_scope_denied if {
+	not token.allow with input.path as ["api", "devices"]
+		with input.method as "POST"
+		with input.access_token as "NT:token"
+		with input.api_token as {"user_id": "3e6f0bf6-9f5b-4ca5-8c8e-5d2b3d8d4d52", "user_name": "ci", "scopes": ["read:devices"]}
+}
+
+test_api_token_user_id if {
+	token.user_id == "3e6f0bf6-9f5b-4ca5-8c8e-5d2b3d8d4d52" with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.access_token as "NT:token"
+		with input.api_token as {"user_id": "3e6f0bf6-9f5b-4ca5-8c8e-5d2b3d8d4d52", "user_name": "ci", "scopes": ["read:devices"]}
+}
+
+test_email_domain_allowed if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.provider as restricted_provider
+		with input.access_token as "google-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_email_subdomain_allowed if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.provider as restricted_provider
+		with input.access_token as "google-subdomain-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_email_other_domain_denied if {
+	not token.valid_token with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.provider as restricted_provider
+		with input.access_token as "google-other-domain-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_email_domain_claim_not_set_allowed if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.provider as restricted_provider
+		with input.access_token as "local-user-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_email_domain_without_claim_denied if {
+	not token.valid_token with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.provider as object.union(restricted_provider, {"email_domain_claim": ""})
+		with input.access_token as "local-user-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_groups_of_the_first_provider_are_not_prefixed if {
+	token.groups == ["admins"] with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.provider as provider
+		with input.access_token as "local-user-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_claim_mapping if {
+	mapped := object.union(provider, {"user_id_prefix": "corp:", "claims": {"subject": "email", "username": "name"}})
+	token.user_id == "corp:user@other.com" with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.provider as mapped
+		with input.access_token as "local-user-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+	token.user_name == "valid-user" with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.provider as mapped
+		with input.access_token as "local-user-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+	token.groups == ["corp:admins"] with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.provider as mapped
+		with input.access_token as "local-user-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+github := {
+	"name": "github",
+	"type": "oauth2",
+	"user_id_prefix": "github:",
+	"claims": {
+		"subject": "id",
+		"username": "login",
+		"full_name": "name",
+		"email": "email",
+		"groups": "",
+	},
+	"allowed_email_domains": [],
+	"email_domain_claim": "",
+}
+
+github_user := {"id": 1234, "login": "octocat", "name": "The Octocat", "email": null}
+
+test_oauth2_get_devices_allowed if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.access_token as "OA:github:token"
+		with input.provider as github
+		with input.oauth2 as {"claims": github_user, "scopes": ["read:devices"]}
+}
+
+test_oauth2_post_devices_without_scope_denied if {
+	not token.allow with input.path as ["api", "devices"]
+		with input.method as "POST"
+		with input.access_token as "OA:github:token"
+		with input.provider as github
+		with input.oauth2 as {"claims": github_user, "scopes": ["read:devices"]}
+}
+
+test_oauth2_without_granted_scopes_denied if {
+	not token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.access_token as "OA:github:token"
+		with input.provider as github
+		with input.oauth2 as {"claims": github_user, "scopes": null}
+}
+
+test_oauth2_user if {
+	token.user_id == "github:1234" with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.access_token as "OA:github:token"
+		with input.provider as github
+		with input.oauth2 as {"claims": github_user, "scopes": ["read:devices"]}
+	token.user_name == "octocat" with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.access_token as "OA:github:token"
+		with input.provider as github
+		with input.oauth2 as {"claims": github_user, "scopes": ["read:devices"]}
+	token.email == "" with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.access_token as "OA:github:token"
+		with input.provider as github
+		with input.oauth2 as {"claims": github_user, "scopes": ["read:devices"]}
+}
+
+test_oauth2_email_domain_denied if {
+	not token.valid_token with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.access_token as "OA:github:token"
+		with input.provider as object.union(github, {"allowed_email_domains": ["example.com"]})
+		with input.oauth2 as {"claims": github_user, "scopes": ["read:devices"]}
+}
+
+test_verified_token_allowed if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.access_token as "device-read-jwt"
+		with input.provider as provider
+		with input.verified_token as {"claims": valid_user("openid profile email read:devices")}
+	token.user_id == "00a7b7f4-f11f-4ea3-89de-7b1cde4316a9" with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.access_token as "device-read-jwt"
+		with input.provider as provider
+		with input.verified_token as {"claims": valid_user("openid profile email read:devices")}
+}
+
+test_verified_token_without_scope_denied if {
+	not token.allow with input.path as ["api", "devices"]
+		with input.method as "POST"
+		with input.access_token as "device-read-jwt"
+		with input.provider as provider
+		with input.verified_token as {"claims": valid_user("openid profile email read:devices")}
+}
+
+test_verified_token_email_domain_denied if {
+	not token.valid_token with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.access_token as "device-read-jwt"
+		with input.provider as restricted_provider
+		with input.verified_token as {"claims": object.union(valid_user("openid profile email read:devices"), {"email": "user@other.com", "from_google": true})}
+}
+
+test_email_verified if {
+	token.email_verified with input.path as ["api", "invitations"]
+		with input.method as "GET"
+		with input.access_token as "org-read-jwt"
+		with input.provider as provider
+		with input.verified_token as {"claims": object.union(valid_user("read:organizations"), {"email": "user@example.com", "email_verified": true})}
+	not token.email_verified with input.path as ["api", "invitations"]
+		with input.method as "GET"
+		with input.access_token as "org-read-jwt"
+		with input.provider as provider
+		with input.verified_token as {"claims": object.union(valid_user("read:organizations"), {"email": "user@example.com", "email_verified": "true"})}
+	not token.email_verified with input.path as ["api", "invitations"]
+		with input.method as "GET"
+		with input.access_token as "org-read-jwt"
+		with input.provider as provider
+		with input.verified_token as {"claims": valid_user("read:organizations")}
+}
@@ -0,0 +1,94 @@
+package signalbus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/util"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var _ SignalBus = &RedisSignalBus{} // type check the interface is implemented.
+
+// redisSignalBusChannel is the redis pub/sub channel that the signals are published to.
+const redisSignalBusChannel = "signalbus"
+
+// RedisSignalBus implements a signalbus.SignalBus that is clustered using redis pub/sub.
+type RedisSignalBus struct {
+	client    *redis.Client
+	signalBus SignalBus // typically an in memory signal bus.
+	logger    *zap.SugaredLogger
+}
+
+// NewRedisSignalBus creates a new RedisSignalBus
+func NewRedisSignalBus(signalBus SignalBus, client *redis.Client, logger *zap.SugaredLogger) *RedisSignalBus {
+	return &RedisSignalBus{
+		client:    client,
+		signalBus: signalBus,
+		logger:    logger,
+	}
+}
+
+// Notify will notify all the subscriptions created across the cluster of the given named signal.
+func (rsb *RedisSignalBus) Notify(name string) {
+	// like the PgSignalBus, the signal is only sent to the in memory bus when redis
+	// sends it back to us and all the other processes subscribed to the channel.
+	if err := rsb.client.Publish(context.Background(), redisSignalBusChannel, name).Err(); err != nil {
+		rsb.logger.Info("notify failed:", err.Error())
+	}
+}
+
+func (rsb *RedisSignalBus) NotifyAll() {
+	if err := rsb.client.Publish(context.Background(), redisSignalBusChannel, "*").Err(); err != nil {
+		rsb.logger.Info("notify failed:", err.Error())
+	}
+}
+
+// Subscribe creates a subscription the named signal.
+// They are performed on the in memory bus.
+func (rsb *RedisSignalBus) Subscribe(name string) *Subscription {
+	return rsb.signalBus.Subscribe(name)
+}
+
+// Start starts the background worker that receives the signals that are published
+// by this process and all other processes publishing to the signalbus channel.
+func (rsb *RedisSignalBus) Start(ctx context.Context, wg *sync.WaitGroup) {
+	pubsub := rsb.client.Subscribe(ctx, redisSignalBusChannel)
+	util.GoWithWaitGroup(wg, func() {
+		// Receive does not return when ctx is done, closing the pubsub unblocks it.
+		<-ctx.Done()
+		_ = pubsub.Close()
+	})
+	util.GoWithWaitGroup(wg, func() {
+		subscribed := false
+		for {
+			msg, err := pubsub.Receive(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				// the next Receive reconnects and subscribes to the channel again.
+				rsb.logger.Errorln("error receiving signal:", err.Error())
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				// signals published while we were disconnected are lost, so after
+				// a reconnect let all the subscribers check for changes.
+				if subscribed {
+					rsb.signalBus.NotifyAll()
+				}
+				subscribed = true
+			case *redis.Message:
+				if msg.Payload == "*" {
+					rsb.signalBus.NotifyAll()
+				} else {
+					rsb.signalBus.Notify(msg.Payload)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package signalbus
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRedisSignalBus(t *testing.T) {
+	require := require.New(t)
+
+	server := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	// two buses sharing a redis server, like two apiserver replicas.
+	newBus := func() *RedisSignalBus {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() {
+			_ = client.Close()
+		})
+		bus := NewRedisSignalBus(NewSignalBus(), client, zap.NewNop().Sugar())
+		bus.Start(ctx, wg)
+		return bus
+	}
+	bus1 := newBus()
+	bus2 := newBus()
+	require.Eventually(func() bool {
+		return server.PubSubNumSub(redisSignalBusChannel)[redisSignalBusChannel] == 2
+	}, 2*time.Second, time.Millisecond)
+
+	aSub := bus2.Subscribe("a")
+	defer aSub.Close()
+	bSub := bus2.Subscribe("b")
+	defer bSub.Close()
+
+	// a notification on one replica signals the subscriptions of the other.
+	bus1.Notify("a")
+	require.Eventually(aSub.IsSignaled, 2*time.Second, time.Millisecond)
+	require.False(bSub.IsSignaled())
+
+	bus1.NotifyAll()
+	require.Eventually(aSub.IsSignaled, 2*time.Second, time.Millisecond)
+	require.Eventually(bSub.IsSignaled, 2*time.Second, time.Millisecond)
+
+	// after redis restarts, the subscriptions are signaled since notifications may have been lost.
+	server.Close()
+	require.NoError(server.Restart())
+	require.Eventually(aSub.IsSignaled, 5*time.Second, time.Millisecond)
+	require.Eventually(bSub.IsSignaled, 5*time.Second, time.Millisecond)
+
+	bus1.Notify("b")
+	require.Eventually(bSub.IsSignaled, 5*time.Second, time.Millisecond)
+}
@@ -11,6 +11,14 @@ type State struct {
 	PublicKey        string           `json:"public-key"`
 	PrivateKey       string           `json:"private-key"`
 	ProxyRulesConfig ProxyRulesConfig `json:"proxy-rules-config"`
+	// Organizations holds the state of the additional organizations nexd joins, by organization id.
+	Organizations map[string]OrganizationState `json:"organizations,omitempty"`
+}
+
+// OrganizationState is the state of the device of an additional organization.
+type OrganizationState struct {
+	PublicKey  string `json:"public-key"`
+	PrivateKey string `json:"private-key"`
 }
 
 type ProxyRulesConfig struct {
@@ -36,3 +36,17 @@ func (c *RWMutexCache[K, V]) Delete(key K) (V, bool) {
 	delete(c.data, key)
 	return x, found
 }
+
+// DeleteFunc deletes the entries for which del returns true, and returns how many were deleted.
+func (c *RWMutexCache[K, V]) DeleteFunc(del func(key K, value V) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	deleted := 0
+	for key, value := range c.data {
+		if del(key, value) {
+			delete(c.data, key)
+			deleted++
+		}
+	}
+	return deleted
+}
@@ -53,3 +53,12 @@ func (c *RWMutexTTLCache[K, V]) Delete(key K) (V, bool) {
 	}
 	return x.value, found
 }
+
+// DeleteExpired deletes the expired entries, which are otherwise only replaced when their key is
+// put again, and returns how many were deleted.
+func (c *RWMutexTTLCache[K, V]) DeleteExpired() int {
+	now := time.Now()
+	return c.data.DeleteFunc(func(_ K, x entry[V]) bool {
+		return x.expiresAt.Before(now)
+	})
+}
@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRWMutexTTLCache_DeleteExpired(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	cache := NewRWMutexTTLCache[string, string](time.Hour)
+
+	cache.Put("fresh", "a")
+	cache.PutWithTTL("expired", "b", -time.Second)
+	require.Equal(1, cache.DeleteExpired())
+
+	value, found := cache.Get("fresh")
+	require.True(found)
+	require.Equal("a", value)
+	_, found = cache.Get("expired")
+	require.False(found)
+	require.Equal(0, cache.DeleteExpired())
+}
@@ -27,6 +27,9 @@ type OidcAgent struct {
 	backend        *url.URL
 	cookieKey      string
 	insecureTLS    bool
+	scopes         []string
+	tokenURL       string
+	tokenPrefix    string
 }
 
 type OauthConfig interface {
@@ -128,6 +131,34 @@ func NewOidcAgent(ctx context.Context,
 	return auth, nil
 }
 
+// NewOAuth2Agent creates an agent for the device flow of a plain OAuth2 provider, which has
+// no discovery document to find its endpoints nor issues ID tokens. The clients prepend the
+// tokenPrefix to the access tokens of the provider, so the backend can tell them apart.
+func NewOAuth2Agent(
+	logger *zap.Logger,
+	clientID string,
+	deviceAuthURL string,
+	tokenURL string,
+	scopes []string,
+	tokenPrefix string,
+) *OidcAgent {
+	return &OidcAgent{
+		logger:   logger.Sugar(),
+		clientID: clientID,
+		oauthConfig: &oauth2.Config{
+			ClientID: clientID,
+			Endpoint: oauth2.Endpoint{
+				TokenURL: tokenURL,
+			},
+			Scopes: scopes,
+		},
+		deviceAuthURL: deviceAuthURL,
+		tokenURL:      tokenURL,
+		tokenPrefix:   tokenPrefix,
+		scopes:        scopes,
+	}
+}
+
 func (o *OidcAgent) LogoutURL(idToken string) (*url.URL, error) {
 	u, err := url.Parse(o.endSessionURL)
 	if err != nil {
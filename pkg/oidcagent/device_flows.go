@@ -0,0 +1,61 @@
+package oidcagent
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceFlows serves the device flow of several identity providers, the device chooses
+// one of them by name when it starts the login.
+type DeviceFlows struct {
+	names  []string
+	agents map[string]*OidcAgent
+}
+
+func NewDeviceFlows() *DeviceFlows {
+	return &DeviceFlows{
+		agents: map[string]*OidcAgent{},
+	}
+}
+
+// Add adds the agent of a provider, the first one added is used when the device doesn't
+// choose a provider.
+func (d *DeviceFlows) Add(name string, agent *OidcAgent) {
+	if _, found := d.agents[name]; !found {
+		d.names = append(d.names, name)
+	}
+	d.agents[name] = agent
+}
+
+// Names returns the names of the providers, in the order they were added.
+func (d *DeviceFlows) Names() []string {
+	return d.names
+}
+
+// DeviceStart starts a device login request
+// @Summary      Start Login
+// @Description  Starts a device login request with an identity provider
+// @Id 			 DeviceStart
+// @Tags         Auth
+// @Accepts		 json
+// @Produce      json
+// @Param        provider  query  string  false  "Name of the identity provider, the default provider is used when not set"
+// @Success      200  {object}  models.DeviceStartResponse
+// @Failure      400
+// @Router       /device/login/start [post]
+func (d *DeviceFlows) DeviceStart(c *gin.Context) {
+	name := c.Query("provider")
+	if name == "" && len(d.names) > 0 {
+		name = d.names[0]
+	}
+	agent, found := d.agents[name]
+	if !found {
+		_ = c.AbortWithError(http.StatusBadRequest, fmt.Errorf("unknown identity provider: %s", name))
+		return
+	}
+	response := agent.deviceStartResponse()
+	response.Provider = name
+	c.JSON(http.StatusOK, response)
+}
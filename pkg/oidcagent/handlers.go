@@ -432,20 +432,19 @@ func isLoggedIn(c *gin.Context) bool {
 }
 
 // DeviceStart starts a device login request
-// @Summary      Start Login
-// @Description  Starts a device login request
-// @Id 			 DeviceStart
-// @Tags         Auth
-// @Accepts		 json
-// @Produce      json
-// @Success      200  {object}  models.DeviceStartResponse
-// @Router       /device/login/start [post]
 func (o *OidcAgent) DeviceStart(c *gin.Context) {
-	c.JSON(http.StatusOK, models.DeviceStartResponse{
+	c.JSON(http.StatusOK, o.deviceStartResponse())
+}
+
+func (o *OidcAgent) deviceStartResponse() models.DeviceStartResponse {
+	return models.DeviceStartResponse{
 		DeviceAuthURL: o.deviceAuthURL,
 		Issuer:        o.oidcIssuer,
 		ClientID:      o.clientID,
-	})
+		TokenEndpoint: o.tokenURL,
+		Scopes:        o.scopes,
+		TokenPrefix:   o.tokenPrefix,
+	}
 }
 
 func (o *OidcAgent) DeviceFlowProxy(c *gin.Context) {
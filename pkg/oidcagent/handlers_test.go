@@ -397,6 +397,53 @@ func TestDeviceStart(t *testing.T) {
 	assert.Equal(t, "cli-app", response.ClientID)
 }
 
+func TestDeviceFlows(t *testing.T) {
+	flows := NewDeviceFlows()
+	flows.Add("keycloak", &OidcAgent{
+		logger:        zap.NewExample().Sugar(),
+		oidcIssuer:    "http://auth.example.com",
+		deviceAuthURL: "http://auth.example.com/device",
+		clientID:      "cli-app",
+	})
+	flows.Add("github", NewOAuth2Agent(zap.NewExample(), "github-app",
+		"https://github.com/login/device/code", "https://github.com/login/oauth/access_token",
+		[]string{"read:user"}, "OA:github:"))
+	assert.Equal(t, []string{"keycloak", "github"}, flows.Names())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/login/start", flows.DeviceStart)
+	start := func(query string) (int, models.DeviceStartResponse) {
+		req, _ := http.NewRequest("POST", "/login/start"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var response models.DeviceStartResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		}
+		return w.Code, response
+	}
+
+	code, response := start("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "keycloak", response.Provider)
+	assert.Equal(t, "http://auth.example.com", response.Issuer)
+
+	code, response = start("?provider=github")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, models.DeviceStartResponse{
+		DeviceAuthURL: "https://github.com/login/device/code",
+		ClientID:      "github-app",
+		TokenEndpoint: "https://github.com/login/oauth/access_token",
+		Scopes:        []string{"read:user"},
+		TokenPrefix:   "OA:github:",
+		Provider:      "github",
+	}, response)
+
+	code, _ = start("?provider=missing")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
 func setUnexportedField(field reflect.Value, value interface{}) {
 	reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).
 		Elem().
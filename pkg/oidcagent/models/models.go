@@ -32,4 +32,12 @@ type DeviceStartResponse struct {
 	DeviceAuthURL string `json:"device_authorization_endpoint"`
 	Issuer        string `json:"issuer"`
 	ClientID      string `json:"client_id"`
+	// TokenEndpoint is set when the provider has no issuer to discover it from.
+	TokenEndpoint string `json:"token_endpoint,omitempty"`
+	// Scopes are requested by the device.
+	Scopes []string `json:"scopes,omitempty"`
+	// TokenPrefix is prepended to the access tokens sent to the api.
+	TokenPrefix string `json:"token_prefix,omitempty"`
+	// Provider is the name of the identity provider, when the device can choose one.
+	Provider string `json:"provider,omitempty"`
 }
@@ -1,4 +1,5 @@
-import { Admin, Resource, fetchUtils } from "react-admin";
+import { Admin, CustomRoutes, Resource, fetchUtils } from "react-admin";
+import { Route } from "react-router-dom";
 import simpleRestProvider from "ra-data-simple-rest";
 import { goOidcAgentAuthProvider } from "./providers/AuthProvider";
 
@@ -20,6 +21,7 @@ import Dashboard from "./pages/Dashboard";
 import LoginPage from "./pages/Login";
 import Layout from "./layout/Layout";
 import {
+  InvitationAccept,
   InvitationCreate,
   InvitationList,
   InvitationShow,
@@ -45,6 +47,12 @@ const dataProvider = {
       (response) => response,
     );
   },
+  acceptInvitation: (token: string) => {
+    return fetchJson(new URL(`${backend}/api/invitations/accept`), {
+      method: "POST",
+      body: JSON.stringify({ token }),
+    });
+  },
 };
 
 const App = () => {
@@ -88,6 +96,9 @@ const App = () => {
         create={InvitationCreate}
         recordRepresentation={(record) => `${record.hostname}`}
       />
+      <CustomRoutes>
+        <Route path="/invitations/accept" element={<InvitationAccept />} />
+      </CustomRoutes>
     </Admin>
   );
 };
@@ -1,4 +1,4 @@
-import { Fragment } from "react";
+import { Fragment, useEffect, useRef } from "react";
 import {
   Datagrid,
   List,
@@ -18,8 +18,11 @@ import {
   SimpleForm,
   ReferenceInput,
   TextInput,
-  required,
   useGetIdentity,
+  SelectInput,
+  useDataProvider,
+  useNotify,
+  useRedirect,
 } from "react-admin";
 
 const InvitationListBulkActions = () => (
@@ -34,6 +37,7 @@ export const InvitationList = () => (
     <Datagrid rowClick="show" bulkActionButtons={<InvitationListBulkActions />}>
       <TextField label="ID" source="id" />
       <TextField label="User ID" source="user_id" />
+      <TextField label="Email" source="email" />
       {/* Right now we can't look up other users, we don't have access */}
       {/*<ReferenceField*/}
       {/*  label="User"*/}
@@ -47,6 +51,7 @@ export const InvitationList = () => (
         reference="organizations"
         link="show"
       />
+      <TextField label="Role" source="role" />
       <TextField label="Expires" source="expiry" />
     </Datagrid>
   </List>
@@ -57,6 +62,7 @@ export const InvitationShow = () => (
     <SimpleShowLayout>
       <TextField label="ID" source="id" />
       <TextField label="User ID" source="user_id" />
+      <TextField label="Email" source="email" />
       {/* Right now we can't look up other users, we don't have access */}
       {/*<ReferenceField*/}
       {/*  label="User"*/}
@@ -70,6 +76,7 @@ export const InvitationShow = () => (
         reference="organizations"
         link="show"
       />
+      <TextField label="Role" source="role" />
       <TextField label="Expires" source="expiry" />
     </SimpleShowLayout>
   </Show>
@@ -87,9 +94,26 @@ export const InvitationCreate = () => {
           label="User Name"
           name="user_name"
           source="user_name"
-          validate={[required()]}
           fullWidth
         />
+        <TextInput
+          label="Email (for users that never logged in)"
+          name="email"
+          source="email"
+          type="email"
+          fullWidth
+        />
+        <SelectInput
+          label="Role"
+          name="role"
+          source="role"
+          defaultValue="member"
+          choices={[
+            { id: "admin", name: "Admin" },
+            { id: "member", name: "Member" },
+            { id: "read-only", name: "Read Only" },
+          ]}
+        />
         <ReferenceInput
           label="User Name"
           name="organization_id"
@@ -101,3 +125,38 @@ export const InvitationCreate = () => {
     </Create>
   );
 };
+
+// InvitationAccept accepts the invitation of the link the user opened.
+export const InvitationAccept = () => {
+  const dataProvider = useDataProvider();
+  const notify = useNotify();
+  const redirect = useRedirect();
+  const accepted = useRef(false);
+  useEffect(() => {
+    // the link can only be used once, so it's only accepted once.
+    if (accepted.current) {
+      return;
+    }
+    accepted.current = true;
+    const query = window.location.hash.split("?")[1] || "";
+    const token = new URLSearchParams(query).get("token");
+    if (!token) {
+      notify("The invitation link is invalid", { type: "error" });
+      redirect("/invitations");
+      return;
+    }
+    dataProvider
+      .acceptInvitation(token)
+      .then(() => {
+        notify("Invitation accepted", { type: "success" });
+        redirect("/organizations");
+      })
+      .catch(() => {
+        notify("The invitation is invalid, expired or was already accepted", {
+          type: "error",
+        });
+        redirect("/invitations");
+      });
+  }, [dataProvider, notify, redirect]);
+  return <Loading />;
+};